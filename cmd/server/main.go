@@ -70,6 +70,20 @@ func shouldEnableExampleAPIKeySafeMode(cfg *config.Config, commandMode, tuiMode,
 // It parses command-line flags, loads configuration, and starts the appropriate
 // service based on the provided flags (login, codex-login, or server mode).
 func main() {
+	// The validate subcommand prints a machine-readable report, so it runs before the banner.
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(cmd.DoValidate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	// The login subcommand is shorthand for the per-provider login flags.
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		loginArgs, errLogin := cmd.LoginSubcommandArgs(os.Args[2:])
+		if errLogin != nil {
+			_, _ = fmt.Fprintln(os.Stderr, errLogin)
+			os.Exit(2)
+		}
+		os.Args = append([]string{os.Args[0]}, loginArgs...)
+	}
+
 	fmt.Printf("CLIProxyAPI Version: %s, Commit: %s, BuiltAt: %s\n", buildinfo.Version, buildinfo.Commit, buildinfo.BuildDate)
 
 	// Command-line flags to control the application's behavior.
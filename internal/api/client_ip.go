@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/api/middleware"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// clientIPPolicy is the parsed form of server.trusted-proxies and remote-management.allowed-cidrs.
+type clientIPPolicy struct {
+	trustedProxies  []netip.Prefix
+	managementCIDRs []netip.Prefix
+	// managementRestricted is set whenever allowed-cidrs is configured, even if no entry
+	// parsed, so a typo locks the management API instead of opening it.
+	managementRestricted bool
+}
+
+func newClientIPPolicy(cfg *config.Config) *clientIPPolicy {
+	policy := &clientIPPolicy{}
+	if cfg == nil {
+		return policy
+	}
+	var invalid []string
+	policy.trustedProxies, invalid = middleware.ParseIPPrefixes(cfg.Server.TrustedProxies)
+	if len(invalid) > 0 {
+		log.Warnf("ignoring invalid server.trusted-proxies entries: %s", strings.Join(invalid, ", "))
+	}
+	policy.managementCIDRs, invalid = middleware.ParseIPPrefixes(cfg.RemoteManagement.AllowedCIDRs)
+	if len(invalid) > 0 {
+		log.Warnf("ignoring invalid remote-management.allowed-cidrs entries: %s", strings.Join(invalid, ", "))
+	}
+	for _, entry := range cfg.RemoteManagement.AllowedCIDRs {
+		if strings.TrimSpace(entry) != "" {
+			policy.managementRestricted = true
+			break
+		}
+	}
+	return policy
+}
+
+// clientIPPolicyHolder publishes the current policy to request middleware across reloads.
+type clientIPPolicyHolder struct {
+	policy atomic.Pointer[clientIPPolicy]
+}
+
+func (h *clientIPPolicyHolder) trustedProxies() []netip.Prefix {
+	if policy := h.policy.Load(); policy != nil {
+		return policy.trustedProxies
+	}
+	return nil
+}
+
+func (h *clientIPPolicyHolder) managementCIDRs() ([]netip.Prefix, bool) {
+	if policy := h.policy.Load(); policy != nil {
+		return policy.managementCIDRs, policy.managementRestricted
+	}
+	return nil, false
+}
@@ -0,0 +1,15 @@
+package management
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor"
+)
+
+// GetAntigravityEndpoints returns the health of each Antigravity base URL: call counts by
+// outcome, the latest error and the availability over the last five minutes.
+func (h *Handler) GetAntigravityEndpoints(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"endpoints": executor.AntigravityEndpointHealth(time.Now())})
+}
@@ -0,0 +1,73 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func TestGetAntigravityEndpoints(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}}`))
+	}))
+	defer healthy.Close()
+	unreachable := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	unreachable.Close()
+
+	exec := executor.NewAntigravityExecutor(&config.Config{})
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	send := func(baseURL string) error {
+		auth := &coreauth.Auth{
+			ID:         "antigravity-endpoints-" + baseURL,
+			Provider:   "antigravity",
+			Attributes: map[string]string{"api_key": "gateway-key", "base_url": baseURL},
+		}
+		_, errExecute := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload},
+			cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: payload})
+		return errExecute
+	}
+	for i := 0; i < 3; i++ {
+		if errSend := send(healthy.URL); errSend != nil {
+			t.Fatalf("healthy Execute() error = %v", errSend)
+		}
+	}
+	if errSend := send(unreachable.URL); errSend == nil {
+		t.Fatal("unreachable Execute() error = nil, want transport error")
+	}
+
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v0/management/providers/antigravity/endpoints", nil)
+	h.GetAntigravityEndpoints(ctx)
+
+	var body struct {
+		Endpoints []executor.AntigravityEndpointStatus `json:"endpoints"`
+	}
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &body); errUnmarshal != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	byURL := make(map[string]executor.AntigravityEndpointStatus, len(body.Endpoints))
+	for _, endpoint := range body.Endpoints {
+		byURL[endpoint.BaseURL] = endpoint
+	}
+	if got := byURL[healthy.URL]; got.Successes != 3 || got.RecentCalls != 3 || got.Availability == nil || *got.Availability != 1 {
+		t.Fatalf("healthy endpoint = %+v, want 3 successes at full availability", got)
+	}
+	if got := byURL[unreachable.URL]; got.TransportErrors != 1 || got.LastError == "" || got.LastErrorAt == nil || got.Availability == nil || *got.Availability != 0 {
+		t.Fatalf("unreachable endpoint = %+v, want 1 transport error at zero availability", got)
+	}
+	if len(body.Endpoints) < 3 {
+		t.Fatalf("endpoints = %+v, want the default base URLs listed too", body.Endpoints)
+	}
+}
@@ -0,0 +1,299 @@
+package management
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// auditLogFileName is the JSON-lines file in the log directory holding audit entries.
+	auditLogFileName = "management-audit.log"
+	// defaultAuditQueryLimit is the page size used when GET /audit does not specify one.
+	defaultAuditQueryLimit = 50
+	// maxAuditQueryLimit caps the page size of GET /audit.
+	maxAuditQueryLimit = 500
+
+	auditKeyFingerprintContextKey = "managementKeyFingerprint"
+)
+
+// auditSensitiveNames are config key fragments whose values are replaced by a hash prefix
+// in audit entries.
+var auditSensitiveNames = []string{"key", "secret", "token", "password", "authorization", "cookie", "credential", "proxy-url"}
+
+// AuditEntry records one mutating management API call.
+type AuditEntry struct {
+	ID             uint64        `json:"id"`
+	Timestamp      time.Time     `json:"timestamp"`
+	KeyFingerprint string        `json:"key_fingerprint"`
+	ClientIP       string        `json:"client_ip"`
+	Method         string        `json:"method"`
+	Route          string        `json:"route"`
+	StatusCode     int           `json:"status_code"`
+	ConfigChanges  []AuditChange `json:"config_changes,omitempty"`
+	AuthsAdded     []string      `json:"auths_added,omitempty"`
+	AuthsRemoved   []string      `json:"auths_removed,omitempty"`
+}
+
+// AuditChange is one changed config value. Secret values are replaced by
+// "sha256:" and the first 12 hex digits of their hash, so changes to the same secret
+// can be correlated without revealing it.
+type AuditChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// auditSnapshot captures the state a management call may change.
+type auditSnapshot struct {
+	config map[string]string
+	auths  map[string]struct{}
+}
+
+// auditFingerprint returns the stable hash prefix that stands in for a secret value.
+func auditFingerprint(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// AuditMiddleware records every mutating management call in the audit log, with the
+// redacted config values and the auth records it changed. It must run after Middleware.
+func (h *Handler) AuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		before := h.auditSnapshot()
+		c.Next()
+		after := h.auditSnapshot()
+
+		entry := AuditEntry{
+			Timestamp:      time.Now().UTC(),
+			KeyFingerprint: c.GetString(auditKeyFingerprintContextKey),
+			ClientIP:       c.ClientIP(),
+			Method:         c.Request.Method,
+			Route:          c.FullPath(),
+			StatusCode:     c.Writer.Status(),
+			ConfigChanges:  diffAuditConfig(before.config, after.config),
+			AuthsAdded:     diffAuditAuths(after.auths, before.auths),
+			AuthsRemoved:   diffAuditAuths(before.auths, after.auths),
+		}
+		if entry.Route == "" {
+			entry.Route = c.Request.URL.Path
+		}
+		if errWrite := h.appendAuditEntry(entry); errWrite != nil {
+			log.Warnf("management audit: failed to record %s %s: %v", entry.Method, entry.Route, errWrite)
+		}
+	}
+}
+
+func (h *Handler) auditSnapshot() auditSnapshot {
+	snapshot := auditSnapshot{config: make(map[string]string), auths: make(map[string]struct{})}
+	h.mu.Lock()
+	cfg := h.cfg
+	manager := h.authManager
+	var raw []byte
+	if cfg != nil {
+		raw, _ = yaml.Marshal(cfg)
+	}
+	h.mu.Unlock()
+
+	var tree any
+	if len(raw) > 0 && yaml.Unmarshal(raw, &tree) == nil {
+		flattenAuditConfig("", "", false, tree, snapshot.config)
+	}
+	if manager != nil {
+		for _, auth := range manager.List() {
+			snapshot.auths[auth.ID] = struct{}{}
+		}
+	}
+	return snapshot
+}
+
+// flattenAuditConfig writes every leaf of tree into out keyed by its dotted path, hashing
+// leaves whose nearest key name is sensitive or that sit below a headers map.
+func flattenAuditConfig(path, name string, sensitive bool, tree any, out map[string]string) {
+	switch value := tree.(type) {
+	case map[string]any:
+		for key, child := range value {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flattenAuditConfig(childPath, key, sensitive || strings.EqualFold(key, "headers"), child, out)
+		}
+	case []any:
+		for i, child := range value {
+			flattenAuditConfig(fmt.Sprintf("%s[%d]", path, i), name, sensitive, child, out)
+		}
+	case nil:
+	default:
+		text := fmt.Sprint(value)
+		if sensitive || auditSensitiveName(name) {
+			text = auditFingerprint(text)
+		}
+		out[path] = text
+	}
+}
+
+func auditSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, fragment := range auditSensitiveNames {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func diffAuditConfig(before, after map[string]string) []AuditChange {
+	var changes []AuditChange
+	for path, oldValue := range before {
+		if newValue, ok := after[path]; !ok || newValue != oldValue {
+			changes = append(changes, AuditChange{Path: path, Old: oldValue, New: after[path]})
+		}
+	}
+	for path, newValue := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, AuditChange{Path: path, New: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// diffAuditAuths returns the sorted auth IDs in a that are missing from b.
+func diffAuditAuths(a, b map[string]struct{}) []string {
+	var ids []string
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (h *Handler) auditLogPath() string {
+	dir := h.logDirectory()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, auditLogFileName)
+}
+
+func (h *Handler) appendAuditEntry(entry AuditEntry) error {
+	path := h.auditLogPath()
+	if path == "" {
+		return fmt.Errorf("log directory is not configured")
+	}
+	line, errMarshal := json.Marshal(entry)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	h.auditMu.Lock()
+	defer h.auditMu.Unlock()
+	if errMkdir := os.MkdirAll(filepath.Dir(path), 0o755); errMkdir != nil {
+		return errMkdir
+	}
+	file, errOpen := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if errOpen != nil {
+		return errOpen
+	}
+	if _, errWrite := file.Write(append(line, '\n')); errWrite != nil {
+		_ = file.Close()
+		return errWrite
+	}
+	return file.Close()
+}
+
+// readAuditEntries returns all audit entries in write order, numbering them from 1.
+func (h *Handler) readAuditEntries() ([]AuditEntry, error) {
+	path := h.auditLogPath()
+	if path == "" {
+		return nil, nil
+	}
+	h.auditMu.Lock()
+	defer h.auditMu.Unlock()
+	file, errOpen := os.Open(path)
+	if errOpen != nil {
+		if os.IsNotExist(errOpen) {
+			return nil, nil
+		}
+		return nil, errOpen
+	}
+	defer func() { _ = file.Close() }()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if errUnmarshal := json.Unmarshal(scanner.Bytes(), &entry); errUnmarshal != nil {
+			continue
+		}
+		entry.ID = uint64(len(entries) + 1)
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// GetAudit lists management audit entries, newest first.
+//
+// Query parameters:
+//   - before: cursor returned as next_before by the previous page
+//   - limit: page size (default 50, max 500)
+func (h *Handler) GetAudit(c *gin.Context) {
+	var before uint64
+	if raw := strings.TrimSpace(c.Query("before")); raw != "" {
+		parsed, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before"})
+			return
+		}
+		before = parsed
+	}
+	limit := defaultAuditQueryLimit
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(parsed, maxAuditQueryLimit)
+	}
+
+	entries, errRead := h.readAuditEntries()
+	if errRead != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errRead.Error()})
+		return
+	}
+	end := len(entries)
+	if before > 0 && before <= uint64(end) {
+		end = int(before) - 1
+	}
+	page := make([]AuditEntry, 0, limit)
+	for i := end - 1; i >= 0 && len(page) < limit; i-- {
+		page = append(page, entries[i])
+	}
+	response := gin.H{"entries": page, "total": len(entries)}
+	if last := len(page) - 1; last >= 0 && page[last].ID > 1 {
+		response["next_before"] = page[last].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
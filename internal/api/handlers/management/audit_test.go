@@ -0,0 +1,120 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+)
+
+const auditTestManagementKey = "audit-management-key"
+
+func newAuditTestRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	t.Setenv("MANAGEMENT_PASSWORD", auditTestManagementKey)
+
+	tempDir := t.TempDir()
+	authDir := filepath.Join(tempDir, "auth")
+	if errMkdir := os.MkdirAll(authDir, 0o700); errMkdir != nil {
+		t.Fatalf("failed to create auth dir: %v", errMkdir)
+	}
+	authPath := filepath.Join(authDir, "codex-audit.json")
+	if errWrite := os.WriteFile(authPath, []byte(`{"type":"codex","access_token":"secret-token"}`), 0o600); errWrite != nil {
+		t.Fatalf("failed to write auth file: %v", errWrite)
+	}
+	manager := coreauth.NewManager(nil, nil, nil)
+	record := &coreauth.Auth{
+		ID:         "codex-audit.json",
+		FileName:   "codex-audit.json",
+		Provider:   "codex",
+		Attributes: map[string]string{"path": authPath},
+	}
+	if _, errRegister := manager.Register(context.Background(), record); errRegister != nil {
+		t.Fatalf("failed to register auth record: %v", errRegister)
+	}
+
+	h := NewHandler(&config.Config{AuthDir: authDir, SDKConfig: config.SDKConfig{APIKeys: []string{"old-client-key"}}}, writeTestConfigFile(t), manager)
+	h.tokenStore = &memoryAuthStore{}
+	h.SetLogDirectory(filepath.Join(tempDir, "logs"))
+
+	router := gin.New()
+	mgmt := router.Group("/v0/management", h.Middleware(), h.AuditMiddleware())
+	mgmt.GET("/audit", h.GetAudit)
+	mgmt.PUT("/api-keys", h.PutAPIKeys)
+	mgmt.DELETE("/auth-files", h.DeleteAuthFile)
+	return router, h.auditLogPath()
+}
+
+func serveAuditTestRequest(t *testing.T, router *gin.Engine, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+auditTestManagementKey)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s %s status = %d, body %s", method, target, rec.Code, rec.Body.String())
+	}
+	return rec
+}
+
+func TestAuditRecordsConfigChangeAndAuthDeletion(t *testing.T) {
+	router, auditPath := newAuditTestRouter(t)
+
+	serveAuditTestRequest(t, router, http.MethodPut, "/v0/management/api-keys", `["new-client-key"]`)
+	serveAuditTestRequest(t, router, http.MethodDelete, "/v0/management/auth-files?name=codex-audit.json", "")
+	serveAuditTestRequest(t, router, http.MethodGet, "/v0/management/audit", "")
+
+	rawLog, errRead := os.ReadFile(auditPath)
+	if errRead != nil {
+		t.Fatalf("failed to read audit log: %v", errRead)
+	}
+	for _, secret := range []string{auditTestManagementKey, "old-client-key", "new-client-key", "secret-token"} {
+		if strings.Contains(string(rawLog), secret) {
+			t.Fatalf("audit log leaks %q:\n%s", secret, rawLog)
+		}
+	}
+
+	var page struct {
+		Entries    []AuditEntry `json:"entries"`
+		Total      int          `json:"total"`
+		NextBefore uint64       `json:"next_before"`
+	}
+	rec := serveAuditTestRequest(t, router, http.MethodGet, "/v0/management/audit?limit=1", "")
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &page); errUnmarshal != nil {
+		t.Fatalf("failed to decode audit page: %v", errUnmarshal)
+	}
+	if page.Total != 2 || len(page.Entries) != 1 || page.NextBefore != 2 {
+		t.Fatalf("page = %+v, want the newest of 2 entries and next_before 2", page)
+	}
+	deletion := page.Entries[0]
+	if deletion.Method != http.MethodDelete || deletion.Route != "/v0/management/auth-files" || deletion.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected deletion entry: %+v", deletion)
+	}
+	if len(deletion.AuthsRemoved) != 1 || deletion.AuthsRemoved[0] != "codex-audit.json" || len(deletion.ConfigChanges) != 0 {
+		t.Fatalf("deletion entry changes = %+v", deletion)
+	}
+	if deletion.KeyFingerprint != auditFingerprint(auditTestManagementKey) || deletion.ClientIP == "" || deletion.Timestamp.IsZero() {
+		t.Fatalf("deletion entry identity = %+v", deletion)
+	}
+
+	rec = serveAuditTestRequest(t, router, http.MethodGet, "/v0/management/audit?before=2", "")
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &page); errUnmarshal != nil {
+		t.Fatalf("failed to decode audit page: %v", errUnmarshal)
+	}
+	if len(page.Entries) != 1 {
+		t.Fatalf("second page = %+v, want the config change", page)
+	}
+	change := page.Entries[0]
+	want := []AuditChange{{Path: "api-keys[0]", Old: auditFingerprint("old-client-key"), New: auditFingerprint("new-client-key")}}
+	if change.Route != "/v0/management/api-keys" || len(change.ConfigChanges) != 1 || change.ConfigChanges[0] != want[0] {
+		t.Fatalf("config change entry = %+v, want changes %+v", change, want)
+	}
+}
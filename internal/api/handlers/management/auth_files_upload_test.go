@@ -6,6 +6,8 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -67,3 +69,30 @@ func TestUploadAuthFile_PreservesPriorityAttributes(t *testing.T) {
 		t.Fatalf("priority metadata = %#v, want 98", got)
 	}
 }
+
+func TestUploadAuthFile_RejectsSecretReferences(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	gin.SetMode(gin.TestMode)
+
+	authDir := t.TempDir()
+	manager := coreauth.NewManager(nil, nil, nil)
+	h := NewHandlerWithoutConfigFilePath(&config.Config{AuthDir: authDir}, manager)
+
+	content := `{"type":"claude","email":"user@example.com","access_token":"env://MANAGEMENT_PASSWORD"}`
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/auth-files?name=claude-user.json", bytes.NewBufferString(content))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	h.UploadAuthFile(ctx)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected upload status %d, got %d with body %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(authDir, "claude-user.json")); !os.IsNotExist(err) {
+		t.Fatalf("stat uploaded file = %v, want it not written", err)
+	}
+	if _, ok := manager.GetByID("claude-user.json"); ok {
+		t.Fatal("expected no auth record for the rejected upload")
+	}
+}
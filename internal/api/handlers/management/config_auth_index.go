@@ -39,16 +39,17 @@ type openAICompatibilityAPIKeyWithAuthIndex struct {
 }
 
 type openAICompatibilityWithAuthIndex struct {
-	Name           string                                   `json:"name"`
-	Priority       int                                      `json:"priority,omitempty"`
-	Disabled       bool                                     `json:"disabled"`
-	Prefix         string                                   `json:"prefix,omitempty"`
-	BaseURL        string                                   `json:"base-url"`
-	APIKeyEntries  []openAICompatibilityAPIKeyWithAuthIndex `json:"api-key-entries,omitempty"`
-	Models         []config.OpenAICompatibilityModel        `json:"models,omitempty"`
-	Headers        map[string]string                        `json:"headers,omitempty"`
-	DisableCooling bool                                     `json:"disable-cooling,omitempty"`
-	AuthIndex      string                                   `json:"auth-index,omitempty"`
+	Name              string                                   `json:"name"`
+	Priority          int                                      `json:"priority,omitempty"`
+	Disabled          bool                                     `json:"disabled"`
+	Prefix            string                                   `json:"prefix,omitempty"`
+	BaseURL           string                                   `json:"base-url"`
+	APIKeyEntries     []openAICompatibilityAPIKeyWithAuthIndex `json:"api-key-entries,omitempty"`
+	Models            []config.OpenAICompatibilityModel        `json:"models,omitempty"`
+	Headers           map[string]string                        `json:"headers,omitempty"`
+	DisableCooling    bool                                     `json:"disable-cooling,omitempty"`
+	EndpointSelection string                                   `json:"endpoint-selection,omitempty"`
+	AuthIndex         string                                   `json:"auth-index,omitempty"`
 }
 
 func (h *Handler) liveAuthIndexByID() map[string]string {
@@ -278,17 +279,25 @@ func (h *Handler) openAICompatibilityWithAuthIndex() []openAICompatibilityWithAu
 		idKind := fmt.Sprintf("openai-compatibility:%s", providerName)
 
 		response := openAICompatibilityWithAuthIndex{
-			Name:           entry.Name,
-			Priority:       entry.Priority,
-			Disabled:       entry.Disabled,
-			Prefix:         entry.Prefix,
-			BaseURL:        entry.BaseURL,
-			Models:         entry.Models,
-			Headers:        entry.Headers,
-			DisableCooling: entry.DisableCooling,
-			AuthIndex:      "",
+			Name:              entry.Name,
+			Priority:          entry.Priority,
+			Disabled:          entry.Disabled,
+			Prefix:            entry.Prefix,
+			BaseURL:           entry.BaseURL,
+			Models:            entry.Models,
+			Headers:           entry.Headers,
+			DisableCooling:    entry.DisableCooling,
+			EndpointSelection: entry.EndpointSelection,
+			AuthIndex:         "",
 		}
-		if len(entry.APIKeyEntries) == 0 {
+		if entry.EndpointSelection != "" && len(entry.APIKeyEntries) > 0 {
+			id, _ := idGen.Next(idKind, "endpoint-pool", entry.BaseURL)
+			response.AuthIndex = liveIndexByID[id]
+			response.APIKeyEntries = make([]openAICompatibilityAPIKeyWithAuthIndex, len(entry.APIKeyEntries))
+			for j := range entry.APIKeyEntries {
+				response.APIKeyEntries[j] = openAICompatibilityAPIKeyWithAuthIndex{OpenAICompatibilityAPIKey: entry.APIKeyEntries[j]}
+			}
+		} else if len(entry.APIKeyEntries) == 0 {
 			id, _ := idGen.Next(idKind, entry.BaseURL)
 			response.AuthIndex = liveIndexByID[id]
 		} else {
@@ -476,6 +476,7 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 		Headers                 *map[string]string    `json:"headers"`
 		ExcludedModels          *[]string             `json:"excluded-models"`
 		RebuildMidSystemMessage *bool                 `json:"rebuild-mid-system-message"`
+		SystemAsString          *bool                 `json:"system-as-string"`
 	}
 	var body struct {
 		Index *int            `json:"index"`
@@ -532,6 +533,9 @@ func (h *Handler) PatchClaudeKey(c *gin.Context) {
 	if body.Value.RebuildMidSystemMessage != nil {
 		entry.RebuildMidSystemMessage = *body.Value.RebuildMidSystemMessage
 	}
+	if body.Value.SystemAsString != nil {
+		entry.SystemAsString = *body.Value.SystemAsString
+	}
 	normalizeClaudeKey(&entry)
 	h.cfg.ClaudeKey[targetIndex] = entry
 	h.cfg.SanitizeClaudeKeys()
@@ -614,6 +618,10 @@ func (h *Handler) PutOpenAICompat(c *gin.Context) {
 	}
 	filtered := make([]config.OpenAICompatibility, 0, len(arr))
 	for i := range arr {
+		if errSelection := config.ValidateOpenAICompatEndpointSelection(arr[i].EndpointSelection); errSelection != nil {
+			c.JSON(400, gin.H{"error": errSelection.Error()})
+			return
+		}
 		normalizeOpenAICompatibilityEntry(&arr[i])
 		if strings.TrimSpace(arr[i].BaseURL) != "" {
 			filtered = append(filtered, arr[i])
@@ -635,6 +643,8 @@ func (h *Handler) PatchOpenAICompat(c *gin.Context) {
 		APIKeyEntries  *[]config.OpenAICompatibilityAPIKey `json:"api-key-entries"`
 		Models         *[]config.OpenAICompatibilityModel  `json:"models"`
 		Headers        *map[string]string                  `json:"headers"`
+		// EndpointSelection pools the api-key-entries behind one credential; empty disables pooling.
+		EndpointSelection *string `json:"endpoint-selection"`
 	}
 	var body struct {
 		Name  *string            `json:"name"`
@@ -645,6 +655,12 @@ func (h *Handler) PatchOpenAICompat(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "invalid body"})
 		return
 	}
+	if body.Value.EndpointSelection != nil {
+		if errSelection := config.ValidateOpenAICompatEndpointSelection(*body.Value.EndpointSelection); errSelection != nil {
+			c.JSON(400, gin.H{"error": errSelection.Error()})
+			return
+		}
+	}
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -698,6 +714,9 @@ func (h *Handler) PatchOpenAICompat(c *gin.Context) {
 	if body.Value.Headers != nil {
 		entry.Headers = config.NormalizeHeaders(*body.Value.Headers)
 	}
+	if body.Value.EndpointSelection != nil {
+		entry.EndpointSelection = strings.TrimSpace(*body.Value.EndpointSelection)
+	}
 	normalizeOpenAICompatibilityEntry(&entry)
 	h.cfg.OpenAICompatibility[targetIndex] = entry
 	h.cfg.SanitizeOpenAICompatibility()
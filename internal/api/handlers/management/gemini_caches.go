@@ -0,0 +1,233 @@
+package management
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const geminiCachesDefaultBaseURL = "https://generativelanguage.googleapis.com"
+
+type geminiCacheCreateRequest struct {
+	AuthIndexSnake *string         `json:"auth_index"`
+	AuthIndexCamel *string         `json:"authIndex"`
+	CachedContent  json.RawMessage `json:"cached_content"`
+}
+
+// CreateGeminiCachedContent creates an explicit context cache on the Gemini API using the
+// selected Gemini API key credential and returns the cache resource name.
+//
+// Endpoint:
+//
+//	POST /v0/management/gemini/cached-contents
+//
+// Request JSON:
+//   - auth_index (required): a Gemini API key credential from GET /v0/management/auth-files.
+//   - cached_content (required): the CachedContent resource as accepted by the Gemini API, e.g.
+//     {"model":"gemini-2.5-flash","systemInstruction":{...},"contents":[...],"ttl":"3600s"}.
+//     A model without the "models/" prefix gets it added.
+//
+// Response JSON: {"name":"cachedContents/...","cached_content":{...upstream resource...}}.
+// Reference the name as cachedContent in Gemini requests, or as cached_content /
+// extra_body.google.cached_content in OpenAI chat requests. Caches belong to the API key
+// that created them, so requests using one must be routed to that credential.
+func (h *Handler) CreateGeminiCachedContent(c *gin.Context) {
+	var body geminiCacheCreateRequest
+	if errBindJSON := c.ShouldBindJSON(&body); errBindJSON != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	auth, apiKey, ok := h.geminiCacheAuth(c, firstNonEmptyString(body.AuthIndexSnake, body.AuthIndexCamel))
+	if !ok {
+		return
+	}
+	resource := bytes.TrimSpace(body.CachedContent)
+	if len(resource) == 0 || !gjson.ValidBytes(resource) || !gjson.ParseBytes(resource).IsObject() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing cached_content"})
+		return
+	}
+	model := strings.TrimSpace(gjson.GetBytes(resource, "model").String())
+	if model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing cached_content.model"})
+		return
+	}
+	if !strings.HasPrefix(model, "models/") {
+		resource, _ = sjson.SetBytes(resource, "model", "models/"+model)
+	}
+
+	status, respBody, errCall := h.callGeminiCaches(c, auth, apiKey, http.MethodPost, "cachedContents", nil, resource)
+	if errCall != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "request failed"})
+		return
+	}
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		writeGeminiCacheUpstreamError(c, status, respBody)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"name":           gjson.GetBytes(respBody, "name").String(),
+		"cached_content": json.RawMessage(respBody),
+	})
+}
+
+// ListGeminiCachedContents lists the context caches of the selected Gemini API key credential.
+//
+// Endpoint:
+//
+//	GET /v0/management/gemini/cached-contents?auth_index=<AUTH_INDEX>[&page_size=N][&page_token=T]
+//
+// Response JSON: {"names":[...],"cached_contents":[...],"next_page_token":"..."}.
+func (h *Handler) ListGeminiCachedContents(c *gin.Context) {
+	auth, apiKey, ok := h.geminiCacheAuth(c, c.Query("auth_index"))
+	if !ok {
+		return
+	}
+	query := url.Values{}
+	if pageSize := strings.TrimSpace(c.Query("page_size")); pageSize != "" {
+		query.Set("pageSize", pageSize)
+	}
+	if pageToken := strings.TrimSpace(c.Query("page_token")); pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	status, respBody, errCall := h.callGeminiCaches(c, auth, apiKey, http.MethodGet, "cachedContents", query, nil)
+	if errCall != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "request failed"})
+		return
+	}
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		writeGeminiCacheUpstreamError(c, status, respBody)
+		return
+	}
+	names := make([]string, 0)
+	caches := make([]json.RawMessage, 0)
+	gjson.GetBytes(respBody, "cachedContents").ForEach(func(_, cache gjson.Result) bool {
+		names = append(names, cache.Get("name").String())
+		caches = append(caches, json.RawMessage(cache.Raw))
+		return true
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"names":           names,
+		"cached_contents": caches,
+		"next_page_token": gjson.GetBytes(respBody, "nextPageToken").String(),
+	})
+}
+
+// DeleteGeminiCachedContent deletes a context cache of the selected Gemini API key credential.
+//
+// Endpoint:
+//
+//	DELETE /v0/management/gemini/cached-contents?auth_index=<AUTH_INDEX>&name=cachedContents/<ID>
+func (h *Handler) DeleteGeminiCachedContent(c *gin.Context) {
+	auth, apiKey, ok := h.geminiCacheAuth(c, c.Query("auth_index"))
+	if !ok {
+		return
+	}
+	name := strings.TrimSpace(c.Query("name"))
+	id := strings.TrimPrefix(name, "cachedContents/")
+	if id == "" || strings.ContainsAny(id, "/?#") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid name"})
+		return
+	}
+
+	status, respBody, errCall := h.callGeminiCaches(c, auth, apiKey, http.MethodDelete, "cachedContents/"+url.PathEscape(id), nil, nil)
+	if errCall != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "request failed"})
+		return
+	}
+	if status < http.StatusOK || status >= http.StatusMultipleChoices {
+		writeGeminiCacheUpstreamError(c, status, respBody)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "name": "cachedContents/" + id})
+}
+
+// geminiCacheAuth resolves the Gemini API key credential named by authIndex, writing the
+// error response and reporting false when there is none.
+func (h *Handler) geminiCacheAuth(c *gin.Context, authIndex string) (*coreauth.Auth, string, bool) {
+	if strings.TrimSpace(authIndex) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing auth_index"})
+		return nil, "", false
+	}
+	auth := h.authByIndex(authIndex)
+	if auth == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+		return nil, "", false
+	}
+	apiKey := ""
+	if auth.Attributes != nil {
+		apiKey = strings.TrimSpace(auth.Attributes["api_key"])
+	}
+	if !strings.EqualFold(strings.TrimSpace(auth.Provider), "gemini") || apiKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth is not a gemini api key credential"})
+		return nil, "", false
+	}
+	return auth, apiKey, true
+}
+
+// callGeminiCaches sends one request to the Gemini cachedContents API of auth.
+func (h *Handler) callGeminiCaches(c *gin.Context, auth *coreauth.Auth, apiKey, method, path string, query url.Values, body []byte) (int, []byte, error) {
+	baseURL := geminiCachesDefaultBaseURL
+	if custom := strings.TrimSpace(auth.Attributes["base_url"]); custom != "" {
+		baseURL = strings.TrimRight(custom, "/")
+	}
+	target := fmt.Sprintf("%s/v1beta/%s", baseURL, path)
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		requestBody = bytes.NewReader(body)
+	}
+	req, errNewRequest := http.NewRequestWithContext(c.Request.Context(), method, target, requestBody)
+	if errNewRequest != nil {
+		return 0, nil, errNewRequest
+	}
+	req.Header.Set("x-goog-api-key", apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := &http.Client{
+		Timeout:   defaultAPICallTimeout,
+		Transport: h.apiCallTransport(auth),
+	}
+	resp, errDo := httpClient.Do(req)
+	if errDo != nil {
+		log.WithError(errDo).Debug("management gemini cachedContents request failed")
+		return 0, nil, errDo
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("response body close error: %v", errClose)
+		}
+	}()
+	respBody, errReadAll := io.ReadAll(resp.Body)
+	if errReadAll != nil {
+		return 0, nil, errReadAll
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// writeGeminiCacheUpstreamError relays an upstream error status with its message.
+func writeGeminiCacheUpstreamError(c *gin.Context, status int, body []byte) {
+	message := strings.TrimSpace(gjson.GetBytes(body, "error.message").String())
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	c.JSON(status, gin.H{"error": message})
+}
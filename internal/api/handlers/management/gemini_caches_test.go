@@ -0,0 +1,151 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+type geminiCacheUpstreamCall struct {
+	method string
+	path   string
+	query  string
+	apiKey string
+	body   []byte
+}
+
+func newGeminiCacheTestHandler(t *testing.T, upstream http.HandlerFunc) (*Handler, string, string, chan geminiCacheUpstreamCall) {
+	t.Helper()
+	calls := make(chan geminiCacheUpstreamCall, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls <- geminiCacheUpstreamCall{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, apiKey: r.Header.Get("x-goog-api-key"), body: body}
+		upstream(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	geminiAuth := &coreauth.Auth{
+		ID:         "gemini:apikey:cache",
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "cache-key", "base_url": server.URL},
+	}
+	codexAuth := &coreauth.Auth{
+		ID:         "codex:apikey:cache",
+		Provider:   "codex",
+		Attributes: map[string]string{"api_key": "codex-key"},
+	}
+	for _, auth := range []*coreauth.Auth{geminiAuth, codexAuth} {
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+	}
+	return &Handler{authManager: manager}, geminiAuth.EnsureIndex(), codexAuth.EnsureIndex(), calls
+}
+
+func serveGeminiCacheRequest(handler gin.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return recorder
+}
+
+func TestCreateGeminiCachedContent(t *testing.T) {
+	h, geminiIndex, codexIndex, calls := newGeminiCacheTestHandler(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"cachedContents/abc123","model":"models/gemini-2.5-flash","expireTime":"2026-10-16T15:00:00Z","usageMetadata":{"totalTokenCount":40000}}`))
+	})
+
+	body := `{"auth_index":"` + geminiIndex + `","cached_content":{"model":"gemini-2.5-flash","systemInstruction":{"parts":[{"text":"static prompt"}]},"ttl":"3600s"}}`
+	recorder := serveGeminiCacheRequest(h.CreateGeminiCachedContent, http.MethodPost, "/v0/management/gemini/cached-contents", body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	call := <-calls
+	if call.method != http.MethodPost || call.path != "/v1beta/cachedContents" || call.apiKey != "cache-key" {
+		t.Fatalf("upstream call = %s %s key=%q", call.method, call.path, call.apiKey)
+	}
+	if got := gjson.GetBytes(call.body, "model").String(); got != "models/gemini-2.5-flash" {
+		t.Fatalf("upstream model = %q, want models/gemini-2.5-flash", got)
+	}
+	if got := gjson.GetBytes(call.body, "systemInstruction.parts.0.text").String(); got != "static prompt" {
+		t.Fatalf("upstream systemInstruction = %q; body=%s", got, call.body)
+	}
+	if got := gjson.Get(recorder.Body.String(), "name").String(); got != "cachedContents/abc123" {
+		t.Fatalf("name = %q, want cachedContents/abc123", got)
+	}
+	if got := gjson.Get(recorder.Body.String(), "cached_content.usageMetadata.totalTokenCount").Int(); got != 40000 {
+		t.Fatalf("cached_content not relayed: %s", recorder.Body.String())
+	}
+
+	body = `{"auth_index":"` + codexIndex + `","cached_content":{"model":"gemini-2.5-flash"}}`
+	if recorder = serveGeminiCacheRequest(h.CreateGeminiCachedContent, http.MethodPost, "/v0/management/gemini/cached-contents", body); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status for non-gemini auth = %d, want 400", recorder.Code)
+	}
+}
+
+func TestListGeminiCachedContents(t *testing.T) {
+	h, geminiIndex, _, calls := newGeminiCacheTestHandler(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"cachedContents":[{"name":"cachedContents/a"},{"name":"cachedContents/b"}],"nextPageToken":"next"}`))
+	})
+
+	recorder := serveGeminiCacheRequest(h.ListGeminiCachedContents, http.MethodGet, "/v0/management/gemini/cached-contents?auth_index="+geminiIndex+"&page_size=2&page_token=tok", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	call := <-calls
+	if call.method != http.MethodGet || call.path != "/v1beta/cachedContents" || call.query != "pageSize=2&pageToken=tok" {
+		t.Fatalf("upstream call = %s %s?%s", call.method, call.path, call.query)
+	}
+	var response struct {
+		Names         []string          `json:"names"`
+		CachedContent []json.RawMessage `json:"cached_contents"`
+		NextPageToken string            `json:"next_page_token"`
+	}
+	if errUnmarshal := json.Unmarshal(recorder.Body.Bytes(), &response); errUnmarshal != nil {
+		t.Fatalf("decode response: %v", errUnmarshal)
+	}
+	if len(response.Names) != 2 || response.Names[0] != "cachedContents/a" || response.Names[1] != "cachedContents/b" || len(response.CachedContent) != 2 || response.NextPageToken != "next" {
+		t.Fatalf("response = %+v", response)
+	}
+}
+
+func TestDeleteGeminiCachedContent(t *testing.T) {
+	h, geminiIndex, _, calls := newGeminiCacheTestHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"CachedContent not found","status":"NOT_FOUND"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	})
+
+	recorder := serveGeminiCacheRequest(h.DeleteGeminiCachedContent, http.MethodDelete, "/v0/management/gemini/cached-contents?auth_index="+geminiIndex+"&name=cachedContents/abc123", "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+	if call := <-calls; call.method != http.MethodDelete || call.path != "/v1beta/cachedContents/abc123" {
+		t.Fatalf("upstream call = %s %s", call.method, call.path)
+	}
+
+	recorder = serveGeminiCacheRequest(h.DeleteGeminiCachedContent, http.MethodDelete, "/v0/management/gemini/cached-contents?auth_index="+geminiIndex+"&name=missing", "")
+	<-calls
+	if recorder.Code != http.StatusNotFound || gjson.Get(recorder.Body.String(), "error").String() != "CachedContent not found" {
+		t.Fatalf("missing cache: status = %d, body=%s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = serveGeminiCacheRequest(h.DeleteGeminiCachedContent, http.MethodDelete, "/v0/management/gemini/cached-contents?auth_index="+geminiIndex+"&name=cachedContents/a/b", "")
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status for invalid name = %d, want 400", recorder.Code)
+	}
+}
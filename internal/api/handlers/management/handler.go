@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/api/middleware"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/buildinfo"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/pluginhost"
@@ -60,6 +61,8 @@ type Handler struct {
 	pluginStoreHTTPClient   pluginstore.HTTPDoer
 	pluginReleaseCacheMu    sync.Mutex
 	pluginReleaseCache      map[string]pluginReleaseCacheEntry
+	auditMu                 sync.Mutex
+	activeStreams           func() int
 }
 
 type configReloadSnapshot struct {
@@ -160,6 +163,16 @@ func (h *Handler) SetConfigReloadHook(hook func(context.Context, *config.Config)
 	h.mu.Unlock()
 }
 
+// SetActiveStreamsFunc sets the callback reporting how many streaming responses are in flight.
+func (h *Handler) SetActiveStreamsFunc(fn func() int) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	h.activeStreams = fn
+	h.mu.Unlock()
+}
+
 // reloadSnapshotConfigLocked clones the runtime config and assigns a reload generation.
 // Callers must hold h.mu.
 func (h *Handler) reloadSnapshotConfigLocked() configReloadSnapshot {
@@ -270,7 +283,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 		c.Header("X-CPA-SUPPORT-PLUGIN", pluginhost.SupportPluginHeaderValue())
 
 		clientIP := c.ClientIP()
-		localClient := clientIP == "127.0.0.1" || clientIP == "::1"
+		localClient := middleware.IsLocalClient(c)
 
 		// Accept either Authorization: Bearer <key> or X-Management-Key
 		var provided string
@@ -291,6 +304,7 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 			c.AbortWithStatusJSON(statusCode, gin.H{"error": errMsg})
 			return
 		}
+		c.Set(auditKeyFingerprintContextKey, auditFingerprint(provided))
 		c.Next()
 	}
 }
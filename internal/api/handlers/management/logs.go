@@ -257,7 +257,8 @@ func (h *Handler) GetRequestErrorLogs(c *gin.Context) {
 }
 
 // GetRequestLogByID finds and downloads a request log file by its request ID.
-// The ID is matched against the suffix of log file names (format: *-{requestID}.log).
+// The ID is matched against the suffix of log file names (format: *-{requestID}.log), in
+// the file-name form of logging.RequestIDFilenamePart.
 func (h *Handler) GetRequestLogByID(c *gin.Context) {
 	if h == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "handler unavailable"})
@@ -297,7 +298,7 @@ func (h *Handler) GetRequestLogByID(c *gin.Context) {
 		return
 	}
 
-	suffix := "-" + requestID + ".log"
+	suffix := "-" + logging.RequestIDFilenamePart(requestID) + ".log"
 	var matchedFile string
 	for _, entry := range entries {
 		if entry.IsDir() {
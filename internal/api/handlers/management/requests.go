@@ -0,0 +1,89 @@
+package management
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/requeststore"
+)
+
+// GetRequests lists rows from the persistent request store, newest first.
+//
+// Query parameters:
+//   - model: upstream model or client alias
+//   - provider: provider identifier
+//   - status: HTTP status code
+//   - since: RFC3339 timestamp, Unix seconds, or a duration such as "1h" meaning that long ago
+//   - before: cursor returned as next_before by the previous page
+//   - limit: page size (default 50, max 500)
+func (h *Handler) GetRequests(c *gin.Context) {
+	store := requeststore.Default()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "request store is disabled"})
+		return
+	}
+
+	filter := requeststore.Filter{
+		Model:    strings.TrimSpace(c.Query("model")),
+		Provider: strings.TrimSpace(c.Query("provider")),
+	}
+	if raw := strings.TrimSpace(c.Query("status")); raw != "" {
+		status, errParse := strconv.Atoi(raw)
+		if errParse != nil || status <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		filter.Status = status
+	}
+	if raw := strings.TrimSpace(c.Query("since")); raw != "" {
+		since, ok := parseRequestsSince(raw, time.Now())
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since"})
+			return
+		}
+		filter.Since = since
+	}
+	if raw := strings.TrimSpace(c.Query("before")); raw != "" {
+		before, errParse := strconv.ParseUint(raw, 10, 64)
+		if errParse != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before"})
+			return
+		}
+		filter.Before = before
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, errParse := strconv.Atoi(raw)
+		if errParse != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, next, errQuery := store.Query(filter)
+	if errQuery != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errQuery.Error()})
+		return
+	}
+	response := gin.H{"requests": entries, "stats": store.Stats()}
+	if next > 0 {
+		response["next_before"] = next
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func parseRequestsSince(raw string, now time.Time) (time.Time, bool) {
+	if ts, errParse := time.Parse(time.RFC3339, raw); errParse == nil {
+		return ts, true
+	}
+	if seconds, errParse := strconv.ParseInt(raw, 10, 64); errParse == nil && seconds >= 0 {
+		return time.Unix(seconds, 0), true
+	}
+	if ago, errParse := time.ParseDuration(raw); errParse == nil && ago > 0 {
+		return now.Add(-ago), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,131 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/requeststore"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+type requestsResponse struct {
+	Requests   []requeststore.Entry `json:"requests"`
+	NextBefore uint64               `json:"next_before"`
+	Stats      requeststore.Stats   `json:"stats"`
+}
+
+func performGetRequests(t *testing.T, h *Handler, query string) (int, requestsResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v0/management/requests?"+query, nil)
+	h.GetRequests(ctx)
+	var body requestsResponse
+	if rec.Code == http.StatusOK {
+		if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &body); errUnmarshal != nil {
+			t.Fatalf("decode response: %v; body = %s", errUnmarshal, rec.Body.String())
+		}
+	}
+	return rec.Code, body
+}
+
+func TestGetRequestsDisabled(t *testing.T) {
+	if errConfigure := requeststore.Configure(&config.Config{}); errConfigure != nil {
+		t.Fatalf("Configure() error = %v", errConfigure)
+	}
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+	if code, _ := performGetRequests(t, h, ""); code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", code)
+	}
+}
+
+func TestGetRequestsListsExecutedRequests(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(r.URL.Path, "gemini-2.5-pro") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"code":429,"message":"quota exceeded","status":"RESOURCE_EXHAUSTED"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{}
+	cfg.RequestStore = config.RequestStoreConfig{Enable: true, Path: filepath.Join(t.TempDir(), "requests.db")}
+	if errConfigure := requeststore.Configure(cfg); errConfigure != nil {
+		t.Fatalf("Configure() error = %v", errConfigure)
+	}
+	t.Cleanup(func() { _ = requeststore.CloseDefault() })
+
+	exec := executor.NewGeminiExecutor(cfg)
+	auth := &cliproxyauth.Auth{
+		ID:         "gemini-key-1",
+		Provider:   "gemini",
+		Label:      "primary key",
+		Attributes: map[string]string{"api_key": "test-key", "base_url": upstream.URL},
+	}
+	run := func(model string) {
+		ctx := requeststore.WithCapture(context.Background())
+		payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+		_, _ = exec.Execute(ctx, auth, cliproxyexecutor.Request{Model: model, Payload: payload}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini})
+	}
+	run("gemini-2.5-flash")
+	run("gemini-2.5-flash")
+	run("gemini-2.5-pro")
+
+	store := requeststore.Default()
+	deadline := time.Now().Add(2 * time.Second)
+	for store.Stats().Rows < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		store.Flush()
+	}
+
+	h := NewHandlerWithoutConfigFilePath(cfg, nil)
+	code, body := performGetRequests(t, h, "model=gemini-2.5-flash&limit=1")
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if len(body.Requests) != 1 || body.NextBefore == 0 {
+		t.Fatalf("first page = %+v", body)
+	}
+	row := body.Requests[0]
+	if row.Provider != "gemini" || row.StatusCode != http.StatusOK || row.TotalTokens != 5 {
+		t.Fatalf("row = %+v", row)
+	}
+	if !strings.Contains(row.RequestBody, `"hi"`) || !strings.Contains(row.ResponseBody, `"ok"`) {
+		t.Fatalf("row bodies not captured: %+v", row)
+	}
+	if body.Stats.Rows != 3 {
+		t.Fatalf("stats = %+v, want 3 rows", body.Stats)
+	}
+
+	_, next := performGetRequests(t, h, "model=gemini-2.5-flash&limit=1&before="+strconv.FormatUint(body.NextBefore, 10))
+	if len(next.Requests) != 1 || next.Requests[0].ID >= row.ID || next.NextBefore != 0 {
+		t.Fatalf("second page = %+v", next)
+	}
+
+	_, failed := performGetRequests(t, h, "status=429&since=1h")
+	if len(failed.Requests) != 1 {
+		t.Fatalf("status filter = %+v", failed)
+	}
+	if got := failed.Requests[0]; got.Model != "gemini-2.5-pro" || !got.Failed || !strings.Contains(got.ResponseBody, "quota exceeded") {
+		t.Fatalf("failed row = %+v", got)
+	}
+
+	if code, _ := performGetRequests(t, h, "status=abc"); code != http.StatusBadRequest {
+		t.Fatalf("invalid status code = %d, want 400", code)
+	}
+}
@@ -0,0 +1,103 @@
+package management
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/usagestats"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+)
+
+// statusAuth summarizes one enabled credential for the status page.
+type statusAuth struct {
+	ID          string          `json:"id"`
+	AuthIndex   string          `json:"auth_index"`
+	Provider    string          `json:"provider"`
+	Label       string          `json:"label,omitempty"`
+	Status      coreauth.Status `json:"status"`
+	Unavailable bool            `json:"unavailable"`
+	Models      int             `json:"models"`
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty"`
+	// ExpiresIn is the number of seconds until ExpiresAt, negative once expired.
+	ExpiresIn      *int64     `json:"expires_in_seconds,omitempty"`
+	NextRetryAfter *time.Time `json:"next_retry_after,omitempty"`
+}
+
+// statusResponse is the body of GET /v0/management/status.
+type statusResponse struct {
+	GeneratedAt   time.Time               `json:"generated_at"`
+	ActiveStreams int                     `json:"active_streams"`
+	Auths         []statusAuth            `json:"auths"`
+	UsageSince    time.Time               `json:"usage_since"`
+	Models        []usagestats.ModelStats `json:"models"`
+	RecentErrors  []usagestats.ErrorEntry `json:"recent_errors"`
+}
+
+// GetStatus returns an overview of the running proxy: the enabled credentials with their
+// expiry, request counts per model and the most recent errors since startup, and the
+// number of streaming responses in flight.
+func (h *Handler) GetStatus(c *gin.Context) {
+	if h == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "handler not initialized"})
+		return
+	}
+
+	h.mu.Lock()
+	manager := h.authManager
+	activeStreams := h.activeStreams
+	h.mu.Unlock()
+
+	now := time.Now()
+	out := statusResponse{GeneratedAt: now, Auths: []statusAuth{}}
+	if activeStreams != nil {
+		out.ActiveStreams = activeStreams()
+	}
+	if manager != nil {
+		out.Auths = statusAuths(manager.List(), now)
+	}
+	snapshot := usagestats.Default().Snapshot()
+	out.UsageSince = snapshot.Since
+	out.Models = snapshot.Models
+	out.RecentErrors = snapshot.RecentErrors
+	c.JSON(http.StatusOK, out)
+}
+
+// statusAuths lists the enabled auths sorted by provider and ID.
+func statusAuths(auths []*coreauth.Auth, now time.Time) []statusAuth {
+	out := make([]statusAuth, 0, len(auths))
+	reg := registry.GetGlobalRegistry()
+	for _, auth := range auths {
+		if auth == nil || auth.Disabled {
+			continue
+		}
+		entry := statusAuth{
+			ID:          auth.ID,
+			AuthIndex:   auth.EnsureIndex(),
+			Provider:    strings.TrimSpace(auth.Provider),
+			Label:       strings.TrimSpace(auth.Label),
+			Status:      auth.Status,
+			Unavailable: auth.Unavailable,
+			Models:      len(reg.GetModelsForClient(auth.ID)),
+		}
+		if !auth.NextRetryAfter.IsZero() {
+			nextRetryAfter := auth.NextRetryAfter
+			entry.NextRetryAfter = &nextRetryAfter
+		}
+		if expiresAt, ok := auth.ExpirationTime(); ok && !expiresAt.IsZero() {
+			expiresIn := int64(expiresAt.Sub(now) / time.Second)
+			entry.ExpiresAt, entry.ExpiresIn = &expiresAt, &expiresIn
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
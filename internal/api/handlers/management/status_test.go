@@ -0,0 +1,131 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/usagestats"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+)
+
+func TestGetStatus_AggregatesAuthsUsageAndStreams(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+	usagestats.Default().Reset()
+	t.Cleanup(usagestats.Default().Reset)
+
+	expiresAt := time.Now().Add(90 * time.Minute).UTC().Truncate(time.Second)
+	manager := coreauth.NewManager(nil, nil, nil)
+	for _, auth := range []*coreauth.Auth{
+		{ID: "status-gemini", Provider: "gemini", Status: coreauth.StatusActive, Metadata: map[string]any{"expired": expiresAt.Format(time.RFC3339)}},
+		{ID: "status-claude", Provider: "claude", Status: coreauth.StatusActive, Attributes: map[string]string{"api_key": "k"}},
+		{ID: "status-disabled", Provider: "codex", Status: coreauth.StatusDisabled, Disabled: true},
+	} {
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("register %s: %v", auth.ID, errRegister)
+		}
+	}
+	registry.GetGlobalRegistry().RegisterClient("status-gemini", "gemini", []*registry.ModelInfo{{ID: "status-model-a"}, {ID: "status-model-b"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient("status-gemini") })
+
+	usagestats.Default().Record("gemini-2.5-pro", 12, nil)
+	usagestats.Default().Record("gemini-2.5-pro", 0, &usagestats.ErrorEntry{Provider: "gemini", AuthID: "status-gemini", StatusCode: 429, Message: "quota"})
+
+	h := NewHandlerWithoutConfigFilePath(&config.Config{AuthDir: t.TempDir()}, manager)
+	h.SetActiveStreamsFunc(func() int { return 3 })
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v0/management/status", nil)
+	h.GetStatus(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var payload statusResponse
+	if errDecode := json.Unmarshal(rec.Body.Bytes(), &payload); errDecode != nil {
+		t.Fatalf("decode payload: %v", errDecode)
+	}
+
+	if payload.ActiveStreams != 3 {
+		t.Fatalf("active_streams = %d, want 3", payload.ActiveStreams)
+	}
+	if len(payload.Auths) != 2 || payload.Auths[0].ID != "status-claude" || payload.Auths[1].ID != "status-gemini" {
+		t.Fatalf("auths = %+v, want the enabled claude and gemini auths sorted by provider", payload.Auths)
+	}
+	if claude := payload.Auths[0]; claude.ExpiresAt != nil || claude.ExpiresIn != nil {
+		t.Fatalf("api key auth has an expiry: %+v", claude)
+	}
+	gemini := payload.Auths[1]
+	if gemini.ExpiresAt == nil || !gemini.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("gemini expires_at = %v, want %v", gemini.ExpiresAt, expiresAt)
+	}
+	if gemini.ExpiresIn == nil || *gemini.ExpiresIn <= 80*60 || *gemini.ExpiresIn > 90*60 {
+		t.Fatalf("gemini expires_in_seconds = %v, want about 90 minutes", gemini.ExpiresIn)
+	}
+	if gemini.Models != 2 || gemini.AuthIndex == "" {
+		t.Fatalf("gemini auth = %+v, want 2 registered models and an auth index", gemini)
+	}
+	if len(payload.Models) != 1 || payload.Models[0] != (usagestats.ModelStats{Model: "gemini-2.5-pro", Requests: 2, Failed: 1, TotalTokens: 12}) {
+		t.Fatalf("models = %+v, want the gemini-2.5-pro counters", payload.Models)
+	}
+	if len(payload.RecentErrors) != 1 || payload.RecentErrors[0].StatusCode != 429 || payload.RecentErrors[0].Message != "quota" {
+		t.Fatalf("recent_errors = %+v, want the 429", payload.RecentErrors)
+	}
+}
+
+func TestStatusUIMiddleware(t *testing.T) {
+	newEngine := func(enabled bool) *gin.Engine {
+		cfg := &config.Config{}
+		cfg.RemoteManagement.EnableStatusUI = enabled
+		h := &Handler{cfg: cfg, failedAttempts: make(map[string]*attemptInfo), envSecret: "test-secret"}
+		engine := gin.New()
+		ui := engine.Group("/v0/ui", h.StatusUIMiddleware())
+		ui.GET("/", h.ServeStatusUI)
+		return engine
+	}
+	serve := func(engine *gin.Engine, setup func(*http.Request)) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v0/ui/", nil)
+		req.RemoteAddr = "127.0.0.1:12345"
+		if setup != nil {
+			setup(req)
+		}
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(newEngine(false), func(r *http.Request) { r.SetBasicAuth("admin", "test-secret") }); rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	engine := newEngine(true)
+	rec := serve(engine, nil)
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+		t.Fatalf("anonymous status = %d, WWW-Authenticate = %q, want a basic auth challenge", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if rec = serve(engine, func(r *http.Request) { r.SetBasicAuth("admin", "wrong-secret") }); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong key status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	for name, setup := range map[string]func(*http.Request){
+		"basic auth":       func(r *http.Request) { r.SetBasicAuth("admin", "test-secret") },
+		"management key":   func(r *http.Request) { r.Header.Set("X-Management-Key", "test-secret") },
+		"bearer authorize": func(r *http.Request) { r.Header.Set("Authorization", "Bearer test-secret") },
+	} {
+		rec := serve(engine, setup)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d body=%s", name, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), `fetch("status.json"`) {
+			t.Fatalf("%s: body is not the status page", name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package management
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/api/middleware"
+)
+
+// statusUIPage is the single page served under /v0/ui/. It polls status.json, the
+// GetStatus endpoint mounted next to it.
+//
+//go:embed status_ui.html
+var statusUIPage []byte
+
+// statusUIRealm is announced to browsers so they prompt for the management key.
+const statusUIRealm = `Basic realm="CLIProxyAPI management", charset="UTF-8"`
+
+// StatusUIMiddleware guards the embedded status page. It answers 404 unless
+// remote-management.enable-status-ui is set, and otherwise requires the management key
+// like Middleware. Browsers cannot attach headers when navigating, so the key may also be
+// given as the password of HTTP basic authentication, which they prompt for.
+func (h *Handler) StatusUIMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h == nil || h.cfg == nil || !h.cfg.RemoteManagement.EnableStatusUI {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		clientIP := c.ClientIP()
+		localClient := middleware.IsLocalClient(c)
+
+		provided := ""
+		if _, password, ok := c.Request.BasicAuth(); ok {
+			provided = password
+		} else if ah := c.GetHeader("Authorization"); ah != "" {
+			parts := strings.SplitN(ah, " ", 2)
+			if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+				provided = parts[1]
+			} else {
+				provided = ah
+			}
+		}
+		if provided == "" {
+			provided = c.GetHeader("X-Management-Key")
+		}
+		if provided == "" {
+			// The first navigation never carries credentials; challenge without counting
+			// it as a failed attempt.
+			c.Header("WWW-Authenticate", statusUIRealm)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing management key"})
+			return
+		}
+
+		allowed, statusCode, errMsg := h.AuthenticateManagementKey(clientIP, localClient, provided)
+		if !allowed {
+			if statusCode == http.StatusUnauthorized {
+				c.Header("WWW-Authenticate", statusUIRealm)
+			}
+			c.AbortWithStatusJSON(statusCode, gin.H{"error": errMsg})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ServeStatusUI serves the embedded status page.
+func (h *Handler) ServeStatusUI(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", statusUIPage)
+}
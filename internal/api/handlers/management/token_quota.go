@@ -0,0 +1,62 @@
+package management
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tokenquota"
+)
+
+// GetTokenQuota returns the token consumption of every counted credential in the current
+// day and month together with its configured budgets.
+func (h *Handler) GetTokenQuota(c *gin.Context) {
+	tracker := tokenquota.Default()
+	if tracker == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "token quota is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"auths": tracker.Snapshot(time.Now())})
+}
+
+// ResetTokenQuota clears the token counters of one credential, identified by auth_index or
+// auth_id, so it becomes selectable again before its budget period ends.
+func (h *Handler) ResetTokenQuota(c *gin.Context) {
+	tracker := tokenquota.Default()
+	if tracker == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "token quota is disabled"})
+		return
+	}
+
+	var req struct {
+		AuthIndex string `json:"auth_index"`
+		AuthID    string `json:"auth_id"`
+	}
+	if errBindJSON := c.ShouldBindJSON(&req); errBindJSON != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	authID := strings.TrimSpace(req.AuthID)
+	if authIndex := strings.TrimSpace(req.AuthIndex); authIndex != "" {
+		auth := h.authByIndex(authIndex)
+		if auth == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "auth not found"})
+			return
+		}
+		authID = auth.ID
+	}
+	if authID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth_index or auth_id is required"})
+		return
+	}
+
+	reset, errReset := tracker.Reset(authID)
+	if errReset != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to reset token quota: %v", errReset)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "auth_id": authID, "reset": reset})
+}
@@ -0,0 +1,64 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tokenquota"
+)
+
+func TestTokenQuotaEndpoints(t *testing.T) {
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v0/management/token-quota", nil)
+	h.GetTokenQuota(ctx)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("disabled status = %d, want 503", rec.Code)
+	}
+
+	cfg := &config.Config{}
+	cfg.TokenQuota = config.TokenQuotaConfig{
+		Path:  filepath.Join(t.TempDir(), "token-quota.json"),
+		Auths: []config.TokenQuotaRule{{Auth: "auth-1", TokenQuotaLimits: config.TokenQuotaLimits{DailyTokenLimit: 100}}},
+	}
+	if errConfigure := tokenquota.Configure(cfg); errConfigure != nil {
+		t.Fatalf("Configure() error = %v", errConfigure)
+	}
+	t.Cleanup(func() { _ = tokenquota.CloseDefault() })
+	tokenquota.Default().Add("auth-1", "", []string{"gpt-5"}, 120, time.Now())
+
+	rec = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v0/management/token-quota", nil)
+	h.GetTokenQuota(ctx)
+	var body struct {
+		Auths []tokenquota.Usage `json:"auths"`
+	}
+	if errUnmarshal := json.Unmarshal(rec.Body.Bytes(), &body); errUnmarshal != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(body.Auths) != 1 || body.Auths[0].DayTokens != 120 || body.Auths[0].Exhausted == nil || body.Auths[0].Models["gpt-5"].DayTokens != 120 {
+		t.Fatalf("GET auths = %+v, want auth-1 with 120 tokens and exhausted", body.Auths)
+	}
+
+	rec = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/token-quota/reset", strings.NewReader(`{"auth_id":"auth-1"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	h.ResetTokenQuota(ctx)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reset":true`) {
+		t.Fatalf("reset status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if _, exhausted := tokenquota.Default().Check("auth-1", "", "", time.Now()); exhausted {
+		t.Fatal("auth still exhausted after reset")
+	}
+}
@@ -0,0 +1,124 @@
+package management
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// translateResponseEnvelope is the body accepted by TranslateDryRun for direction=response.
+// Request holds the original client request and Response the captured upstream body,
+// either as JSON or, for SSE captures, as a string.
+type translateResponseEnvelope struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// TranslateDryRun runs the executor translation pipeline without contacting the upstream.
+//
+// Query parameters:
+//   - from: source format of the client request (required)
+//   - to: target provider (required, currently "antigravity")
+//   - model: model name, including any thinking suffix (required)
+//   - direction: "request" (default) or "response"
+//   - stream: "true" to exercise the streaming path
+//   - project: optional project ID placed in the upstream envelope
+//   - path: optional client request path used when matching payload rules
+//
+// For direction=request the body is the client request and the final upstream payload is
+// returned. For direction=response the body is {"request": ..., "response": ...} and the
+// converted client output is returned.
+func (h *Handler) TranslateDryRun(c *gin.Context) {
+	from := strings.TrimSpace(c.Query("from"))
+	to := strings.ToLower(strings.TrimSpace(c.Query("to")))
+	model := strings.TrimSpace(c.Query("model"))
+	if from == "" || to == "" || model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from, to and model are required"})
+		return
+	}
+	if to != "antigravity" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported target", "to": to, "supported": []string{"antigravity"}})
+		return
+	}
+	direction := strings.ToLower(strings.TrimSpace(c.DefaultQuery("direction", "request")))
+	if direction != "request" && direction != "response" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be request or response"})
+		return
+	}
+	stream := strings.EqualFold(strings.TrimSpace(c.Query("stream")), "true")
+
+	body, errRead := io.ReadAll(c.Request.Body)
+	if errRead != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+
+	opts := cliproxyexecutor.Options{
+		Stream:       stream,
+		SourceFormat: sdktranslator.FromString(from),
+		Metadata:     map[string]any{cliproxyexecutor.RequestedModelMetadataKey: model},
+	}
+	if requestPath := strings.TrimSpace(c.Query("path")); requestPath != "" {
+		opts.Metadata[cliproxyexecutor.RequestPathMetadataKey] = requestPath
+	}
+	exec := executor.NewAntigravityExecutor(h.cfg)
+
+	if direction == "request" {
+		if !gjson.ValidBytes(body) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "body must be a JSON request"})
+			return
+		}
+		var auth *cliproxyauth.Auth
+		if project := strings.TrimSpace(c.Query("project")); project != "" {
+			auth = &cliproxyauth.Auth{Metadata: map[string]any{"project_id": project}}
+		}
+		payload, errDryRun := exec.DryRunRequest(c.Request.Context(), auth, cliproxyexecutor.Request{Model: model, Payload: body}, opts)
+		if errDryRun != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errDryRun.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "model": model, "stream": stream, "payload": json.RawMessage(payload)})
+		return
+	}
+
+	var envelope translateResponseEnvelope
+	if errUnmarshal := json.Unmarshal(body, &envelope); errUnmarshal != nil || len(envelope.Response) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must be {\"request\": ..., \"response\": ...}"})
+		return
+	}
+	upstream := []byte(envelope.Response)
+	if raw := gjson.ParseBytes(upstream); raw.Type == gjson.String {
+		upstream = []byte(raw.String())
+	}
+	var original []byte
+	if len(envelope.Request) > 0 && string(envelope.Request) != "null" {
+		original = envelope.Request
+	}
+	opts.OriginalRequest = original
+	chunks, errDryRun := exec.DryRunResponse(c.Request.Context(), cliproxyexecutor.Request{Model: model, Payload: original}, opts, upstream)
+	if errDryRun != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errDryRun.Error()})
+		return
+	}
+	if !stream {
+		var output any = string(chunks[0])
+		if gjson.ValidBytes(chunks[0]) {
+			output = json.RawMessage(chunks[0])
+		}
+		c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "model": model, "stream": false, "output": output})
+		return
+	}
+	output := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		output = append(output, string(chunk))
+	}
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "model": model, "stream": true, "chunks": output})
+}
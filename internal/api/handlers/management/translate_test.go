@@ -0,0 +1,83 @@
+package management
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	"github.com/tidwall/gjson"
+)
+
+func performTranslateDryRun(t *testing.T, h *Handler, query, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v0/management/translate?"+query, strings.NewReader(body))
+	h.TranslateDryRun(ctx)
+	return rec
+}
+
+func TestTranslateDryRun_RequestReturnsUpstreamPayload(t *testing.T) {
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+	body := `{"model":"m","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{"name":"f","parameters":{"$schema":"x","type":"object","properties":{}}}}]}`
+
+	rec := performTranslateDryRun(t, h, "from=openai&to=antigravity&model=claude-sonnet-4-5&project=p-1", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	payload := gjson.GetBytes(rec.Body.Bytes(), "payload")
+	if got := payload.Get("project").String(); got != "p-1" {
+		t.Fatalf("project = %q, want p-1", got)
+	}
+	if got := payload.Get("request.contents.0.parts.0.text").String(); got != "hi" {
+		t.Fatalf("contents not translated: %s", payload.Raw)
+	}
+	if payload.Get("request.tools.0.functionDeclarations.0.parameters.$schema").Exists() {
+		t.Fatalf("tool schema was not cleaned: %s", payload.Raw)
+	}
+	if got := payload.Get("request.toolConfig.functionCallingConfig.mode").String(); got != "VALIDATED" {
+		t.Fatalf("functionCallingConfig.mode = %q, want VALIDATED", got)
+	}
+}
+
+func TestTranslateDryRun_ResponseConvertsCapturedStream(t *testing.T) {
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+	body := `{"request":{"model":"m","messages":[{"role":"user","content":"hi"}]},"response":"data: {\"response\":{\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"hello\"}]},\"finishReason\":\"STOP\"}]}}\n\n"}`
+
+	rec := performTranslateDryRun(t, h, "from=openai&to=antigravity&model=gemini-2.5-flash&direction=response&stream=true", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	chunks := gjson.GetBytes(rec.Body.Bytes(), "chunks").Array()
+	if len(chunks) == 0 {
+		t.Fatalf("expected translated chunks, got %s", rec.Body.String())
+	}
+	if got := gjson.Get(chunks[0].String(), "choices.0.delta.content").String(); got != "hello" {
+		t.Fatalf("first chunk content = %q, want hello; chunks = %s", got, rec.Body.String())
+	}
+
+	rec = performTranslateDryRun(t, h, "from=openai&to=antigravity&model=gemini-2.5-flash&direction=response", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := gjson.GetBytes(rec.Body.Bytes(), "output.choices.0.message.content").String(); got != "hello" {
+		t.Fatalf("non-stream content = %q, body = %s", got, rec.Body.String())
+	}
+}
+
+func TestTranslateDryRun_RejectsInvalidParameters(t *testing.T) {
+	h := NewHandlerWithoutConfigFilePath(&config.Config{}, nil)
+	for _, query := range []string{
+		"from=openai&to=antigravity",
+		"from=openai&to=codex&model=gpt-5",
+		"from=openai&to=antigravity&model=m&direction=sideways",
+	} {
+		if rec := performTranslateDryRun(t, h, query, `{}`); rec.Code != http.StatusBadRequest {
+			t.Fatalf("query %q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+func newBodyLimitTestRouter(limit int64, seen *string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestBodyLimitMiddleware(func() int64 { return limit }))
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		*seen = string(body)
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRequestBodyLimitMiddlewareRejectsOversizedBody(t *testing.T) {
+	var seen string
+	router := newBodyLimitTestRouter(16, &seen)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(strings.Repeat("x", 17))))
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", recorder.Code)
+	}
+	if got := gjson.Get(recorder.Body.String(), "error.type").String(); got != "invalid_request_error" {
+		t.Fatalf("error.type = %q, body = %s", got, recorder.Body.String())
+	}
+	if !strings.Contains(gjson.Get(recorder.Body.String(), "error.message").String(), "16 bytes") {
+		t.Fatalf("error.message should mention the limit: %s", recorder.Body.String())
+	}
+	if seen != "" {
+		t.Fatalf("handler should not run for oversized bodies")
+	}
+}
+
+func TestRequestBodyLimitMiddlewareRejectsOversizedChunkedBody(t *testing.T) {
+	var seen string
+	router := newBodyLimitTestRouter(16, &seen)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", io.NopCloser(strings.NewReader(strings.Repeat("x", 64))))
+	req.ContentLength = -1
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", recorder.Code)
+	}
+}
+
+func TestRequestBodyLimitMiddlewareAllowsBodiesWithinLimit(t *testing.T) {
+	var seen string
+	router := newBodyLimitTestRouter(16, &seen)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", io.NopCloser(strings.NewReader(`{"a":1}`)))
+	req.ContentLength = -1
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK || seen != `{"a":1}` {
+		t.Fatalf("status = %d, seen = %q", recorder.Code, seen)
+	}
+
+	seen = ""
+	disabled := newBodyLimitTestRouter(0, &seen)
+	recorder = httptest.NewRecorder()
+	disabled.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(strings.Repeat("x", 64))))
+	if recorder.Code != http.StatusOK || len(seen) != 64 {
+		t.Fatalf("disabled limit: status = %d, len = %d", recorder.Code, len(seen))
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIPHeader carries the client address resolved by ClientIPMiddleware. The server sets
+// it as the engine's TrustedPlatform so gin's Context.ClientIP returns the resolved address.
+// Any value sent by the client is overwritten.
+const ClientIPHeader = "X-CLIProxy-Client-IP"
+
+// ParseIPPrefixes parses CIDR blocks and bare IPv4/IPv6 addresses. Bare addresses become
+// single-host prefixes. Entries that cannot be parsed are returned in invalid.
+func ParseIPPrefixes(values []string) (prefixes []netip.Prefix, invalid []string) {
+	for _, raw := range values {
+		value := strings.TrimSpace(raw)
+		if value == "" {
+			continue
+		}
+		if strings.Contains(value, "/") {
+			prefix, errParse := netip.ParsePrefix(value)
+			if errParse != nil {
+				invalid = append(invalid, value)
+				continue
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, errParse := netip.ParseAddr(value)
+		if errParse != nil {
+			invalid = append(invalid, value)
+			continue
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, invalid
+}
+
+// IPInPrefixes reports whether ip falls inside any of prefixes.
+func IPInPrefixes(ip string, prefixes []netip.Prefix) bool {
+	addr, ok := parseHopAddr(ip)
+	return ok && addrInPrefixes(addr, prefixes)
+}
+
+// ResolveClientIP returns the address of the client behind remoteAddr. X-Forwarded-For is only
+// consulted when the peer is a trusted proxy; the hops are then walked from the right and the
+// first address that is not itself a trusted proxy is the client.
+func ResolveClientIP(remoteAddr string, forwardedFor []string, trusted []netip.Prefix) string {
+	peer, ok := parseHopAddr(remoteAddr)
+	if !ok {
+		return strings.TrimSpace(remoteAddr)
+	}
+	client := peer
+	if !addrInPrefixes(client, trusted) {
+		return client.String()
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, okHop := parseHopAddr(hops[i])
+		if !okHop {
+			break
+		}
+		client = hop
+		if !addrInPrefixes(client, trusted) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// ForwardedByUntrustedPeer reports whether the request carries forwarding headers naming a
+// client other than the peer, although the peer is not a trusted proxy. Such headers never
+// decide the client address, but they show the peer may be a reverse proxy relaying a remote
+// client, so the request must not be treated as coming from the local host.
+func ForwardedByUntrustedPeer(remoteAddr string, header http.Header, trusted []netip.Prefix) bool {
+	peer, ok := parseHopAddr(remoteAddr)
+	if ok && addrInPrefixes(peer, trusted) {
+		return false
+	}
+	for _, name := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		for _, value := range header.Values(name) {
+			for _, hop := range strings.Split(value, ",") {
+				if strings.TrimSpace(hop) == "" {
+					continue
+				}
+				if addr, okHop := parseHopAddr(hop); !okHop || !ok || addr != peer {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ClientIPMiddleware resolves the client address once per request using the proxies returned
+// by trusted and records it in ClientIPHeader. Without trusted proxies the client is always the
+// peer; forwarding headers are then only noted for IsLocalClient.
+func ClientIPMiddleware(trusted func() []netip.Prefix) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var prefixes []netip.Prefix
+		if trusted != nil {
+			prefixes = trusted()
+		}
+		c.Request.Header.Set(ClientIPHeader, ResolveClientIP(c.Request.RemoteAddr, c.Request.Header.Values("X-Forwarded-For"), prefixes))
+		if ForwardedByUntrustedPeer(c.Request.RemoteAddr, c.Request.Header, prefixes) {
+			c.Set(untrustedForwardedKey, true)
+		}
+		c.Next()
+	}
+}
+
+const untrustedForwardedKey = "cliproxy.untrustedForwarded"
+
+// IsLocalClient reports whether the client of c is the local host: its resolved address is a
+// loopback address and no untrusted peer forwarded the request on behalf of another client.
+func IsLocalClient(c *gin.Context) bool {
+	clientIP := c.ClientIP()
+	if clientIP != "127.0.0.1" && clientIP != "::1" {
+		return false
+	}
+	return !c.GetBool(untrustedForwardedKey)
+}
+
+// ClientIPAllowed applies the allowlist returned by allowed to the client of c, aborting
+// the request with 403 and returning false when the client is not allowed.
+func ClientIPAllowed(c *gin.Context, allowed func() (prefixes []netip.Prefix, restricted bool)) bool {
+	if allowed == nil {
+		return true
+	}
+	prefixes, restricted := allowed()
+	if !restricted || IPInPrefixes(c.ClientIP(), prefixes) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "access denied for client address"})
+	return false
+}
+
+func addrInPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHopAddr parses an address that may carry a port or IPv6 brackets, e.g. "[::1]:8080".
+func parseHopAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return netip.Addr{}, false
+	}
+	if addr, errParse := netip.ParseAddr(value); errParse == nil {
+		return addr.Unmap().WithZone(""), true
+	}
+	host, _, errSplit := net.SplitHostPort(value)
+	if errSplit != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	}
+	addr, errParse := netip.ParseAddr(host)
+	if errParse != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
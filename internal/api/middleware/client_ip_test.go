@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	trusted, invalid := ParseIPPrefixes([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/48", "not-an-ip"})
+	if len(invalid) != 1 || invalid[0] != "not-an-ip" {
+		t.Fatalf("invalid = %v, want [not-an-ip]", invalid)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "spoofed header from untrusted peer", remoteAddr: "203.0.113.9:4000", forwardedFor: []string{"127.0.0.1"}, want: "203.0.113.9"},
+		{name: "no header from trusted peer", remoteAddr: "192.0.2.1:4000", want: "192.0.2.1"},
+		{name: "single trusted proxy", remoteAddr: "192.0.2.1:4000", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+		{name: "trusted proxy chain", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"198.51.100.7, 10.1.2.3", "192.0.2.1"}, want: "198.51.100.7"},
+		{name: "spoofed hop left of untrusted hop", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"127.0.0.1, 198.51.100.7, 10.1.2.3"}, want: "198.51.100.7"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"10.9.9.9, 10.1.2.3"}, want: "10.9.9.9"},
+		{name: "malformed hop", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"198.51.100.7, garbage"}, want: "10.0.0.2"},
+		{name: "ipv6 trusted proxy", remoteAddr: "[2001:db8:0:1::5]:4000", forwardedFor: []string{"2001:db8:beef::1"}, want: "2001:db8:beef::1"},
+		{name: "ipv6 hop with port", remoteAddr: "10.0.0.2:4000", forwardedFor: []string{"[2001:db8:beef::1]:5555"}, want: "2001:db8:beef::1"},
+		{name: "ipv4-mapped peer", remoteAddr: "[::ffff:10.0.0.2]:4000", forwardedFor: []string{"198.51.100.7"}, want: "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveClientIP(tt.remoteAddr, tt.forwardedFor, trusted); got != tt.want {
+				t.Fatalf("ResolveClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForwardedByUntrustedPeer(t *testing.T) {
+	trusted, _ := ParseIPPrefixes([]string{"10.0.0.0/8"})
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       bool
+	}{
+		{name: "peer only", remoteAddr: "127.0.0.1:4000", want: false},
+		{name: "local reverse proxy", remoteAddr: "127.0.0.1:4000", headers: map[string]string{"X-Forwarded-For": "198.51.100.7"}, want: true},
+		{name: "real ip header", remoteAddr: "[::1]:4000", headers: map[string]string{"X-Real-IP": "198.51.100.7"}, want: true},
+		{name: "header naming the peer", remoteAddr: "127.0.0.1:4000", headers: map[string]string{"X-Forwarded-For": "127.0.0.1"}, want: false},
+		{name: "trusted proxy", remoteAddr: "10.0.0.2:4000", headers: map[string]string{"X-Forwarded-For": "198.51.100.7"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.headers {
+				header.Set(name, value)
+			}
+			if got := ForwardedByUntrustedPeer(tt.remoteAddr, header, trusted); got != tt.want {
+				t.Fatalf("ForwardedByUntrustedPeer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPAllowedMatchesIPv6CIDRs(t *testing.T) {
+	allowed, _ := ParseIPPrefixes([]string{"2001:db8:abcd::/48", "127.0.0.1"})
+	allowlist := func() (prefixes []netip.Prefix, restricted bool) { return allowed, true }
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.TrustedPlatform = ClientIPHeader
+	router.Use(ClientIPMiddleware(nil))
+	router.GET("/", func(c *gin.Context) {
+		if !ClientIPAllowed(c, allowlist) {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		remoteAddr string
+		want       int
+	}{
+		{remoteAddr: "[2001:db8:abcd:12::1]:4000", want: http.StatusNoContent},
+		{remoteAddr: "[2001:db8:abce::1]:4000", want: http.StatusForbidden},
+		{remoteAddr: "127.0.0.1:4000", want: http.StatusNoContent},
+		{remoteAddr: "[::1]:4000", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set(ClientIPHeader, "2001:db8:abcd::1")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.remoteAddr, rr.Code, tt.want)
+		}
+		if tt.want == http.StatusForbidden && rr.Body.String() != `{"error":"access denied for client address"}` {
+			t.Fatalf("%s: body = %s, want the error envelope", tt.remoteAddr, rr.Body.String())
+		}
+	}
+}
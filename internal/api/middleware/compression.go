@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+)
+
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+)
+
+var (
+	gzipWriterPool = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	zstdWriterPool = sync.Pool{New: func() any {
+		encoder, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return encoder
+	}}
+)
+
+// ResponseCompressionMiddleware compresses responses with gzip or zstd when settings
+// enables it and the client accepts either encoding. Only bodies of an allowed content type
+// that reach the size threshold are compressed; server-sent event streams and responses
+// that flush before reaching the threshold are sent unchanged. Register it before
+// RequestLoggingMiddleware so request logs record the uncompressed body.
+func ResponseCompressionMiddleware(settings func() config.ResponseCompressionConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if settings == nil || c.Request == nil || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		cfg := settings()
+		if !cfg.Enable {
+			c.Next()
+			return
+		}
+		encoding := negotiateResponseEncoding(c.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" || strings.EqualFold(c.Request.Header.Get("Upgrade"), "websocket") {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+		writer := &compressResponseWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			minBytes:       cfg.EffectiveMinBytes(),
+			contentTypes:   cfg.EffectiveContentTypes(),
+		}
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			if c.Writer == writer {
+				c.Writer = writer.ResponseWriter
+			}
+		}()
+		c.Next()
+	}
+}
+
+// negotiateResponseEncoding picks zstd or gzip from an Accept-Encoding header, honouring
+// q-values and preferring zstd on ties. It returns "" when neither is acceptable.
+func negotiateResponseEncoding(header string) string {
+	best, bestQ := "", 0.0
+	wildcardQ := -1.0
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, errParse := strconv.ParseFloat(strings.TrimSpace(value), 64); errParse == nil {
+					q = parsed
+				}
+			}
+		}
+		switch name {
+		case encodingZstd, encodingGzip:
+			if q > bestQ || (q == bestQ && q > 0 && name == encodingZstd) {
+				best, bestQ = name, q
+			}
+		case "*":
+			wildcardQ = q
+		}
+	}
+	if best == "" && wildcardQ > 0 {
+		return encodingGzip
+	}
+	return best
+}
+
+// compressResponseWriter holds back the start of a response until it knows whether the body
+// qualifies for compression, then either compresses or passes it through unchanged.
+type compressResponseWriter struct {
+	gin.ResponseWriter
+	encoding     string
+	minBytes     int
+	contentTypes []string
+
+	status      int
+	buffer      []byte
+	decided     bool
+	compressing bool
+	encoder     io.WriteCloser
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow is a no-op until the body decides whether the response is compressed.
+func (w *compressResponseWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressResponseWriter) Status() int {
+	if !w.decided && w.status > 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressResponseWriter) Written() bool {
+	return w.ResponseWriter.Written() || (!w.decided && (w.status > 0 || len(w.buffer) > 0))
+}
+
+func (w *compressResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if !w.eligible() {
+			if errPass := w.passThrough(); errPass != nil {
+				return 0, errPass
+			}
+		} else {
+			w.buffer = append(w.buffer, data...)
+			if len(w.buffer) < w.minBytes {
+				return len(data), nil
+			}
+			if errStart := w.startCompression(); errStart != nil {
+				return 0, errStart
+			}
+			return len(data), nil
+		}
+	}
+	if w.compressing {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressResponseWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
+
+// Flush sends everything written so far. A response flushed before reaching the threshold
+// is treated as a stream and passes through uncompressed.
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		if errPass := w.passThrough(); errPass != nil {
+			return
+		}
+	}
+	if w.compressing {
+		if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+			_ = flusher.Flush()
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.decided {
+		w.decided = true
+		if w.status > 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	return w.ResponseWriter.Hijack()
+}
+
+// eligible reports whether the response headers allow compression.
+func (w *compressResponseWriter) eligible() bool {
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	mediaType, _, errParse := mime.ParseMediaType(header.Get("Content-Type"))
+	if errParse != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	for _, allowed := range w.contentTypes {
+		if strings.EqualFold(strings.TrimSpace(allowed), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressResponseWriter) passThrough() error {
+	w.decided = true
+	if w.status > 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	buffered := w.buffer
+	w.buffer = nil
+	_, errWrite := w.ResponseWriter.Write(buffered)
+	return errWrite
+}
+
+func (w *compressResponseWriter) startCompression() error {
+	w.decided = true
+	w.compressing = true
+	header := w.ResponseWriter.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	if w.status > 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	switch w.encoding {
+	case encodingZstd:
+		encoder := zstdWriterPool.Get().(*zstd.Encoder)
+		encoder.Reset(w.ResponseWriter)
+		w.encoder = encoder
+	default:
+		encoder := gzipWriterPool.Get().(*gzip.Writer)
+		encoder.Reset(w.ResponseWriter)
+		w.encoder = encoder
+	}
+	buffered := w.buffer
+	w.buffer = nil
+	_, errWrite := w.encoder.Write(buffered)
+	return errWrite
+}
+
+// finish sends a response that stayed below the threshold unchanged, or closes the encoder.
+func (w *compressResponseWriter) finish() {
+	if !w.decided {
+		_ = w.passThrough()
+		return
+	}
+	if !w.compressing || w.encoder == nil {
+		return
+	}
+	_ = w.encoder.Close()
+	switch encoder := w.encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(io.Discard)
+		zstdWriterPool.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(io.Discard)
+		gzipWriterPool.Put(encoder)
+	}
+	w.encoder = nil
+}
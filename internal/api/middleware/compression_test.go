@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+)
+
+func largeJSONBody() string {
+	return `{"content":"` + strings.Repeat("func main() { println(\"hello\") }\n", 200) + `"}`
+}
+
+func newCompressionRouter(cfg config.ResponseCompressionConfig, extra ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ResponseCompressionMiddleware(func() config.ResponseCompressionConfig { return cfg }))
+	router.Use(extra...)
+	router.Any("/json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(largeJSONBody()))
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/text", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/plain", []byte(largeJSONBody()))
+	})
+	router.GET("/sse", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			_, _ = c.Writer.WriteString("data: " + strings.Repeat("x", 600) + "\n\n")
+			c.Writer.Flush()
+		}
+	})
+	return router
+}
+
+func serveCompression(router *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	return serveCompressionMethod(router, http.MethodGet, path, acceptEncoding)
+}
+
+func serveCompressionMethod(router *gin.Engine, method, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeResponseBody(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var reader io.Reader
+	switch rec.Header().Get("Content-Encoding") {
+	case "gzip":
+		gz, errGzip := gzip.NewReader(rec.Body)
+		if errGzip != nil {
+			t.Fatalf("gzip reader: %v", errGzip)
+		}
+		reader = gz
+	case "zstd":
+		zr, errZstd := zstd.NewReader(rec.Body)
+		if errZstd != nil {
+			t.Fatalf("zstd reader: %v", errZstd)
+		}
+		defer zr.Close()
+		reader = zr
+	default:
+		reader = rec.Body
+	}
+	data, errRead := io.ReadAll(reader)
+	if errRead != nil {
+		t.Fatalf("decode body: %v", errRead)
+	}
+	return string(data)
+}
+
+func TestResponseCompressionNegotiatesEncoding(t *testing.T) {
+	router := newCompressionRouter(config.ResponseCompressionConfig{Enable: true})
+	cases := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "gzip", want: "gzip"},
+		{acceptEncoding: "gzip, zstd", want: "zstd"},
+		{acceptEncoding: "zstd;q=0.5, gzip;q=0.9", want: "gzip"},
+		{acceptEncoding: "zstd;q=0, gzip", want: "gzip"},
+		{acceptEncoding: "*", want: "gzip"},
+		{acceptEncoding: "br, deflate", want: ""},
+		{acceptEncoding: "", want: ""},
+	}
+	for _, tc := range cases {
+		rec := serveCompression(router, "/json", tc.acceptEncoding)
+		if got := rec.Header().Get("Content-Encoding"); got != tc.want {
+			t.Fatalf("Accept-Encoding %q: Content-Encoding = %q, want %q", tc.acceptEncoding, got, tc.want)
+		}
+		if got := decodeResponseBody(t, rec); got != largeJSONBody() {
+			t.Fatalf("Accept-Encoding %q: decoded body differs from the original", tc.acceptEncoding)
+		}
+		if tc.want != "" && rec.Body.Len() >= len(largeJSONBody()) {
+			t.Fatalf("Accept-Encoding %q: compressed body is not smaller", tc.acceptEncoding)
+		}
+	}
+}
+
+func TestResponseCompressionLeavesIneligibleResponsesUntouched(t *testing.T) {
+	router := newCompressionRouter(config.ResponseCompressionConfig{Enable: true})
+	for _, path := range []string{"/small", "/text", "/sse"} {
+		rec := serveCompression(router, path, "gzip, zstd")
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Fatalf("%s: Content-Encoding = %q, want none", path, got)
+		}
+	}
+	rec := serveCompression(router, "/sse", "gzip")
+	if got := rec.Body.String(); strings.Count(got, "data: ") != 3 || !strings.HasPrefix(got, "data: xxx") {
+		t.Fatalf("SSE body changed: %q", got[:min(len(got), 40)])
+	}
+
+	disabled := newCompressionRouter(config.ResponseCompressionConfig{})
+	if got := serveCompression(disabled, "/json", "gzip").Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("disabled: Content-Encoding = %q, want none", got)
+	}
+	allowText := newCompressionRouter(config.ResponseCompressionConfig{Enable: true, ContentTypes: []string{"text/plain"}})
+	if got := serveCompression(allowText, "/text", "gzip").Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("text/plain allowlisted: Content-Encoding = %q, want gzip", got)
+	}
+}
+
+func TestResponseCompressionKeepsRequestLogUncompressed(t *testing.T) {
+	logsDir := t.TempDir()
+	logger := logging.NewFileRequestLogger(true, logsDir, "", 10)
+	router := newCompressionRouter(config.ResponseCompressionConfig{Enable: true}, RequestLoggingMiddleware(logger))
+
+	rec := serveCompressionMethod(router, http.MethodPost, "/json", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	entries, errReadDir := os.ReadDir(logsDir)
+	if errReadDir != nil {
+		t.Fatalf("read logs dir: %v", errReadDir)
+	}
+	var content []byte
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".log") {
+			data, errRead := os.ReadFile(filepath.Join(logsDir, entry.Name()))
+			if errRead != nil {
+				t.Fatalf("read log: %v", errRead)
+			}
+			content = append(content, data...)
+		}
+	}
+	if !bytes.Contains(content, []byte(largeJSONBody())) {
+		t.Fatalf("request log does not contain the uncompressed response body; logs=%d bytes", len(content))
+	}
+}
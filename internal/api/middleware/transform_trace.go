@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// TransformTraceMiddleware echoes the request's transformation trace to clients that send
+// X-CLIProxy-Debug: transforms while enabled reports true. Non-streaming JSON responses get
+// the trace as a _cliproxy_transforms field and server-sent event streams end with a comment
+// line carrying it. Register it after RequestLoggingMiddleware so request logs record the
+// response the client received.
+func TransformTraceMiddleware(enabled func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled == nil || c.Request == nil || !enabled() || !transformtrace.Requested(c.Request.Header.Get(transformtrace.DebugHeader)) {
+			c.Next()
+			return
+		}
+
+		writer := &transformTraceWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		if c.Writer == writer {
+			c.Writer = writer.ResponseWriter
+		}
+		writer.finish(transformTraceSteps(c))
+	}
+}
+
+// transformTraceSteps returns the trace recorded for the request behind c.
+func transformTraceSteps(c *gin.Context) []string {
+	value, exists := c.Get(transformtrace.GinKey)
+	if !exists {
+		return []string{}
+	}
+	trace, ok := value.(*transformtrace.Trace)
+	if !ok {
+		return []string{}
+	}
+	return trace.Steps()
+}
+
+// transformTraceWriter holds back JSON bodies so the trace can be added once the handler
+// finishes, and passes every other response through.
+type transformTraceWriter struct {
+	gin.ResponseWriter
+
+	status    int
+	buffer    []byte
+	decided   bool
+	buffering bool
+	stream    bool
+}
+
+func (w *transformTraceWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow is a no-op until the body decides whether the response is held back.
+func (w *transformTraceWriter) WriteHeaderNow() {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *transformTraceWriter) Status() int {
+	if (!w.decided || w.buffering) && w.status > 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *transformTraceWriter) Written() bool {
+	return w.ResponseWriter.Written() || w.status > 0 || len(w.buffer) > 0
+}
+
+func (w *transformTraceWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decide()
+	}
+	if w.buffering {
+		w.buffer = append(w.buffer, data...)
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *transformTraceWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
+
+// Flush sends keep-alive whitespace written ahead of a held back JSON body; the body itself
+// stays buffered until the handler finishes.
+func (w *transformTraceWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.buffering {
+		if len(w.buffer) == 0 || len(bytes.TrimSpace(w.buffer)) > 0 {
+			return
+		}
+		w.writeStatus()
+		_, _ = w.ResponseWriter.Write(w.buffer)
+		w.buffer = nil
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *transformTraceWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.decided {
+		w.decided = true
+		w.writeStatus()
+	}
+	w.buffering = false
+	return w.ResponseWriter.Hijack()
+}
+
+// decide inspects the response headers once the body starts.
+func (w *transformTraceWriter) decide() {
+	w.decided = true
+	mediaType, _, _ := mime.ParseMediaType(w.ResponseWriter.Header().Get("Content-Type"))
+	switch mediaType {
+	case "text/event-stream":
+		w.stream = true
+	case "application/json":
+		w.buffering = true
+		return
+	}
+	w.writeStatus()
+}
+
+func (w *transformTraceWriter) writeStatus() {
+	if w.status > 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+		w.status = 0
+	}
+}
+
+// finish writes the held back body with the trace added, or ends a stream with the trace.
+func (w *transformTraceWriter) finish(steps []string) {
+	if !w.decided {
+		w.writeStatus()
+		return
+	}
+	if w.stream {
+		encoded, errMarshal := json.Marshal(steps)
+		if errMarshal != nil {
+			return
+		}
+		_, _ = w.ResponseWriter.Write([]byte(": " + transformtrace.ResponseField + " " + string(encoded) + "\n\n"))
+		w.ResponseWriter.Flush()
+		return
+	}
+	if !w.buffering {
+		return
+	}
+	w.buffering = false
+	body := w.buffer
+	w.buffer = nil
+	// Keep keep-alive whitespace that was not flushed ahead of the JSON object.
+	prefix := len(body) - len(bytes.TrimLeft(body, " \t\r\n"))
+	if object := bytes.TrimSpace(body); gjson.ValidBytes(object) && gjson.ParseBytes(object).IsObject() {
+		if updated, errSet := sjson.SetBytes(object, transformtrace.ResponseField, steps); errSet == nil {
+			body = append(body[:prefix:prefix], updated...)
+			w.ResponseWriter.Header().Del("Content-Length")
+		}
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.writeStatus()
+	_, _ = w.ResponseWriter.Write(body)
+}
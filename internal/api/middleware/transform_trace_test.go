@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	"github.com/tidwall/gjson"
+)
+
+func newTransformTraceRouter(enabled bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TransformTraceMiddleware(func() bool { return enabled }))
+	shape := func(c *gin.Context) {
+		trace := transformtrace.New()
+		c.Set(transformtrace.GinKey, trace)
+		ctx := transformtrace.NewContext(context.Background(), trace)
+		transformtrace.Record(ctx, "payload.default[0]")
+		transformtrace.Record(ctx, "thinking: suffix budget=100000 clamped to budget=32768")
+		transformtrace.EndAttempt(ctx)
+	}
+	router.POST("/json", func(c *gin.Context) {
+		shape(c)
+		c.Header("Content-Type", "application/json")
+		_, _ = c.Writer.Write([]byte("\n"))
+		c.Writer.Flush()
+		_, _ = c.Writer.Write([]byte(`{"id":"chatcmpl_1","choices":[]}`))
+	})
+	router.POST("/sse", func(c *gin.Context) {
+		shape(c)
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.Write([]byte("data: {\"id\":1}\n\n"))
+		c.Writer.Flush()
+		_, _ = c.Writer.Write([]byte("data: [DONE]\n\n"))
+	})
+	return router
+}
+
+func performTransformTraceRequest(router *gin.Engine, path, debug string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+	if debug != "" {
+		req.Header.Set(transformtrace.DebugHeader, debug)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestTransformTraceMiddlewareAddsFieldToJSON(t *testing.T) {
+	recorder := performTransformTraceRequest(newTransformTraceRouter(true), "/json", "transforms")
+
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "\n{") {
+		t.Fatalf("body = %q, want keep-alive newline followed by the JSON object", body)
+	}
+	steps := gjson.Get(strings.TrimSpace(body), transformtrace.ResponseField).Array()
+	if len(steps) != 2 || steps[0].String() != "payload.default[0]" || steps[1].String() != "thinking: suffix budget=100000 clamped to budget=32768" {
+		t.Fatalf("%s = %v, want both recorded steps; body=%s", transformtrace.ResponseField, steps, body)
+	}
+	if got := gjson.Get(strings.TrimSpace(body), "id").String(); got != "chatcmpl_1" {
+		t.Fatalf("id = %q, want chatcmpl_1", got)
+	}
+}
+
+func TestTransformTraceMiddlewareEndsStreamWithComment(t *testing.T) {
+	recorder := performTransformTraceRequest(newTransformTraceRouter(true), "/sse", "Transforms")
+
+	want := "data: {\"id\":1}\n\ndata: [DONE]\n\n" +
+		`: _cliproxy_transforms ["payload.default[0]","thinking: suffix budget=100000 clamped to budget=32768"]` + "\n\n"
+	if got := recorder.Body.String(); got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+}
+
+func TestTransformTraceMiddlewareLeavesResponsesWithoutOptIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		debug   string
+	}{
+		{name: "disabled in config", enabled: false, debug: "transforms"},
+		{name: "header missing", enabled: true, debug: ""},
+		{name: "other debug value", enabled: true, debug: "timing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTransformTraceRouter(tt.enabled)
+			if got := performTransformTraceRequest(router, "/json", tt.debug).Body.String(); got != "\n"+`{"id":"chatcmpl_1","choices":[]}` {
+				t.Fatalf("json body = %q", got)
+			}
+			if got := performTransformTraceRequest(router, "/sse", tt.debug).Body.String(); strings.Contains(got, transformtrace.ResponseField) {
+				t.Fatalf("stream body = %q, want no trace comment", got)
+			}
+		})
+	}
+}
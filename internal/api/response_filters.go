@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	log "github.com/sirupsen/logrus"
+)
+
+// applyResponseFilters installs the registered response filters followed by the
+// response-redaction filter of cfg.
+func (s *Server) applyResponseFilters(cfg *config.Config) {
+	if s == nil || s.handlers == nil {
+		return
+	}
+	filters := append([]handlers.ResponseFilter(nil), s.responseFilters...)
+	if cfg != nil && len(cfg.ResponseRedaction) > 0 {
+		redaction, errRedaction := handlers.NewRegexRedactionFilter(cfg.ResponseRedaction)
+		if errRedaction != nil {
+			log.Errorf("response-redaction: %v", errRedaction)
+		}
+		if redaction != nil {
+			filters = append(filters, redaction)
+		}
+	}
+	s.handlers.SetResponseFilters(filters...)
+}
@@ -16,6 +16,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -24,6 +25,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/access"
 	managementHandlers "github.com/router-for-me/CLIProxyAPI/v7/internal/api/handlers/management"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/api/middleware"
@@ -32,11 +34,15 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/home"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/pluginhost"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/redisqueue"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/requeststore"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor/helps"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/safemode"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tokenquota"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v7/sdk/access"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
@@ -89,6 +95,7 @@ type serverOptionConfig struct {
 	pluginHost            *pluginhost.Host
 	configReloadHook      func(context.Context, *config.Config)
 	exampleAPIKeySafeMode bool
+	responseFilters       []handlers.ResponseFilter
 }
 
 // ServerOption customises HTTP server construction.
@@ -188,6 +195,14 @@ func WithConfigReloadHook(hook func(context.Context, *config.Config)) ServerOpti
 	}
 }
 
+// WithResponseFilters registers filters applied to every response before it is written,
+// ahead of the response-redaction filter built from config.
+func WithResponseFilters(filters ...handlers.ResponseFilter) ServerOption {
+	return func(cfg *serverOptionConfig) {
+		cfg.responseFilters = append(cfg.responseFilters, filters...)
+	}
+}
+
 // WithExampleAPIKeySafeMode blocks proxy API endpoints while template API keys remain configured.
 func WithExampleAPIKeySafeMode() ServerOption {
 	return func(cfg *serverOptionConfig) {
@@ -239,12 +254,30 @@ type Server struct {
 	wsAuthChanged func(bool, bool)
 	wsAuthEnabled atomic.Bool
 
+	// maxRequestBodyBytes holds the current inbound body limit enforced by RequestBodyLimitMiddleware.
+	maxRequestBodyBytes *atomic.Int64
+
+	// exposeAuthHeader reports whether AuthHeaderMiddleware exposes the serving credential.
+	exposeAuthHeader *atomic.Bool
+
+	// responseCompression holds the settings applied by ResponseCompressionMiddleware.
+	responseCompression *atomic.Pointer[config.ResponseCompressionConfig]
+
+	// debugTransforms reports whether TransformTraceMiddleware echoes transformation traces.
+	debugTransforms *atomic.Bool
+
+	// clientIPs holds the trusted proxies and the management allowlist.
+	clientIPs *clientIPPolicyHolder
+
 	// management handler
 	mgmt *managementHandlers.Handler
 
 	// pluginHost owns dynamic plugin Management API route dispatch.
 	pluginHost *pluginhost.Host
 
+	// responseFilters are the response filters registered through WithResponseFilters.
+	responseFilters []handlers.ResponseFilter
+
 	// managementRoutesRegistered tracks whether the management routes have been attached to the engine.
 	managementRoutesRegistered atomic.Bool
 	// managementRoutesEnabled controls whether management endpoints serve real handlers.
@@ -287,16 +320,35 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create gin engine
+	// Create gin engine. The client address is resolved by ClientIPMiddleware so that
+	// X-Forwarded-For is only honored from the configured trusted proxies.
 	engine := gin.New()
+	engine.TrustedPlatform = middleware.ClientIPHeader
 	if optionState.engineConfigurator != nil {
 		optionState.engineConfigurator(engine)
 	}
+	clientIPs := &clientIPPolicyHolder{}
+	clientIPs.policy.Store(newClientIPPolicy(cfg))
 
 	// Add middleware
+	engine.Use(middleware.ClientIPMiddleware(clientIPs.trustedProxies))
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
 	engine.Use(logging.CPATraceIDMiddleware())
+	engine.Use(tracing.Middleware())
+	exposeAuthHeader := &atomic.Bool{}
+	exposeAuthHeader.Store(cfg.Server.ExposeAuthHeader)
+	engine.Use(logging.AuthHeaderMiddleware(exposeAuthHeader.Load))
+	maxRequestBodyBytes := &atomic.Int64{}
+	maxRequestBodyBytes.Store(cfg.Server.EffectiveMaxRequestBodyBytes())
+	engine.Use(middleware.RequestBodyLimitMiddleware(maxRequestBodyBytes.Load))
+	// Compression wraps the writer before request logging so logs keep the uncompressed body.
+	responseCompression := &atomic.Pointer[config.ResponseCompressionConfig]{}
+	compression := cfg.Server.Compression
+	responseCompression.Store(&compression)
+	engine.Use(middleware.ResponseCompressionMiddleware(func() config.ResponseCompressionConfig {
+		return *responseCompression.Load()
+	}))
 	for _, mw := range optionState.extraMiddleware {
 		engine.Use(mw)
 	}
@@ -314,9 +366,15 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 			if setter, ok := requestLogger.(interface{ SetEnabled(bool) }); ok {
 				toggle = setter.SetEnabled
 			}
+			authManager.SetShadowRecorder(shadowLogRecorder{logger: requestLogger})
 		}
 	}
 
+	// The transformation trace is added inside request logging so logs match what the client received.
+	debugTransforms := &atomic.Bool{}
+	debugTransforms.Store(cfg.Server.DebugTransforms)
+	engine.Use(middleware.TransformTraceMiddleware(debugTransforms.Load))
+
 	engine.Use(corsMiddleware())
 	wd, err := os.Getwd()
 	if err != nil {
@@ -339,13 +397,20 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		currentPath:         wd,
 		envManagementSecret: envManagementSecret,
 		wsRoutes:            make(map[string]struct{}),
+		maxRequestBodyBytes: maxRequestBodyBytes,
+		exposeAuthHeader:    exposeAuthHeader,
+		responseCompression: responseCompression,
+		debugTransforms:     debugTransforms,
+		clientIPs:           clientIPs,
 		pluginHost:          optionState.pluginHost,
+		responseFilters:     optionState.responseFilters,
 
 		exampleAPIKeySafeModeEnabled: optionState.exampleAPIKeySafeMode,
 	}
 	s.wsAuthEnabled.Store(cfg.WebsocketAuth)
 	s.exampleAPIKeySafeModeActive.Store(s.exampleAPIKeySafeModeRequired(cfg))
 	s.handlers.SetPluginHost(optionState.pluginHost)
+	s.applyResponseFilters(cfg)
 	if optionState.pluginHost != nil {
 		optionState.pluginHost.SetModelExecutor(s.handlers)
 		optionState.pluginHost.SetAuthManager(authManager)
@@ -364,6 +429,7 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
 	s.mgmt.SetPluginHost(optionState.pluginHost)
 	s.mgmt.SetConfigReloadHook(optionState.configReloadHook)
+	s.mgmt.SetActiveStreamsFunc(s.handlers.ActiveStreams)
 	if optionState.localPassword != "" {
 		s.mgmt.SetLocalPassword(optionState.localPassword)
 	}
@@ -395,6 +461,16 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	hasManagementSecret := cfg.RemoteManagement.SecretKey != "" || envManagementSecret || s.localPassword != ""
 	s.managementRoutesEnabled.Store(hasManagementSecret)
 	redisqueue.SetEnabled(hasManagementSecret || (cfg != nil && cfg.Home.Enabled))
+	metrics.SetEnabled(cfg.Metrics.Enable)
+	if errTracing := tracing.Configure(cfg.Tracing); errTracing != nil {
+		log.Errorf("failed to configure tracing: %v", errTracing)
+	}
+	if errStore := requeststore.Configure(cfg); errStore != nil {
+		log.Errorf("failed to open request store: %v", errStore)
+	}
+	if errQuota := tokenquota.Configure(cfg); errQuota != nil {
+		log.Errorf("failed to open token quota counters: %v", errQuota)
+	}
 	if hasManagementSecret {
 		s.registerManagementRoutes()
 	}
@@ -514,6 +590,7 @@ func (s *Server) setupRoutes() {
 	}
 	s.engine.GET("/healthz", healthzHandler)
 	s.engine.HEAD("/healthz", healthzHandler)
+	s.engine.GET("/metrics", metrics.Handler)
 
 	s.engine.GET("/management.html", s.serveManagementControlPanel)
 	openaiHandlers := openai.NewOpenAIAPIHandler(s.handlers)
@@ -527,7 +604,9 @@ func (s *Server) setupRoutes() {
 	{
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
+		v1.GET("/chat/completions/ws", openaiHandlers.ChatCompletionsWebsocket)
 		v1.POST("/completions", openaiHandlers.Completions)
+		v1.POST("/token_count", openaiHandlers.TokenCount)
 		v1.POST("/images/generations", openaiHandlers.ImagesGenerations)
 		v1.POST("/images/edits", openaiHandlers.ImagesEdits)
 		v1.POST("/videos", openaiHandlers.XAIVideosGenerations)
@@ -537,6 +616,11 @@ func (s *Server) setupRoutes() {
 		v1.GET("/videos/:request_id", openaiHandlers.XAIVideosRetrieve)
 		v1.POST("/messages", claudeCodeHandlers.ClaudeMessages)
 		v1.POST("/messages/count_tokens", claudeCodeHandlers.ClaudeCountTokens)
+		v1.POST("/messages/batches", claudeCodeHandlers.ClaudeMessageBatchesCreate)
+		v1.GET("/messages/batches", claudeCodeHandlers.ClaudeMessageBatchesList)
+		v1.GET("/messages/batches/:batch_id", claudeCodeHandlers.ClaudeMessageBatchesGet)
+		v1.GET("/messages/batches/:batch_id/results", claudeCodeHandlers.ClaudeMessageBatchesResults)
+		v1.POST("/messages/batches/:batch_id/cancel", claudeCodeHandlers.ClaudeMessageBatchesCancel)
 		v1.GET("/responses", openaiResponsesHandlers.ResponsesWebsocket)
 		v1.POST("/responses", openaiResponsesHandlers.Responses)
 		v1.POST("/responses/compact", openaiResponsesHandlers.Compact)
@@ -935,8 +1019,15 @@ func (s *Server) registerManagementRoutes() {
 	s.engine.POST("/v0/management/oauth-callback", s.managementAvailabilityMiddleware(), s.mgmt.PostOAuthCallback)
 	s.engine.GET("/v0/management/oauth-callback", s.managementAvailabilityMiddleware(), s.mgmt.GetOAuthCallback)
 
+	ui := s.engine.Group("/v0/ui")
+	ui.Use(s.managementAvailabilityMiddleware(), s.mgmt.StatusUIMiddleware())
+	{
+		ui.GET("/", s.mgmt.ServeStatusUI)
+		ui.GET("/status.json", s.mgmt.GetStatus)
+	}
+
 	mgmt := s.engine.Group("/v0/management")
-	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware())
+	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware(), s.mgmt.AuditMiddleware())
 	{
 		mgmt.GET("/config", s.mgmt.GetConfig)
 		mgmt.GET("/config.yaml", s.mgmt.GetConfigYAML)
@@ -977,6 +1068,13 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.DELETE("/proxy-url", s.mgmt.DeleteProxyURL)
 
 		mgmt.POST("/api-call", s.mgmt.APICall)
+		mgmt.GET("/gemini/cached-contents", s.mgmt.ListGeminiCachedContents)
+		mgmt.POST("/gemini/cached-contents", s.mgmt.CreateGeminiCachedContent)
+		mgmt.DELETE("/gemini/cached-contents", s.mgmt.DeleteGeminiCachedContent)
+		mgmt.POST("/translate", s.mgmt.TranslateDryRun)
+		mgmt.GET("/requests", s.mgmt.GetRequests)
+		mgmt.GET("/status", s.mgmt.GetStatus)
+		mgmt.GET("/audit", s.mgmt.GetAudit)
 
 		mgmt.GET("/quota-exceeded/switch-project", s.mgmt.GetSwitchProject)
 		mgmt.PUT("/quota-exceeded/switch-project", s.mgmt.PutSwitchProject)
@@ -986,6 +1084,9 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PUT("/quota-exceeded/switch-preview-model", s.mgmt.PutSwitchPreviewModel)
 		mgmt.PATCH("/quota-exceeded/switch-preview-model", s.mgmt.PutSwitchPreviewModel)
 		mgmt.POST("/reset-quota", s.mgmt.ResetQuota)
+		mgmt.GET("/token-quota", s.mgmt.GetTokenQuota)
+		mgmt.POST("/token-quota/reset", s.mgmt.ResetTokenQuota)
+		mgmt.GET("/providers/antigravity/endpoints", s.mgmt.GetAntigravityEndpoints)
 
 		mgmt.GET("/api-keys", s.mgmt.GetAPIKeys)
 		mgmt.PUT("/api-keys", s.mgmt.PutAPIKeys)
@@ -1108,6 +1209,9 @@ func (s *Server) managementAvailable(c *gin.Context) bool {
 		c.AbortWithStatus(http.StatusNotFound)
 		return false
 	}
+	if s.clientIPs != nil && !middleware.ClientIPAllowed(c, s.clientIPs.managementCIDRs) {
+		return false
+	}
 	return true
 }
 
@@ -1301,10 +1405,10 @@ func (s *Server) watchKeepAlive() {
 }
 
 // isAnthropicModelsRequest reports whether a /v1/models request should be served in
-// Anthropic format. Anthropic API clients send the Anthropic-Version header; Claude
-// Code additionally uses a claude-cli User-Agent.
+// Anthropic format. Anthropic API clients send the Anthropic-Version header, some only
+// Anthropic-Beta; Claude Code additionally uses a claude-cli User-Agent.
 func isAnthropicModelsRequest(c *gin.Context) bool {
-	if c.GetHeader("Anthropic-Version") != "" {
+	if c.GetHeader("Anthropic-Version") != "" || c.GetHeader("Anthropic-Beta") != "" {
 		return true
 	}
 	return strings.HasPrefix(c.GetHeader("User-Agent"), "claude-cli")
@@ -1382,6 +1486,10 @@ func (s *Server) geminiModelsHandler(geminiHandler *gemini.GeminiAPIHandler) gin
 
 func (s *Server) geminiGetHandler(geminiHandler *gemini.GeminiAPIHandler) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if websocket.IsWebSocketUpgrade(c.Request) {
+			geminiHandler.StreamGenerateContentWebsocket(c)
+			return
+		}
 		if s != nil && s.cfg != nil && s.cfg.Home.Enabled {
 			s.handleHomeGeminiModel(c)
 			return
@@ -1890,10 +1998,36 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
-	// Shutdown the HTTP server.
-	if err := s.server.Shutdown(ctx); err != nil {
+	// Shutdown the HTTP server. Shutdown stops accepting connections and waits for
+	// in-flight requests; active streams get the drain window before being cancelled.
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- s.server.Shutdown(ctx)
+	}()
+	if s.handlers != nil {
+		drainWindow := config.DefaultShutdownDrainSeconds * time.Second
+		if s.cfg != nil {
+			drainWindow = s.cfg.Server.EffectiveShutdownDrainTimeout()
+		}
+		if active := s.handlers.ActiveStreams(); active > 0 {
+			log.Infof("waiting up to %s for %d active stream(s) to finish", drainWindow, active)
+		}
+		if aborted := s.handlers.DrainStreams(ctx, drainWindow); aborted > 0 {
+			log.Warnf("cancelled %d active stream(s) after shutdown drain window", aborted)
+		}
+	}
+	if err := <-shutdownDone; err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %v", err)
 	}
+	if errStore := requeststore.CloseDefault(); errStore != nil {
+		log.Debugf("failed to close request store: %v", errStore)
+	}
+	if errQuota := tokenquota.CloseDefault(); errQuota != nil {
+		log.Debugf("failed to save token quota counters: %v", errQuota)
+	}
+	if errTracing := tracing.Shutdown(ctx); errTracing != nil {
+		log.Debugf("failed to flush traces: %v", errTracing)
+	}
 
 	log.Debug("API server stopped")
 	return nil
@@ -1994,6 +2128,28 @@ func (s *Server) UpdateClientsContext(ctx context.Context, cfg *config.Config) b
 		redisqueue.SetRetentionSeconds(cfg.RedisUsageQueueRetentionSeconds)
 	}
 
+	if oldCfg == nil || oldCfg.Metrics != cfg.Metrics {
+		metrics.SetEnabled(cfg.Metrics.Enable)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.Tracing, cfg.Tracing) {
+		if errTracing := tracing.Configure(cfg.Tracing); errTracing != nil {
+			log.Errorf("failed to reconfigure tracing: %v", errTracing)
+		}
+	}
+
+	if oldCfg == nil || oldCfg.RequestStore != cfg.RequestStore {
+		if errStore := requeststore.Configure(cfg); errStore != nil {
+			log.Errorf("failed to reconfigure request store: %v", errStore)
+		}
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.TokenQuota, cfg.TokenQuota) {
+		if errQuota := tokenquota.Configure(cfg); errQuota != nil {
+			log.Errorf("failed to reconfigure token quota counters: %v", errQuota)
+		}
+	}
+
 	if s.requestLogger != nil && (oldCfg == nil || oldCfg.ErrorLogsMaxFiles != cfg.ErrorLogsMaxFiles) {
 		if setter, ok := s.requestLogger.(interface{ SetErrorLogsMaxFiles(int) }); ok {
 			setter.SetErrorLogsMaxFiles(cfg.ErrorLogsMaxFiles)
@@ -2013,6 +2169,10 @@ func (s *Server) UpdateClientsContext(ctx context.Context, cfg *config.Config) b
 
 	applySignatureCacheConfig(oldCfg, cfg)
 
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ResponseRedaction, cfg.ResponseRedaction) {
+		s.applyResponseFilters(cfg)
+	}
+
 	if s.handlers != nil && s.handlers.AuthManager != nil {
 		s.handlers.AuthManager.SetRetryConfig(cfg.RequestRetry, time.Duration(cfg.MaxRetryInterval)*time.Second, cfg.MaxRetryCredentials)
 	}
@@ -2065,6 +2225,22 @@ func (s *Server) UpdateClientsContext(ctx context.Context, cfg *config.Config) b
 	}
 	s.cfg = cfg
 	s.wsAuthEnabled.Store(cfg.WebsocketAuth)
+	if s.maxRequestBodyBytes != nil {
+		s.maxRequestBodyBytes.Store(cfg.Server.EffectiveMaxRequestBodyBytes())
+	}
+	if s.exposeAuthHeader != nil {
+		s.exposeAuthHeader.Store(cfg.Server.ExposeAuthHeader)
+	}
+	if s.responseCompression != nil {
+		compression := cfg.Server.Compression
+		s.responseCompression.Store(&compression)
+	}
+	if s.debugTransforms != nil {
+		s.debugTransforms.Store(cfg.Server.DebugTransforms)
+	}
+	if s.clientIPs != nil {
+		s.clientIPs.policy.Store(newClientIPPolicy(cfg))
+	}
 	if oldCfg != nil && s.wsAuthChanged != nil && oldCfg.WebsocketAuth != cfg.WebsocketAuth {
 		s.wsAuthChanged(oldCfg.WebsocketAuth, cfg.WebsocketAuth)
 	}
@@ -1844,3 +1844,99 @@ func TestInteractionsRouteRegistered(t *testing.T) {
 		t.Fatalf("status = %d, want route registered; body=%s", rr.Code, rr.Body.String())
 	}
 }
+
+func TestManagementForwardedRemoteClientIsNotLocal(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "")
+
+	server := newTestServerWithOptions(t, WithLocalManagementPassword("local-password"))
+	server.cfg.RemoteManagement.SecretKey = "unused-secret-hash"
+
+	tests := []struct {
+		name         string
+		forwardedFor string
+		want         int
+	}{
+		{name: "local client", want: http.StatusOK},
+		{name: "remote client behind local reverse proxy", forwardedFor: "203.0.113.5", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v0/management/config", nil)
+			req.RemoteAddr = "127.0.0.1:4000"
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			req.Header.Set("Authorization", "Bearer local-password")
+			rr := httptest.NewRecorder()
+			server.engine.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("status = %d, want %d body=%s", rr.Code, tt.want, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestManagementAllowedCIDRsIgnoreForwardedForWithoutTrustedProxies(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "test-management-key")
+
+	server := newTestServer(t)
+	cfg := *server.cfg
+	cfg.Server.TrustedProxies = nil
+	cfg.RemoteManagement.AllowedCIDRs = []string{"10.0.0.0/8"}
+	server.clientIPs.policy.Store(newClientIPPolicy(&cfg))
+
+	for _, header := range []string{"X-Forwarded-For", "X-Real-IP"} {
+		req := httptest.NewRequest(http.MethodGet, "/v0/management/config", nil)
+		req.RemoteAddr = "203.0.113.5:4000"
+		req.Header.Set(header, "10.1.1.1")
+		req.Header.Set("Authorization", "Bearer test-management-key")
+		rr := httptest.NewRecorder()
+		server.engine.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Fatalf("%s: status = %d, want %d body=%s", header, rr.Code, http.StatusForbidden, rr.Body.String())
+		}
+	}
+}
+
+func TestManagementAllowedCIDRsUseTrustedProxyClientIP(t *testing.T) {
+	t.Setenv("MANAGEMENT_PASSWORD", "test-management-key")
+
+	server := newTestServer(t)
+	cfg := *server.cfg
+	cfg.Server.TrustedProxies = []string{"192.0.2.10"}
+	cfg.RemoteManagement.AllowedCIDRs = []string{"10.0.0.0/8", "fd00::/8"}
+	server.clientIPs.policy.Store(newClientIPPolicy(&cfg))
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		want         int
+	}{
+		{name: "spoofed header from untrusted peer", remoteAddr: "203.0.113.5:4000", forwardedFor: "10.1.1.1", want: http.StatusForbidden},
+		{name: "trusted proxy chain", remoteAddr: "192.0.2.10:4000", forwardedFor: "203.0.113.5, 10.1.1.1", want: http.StatusOK},
+		{name: "trusted proxy with outside client", remoteAddr: "192.0.2.10:4000", forwardedFor: "203.0.113.5", want: http.StatusForbidden},
+		{name: "ipv6 client", remoteAddr: "[fd12::1]:4000", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v0/management/config", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			req.Header.Set("Authorization", "Bearer test-management-key")
+			rr := httptest.NewRecorder()
+			server.engine.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("status = %d, want %d body=%s", rr.Code, tt.want, rr.Body.String())
+			}
+			if tt.want == http.StatusForbidden && !strings.Contains(rr.Body.String(), `"error":`) {
+				t.Fatalf("403 body = %s, want the error envelope", rr.Body.String())
+			}
+		})
+	}
+}
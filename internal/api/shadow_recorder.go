@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// shadowLogRecorder writes shadow pairs to the request logs. Each pair is one log under
+// /shadow/<shadow-model> named after the client request ID, with the client request as
+// the body and both responses, their latency and usage as the response.
+type shadowLogRecorder struct {
+	logger logging.RequestLogger
+}
+
+func (r shadowLogRecorder) ShadowRecordingEnabled() bool {
+	return r.logger != nil && r.logger.IsEnabled()
+}
+
+func (r shadowLogRecorder) RecordShadowPair(pair auth.ShadowPair) {
+	headers := map[string][]string{
+		"X-Shadow-Pair":          {pair.RequestID},
+		"X-Shadow-Source-Format": {pair.SourceFormat},
+	}
+	now := time.Now()
+	errLog := r.logger.LogRequest(
+		"/shadow/"+pair.Shadow.Model, http.MethodPost, headers, pair.Request,
+		http.StatusOK, map[string][]string{"Content-Type": {"application/json"}}, shadowPairSummary(pair),
+		nil, nil, nil, nil, nil, pair.RequestID, now, now,
+	)
+	if errLog != nil {
+		log.WithField("request_id", pair.RequestID).Warnf("shadow traffic: failed to record shadow pair: %v", errLog)
+	}
+}
+
+// shadowPairSummary renders both sides of pair as one JSON document.
+func shadowPairSummary(pair auth.ShadowPair) []byte {
+	out := []byte(`{}`)
+	if pair.RequestID != "" {
+		out, _ = sjson.SetBytes(out, "request_id", pair.RequestID)
+	}
+	out, _ = sjson.SetRawBytes(out, "primary", shadowResultSummary(pair.Primary))
+	out, _ = sjson.SetRawBytes(out, "shadow", shadowResultSummary(pair.Shadow))
+	return out
+}
+
+func shadowResultSummary(result auth.ShadowResult) []byte {
+	out := []byte(`{}`)
+	out, _ = sjson.SetBytes(out, "model", result.Model)
+	out, _ = sjson.SetBytes(out, "latency_ms", result.Latency.Milliseconds())
+	if result.Err != nil {
+		out, _ = sjson.SetBytes(out, "error", strings.TrimSpace(result.Err.Error()))
+		return out
+	}
+	if len(result.Usage) > 0 {
+		out, _ = sjson.SetRawBytes(out, "usage", result.Usage)
+	}
+	if gjson.ValidBytes(result.Payload) {
+		out, _ = sjson.SetRawBytes(out, "response", result.Payload)
+	} else {
+		out, _ = sjson.SetBytes(out, "response", string(result.Payload))
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package antigravity
+
+import (
+	"strings"
+	"time"
+)
+
+// CredentialMetadata returns the content of the credential file written after a
+// successful login, in the shape the antigravity executor and refresher read.
+func CredentialMetadata(token *TokenResponse, email, projectID string, now time.Time) map[string]any {
+	metadata := map[string]any{
+		"type":          "antigravity",
+		"access_token":  token.AccessToken,
+		"refresh_token": token.RefreshToken,
+		"expires_in":    token.ExpiresIn,
+		"timestamp":     now.UnixMilli(),
+		"expired":       now.Add(time.Duration(token.ExpiresIn) * time.Second).Format(time.RFC3339),
+	}
+	if email = strings.TrimSpace(email); email != "" {
+		metadata["email"] = email
+	}
+	if projectID = strings.TrimSpace(projectID); projectID != "" {
+		metadata["project_id"] = projectID
+	}
+	return metadata
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// loginSubcommandFlags maps the providers accepted by the login subcommand to the flag
+// that starts their login flow.
+var loginSubcommandFlags = map[string]string{
+	"antigravity":  "-antigravity-login",
+	"claude":       "-claude-login",
+	"codex":        "-codex-login",
+	"codex-device": "-codex-device-login",
+	"kimi":         "-kimi-login",
+	"xai":          "-xai-login",
+}
+
+// LoginSubcommandArgs translates the arguments of `login <provider> [flags...]` into the
+// equivalent flag arguments, so `login antigravity --no-browser` runs exactly like
+// `-antigravity-login -no-browser`.
+func LoginSubcommandArgs(args []string) ([]string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return nil, fmt.Errorf("usage: login <provider> [flags], providers: %s", loginSubcommandProviders())
+	}
+	loginFlag, ok := loginSubcommandFlags[strings.ToLower(strings.TrimSpace(args[0]))]
+	if !ok {
+		return nil, fmt.Errorf("unknown login provider %q, providers: %s", args[0], loginSubcommandProviders())
+	}
+	return append([]string{loginFlag}, args[1:]...), nil
+}
+
+func loginSubcommandProviders() string {
+	providers := make([]string, 0, len(loginSubcommandFlags))
+	for provider := range loginSubcommandFlags {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return strings.Join(providers, ", ")
+}
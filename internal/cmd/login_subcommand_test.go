@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoginSubcommandArgs(t *testing.T) {
+	got, err := LoginSubcommandArgs([]string{"antigravity", "--no-browser", "-config", "config.yaml"})
+	if err != nil {
+		t.Fatalf("LoginSubcommandArgs() error = %v", err)
+	}
+	want := []string{"-antigravity-login", "--no-browser", "-config", "config.yaml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LoginSubcommandArgs() = %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{nil, {"--no-browser"}, {"gemini"}} {
+		if _, errArgs := LoginSubcommandArgs(args); errArgs == nil {
+			t.Fatalf("LoginSubcommandArgs(%q) error = nil, want an error", args)
+		}
+	}
+}
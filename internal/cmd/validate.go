@@ -0,0 +1,394 @@
+// Package cmd contains CLI helpers. This file implements the "validate" subcommand, which
+// checks a configuration file and the auth files it references without starting the server.
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/sjson"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ValidationSeverityError marks an issue that would break or silently drop configuration.
+	ValidationSeverityError = "error"
+	// ValidationSeverityWarning marks an issue worth fixing that does not prevent startup.
+	ValidationSeverityWarning = "warning"
+)
+
+// ValidationIssue is a single finding of ValidateConfigFile.
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	// Source is the file the issue was found in.
+	Source string `json:"source"`
+	// Location points at the offending entry inside Source, e.g. "payload.override[0].params.x".
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport summarises the validation of a configuration file and its auth files.
+type ValidationReport struct {
+	ConfigPath string            `json:"config"`
+	AuthDir    string            `json:"auth_dir,omitempty"`
+	AuthFiles  int               `json:"auth_files"`
+	Errors     int               `json:"errors"`
+	Warnings   int               `json:"warnings"`
+	Issues     []ValidationIssue `json:"issues"`
+}
+
+// OK reports whether the report contains no errors. Warnings are allowed.
+func (r *ValidationReport) OK() bool {
+	return r != nil && r.Errors == 0
+}
+
+func (r *ValidationReport) add(severity, source, location, format string, args ...any) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity: severity,
+		Source:   source,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == ValidationSeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// WriteText renders the report in a human readable form.
+func (r *ValidationReport) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "config: %s\n", r.ConfigPath)
+	if r.AuthDir != "" {
+		fmt.Fprintf(&buf, "auth dir: %s (%d files)\n", r.AuthDir, r.AuthFiles)
+	}
+	for _, issue := range r.Issues {
+		where := issue.Source
+		if issue.Location != "" {
+			where += ": " + issue.Location
+		}
+		fmt.Fprintf(&buf, "%-7s %s: %s\n", issue.Severity, where, issue.Message)
+	}
+	fmt.Fprintf(&buf, "result: %d errors, %d warnings\n", r.Errors, r.Warnings)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// DoValidate runs the "validate" subcommand with args (the arguments after "validate")
+// and returns the process exit code: 0 when no errors were found, 1 on validation
+// errors and 2 on usage errors.
+func DoValidate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var configPath string
+	var asJSON bool
+	flags.StringVar(&configPath, "config", "", "Configure File Path (default config.yaml in the working directory)")
+	flags.BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	if errParse := flags.Parse(args); errParse != nil {
+		return 2
+	}
+	if strings.TrimSpace(configPath) == "" {
+		wd, errWd := os.Getwd()
+		if errWd != nil {
+			_, _ = fmt.Fprintf(stderr, "validate: failed to get working directory: %v\n", errWd)
+			return 2
+		}
+		configPath = filepath.Join(wd, "config.yaml")
+	}
+
+	report := ValidateConfigFile(configPath, time.Now())
+	var errWrite error
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		errWrite = encoder.Encode(report)
+	} else {
+		errWrite = report.WriteText(stdout)
+	}
+	if errWrite != nil {
+		_, _ = fmt.Fprintf(stderr, "validate: failed to write report: %v\n", errWrite)
+		return 2
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// ValidateConfigFile loads configPath through the same parsing path the server uses, without
+// writing anything back, and checks payload rules, TLS files and every auth file under
+// auth-dir. now is used to evaluate auth expiry timestamps.
+func ValidateConfigFile(configPath string, now time.Time) *ValidationReport {
+	report := &ValidationReport{ConfigPath: configPath, Issues: []ValidationIssue{}}
+
+	data, errRead := os.ReadFile(configPath)
+	if errRead != nil {
+		report.add(ValidationSeverityError, configPath, "", "cannot read config file: %v", errRead)
+		return report
+	}
+	cfg, errParse := config.ParseConfigBytes(data)
+	if errParse != nil {
+		report.add(ValidationSeverityError, configPath, "", "%v", errParse)
+		return report
+	}
+
+	// The server drops invalid raw payload rules while loading, so inspect them as written.
+	var raw struct {
+		Payload config.PayloadConfig `yaml:"payload"`
+	}
+	if errUnmarshal := yaml.Unmarshal(data, &raw); errUnmarshal == nil {
+		validatePayloadConfig(report, configPath, raw.Payload)
+	}
+
+	if cfg.TLS.Enable {
+		validateReferencedFile(report, configPath, "tls.cert", cfg.TLS.Cert)
+		validateReferencedFile(report, configPath, "tls.key", cfg.TLS.Key)
+	}
+
+	validateAuthDir(report, configPath, cfg.AuthDir, now)
+	return report
+}
+
+func validateReferencedFile(report *ValidationReport, source, location, path string) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		report.add(ValidationSeverityError, source, location, "path is empty")
+		return
+	}
+	info, errStat := os.Stat(path)
+	if errStat != nil {
+		report.add(ValidationSeverityError, source, location, "file %s does not exist or is not readable: %v", path, errStat)
+		return
+	}
+	if info.IsDir() {
+		report.add(ValidationSeverityError, source, location, "%s is a directory, not a file", path)
+	}
+}
+
+var validPayloadProtocols = map[string]struct{}{
+	string(sdktranslator.FormatOpenAI):         {},
+	string(sdktranslator.FormatOpenAIResponse): {},
+	string(sdktranslator.FormatClaude):         {},
+	string(sdktranslator.FormatGemini):         {},
+	string(sdktranslator.FormatCodex):          {},
+	string(sdktranslator.FormatAntigravity):    {},
+	string(sdktranslator.FormatInteractions):   {},
+}
+
+var validPayloadFromProtocols = map[string]struct{}{
+	string(sdktranslator.FormatOpenAI):       {},
+	"responses":                              {},
+	"openai-response":                        {},
+	"openai-responses":                       {},
+	"response":                               {},
+	string(sdktranslator.FormatClaude):       {},
+	string(sdktranslator.FormatGemini):       {},
+	string(sdktranslator.FormatCodex):        {},
+	string(sdktranslator.FormatAntigravity):  {},
+	string(sdktranslator.FormatInteractions): {},
+}
+
+func validatePayloadConfig(report *ValidationReport, source string, payload config.PayloadConfig) {
+	sections := []struct {
+		name  string
+		rules []config.PayloadRule
+		raw   bool
+	}{
+		{"default", payload.Default, false},
+		{"default-raw", payload.DefaultRaw, true},
+		{"override", payload.Override, false},
+		{"override-raw", payload.OverrideRaw, true},
+	}
+	for _, section := range sections {
+		for i, rule := range section.rules {
+			location := fmt.Sprintf("payload.%s[%d]", section.name, i)
+			validatePayloadTargets(report, source, location, rule.Models, rule.Providers)
+			if len(rule.Params) == 0 {
+				report.add(ValidationSeverityWarning, source, location+".params", "rule has no params")
+			}
+			for _, path := range sortedParamPaths(rule.Params) {
+				paramLocation := location + ".params." + path
+				if errPath := validatePayloadPath(path); errPath != nil {
+					report.add(ValidationSeverityError, source, paramLocation, "%v", errPath)
+					continue
+				}
+				if !section.raw {
+					continue
+				}
+				rawValue, isString := rule.Params[path].(string)
+				if !isString {
+					continue
+				}
+				if trimmed := strings.TrimSpace(rawValue); trimmed == "" || !json.Valid([]byte(trimmed)) {
+					report.add(ValidationSeverityError, source, paramLocation, "value is not valid JSON; the server drops this rule")
+				}
+			}
+		}
+	}
+	for i, rule := range payload.Filter {
+		location := fmt.Sprintf("payload.filter[%d]", i)
+		validatePayloadTargets(report, source, location, rule.Models, rule.Providers)
+		if len(rule.Params) == 0 {
+			report.add(ValidationSeverityWarning, source, location+".params", "rule has no params")
+		}
+		for j, path := range rule.Params {
+			if errPath := validatePayloadPath(path); errPath != nil {
+				report.add(ValidationSeverityError, source, fmt.Sprintf("%s.params[%d]", location, j), "%v", errPath)
+			}
+		}
+	}
+}
+
+func validatePayloadTargets(report *ValidationReport, source, location string, models []config.PayloadModelRule, providers []string) {
+	if len(models) == 0 && len(providers) == 0 {
+		report.add(ValidationSeverityError, source, location, "rule has neither models nor providers and never matches")
+	}
+	for i, model := range models {
+		modelLocation := fmt.Sprintf("%s.models[%d]", location, i)
+		name := strings.TrimSpace(model.Name)
+		if name == "" {
+			report.add(ValidationSeverityError, source, modelLocation+".name", "model name is empty and never matches")
+		} else if strings.ContainsAny(name, "?[]^$()|\\+{}") {
+			report.add(ValidationSeverityError, source, modelLocation+".name", "invalid model pattern %q: only '*' wildcards are supported", name)
+		}
+		if protocol := strings.ToLower(strings.TrimSpace(model.Protocol)); protocol != "" {
+			if _, ok := validPayloadProtocols[protocol]; !ok {
+				report.add(ValidationSeverityError, source, modelLocation+".protocol", "unknown protocol %q", model.Protocol)
+			}
+		}
+		if fromProtocol := strings.ToLower(strings.TrimSpace(model.FromProtocol)); fromProtocol != "" {
+			if _, ok := validPayloadFromProtocols[fromProtocol]; !ok {
+				report.add(ValidationSeverityError, source, modelLocation+".from-protocol", "unknown protocol %q", model.FromProtocol)
+			}
+		}
+	}
+}
+
+// validatePayloadPath reports whether sjson can write and delete path. Query syntax such as
+// wildcards, "#(...)" filters or modifiers is only understood by gjson.
+func validatePayloadPath(path string) error {
+	if strings.TrimSpace(path) == "" {
+		return errors.New("JSON path is empty")
+	}
+	if _, errSet := sjson.SetBytes([]byte(`{}`), path, true); errSet != nil {
+		return fmt.Errorf("JSON path %q cannot be set: %v", path, errSet)
+	}
+	if _, errDelete := sjson.DeleteBytes([]byte(`{}`), path); errDelete != nil {
+		return fmt.Errorf("JSON path %q uses query syntax that sjson cannot write", path)
+	}
+	return nil
+}
+
+func sortedParamPaths(params map[string]any) []string {
+	paths := make([]string, 0, len(params))
+	for path := range params {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func validateAuthDir(report *ValidationReport, source, authDir string, now time.Time) {
+	resolved, errResolve := util.ResolveAuthDir(authDir)
+	if errResolve != nil {
+		report.add(ValidationSeverityError, source, "auth-dir", "%v", errResolve)
+		return
+	}
+	report.AuthDir = resolved
+	info, errStat := os.Stat(resolved)
+	if errStat != nil {
+		if errors.Is(errStat, fs.ErrNotExist) {
+			report.add(ValidationSeverityWarning, source, "auth-dir", "directory %s does not exist; it is created on startup", resolved)
+			return
+		}
+		report.add(ValidationSeverityError, source, "auth-dir", "cannot access %s: %v", resolved, errStat)
+		return
+	}
+	if !info.IsDir() {
+		report.add(ValidationSeverityError, source, "auth-dir", "%s is not a directory", resolved)
+		return
+	}
+
+	errWalk := filepath.WalkDir(resolved, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			report.add(ValidationSeverityError, path, "", "cannot read: %v", walkErr)
+			return nil
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
+			return nil
+		}
+		report.AuthFiles++
+		validateAuthFile(report, path, now)
+		return nil
+	})
+	if errWalk != nil {
+		report.add(ValidationSeverityError, source, "auth-dir", "cannot walk %s: %v", resolved, errWalk)
+	}
+}
+
+func validateAuthFile(report *ValidationReport, path string, now time.Time) {
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		report.add(ValidationSeverityError, path, "", "cannot read auth file: %v", errRead)
+		return
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		report.add(ValidationSeverityWarning, path, "", "auth file is empty and is ignored")
+		return
+	}
+	metadata := make(map[string]any)
+	if errUnmarshal := json.Unmarshal(data, &metadata); errUnmarshal != nil {
+		report.add(ValidationSeverityError, path, "", "auth file is not a JSON object: %v", errUnmarshal)
+		return
+	}
+	provider, _ := metadata["type"].(string)
+	if strings.TrimSpace(provider) == "" {
+		report.add(ValidationSeverityWarning, path, "type", "auth file has no provider type and is loaded as \"unknown\"")
+	}
+	if disabled, _ := metadata["disabled"].(bool); disabled {
+		return
+	}
+
+	auth := &coreauth.Auth{Metadata: metadata}
+	expiresAt, hasExpiry := auth.ExpirationTime()
+	if !hasExpiry {
+		if key, value, present := authExpiryValue(metadata); present {
+			report.add(ValidationSeverityError, path, key, "cannot parse expiry timestamp %v", value)
+		}
+		return
+	}
+	if !expiresAt.Before(now) {
+		return
+	}
+	if refreshToken, _ := metadata["refresh_token"].(string); strings.TrimSpace(refreshToken) != "" {
+		report.add(ValidationSeverityWarning, path, "", "token expired at %s; it is refreshed on first use", expiresAt.UTC().Format(time.RFC3339))
+		return
+	}
+	report.add(ValidationSeverityWarning, path, "", "token expired at %s and the file has no refresh_token", expiresAt.UTC().Format(time.RFC3339))
+}
+
+// authExpiryValue returns the first top-level expiry field present in metadata, matching
+// the keys inspected by Auth.ExpirationTime.
+func authExpiryValue(metadata map[string]any) (string, any, bool) {
+	for _, key := range []string{"expired", "expire", "expires_at", "expiresAt", "expiry", "expires"} {
+		if value, ok := metadata[key]; ok && value != nil && value != "" {
+			return key, value, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var validateTestNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func findValidationIssue(report *ValidationReport, severity, location, message string) bool {
+	for _, issue := range report.Issues {
+		if issue.Severity == severity && issue.Location == location && strings.Contains(issue.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateConfigFileGoodFixture(t *testing.T) {
+	report := ValidateConfigFile(filepath.Join("testdata", "validate", "good.yaml"), validateTestNow)
+	if !report.OK() || report.Warnings != 0 {
+		t.Fatalf("expected a clean report, got %+v", report.Issues)
+	}
+	if report.AuthFiles != 1 {
+		t.Fatalf("AuthFiles = %d, want 1", report.AuthFiles)
+	}
+}
+
+func TestValidateConfigFileBrokenFixture(t *testing.T) {
+	report := ValidateConfigFile(filepath.Join("testdata", "validate", "broken.yaml"), validateTestNow)
+	configPath := filepath.Join("testdata", "validate", "broken.yaml")
+
+	wantErrors := []struct {
+		location string
+		message  string
+	}{
+		{"payload.default[0].models[0].name", `invalid model pattern "gemini-(pro|flash)": only '*' wildcards are supported`},
+		{"payload.default[0].models[0].protocol", `unknown protocol "gemeni"`},
+		{"payload.override[0].models[0].from-protocol", `unknown protocol "chat"`},
+		{`payload.override[0].params.tools.#(type=="web_search").enabled`, "uses query syntax that sjson cannot write"},
+		{"payload.default-raw[0].params.metadata", "value is not valid JSON; the server drops this rule"},
+		{"payload.filter[0]", "rule has neither models nor providers and never matches"},
+		{"payload.filter[0].params[0]", `JSON path "messages.*.cache_control" uses query syntax`},
+		{"tls.cert", "does not exist"},
+		{"tls.key", "does not exist"},
+	}
+	for _, want := range wantErrors {
+		if !findValidationIssue(report, ValidationSeverityError, want.location, want.message) {
+			t.Errorf("missing error at %s containing %q; issues: %+v", want.location, want.message, report.Issues)
+		}
+	}
+	for _, issue := range report.Issues {
+		if strings.HasPrefix(issue.Location, "payload.") && issue.Source != configPath {
+			t.Errorf("payload issue source = %q, want %q", issue.Source, configPath)
+		}
+	}
+
+	authDir := filepath.Join("testdata", "validate", "broken-auths")
+	if report.AuthFiles != 3 {
+		t.Fatalf("AuthFiles = %d, want 3", report.AuthFiles)
+	}
+	wantAuth := []struct {
+		file     string
+		severity string
+		location string
+		message  string
+	}{
+		{"truncated.json", ValidationSeverityError, "", "auth file is not a JSON object"},
+		{"kimi-bad-expiry.json", ValidationSeverityError, "expired", "cannot parse expiry timestamp next tuesday"},
+		{"claude-expired.json", ValidationSeverityWarning, "", "token expired at 2020-01-01T00:00:00Z and the file has no refresh_token"},
+	}
+	for _, want := range wantAuth {
+		found := false
+		for _, issue := range report.Issues {
+			if issue.Source == filepath.Join(authDir, want.file) && issue.Severity == want.severity && issue.Location == want.location && strings.Contains(issue.Message, want.message) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing %s for %s containing %q; issues: %+v", want.severity, want.file, want.message, report.Issues)
+		}
+	}
+}
+
+func TestValidateConfigFileInvalidYAML(t *testing.T) {
+	report := ValidateConfigFile(filepath.Join("testdata", "validate", "invalid-yaml.yaml"), validateTestNow)
+	if report.OK() || !findValidationIssue(report, ValidationSeverityError, "", "parse config payload") {
+		t.Fatalf("expected a parse error, got %+v", report.Issues)
+	}
+}
+
+func TestValidateConfigFileMissingFile(t *testing.T) {
+	report := ValidateConfigFile(filepath.Join("testdata", "validate", "absent.yaml"), validateTestNow)
+	if report.OK() || !findValidationIssue(report, ValidationSeverityError, "", "cannot read config file") {
+		t.Fatalf("expected a read error, got %+v", report.Issues)
+	}
+}
+
+func TestDoValidateExitCodesAndJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := DoValidate([]string{"--config", filepath.Join("testdata", "validate", "good.yaml")}, &stdout, &stderr); code != 0 {
+		t.Fatalf("good config exit code = %d, want 0; output:\n%s", code, stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "result: 0 errors, 0 warnings") {
+		t.Fatalf("text report missing summary:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := DoValidate([]string{"--config", filepath.Join("testdata", "validate", "broken.yaml"), "--json"}, &stdout, &stderr); code != 1 {
+		t.Fatalf("broken config exit code = %d, want 1", code)
+	}
+	var report ValidationReport
+	if errUnmarshal := json.Unmarshal(stdout.Bytes(), &report); errUnmarshal != nil {
+		t.Fatalf("json report: %v\n%s", errUnmarshal, stdout.String())
+	}
+	if report.Errors == 0 || report.Warnings == 0 || len(report.Issues) != report.Errors+report.Warnings {
+		t.Fatalf("unexpected json report counts: %+v", report)
+	}
+
+	if code := DoValidate([]string{"--unknown"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("usage error exit code = %d, want 2", code)
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIKeyRoute restricts the credentials the requests of one client API key may use.
+type APIKeyRoute struct {
+	// APIKey is the client API key the route applies to.
+	APIKey string `yaml:"api-key" json:"-"`
+	// Auths lists the auth IDs the key may use. Entries are either a bare auth ID or an
+	// object with an id and a weight.
+	Auths []WeightedAuth `yaml:"auths" json:"auths"`
+	// FallbackToPool lets requests use the other credentials when none of the listed
+	// auths is available.
+	FallbackToPool bool `yaml:"fallback-to-pool,omitempty" json:"fallback-to-pool,omitempty"`
+}
+
+// WeightedAuth is an auth ID with its share of the traffic of an APIKeyRoute.
+type WeightedAuth struct {
+	ID string `yaml:"id" json:"id"`
+	// Weight is relative to the other auths of the route. 0 or less counts as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+}
+
+// UnmarshalYAML accepts a bare auth ID as well as an {id, weight} mapping.
+func (w *WeightedAuth) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*w = WeightedAuth{ID: strings.TrimSpace(value.Value)}
+		return nil
+	}
+	type plain WeightedAuth
+	var out plain
+	if errDecode := value.Decode(&out); errDecode != nil {
+		return errDecode
+	}
+	*w = WeightedAuth(out)
+	w.ID = strings.TrimSpace(w.ID)
+	return nil
+}
+
+// UnmarshalJSON accepts a bare auth ID as well as an {id, weight} object.
+func (w *WeightedAuth) UnmarshalJSON(data []byte) error {
+	var id string
+	if errID := json.Unmarshal(data, &id); errID == nil {
+		*w = WeightedAuth{ID: strings.TrimSpace(id)}
+		return nil
+	}
+	type plain WeightedAuth
+	var out plain
+	if errDecode := json.Unmarshal(data, &out); errDecode != nil {
+		return errDecode
+	}
+	*w = WeightedAuth(out)
+	w.ID = strings.TrimSpace(w.ID)
+	return nil
+}
+
+// EffectiveWeight returns the weight used for selection.
+func (w WeightedAuth) EffectiveWeight() int {
+	if w.Weight <= 0 {
+		return 1
+	}
+	return w.Weight
+}
+
+// Weighted reports whether any auth of the route has an explicit weight. Routes without
+// weights leave the choice among their auths to the routing strategy.
+func (r APIKeyRoute) Weighted() bool {
+	for _, auth := range r.Auths {
+		if auth.Weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRoute returns the route configured for the client API key, or nil.
+func (c RoutingConfig) APIKeyRoute(apiKey string) *APIKeyRoute {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return nil
+	}
+	for i := range c.APIKeyRoutes {
+		route := &c.APIKeyRoutes[i]
+		if strings.TrimSpace(route.APIKey) == apiKey && len(route.Auths) > 0 {
+			return route
+		}
+	}
+	return nil
+}
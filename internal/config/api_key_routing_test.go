@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestAPIKeyRoutes_UnmarshalMixedAuths(t *testing.T) {
+	var cfg Config
+	src := `
+routing:
+  api-key-routes:
+    - api-key: team-a
+      auths: ["auth-1", "auth-2"]
+    - api-key: team-b
+      auths:
+        - id: auth-3
+          weight: 70
+        - auth-4
+      fallback-to-pool: true
+`
+	if err := yaml.Unmarshal([]byte(src), &cfg); err != nil {
+		t.Fatalf("unmarshal yaml: %v", err)
+	}
+	want := []APIKeyRoute{
+		{APIKey: "team-a", Auths: []WeightedAuth{{ID: "auth-1"}, {ID: "auth-2"}}},
+		{APIKey: "team-b", Auths: []WeightedAuth{{ID: "auth-3", Weight: 70}, {ID: "auth-4"}}, FallbackToPool: true},
+	}
+	if !reflect.DeepEqual(cfg.Routing.APIKeyRoutes, want) {
+		t.Fatalf("api-key-routes = %+v, want %+v", cfg.Routing.APIKeyRoutes, want)
+	}
+	if cfg.Routing.APIKeyRoute("team-a").Weighted() || !cfg.Routing.APIKeyRoute("team-b").Weighted() {
+		t.Fatalf("Weighted() should only be true for team-b")
+	}
+	if cfg.Routing.APIKeyRoute("team-c") != nil {
+		t.Fatalf("APIKeyRoute(team-c) should be nil")
+	}
+
+	var auths []WeightedAuth
+	if err := json.Unmarshal([]byte(`["auth-1",{"id":"auth-2","weight":3}]`), &auths); err != nil {
+		t.Fatalf("unmarshal json: %v", err)
+	}
+	if !reflect.DeepEqual(auths, []WeightedAuth{{ID: "auth-1"}, {ID: "auth-2", Weight: 3}}) {
+		t.Fatalf("json auths = %+v", auths)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
 	sdkpluginstore "github.com/router-for-me/CLIProxyAPI/v7/sdk/pluginstore"
@@ -38,6 +39,9 @@ type Config struct {
 	// TLS config controls HTTPS server settings.
 	TLS TLSConfig `yaml:"tls" json:"tls"`
 
+	// Server config controls inbound request and buffered upstream response size limits.
+	Server ServerConfig `yaml:"server" json:"server"`
+
 	// Home config is runtime-only and is populated from -home-jwt.
 	Home HomeConfig `yaml:"-" json:"-"`
 
@@ -56,12 +60,21 @@ type Config struct {
 	// AuthDir is the directory where authentication token files are stored.
 	AuthDir string `yaml:"auth-dir" json:"-"`
 
+	// SecretReferences enables and restricts secret references such as env://NAME.
+	SecretReferences SecretReferencesConfig `yaml:"secret-references,omitempty" json:"-"`
+
 	// Debug enables or disables debug-level logging and other debug features.
 	Debug bool `yaml:"debug" json:"debug"`
 
 	// Pprof config controls the optional pprof HTTP debug server.
 	Pprof PprofConfig `yaml:"pprof" json:"pprof"`
 
+	// Metrics config controls the optional Prometheus /metrics endpoint.
+	Metrics MetricsConfig `yaml:"metrics" json:"metrics"`
+
+	// Tracing config controls the optional OpenTelemetry traces exported over OTLP/HTTP.
+	Tracing TracingConfig `yaml:"tracing" json:"tracing"`
+
 	// CommercialMode disables high-overhead request logging and HTTP middleware features to minimize per-request memory usage.
 	CommercialMode bool `yaml:"commercial-mode" json:"commercial-mode"`
 
@@ -84,6 +97,12 @@ type Config struct {
 	// Default: 60. Max: 3600.
 	RedisUsageQueueRetentionSeconds int `yaml:"redis-usage-queue-retention-seconds" json:"redis-usage-queue-retention-seconds"`
 
+	// RequestStore configures the optional persistent request log store queried via the Management API.
+	RequestStore RequestStoreConfig `yaml:"request-store" json:"request-store"`
+
+	// TokenQuota enforces daily and monthly token budgets per credential.
+	TokenQuota TokenQuotaConfig `yaml:"token-quota,omitempty" json:"token-quota,omitempty"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
@@ -106,6 +125,28 @@ type Config struct {
 	// MaxRetryInterval defines the maximum wait time in seconds before retrying a cooled-down credential.
 	MaxRetryInterval int `yaml:"max-retry-interval" json:"max-retry-interval"`
 
+	// RequestHedging sends a second copy of slow non-streaming requests to another credential.
+	RequestHedging RequestHedgingConfig `yaml:"request-hedging" json:"request-hedging"`
+
+	// ModelTimeouts bounds how long requests for matching models may run.
+	ModelTimeouts []ModelTimeout `yaml:"model-timeouts,omitempty" json:"model-timeouts,omitempty"`
+
+	// Retry retries transient upstream failures on the same credential with backoff.
+	Retry UpstreamRetryConfig `yaml:"retry" json:"retry"`
+
+	// Shadow mirrors a sample of non-streaming requests to a second model for evaluation.
+	Shadow ShadowConfig `yaml:"shadow" json:"shadow"`
+
+	// Cache serves repeated identical non-streaming requests from memory.
+	Cache ResponseCacheConfig `yaml:"cache" json:"cache"`
+
+	// ResponseRedaction scrubs matching text from responses of every provider and format.
+	ResponseRedaction []ResponseRedactionRule `yaml:"response-redaction,omitempty" json:"response-redaction,omitempty"`
+
+	// Providers holds per-provider upstream HTTP client settings and model policies keyed
+	// by provider name (e.g. "gemini", "antigravity", "openai-compatibility").
+	Providers map[string]ProviderHTTPConfig `yaml:"providers,omitempty" json:"providers,omitempty"`
+
 	// QuotaExceeded defines the behavior when a quota is exceeded.
 	QuotaExceeded QuotaExceeded `yaml:"quota-exceeded" json:"quota-exceeded"`
 
@@ -122,6 +163,13 @@ type Config struct {
 
 	AntigravitySignatureBypassStrict *bool `yaml:"antigravity-signature-bypass-strict,omitempty" json:"antigravity-signature-bypass-strict,omitempty"`
 
+	// AntigravityBaseURLStrategy picks the base URL an Antigravity request starts from:
+	// "fixed" (default) always starts with the first URL of the fallback chain, "round_robin"
+	// rotates the start per request for each auth, and "least_errors" starts from the URL with
+	// the fewest recent failures. The full fallback chain is tried either way. An auth's
+	// base_url_strategy attribute overrides it.
+	AntigravityBaseURLStrategy string `yaml:"antigravity-base-url-strategy,omitempty" json:"antigravity-base-url-strategy,omitempty"`
+
 	// GeminiKey defines Gemini API key configurations with optional routing overrides.
 	GeminiKey []GeminiKey `yaml:"gemini-api-key" json:"gemini-api-key"`
 
@@ -303,6 +351,103 @@ type TLSConfig struct {
 	Key string `yaml:"key" json:"key"`
 }
 
+const (
+	// DefaultMaxRequestBodyBytes is the inbound request body limit used when none is configured.
+	DefaultMaxRequestBodyBytes int64 = 32 << 20
+	// DefaultMaxResponseBodyBytes is the buffered non-streaming upstream response limit used when none is configured.
+	DefaultMaxResponseBodyBytes int64 = 64 << 20
+	// DefaultShutdownDrainSeconds is how long shutdown waits for active streams when none is configured.
+	DefaultShutdownDrainSeconds = 30
+)
+
+// ServerConfig holds HTTP body size limits and shutdown behavior.
+type ServerConfig struct {
+	// MaxRequestBodyBytes caps inbound request bodies; larger requests are rejected with 413.
+	// 0 uses DefaultMaxRequestBodyBytes and a negative value disables the limit.
+	MaxRequestBodyBytes int64 `yaml:"max-request-body-bytes" json:"max-request-body-bytes"`
+	// MaxResponseBodyBytes caps buffered non-streaming upstream responses; larger responses fail with 502.
+	// 0 uses DefaultMaxResponseBodyBytes and a negative value disables the limit.
+	MaxResponseBodyBytes int64 `yaml:"max-response-body-bytes" json:"max-response-body-bytes"`
+	// ShutdownDrainSeconds is how long shutdown lets active streams finish before cancelling them.
+	// 0 uses DefaultShutdownDrainSeconds and a negative value cancels streams immediately.
+	ShutdownDrainSeconds int `yaml:"shutdown-drain-seconds" json:"shutdown-drain-seconds"`
+	// ExposeAuthHeader sets X-CLIProxy-Auth-Label and X-CLIProxy-Provider response headers
+	// naming the credential that served the request.
+	ExposeAuthHeader bool `yaml:"expose-auth-header,omitempty" json:"expose-auth-header,omitempty"`
+	// DebugTransforms echoes the request shaping trace (matched payload rules, thinking
+	// normalization, schema cleaning, cloaking) to clients sending X-CLIProxy-Debug: transforms.
+	DebugTransforms bool `yaml:"debug-transforms,omitempty" json:"debug-transforms,omitempty"`
+	// TrustedProxies lists proxy addresses or CIDR blocks allowed to report the client IP through
+	// X-Forwarded-For. Requests from any other peer are attributed to the peer address itself.
+	TrustedProxies []string `yaml:"trusted-proxies,omitempty" json:"trusted-proxies,omitempty"`
+	// Compression compresses non-streaming responses for clients that accept gzip or zstd.
+	Compression ResponseCompressionConfig `yaml:"compression,omitempty" json:"compression,omitempty"`
+}
+
+// DefaultResponseCompressionMinBytes is the smallest response compressed when no threshold is configured.
+const DefaultResponseCompressionMinBytes = 1024
+
+// ResponseCompressionConfig configures gzip and zstd compression of responses, negotiated
+// from the client's Accept-Encoding. Server-sent event streams and flushed responses are
+// never compressed.
+type ResponseCompressionConfig struct {
+	// Enable turns on response compression.
+	Enable bool `yaml:"enable" json:"enable"`
+	// MinBytes is the smallest body that is compressed. 0 uses DefaultResponseCompressionMinBytes.
+	MinBytes int `yaml:"min-bytes,omitempty" json:"min-bytes,omitempty"`
+	// ContentTypes lists the media types that may be compressed. Empty allows application/json.
+	ContentTypes []string `yaml:"content-types,omitempty" json:"content-types,omitempty"`
+}
+
+// EffectiveMinBytes returns the compression threshold.
+func (c ResponseCompressionConfig) EffectiveMinBytes() int {
+	if c.MinBytes <= 0 {
+		return DefaultResponseCompressionMinBytes
+	}
+	return c.MinBytes
+}
+
+// EffectiveContentTypes returns the media types that may be compressed.
+func (c ResponseCompressionConfig) EffectiveContentTypes() []string {
+	if len(c.ContentTypes) == 0 {
+		return []string{"application/json"}
+	}
+	return c.ContentTypes
+}
+
+// EffectiveMaxRequestBodyBytes returns the inbound body limit, or 0 when the limit is disabled.
+func (c ServerConfig) EffectiveMaxRequestBodyBytes() int64 {
+	return effectiveBodyLimit(c.MaxRequestBodyBytes, DefaultMaxRequestBodyBytes)
+}
+
+// EffectiveMaxResponseBodyBytes returns the buffered upstream response limit, or 0 when the limit is disabled.
+func (c ServerConfig) EffectiveMaxResponseBodyBytes() int64 {
+	return effectiveBodyLimit(c.MaxResponseBodyBytes, DefaultMaxResponseBodyBytes)
+}
+
+// EffectiveShutdownDrainTimeout returns how long shutdown waits for active streams.
+func (c ServerConfig) EffectiveShutdownDrainTimeout() time.Duration {
+	switch {
+	case c.ShutdownDrainSeconds < 0:
+		return 0
+	case c.ShutdownDrainSeconds == 0:
+		return DefaultShutdownDrainSeconds * time.Second
+	default:
+		return time.Duration(c.ShutdownDrainSeconds) * time.Second
+	}
+}
+
+func effectiveBodyLimit(value, fallback int64) int64 {
+	switch {
+	case value < 0:
+		return 0
+	case value == 0:
+		return fallback
+	default:
+		return value
+	}
+}
+
 // PprofConfig holds pprof HTTP server settings.
 type PprofConfig struct {
 	// Enable toggles the pprof HTTP debug server.
@@ -311,6 +456,12 @@ type PprofConfig struct {
 	Addr string `yaml:"addr" json:"addr"`
 }
 
+// MetricsConfig holds Prometheus metrics endpoint settings.
+type MetricsConfig struct {
+	// Enable exposes request, token, stream and credential metrics at /metrics.
+	Enable bool `yaml:"enable" json:"enable"`
+}
+
 // RemoteManagement holds management API configuration under 'remote-management'.
 type RemoteManagement struct {
 	// AllowRemote toggles remote (non-localhost) access to management API.
@@ -325,6 +476,11 @@ type RemoteManagement struct {
 	// PanelGitHubRepository overrides the GitHub repository used to fetch the management panel asset.
 	// Accepts either a repository URL (https://github.com/org/repo) or an API releases endpoint.
 	PanelGitHubRepository string `yaml:"panel-github-repository"`
+	// AllowedCIDRs restricts management routes to client addresses inside these CIDR blocks or
+	// addresses. Other clients receive 403 before the management key is checked. Empty allows all.
+	AllowedCIDRs []string `yaml:"allowed-cidrs,omitempty"`
+	// EnableStatusUI serves the embedded status page under /v0/ui/, guarded by the management key.
+	EnableStatusUI bool `yaml:"enable-status-ui"`
 }
 
 // QuotaExceeded defines the behavior when API quota limits are exceeded.
@@ -342,6 +498,162 @@ type QuotaExceeded struct {
 	AntigravityCredits bool `yaml:"antigravity-credits" json:"antigravity-credits"`
 }
 
+// DefaultRequestHedgingDelaySeconds is the hedging delay used when none is configured.
+const DefaultRequestHedgingDelaySeconds = 5
+
+// RequestHedgingConfig configures request hedging for non-streaming requests.
+// When a request has not completed after the delay, the same request is sent
+// with a second credential of the same provider and the first response wins.
+// Only requests carrying an Idempotency-Key header are hedged.
+type RequestHedgingConfig struct {
+	// Enabled turns request hedging on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// DelaySeconds is how long to wait for the first attempt before hedging.
+	// 0 uses DefaultRequestHedgingDelaySeconds.
+	DelaySeconds int `yaml:"delay-seconds,omitempty" json:"delay-seconds,omitempty"`
+	// Providers limits hedging to these providers. Empty hedges every provider.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// Delay returns the configured hedging delay.
+func (c RequestHedgingConfig) Delay() time.Duration {
+	if c.DelaySeconds <= 0 {
+		return DefaultRequestHedgingDelaySeconds * time.Second
+	}
+	return time.Duration(c.DelaySeconds) * time.Second
+}
+
+// ModelTimeout limits the duration of requests for models matching Name.
+// Name supports the '*' wildcard of payload rules; the first matching entry wins
+// and overrides the model's registry default.
+type ModelTimeout struct {
+	// Name is the model name or wildcard pattern, matched against the requested model
+	// and then the routed model.
+	Name string `yaml:"name" json:"name"`
+	// TimeoutSeconds is the hard deadline of non-streaming requests. 0 disables it.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+	// StreamMaxDurationSeconds is the maximum total duration of streams.
+	// 0 uses TimeoutSeconds.
+	StreamMaxDurationSeconds int `yaml:"stream-max-duration-seconds,omitempty" json:"stream-max-duration-seconds,omitempty"`
+}
+
+// Timeout returns the non-streaming deadline of the entry.
+func (t ModelTimeout) Timeout() time.Duration {
+	if t.TimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(t.TimeoutSeconds) * time.Second
+}
+
+// StreamMaxDuration returns the maximum stream duration of the entry.
+func (t ModelTimeout) StreamMaxDuration() time.Duration {
+	if t.StreamMaxDurationSeconds <= 0 {
+		return t.Timeout()
+	}
+	return time.Duration(t.StreamMaxDurationSeconds) * time.Second
+}
+
+// Defaults used by UpstreamRetryConfig when a value is not configured.
+const (
+	DefaultUpstreamRetryInitialBackoffMillis = 500
+	DefaultUpstreamRetryMaxBackoffMillis     = 8000
+	DefaultUpstreamRetryBudgetPerMinute      = 10
+)
+
+// UpstreamRetryConfig configures retries of transient upstream failures (HTTP 500, 502,
+// 503, 504 and connection resets) on the same credential. Non-streaming requests and
+// streaming requests that have not sent any data yet are retried; 4xx responses never are.
+type UpstreamRetryConfig struct {
+	// MaxAttempts is the number of attempts per credential, including the first.
+	// 0 or 1 disables retries.
+	MaxAttempts int `yaml:"max-attempts" json:"max-attempts"`
+	// InitialBackoffMillis is the delay before the first retry; it doubles for every further
+	// retry. 0 uses DefaultUpstreamRetryInitialBackoffMillis.
+	InitialBackoffMillis int `yaml:"initial-backoff-ms,omitempty" json:"initial-backoff-ms,omitempty"`
+	// MaxBackoffMillis caps the backoff and the Retry-After delay that is waited for.
+	// 0 uses DefaultUpstreamRetryMaxBackoffMillis.
+	MaxBackoffMillis int `yaml:"max-backoff-ms,omitempty" json:"max-backoff-ms,omitempty"`
+	// BudgetPerMinute is the number of retries one credential may spend per minute.
+	// 0 uses DefaultUpstreamRetryBudgetPerMinute.
+	BudgetPerMinute int `yaml:"budget-per-minute,omitempty" json:"budget-per-minute,omitempty"`
+}
+
+// InitialBackoff returns the configured delay before the first retry.
+func (c UpstreamRetryConfig) InitialBackoff() time.Duration {
+	if c.InitialBackoffMillis <= 0 {
+		return DefaultUpstreamRetryInitialBackoffMillis * time.Millisecond
+	}
+	return time.Duration(c.InitialBackoffMillis) * time.Millisecond
+}
+
+// MaxBackoff returns the configured backoff cap.
+func (c UpstreamRetryConfig) MaxBackoff() time.Duration {
+	if c.MaxBackoffMillis <= 0 {
+		return DefaultUpstreamRetryMaxBackoffMillis * time.Millisecond
+	}
+	return time.Duration(c.MaxBackoffMillis) * time.Millisecond
+}
+
+// Budget returns the number of retries one credential may spend per minute.
+func (c UpstreamRetryConfig) Budget() int {
+	if c.BudgetPerMinute <= 0 {
+		return DefaultUpstreamRetryBudgetPerMinute
+	}
+	return c.BudgetPerMinute
+}
+
+// Defaults used by ResponseCacheConfig when a value is not configured.
+const (
+	DefaultResponseCacheTTLSeconds    = 300
+	DefaultResponseCacheMaxEntries    = 1000
+	DefaultResponseCacheMaxEntryBytes = 1 << 20
+)
+
+// ResponseCacheConfig configures the in-memory cache of non-streaming responses.
+// Identical requests for the same providers and model are answered from the cache
+// until the entry expires. Requests that do not set a temperature of 0 are not cached
+// unless IgnoreTemperature is set, and a Cache-Control: no-cache request header skips
+// the lookup and replaces the cached entry with the fresh response.
+type ResponseCacheConfig struct {
+	// Enabled turns the response cache on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// TTLSeconds is how long a response is served from the cache.
+	// 0 uses DefaultResponseCacheTTLSeconds.
+	TTLSeconds int `yaml:"ttl-seconds,omitempty" json:"ttl-seconds,omitempty"`
+	// MaxEntries bounds the number of cached responses; the least recently used one is
+	// evicted first. 0 uses DefaultResponseCacheMaxEntries.
+	MaxEntries int `yaml:"max-entries,omitempty" json:"max-entries,omitempty"`
+	// MaxEntryBytes is the largest response body that is cached.
+	// 0 uses DefaultResponseCacheMaxEntryBytes.
+	MaxEntryBytes int `yaml:"max-entry-bytes,omitempty" json:"max-entry-bytes,omitempty"`
+	// IgnoreTemperature caches requests regardless of their temperature.
+	IgnoreTemperature bool `yaml:"ignore-temperature,omitempty" json:"ignore-temperature,omitempty"`
+}
+
+// TTL returns how long a cached response stays valid.
+func (c ResponseCacheConfig) TTL() time.Duration {
+	if c.TTLSeconds <= 0 {
+		return DefaultResponseCacheTTLSeconds * time.Second
+	}
+	return time.Duration(c.TTLSeconds) * time.Second
+}
+
+// EntryLimit returns the maximum number of cached responses.
+func (c ResponseCacheConfig) EntryLimit() int {
+	if c.MaxEntries <= 0 {
+		return DefaultResponseCacheMaxEntries
+	}
+	return c.MaxEntries
+}
+
+// EntryBytesLimit returns the largest response body that is cached.
+func (c ResponseCacheConfig) EntryBytesLimit() int {
+	if c.MaxEntryBytes <= 0 {
+		return DefaultResponseCacheMaxEntryBytes
+	}
+	return c.MaxEntryBytes
+}
+
 // RoutingConfig configures how credentials are selected for requests.
 type RoutingConfig struct {
 	// Strategy selects the credential selection strategy.
@@ -351,13 +663,21 @@ type RoutingConfig struct {
 	// SessionAffinity enables universal session-sticky routing for all clients.
 	// Session IDs are extracted from multiple sources:
 	// metadata.user_id (Claude Code session format), X-Session-ID, Session_id (Codex),
-	// X-Client-Request-Id (PI), metadata.user_id, conversation_id, or message hash.
+	// X-Client-Request-Id (PI), metadata.user_id, conversation_id, session_id,
+	// Idempotency-Key, or message hash.
 	// Automatic failover is always enabled when bound auth becomes unavailable.
 	SessionAffinity bool `yaml:"session-affinity,omitempty" json:"session-affinity,omitempty"`
 
 	// SessionAffinityTTL specifies how long session-to-auth bindings are retained.
 	// Default: 1h. Accepts duration strings like "30m", "1h", "2h30m".
 	SessionAffinityTTL string `yaml:"session-affinity-ttl,omitempty" json:"session-affinity-ttl,omitempty"`
+
+	// SessionAffinityMaxEntries bounds the session-to-auth bindings kept in memory; the least
+	// recently used binding is evicted beyond it. Default: 10000.
+	SessionAffinityMaxEntries int `yaml:"session-affinity-max-entries,omitempty" json:"session-affinity-max-entries,omitempty"`
+
+	// APIKeyRoutes pins the requests of a client API key to a weighted set of auths.
+	APIKeyRoutes []APIKeyRoute `yaml:"api-key-routes,omitempty" json:"-"`
 }
 
 // OAuthModelAlias defines a model ID alias for a specific channel.
@@ -387,20 +707,87 @@ type PayloadConfig struct {
 	OverrideRaw []PayloadRule `yaml:"override-raw" json:"override-raw"`
 	// Filter defines rules that remove parameters from the payload by JSON path.
 	Filter []PayloadFilterRule `yaml:"filter" json:"filter"`
+	// FillMaxTokens writes the model's registry max completion tokens into requests
+	// that omit an output token limit. Requested limits above it are always capped.
+	FillMaxTokens bool `yaml:"fill-max-tokens,omitempty" json:"fill-max-tokens,omitempty"`
 }
 
 // PayloadFilterRule describes a rule to remove specific JSON paths from matching model payloads.
 type PayloadFilterRule struct {
 	// Models lists model entries with name pattern and protocol constraint.
 	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// Providers restricts the rule to requests routed to these providers (e.g., "claude", "antigravity").
+	// A rule with providers but no models matches every model routed to those providers.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
 	// Params lists JSON paths (gjson/sjson syntax) to remove from the payload.
 	Params []string `yaml:"params" json:"params"`
 }
 
+// ProviderHTTPConfig tunes the upstream HTTP client used for one provider.
+// Zero values keep the Go defaults.
+type ProviderHTTPConfig struct {
+	// RequestTimeoutSeconds bounds a whole non-streaming upstream call, including reading the body.
+	// Streaming calls are exempt so long generations are not cut off.
+	RequestTimeoutSeconds int `yaml:"request-timeout-seconds,omitempty" json:"request-timeout-seconds,omitempty"`
+	// ResponseHeaderTimeoutSeconds bounds the wait for upstream response headers, for both
+	// streaming and non-streaming calls.
+	ResponseHeaderTimeoutSeconds int `yaml:"response-header-timeout-seconds,omitempty" json:"response-header-timeout-seconds,omitempty"`
+	// TLSHandshakeTimeoutSeconds bounds the TLS handshake with the upstream.
+	TLSHandshakeTimeoutSeconds int `yaml:"tls-handshake-timeout-seconds,omitempty" json:"tls-handshake-timeout-seconds,omitempty"`
+	// MaxIdleConnsPerHost sets how many idle keep-alive connections are kept per upstream host.
+	MaxIdleConnsPerHost int `yaml:"max-idle-conns-per-host,omitempty" json:"max-idle-conns-per-host,omitempty"`
+	// ModelPolicy hides and rejects models of the provider without removing them from the registry.
+	ModelPolicy ModelPolicy `yaml:"model-policy,omitempty" json:"model-policy,omitempty"`
+}
+
+// TransportConfigured reports whether any transport-level setting is set.
+func (p ProviderHTTPConfig) TransportConfigured() bool {
+	return p.ResponseHeaderTimeoutSeconds > 0 || p.TLSHandshakeTimeoutSeconds > 0 || p.MaxIdleConnsPerHost > 0
+}
+
+// ProviderHTTP returns the HTTP client settings for provider, matched case-insensitively.
+func (cfg *Config) ProviderHTTP(provider string) ProviderHTTPConfig {
+	provider = strings.TrimSpace(provider)
+	if cfg == nil || provider == "" || len(cfg.Providers) == 0 {
+		return ProviderHTTPConfig{}
+	}
+	if settings, ok := cfg.Providers[provider]; ok {
+		return settings
+	}
+	for name, settings := range cfg.Providers {
+		if strings.EqualFold(strings.TrimSpace(name), provider) {
+			return settings
+		}
+	}
+	return ProviderHTTPConfig{}
+}
+
+// RequestStoreConfig configures the persistent request log store. Each upstream request
+// is summarised into one row with truncated bodies; writes never block request handling.
+type RequestStoreConfig struct {
+	// Enable turns on the request store.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Path is the database file. Empty stores requests.db in the logs directory.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// MaxRows caps the number of stored rows; the oldest rows are removed first.
+	// Default is 10000. Negative disables the row limit.
+	MaxRows int `yaml:"max-rows,omitempty" json:"max-rows,omitempty"`
+	// MaxAgeHours removes rows older than this many hours. 0 disables age-based retention.
+	MaxAgeHours int `yaml:"max-age-hours,omitempty" json:"max-age-hours,omitempty"`
+	// MaxBodyBytes truncates stored request and response bodies. Default is 16384.
+	MaxBodyBytes int `yaml:"max-body-bytes,omitempty" json:"max-body-bytes,omitempty"`
+	// BufferSize is the capacity of the write queue. Rows arriving while it is full are
+	// dropped and counted. Default is 1024.
+	BufferSize int `yaml:"buffer-size,omitempty" json:"buffer-size,omitempty"`
+}
+
 // PayloadRule describes a single rule targeting a list of models with parameter updates.
 type PayloadRule struct {
 	// Models lists model entries with name pattern and protocol constraint.
 	Models []PayloadModelRule `yaml:"models" json:"models"`
+	// Providers restricts the rule to requests routed to these providers (e.g., "claude", "antigravity").
+	// A rule with providers but no models matches every model routed to those providers.
+	Providers []string `yaml:"providers,omitempty" json:"providers,omitempty"`
 	// Params maps JSON paths (gjson/sjson syntax) to values written into the payload.
 	// For *-raw rules, values are treated as raw JSON fragments (strings are used as-is).
 	Params map[string]any `yaml:"params" json:"params"`
@@ -447,6 +834,20 @@ type CloakConfig struct {
 	// CacheUserID controls whether Claude user_id values are cached per API key.
 	// When false, a fresh random user_id is generated for every request.
 	CacheUserID *bool `yaml:"cache-user-id,omitempty" json:"cache-user-id,omitempty"`
+
+	// PreserveUserID keeps attribution of client-supplied user_id values that are not in
+	// Claude Code format: instead of a random user_id, the original value is hashed into a
+	// valid-format one, so the same client id always maps to the same upstream id.
+	PreserveUserID bool `yaml:"preserve-user-id,omitempty" json:"preserve-user-id,omitempty"`
+
+	// SystemPrompt is injected into the system instruction of Gemini and Antigravity requests.
+	// StrictMode replaces the client's system instruction with it instead of prepending it.
+	// Claude requests always use the Claude Code prompt.
+	SystemPrompt string `yaml:"system-prompt,omitempty" json:"system-prompt,omitempty"`
+
+	// ReverseSensitiveWords removes the zero-width characters inserted by SensitiveWords
+	// obfuscation from responses, so clients see the original words.
+	ReverseSensitiveWords bool `yaml:"reverse-sensitive-words,omitempty" json:"reverse-sensitive-words,omitempty"`
 }
 
 // ClaudeKey represents the configuration for a Claude API key,
@@ -475,12 +876,19 @@ type ClaudeKey struct {
 	// Headers optionally adds extra HTTP headers for requests sent with this key.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// ForwardClientHeaders lists inbound client headers (e.g. tenant IDs) copied onto
+	// upstream requests sent with this key. Credential and transport headers are never forwarded.
+	ForwardClientHeaders []string `yaml:"forward-client-headers,omitempty" json:"forward-client-headers,omitempty"`
+
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
 
 	// RebuildMidSystemMessage moves Claude messages with role "system" into the top-level system field.
 	RebuildMidSystemMessage bool `yaml:"rebuild-mid-system-message,omitempty" json:"rebuild-mid-system-message,omitempty"`
 
+	// SystemAsString sends the top-level system field as a plain string instead of content blocks.
+	SystemAsString bool `yaml:"system-as-string,omitempty" json:"system-as-string,omitempty"`
+
 	// DisableCooling disables auth/model cooldown scheduling for this credential when true.
 	DisableCooling bool `yaml:"disable-cooling,omitempty" json:"disable-cooling,omitempty"`
 
@@ -545,6 +953,10 @@ type CodexKey struct {
 	// Headers optionally adds extra HTTP headers for requests sent with this key.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// ForwardClientHeaders lists inbound client headers (e.g. tenant IDs) copied onto
+	// upstream requests sent with this key. Credential and transport headers are never forwarded.
+	ForwardClientHeaders []string `yaml:"forward-client-headers,omitempty" json:"forward-client-headers,omitempty"`
+
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
 
@@ -606,11 +1018,18 @@ type GeminiKey struct {
 	// Headers optionally adds extra HTTP headers for requests sent with this key.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// ForwardClientHeaders lists inbound client headers (e.g. tenant IDs) copied onto
+	// upstream requests sent with this key. Credential and transport headers are never forwarded.
+	ForwardClientHeaders []string `yaml:"forward-client-headers,omitempty" json:"forward-client-headers,omitempty"`
+
 	// ExcludedModels lists model IDs that should be excluded for this provider.
 	ExcludedModels []string `yaml:"excluded-models,omitempty" json:"excluded-models,omitempty"`
 
 	// DisableCooling disables auth/model cooldown scheduling for this credential when true.
 	DisableCooling bool `yaml:"disable-cooling,omitempty" json:"disable-cooling,omitempty"`
+
+	// Cloak configures system prompt injection and sensitive word obfuscation for this key.
+	Cloak *CloakConfig `yaml:"cloak,omitempty" json:"cloak,omitempty"`
 }
 
 func (k GeminiKey) GetAPIKey() string  { return k.APIKey }
@@ -664,8 +1083,23 @@ type OpenAICompatibility struct {
 	// Headers optionally adds extra HTTP headers for requests sent to this provider.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// ForwardClientHeaders lists inbound client headers (e.g. tenant IDs) copied onto
+	// upstream requests sent to this provider. Credential and transport headers are never forwarded.
+	ForwardClientHeaders []string `yaml:"forward-client-headers,omitempty" json:"forward-client-headers,omitempty"`
+
 	// DisableCooling disables auth/model cooldown scheduling for this provider when true.
 	DisableCooling bool `yaml:"disable-cooling,omitempty" json:"disable-cooling,omitempty"`
+
+	// EndpointSelection pools all APIKeyEntries behind a single credential and lets the
+	// executor rotate across them, skipping endpoints that keep failing.
+	// Supported values: "weighted" (weighted random) and "round-robin"; other values
+	// are rejected when the config is loaded. Empty keeps one credential per API key entry.
+	EndpointSelection string `yaml:"endpoint-selection,omitempty" json:"endpoint-selection,omitempty"`
+
+	// ExtraBodyPassthrough lists top-level request fields, such as vLLM's top_k or
+	// guided_json, that are copied from the client request onto the upstream payload
+	// even when the request translator does not carry them over.
+	ExtraBodyPassthrough []string `yaml:"extra-body-passthrough,omitempty" json:"extra-body-passthrough,omitempty"`
 }
 
 // OpenAICompatibilityAPIKey represents an API key configuration with optional proxy setting.
@@ -675,6 +1109,13 @@ type OpenAICompatibilityAPIKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+
+	// BaseURL overrides the provider base URL for this entry when endpoint selection is enabled.
+	BaseURL string `yaml:"base-url,omitempty" json:"base-url,omitempty"`
+
+	// Weight is the relative share of traffic for this entry under weighted endpoint selection.
+	// Values <= 0 default to 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
 }
 
 // OpenAICompatibilityModel represents a model configuration for OpenAI compatibility,
@@ -751,6 +1192,12 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		return cfg, nil
 	}
 
+	// Resolve secret references (e.g. env://CLAUDE_KEY) before decoding.
+	data, secretPaths, err := resolveConfigSecrets(data)
+	if err != nil {
+		return nil, err
+	}
+
 	// Unmarshal the YAML data into the Config struct.
 	var cfg Config
 	// Set defaults before unmarshal so that absent keys keep defaults.
@@ -783,6 +1230,9 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 	if errValidate := cfg.CredentialInFlight.Validate(); errValidate != nil {
 		return nil, errValidate
 	}
+	if errValidate := cfg.ValidateOpenAICompatibility(); errValidate != nil {
+		return nil, errValidate
+	}
 
 	// Hash remote management key if plaintext is detected (nested)
 	// We consider a value to be already hashed if it looks like a bcrypt hash ($2a$, $2b$, or $2y$ prefix).
@@ -794,8 +1244,11 @@ func LoadConfigOptional(configFile string, optional bool) (*Config, error) {
 		cfg.RemoteManagement.SecretKey = hashed
 
 		// Persist the hashed value back to the config file to avoid re-hashing on next startup.
-		// Preserve YAML comments and ordering; update only the nested key.
-		_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
+		// Preserve YAML comments and ordering; update only the nested key. A key read from a
+		// secret reference keeps its reference.
+		if _, isRef := secretPaths["remote-management.secret-key"]; !isRef {
+			_ = SaveConfigPreserveCommentsUpdateNestedScalar(configFile, []string{"remote-management", "secret-key"}, hashed)
+		}
 	}
 
 	cfg.RemoteManagement.PanelGitHubRepository = strings.TrimSpace(cfg.RemoteManagement.PanelGitHubRepository)
@@ -1035,6 +1488,10 @@ func (cfg *Config) SanitizeOpenAICompatibility() {
 		e.Prefix = normalizeModelPrefix(e.Prefix)
 		e.BaseURL = strings.TrimSpace(e.BaseURL)
 		e.Headers = NormalizeHeaders(e.Headers)
+		e.EndpointSelection = normalizeOpenAICompatEndpointSelection(e.EndpointSelection)
+		for j := range e.APIKeyEntries {
+			e.APIKeyEntries[j].BaseURL = strings.TrimSpace(e.APIKeyEntries[j].BaseURL)
+		}
 		if e.BaseURL == "" {
 			// Skip providers with no base-url; treated as removed
 			continue
@@ -1044,6 +1501,43 @@ func (cfg *Config) SanitizeOpenAICompatibility() {
 	cfg.OpenAICompatibility = out
 }
 
+// normalizeOpenAICompatEndpointSelection lowercases the endpoint-selection value
+// and resolves its aliases. Unknown values are returned lowercased so
+// ValidateOpenAICompatibility can reject them.
+func normalizeOpenAICompatEndpointSelection(value string) string {
+	switch normalized := strings.ToLower(strings.TrimSpace(value)); normalized {
+	case "round-robin", "roundrobin", "rr":
+		return "round-robin"
+	default:
+		return normalized
+	}
+}
+
+// ValidateOpenAICompatEndpointSelection reports an error for an endpoint-selection
+// value other than empty, "weighted" or "round-robin" and its aliases.
+func ValidateOpenAICompatEndpointSelection(value string) error {
+	switch normalizeOpenAICompatEndpointSelection(value) {
+	case "", "weighted", "round-robin":
+		return nil
+	default:
+		return fmt.Errorf("unsupported endpoint-selection %q (supported: weighted, round-robin)", strings.TrimSpace(value))
+	}
+}
+
+// ValidateOpenAICompatibility rejects OpenAI-compatibility entries whose settings
+// cannot be applied.
+func (cfg *Config) ValidateOpenAICompatibility() error {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.OpenAICompatibility {
+		if errSelection := ValidateOpenAICompatEndpointSelection(cfg.OpenAICompatibility[i].EndpointSelection); errSelection != nil {
+			return fmt.Errorf("openai-compatibility %q: %w", strings.TrimSpace(cfg.OpenAICompatibility[i].Name), errSelection)
+		}
+	}
+	return nil
+}
+
 // SanitizeCodexKeys removes Codex API key entries missing a BaseURL.
 // It trims whitespace and preserves order for remaining entries.
 func (cfg *Config) SanitizeCodexKeys() {
@@ -1252,6 +1746,15 @@ func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	if original.Content[0] == nil || original.Content[0].Kind != yaml.MappingNode {
 		return fmt.Errorf("expected root mapping node")
 	}
+	// Merge against resolved secret values so unchanged secrets keep their references.
+	var section struct {
+		SecretReferences SecretReferencesConfig `yaml:"secret-references"`
+	}
+	_ = original.Decode(&section)
+	secretNodes, _, err := resolveSecretScalars(&original, section.SecretReferences.Policy())
+	if err != nil {
+		return fmt.Errorf("failed to resolve secret reference at %w", err)
+	}
 
 	// Marshal the current cfg to YAML, then unmarshal to a yaml.Node we can merge from.
 	rendered, err := yaml.Marshal(persistCfg)
@@ -1282,6 +1785,7 @@ func SaveConfigPreserveComments(configFile string, cfg *Config) error {
 	// Merge generated into original in-place, preserving comments/order of existing nodes.
 	mergeMappingPreserve(original.Content[0], generated.Content[0])
 	normalizeCollectionNodeStyles(original.Content[0])
+	restoreSecretScalars(secretNodes)
 
 	// Write back.
 	f, err := os.Create(configFile)
@@ -0,0 +1,113 @@
+package config
+
+import "strings"
+
+// ModelPolicy limits the models of a provider that clients may list and request.
+// Patterns use '*' as a wildcard and match case-insensitively.
+type ModelPolicy struct {
+	// Allow lists the model patterns clients may use. Empty allows every model not denied.
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	// Deny lists the model patterns clients may not use. Deny wins over Allow.
+	Deny []string `yaml:"deny,omitempty" json:"deny,omitempty"`
+	// APIKeys scopes the policy to requests authenticated with these client API keys.
+	// Empty applies the policy to every request.
+	APIKeys []string `yaml:"api-keys,omitempty" json:"-"`
+}
+
+// Configured reports whether the policy restricts any model.
+func (p ModelPolicy) Configured() bool {
+	return len(p.Allow) > 0 || len(p.Deny) > 0
+}
+
+// AppliesTo reports whether the policy covers requests made with apiKey.
+func (p ModelPolicy) AppliesTo(apiKey string) bool {
+	if !p.Configured() {
+		return false
+	}
+	if len(p.APIKeys) == 0 {
+		return true
+	}
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return false
+	}
+	for _, key := range p.APIKeys {
+		if strings.TrimSpace(key) == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelPolicy returns the model policy of provider for requests made with apiKey.
+// ok is false when no policy applies.
+func (cfg *Config) ModelPolicy(provider, apiKey string) (policy ModelPolicy, ok bool) {
+	policy = cfg.ProviderHTTP(provider).ModelPolicy
+	if !policy.AppliesTo(apiKey) {
+		return ModelPolicy{}, false
+	}
+	return policy, true
+}
+
+// ModelPolicyConfigured reports whether any provider has a model policy.
+func (cfg *Config) ModelPolicyConfigured() bool {
+	if cfg == nil {
+		return false
+	}
+	for _, settings := range cfg.Providers {
+		if settings.ModelPolicy.Configured() {
+			return true
+		}
+	}
+	return false
+}
+
+// UpstreamModelAliases returns the upstream models that model is an alias of for
+// provider, from oauth-model-alias and the models of the provider's API key entries.
+func (cfg *Config) UpstreamModelAliases(provider, model string) []string {
+	if cfg == nil {
+		return nil
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	model = strings.TrimSpace(model)
+	if provider == "" || model == "" {
+		return nil
+	}
+	var out []string
+	add := func(name, alias string) {
+		name = strings.TrimSpace(name)
+		if name == "" || !strings.EqualFold(strings.TrimSpace(alias), model) || strings.EqualFold(name, model) {
+			return
+		}
+		out = append(out, name)
+	}
+	for channel, aliases := range cfg.OAuthModelAlias {
+		if !strings.EqualFold(strings.TrimSpace(channel), provider) {
+			continue
+		}
+		for _, alias := range aliases {
+			add(alias.Name, alias.Alias)
+		}
+	}
+	switch provider {
+	case "claude":
+		for _, key := range cfg.ClaudeKey {
+			for _, m := range key.Models {
+				add(m.Name, m.Alias)
+			}
+		}
+	case "codex":
+		for _, key := range cfg.CodexKey {
+			for _, m := range key.Models {
+				add(m.Name, m.Alias)
+			}
+		}
+	case "gemini":
+		for _, key := range cfg.GeminiKey {
+			for _, m := range key.Models {
+				add(m.Name, m.Alias)
+			}
+		}
+	}
+	return out
+}
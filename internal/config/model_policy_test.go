@@ -0,0 +1,49 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModelPolicyAppliesToScopedAPIKeys(t *testing.T) {
+	cfg := &Config{Providers: map[string]ProviderHTTPConfig{
+		"claude": {ModelPolicy: ModelPolicy{Deny: []string{"claude-opus-*"}, APIKeys: []string{"team-a"}}},
+		"codex":  {ModelPolicy: ModelPolicy{Allow: []string{"gpt-5*"}}},
+	}}
+	if !cfg.ModelPolicyConfigured() {
+		t.Fatal("ModelPolicyConfigured() = false, want true")
+	}
+	if _, ok := cfg.ModelPolicy("claude", "team-a"); !ok {
+		t.Fatal("claude policy does not apply to team-a")
+	}
+	if _, ok := cfg.ModelPolicy("claude", "team-b"); ok {
+		t.Fatal("claude policy applies to team-b, want scoped to team-a")
+	}
+	if _, ok := cfg.ModelPolicy("claude", ""); ok {
+		t.Fatal("claude policy applies to a request without API key")
+	}
+	if _, ok := cfg.ModelPolicy("codex", "anyone"); !ok {
+		t.Fatal("unscoped codex policy does not apply to every key")
+	}
+	if _, ok := cfg.ModelPolicy("gemini", "team-a"); ok {
+		t.Fatal("gemini has no policy but ModelPolicy() reported one")
+	}
+}
+
+func TestUpstreamModelAliases(t *testing.T) {
+	cfg := &Config{
+		OAuthModelAlias: map[string][]OAuthModelAlias{
+			"antigravity": {{Name: "gemini-3-pro-high", Alias: "g3"}},
+		},
+		ClaudeKey: []ClaudeKey{{Models: []ClaudeModel{{Name: "claude-opus-4-1", Alias: "opus"}}}},
+	}
+	if got := cfg.UpstreamModelAliases("antigravity", "G3"); !reflect.DeepEqual(got, []string{"gemini-3-pro-high"}) {
+		t.Fatalf("antigravity aliases = %v", got)
+	}
+	if got := cfg.UpstreamModelAliases("claude", "opus"); !reflect.DeepEqual(got, []string{"claude-opus-4-1"}) {
+		t.Fatalf("claude aliases = %v", got)
+	}
+	if got := cfg.UpstreamModelAliases("codex", "opus"); len(got) != 0 {
+		t.Fatalf("codex aliases = %v, want none", got)
+	}
+}
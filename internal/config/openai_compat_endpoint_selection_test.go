@@ -0,0 +1,29 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfigBytesOpenAICompatEndpointSelection(t *testing.T) {
+	parse := func(selection string) (*Config, error) {
+		return ParseConfigBytes([]byte(`
+openai-compatibility:
+  - name: pool
+    base-url: https://example.com/v1
+    endpoint-selection: ` + selection + `
+`))
+	}
+
+	cfg, err := parse("RR")
+	if err != nil {
+		t.Fatalf("ParseConfigBytes() error = %v", err)
+	}
+	if got := cfg.OpenAICompatibility[0].EndpointSelection; got != "round-robin" {
+		t.Fatalf("EndpointSelection = %q, want round-robin", got)
+	}
+
+	if _, err = parse("fastest"); err == nil || !strings.Contains(err.Error(), `"fastest"`) {
+		t.Fatalf("ParseConfigBytes() error = %v, want unsupported endpoint-selection rejected", err)
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// Request priority classes carried by the X-CLIProxy-Priority header.
+const (
+	RequestPriorityHigh   = "high"
+	RequestPriorityNormal = "normal"
+	RequestPriorityLow    = "low"
+)
+
+// Defaults used by RequestPriorityConfig when a value is not configured.
+const (
+	DefaultRequestPriorityQueueDepth          = 100
+	DefaultRequestPriorityQueueTimeoutSeconds = 30
+)
+
+// RequestPriorityConfig caps concurrent upstream requests per credential and per provider.
+// Requests beyond a cap wait in a queue that is served in priority order, oldest first
+// within a priority, and fail with HTTP 429 once the queue is full or their wait exceeds
+// the timeout of their priority.
+type RequestPriorityConfig struct {
+	// MaxConcurrentPerAuth caps in-flight requests per credential. 0 disables the cap.
+	MaxConcurrentPerAuth int `yaml:"max-concurrent-per-auth,omitempty" json:"max-concurrent-per-auth,omitempty"`
+	// MaxConcurrentPerProvider caps in-flight requests per provider. 0 disables the cap.
+	MaxConcurrentPerProvider int `yaml:"max-concurrent-per-provider,omitempty" json:"max-concurrent-per-provider,omitempty"`
+	// MaxQueueDepth is the number of requests that may wait for one cap.
+	// 0 uses DefaultRequestPriorityQueueDepth.
+	MaxQueueDepth int `yaml:"max-queue-depth,omitempty" json:"max-queue-depth,omitempty"`
+	// QueueTimeoutSeconds maps a priority to its longest wait in the queue. Priorities
+	// without an entry use DefaultRequestPriorityQueueTimeoutSeconds.
+	QueueTimeoutSeconds map[string]int `yaml:"queue-timeout-seconds,omitempty" json:"queue-timeout-seconds,omitempty"`
+	// APIKeyPriorities maps a client API key to the highest priority its requests get.
+	// X-CLIProxy-Priority may lower it; keys without an entry get at most normal.
+	APIKeyPriorities map[string]string `yaml:"api-key-priorities,omitempty" json:"-"`
+}
+
+// Enabled reports whether any concurrency cap is configured.
+func (c RequestPriorityConfig) Enabled() bool {
+	return c.MaxConcurrentPerAuth > 0 || c.MaxConcurrentPerProvider > 0
+}
+
+// QueueDepth returns the configured queue depth.
+func (c RequestPriorityConfig) QueueDepth() int {
+	if c.MaxQueueDepth <= 0 {
+		return DefaultRequestPriorityQueueDepth
+	}
+	return c.MaxQueueDepth
+}
+
+// QueueTimeout returns how long a request of the given priority may wait in the queue.
+func (c RequestPriorityConfig) QueueTimeout(priority string) time.Duration {
+	if seconds := c.QueueTimeoutSeconds[priority]; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return DefaultRequestPriorityQueueTimeoutSeconds * time.Second
+}
+
+// RequestPriorityRank orders priority classes; higher ranks are served first. Unknown
+// classes rank as normal.
+func RequestPriorityRank(priority string) int {
+	switch priority {
+	case RequestPriorityHigh:
+		return 2
+	case RequestPriorityLow:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// NormalizeRequestPriority returns the priority class named by value, accepting any case.
+func NormalizeRequestPriority(value string) (string, bool) {
+	switch priority := strings.ToLower(strings.TrimSpace(value)); priority {
+	case RequestPriorityHigh, RequestPriorityNormal, RequestPriorityLow:
+		return priority, true
+	default:
+		return "", false
+	}
+}
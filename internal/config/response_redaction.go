@@ -0,0 +1,11 @@
+package config
+
+// ResponseRedactionRule replaces every match of a regular expression in model output
+// before the response leaves the proxy.
+type ResponseRedactionRule struct {
+	// Pattern is a Go regular expression (RE2 syntax).
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// Replacement replaces each match. It may reference capture groups as $1 or ${name}.
+	// Empty uses "[REDACTED]".
+	Replacement string `yaml:"replacement,omitempty" json:"replacement,omitempty"`
+}
@@ -4,6 +4,8 @@
 // debug settings, proxy configuration, and API keys.
 package config
 
+import "time"
+
 // SDKConfig represents the application's configuration, loaded from a YAML file.
 type SDKConfig struct {
 	// ProxyURL is the URL of an optional proxy server to use for outbound requests.
@@ -55,6 +57,34 @@ type SDKConfig struct {
 	// NonStreamKeepAliveInterval controls how often blank lines are emitted for non-streaming responses.
 	// <= 0 disables keep-alives. Value is in seconds.
 	NonStreamKeepAliveInterval int `yaml:"nonstream-keepalive-interval,omitempty" json:"nonstream-keepalive-interval,omitempty"`
+
+	// AutoModel configures how the "auto" model name is resolved to a concrete model.
+	AutoModel AutoModelConfig `yaml:"auto-model,omitempty" json:"auto-model,omitempty"`
+
+	// RequestPriority caps upstream concurrency and queues excess requests by priority.
+	RequestPriority RequestPriorityConfig `yaml:"request-priority,omitempty" json:"request-priority,omitempty"`
+}
+
+// AutoModelConfig controls weighted, optionally sticky resolution of the "auto" model.
+type AutoModelConfig struct {
+	// Models lists candidate models in preference order. When empty, or when no
+	// candidate has an available credential, "auto" resolves to the first available
+	// registered model.
+	Models []AutoModelCandidate `yaml:"models,omitempty" json:"models,omitempty"`
+
+	// Sticky keeps a conversation on the same candidate while that candidate stays
+	// available. Conversations are keyed by metadata.session_id or a hash of the
+	// opening messages.
+	Sticky bool `yaml:"sticky,omitempty" json:"sticky,omitempty"`
+}
+
+// AutoModelCandidate is a weighted auto-routing target.
+type AutoModelCandidate struct {
+	// Name is the model ID requests are routed to.
+	Name string `yaml:"name" json:"name"`
+
+	// Weight is the relative selection weight. Values <= 0 count as 1.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
 }
 
 // StreamingConfig holds server streaming behavior configuration.
@@ -67,4 +97,37 @@ type StreamingConfig struct {
 	// to allow auth rotation / transient recovery.
 	// <= 0 disables bootstrap retries. Default is 0.
 	BootstrapRetries int `yaml:"bootstrap-retries,omitempty" json:"bootstrap-retries,omitempty"`
+
+	// IdleTimeoutSeconds cancels an upstream stream that has sent no bytes for this long.
+	// 0 uses the default of 120 seconds; < 0 disables the watchdog.
+	IdleTimeoutSeconds int `yaml:"idle-timeout-seconds,omitempty" json:"idle-timeout-seconds,omitempty"`
+
+	// ResumeOnError continues an OpenAI chat or Claude messages stream that fails with a
+	// retryable error after bytes were sent: the request is re-issued with the text emitted
+	// so far as an assistant prefill, and text the new attempt repeats is not sent twice.
+	// Default is false.
+	ResumeOnError bool `yaml:"resume-on-error,omitempty" json:"resume-on-error,omitempty"`
+
+	// CoalesceMs buffers consecutive OpenAI chat and Claude text deltas for up to this many
+	// milliseconds and sends them as one chunk, reducing writes for upstreams that stream
+	// single tokens. Terminal chunks, tool calls and errors are never delayed.
+	// <= 0 disables coalescing. Default is 0.
+	CoalesceMs int `yaml:"coalesce-ms,omitempty" json:"coalesce-ms,omitempty"`
+}
+
+// DefaultStreamIdleTimeout is the upstream stream inactivity limit used when
+// IdleTimeoutSeconds is unset.
+const DefaultStreamIdleTimeout = 120 * time.Second
+
+// IdleTimeout returns how long an upstream stream may stay silent before it is cancelled.
+// Zero disables the watchdog.
+func (s StreamingConfig) IdleTimeout() time.Duration {
+	switch {
+	case s.IdleTimeoutSeconds < 0:
+		return 0
+	case s.IdleTimeoutSeconds == 0:
+		return DefaultStreamIdleTimeout
+	default:
+		return time.Duration(s.IdleTimeoutSeconds) * time.Second
+	}
 }
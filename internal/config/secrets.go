@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// SecretReferencesConfig enables secret references and limits what they may point to.
+type SecretReferencesConfig struct {
+	// Enable resolves references in the config file.
+	Enable bool `yaml:"enable"`
+	// AuthFiles also resolves references in the auth files of the auth directory.
+	AuthFiles bool `yaml:"auth-files"`
+	// Env lists the environment variables env:// references may read; a trailing "*"
+	// matches a prefix.
+	Env []string `yaml:"env,omitempty"`
+	// EncfileRoots lists the directories encfile:// references may read and write under.
+	EncfileRoots []string `yaml:"encfile-roots,omitempty"`
+}
+
+// Policy returns the secrets policy the section describes.
+func (c SecretReferencesConfig) Policy() secrets.Policy {
+	return secrets.Policy{
+		Enable:    c.Enable,
+		AuthFiles: c.Enable && c.AuthFiles,
+		Env:       append([]string(nil), c.Env...),
+		FileRoots: append([]string(nil), c.EncfileRoots...),
+	}
+}
+
+// secretScalar is a YAML string scalar that was written as a secret reference.
+type secretScalar struct {
+	ref   string
+	value string
+}
+
+// resolveSecretScalars replaces the secret references among the string scalars under node
+// by their values. It returns the replaced nodes keyed by node and by dotted key path.
+func resolveSecretScalars(node *yaml.Node, policy secrets.Policy) (map[*yaml.Node]secretScalar, map[string]struct{}, error) {
+	nodes := make(map[*yaml.Node]secretScalar)
+	paths := make(map[string]struct{})
+	if !policy.Enable {
+		return nodes, paths, nil
+	}
+	err := walkSecretScalars(node, "", func(scalar *yaml.Node, path string) error {
+		ref, ok := secrets.ParseReference(scalar.Value)
+		if !ok {
+			return nil
+		}
+		value, errResolve := policy.ResolveReference(context.Background(), ref)
+		if errResolve != nil {
+			return fmt.Errorf("%s: %w", path, errResolve)
+		}
+		nodes[scalar] = secretScalar{ref: scalar.Value, value: value}
+		paths[path] = struct{}{}
+		scalar.Value = value
+		return nil
+	})
+	return nodes, paths, err
+}
+
+func walkSecretScalars(node *yaml.Node, path string, fn func(*yaml.Node, string) error) error {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := walkSecretScalars(child, path, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := node.Content[i].Value
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+			if err := walkSecretScalars(node.Content[i+1], childPath, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := walkSecretScalars(child, path+"."+strconv.Itoa(i), fn); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" || node.Tag == "" {
+			return fn(node, path)
+		}
+	}
+	return nil
+}
+
+// restoreSecretScalars puts the references back into the nodes whose value is still the
+// one resolved from them, so saving the config never writes resolved secrets.
+func restoreSecretScalars(nodes map[*yaml.Node]secretScalar) {
+	for node, scalar := range nodes {
+		if node.Kind == yaml.ScalarNode && node.Value == scalar.value {
+			node.Value = scalar.ref
+		}
+	}
+}
+
+// resolveConfigSecrets returns data with its secret references resolved under the policy
+// of its own secret-references section, and the dotted key paths that held one. data is
+// returned unchanged when it holds no reference or references are not enabled.
+func resolveConfigSecrets(data []byte) ([]byte, map[string]struct{}, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		// Leave reporting invalid YAML to the regular decoding step.
+		return data, nil, nil
+	}
+	var section struct {
+		SecretReferences SecretReferencesConfig `yaml:"secret-references"`
+	}
+	if err := root.Decode(&section); err != nil {
+		return data, nil, nil
+	}
+	nodes, paths, err := resolveSecretScalars(&root, section.SecretReferences.Policy())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve secret reference at %w", err)
+	}
+	if len(nodes) == 0 {
+		return data, nil, nil
+	}
+	resolved, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolved, paths, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigResolvesSecretReferencesAndSaveKeepsThem(t *testing.T) {
+	t.Setenv("CONFIG_TEST_CLAUDE_KEY", "sk-ant-from-env")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	raw := "port: 8317\nsecret-references:\n  enable: true\n  env: [CONFIG_TEST_CLAUDE_KEY]\nclaude-api-key:\n  - api-key: env://CONFIG_TEST_CLAUDE_KEY # from the environment\n    base-url: https://api.anthropic.com\n"
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.ClaudeKey) != 1 || cfg.ClaudeKey[0].APIKey != "sk-ant-from-env" {
+		t.Fatalf("claude-api-key = %+v, want the resolved key", cfg.ClaudeKey)
+	}
+	if cfg.ClaudeKey[0].BaseURL != "https://api.anthropic.com" {
+		t.Fatalf("base-url = %q, URLs must not be treated as references", cfg.ClaudeKey[0].BaseURL)
+	}
+
+	cfg.Port = 9000
+	if err = SaveConfigPreserveComments(path, cfg); err != nil {
+		t.Fatalf("SaveConfigPreserveComments() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if strings.Contains(string(saved), "sk-ant-from-env") || !strings.Contains(string(saved), "env://CONFIG_TEST_CLAUDE_KEY") {
+		t.Fatalf("saved config lost the secret reference:\n%s", saved)
+	}
+	if !strings.Contains(string(saved), "port: 9000") {
+		t.Fatalf("saved config lacks the update:\n%s", saved)
+	}
+}
+
+func TestLoadConfigFailsOnUnresolvableSecretReference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("secret-references:\n  enable: true\n  env: [CONFIG_TEST_*]\napi-keys:\n  - env://CONFIG_TEST_UNSET_KEY\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "api-keys.0") {
+		t.Fatalf("LoadConfig() error = %v, want it to name the unresolved key", err)
+	}
+}
+
+func TestLoadConfigSecretReferencesAreOptIn(t *testing.T) {
+	t.Setenv("CONFIG_TEST_CLAUDE_KEY", "sk-ant-from-env")
+	t.Setenv("CONFIG_TEST_PRIVATE", "private")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("api-keys:\n  - env://CONFIG_TEST_CLAUDE_KEY\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.APIKeys) != 1 || cfg.APIKeys[0] != "env://CONFIG_TEST_CLAUDE_KEY" {
+		t.Fatalf("api-keys = %v, want the reference kept verbatim while disabled", cfg.APIKeys)
+	}
+
+	raw := "secret-references:\n  enable: true\n  env: [CONFIG_TEST_CLAUDE_KEY]\napi-keys:\n  - env://CONFIG_TEST_PRIVATE\n"
+	if err = os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err = LoadConfig(path); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("LoadConfig() error = %v, want the variable outside the allowlist refused", err)
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import "strings"
+
+// DefaultShadowMaxConcurrent is the number of shadow requests that may run at once when
+// shadow.max-concurrent is not set.
+const DefaultShadowMaxConcurrent = 4
+
+// ShadowConfig mirrors a sample of non-streaming requests to a second model for offline
+// evaluation. The client always receives the primary response; the shadow response is
+// only recorded in the request logs next to it.
+type ShadowConfig struct {
+	// Enabled turns shadow traffic on.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// SampleRate is the share of matching requests that are mirrored, from 0 to 1.
+	SampleRate float64 `yaml:"sample-rate,omitempty" json:"sample-rate,omitempty"`
+	// SourceModelPattern selects the requested models that are mirrored. It supports the
+	// '*' wildcard; empty matches every model.
+	SourceModelPattern string `yaml:"source-model-pattern,omitempty" json:"source-model-pattern,omitempty"`
+	// ShadowModel is the model the mirrored requests are sent to.
+	ShadowModel string `yaml:"shadow-model,omitempty" json:"shadow-model,omitempty"`
+	// MaxConcurrent caps the shadow requests in flight; sampled requests beyond it are not
+	// mirrored. 0 uses DefaultShadowMaxConcurrent.
+	MaxConcurrent int `yaml:"max-concurrent,omitempty" json:"max-concurrent,omitempty"`
+}
+
+// Active reports whether shadow traffic is enabled with a model and a positive rate.
+func (c ShadowConfig) Active() bool {
+	return c.Enabled && c.SampleRate > 0 && strings.TrimSpace(c.ShadowModel) != ""
+}
+
+// EffectiveMaxConcurrent returns the configured concurrency cap or the default.
+func (c ShadowConfig) EffectiveMaxConcurrent() int {
+	if c.MaxConcurrent <= 0 {
+		return DefaultShadowMaxConcurrent
+	}
+	return c.MaxConcurrent
+}
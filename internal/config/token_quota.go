@@ -0,0 +1,47 @@
+package config
+
+import "strings"
+
+// TokenQuotaConfig configures per-credential token budgets. Tokens reported by upstream
+// usage are counted per credential for the current UTC day and month; a credential whose
+// count reaches a limit is skipped by selection until the period resets.
+type TokenQuotaConfig struct {
+	// Path is the file the counters are persisted to. Empty stores token-quota.json in
+	// the logs directory.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Auths lists the budgets. Token counting is enabled when at least one is configured.
+	Auths []TokenQuotaRule `yaml:"auths,omitempty" json:"auths,omitempty"`
+}
+
+// TokenQuotaLimits is a pair of token budgets. A zero limit is not enforced.
+type TokenQuotaLimits struct {
+	// DailyTokenLimit caps the tokens used per UTC day.
+	DailyTokenLimit int64 `yaml:"daily-token-limit,omitempty" json:"daily-token-limit,omitempty"`
+	// MonthlyTokenLimit caps the tokens used per UTC month.
+	MonthlyTokenLimit int64 `yaml:"monthly-token-limit,omitempty" json:"monthly-token-limit,omitempty"`
+}
+
+// Enforced reports whether any limit is set.
+func (l TokenQuotaLimits) Enforced() bool {
+	return l.DailyTokenLimit > 0 || l.MonthlyTokenLimit > 0
+}
+
+// TokenQuotaRule sets the budgets of one credential.
+type TokenQuotaRule struct {
+	// Auth is the auth ID or auth index of the credential.
+	Auth string `yaml:"auth" json:"auth"`
+	// TokenQuotaLimits applies to the tokens of every model.
+	TokenQuotaLimits `yaml:",inline"`
+	// Models maps a model name to budgets that only count the tokens of that model.
+	Models map[string]TokenQuotaLimits `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// Enabled reports whether any budget is configured.
+func (c TokenQuotaConfig) Enabled() bool {
+	for _, rule := range c.Auths {
+		if strings.TrimSpace(rule.Auth) != "" {
+			return true
+		}
+	}
+	return false
+}
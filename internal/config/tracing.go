@@ -0,0 +1,39 @@
+package config
+
+import "strings"
+
+// DefaultTracingServiceName is the service.name reported when tracing.service-name is empty.
+const DefaultTracingServiceName = "cli-proxy-api"
+
+// TracingConfig configures the optional OpenTelemetry traces exported over OTLP/HTTP.
+type TracingConfig struct {
+	// Enable turns on span recording and export.
+	Enable bool `yaml:"enable" json:"enable"`
+	// Endpoint is the OTLP/HTTP traces endpoint, e.g. "http://localhost:4318/v1/traces".
+	// Empty uses the OTEL_EXPORTER_OTLP_* environment variables or the exporter default.
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	// Headers are sent with every export request, e.g. collector credentials.
+	Headers map[string]string `yaml:"headers,omitempty" json:"-"`
+	// SamplingRatio is the share of new traces that are recorded, from 0 to 1. Requests
+	// that arrive with a sampled traceparent are always recorded. 0 records every trace.
+	SamplingRatio float64 `yaml:"sampling-ratio,omitempty" json:"sampling-ratio,omitempty"`
+	// ServiceName overrides the reported service.name.
+	ServiceName string `yaml:"service-name,omitempty" json:"service-name,omitempty"`
+}
+
+// EffectiveSamplingRatio returns the sampling ratio clamped to (0, 1], where 0 or less
+// means every trace.
+func (c TracingConfig) EffectiveSamplingRatio() float64 {
+	if c.SamplingRatio <= 0 || c.SamplingRatio > 1 {
+		return 1
+	}
+	return c.SamplingRatio
+}
+
+// EffectiveServiceName returns the configured service name or DefaultTracingServiceName.
+func (c TracingConfig) EffectiveServiceName() string {
+	if name := strings.TrimSpace(c.ServiceName); name != "" {
+		return name
+	}
+	return DefaultTracingServiceName
+}
@@ -32,6 +32,10 @@ type VertexCompatKey struct {
 	// Commonly used for cookies, user-agent, and other authentication headers.
 	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
 
+	// ForwardClientHeaders lists inbound client headers (e.g. tenant IDs) copied onto
+	// upstream requests sent with this key. Credential and transport headers are never forwarded.
+	ForwardClientHeaders []string `yaml:"forward-client-headers,omitempty" json:"forward-client-headers,omitempty"`
+
 	// Models defines the model configurations including aliases for routing.
 	Models []VertexCompatModel `yaml:"models,omitempty" json:"models,omitempty"`
 
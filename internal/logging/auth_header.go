@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AuthLabelHeader is the downstream response header naming the credential that served the request.
+	AuthLabelHeader = "X-CLIProxy-Auth-Label"
+	// AuthProviderHeader is the downstream response header naming the provider of that credential.
+	AuthProviderHeader = "X-CLIProxy-Provider"
+)
+
+const ginAuthHeaderStateKey = "__auth_header_state__"
+
+type authHeaderState struct {
+	mu       sync.RWMutex
+	provider string
+	label    string
+}
+
+func (s *authHeaderState) set(provider, label string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.provider = strings.TrimSpace(provider)
+	s.label = strings.TrimSpace(label)
+	s.mu.Unlock()
+}
+
+func (s *authHeaderState) get() (provider, label string) {
+	if s == nil {
+		return "", ""
+	}
+	s.mu.RLock()
+	provider, label = s.provider, s.label
+	s.mu.RUnlock()
+	return provider, label
+}
+
+// GinAuthHeaderCallback returns a callback recording the credential that served the request,
+// or nil when auth headers are not exposed for it. The latest call before the response
+// headers are committed wins, so retries report the auth of the final attempt.
+func GinAuthHeaderCallback(c *gin.Context) func(provider, label string) {
+	if c == nil {
+		return nil
+	}
+	value, exists := c.Get(ginAuthHeaderStateKey)
+	if !exists {
+		return nil
+	}
+	state, ok := value.(*authHeaderState)
+	if !ok || state == nil {
+		return nil
+	}
+	return state.set
+}
+
+// AuthHeaderMiddleware exposes the serving credential through AuthLabelHeader and
+// AuthProviderHeader while enabled reports true. The headers are injected immediately
+// before the response headers are committed.
+func AuthHeaderMiddleware(enabled func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if enabled == nil || !enabled() {
+			c.Next()
+			return
+		}
+		state := &authHeaderState{}
+		c.Set(ginAuthHeaderStateKey, state)
+		c.Writer = &authHeaderResponseWriter{ResponseWriter: c.Writer, state: state}
+		c.Next()
+	}
+}
+
+type authHeaderResponseWriter struct {
+	gin.ResponseWriter
+	state *authHeaderState
+}
+
+func (w *authHeaderResponseWriter) WriteHeader(statusCode int) {
+	w.applyAuthHeaders()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *authHeaderResponseWriter) WriteHeaderNow() {
+	w.applyAuthHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *authHeaderResponseWriter) Write(data []byte) (int, error) {
+	w.applyAuthHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *authHeaderResponseWriter) WriteString(data string) (int, error) {
+	w.applyAuthHeaders()
+	return w.ResponseWriter.WriteString(data)
+}
+
+func (w *authHeaderResponseWriter) Flush() {
+	w.applyAuthHeaders()
+	w.ResponseWriter.Flush()
+}
+
+func (w *authHeaderResponseWriter) applyAuthHeaders() {
+	if w == nil || w.ResponseWriter == nil || w.ResponseWriter.Written() {
+		return
+	}
+	provider, label := w.state.get()
+	if label != "" {
+		w.ResponseWriter.Header().Set(AuthLabelHeader, label)
+	}
+	if provider != "" {
+		w.ResponseWriter.Header().Set(AuthProviderHeader, provider)
+	}
+}
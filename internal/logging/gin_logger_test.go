@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -148,3 +149,51 @@ func TestGinLogrusLoggerAddsRequestIDForCodexBackend(t *testing.T) {
 		t.Fatalf("expected Gin request ID %q to match context request ID %q", requestIDFromGin, requestIDFromContext)
 	}
 }
+
+func TestGinLogrusLoggerRequestIDHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(GinLogrusLogger())
+	var requestIDFromContext string
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		requestIDFromContext = GetRequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name    string
+		inbound string
+		want    string
+	}{
+		{name: "reuses provided id", inbound: "gw-7f3a:trace_01.2", want: "gw-7f3a:trace_01.2"},
+		{name: "strips unsafe characters", inbound: " abc\r\nX-Injected: 1 ", want: "abcX-Injected:1"},
+		{name: "caps length", inbound: strings.Repeat("a", 100), want: strings.Repeat("a", 64)},
+		{name: "generates when absent"},
+		{name: "generates when nothing survives sanitizing", inbound: "<>  {}"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tc.inbound != "" {
+				req.Header[RequestIDHeader] = []string{tc.inbound}
+			}
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, req)
+
+			got := recorder.Header().Get(RequestIDHeader)
+			if got != requestIDFromContext {
+				t.Fatalf("response header %q does not match context request ID %q", got, requestIDFromContext)
+			}
+			if tc.want != "" {
+				if got != tc.want {
+					t.Fatalf("request ID = %q, want %q", got, tc.want)
+				}
+				return
+			}
+			if len(got) != 8 {
+				t.Fatalf("request ID = %q, want a generated 8-character ID", got)
+			}
+		})
+	}
+}
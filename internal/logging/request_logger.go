@@ -657,7 +657,7 @@ func (l *FileRequestLogger) logRequestWithSources(url, method string, requestHea
 		responseToWrite = response
 	}
 
-	logFile, errOpen := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	logFile, _, errOpen := createLogFile(filePath)
 	if errOpen != nil {
 		return fmt.Errorf("failed to create log file: %w", errOpen)
 	}
@@ -823,9 +823,10 @@ func (l *FileRequestLogger) generateFilename(url string, requestID ...string) st
 
 	// Use request ID if provided, otherwise use sequential ID
 	var idPart string
-	if len(requestID) > 0 && requestID[0] != "" {
-		idPart = requestID[0]
-	} else {
+	if len(requestID) > 0 {
+		idPart = RequestIDFilenamePart(requestID[0])
+	}
+	if idPart == "" {
 		id := requestLogID.Add(1)
 		idPart = fmt.Sprintf("%d", id)
 	}
@@ -833,6 +834,37 @@ func (l *FileRequestLogger) generateFilename(url string, requestID ...string) st
 	return fmt.Sprintf("%s-%s-%s.log", sanitized, timestamp, idPart)
 }
 
+// RequestIDFilenamePart returns the form of requestID used in log file names: every
+// character other than letters, digits, '-', '_' and '.' becomes '-', so IDs such as
+// "trace:42" stay valid file names on every platform.
+func RequestIDFilenamePart(requestID string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(requestID)), "-.")
+}
+
+// createLogFile creates the log file at path without replacing an existing one. Request IDs
+// may come from clients and repeat, so when path is taken a sequence number is appended
+// before the extension. It returns the open file and the path actually used.
+func createLogFile(path string) (*os.File, string, error) {
+	candidate := path
+	for attempt := 0; attempt < 100; attempt++ {
+		file, errOpen := os.OpenFile(candidate, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errOpen == nil {
+			return file, candidate, nil
+		}
+		if !os.IsExist(errOpen) {
+			return nil, "", errOpen
+		}
+		candidate = fmt.Sprintf("%s-%d.log", strings.TrimSuffix(path, ".log"), requestLogID.Add(1))
+	}
+	return nil, "", fmt.Errorf("log file %s already exists", filepath.Base(path))
+}
+
 // sanitizeForFilename replaces characters that are not safe for filenames.
 //
 // Parameters:
@@ -1845,11 +1877,12 @@ func (w *FileStreamingLogWriter) Close() error {
 		return nil
 	}
 
-	logFile, errOpen := os.OpenFile(w.logFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	logFile, logFilePath, errOpen := createLogFile(w.logFilePath)
 	if errOpen != nil {
 		w.cleanupTempFiles()
 		return fmt.Errorf("failed to create log file: %w", errOpen)
 	}
+	w.logFilePath = logFilePath
 
 	writeErr := w.writeFinalLog(logFile)
 	if errClose := logFile.Close(); errClose != nil {
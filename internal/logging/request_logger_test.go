@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRequestLoggerReusedRequestIDKeepsEarlierLog(t *testing.T) {
+	logsDir := t.TempDir()
+	logger := NewFileRequestLogger(true, logsDir, "", 0)
+	headers := map[string][]string{RequestIDHeader: {"gw:7f3a"}}
+
+	for _, body := range []string{"first request", "second request"} {
+		if errLog := logger.LogRequest("/v1/chat/completions", "POST", headers, []byte(body), 200, nil, []byte("ok"), nil, nil, nil, nil, nil, "gw:7f3a", time.Now(), time.Now()); errLog != nil {
+			t.Fatalf("LogRequest() error = %v", errLog)
+		}
+	}
+
+	entries, errRead := os.ReadDir(logsDir)
+	if errRead != nil {
+		t.Fatalf("ReadDir() error = %v", errRead)
+	}
+	var contents []string
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".log") {
+			continue
+		}
+		if strings.Contains(entry.Name(), ":") {
+			t.Fatalf("log file name %q contains ':'", entry.Name())
+		}
+		data, errFile := os.ReadFile(filepath.Join(logsDir, entry.Name()))
+		if errFile != nil {
+			t.Fatalf("ReadFile() error = %v", errFile)
+		}
+		contents = append(contents, string(data))
+	}
+	if len(contents) != 2 {
+		t.Fatalf("log files = %d, want one per request", len(contents))
+	}
+	joined := strings.Join(contents, "\n")
+	for _, want := range []string{"first request", "second request", "gw:7f3a"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("logs lack %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestRequestIDFilenamePart(t *testing.T) {
+	for in, want := range map[string]string{"a1b2c3d4": "a1b2c3d4", "gw-7f3a:trace_01.2": "gw-7f3a-trace_01.2", ":..": ""} {
+		if got := RequestIDFilenamePart(in); got != want {
+			t.Fatalf("RequestIDFilenamePart(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,6 +15,13 @@ type requestIDKey struct{}
 // ginRequestIDKey is the Gin context key for request IDs.
 const ginRequestIDKey = "__request_id__"
 
+// RequestIDHeader carries the request ID to clients and to upstreams that accept extra
+// headers. An inbound value is reused as the request ID after SanitizeRequestID.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength caps client-supplied request IDs.
+const maxRequestIDLength = 64
+
 // GenerateRequestID creates a new 8-character hex request ID.
 func GenerateRequestID() string {
 	b := make([]byte, 4)
@@ -23,6 +31,22 @@ func GenerateRequestID() string {
 	return hex.EncodeToString(b)
 }
 
+// SanitizeRequestID returns value reduced to letters, digits and "-_.:" and capped at
+// 64 characters, so a client-supplied ID is safe to log and to send upstream.
+func SanitizeRequestID(value string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		if b.Len() >= maxRequestIDLength {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
 // WithRequestID returns a new context with the request ID attached.
 func WithRequestID(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, requestIDKey{}, requestID)
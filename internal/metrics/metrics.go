@@ -0,0 +1,228 @@
+// Package metrics collects proxy request, token, stream and credential metrics
+// with the Prometheus client library and exposes them on /metrics.
+//
+// Recording is a no-op until SetEnabled(true) is called, so the hooks in the
+// executors and the auth manager cost nothing when the endpoint is disabled.
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+	internallogging "github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+// otherLabel replaces label values that would otherwise be unbounded, such as
+// model names unknown to the registry.
+const otherLabel = "other"
+
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+var (
+	enabled atomic.Bool
+
+	// metricsRegistry holds only the proxy's own metrics, without the Go runtime and
+	// process collectors of the default Prometheus registry.
+	metricsRegistry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_requests_total",
+		Help: "Upstream requests by client handler, provider, model and status code.",
+	}, []string{"handler", "provider", "model", "status"})
+	upstreamLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cliproxy_upstream_latency_seconds",
+		Help:    "Upstream request latency in seconds by provider.",
+		Buckets: latencyBuckets,
+	}, []string{"provider"})
+	tokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_tokens_total",
+		Help: "Tokens reported by upstream providers by model and token type.",
+	}, []string{"model", "type"})
+	activeStreams = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cliproxy_active_streams",
+		Help: "Streaming responses currently being forwarded by provider.",
+	}, []string{"provider"})
+	authRefreshTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_auth_refresh_total",
+		Help: "Credential refresh attempts by provider and result.",
+	}, []string{"provider", "result"})
+	antigravityFallbacksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_antigravity_fallbacks_total",
+		Help: "Antigravity requests retried on the next base URL after a 429 response, by the rate limited base URL.",
+	}, []string{"base_url"})
+	responseCacheTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cliproxy_response_cache_total",
+		Help: "Response cache lookups of non-streaming requests by result (hit, miss or bypass).",
+	}, []string{"result"})
+
+	// resettable lists the vectors cleared by Reset.
+	resettable = []interface{ Reset() }{
+		requestsTotal, upstreamLatency, tokensTotal, activeStreams,
+		authRefreshTotal, antigravityFallbacksTotal, responseCacheTotal,
+	}
+
+	metricsHandler = promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+)
+
+func init() {
+	metricsRegistry.MustRegister(requestsTotal, upstreamLatency, tokensTotal, activeStreams,
+		authRefreshTotal, antigravityFallbacksTotal, responseCacheTotal)
+}
+
+// SetEnabled toggles metric recording and the /metrics endpoint.
+func SetEnabled(on bool) {
+	enabled.Store(on)
+}
+
+// Enabled reports whether metric recording is active.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// ObserveUsage records request count, upstream latency and token usage for a
+// completed upstream request.
+func ObserveUsage(ctx context.Context, record coreusage.Record) {
+	if !Enabled() {
+		return
+	}
+	provider := labelOrUnknown(record.Provider)
+	model := NormalizeModel(record.Model, record.Provider)
+	requestsTotal.WithLabelValues(handlerLabel(ctx), provider, model, statusLabel(record)).Inc()
+	if record.Latency > 0 {
+		upstreamLatency.WithLabelValues(provider).Observe(record.Latency.Seconds())
+	}
+	if tokens := record.Detail.InputTokens; tokens > 0 {
+		tokensTotal.WithLabelValues(model, "prompt").Add(float64(tokens))
+	}
+	if tokens := record.Detail.OutputTokens; tokens > 0 {
+		tokensTotal.WithLabelValues(model, "completion").Add(float64(tokens))
+	}
+}
+
+// StreamStarted increments the active streams gauge for provider and returns
+// the function that decrements it once the stream ends.
+func StreamStarted(provider string) func() {
+	if !Enabled() {
+		return func() {}
+	}
+	gauge := activeStreams.WithLabelValues(labelOrUnknown(provider))
+	gauge.Inc()
+	var done atomic.Bool
+	return func() {
+		if done.CompareAndSwap(false, true) {
+			gauge.Dec()
+		}
+	}
+}
+
+// ObserveAuthRefresh records the outcome of a credential refresh.
+func ObserveAuthRefresh(provider string, err error) {
+	if !Enabled() {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	authRefreshTotal.WithLabelValues(labelOrUnknown(provider), result).Inc()
+}
+
+// ObserveAntigravityFallback records a 429 on baseURL that moved an Antigravity
+// request on to the next fallback base URL.
+func ObserveAntigravityFallback(baseURL string) {
+	if !Enabled() {
+		return
+	}
+	antigravityFallbacksTotal.WithLabelValues(labelOrUnknown(strings.TrimRight(strings.TrimSpace(baseURL), "/"))).Inc()
+}
+
+// ObserveResponseCache records a response cache lookup. result is "hit", "miss"
+// or "bypass" for requests that were not eligible for the cache.
+func ObserveResponseCache(result string) {
+	if !Enabled() {
+		return
+	}
+	responseCacheTotal.WithLabelValues(labelOrUnknown(result)).Inc()
+}
+
+// NormalizeModel maps a model name onto a bounded label value. Thinking suffixes
+// such as "(8192)" are stripped and names unknown to the model registry collapse
+// into "other".
+func NormalizeModel(model, provider string) string {
+	base := strings.TrimSpace(thinking.ParseSuffix(strings.TrimSpace(model)).ModelName)
+	if base == "" {
+		return "unknown"
+	}
+	if info := registry.LookupModelInfo(base, provider); info != nil {
+		if id := strings.TrimSpace(info.ID); id != "" {
+			return id
+		}
+		return base
+	}
+	return otherLabel
+}
+
+// Write renders all recorded metrics in the Prometheus text format.
+func Write(w io.Writer) error {
+	families, errGather := metricsRegistry.Gather()
+	if errGather != nil {
+		return errGather
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if errEncode := encoder.Encode(family); errEncode != nil {
+			return errEncode
+		}
+	}
+	return nil
+}
+
+// Handler serves the recorded metrics. It answers 404 while metrics are
+// disabled so the route can stay registered across config reloads.
+func Handler(c *gin.Context) {
+	if !Enabled() {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
+
+// Reset clears every recorded series. It is intended for tests.
+func Reset() {
+	for _, vector := range resettable {
+		vector.Reset()
+	}
+}
+
+func handlerLabel(ctx context.Context) string {
+	return labelOrUnknown(internallogging.GetEndpoint(ctx))
+}
+
+func statusLabel(record coreusage.Record) string {
+	if !record.Failed {
+		return strconv.Itoa(http.StatusOK)
+	}
+	if record.Fail.StatusCode > 0 {
+		return strconv.Itoa(record.Fail.StatusCode)
+	}
+	return "error"
+}
+
+func labelOrUnknown(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
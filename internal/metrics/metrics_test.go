@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+func enableForTest(t *testing.T) {
+	t.Helper()
+	Reset()
+	SetEnabled(true)
+	t.Cleanup(func() {
+		SetEnabled(false)
+		Reset()
+	})
+}
+
+func TestDisabledRecordsNothing(t *testing.T) {
+	Reset()
+	SetEnabled(false)
+	ObserveUsage(context.Background(), coreusage.Record{Provider: "gemini", Model: "gemini-2.5-flash"})
+	StreamStarted("gemini")()
+	ObserveAuthRefresh("codex", nil)
+	if got, errGather := testutil.GatherAndCount(metricsRegistry); errGather != nil || got != 0 {
+		t.Fatalf("GatherAndCount() = %d, %v; want no series while disabled", got, errGather)
+	}
+}
+
+func TestRecordedSeries(t *testing.T) {
+	enableForTest(t)
+	ObserveUsage(context.Background(), coreusage.Record{
+		Provider: "gemini",
+		Model:    "not-a-registered-model",
+		Failed:   true,
+		Fail:     coreusage.Failure{StatusCode: http.StatusTooManyRequests},
+		Latency:  750 * time.Millisecond,
+	})
+	ObserveAuthRefresh("codex", nil)
+	ObserveAuthRefresh("codex", errors.New("invalid_grant"))
+	ObserveAntigravityFallback("https://daily.example.com/ ")
+	ObserveResponseCache("hit")
+
+	for name, tt := range map[string]struct {
+		counter prometheus.Collector
+		want    float64
+	}{
+		"request":         {counter: requestsTotal.WithLabelValues("unknown", "gemini", "other", "429"), want: 1},
+		"refresh success": {counter: authRefreshTotal.WithLabelValues("codex", "success"), want: 1},
+		"refresh failure": {counter: authRefreshTotal.WithLabelValues("codex", "failure"), want: 1},
+		"fallback":        {counter: antigravityFallbacksTotal.WithLabelValues("https://daily.example.com"), want: 1},
+		"response cache":  {counter: responseCacheTotal.WithLabelValues("hit"), want: 1},
+	} {
+		if got := testutil.ToFloat64(tt.counter); got != tt.want {
+			t.Errorf("%s = %v, want %v", name, got, tt.want)
+		}
+	}
+	if got := testutil.CollectAndCount(tokensTotal); got != 0 {
+		t.Errorf("tokens series = %d, want none without token samples", got)
+	}
+
+	wantLatency := `
+# HELP cliproxy_upstream_latency_seconds Upstream request latency in seconds by provider.
+# TYPE cliproxy_upstream_latency_seconds histogram
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="0.1"} 0
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="0.25"} 0
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="0.5"} 0
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="1"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="2.5"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="5"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="10"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="30"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="60"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="120"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="300"} 1
+cliproxy_upstream_latency_seconds_bucket{provider="gemini",le="+Inf"} 1
+cliproxy_upstream_latency_seconds_sum{provider="gemini"} 0.75
+cliproxy_upstream_latency_seconds_count{provider="gemini"} 1
+`
+	if errCompare := testutil.CollectAndCompare(upstreamLatency, strings.NewReader(wantLatency)); errCompare != nil {
+		t.Fatal(errCompare)
+	}
+}
+
+func TestStreamStartedGauge(t *testing.T) {
+	enableForTest(t)
+	first := StreamStarted("claude")
+	second := StreamStarted("claude")
+	if got := testutil.ToFloat64(activeStreams.WithLabelValues("claude")); got != 2 {
+		t.Fatalf("active streams after start = %v, want 2", got)
+	}
+	first()
+	first()
+	second()
+	if got := testutil.ToFloat64(activeStreams.WithLabelValues("claude")); got != 0 {
+		t.Fatalf("active streams after done = %v, want 0", got)
+	}
+}
+
+func TestNormalizeModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{model: "gemini-2.5-pro", want: "gemini-2.5-pro"},
+		{model: "gemini-2.5-pro(8192)", want: "gemini-2.5-pro"},
+		{model: "made-up-model-123", want: "other"},
+		{model: " ", want: "unknown"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeModel(tt.model, ""); got != tt.want {
+			t.Errorf("NormalizeModel(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	Reset()
+	SetEnabled(false)
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	Handler(ctx)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("disabled status = %d, want 404", rec.Code)
+	}
+
+	enableForTest(t)
+	ObserveAuthRefresh("gemini", nil)
+	rec = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	Handler(ctx)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("enabled response = %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `cliproxy_auth_refresh_total{provider="gemini",result="success"} 1`) {
+		t.Fatalf("body = %s", rec.Body.String())
+	}
+}
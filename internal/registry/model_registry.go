@@ -81,6 +81,9 @@ type ModelConfig struct {
 	// OverrideHeader forces upstream request headers when non-empty.
 	// Keys are header names (e.g. "user-agent"); values replace any existing header.
 	OverrideHeader map[string]string `json:"override_header,omitempty"`
+	// TimeoutSeconds is the default request time limit of the model; config
+	// model-timeouts entries override it. 0 means no limit.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
 }
 
 type availableModelsCacheEntry struct {
@@ -218,6 +221,15 @@ func ModelOverrideHeaders(modelID string, provider ...string) map[string]string
 	return out
 }
 
+// ModelDefaultTimeout returns models.json config.timeout_seconds for the model, or 0.
+func ModelDefaultTimeout(modelID string, provider ...string) time.Duration {
+	info := LookupModelInfo(modelID, provider...)
+	if info == nil || info.Config == nil || info.Config.TimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(info.Config.TimeoutSeconds) * time.Second
+}
+
 // SetHook sets an optional hook for observing model registration changes.
 func (r *ModelRegistry) SetHook(hook ModelRegistryHook) {
 	if r == nil {
@@ -0,0 +1,118 @@
+package requeststore
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+type captureKey struct{}
+
+// capture holds the request and response bodies of the latest upstream attempt.
+type capture struct {
+	mu                sync.Mutex
+	authLabel         string
+	status            int
+	request           []byte
+	requestTruncated  bool
+	response          []byte
+	responseTruncated bool
+}
+
+// WithCapture installs a per-request body capture in ctx. It is a no-op when one exists.
+func WithCapture(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if holder, ok := ctx.Value(captureKey{}).(*capture); ok && holder != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, captureKey{}, &capture{})
+}
+
+// FreezeCapture returns a context whose capture is a snapshot of the current one, so
+// asynchronous consumers are not affected by later attempts of the same request.
+func FreezeCapture(ctx context.Context) context.Context {
+	holder := captureFrom(ctx)
+	if holder == nil {
+		return ctx
+	}
+	holder.mu.Lock()
+	snapshot := &capture{
+		authLabel:         holder.authLabel,
+		status:            holder.status,
+		request:           bytes.Clone(holder.request),
+		requestTruncated:  holder.requestTruncated,
+		response:          bytes.Clone(holder.response),
+		responseTruncated: holder.responseTruncated,
+	}
+	holder.mu.Unlock()
+	return context.WithValue(ctx, captureKey{}, snapshot)
+}
+
+// CaptureRequest records the upstream request body of a new attempt and resets the
+// response captured for the previous one.
+func CaptureRequest(ctx context.Context, authLabel string, body []byte) {
+	store := Default()
+	holder := captureFrom(ctx)
+	if store == nil || holder == nil {
+		return
+	}
+	limit := store.opts.MaxBodyBytes
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	holder.authLabel = authLabel
+	holder.status = 0
+	holder.request, holder.requestTruncated = appendLimited(nil, body, limit)
+	holder.response, holder.responseTruncated = nil, false
+}
+
+// CaptureResponseStatus records the upstream status code of the latest attempt.
+func CaptureResponseStatus(ctx context.Context, status int) {
+	if status <= 0 || Default() == nil {
+		return
+	}
+	holder := captureFrom(ctx)
+	if holder == nil {
+		return
+	}
+	holder.mu.Lock()
+	holder.status = status
+	holder.mu.Unlock()
+}
+
+// CaptureResponseChunk appends an upstream response chunk of the latest attempt.
+func CaptureResponseChunk(ctx context.Context, chunk []byte) {
+	store := Default()
+	holder := captureFrom(ctx)
+	if store == nil || holder == nil || len(chunk) == 0 {
+		return
+	}
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	if holder.responseTruncated {
+		return
+	}
+	if len(holder.response) > 0 {
+		chunk = append([]byte("\n"), chunk...)
+	}
+	holder.response, holder.responseTruncated = appendLimited(holder.response, chunk, store.opts.MaxBodyBytes)
+}
+
+func captureFrom(ctx context.Context) *capture {
+	if ctx == nil {
+		return nil
+	}
+	holder, _ := ctx.Value(captureKey{}).(*capture)
+	return holder
+}
+
+func appendLimited(dst, data []byte, limit int) ([]byte, bool) {
+	if limit <= 0 || len(dst)+len(data) <= limit {
+		return append(dst, data...), false
+	}
+	if room := limit - len(dst); room > 0 {
+		dst = append(dst, data[:room]...)
+	}
+	return dst, true
+}
@@ -0,0 +1,95 @@
+package requeststore
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+)
+
+const defaultFileName = "requests.db"
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore *Store
+)
+
+// Default returns the process-wide store, or nil when the request store is disabled.
+func Default() *Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
+
+// OptionsFromConfig resolves the effective store options for cfg, applying defaults.
+func OptionsFromConfig(cfg *config.Config) Options {
+	if cfg == nil {
+		return Options{}
+	}
+	rs := cfg.RequestStore
+	opts := Options{
+		Path:         strings.TrimSpace(rs.Path),
+		MaxRows:      rs.MaxRows,
+		MaxBodyBytes: rs.MaxBodyBytes,
+		BufferSize:   rs.BufferSize,
+	}
+	if opts.Path == "" {
+		opts.Path = filepath.Join(logging.ResolveLogDirectory(cfg), defaultFileName)
+	}
+	if opts.MaxRows == 0 {
+		opts.MaxRows = DefaultMaxRows
+	}
+	if rs.MaxAgeHours > 0 {
+		opts.MaxAge = time.Duration(rs.MaxAgeHours) * time.Hour
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	return opts
+}
+
+// Configure opens, reopens or closes the process-wide store to match cfg. The current
+// store is kept when its options are unchanged.
+func Configure(cfg *config.Config) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if cfg == nil || !cfg.RequestStore.Enable {
+		return closeDefaultLocked()
+	}
+	opts := OptionsFromConfig(cfg)
+	if defaultStore != nil && defaultStore.opts == opts {
+		return nil
+	}
+	if errClose := closeDefaultLocked(); errClose != nil {
+		return errClose
+	}
+	store, errOpen := Open(opts)
+	if errOpen != nil {
+		return errOpen
+	}
+	defaultStore = store
+	return nil
+}
+
+// CloseDefault flushes and closes the process-wide store.
+func CloseDefault() error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return closeDefaultLocked()
+}
+
+func closeDefaultLocked() error {
+	if defaultStore == nil {
+		return nil
+	}
+	store := defaultStore
+	defaultStore = nil
+	return store.Close()
+}
@@ -0,0 +1,84 @@
+package requeststore
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	internallogging "github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(&requestStorePlugin{})
+}
+
+// requestStorePlugin turns usage records into stored request rows.
+type requestStorePlugin struct{}
+
+func (p *requestStorePlugin) HandleUsage(ctx context.Context, record coreusage.Record) {
+	store := Default()
+	if p == nil || store == nil {
+		return
+	}
+	store.Enqueue(buildEntry(ctx, record))
+}
+
+func buildEntry(ctx context.Context, record coreusage.Record) Entry {
+	timestamp := record.RequestedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	detail := record.Detail
+	entry := Entry{
+		Timestamp:       timestamp,
+		RequestID:       strings.TrimSpace(internallogging.GetRequestID(ctx)),
+		Endpoint:        strings.TrimSpace(internallogging.GetEndpoint(ctx)),
+		Provider:        strings.TrimSpace(record.Provider),
+		AuthID:          strings.TrimSpace(record.AuthID),
+		AuthIndex:       strings.TrimSpace(record.AuthIndex),
+		Model:           strings.TrimSpace(record.Model),
+		Alias:           strings.TrimSpace(record.Alias),
+		Failed:          record.Failed,
+		LatencyMs:       record.Latency.Milliseconds(),
+		InputTokens:     detail.InputTokens,
+		OutputTokens:    detail.OutputTokens,
+		ReasoningTokens: detail.ReasoningTokens,
+		CachedTokens:    detail.CachedTokens,
+		TotalTokens:     detail.TotalTokens,
+	}
+
+	status := 0
+	if holder := captureFrom(ctx); holder != nil {
+		holder.mu.Lock()
+		entry.AuthLabel = holder.authLabel
+		status = holder.status
+		entry.RequestBody = string(holder.request)
+		entry.RequestTruncated = holder.requestTruncated
+		entry.ResponseBody = string(holder.response)
+		entry.ResponseTruncated = holder.responseTruncated
+		holder.mu.Unlock()
+	}
+	if record.Failed && record.Fail.StatusCode > 0 {
+		status = record.Fail.StatusCode
+	}
+	if status <= 0 {
+		status = internallogging.GetResponseStatus(ctx)
+	}
+	if status <= 0 {
+		if record.Failed {
+			status = http.StatusInternalServerError
+		} else {
+			status = http.StatusOK
+		}
+	}
+	entry.StatusCode = status
+	if !entry.Failed && status >= http.StatusBadRequest {
+		entry.Failed = true
+	}
+	if entry.ResponseBody == "" && record.Failed {
+		entry.ResponseBody = strings.TrimSpace(record.Fail.Body)
+	}
+	return entry
+}
@@ -0,0 +1,426 @@
+// Package requeststore persists one summary row per upstream request in a bbolt database
+// so request history can be queried through the Management API instead of scanning log files.
+package requeststore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultMaxRows is the row limit applied when none is configured.
+	DefaultMaxRows = 10000
+	// DefaultMaxBodyBytes is the stored body size limit applied when none is configured.
+	DefaultMaxBodyBytes = 16 * 1024
+	// DefaultBufferSize is the write queue capacity applied when none is configured.
+	DefaultBufferSize = 1024
+	// DefaultQueryLimit is the page size used when a query does not specify one.
+	DefaultQueryLimit = 50
+	// MaxQueryLimit caps the page size of a single query.
+	MaxQueryLimit = 500
+
+	writeBatchSize = 128
+)
+
+var requestsBucket = []byte("requests")
+
+// Entry is a single stored request row.
+type Entry struct {
+	ID                uint64    `json:"id"`
+	Timestamp         time.Time `json:"timestamp"`
+	RequestID         string    `json:"request_id,omitempty"`
+	Endpoint          string    `json:"endpoint,omitempty"`
+	Provider          string    `json:"provider"`
+	AuthID            string    `json:"auth_id,omitempty"`
+	AuthIndex         string    `json:"auth_index,omitempty"`
+	AuthLabel         string    `json:"auth_label,omitempty"`
+	Model             string    `json:"model"`
+	Alias             string    `json:"alias,omitempty"`
+	StatusCode        int       `json:"status_code"`
+	Failed            bool      `json:"failed"`
+	LatencyMs         int64     `json:"latency_ms"`
+	InputTokens       int64     `json:"input_tokens"`
+	OutputTokens      int64     `json:"output_tokens"`
+	ReasoningTokens   int64     `json:"reasoning_tokens"`
+	CachedTokens      int64     `json:"cached_tokens"`
+	TotalTokens       int64     `json:"total_tokens"`
+	RequestBody       string    `json:"request_body,omitempty"`
+	RequestTruncated  bool      `json:"request_truncated,omitempty"`
+	ResponseBody      string    `json:"response_body,omitempty"`
+	ResponseTruncated bool      `json:"response_truncated,omitempty"`
+}
+
+// Options configures a Store.
+type Options struct {
+	// Path is the database file.
+	Path string
+	// MaxRows caps the number of rows. Zero or negative disables the limit.
+	MaxRows int
+	// MaxAge removes rows older than this duration. Zero disables age-based retention.
+	MaxAge time.Duration
+	// MaxBodyBytes truncates captured bodies.
+	MaxBodyBytes int
+	// BufferSize is the capacity of the write queue.
+	BufferSize int
+}
+
+// Filter selects rows returned by Query.
+type Filter struct {
+	// Model matches the upstream model or the client alias, case-insensitively.
+	Model string
+	// Provider matches the provider identifier, case-insensitively.
+	Provider string
+	// Status matches the status code exactly when positive.
+	Status int
+	// Since excludes rows recorded before this time when non-zero.
+	Since time.Time
+	// Before returns only rows with an ID lower than this cursor when positive.
+	Before uint64
+	// Limit is the page size; DefaultQueryLimit when zero, capped at MaxQueryLimit.
+	Limit int
+}
+
+// Stats reports store counters.
+type Stats struct {
+	Rows    int64  `json:"rows"`
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Pruned  uint64 `json:"pruned"`
+}
+
+// Store is a bbolt-backed request log with an asynchronous writer.
+type Store struct {
+	db   *bolt.DB
+	opts Options
+
+	queue   chan Entry
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	closed    atomic.Bool
+	closeOnce sync.Once
+
+	rows    atomic.Int64
+	written atomic.Uint64
+	dropped atomic.Uint64
+	pruned  atomic.Uint64
+
+	now func() time.Time
+}
+
+// Open opens or creates the database at opts.Path and starts the background writer.
+func Open(opts Options) (*Store, error) {
+	path := strings.TrimSpace(opts.Path)
+	if path == "" {
+		return nil, fmt.Errorf("requeststore: path is required")
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if errMkdir := os.MkdirAll(filepath.Dir(path), 0o755); errMkdir != nil {
+		return nil, fmt.Errorf("requeststore: create directory: %w", errMkdir)
+	}
+	db, errOpen := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if errOpen != nil {
+		return nil, fmt.Errorf("requeststore: open %s: %w", path, errOpen)
+	}
+	var rows int
+	errInit := db.Update(func(tx *bolt.Tx) error {
+		bucket, errBucket := tx.CreateBucketIfNotExists(requestsBucket)
+		if errBucket != nil {
+			return errBucket
+		}
+		rows = bucket.Stats().KeyN
+		return nil
+	})
+	if errInit != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("requeststore: init bucket: %w", errInit)
+	}
+
+	s := &Store{
+		db:      db,
+		opts:    opts,
+		queue:   make(chan Entry, opts.BufferSize),
+		flushes: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		now:     time.Now,
+	}
+	s.rows.Store(int64(rows))
+	go s.run()
+	return s, nil
+}
+
+// Options returns the effective options of the store.
+func (s *Store) Options() Options {
+	if s == nil {
+		return Options{}
+	}
+	return s.opts
+}
+
+// Enqueue schedules entry for writing without blocking. It returns false and counts the
+// entry as dropped when the write queue is full or the store is closed.
+func (s *Store) Enqueue(entry Entry) bool {
+	if s == nil {
+		return false
+	}
+	if s.closed.Load() {
+		s.dropped.Add(1)
+		return false
+	}
+	select {
+	case s.queue <- entry:
+		return true
+	default:
+		s.dropped.Add(1)
+		return false
+	}
+}
+
+// Flush blocks until every entry enqueued before the call has been written.
+func (s *Store) Flush() {
+	if s == nil || s.closed.Load() {
+		return
+	}
+	ack := make(chan struct{})
+	select {
+	case s.flushes <- ack:
+		<-ack
+	case <-s.done:
+	}
+}
+
+// Close drains the write queue and closes the database.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	var errClose error
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		close(s.stop)
+		<-s.done
+		errClose = s.db.Close()
+	})
+	return errClose
+}
+
+// Stats returns the current counters.
+func (s *Store) Stats() Stats {
+	if s == nil {
+		return Stats{}
+	}
+	return Stats{
+		Rows:    s.rows.Load(),
+		Written: s.written.Load(),
+		Dropped: s.dropped.Load(),
+		Pruned:  s.pruned.Load(),
+	}
+}
+
+// Query returns matching rows newest first. next is the cursor for the following page, or
+// zero when there are no more rows.
+func (s *Store) Query(filter Filter) (entries []Entry, next uint64, err error) {
+	if s == nil {
+		return nil, 0, nil
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	if limit > MaxQueryLimit {
+		limit = MaxQueryLimit
+	}
+	model := strings.TrimSpace(filter.Model)
+	provider := strings.TrimSpace(filter.Provider)
+
+	entries = make([]Entry, 0, limit)
+	err = s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		var key, value []byte
+		if filter.Before > 0 {
+			key, value = cursor.Seek(encodeKey(filter.Before))
+			if key == nil {
+				key, value = cursor.Last()
+			}
+			for key != nil && decodeKey(key) >= filter.Before {
+				key, value = cursor.Prev()
+			}
+		} else {
+			key, value = cursor.Last()
+		}
+		for ; key != nil; key, value = cursor.Prev() {
+			var entry Entry
+			if errUnmarshal := json.Unmarshal(value, &entry); errUnmarshal != nil {
+				continue
+			}
+			// Rows are keyed in insertion order, so older rows only follow once Since is passed.
+			if !filter.Since.IsZero() && entry.Timestamp.Before(filter.Since) {
+				break
+			}
+			if model != "" && !strings.EqualFold(entry.Model, model) && !strings.EqualFold(entry.Alias, model) {
+				continue
+			}
+			if provider != "" && !strings.EqualFold(entry.Provider, provider) {
+				continue
+			}
+			if filter.Status > 0 && entry.StatusCode != filter.Status {
+				continue
+			}
+			if len(entries) == limit {
+				next = entries[len(entries)-1].ID
+				return nil
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, next, err
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+	for {
+		select {
+		case entry := <-s.queue:
+			batch := append(make([]Entry, 0, writeBatchSize), entry)
+		collect:
+			for len(batch) < writeBatchSize {
+				select {
+				case more := <-s.queue:
+					batch = append(batch, more)
+				default:
+					break collect
+				}
+			}
+			s.write(batch)
+		case ack := <-s.flushes:
+			s.write(s.drain())
+			close(ack)
+		case <-s.stop:
+			s.write(s.drain())
+			return
+		}
+	}
+}
+
+func (s *Store) drain() []Entry {
+	var batch []Entry
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+		default:
+			return batch
+		}
+	}
+}
+
+func (s *Store) write(batch []Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	var added, removed int
+	errUpdate := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+		if bucket == nil {
+			return fmt.Errorf("bucket %s missing", requestsBucket)
+		}
+		for i := range batch {
+			id, errSeq := bucket.NextSequence()
+			if errSeq != nil {
+				return errSeq
+			}
+			batch[i].ID = id
+			data, errMarshal := json.Marshal(batch[i])
+			if errMarshal != nil {
+				continue
+			}
+			if errPut := bucket.Put(encodeKey(id), data); errPut != nil {
+				return errPut
+			}
+			added++
+		}
+		var errPrune error
+		removed, errPrune = s.prune(bucket, s.rows.Load()+int64(added))
+		return errPrune
+	})
+	if errUpdate != nil {
+		log.WithError(errUpdate).Warn("requeststore: failed to write request rows")
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+	s.rows.Add(int64(added - removed))
+	s.written.Add(uint64(added))
+	s.pruned.Add(uint64(removed))
+}
+
+// prune removes the oldest rows while the bucket exceeds MaxRows or holds rows older than MaxAge.
+func (s *Store) prune(bucket *bolt.Bucket, rows int64) (int, error) {
+	maxRows := int64(s.opts.MaxRows)
+	var cutoff time.Time
+	if s.opts.MaxAge > 0 {
+		cutoff = s.now().Add(-s.opts.MaxAge)
+	}
+	if maxRows <= 0 && cutoff.IsZero() {
+		return 0, nil
+	}
+
+	var stale [][]byte
+	cursor := bucket.Cursor()
+	for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+		if maxRows > 0 && rows-int64(len(stale)) > maxRows {
+			stale = append(stale, append([]byte(nil), key...))
+			continue
+		}
+		if cutoff.IsZero() {
+			break
+		}
+		var row struct {
+			Timestamp time.Time `json:"timestamp"`
+		}
+		if errUnmarshal := json.Unmarshal(value, &row); errUnmarshal == nil && !row.Timestamp.Before(cutoff) {
+			break
+		}
+		stale = append(stale, append([]byte(nil), key...))
+	}
+	for _, key := range stale {
+		if errDelete := bucket.Delete(key); errDelete != nil {
+			return 0, errDelete
+		}
+	}
+	return len(stale), nil
+}
+
+func encodeKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+func decodeKey(key []byte) uint64 {
+	if len(key) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(key)
+}
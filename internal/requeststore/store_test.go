@@ -0,0 +1,175 @@
+package requeststore
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+func openTestStore(t *testing.T, opts Options) *Store {
+	t.Helper()
+	if opts.Path == "" {
+		opts.Path = filepath.Join(t.TempDir(), "requests.db")
+	}
+	store, errOpen := Open(opts)
+	if errOpen != nil {
+		t.Fatalf("Open() error = %v", errOpen)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestStoreRetainsNewestRowsUpToMaxRows(t *testing.T) {
+	store := openTestStore(t, Options{MaxRows: 3})
+	for i := 0; i < 5; i++ {
+		store.Enqueue(Entry{Timestamp: time.Now(), Model: "m", StatusCode: 200 + i})
+	}
+	store.Flush()
+
+	entries, next, errQuery := store.Query(Filter{})
+	if errQuery != nil {
+		t.Fatalf("Query() error = %v", errQuery)
+	}
+	if len(entries) != 3 || next != 0 {
+		t.Fatalf("Query() returned %d rows (next %d), want 3 rows", len(entries), next)
+	}
+	if entries[0].StatusCode != 204 || entries[2].StatusCode != 202 {
+		t.Fatalf("unexpected rows retained: %+v", entries)
+	}
+	if stats := store.Stats(); stats.Rows != 3 || stats.Written != 5 || stats.Pruned != 2 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+}
+
+func TestStorePrunesRowsOlderThanMaxAge(t *testing.T) {
+	store := openTestStore(t, Options{MaxAge: time.Hour})
+	now := time.Now()
+	store.Enqueue(Entry{Timestamp: now.Add(-2 * time.Hour), Model: "old"})
+	store.Enqueue(Entry{Timestamp: now, Model: "new"})
+	store.Flush()
+
+	entries, _, errQuery := store.Query(Filter{})
+	if errQuery != nil {
+		t.Fatalf("Query() error = %v", errQuery)
+	}
+	if len(entries) != 1 || entries[0].Model != "new" {
+		t.Fatalf("Query() = %+v, want only the new row", entries)
+	}
+}
+
+func TestStoreEnqueueDropsWhenBufferFull(t *testing.T) {
+	store := &Store{queue: make(chan Entry, 1)}
+	if !store.Enqueue(Entry{Model: "a"}) {
+		t.Fatal("first Enqueue() = false, want true")
+	}
+	if store.Enqueue(Entry{Model: "b"}) {
+		t.Fatal("Enqueue() on a full buffer = true, want false")
+	}
+	if got := store.Stats().Dropped; got != 1 {
+		t.Fatalf("Dropped = %d, want 1", got)
+	}
+}
+
+func TestStoreQueryFiltersAndPaginates(t *testing.T) {
+	store := openTestStore(t, Options{})
+	base := time.Now().Add(-time.Minute)
+	for i := 0; i < 6; i++ {
+		model := "gemini-2.5-flash"
+		status := 200
+		if i%2 == 1 {
+			model = "claude-sonnet-4-5"
+			status = 429
+		}
+		store.Enqueue(Entry{Timestamp: base.Add(time.Duration(i) * time.Second), Model: model, Provider: "p", StatusCode: status})
+	}
+	store.Flush()
+
+	page, next, errQuery := store.Query(Filter{Model: "GEMINI-2.5-flash", Limit: 2})
+	if errQuery != nil {
+		t.Fatalf("Query() error = %v", errQuery)
+	}
+	if len(page) != 2 || next == 0 {
+		t.Fatalf("first page = %d rows, next = %d", len(page), next)
+	}
+	rest, next, errQuery := store.Query(Filter{Model: "gemini-2.5-flash", Limit: 2, Before: next})
+	if errQuery != nil {
+		t.Fatalf("Query() error = %v", errQuery)
+	}
+	if len(rest) != 1 || next != 0 || rest[0].ID >= page[1].ID {
+		t.Fatalf("second page = %+v, next = %d", rest, next)
+	}
+
+	failed, _, _ := store.Query(Filter{Status: 429})
+	if len(failed) != 3 {
+		t.Fatalf("status filter returned %d rows, want 3", len(failed))
+	}
+	recent, _, _ := store.Query(Filter{Since: base.Add(3 * time.Second)})
+	if len(recent) != 3 {
+		t.Fatalf("since filter returned %d rows, want 3", len(recent))
+	}
+}
+
+func TestStoreReopenKeepsRowsAndSequence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.db")
+	store, errOpen := Open(Options{Path: path})
+	if errOpen != nil {
+		t.Fatalf("Open() error = %v", errOpen)
+	}
+	store.Enqueue(Entry{Timestamp: time.Now(), Model: "m"})
+	if errClose := store.Close(); errClose != nil {
+		t.Fatalf("Close() error = %v", errClose)
+	}
+
+	reopened := openTestStore(t, Options{Path: path})
+	if got := reopened.Stats().Rows; got != 1 {
+		t.Fatalf("Rows after reopen = %d, want 1", got)
+	}
+	reopened.Enqueue(Entry{Timestamp: time.Now(), Model: "m"})
+	reopened.Flush()
+	entries, _, _ := reopened.Query(Filter{})
+	if len(entries) != 2 || entries[0].ID != 2 {
+		t.Fatalf("entries after reopen = %+v", entries)
+	}
+}
+
+func TestBuildEntryUsesCaptureAndTruncatesBodies(t *testing.T) {
+	store := openTestStore(t, Options{MaxBodyBytes: 8})
+	defaultMu.Lock()
+	defaultStore = store
+	defaultMu.Unlock()
+	t.Cleanup(func() {
+		defaultMu.Lock()
+		defaultStore = nil
+		defaultMu.Unlock()
+	})
+
+	ctx := WithCapture(context.Background())
+	CaptureRequest(ctx, "stale", []byte("first attempt"))
+	CaptureResponseChunk(ctx, []byte("stale"))
+	CaptureRequest(ctx, "user@example.com", []byte(`{"model":"m"}`))
+	CaptureResponseStatus(ctx, 429)
+	CaptureResponseChunk(ctx, []byte(`{"error":"quota"}`))
+	frozen := FreezeCapture(ctx)
+	CaptureRequest(ctx, "next", nil)
+
+	entry := buildEntry(frozen, coreusage.Record{
+		Provider: "gemini",
+		Model:    "m",
+		Failed:   true,
+		Latency:  1500 * time.Millisecond,
+		Detail:   coreusage.Detail{InputTokens: 3, OutputTokens: 2, TotalTokens: 5},
+	})
+	if entry.AuthLabel != "user@example.com" || entry.StatusCode != 429 || entry.LatencyMs != 1500 || entry.TotalTokens != 5 {
+		t.Fatalf("buildEntry() = %+v", entry)
+	}
+	if entry.RequestBody != `{"model"` || !entry.RequestTruncated {
+		t.Fatalf("request body = %q (truncated %t)", entry.RequestBody, entry.RequestTruncated)
+	}
+	if !strings.HasPrefix(entry.ResponseBody, `{"error"`) || !entry.ResponseTruncated {
+		t.Fatalf("response body = %q (truncated %t)", entry.ResponseBody, entry.ResponseTruncated)
+	}
+}
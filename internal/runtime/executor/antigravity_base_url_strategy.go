@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+)
+
+const (
+	antigravityBaseURLStrategyFixed       = "fixed"
+	antigravityBaseURLStrategyRoundRobin  = "round_robin"
+	antigravityBaseURLStrategyLeastErrors = "least_errors"
+)
+
+var antigravityBaseURLCounters sync.Map // auth ID -> *atomic.Uint64
+
+// antigravityBaseURLOrder returns the base URLs one request tries, in order. The strategy
+// only picks where the fallback chain starts; every URL is still tried.
+func antigravityBaseURLOrder(cfg *config.Config, auth *cliproxyauth.Auth) []string {
+	baseURLs := antigravityBaseURLFallbackOrder(auth)
+	if len(baseURLs) < 2 {
+		return baseURLs
+	}
+	start := 0
+	switch antigravityBaseURLStrategy(cfg, auth) {
+	case antigravityBaseURLStrategyRoundRobin:
+		authID := ""
+		if auth != nil {
+			authID = auth.ID
+		}
+		counter, _ := antigravityBaseURLCounters.LoadOrStore(authID, new(atomic.Uint64))
+		start = int((counter.(*atomic.Uint64).Add(1) - 1) % uint64(len(baseURLs)))
+	case antigravityBaseURLStrategyLeastErrors:
+		start = leastFailedAntigravityBaseURL(baseURLs, time.Now())
+	}
+	if start == 0 {
+		return baseURLs
+	}
+	ordered := make([]string, 0, len(baseURLs))
+	ordered = append(ordered, baseURLs[start:]...)
+	return append(ordered, baseURLs[:start]...)
+}
+
+// ForgetAntigravityBaseURLCounterForAuthID drops the round_robin position of a removed
+// Antigravity auth.
+func ForgetAntigravityBaseURLCounterForAuthID(authID string) {
+	authID = strings.TrimSpace(authID)
+	if authID == "" {
+		return
+	}
+	antigravityBaseURLCounters.Delete(authID)
+}
+
+// antigravityBaseURLStrategy resolves the strategy from the auth's base_url_strategy
+// attribute, then the config. Unknown values fall back to fixed.
+func antigravityBaseURLStrategy(cfg *config.Config, auth *cliproxyauth.Auth) string {
+	raw := ""
+	if auth != nil && auth.Attributes != nil {
+		raw = auth.Attributes["base_url_strategy"]
+	}
+	if strings.TrimSpace(raw) == "" && cfg != nil {
+		raw = cfg.AntigravityBaseURLStrategy
+	}
+	switch strategy := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(raw)), "-", "_"); strategy {
+	case antigravityBaseURLStrategyRoundRobin, antigravityBaseURLStrategyLeastErrors:
+		return strategy
+	default:
+		return antigravityBaseURLStrategyFixed
+	}
+}
+
+// observeAntigravityBaseURL records an upstream call in the endpoint health, which
+// least_errors reads back.
+func observeAntigravityBaseURL(baseURL string, statusCode int, errDo error) {
+	recordAntigravityEndpointHealth(baseURL, statusCode, errDo, time.Now())
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func withAntigravityBaseURLs(t *testing.T, baseURLs ...string) {
+	t.Helper()
+	originalOrder := antigravityBaseURLFallbackOrder
+	antigravityBaseURLFallbackOrder = func(*cliproxyauth.Auth) []string { return append([]string(nil), baseURLs...) }
+	t.Cleanup(func() { antigravityBaseURLFallbackOrder = originalOrder })
+}
+
+func TestAntigravityBaseURLOrderRoundRobinRotatesPerAuth(t *testing.T) {
+	withAntigravityBaseURLs(t, "https://a", "https://b", "https://c")
+	cfg := &config.Config{AntigravityBaseURLStrategy: "round-robin"}
+	auth := &cliproxyauth.Auth{ID: fmt.Sprintf("round-robin-%d", time.Now().UnixNano())}
+	other := &cliproxyauth.Auth{ID: auth.ID + "-other"}
+
+	want := [][]string{
+		{"https://a", "https://b", "https://c"},
+		{"https://b", "https://c", "https://a"},
+		{"https://c", "https://a", "https://b"},
+		{"https://a", "https://b", "https://c"},
+	}
+	for i, wantOrder := range want {
+		if got := antigravityBaseURLOrder(cfg, auth); !reflect.DeepEqual(got, wantOrder) {
+			t.Fatalf("request %d order = %v, want %v", i, got, wantOrder)
+		}
+	}
+	if got := antigravityBaseURLOrder(cfg, other); got[0] != "https://a" {
+		t.Fatalf("another auth starts at %q, want its own counter starting at https://a", got[0])
+	}
+}
+
+func TestForgetAntigravityBaseURLCounterForAuthID(t *testing.T) {
+	withAntigravityBaseURLs(t, "https://a", "https://b")
+	cfg := &config.Config{AntigravityBaseURLStrategy: "round-robin"}
+	auth := &cliproxyauth.Auth{ID: fmt.Sprintf("round-robin-removed-%d", time.Now().UnixNano())}
+	antigravityBaseURLOrder(cfg, auth)
+
+	ForgetAntigravityBaseURLCounterForAuthID(auth.ID)
+	if _, ok := antigravityBaseURLCounters.Load(auth.ID); ok {
+		t.Fatal("round_robin counter of a removed auth was kept")
+	}
+}
+
+func TestAntigravityBaseURLOrderFixedByDefault(t *testing.T) {
+	withAntigravityBaseURLs(t, "https://a", "https://b")
+	auth := &cliproxyauth.Auth{ID: "fixed-order"}
+	for i := 0; i < 3; i++ {
+		if got := antigravityBaseURLOrder(&config.Config{}, auth); !reflect.DeepEqual(got, []string{"https://a", "https://b"}) {
+			t.Fatalf("request %d order = %v, want the fallback order", i, got)
+		}
+	}
+
+	auth.Attributes = map[string]string{"base_url_strategy": "fixed"}
+	if got := antigravityBaseURLOrder(&config.Config{AntigravityBaseURLStrategy: "round_robin"}, auth); got[0] != "https://a" {
+		t.Fatalf("auth attribute did not override config: %v", got)
+	}
+}
+
+func TestLeastFailedAntigravityBaseURLReadsEndpointHealth(t *testing.T) {
+	prefix := fmt.Sprintf("https://least-failed-%d", time.Now().UnixNano())
+	a, b, c := prefix+"-a", prefix+"-b", prefix+"-c"
+	baseURLs := []string{a, b, c}
+	now := time.Now()
+
+	if got := leastFailedAntigravityBaseURL(baseURLs, now); got != 0 {
+		t.Fatalf("leastFailed without failures = %d, want 0", got)
+	}
+	recordAntigravityEndpointHealth(a, http.StatusTooManyRequests, nil, now)
+	recordAntigravityEndpointHealth(a, 0, errors.New("connection reset"), now)
+	recordAntigravityEndpointHealth(b, http.StatusBadGateway, nil, now)
+	recordAntigravityEndpointHealth(c, http.StatusOK, nil, now)
+	recordAntigravityEndpointHealth(c, http.StatusBadRequest, nil, now)
+	if got := leastFailedAntigravityBaseURL(baseURLs, now); got != 2 {
+		t.Fatalf("leastFailed = %d, want 2 (no failures)", got)
+	}
+	later := now.Add(3 * time.Minute)
+	recordAntigravityEndpointHealth(c, http.StatusServiceUnavailable, nil, later)
+	recordAntigravityEndpointHealth(c, http.StatusServiceUnavailable, nil, later)
+	if got := leastFailedAntigravityBaseURL(baseURLs, later); got != 1 {
+		t.Fatalf("leastFailed = %d, want 1 (one failure)", got)
+	}
+	// The failures of a and b leave the window; c's are still recent.
+	if got := leastFailedAntigravityBaseURL(baseURLs, now.Add(6*time.Minute)); got != 0 {
+		t.Fatalf("leastFailed after the window = %d, want 0", got)
+	}
+}
+
+func TestAntigravityExecutorRoundRobinKeepsFallbackChain(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	var limitB bool
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			mu.Lock()
+			hits = append(hits, name)
+			limited := name == "b" && limitB
+			mu.Unlock()
+			if limited {
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`rate limited`))
+				return
+			}
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(`data: {"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}}` + "\n\n"))
+		}))
+	}
+	serverA, serverB := newServer("a"), newServer("b")
+	defer serverA.Close()
+	defer serverB.Close()
+	withAntigravityBaseURLs(t, serverA.URL, serverB.URL)
+
+	exec := NewAntigravityExecutor(&config.Config{AntigravityBaseURLStrategy: "round_robin"})
+	auth := &cliproxyauth.Auth{
+		ID:       fmt.Sprintf("round-robin-executor-%d", time.Now().UnixNano()),
+		Provider: "antigravity",
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	send := func() []string {
+		t.Helper()
+		mu.Lock()
+		hits = nil
+		mu.Unlock()
+		payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+		result, errExecute := exec.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{
+			Model:   "gemini-2.5-flash",
+			Payload: payload,
+		}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, Stream: true, OriginalRequest: payload})
+		if errExecute != nil {
+			t.Fatalf("ExecuteStream() error = %v", errExecute)
+		}
+		for chunk := range result.Chunks {
+			if chunk.Err != nil {
+				t.Fatalf("stream chunk error: %v", chunk.Err)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), hits...)
+	}
+
+	if got := send(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("first request hit %v, want [a]", got)
+	}
+	if got := send(); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("second request hit %v, want [b]", got)
+	}
+	mu.Lock()
+	limitB = true
+	mu.Unlock()
+	if got := send(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("third request hit %v, want [a]", got)
+	}
+	if got := send(); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Fatalf("fourth request hit %v, want [b a] (rate limited start falls back)", got)
+	}
+}
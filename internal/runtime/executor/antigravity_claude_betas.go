@@ -0,0 +1,136 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// antigravityClaudeBeta describes how a Claude beta that changes model behavior is carried
+// over to a claude-* model served by Antigravity.
+type antigravityClaudeBeta struct {
+	// prefix matches the beta name without its date suffix.
+	prefix string
+	// target names the Gemini-side setting that carries the beta, for the transformation
+	// trace. Empty means Antigravity has no equivalent and the beta is dropped with a
+	// warning.
+	target string
+	// outputCeiling raises the maxOutputTokens ceiling of the model to this value.
+	outputCeiling int
+}
+
+// antigravityClaudeBetas lists the Claude betas detected on requests for Antigravity
+// claude-* models. Betas not listed here are dropped silently, as they do not change
+// the response.
+var antigravityClaudeBetas = []antigravityClaudeBeta{
+	// Antigravity returns thoughts between tool calls whenever thinking is enabled, and the
+	// thinking config already asks for them through includeThoughts.
+	{prefix: "interleaved-thinking", target: "thinkingConfig.includeThoughts"},
+	{prefix: "output-128k", target: "maxOutputTokens", outputCeiling: 128000},
+	{prefix: "fine-grained-tool-streaming"},
+	{prefix: "token-efficient-tools"},
+	{prefix: "context-management"},
+	{prefix: "context-1m"},
+	{prefix: "redact-thinking"},
+	{prefix: "computer-use"},
+	{prefix: "code-execution"},
+	{prefix: "files-api"},
+	{prefix: "mcp-client"},
+}
+
+// antigravityClaudeBetaWarned records the listed betas already warned about, so a client
+// sending a beta on every request logs one warning rather than one per request.
+var antigravityClaudeBetaWarned sync.Map
+
+// extractAntigravityClaudeBetas removes the betas array from a Claude request and returns
+// it together with the betas of the anthropic-beta headers, without duplicates.
+func extractAntigravityClaudeBetas(payload []byte, headers http.Header) ([]string, []byte) {
+	bodyBetas, payload := extractAndRemoveBetas(payload)
+	var betas []string
+	seen := make(map[string]struct{})
+	add := func(beta string) {
+		beta = strings.TrimSpace(beta)
+		if beta == "" {
+			return
+		}
+		if _, ok := seen[beta]; ok {
+			return
+		}
+		seen[beta] = struct{}{}
+		betas = append(betas, beta)
+	}
+	for _, beta := range bodyBetas {
+		add(beta)
+	}
+	for _, value := range headers.Values("Anthropic-Beta") {
+		for _, beta := range strings.Split(value, ",") {
+			add(beta)
+		}
+	}
+	return betas, payload
+}
+
+// applyAntigravityClaudeBetas maps the detected Claude betas of a claude-* model and
+// returns the maxOutputTokens ceiling they allow, or 0 to keep the model's. Betas without
+// an equivalent are logged and noted in the transformation trace.
+func applyAntigravityClaudeBetas(ctx context.Context, modelName string, betas []string) int {
+	outputCeiling := 0
+	for _, beta := range betas {
+		spec, ok := lookupAntigravityClaudeBeta(beta)
+		if !ok {
+			continue
+		}
+		if spec.target == "" {
+			entry := log.WithFields(log.Fields{
+				"component": "claude_betas",
+				"executor":  "antigravity",
+				"model":     modelName,
+				"beta":      beta,
+				"action":    "drop",
+			})
+			if _, warned := antigravityClaudeBetaWarned.LoadOrStore(spec.prefix, struct{}{}); warned {
+				entry.Debug("antigravity executor: Claude beta has no Antigravity equivalent")
+			} else {
+				entry.Warn("antigravity executor: Claude beta has no Antigravity equivalent")
+			}
+			transformtrace.Record(ctx, "claude-beta: %s dropped (no antigravity equivalent)", beta)
+			continue
+		}
+		outputCeiling = max(outputCeiling, spec.outputCeiling)
+		transformtrace.Record(ctx, "claude-beta: %s -> %s", beta, spec.target)
+	}
+	return outputCeiling
+}
+
+func lookupAntigravityClaudeBeta(beta string) (antigravityClaudeBeta, bool) {
+	for _, spec := range antigravityClaudeBetas {
+		if strings.HasPrefix(beta, spec.prefix) {
+			return spec, true
+		}
+	}
+	return antigravityClaudeBeta{}, false
+}
+
+// clampAntigravityMaxOutputTokens caps maxOutputTokens to the model's max_completion_tokens
+// from the registry, or to outputCeiling when that is higher.
+func clampAntigravityMaxOutputTokens(modelName string, payload []byte, outputCeiling int) []byte {
+	maxOut := gjson.GetBytes(payload, "request.generationConfig.maxOutputTokens")
+	if !maxOut.Exists() || maxOut.Type != gjson.Number {
+		return payload
+	}
+	modelInfo := registry.LookupModelInfo(modelName, "antigravity")
+	if modelInfo == nil || modelInfo.MaxCompletionTokens <= 0 {
+		return payload
+	}
+	if ceiling := max(modelInfo.MaxCompletionTokens, outputCeiling); int(maxOut.Int()) > ceiling {
+		payload, _ = sjson.SetBytes(payload, "request.generationConfig.maxOutputTokens", ceiling)
+	}
+	return payload
+}
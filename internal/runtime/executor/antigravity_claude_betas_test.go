@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/tidwall/gjson"
+)
+
+// translateAntigravityClaudeBetaRequest translates and finalizes a Claude request for
+// claude-sonnet-4-6 on Antigravity and returns the payload and the recorded trace steps.
+func translateAntigravityClaudeBetaRequest(t *testing.T, payload []byte, headers http.Header) ([]byte, []string) {
+	t.Helper()
+	trace := transformtrace.New()
+	ctx := transformtrace.NewContext(context.Background(), trace)
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-6(high)", Payload: payload}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, Headers: headers}
+	executor := NewAntigravityExecutor(&config.Config{})
+	translated, errTranslate := executor.translateAntigravityRequest(ctx, sdktranslator.FormatClaude, "claude-sonnet-4-6", req, opts, false)
+	if errTranslate != nil {
+		t.Fatalf("translateAntigravityRequest() error = %v", errTranslate)
+	}
+	return executor.finalizeAntigravityPayload(ctx, "claude-sonnet-4-6", translated, "project-1"), trace.Steps()
+}
+
+func TestAntigravityClaudeBetaExtendedOutputRaisesMaxOutputTokensCeiling(t *testing.T) {
+	modelInfo := registry.LookupModelInfo("claude-sonnet-4-6", "antigravity")
+	if modelInfo == nil || modelInfo.MaxCompletionTokens == 0 || modelInfo.MaxCompletionTokens >= 100000 {
+		t.Fatalf("claude-sonnet-4-6 antigravity max completion tokens = %v, want a ceiling below 100000", modelInfo)
+	}
+	request := func(maxTokens string, betas string) []byte {
+		return []byte(`{"model":"claude-sonnet-4-6","max_tokens":` + maxTokens + `,"messages":[{"role":"user","content":"hi"}]` + betas + `}`)
+	}
+
+	translated, steps := translateAntigravityClaudeBetaRequest(t, request("100000", `,"betas":["output-128k-2025-02-19"]`), nil)
+	if got := gjson.GetBytes(translated, "request.generationConfig.maxOutputTokens").Int(); got != 100000 {
+		t.Fatalf("maxOutputTokens = %d, want the requested 100000; payload = %s", got, translated)
+	}
+	if !slices.Contains(steps, "claude-beta: output-128k-2025-02-19 -> maxOutputTokens") {
+		t.Fatalf("trace = %v, want the output-128k mapping", steps)
+	}
+
+	headers := http.Header{"Anthropic-Beta": {"output-128k-2025-02-19"}}
+	capped, _ := translateAntigravityClaudeBetaRequest(t, request("200000", ""), headers)
+	if got := gjson.GetBytes(capped, "request.generationConfig.maxOutputTokens").Int(); got != 128000 {
+		t.Fatalf("maxOutputTokens = %d, want the beta ceiling 128000; payload = %s", got, capped)
+	}
+
+	plain, _ := translateAntigravityClaudeBetaRequest(t, request("100000", ""), nil)
+	if got := gjson.GetBytes(plain, "request.generationConfig.maxOutputTokens").Int(); got != int64(modelInfo.MaxCompletionTokens) {
+		t.Fatalf("maxOutputTokens without the beta = %d, want the model ceiling %d", got, modelInfo.MaxCompletionTokens)
+	}
+}
+
+func TestAntigravityClaudeBetaInterleavedThinkingIsTraced(t *testing.T) {
+	payload := []byte(`{"model":"claude-sonnet-4-6","max_tokens":4096,"messages":[{"role":"user","content":"hi"}]}`)
+	headers := http.Header{"Anthropic-Beta": {"interleaved-thinking-2025-05-14"}}
+
+	translated, steps := translateAntigravityClaudeBetaRequest(t, payload, headers)
+
+	if !gjson.GetBytes(translated, "request.generationConfig.thinkingConfig.includeThoughts").Bool() {
+		t.Fatalf("includeThoughts not set for a thinking request: %s", translated)
+	}
+	if want := []string{"claude-beta: interleaved-thinking-2025-05-14 -> thinkingConfig.includeThoughts"}; !slices.Equal(claudeBetaSteps(steps), want) {
+		t.Fatalf("trace = %v, want %v", steps, want)
+	}
+}
+
+func TestAntigravityClaudeBetaWithoutEquivalentIsWarnedOnceAndTraced(t *testing.T) {
+	antigravityClaudeBetaWarned.Clear()
+	t.Cleanup(antigravityClaudeBetaWarned.Clear)
+	hook := test.NewLocal(log.StandardLogger())
+	t.Cleanup(hook.Reset)
+	previousLevel := log.GetLevel()
+	log.SetLevel(log.DebugLevel)
+	t.Cleanup(func() { log.SetLevel(previousLevel) })
+	payload := []byte(`{"model":"claude-sonnet-4-6","max_tokens":4096,"messages":[{"role":"user","content":"hi"}]}`)
+	headers := http.Header{"Anthropic-Beta": {"claude-code-20250219, fine-grained-tool-streaming-2025-05-14, computer-use-2025-01-24"}}
+
+	translated, steps := translateAntigravityClaudeBetaRequest(t, payload, headers)
+	_, _ = translateAntigravityClaudeBetaRequest(t, payload, headers)
+
+	plain, _ := translateAntigravityClaudeBetaRequest(t, payload, nil)
+	if got, want := gjson.GetBytes(translated, "request").Raw, gjson.GetBytes(plain, "request").Raw; got != want {
+		t.Fatalf("request = %s, want it unchanged by the betas: %s", got, want)
+	}
+	want := []string{
+		"claude-beta: fine-grained-tool-streaming-2025-05-14 dropped (no antigravity equivalent)",
+		"claude-beta: computer-use-2025-01-24 dropped (no antigravity equivalent)",
+	}
+	if !slices.Equal(claudeBetaSteps(steps), want) {
+		t.Fatalf("trace = %v, want %v", steps, want)
+	}
+	levels := make(map[any][]log.Level)
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["component"] != "claude_betas" {
+			continue
+		}
+		if entry.Data["beta"] == "claude-code-20250219" {
+			t.Fatalf("unlisted beta was logged: %v", entry.Data)
+		}
+		levels[entry.Data["beta"]] = append(levels[entry.Data["beta"]], entry.Level)
+	}
+	for _, beta := range []string{"fine-grained-tool-streaming-2025-05-14", "computer-use-2025-01-24"} {
+		if got := levels[beta]; !slices.Equal(got, []log.Level{log.WarnLevel, log.DebugLevel}) {
+			t.Fatalf("log levels for %s = %v, want one warning then debug", beta, got)
+		}
+	}
+}
+
+func claudeBetaSteps(steps []string) []string {
+	var out []string
+	for _, step := range steps {
+		if strings.HasPrefix(step, "claude-beta:") {
+			out = append(out, step)
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func TestAntigravityClaudeNonStreamPublishesTerminalUsageOnce(t *testing.T) {
+	// Intermediate chunks carry running partial usage; only the terminal chunk
+	// has the billed totals.
+	events := []string{
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}],"usageMetadata":{"promptTokenCount":40,"candidatesTokenCount":1,"totalTokenCount":41}}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}],"usageMetadata":{"promptTokenCount":40,"candidatesTokenCount":2,"totalTokenCount":42}}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"!"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":40,"candidatesTokenCount":3,"totalTokenCount":43}}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			_, _ = w.Write([]byte("data: " + event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	originalOrder := antigravityBaseURLFallbackOrder
+	antigravityBaseURLFallbackOrder = func(*cliproxyauth.Auth) []string { return []string{server.URL} }
+	t.Cleanup(func() { antigravityBaseURLFallbackOrder = originalOrder })
+
+	auth := &cliproxyauth.Auth{
+		ID: fmt.Sprintf("auth-antigravity-claude-nonstream-usage-%d", time.Now().UnixNano()),
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	plugin := &captureStreamCancelUsagePlugin{authID: auth.ID, records: make(chan usage.Record, 4)}
+	usage.RegisterPlugin(plugin)
+
+	_, errExec := NewAntigravityExecutor(&config.Config{}).Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "claude-sonnet-4-5",
+		Payload: []byte(`{"request":{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatAntigravity})
+	if errExec != nil {
+		t.Fatalf("Execute() error = %v", errExec)
+	}
+
+	record := waitForStreamCancelUsageRecord(t, plugin.records)
+	if record.Failed {
+		t.Fatalf("record failed = true (%+v), want success", record.Fail)
+	}
+	if record.Detail.InputTokens != 40 || record.Detail.OutputTokens != 3 {
+		t.Fatalf("usage = %+v, want the terminal chunk totals (input 40, output 3)", record.Detail)
+	}
+	select {
+	case extra := <-plugin.records:
+		t.Fatalf("got a second usage record %+v, want exactly one", extra.Detail)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
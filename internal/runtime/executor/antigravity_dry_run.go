@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/sjson"
+)
+
+// DryRunRequest builds the upstream Antigravity payload for req without sending it.
+// It runs the same translation, thinking, payload rule and schema cleaning steps as
+// Execute and ExecuteStream. The project ID is taken from auth when provided. Steps that
+// depend on live state, such as reasoning replay and credit injection, are skipped.
+func (e *AntigravityExecutor) DryRunRequest(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) ([]byte, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+	from := opts.SourceFormat
+
+	payload, errValidate := validateAntigravityRequestSignatures(ctx, baseModel, from, req.Payload)
+	if errValidate != nil {
+		return nil, errValidate
+	}
+	req.Payload = payload
+
+	stream := opts.Stream || antigravityNonStreamUsesStream(baseModel)
+	translated, err := e.translateAntigravityRequest(ctx, from, baseModel, req, opts, stream)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Stream {
+		translated, _ = sjson.DeleteBytes(translated, "request.stream")
+	}
+	return e.finalizeAntigravityPayload(ctx, baseModel, translated, antigravityProjectIDFromAuth(auth)), nil
+}
+
+// DryRunResponse converts a captured upstream Antigravity body into the client format
+// without contacting the upstream. When opts.Stream is set, body is read as SSE lines and
+// the translated chunks are returned in order; otherwise a single converted payload is
+// returned. Non-streaming bodies captured from the streaming endpoint are aggregated the
+// same way the executor does before conversion. req carries the original client request.
+func (e *AntigravityExecutor) DryRunResponse(ctx context.Context, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, body []byte) ([][]byte, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+	from := opts.SourceFormat
+	responseFormat := cliproxyexecutor.ResponseFormatOrSource(opts)
+	to := sdktranslator.FromString("antigravity")
+
+	var translated []byte
+	if len(req.Payload) > 0 {
+		var err error
+		translated, err = e.translateAntigravityRequest(ctx, from, baseModel, req, opts, opts.Stream || antigravityNonStreamUsesStream(baseModel))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.Stream {
+		if bytes.HasPrefix(bytes.TrimSpace(body), []byte("data:")) {
+			var buffer bytes.Buffer
+			for _, line := range bytes.Split(body, []byte("\n")) {
+				if payload := helps.JSONPayload(helps.FilterSSEUsageMetadata(line)); len(payload) > 0 {
+					_, _ = buffer.Write(payload)
+					_, _ = buffer.Write([]byte("\n"))
+				}
+			}
+			body = e.convertStreamToNonStream(buffer.Bytes())
+		}
+		var param any
+		return [][]byte{sdktranslator.TranslateNonStream(ctx, to, responseFormat, req.Model, req.Payload, translated, body, &param)}, nil
+	}
+
+	claudeInputTokens := helps.NewClaudeInputTokenState(from, to, responseFormat, req.Payload)
+	var param any
+	var out [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, streamScannerBuffer)
+	for scanner.Scan() {
+		line := helps.FilterSSEUsageMetadata(scanner.Bytes())
+		payload := helps.JSONPayload(line)
+		if payload == nil {
+			continue
+		}
+		out = append(out, helps.TranslateStreamWithClaudeInputTokens(ctx, to, responseFormat, req.Model, req.Payload, translated, bytes.Clone(payload), &param, claudeInputTokens)...)
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		return nil, errScan
+	}
+	out = append(out, helps.TranslateStreamWithClaudeInputTokens(ctx, to, responseFormat, req.Model, req.Payload, translated, []byte("[DONE]"), &param, claudeInputTokens)...)
+	return out, nil
+}
@@ -0,0 +1,205 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const antigravityDryRunSSE = "data: {\"response\":{\"responseId\":\"resp-1\",\"modelVersion\":\"m\",\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"hel\"}]}}]}}\n\n" +
+	"data: {\"response\":{\"responseId\":\"resp-1\",\"modelVersion\":\"m\",\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"lo\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2,\"totalTokenCount\":5}}}\n\n"
+
+const antigravityDryRunJSON = `{"response":{"responseId":"resp-1","modelVersion":"m","candidates":[{"content":{"role":"model","parts":[{"text":"hello"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}}`
+
+const antigravityDryRunOpenAIRequest = `{"model":"m","messages":[{"role":"system","content":"be brief"},{"role":"user","content":"weather?"}],"reasoning_effort":"high","max_tokens":1024,"tools":[{"type":"function","function":{"name":"get_weather","description":"weather","parameters":{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","additionalProperties":false,"properties":{"location":{"type":"string","minLength":1}},"required":["location"]}}}]}`
+
+func newAntigravityDryRunUpstream(t *testing.T) (*httptest.Server, func() []byte) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		body []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, errRead := io.ReadAll(r.Body)
+		if errRead != nil {
+			t.Errorf("read upstream body: %v", errRead)
+		}
+		mu.Lock()
+		body = data
+		mu.Unlock()
+		if strings.Contains(r.URL.Path, "streamGenerateContent") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(antigravityDryRunSSE))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(antigravityDryRunJSON))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return body
+	}
+}
+
+func newAntigravityDryRunAuth(baseURL string) *cliproxyauth.Auth {
+	return &cliproxyauth.Auth{
+		ID:         "antigravity-dry-run-auth",
+		Provider:   "antigravity",
+		Attributes: map[string]string{"base_url": baseURL},
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+}
+
+func stripAntigravityRequestID(t *testing.T, payload []byte) string {
+	t.Helper()
+	if !gjson.GetBytes(payload, "requestId").Exists() {
+		t.Fatalf("payload missing requestId: %s", payload)
+	}
+	out, _ := sjson.DeleteBytes(payload, "requestId")
+	return gjson.ParseBytes(out).Get("@pretty:{\"sortKeys\":true}").Raw
+}
+
+func TestAntigravityDryRunRequestMatchesExecutorPayload(t *testing.T) {
+	cases := []struct {
+		name   string
+		model  string
+		stream bool
+	}{
+		{name: "gemini non-stream", model: "gemini-2.5-flash", stream: false},
+		{name: "gemini stream", model: "gemini-2.5-flash", stream: true},
+		{name: "gemini 3 pro aggregated stream", model: "gemini-3-pro-preview", stream: false},
+		{name: "claude alias stream", model: "claude-sonnet-4-5", stream: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, upstreamBody := newAntigravityDryRunUpstream(t)
+			cfg := &config.Config{RequestRetry: 1}
+			cfg.Payload.Override = []config.PayloadRule{{
+				Models: []config.PayloadModelRule{{Name: "*", Protocol: "antigravity"}},
+				Params: map[string]any{"generationConfig.topK": 7},
+			}}
+			exec := NewAntigravityExecutor(cfg)
+			auth := newAntigravityDryRunAuth(server.URL)
+			payload := []byte(antigravityDryRunOpenAIRequest)
+			req := cliproxyexecutor.Request{Model: tc.model, Payload: payload}
+			opts := cliproxyexecutor.Options{
+				SourceFormat:    sdktranslator.FormatOpenAI,
+				Stream:          tc.stream,
+				OriginalRequest: payload,
+			}
+
+			if tc.stream {
+				result, errExecute := exec.ExecuteStream(context.Background(), auth, req, opts)
+				if errExecute != nil {
+					t.Fatalf("ExecuteStream() error = %v", errExecute)
+				}
+				for chunk := range result.Chunks {
+					if chunk.Err != nil {
+						t.Fatalf("stream chunk error: %v", chunk.Err)
+					}
+				}
+			} else if _, errExecute := exec.Execute(context.Background(), auth, req, opts); errExecute != nil {
+				t.Fatalf("Execute() error = %v", errExecute)
+			}
+			sent := upstreamBody()
+			if len(sent) == 0 {
+				t.Fatal("upstream body was not captured")
+			}
+
+			dryRun, errDryRun := exec.DryRunRequest(context.Background(), auth, req, opts)
+			if errDryRun != nil {
+				t.Fatalf("DryRunRequest() error = %v", errDryRun)
+			}
+			if got := gjson.GetBytes(dryRun, "request.generationConfig.topK").Int(); got != 7 {
+				t.Fatalf("payload override not applied: %s", dryRun)
+			}
+			if gjson.GetBytes(dryRun, "request.tools.0.functionDeclarations.0.parameters.$schema").Exists() {
+				t.Fatalf("tool schema was not cleaned: %s", dryRun)
+			}
+			if got, want := stripAntigravityRequestID(t, dryRun), stripAntigravityRequestID(t, sent); got != want {
+				t.Fatalf("dry-run payload differs from executor payload\ndry-run: %s\nexecutor: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAntigravityDryRunResponseMatchesExecutorOutput(t *testing.T) {
+	server, _ := newAntigravityDryRunUpstream(t)
+	exec := NewAntigravityExecutor(&config.Config{RequestRetry: 1})
+	auth := newAntigravityDryRunAuth(server.URL)
+	payload := []byte(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+
+	t.Run("stream", func(t *testing.T) {
+		req := cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload}
+		opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, Stream: true, OriginalRequest: payload}
+		result, errExecute := exec.ExecuteStream(context.Background(), auth, req, opts)
+		if errExecute != nil {
+			t.Fatalf("ExecuteStream() error = %v", errExecute)
+		}
+		var want []string
+		for chunk := range result.Chunks {
+			if chunk.Err != nil {
+				t.Fatalf("stream chunk error: %v", chunk.Err)
+			}
+			want = append(want, stripOpenAIChunkTimestamps(chunk.Payload))
+		}
+
+		chunks, errDryRun := exec.DryRunResponse(context.Background(), req, opts, []byte(antigravityDryRunSSE))
+		if errDryRun != nil {
+			t.Fatalf("DryRunResponse() error = %v", errDryRun)
+		}
+		var got []string
+		for _, chunk := range chunks {
+			got = append(got, stripOpenAIChunkTimestamps(chunk))
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Fatalf("dry-run chunks differ\ndry-run: %v\nexecutor: %v", got, want)
+		}
+	})
+
+	t.Run("non-stream from SSE capture", func(t *testing.T) {
+		req := cliproxyexecutor.Request{Model: "claude-sonnet-4-5", Payload: payload}
+		opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload}
+		resp, errExecute := exec.Execute(context.Background(), auth, req, opts)
+		if errExecute != nil {
+			t.Fatalf("Execute() error = %v", errExecute)
+		}
+		chunks, errDryRun := exec.DryRunResponse(context.Background(), req, opts, []byte(antigravityDryRunSSE))
+		if errDryRun != nil {
+			t.Fatalf("DryRunResponse() error = %v", errDryRun)
+		}
+		if len(chunks) != 1 {
+			t.Fatalf("DryRunResponse() returned %d payloads, want 1", len(chunks))
+		}
+		if got, want := stripOpenAIChunkTimestamps(chunks[0]), stripOpenAIChunkTimestamps(resp.Payload); got != want {
+			t.Fatalf("dry-run output differs\ndry-run: %s\nexecutor: %s", got, want)
+		}
+		if got := gjson.GetBytes(chunks[0], "choices.0.message.content").String(); got != "hello" {
+			t.Fatalf("content = %q, want hello", got)
+		}
+	})
+}
+
+func stripOpenAIChunkTimestamps(payload []byte) string {
+	out, _ := sjson.DeleteBytes(payload, "created")
+	return string(out)
+}
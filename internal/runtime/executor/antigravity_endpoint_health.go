@@ -0,0 +1,184 @@
+package executor
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// antigravityEndpointHealthWindow is the period the rolling availability covers.
+	antigravityEndpointHealthWindow = 5 * time.Minute
+	// antigravityEndpointHealthRing bounds the outcomes remembered per base URL for the
+	// rolling availability.
+	antigravityEndpointHealthRing = 512
+)
+
+// antigravityEndpointHealthByURL holds the health of every base URL the executor called.
+var antigravityEndpointHealthByURL sync.Map // base URL -> *antigravityEndpointHealth
+
+// AntigravityEndpointStatus is the health of one Antigravity base URL.
+type AntigravityEndpointStatus struct {
+	BaseURL string `json:"base_url"`
+	// Successes counts calls answered without a 429 or 5xx status.
+	Successes int64 `json:"successes"`
+	// RateLimited counts 429 responses.
+	RateLimited int64 `json:"rate_limited"`
+	// ServerErrors counts 5xx responses.
+	ServerErrors int64 `json:"server_errors"`
+	// TransportErrors counts calls that got no response.
+	TransportErrors int64 `json:"transport_errors"`
+	// LastError describes the latest failed call.
+	LastError string `json:"last_error,omitempty"`
+	// LastErrorAt is when the latest failed call happened.
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// RecentCalls is the number of calls in the last five minutes.
+	RecentCalls int `json:"recent_calls"`
+	// Availability is the share of recent calls that succeeded, or nil without recent calls.
+	Availability *float64 `json:"availability_5m"`
+}
+
+type antigravityEndpointError struct {
+	message string
+	at      time.Time
+}
+
+type antigravityEndpointOutcome struct {
+	at time.Time
+	ok bool
+}
+
+// antigravityEndpointHealth tracks the calls to one base URL. Totals are atomic counters;
+// the rolling availability reads a fixed-size ring of recent outcomes.
+type antigravityEndpointHealth struct {
+	successes       atomic.Int64
+	rateLimited     atomic.Int64
+	serverErrors    atomic.Int64
+	transportErrors atomic.Int64
+	lastError       atomic.Pointer[antigravityEndpointError]
+
+	mu     sync.Mutex
+	recent [antigravityEndpointHealthRing]antigravityEndpointOutcome
+	next   int
+	size   int
+}
+
+// recordAntigravityEndpointHealth records the outcome of one call to baseURL.
+func recordAntigravityEndpointHealth(baseURL string, statusCode int, errDo error, now time.Time) {
+	if baseURL == "" {
+		return
+	}
+	value, _ := antigravityEndpointHealthByURL.LoadOrStore(baseURL, &antigravityEndpointHealth{})
+	health := value.(*antigravityEndpointHealth)
+
+	ok := false
+	switch {
+	case errDo != nil:
+		health.transportErrors.Add(1)
+		health.lastError.Store(&antigravityEndpointError{message: errDo.Error(), at: now})
+	case statusCode == http.StatusTooManyRequests:
+		health.rateLimited.Add(1)
+		health.lastError.Store(&antigravityEndpointError{message: "HTTP " + strconv.Itoa(statusCode), at: now})
+	case statusCode >= http.StatusInternalServerError:
+		health.serverErrors.Add(1)
+		health.lastError.Store(&antigravityEndpointError{message: "HTTP " + strconv.Itoa(statusCode), at: now})
+	default:
+		health.successes.Add(1)
+		ok = true
+	}
+
+	health.mu.Lock()
+	health.recent[health.next] = antigravityEndpointOutcome{at: now, ok: ok}
+	health.next = (health.next + 1) % antigravityEndpointHealthRing
+	if health.size < antigravityEndpointHealthRing {
+		health.size++
+	}
+	health.mu.Unlock()
+}
+
+func (h *antigravityEndpointHealth) status(baseURL string, now time.Time) AntigravityEndpointStatus {
+	status := AntigravityEndpointStatus{
+		BaseURL:         baseURL,
+		Successes:       h.successes.Load(),
+		RateLimited:     h.rateLimited.Load(),
+		ServerErrors:    h.serverErrors.Load(),
+		TransportErrors: h.transportErrors.Load(),
+	}
+	if lastError := h.lastError.Load(); lastError != nil {
+		at := lastError.at
+		status.LastError, status.LastErrorAt = lastError.message, &at
+	}
+
+	cutoff := now.Add(-antigravityEndpointHealthWindow)
+	succeeded := 0
+	h.mu.Lock()
+	for i := 0; i < h.size; i++ {
+		outcome := h.recent[i]
+		if outcome.at.Before(cutoff) {
+			continue
+		}
+		status.RecentCalls++
+		if outcome.ok {
+			succeeded++
+		}
+	}
+	h.mu.Unlock()
+	if status.RecentCalls > 0 {
+		availability := float64(succeeded) / float64(status.RecentCalls)
+		status.Availability = &availability
+	}
+	return status
+}
+
+// recentFailures returns the number of failed calls in the rolling window.
+func (h *antigravityEndpointHealth) recentFailures(now time.Time) int {
+	cutoff := now.Add(-antigravityEndpointHealthWindow)
+	failures := 0
+	h.mu.Lock()
+	for i := 0; i < h.size; i++ {
+		if outcome := h.recent[i]; !outcome.ok && !outcome.at.Before(cutoff) {
+			failures++
+		}
+	}
+	h.mu.Unlock()
+	return failures
+}
+
+// leastFailedAntigravityBaseURL returns the index of the base URL with the fewest failed
+// calls in the rolling window, preferring the earlier URL on ties.
+func leastFailedAntigravityBaseURL(baseURLs []string, now time.Time) int {
+	best, bestCount := 0, -1
+	for i, baseURL := range baseURLs {
+		count := 0
+		if value, ok := antigravityEndpointHealthByURL.Load(baseURL); ok {
+			count = value.(*antigravityEndpointHealth).recentFailures(now)
+		}
+		if bestCount < 0 || count < bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return best
+}
+
+// AntigravityEndpointHealth returns the health of the default Antigravity base URLs and of
+// every other base URL the executor called, sorted by base URL.
+func AntigravityEndpointHealth(now time.Time) []AntigravityEndpointStatus {
+	seen := make(map[string]struct{})
+	var statuses []AntigravityEndpointStatus
+	antigravityEndpointHealthByURL.Range(func(key, value any) bool {
+		baseURL := key.(string)
+		seen[baseURL] = struct{}{}
+		statuses = append(statuses, value.(*antigravityEndpointHealth).status(baseURL, now))
+		return true
+	})
+	for _, baseURL := range antigravityBaseURLFallbackOrder(nil) {
+		if _, ok := seen[baseURL]; !ok {
+			statuses = append(statuses, AntigravityEndpointStatus{BaseURL: baseURL})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].BaseURL < statuses[j].BaseURL })
+	return statuses
+}
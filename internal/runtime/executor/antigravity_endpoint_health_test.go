@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func findAntigravityEndpointStatus(t *testing.T, statuses []AntigravityEndpointStatus, baseURL string) AntigravityEndpointStatus {
+	t.Helper()
+	for _, status := range statuses {
+		if status.BaseURL == baseURL {
+			return status
+		}
+	}
+	t.Fatalf("no endpoint status for %s in %+v", baseURL, statuses)
+	return AntigravityEndpointStatus{}
+}
+
+func TestAntigravityEndpointHealthCountsMixedOutcomes(t *testing.T) {
+	prefix := fmt.Sprintf("https://health-%d", time.Now().UnixNano())
+	healthy, flaky := prefix+"-healthy", prefix+"-flaky"
+	now := time.Now()
+
+	recordAntigravityEndpointHealth(flaky, http.StatusOK, nil, now.Add(-10*time.Minute))
+	recordAntigravityEndpointHealth(flaky, http.StatusTooManyRequests, nil, now.Add(-3*time.Second))
+	recordAntigravityEndpointHealth(flaky, http.StatusServiceUnavailable, nil, now.Add(-2*time.Second))
+	recordAntigravityEndpointHealth(flaky, http.StatusOK, nil, now.Add(-time.Second))
+	recordAntigravityEndpointHealth(flaky, 0, errors.New("connection reset"), now)
+	for i := 0; i < 3; i++ {
+		recordAntigravityEndpointHealth(healthy, http.StatusOK, nil, now)
+	}
+	recordAntigravityEndpointHealth(healthy, http.StatusBadRequest, nil, now)
+
+	statuses := AntigravityEndpointHealth(now)
+
+	got := findAntigravityEndpointStatus(t, statuses, flaky)
+	if got.Successes != 2 || got.RateLimited != 1 || got.ServerErrors != 1 || got.TransportErrors != 1 {
+		t.Fatalf("flaky counts = %+v, want 2 successes, 1 rate limited, 1 server error, 1 transport error", got)
+	}
+	if got.LastError != "connection reset" || got.LastErrorAt == nil || !got.LastErrorAt.Equal(now) {
+		t.Fatalf("flaky last error = %q at %v, want connection reset at %v", got.LastError, got.LastErrorAt, now)
+	}
+	if got.RecentCalls != 4 || got.Availability == nil || *got.Availability != 0.25 {
+		t.Fatalf("flaky recent = %d availability = %v, want 4 calls at 0.25", got.RecentCalls, got.Availability)
+	}
+
+	got = findAntigravityEndpointStatus(t, statuses, healthy)
+	if got.Successes != 4 || got.RateLimited != 0 || got.ServerErrors != 0 || got.TransportErrors != 0 || got.LastError != "" {
+		t.Fatalf("healthy status = %+v, want 4 successes and no errors (4xx other than 429 count as answered)", got)
+	}
+	if got.RecentCalls != 4 || got.Availability == nil || *got.Availability != 1 {
+		t.Fatalf("healthy recent = %d availability = %v, want 4 calls at 1", got.RecentCalls, got.Availability)
+	}
+
+	later := findAntigravityEndpointStatus(t, AntigravityEndpointHealth(now.Add(time.Hour)), flaky)
+	if later.RecentCalls != 0 || later.Availability != nil || later.Successes != 2 {
+		t.Fatalf("status after the window = %+v, want totals kept and no recent calls", later)
+	}
+}
+
+func TestAntigravityEndpointHealthListsDefaultBaseURLs(t *testing.T) {
+	unused := fmt.Sprintf("https://unused-%d", time.Now().UnixNano())
+	withAntigravityBaseURLs(t, unused)
+
+	got := findAntigravityEndpointStatus(t, AntigravityEndpointHealth(time.Now()), unused)
+	if got.Successes != 0 || got.RecentCalls != 0 || got.Availability != nil {
+		t.Fatalf("unused status = %+v, want an empty entry", got)
+	}
+}
+
+func TestAntigravityEndpointHealthConcurrentRecords(t *testing.T) {
+	baseURL := fmt.Sprintf("https://concurrent-%d", time.Now().UnixNano())
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status := http.StatusOK
+			if i%4 == 0 {
+				status = http.StatusTooManyRequests
+			}
+			recordAntigravityEndpointHealth(baseURL, status, nil, now)
+		}(i)
+	}
+	wg.Wait()
+
+	got := findAntigravityEndpointStatus(t, AntigravityEndpointHealth(now), baseURL)
+	if got.Successes != 150 || got.RateLimited != 50 || got.RecentCalls != 200 {
+		t.Fatalf("status = %+v, want 150 successes, 50 rate limited over 200 recent calls", got)
+	}
+	if got.Availability == nil || *got.Availability != 0.75 {
+		t.Fatalf("availability = %v, want 0.75", got.Availability)
+	}
+}
+
+func TestAntigravityExecutorRecordsEndpointHealth(t *testing.T) {
+	closed := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	closed.Close()
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`rate limited`))
+	}))
+	defer limited.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(antigravityStaticTestResponse))
+	}))
+	defer healthy.Close()
+	withAntigravityBaseURLs(t, closed.URL, limited.URL, healthy.URL)
+
+	exec := NewAntigravityExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		ID:       fmt.Sprintf("endpoint-health-%d", time.Now().UnixNano()),
+		Provider: "antigravity",
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	for i := 0; i < 2; i++ {
+		if _, errExecute := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{
+			Model:   "gemini-2.5-flash",
+			Payload: payload,
+		}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: payload}); errExecute != nil {
+			t.Fatalf("Execute() error = %v", errExecute)
+		}
+	}
+
+	statuses := AntigravityEndpointHealth(time.Now())
+	if got := findAntigravityEndpointStatus(t, statuses, closed.URL); got.TransportErrors != 2 || got.LastError == "" {
+		t.Fatalf("closed status = %+v, want 2 transport errors", got)
+	}
+	if got := findAntigravityEndpointStatus(t, statuses, limited.URL); got.RateLimited != 2 || got.LastError != "HTTP 429" {
+		t.Fatalf("limited status = %+v, want 2 rate limited", got)
+	}
+	if got := findAntigravityEndpointStatus(t, statuses, healthy.URL); got.Successes != 2 || got.Availability == nil || *got.Availability != 1 {
+		t.Fatalf("healthy status = %+v, want 2 successes at full availability", got)
+	}
+}
@@ -26,10 +26,11 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/cache"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	homekv "github.com/router-for-me/CLIProxyAPI/v7/internal/home"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/misc"
-	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor/helps"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
 	antigravityclaude "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/antigravity/claude"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	sdkAuth "github.com/router-for-me/CLIProxyAPI/v7/sdk/auth"
@@ -93,7 +94,9 @@ var (
 	antigravityShortCooldownByAuth    sync.Map
 	antigravityCreditsBalanceByAuth   sync.Map // auth.ID → antigravityCreditsBalance
 	antigravityCreditsHintRefreshByID sync.Map // auth.ID → *antigravityCreditsHintRefreshState
+	antigravityInvalidProjectByAuth   sync.Map // auth.ID → project_id rejected by the upstream
 	antigravityRefreshGroup           singleflight.Group
+	antigravityRefreshedTokenByAuth   sync.Map // auth.ID → *antigravityRefreshedToken
 	antigravityQuotaExhaustedKeywords = []string{
 		"quota_exhausted",
 		"quota exhausted",
@@ -130,6 +133,24 @@ type antigravityTokenRefreshData struct {
 	TokenType    string `json:"token_type"`
 }
 
+// antigravityRefreshedToken is the latest token refreshed for an auth, shared with
+// callers that still hold a stale copy of the same auth.
+type antigravityRefreshedToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+	RefreshedAt  time.Time
+}
+
+func (t *antigravityRefreshedToken) expiry() time.Time {
+	return t.RefreshedAt.Add(time.Duration(t.ExpiresIn) * time.Second)
+}
+
+// usable reports whether the token is still outside the refresh skew window at now.
+func (t *antigravityRefreshedToken) usable(now time.Time) bool {
+	return t.expiry().After(now.Add(refreshSkew))
+}
+
 func antigravityAuthHasCredits(auth *cliproxyauth.Auth) bool {
 	ok, err := antigravityAuthHasCreditsRequired(context.Background(), auth)
 	if err != nil {
@@ -282,18 +303,21 @@ func initAntigravityTransport() {
 func newAntigravityHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
 	antigravityTransportOnce.Do(initAntigravityTransport)
 
-	client := helps.NewProxyAwareHTTPClient(ctx, cfg, auth, timeout)
-	// If no transport is set, use the shared HTTP/1.1 transport.
-	if client.Transport == nil {
-		client.Transport = antigravityTransport
-		return client
+	client := helps.NewUntracedProxyAwareHTTPClient(ctx, cfg, auth, timeout)
+	// If no transport is set, or only the default transport was tuned with provider
+	// settings, use the shared HTTP/1.1 transport tuned the same way.
+	settings := helps.ProviderHTTPSettings(cfg, auth)
+	defaultTransport, _ := http.DefaultTransport.(*http.Transport)
+	if client.Transport == nil || (settings.TransportConfigured() && client.Transport == http.RoundTripper(helps.TunedTransport(defaultTransport, settings))) {
+		client.Transport = helps.TunedTransport(antigravityTransport, settings)
+		return helps.TracedHTTPClient(client)
 	}
 
 	// Preserve proxy settings from proxy-aware transports while forcing HTTP/1.1.
 	if transport, ok := client.Transport.(*http.Transport); ok {
 		client.Transport = cloneTransportWithHTTP11(transport)
 	}
-	return client
+	return helps.TracedHTTPClient(client)
 }
 
 func validateAntigravityRequestSignatures(ctx context.Context, modelName string, from sdktranslator.Format, rawJSON []byte) ([]byte, error) {
@@ -610,6 +634,15 @@ func newAntigravityStatusErr(statusCode int, body []byte) statusErr {
 	return err
 }
 
+// antigravityStreamReadErr reports an upstream stream that went silent as a gateway timeout,
+// so the request is treated as retryable, and returns other read errors unchanged.
+func antigravityStreamReadErr(err error) error {
+	if !helps.IsStreamIdleTimeout(err) {
+		return err
+	}
+	return statusErr{code: http.StatusGatewayTimeout, msg: "antigravity executor: " + err.Error()}
+}
+
 // Execute performs a non-streaming request to the Antigravity API.
 func (e *AntigravityExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
 	if opts.Alt == "responses/compact" {
@@ -624,8 +657,7 @@ func (e *AntigravityExecutor) Execute(ctx context.Context, auth *cliproxyauth.Au
 		return resp, statusErr{code: http.StatusTooManyRequests, msg: fmt.Sprintf("auth in short cooldown, %s remaining", remaining), retryAfter: &d}
 	}
 
-	isClaude := strings.Contains(strings.ToLower(baseModel), "claude")
-	if isClaude || strings.Contains(baseModel, "gemini-3-pro") || strings.Contains(baseModel, "gemini-3.1-flash-image") {
+	if antigravityNonStreamUsesStream(baseModel) {
 		return e.executeClaudeNonStream(ctx, auth, req, opts)
 	}
 
@@ -653,23 +685,20 @@ func (e *AntigravityExecutor) Execute(ctx context.Context, auth *cliproxyauth.Au
 	if updatedAuth != nil {
 		auth = updatedAuth
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayload, false)
-	translated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, false)
-
-	translated, err = thinking.ApplyThinking(translated, req.Model, from.String(), to.String(), e.Identifier())
+	translated, err := e.translateAntigravityRequest(ctx, from, baseModel, req, opts, false)
 	if err != nil {
 		return resp, err
 	}
-
-	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
-	requestPath := helps.PayloadRequestPath(opts)
-	translated = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, "antigravity", from.String(), "request", translated, originalTranslated, requestedModel, requestPath, opts.Headers)
+	cloak := helps.CloakSettingsFromAuth(auth)
+	userAgent := getClientUserAgent(ctx)
+	helps.TraceGeminiCloaking(ctx, cloak, userAgent)
+	translated = helps.ApplyGeminiCloaking(translated, "request", userAgent, cloak)
 	reporter.SetTranslatedReasoningEffort(translated, to.String())
 
 	useCredits := cliproxyauth.AntigravityCreditsRequested(ctx) && antigravityCreditsRetryEnabled(e.cfg)
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
-	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, 0)
+	baseURLs := antigravityBaseURLOrder(e.cfg, auth)
+	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 	httpClient = reporter.TrackHTTPClient(httpClient)
 	attempts := antigravityRetryAttempts(auth, e.cfg)
 
@@ -709,6 +738,7 @@ attemptLoop:
 				if errors.Is(errDo, context.Canceled) || errors.Is(errDo, context.DeadlineExceeded) {
 					return resp, errDo
 				}
+				observeAntigravityBaseURL(baseURL, 0, errDo)
 				lastStatus = 0
 				lastBody = nil
 				lastErr = errDo
@@ -721,7 +751,8 @@ attemptLoop:
 			}
 
 			helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
-			bodyBytes, errRead := io.ReadAll(httpResp.Body)
+			observeAntigravityBaseURL(baseURL, httpResp.StatusCode, nil)
+			bodyBytes, errRead := readUpstreamResponseBody(e.cfg, httpResp.Body)
 			if errClose := httpResp.Body.Close(); errClose != nil {
 				log.Errorf("antigravity executor: close response body error: %v", errClose)
 			}
@@ -769,6 +800,7 @@ attemptLoop:
 				lastErr = nil
 				if httpResp.StatusCode == http.StatusTooManyRequests && idx+1 < len(baseURLs) {
 					log.Debugf("antigravity executor: rate limited on base url %s, retrying with fallback base url: %s", baseURL, baseURLs[idx+1])
+					metrics.ObserveAntigravityFallback(baseURL)
 					continue
 				}
 				if antigravityShouldRetryTransientResourceExhausted429(httpResp.StatusCode, bodyBytes) && attempt+1 < attempts {
@@ -807,6 +839,7 @@ attemptLoop:
 					err = errClear
 					return resp, err
 				}
+				markAntigravityProjectInvalidOnError(auth, httpResp.StatusCode, bodyBytes)
 				err = newAntigravityStatusErr(httpResp.StatusCode, bodyBytes)
 				return resp, err
 			}
@@ -818,6 +851,7 @@ attemptLoop:
 			cacheAntigravityReasoningReplayFromResponse(ctx, replayScope, requestPayload, bodyBytes)
 			bodyBytes = e.resolveWebSearchGroundingURLs(ctx, auth, from, originalPayload, translated, bodyBytes)
 			reporter.Publish(ctx, helps.ParseAntigravityUsage(bodyBytes))
+			bodyBytes = cloak.ResponseWordMatcher(userAgent).RestoreSensitiveWords(bodyBytes)
 			var param any
 			converted := sdktranslator.TranslateNonStream(ctx, to, responseFormat, req.Model, opts.OriginalRequest, translated, bodyBytes, &param)
 			resp = cliproxyexecutor.Response{Payload: converted, Headers: httpResp.Header.Clone()}
@@ -827,6 +861,7 @@ attemptLoop:
 
 		switch {
 		case lastStatus != 0:
+			markAntigravityProjectInvalidOnError(auth, lastStatus, lastBody)
 			err = newAntigravityStatusErr(lastStatus, lastBody)
 		case lastErr != nil:
 			err = lastErr
@@ -839,6 +874,13 @@ attemptLoop:
 	return resp, err
 }
 
+// antigravityNonStreamUsesStream reports whether non-streaming requests for the model are
+// served by the streaming endpoint and aggregated afterwards.
+func antigravityNonStreamUsesStream(baseModel string) bool {
+	isClaude := strings.Contains(strings.ToLower(baseModel), "claude")
+	return isClaude || strings.Contains(baseModel, "gemini-3-pro") || strings.Contains(baseModel, "gemini-3.1-flash-image")
+}
+
 // executeClaudeNonStream performs a claude non-streaming request to the Antigravity API.
 func (e *AntigravityExecutor) executeClaudeNonStream(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (resp cliproxyexecutor.Response, err error) {
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
@@ -874,23 +916,20 @@ func (e *AntigravityExecutor) executeClaudeNonStream(ctx context.Context, auth *
 	if updatedAuth != nil {
 		auth = updatedAuth
 	}
-	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayload, true)
-	translated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, true)
-
-	translated, err = thinking.ApplyThinking(translated, req.Model, from.String(), to.String(), e.Identifier())
+	translated, err := e.translateAntigravityRequest(ctx, from, baseModel, req, opts, true)
 	if err != nil {
 		return resp, err
 	}
-
-	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
-	requestPath := helps.PayloadRequestPath(opts)
-	translated = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, "antigravity", from.String(), "request", translated, originalTranslated, requestedModel, requestPath, opts.Headers)
+	cloak := helps.CloakSettingsFromAuth(auth)
+	userAgent := getClientUserAgent(ctx)
+	helps.TraceGeminiCloaking(ctx, cloak, userAgent)
+	translated = helps.ApplyGeminiCloaking(translated, "request", userAgent, cloak)
 	reporter.SetTranslatedReasoningEffort(translated, to.String())
 
 	useCredits := cliproxyauth.AntigravityCreditsRequested(ctx) && antigravityCreditsRetryEnabled(e.cfg)
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
-	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, 0)
+	baseURLs := antigravityBaseURLOrder(e.cfg, auth)
+	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 	httpClient = reporter.TrackHTTPClient(httpClient)
 
 	attempts := antigravityRetryAttempts(auth, e.cfg)
@@ -921,6 +960,7 @@ attemptLoop:
 				if errors.Is(errDo, context.Canceled) || errors.Is(errDo, context.DeadlineExceeded) {
 					return resp, errDo
 				}
+				observeAntigravityBaseURL(baseURL, 0, errDo)
 				lastStatus = 0
 				lastBody = nil
 				lastErr = errDo
@@ -932,6 +972,7 @@ attemptLoop:
 				return resp, err
 			}
 			helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+			observeAntigravityBaseURL(baseURL, httpResp.StatusCode, nil)
 			if httpResp.StatusCode < http.StatusOK || httpResp.StatusCode >= http.StatusMultipleChoices {
 				bodyBytes, errRead := io.ReadAll(httpResp.Body)
 				if errClose := httpResp.Body.Close(); errClose != nil {
@@ -994,6 +1035,7 @@ attemptLoop:
 				lastErr = nil
 				if httpResp.StatusCode == http.StatusTooManyRequests && idx+1 < len(baseURLs) {
 					log.Debugf("antigravity executor: rate limited on base url %s, retrying with fallback base url: %s", baseURL, baseURLs[idx+1])
+					metrics.ObserveAntigravityFallback(baseURL)
 					continue
 				}
 				if antigravityShouldRetryTransientResourceExhausted429(httpResp.StatusCode, bodyBytes) && attempt+1 < attempts {
@@ -1028,6 +1070,7 @@ attemptLoop:
 						continue attemptLoop
 					}
 				}
+				markAntigravityProjectInvalidOnError(auth, httpResp.StatusCode, bodyBytes)
 				err = newAntigravityStatusErr(httpResp.StatusCode, bodyBytes)
 				return resp, err
 			}
@@ -1039,16 +1082,19 @@ attemptLoop:
 			out := make(chan cliproxyexecutor.StreamChunk)
 			go func(resp *http.Response) {
 				defer close(out)
+				body := helps.NewIdleTimeoutReader(resp.Body, helps.StreamIdleTimeout(e.cfg))
 				defer func() {
-					if errClose := resp.Body.Close(); errClose != nil {
+					if errClose := body.Close(); errClose != nil {
 						log.Errorf("antigravity executor: close response body error: %v", errClose)
 					}
 				}()
-				scanner := bufio.NewScanner(resp.Body)
+				scanner := bufio.NewScanner(helps.NewSSEReader(helps.GeminiStreamBody(body, opts.Alt)))
 				scanner.Buffer(nil, streamScannerBuffer)
+				var streamUsage helps.StreamUsageBuffer
 				for scanner.Scan() {
 					line := scanner.Bytes()
 					helps.AppendAPIResponseChunk(ctx, e.cfg, line)
+					streamUsage.ObserveGeminiStream(line)
 
 					// Filter usage metadata for all models
 					// Only retain usage statistics in the terminal chunk
@@ -1059,18 +1105,17 @@ attemptLoop:
 						continue
 					}
 
-					if detail, ok := helps.ParseAntigravityStreamUsage(payload); ok {
-						reporter.Publish(ctx, detail)
-					}
-
 					out <- cliproxyexecutor.StreamChunk{Payload: payload}
 				}
-				if errScan := scanner.Err(); errScan != nil {
+				// Usage of a successful stream is published once below from the
+				// aggregated response, which carries the terminal chunk totals.
+				if reporter.PublishClientCancelled(ctx, &streamUsage) {
+					out <- cliproxyexecutor.StreamChunk{Err: ctx.Err()}
+				} else if errScan := scanner.Err(); errScan != nil {
+					errScan = antigravityStreamReadErr(errScan)
 					helps.RecordAPIResponseError(ctx, e.cfg, errScan)
 					reporter.PublishFailure(ctx, errScan)
 					out <- cliproxyexecutor.StreamChunk{Err: errScan}
-				} else {
-					reporter.EnsurePublished(ctx)
 				}
 			}(httpResp)
 
@@ -1088,6 +1133,7 @@ attemptLoop:
 
 			resp.Payload = e.resolveWebSearchGroundingURLs(ctx, auth, from, originalPayload, translated, resp.Payload)
 			reporter.Publish(ctx, helps.ParseAntigravityUsage(resp.Payload))
+			resp.Payload = cloak.ResponseWordMatcher(userAgent).RestoreSensitiveWords(resp.Payload)
 			var param any
 			converted := sdktranslator.TranslateNonStream(ctx, to, responseFormat, req.Model, opts.OriginalRequest, translated, resp.Payload, &param)
 			resp = cliproxyexecutor.Response{Payload: converted, Headers: httpResp.Header.Clone()}
@@ -1098,6 +1144,7 @@ attemptLoop:
 
 		switch {
 		case lastStatus != 0:
+			markAntigravityProjectInvalidOnError(auth, lastStatus, lastBody)
 			err = newAntigravityStatusErr(lastStatus, lastBody)
 		case lastErr != nil:
 			err = lastErr
@@ -1110,72 +1157,109 @@ attemptLoop:
 	return resp, err
 }
 
-func (e *AntigravityExecutor) convertStreamToNonStream(stream []byte) []byte {
-	responseTemplate := ""
-	var traceID string
-	var finishReason string
-	var modelVersion string
-	var responseID string
-	var role string
-	var usageRaw string
-	parts := make([]map[string]interface{}, 0)
-	var pendingKind string
-	var pendingText strings.Builder
-	var pendingThoughtSig string
+// antigravityStreamPart is one part of an aggregated stream response. Text and thought
+// parts accumulate their text while later text of the same kind keeps arriving.
+type antigravityStreamPart struct {
+	kind             string
+	text             strings.Builder
+	thoughtSignature string
+	raw              map[string]interface{}
+}
 
-	flushPending := func() {
-		if pendingKind == "" {
-			return
-		}
-		text := pendingText.String()
-		switch pendingKind {
+// antigravityStreamParts aggregates streamed candidate parts into the parts of one
+// response. Parts are kept in arrival order: consecutive text of the same kind is merged
+// into one part, and any other part closes the current text part and is appended as is.
+type antigravityStreamParts struct {
+	parts []*antigravityStreamPart
+}
+
+func (a *antigravityStreamParts) add(part gjson.Result) {
+	sig := part.Get("thoughtSignature").String()
+	if sig == "" {
+		sig = part.Get("thought_signature").String()
+	}
+	isCall := part.Get("functionCall").Exists()
+	isInline := part.Get("inlineData").Exists() || part.Get("inline_data").Exists()
+	thought := part.Get("thought").Bool()
+	if isCall || isInline || (!thought && !part.Get("text").Exists()) {
+		a.parts = append(a.parts, &antigravityStreamPart{raw: normalizeAntigravityStreamPart(part, sig)})
+		return
+	}
+
+	kind := "text"
+	if thought {
+		kind = "thought"
+	}
+	var current *antigravityStreamPart
+	if n := len(a.parts); n > 0 && a.parts[n-1].kind == kind {
+		current = a.parts[n-1]
+	} else {
+		current = &antigravityStreamPart{kind: kind}
+		a.parts = append(a.parts, current)
+	}
+	current.text.WriteString(part.Get("text").String())
+	if kind == "thought" && sig != "" {
+		current.thoughtSignature = sig
+	}
+}
+
+// result returns the aggregated parts, dropping text parts that hold only whitespace and
+// thought parts that hold neither text nor a signature.
+func (a *antigravityStreamParts) result() []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(a.parts))
+	for _, part := range a.parts {
+		text := part.text.String()
+		switch part.kind {
 		case "text":
 			if strings.TrimSpace(text) == "" {
-				pendingKind = ""
-				pendingText.Reset()
-				pendingThoughtSig = ""
-				return
+				continue
 			}
-			parts = append(parts, map[string]interface{}{"text": text})
+			out = append(out, map[string]interface{}{"text": text})
 		case "thought":
-			if strings.TrimSpace(text) == "" && pendingThoughtSig == "" {
-				pendingKind = ""
-				pendingText.Reset()
-				pendingThoughtSig = ""
-				return
+			if strings.TrimSpace(text) == "" && part.thoughtSignature == "" {
+				continue
 			}
-			part := map[string]interface{}{"thought": true}
-			part["text"] = text
-			if pendingThoughtSig != "" {
-				part["thoughtSignature"] = pendingThoughtSig
+			m := map[string]interface{}{"thought": true, "text": text}
+			if part.thoughtSignature != "" {
+				m["thoughtSignature"] = part.thoughtSignature
 			}
-			parts = append(parts, part)
+			out = append(out, m)
+		default:
+			out = append(out, part.raw)
 		}
-		pendingKind = ""
-		pendingText.Reset()
-		pendingThoughtSig = ""
 	}
+	return out
+}
 
-	normalizePart := func(partResult gjson.Result) map[string]interface{} {
-		var m map[string]interface{}
-		_ = json.Unmarshal([]byte(partResult.Raw), &m)
-		if m == nil {
-			m = map[string]interface{}{}
-		}
-		sig := partResult.Get("thoughtSignature").String()
-		if sig == "" {
-			sig = partResult.Get("thought_signature").String()
-		}
-		if sig != "" {
-			m["thoughtSignature"] = sig
-			delete(m, "thought_signature")
-		}
-		if inlineData, ok := m["inline_data"]; ok {
-			m["inlineData"] = inlineData
-			delete(m, "inline_data")
-		}
-		return m
+// normalizeAntigravityStreamPart decodes a non-text part, spelling its signature and
+// inline data fields in camelCase.
+func normalizeAntigravityStreamPart(part gjson.Result, sig string) map[string]interface{} {
+	var m map[string]interface{}
+	_ = json.Unmarshal([]byte(part.Raw), &m)
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	if sig != "" {
+		m["thoughtSignature"] = sig
+		delete(m, "thought_signature")
+	}
+	if inlineData, ok := m["inline_data"]; ok {
+		m["inlineData"] = inlineData
+		delete(m, "inline_data")
 	}
+	return m
+}
+
+func (e *AntigravityExecutor) convertStreamToNonStream(stream []byte) []byte {
+	responseTemplate := ""
+	var traceID string
+	var finishReason string
+	var modelVersion string
+	var responseID string
+	var role string
+	var usageRaw string
+	var groundingRaw string
+	var parts antigravityStreamParts
 
 	for _, line := range bytes.Split(stream, []byte("\n")) {
 		trimmed := bytes.TrimSpace(line)
@@ -1203,7 +1287,7 @@ func (e *AntigravityExecutor) convertStreamToNonStream(stream []byte) []byte {
 		}
 
 		if finishResult := responseNode.Get("candidates.0.finishReason"); finishResult.Exists() && finishResult.String() != "" {
-			finishReason = finishResult.String()
+			finishReason = util.NormalizeFinishReason(util.FinishReasonGemini, finishResult.String())
 		}
 
 		if modelResult := responseNode.Get("modelVersion"); modelResult.Exists() && modelResult.String() != "" {
@@ -1218,51 +1302,22 @@ func (e *AntigravityExecutor) convertStreamToNonStream(stream []byte) []byte {
 			usageRaw = usageMetadataResult.Raw
 		}
 
+		if groundingResult := responseNode.Get("candidates.0.groundingMetadata"); groundingResult.IsObject() {
+			groundingRaw = groundingResult.Raw
+		}
+
 		if partsResult := responseNode.Get("candidates.0.content.parts"); partsResult.IsArray() {
 			for _, part := range partsResult.Array() {
-				hasFunctionCall := part.Get("functionCall").Exists()
-				hasInlineData := part.Get("inlineData").Exists() || part.Get("inline_data").Exists()
-				sig := part.Get("thoughtSignature").String()
-				if sig == "" {
-					sig = part.Get("thought_signature").String()
-				}
-				text := part.Get("text").String()
-				thought := part.Get("thought").Bool()
-
-				if hasFunctionCall || hasInlineData {
-					flushPending()
-					parts = append(parts, normalizePart(part))
-					continue
-				}
-
-				if thought || part.Get("text").Exists() {
-					kind := "text"
-					if thought {
-						kind = "thought"
-					}
-					if pendingKind != "" && pendingKind != kind {
-						flushPending()
-					}
-					pendingKind = kind
-					pendingText.WriteString(text)
-					if kind == "thought" && sig != "" {
-						pendingThoughtSig = sig
-					}
-					continue
-				}
-
-				flushPending()
-				parts = append(parts, normalizePart(part))
+				parts.add(part)
 			}
 		}
 	}
-	flushPending()
 
 	if responseTemplate == "" {
 		responseTemplate = `{"candidates":[{"content":{"role":"model","parts":[]}}]}`
 	}
 
-	partsJSON, _ := json.Marshal(parts)
+	partsJSON, _ := json.Marshal(parts.result())
 	updatedTemplate, _ := sjson.SetRawBytes([]byte(responseTemplate), "candidates.0.content.parts", partsJSON)
 	responseTemplate = string(updatedTemplate)
 	if role != "" {
@@ -1281,6 +1336,10 @@ func (e *AntigravityExecutor) convertStreamToNonStream(stream []byte) []byte {
 		updatedTemplate, _ = sjson.SetBytes([]byte(responseTemplate), "responseId", responseID)
 		responseTemplate = string(updatedTemplate)
 	}
+	if groundingRaw != "" {
+		updatedTemplate, _ = sjson.SetRawBytes([]byte(responseTemplate), "candidates.0.groundingMetadata", []byte(groundingRaw))
+		responseTemplate = string(updatedTemplate)
+	}
 	if usageRaw != "" {
 		updatedTemplate, _ = sjson.SetRawBytes([]byte(responseTemplate), "usageMetadata", []byte(usageRaw))
 		responseTemplate = string(updatedTemplate)
@@ -1344,23 +1403,20 @@ func (e *AntigravityExecutor) ExecuteStream(ctx context.Context, auth *cliproxya
 		auth = updatedAuth
 	}
 
-	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayload, true)
-	translated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, true)
-
-	translated, err = thinking.ApplyThinking(translated, req.Model, from.String(), to.String(), e.Identifier())
+	translated, err := e.translateAntigravityRequest(ctx, from, baseModel, req, opts, true)
 	if err != nil {
 		return nil, err
 	}
-
-	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
-	requestPath := helps.PayloadRequestPath(opts)
-	translated = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, "antigravity", from.String(), "request", translated, originalTranslated, requestedModel, requestPath, opts.Headers)
+	cloak := helps.CloakSettingsFromAuth(auth)
+	userAgent := getClientUserAgent(ctx)
+	helps.TraceGeminiCloaking(ctx, cloak, userAgent)
+	translated = helps.ApplyGeminiCloaking(translated, "request", userAgent, cloak)
 	translated, _ = sjson.DeleteBytes(translated, "request.stream")
 	reporter.SetTranslatedReasoningEffort(translated, to.String())
 
 	useCredits := cliproxyauth.AntigravityCreditsRequested(ctx) && antigravityCreditsRetryEnabled(e.cfg)
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
+	baseURLs := antigravityBaseURLOrder(e.cfg, auth)
 	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, 0)
 	httpClient = reporter.TrackHTTPClient(httpClient)
 
@@ -1400,6 +1456,7 @@ attemptLoop:
 				if errors.Is(errDo, context.Canceled) || errors.Is(errDo, context.DeadlineExceeded) {
 					return nil, errDo
 				}
+				observeAntigravityBaseURL(baseURL, 0, errDo)
 				lastStatus = 0
 				lastBody = nil
 				lastErr = errDo
@@ -1411,6 +1468,7 @@ attemptLoop:
 				return nil, err
 			}
 			helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+			observeAntigravityBaseURL(baseURL, httpResp.StatusCode, nil)
 			if httpResp.StatusCode < http.StatusOK || httpResp.StatusCode >= http.StatusMultipleChoices {
 				bodyBytes, errRead := io.ReadAll(httpResp.Body)
 				if errClose := httpResp.Body.Close(); errClose != nil {
@@ -1473,6 +1531,7 @@ attemptLoop:
 				lastErr = nil
 				if httpResp.StatusCode == http.StatusTooManyRequests && idx+1 < len(baseURLs) {
 					log.Debugf("antigravity executor: rate limited on base url %s, retrying with fallback base url: %s", baseURL, baseURLs[idx+1])
+					metrics.ObserveAntigravityFallback(baseURL)
 					continue
 				}
 				if antigravityShouldRetryTransientResourceExhausted429(httpResp.StatusCode, bodyBytes) && attempt+1 < attempts {
@@ -1511,6 +1570,7 @@ attemptLoop:
 					err = errClear
 					return nil, err
 				}
+				markAntigravityProjectInvalidOnError(auth, httpResp.StatusCode, bodyBytes)
 				err = newAntigravityStatusErr(httpResp.StatusCode, bodyBytes)
 				return nil, err
 			}
@@ -1523,24 +1583,33 @@ attemptLoop:
 			out := make(chan cliproxyexecutor.StreamChunk)
 			go func(resp *http.Response) {
 				defer close(out)
+				body := helps.NewIdleTimeoutReader(resp.Body, helps.StreamIdleTimeout(e.cfg))
 				defer func() {
 					if replayAccumulator != nil {
 						replayAccumulator.Flush(ctx)
 					}
-					if errClose := resp.Body.Close(); errClose != nil {
+					if errClose := body.Close(); errClose != nil {
 						log.Errorf("antigravity executor: close response line error: %v", errClose)
 					}
 				}()
-				scanner := bufio.NewScanner(resp.Body)
+				scanner := bufio.NewScanner(helps.NewSSEReader(helps.GeminiStreamBody(body, opts.Alt)))
 				scanner.Buffer(nil, streamScannerBuffer)
+				wordMatcher := cloak.ResponseWordMatcher(userAgent)
 				claudeInputTokens := helps.NewClaudeInputTokenState(from, to, responseFormat, originalPayload)
 				var param any
+				var streamUsage helps.StreamUsageBuffer
+				forwarding := true
 				for scanner.Scan() {
 					line := scanner.Bytes()
 					helps.AppendAPIResponseChunk(ctx, e.cfg, line)
 					if replayAccumulator != nil {
 						replayAccumulator.ObserveSSELine(line)
 					}
+					streamUsage.ObserveGeminiStream(line)
+					if !forwarding {
+						// The client is gone; keep draining buffered chunks for usage only.
+						continue
+					}
 
 					// Filter usage metadata for all models
 					// Only retain usage statistics in the terminal chunk
@@ -1556,22 +1625,28 @@ attemptLoop:
 					}
 
 					payload = e.resolveWebSearchGroundingURLs(ctx, auth, from, originalPayload, translated, payload)
+					payload = wordMatcher.RestoreSensitiveWords(payload)
 					chunks := helps.TranslateStreamWithClaudeInputTokens(ctx, to, responseFormat, req.Model, opts.OriginalRequest, translated, bytes.Clone(payload), &param, claudeInputTokens)
-					for i := range chunks {
-						select {
-						case out <- cliproxyexecutor.StreamChunk{Payload: chunks[i]}:
-						case <-ctx.Done():
-							return
-						}
-					}
+					forwarding = helps.SendStreamChunks(ctx, out, chunks)
 				}
-				tail := helps.TranslateStreamWithClaudeInputTokens(ctx, to, responseFormat, req.Model, opts.OriginalRequest, translated, []byte("[DONE]"), &param, claudeInputTokens)
-				for i := range tail {
+				if reporter.PublishClientCancelled(ctx, &streamUsage) {
+					return
+				}
+				if errScan := scanner.Err(); helps.IsStreamIdleTimeout(errScan) {
+					// Skip the closing chunks so a stall before the first chunk can still be
+					// retried by the handler's bootstrap logic.
+					errScan = antigravityStreamReadErr(errScan)
+					helps.RecordAPIResponseError(ctx, e.cfg, errScan)
+					reporter.PublishFailure(ctx, errScan)
 					select {
-					case out <- cliproxyexecutor.StreamChunk{Payload: tail[i]}:
+					case out <- cliproxyexecutor.StreamChunk{Err: errScan}:
 					case <-ctx.Done():
-						return
 					}
+					return
+				}
+				tail := helps.TranslateStreamWithClaudeInputTokens(ctx, to, responseFormat, req.Model, opts.OriginalRequest, translated, []byte("[DONE]"), &param, claudeInputTokens)
+				if !helps.SendStreamChunks(ctx, out, tail) {
+					return
 				}
 				if errScan := scanner.Err(); errScan != nil {
 					helps.RecordAPIResponseError(ctx, e.cfg, errScan)
@@ -1589,6 +1664,7 @@ attemptLoop:
 
 		switch {
 		case lastStatus != 0:
+			markAntigravityProjectInvalidOnError(auth, lastStatus, lastBody)
 			err = newAntigravityStatusErr(lastStatus, lastBody)
 		case lastErr != nil:
 			err = lastErr
@@ -1609,6 +1685,9 @@ func (e *AntigravityExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Au
 	if auth == nil {
 		return auth, nil
 	}
+	if antigravityStaticToken(auth) != "" {
+		return auth, nil
+	}
 	updated, errRefresh := e.refreshToken(ctx, auth.Clone())
 	if errRefresh != nil {
 		return nil, errRefresh
@@ -1617,7 +1696,11 @@ func (e *AntigravityExecutor) Refresh(ctx context.Context, auth *cliproxyauth.Au
 }
 
 func (e *AntigravityExecutor) ShouldPrepareRequestAuth(auth *cliproxyauth.Auth) bool {
-	return antigravityProjectIDFromAuth(auth) == ""
+	if antigravityStaticToken(auth) != "" {
+		return false
+	}
+	projectID := antigravityProjectIDFromAuth(auth)
+	return projectID == "" || antigravityProjectMarkedInvalid(auth, projectID)
 }
 
 func (e *AntigravityExecutor) PrepareRequestAuth(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyauth.Auth, error) {
@@ -1633,21 +1716,36 @@ func (e *AntigravityExecutor) PrepareRequestAuth(ctx context.Context, auth *clip
 	if refreshedAuth != nil {
 		updated = refreshedAuth
 	}
-	if antigravityProjectIDFromAuth(updated) != "" {
+	staleProjectID := antigravityProjectIDFromAuth(updated)
+	if staleProjectID != "" && !antigravityProjectMarkedInvalid(updated, staleProjectID) {
 		return updated, nil
 	}
 
 	projectID, errProject := e.fetchAntigravityProjectID(ctx, updated, token)
 	if errProject != nil {
+		if staleProjectID != "" {
+			// Keep the previous project so requests still have a chance to succeed.
+			log.Warnf("antigravity executor: rediscover project id for auth %s failed: %v", updated.ID, errProject)
+			antigravityInvalidProjectByAuth.Delete(updated.ID)
+			return updated, nil
+		}
 		return nil, missingAntigravityProjectIDError(errProject)
 	}
 	if projectID == "" {
+		if staleProjectID != "" {
+			antigravityInvalidProjectByAuth.Delete(updated.ID)
+			return updated, nil
+		}
 		return nil, missingAntigravityProjectIDError(nil)
 	}
 	if updated.Metadata == nil {
 		updated.Metadata = make(map[string]any)
 	}
 	updated.Metadata["project_id"] = projectID
+	antigravityInvalidProjectByAuth.Delete(updated.ID)
+	if staleProjectID != "" && staleProjectID != projectID {
+		log.Infof("antigravity executor: auth %s project id changed from %s to %s", updated.ID, staleProjectID, projectID)
+	}
 	return updated, nil
 }
 
@@ -1680,9 +1778,9 @@ func (e *AntigravityExecutor) CountTokens(ctx context.Context, auth *cliproxyaut
 	}
 
 	// Prepare payload once (doesn't depend on baseURL)
-	payload := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, false)
+	payload := sdktranslator.TranslateRequestWithContext(ctx, from, to, baseModel, req.Payload, false)
 
-	payload, err := thinking.ApplyThinking(payload, req.Model, from.String(), to.String(), e.Identifier())
+	payload, err := thinking.ApplyThinkingWithTrace(ctx, payload, req.Model, from.String(), to.String(), e.Identifier())
 	if err != nil {
 		return cliproxyexecutor.Response{}, err
 	}
@@ -1691,8 +1789,8 @@ func (e *AntigravityExecutor) CountTokens(ctx context.Context, auth *cliproxyaut
 	payload = helps.DeleteJSONField(payload, "model")
 	payload = helps.DeleteJSONField(payload, "request.safetySettings")
 
-	baseURLs := antigravityBaseURLFallbackOrder(auth)
-	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, 0)
+	baseURLs := antigravityBaseURLOrder(e.cfg, auth)
+	httpClient := newAntigravityHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 
 	var authID, authLabel, authType, authValue string
 	if auth != nil {
@@ -1735,6 +1833,7 @@ func (e *AntigravityExecutor) CountTokens(ctx context.Context, auth *cliproxyaut
 			attrs = auth.Attributes
 		}
 		util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+		helps.ApplyForwardedClientHeaders(ctx, httpReq.Header, attrs)
 
 		helps.RecordAPIRequest(ctx, e.cfg, helps.UpstreamRequestLog{
 			URL:       requestURL.String(),
@@ -1754,6 +1853,7 @@ func (e *AntigravityExecutor) CountTokens(ctx context.Context, auth *cliproxyaut
 			if errors.Is(errDo, context.Canceled) || errors.Is(errDo, context.DeadlineExceeded) {
 				return cliproxyexecutor.Response{}, errDo
 			}
+			observeAntigravityBaseURL(baseURL, 0, errDo)
 			lastStatus = 0
 			lastBody = nil
 			lastErr = errDo
@@ -1765,6 +1865,7 @@ func (e *AntigravityExecutor) CountTokens(ctx context.Context, auth *cliproxyaut
 		}
 
 		helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+		observeAntigravityBaseURL(baseURL, httpResp.StatusCode, nil)
 		bodyBytes, errRead := io.ReadAll(httpResp.Body)
 		if errClose := httpResp.Body.Close(); errClose != nil {
 			log.Errorf("antigravity executor: close response body error: %v", errClose)
@@ -1786,6 +1887,7 @@ func (e *AntigravityExecutor) CountTokens(ctx context.Context, auth *cliproxyaut
 		lastErr = nil
 		if httpResp.StatusCode == http.StatusTooManyRequests && idx+1 < len(baseURLs) {
 			log.Debugf("antigravity executor: rate limited on base url %s, retrying with fallback base url: %s", baseURL, baseURLs[idx+1])
+			metrics.ObserveAntigravityFallback(baseURL)
 			continue
 		}
 		sErr := statusErr{code: httpResp.StatusCode, msg: string(bodyBytes)}
@@ -1817,12 +1919,20 @@ func (e *AntigravityExecutor) ensureAccessToken(ctx context.Context, auth *clipr
 	if auth == nil {
 		return "", nil, statusErr{code: http.StatusUnauthorized, msg: "missing auth"}
 	}
+	if token := antigravityStaticToken(auth); token != "" {
+		return token, nil, nil
+	}
 	accessToken := metaStringValue(auth.Metadata, "access_token")
 	expiry := tokenExpiry(auth.Metadata)
 	if accessToken != "" && expiry.After(time.Now().Add(refreshSkew)) {
 		e.maybeRefreshAntigravityCreditsHint(ctx, auth, accessToken)
 		return accessToken, nil, nil
 	}
+	if updated := antigravityAuthWithRefreshedToken(auth); updated != nil {
+		token := metaStringValue(updated.Metadata, "access_token")
+		e.maybeRefreshAntigravityCreditsHint(ctx, updated, token)
+		return token, updated, nil
+	}
 	refreshCtx := context.Background()
 	if ctx != nil {
 		if rt, ok := ctx.Value("cliproxy.roundtripper").(http.RoundTripper); ok && rt != nil {
@@ -1969,6 +2079,7 @@ func (e *AntigravityExecutor) refreshToken(ctx context.Context, auth *cliproxyau
 	auth.Metadata["timestamp"] = now.UnixMilli()
 	auth.Metadata["expired"] = now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second).Format(time.RFC3339)
 	auth.Metadata["type"] = antigravityAuthType
+	storeAntigravityRefreshedToken(auth, tokenResp, now)
 	if errProject := e.ensureAntigravityProjectID(ctx, auth, tokenResp.AccessToken); errProject != nil {
 		log.Warnf("antigravity executor: ensure project id failed: %v", errProject)
 	}
@@ -1976,6 +2087,61 @@ func (e *AntigravityExecutor) refreshToken(ctx context.Context, auth *cliproxyau
 	return auth, nil
 }
 
+// storeAntigravityRefreshedToken records a freshly refreshed token for the auth so concurrent
+// callers holding an older copy reuse it instead of refreshing again. Tokens past the refresh
+// skew window are dropped, so entries of removed auths do not outlive their token.
+func storeAntigravityRefreshedToken(auth *cliproxyauth.Auth, tokenResp *antigravityTokenRefreshData, refreshedAt time.Time) {
+	if auth == nil || tokenResp == nil || strings.TrimSpace(auth.ID) == "" || tokenResp.AccessToken == "" {
+		return
+	}
+	antigravityRefreshedTokenByAuth.Range(func(key, value any) bool {
+		if refreshed, ok := value.(*antigravityRefreshedToken); !ok || refreshed == nil || !refreshed.usable(refreshedAt) {
+			antigravityRefreshedTokenByAuth.Delete(key)
+		}
+		return true
+	})
+	antigravityRefreshedTokenByAuth.Store(auth.ID, &antigravityRefreshedToken{
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		ExpiresIn:    tokenResp.ExpiresIn,
+		RefreshedAt:  refreshedAt,
+	})
+}
+
+// antigravityAuthWithRefreshedToken returns a copy of auth carrying the token refreshed by another
+// caller, or nil when no refreshed token outside the refresh skew window is available.
+func antigravityAuthWithRefreshedToken(auth *cliproxyauth.Auth) *cliproxyauth.Auth {
+	if auth == nil || strings.TrimSpace(auth.ID) == "" {
+		return nil
+	}
+	value, ok := antigravityRefreshedTokenByAuth.Load(auth.ID)
+	if !ok {
+		return nil
+	}
+	refreshed, ok := value.(*antigravityRefreshedToken)
+	if !ok || refreshed == nil || !refreshed.usable(time.Now()) {
+		antigravityRefreshedTokenByAuth.CompareAndDelete(auth.ID, value)
+		return nil
+	}
+	if refreshed.AccessToken == metaStringValue(auth.Metadata, "access_token") {
+		return nil
+	}
+
+	updated := auth.Clone()
+	if updated.Metadata == nil {
+		updated.Metadata = make(map[string]any)
+	}
+	updated.Metadata["access_token"] = refreshed.AccessToken
+	if refreshed.RefreshToken != "" {
+		updated.Metadata["refresh_token"] = refreshed.RefreshToken
+	}
+	updated.Metadata["expires_in"] = refreshed.ExpiresIn
+	updated.Metadata["timestamp"] = refreshed.RefreshedAt.UnixMilli()
+	updated.Metadata["expired"] = refreshed.expiry().Format(time.RFC3339)
+	updated.Metadata["type"] = antigravityAuthType
+	return updated
+}
+
 func (e *AntigravityExecutor) refreshTokenSingleFlight(ctx context.Context, auth *cliproxyauth.Auth, refreshToken string) (*antigravityTokenRefreshData, error) {
 	form := url.Values{}
 	form.Set("client_id", antigravityClientID)
@@ -2071,19 +2237,97 @@ func (e *AntigravityExecutor) projectIDForRequest(_ context.Context, auth *clipr
 	if projectID := antigravityProjectIDFromAuth(auth); projectID != "" {
 		return projectID, nil
 	}
+	// A gateway behind a static token resolves the project itself.
+	if antigravityStaticToken(auth) != "" {
+		return "", nil
+	}
 	return "", missingAntigravityProjectIDError(nil)
 }
 
 func antigravityProjectIDFromAuth(auth *cliproxyauth.Auth) string {
-	if auth == nil || auth.Metadata == nil {
+	if auth == nil {
 		return ""
 	}
-	if pid, ok := auth.Metadata["project_id"].(string); ok {
+	if pid, ok := auth.Metadata["project_id"].(string); ok && strings.TrimSpace(pid) != "" {
 		return strings.TrimSpace(pid)
 	}
+	if auth.Attributes != nil {
+		return strings.TrimSpace(auth.Attributes["project_id"])
+	}
+	return ""
+}
+
+// antigravityStaticToken returns the static bearer token of an auth that reaches a custom
+// Antigravity base URL with an api_key instead of OAuth tokens, read from its attributes
+// or its auth file. Such auths are never refreshed.
+func antigravityStaticToken(auth *cliproxyauth.Auth) string {
+	if resolveCustomAntigravityBaseURL(auth) == "" {
+		return ""
+	}
+	if auth.Attributes != nil {
+		if apiKey := strings.TrimSpace(auth.Attributes["api_key"]); apiKey != "" {
+			return apiKey
+		}
+	}
+	if apiKey, ok := auth.Metadata["api_key"].(string); ok {
+		return strings.TrimSpace(apiKey)
+	}
 	return ""
 }
 
+// markAntigravityProjectInvalidOnError records the auth's project_id as stale when the upstream
+// rejects it, so the next request rediscovers the project via PrepareRequestAuth.
+func markAntigravityProjectInvalidOnError(auth *cliproxyauth.Auth, statusCode int, body []byte) {
+	if auth == nil || strings.TrimSpace(auth.ID) == "" || !antigravityIsInvalidProjectError(statusCode, body) {
+		return
+	}
+	projectID := antigravityProjectIDFromAuth(auth)
+	if projectID == "" {
+		return
+	}
+	log.Debugf("antigravity executor: upstream rejected project id %s for auth %s, scheduling rediscovery", projectID, auth.ID)
+	antigravityInvalidProjectByAuth.Store(auth.ID, projectID)
+}
+
+func antigravityProjectMarkedInvalid(auth *cliproxyauth.Auth, projectID string) bool {
+	if auth == nil || projectID == "" {
+		return false
+	}
+	value, ok := antigravityInvalidProjectByAuth.Load(auth.ID)
+	if !ok {
+		return false
+	}
+	stale, _ := value.(string)
+	return stale == projectID
+}
+
+// antigravityIsInvalidProjectError reports whether an upstream error indicates that the
+// project attached to the request does not exist or is not usable by the credential.
+func antigravityIsInvalidProjectError(statusCode int, body []byte) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound:
+	default:
+		return false
+	}
+	message := strings.ToLower(gjson.GetBytes(body, "error.message").String())
+	if message == "" {
+		message = strings.ToLower(string(body))
+	}
+	if !strings.Contains(message, "project") {
+		return false
+	}
+	status := gjson.GetBytes(body, "error.status").String()
+	if status == "PERMISSION_DENIED" || status == "NOT_FOUND" {
+		return true
+	}
+	for _, keyword := range []string{"invalid project", "project not found", "does not exist", "has been deleted"} {
+		if strings.Contains(message, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
 func missingAntigravityProjectIDError(cause error) statusErr {
 	msg := "antigravity auth missing project_id"
 	if cause != nil {
@@ -2223,60 +2467,11 @@ func (e *AntigravityExecutor) buildRequest(ctx context.Context, auth *cliproxyau
 	if errProject != nil {
 		return nil, errProject
 	}
-	payload = geminiToAntigravity(modelName, payload, projectID)
-
-	// Cap maxOutputTokens to model's max_completion_tokens from registry
-	if maxOut := gjson.GetBytes(payload, "request.generationConfig.maxOutputTokens"); maxOut.Exists() && maxOut.Type == gjson.Number {
-		if modelInfo := registry.LookupModelInfo(modelName, "antigravity"); modelInfo != nil && modelInfo.MaxCompletionTokens > 0 {
-			if int(maxOut.Int()) > modelInfo.MaxCompletionTokens {
-				payload, _ = sjson.SetBytes(payload, "request.generationConfig.maxOutputTokens", modelInfo.MaxCompletionTokens)
-			}
-		}
-	}
-
-	useAntigravitySchema := strings.Contains(modelName, "claude") || strings.Contains(modelName, "gemini-3-pro") || strings.Contains(modelName, "gemini-3.1-pro")
-	var (
-		bodyReader io.Reader
-		payloadLog []byte
-	)
-	if antigravityRequestNeedsSchemaSanitization(payload) {
-		payloadStr := string(payload)
-		paths := make([]string, 0)
-		util.Walk(gjson.Parse(payloadStr), "", "parametersJsonSchema", &paths)
-		for _, p := range paths {
-			payloadStr, _ = util.RenameKey(payloadStr, p, p[:len(p)-len("parametersJsonSchema")]+"parameters")
-		}
-
-		if useAntigravitySchema {
-			payloadStr = util.CleanJSONSchemaForAntigravity(payloadStr)
-		} else {
-			payloadStr = util.CleanJSONSchemaForGemini(payloadStr)
-		}
-
-		if strings.Contains(modelName, "claude") {
-			updated, _ := sjson.SetBytes([]byte(payloadStr), "request.toolConfig.functionCallingConfig.mode", "VALIDATED")
-			payloadStr = string(updated)
-		} else {
-			payloadStr, _ = sjson.Delete(payloadStr, "request.generationConfig.maxOutputTokens")
-		}
-
-		payloadStrBytes := applyAntigravityNativeSignatureReplayIfNeeded(modelName, []byte(payloadStr))
-		bodyReader = bytes.NewReader(payloadStrBytes)
-		if e.cfg != nil && e.cfg.RequestLog {
-			payloadLog = append([]byte(nil), payloadStrBytes...)
-		}
-	} else {
-		if strings.Contains(modelName, "claude") {
-			payload, _ = sjson.SetBytes(payload, "request.toolConfig.functionCallingConfig.mode", "VALIDATED")
-		} else {
-			payload, _ = sjson.DeleteBytes(payload, "request.generationConfig.maxOutputTokens")
-		}
-
-		payload = applyAntigravityNativeSignatureReplayIfNeeded(modelName, payload)
-		bodyReader = bytes.NewReader(payload)
-		if e.cfg != nil && e.cfg.RequestLog {
-			payloadLog = append([]byte(nil), payload...)
-		}
+	payload = e.finalizeAntigravityPayload(ctx, modelName, payload, projectID)
+	bodyReader := bytes.NewReader(payload)
+	var payloadLog []byte
+	if e.cfg != nil && e.cfg.RequestLog {
+		payloadLog = append([]byte(nil), payload...)
 	}
 
 	// if useAntigravitySchema {
@@ -2308,6 +2503,7 @@ func (e *AntigravityExecutor) buildRequest(ctx context.Context, auth *cliproxyau
 		attrs = auth.Attributes
 	}
 	util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+	helps.ApplyForwardedClientHeaders(ctx, httpReq.Header, attrs)
 
 	var authID, authLabel, authType, authValue string
 	if auth != nil {
@@ -2330,6 +2526,112 @@ func (e *AntigravityExecutor) buildRequest(ctx context.Context, auth *cliproxyau
 	return httpReq, nil
 }
 
+// translateAntigravityRequest translates the client payload into the Antigravity format and
+// applies thinking configuration and payload rules, before the request envelope is finalized.
+func (e *AntigravityExecutor) translateAntigravityRequest(ctx context.Context, from sdktranslator.Format, baseModel string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) ([]byte, error) {
+	if errValidate := validateOpenAIRequestForGemini(from, req.Payload); errValidate != nil {
+		return nil, errValidate
+	}
+	if errValidate := validateGeminiRequestForAntigravity(from, req.Payload); errValidate != nil {
+		return nil, errValidate
+	}
+	var claudeBetas []string
+	if from == sdktranslator.FormatClaude && strings.Contains(baseModel, "claude") {
+		claudeBetas, req.Payload = extractAntigravityClaudeBetas(req.Payload, opts.Headers)
+	}
+	to := sdktranslator.FromString("antigravity")
+	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, stream)
+	translated := sdktranslator.TranslateRequestWithContext(ctx, from, to, baseModel, req.Payload, stream)
+
+	translated, err := thinking.ApplyThinkingWithTrace(ctx, translated, req.Model, from.String(), to.String(), e.Identifier())
+	if err != nil {
+		return nil, err
+	}
+	outputCeiling := applyAntigravityClaudeBetas(ctx, baseModel, claudeBetas)
+	if sessionID := antigravityPinnedSessionID(opts); sessionID != "" {
+		translated, _ = sjson.SetBytes(translated, "request.sessionId", sessionID)
+	}
+
+	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
+	requestPath := helps.PayloadRequestPath(opts)
+	translated = helps.ApplyPayloadConfigWithTrace(helps.WithOutputTokenCeiling(ctx, outputCeiling), e.cfg, e.Identifier(), baseModel, "antigravity", from.String(), "request", translated, originalTranslated, requestedModel, requestPath, opts.Headers)
+	return clampAntigravityMaxOutputTokens(baseModel, translated, outputCeiling), nil
+}
+
+// antigravityUnsupportedGenerationConfig lists the generationConfig fields of Gemini-native
+// requests that Antigravity does not honor. seed is forwarded as is.
+var antigravityUnsupportedGenerationConfig = []string{"responseLogprobs", "logprobs"}
+
+// validateGeminiRequestForAntigravity rejects Gemini-native generationConfig fields that
+// Antigravity cannot honor with a structured 400 naming the offending field, instead of
+// letting the upstream ignore them.
+func validateGeminiRequestForAntigravity(from sdktranslator.Format, payload []byte) error {
+	if from != sdktranslator.FormatGemini {
+		return nil
+	}
+	for _, field := range antigravityUnsupportedGenerationConfig {
+		value := gjson.GetBytes(payload, "generationConfig."+field)
+		if !value.Exists() || value.Type == gjson.Null || value.Type == gjson.False {
+			continue
+		}
+		param := "generationConfig." + field
+		msg := `{"error":{"type":"invalid_request_error","code":"unsupported_parameter"}}`
+		msg, _ = sjson.Set(msg, "error.message", fmt.Sprintf("%s is not supported by Antigravity", param))
+		msg, _ = sjson.Set(msg, "error.param", param)
+		return statusErr{code: http.StatusBadRequest, msg: msg}
+	}
+	return nil
+}
+
+// finalizeAntigravityPayload wraps a translated payload in the Antigravity envelope and
+// applies the model-specific schema cleaning and tool config adjustments sent upstream.
+func (e *AntigravityExecutor) finalizeAntigravityPayload(ctx context.Context, modelName string, payload []byte, projectID string) []byte {
+	payload = geminiToAntigravity(modelName, payload, projectID)
+
+	useAntigravitySchema := strings.Contains(modelName, "claude") || strings.Contains(modelName, "gemini-3-pro") || strings.Contains(modelName, "gemini-3.1-pro")
+	if antigravityRequestNeedsSchemaSanitization(payload) {
+		payloadStr := string(payload)
+		paths := make([]string, 0)
+		util.Walk(gjson.Parse(payloadStr), "", "parametersJsonSchema", &paths)
+		for _, p := range paths {
+			payloadStr, _ = util.RenameKey(payloadStr, p, p[:len(p)-len("parametersJsonSchema")]+"parameters")
+		}
+
+		if useAntigravitySchema {
+			payloadStr = util.CleanJSONSchemaForAntigravity(payloadStr)
+			transformtrace.Record(ctx, "schema-cleaner: antigravity")
+		} else {
+			payloadStr = util.CleanJSONSchemaForGemini(payloadStr)
+			transformtrace.Record(ctx, "schema-cleaner: gemini")
+		}
+
+		if strings.Contains(modelName, "claude") {
+			payloadStr = string(defaultAntigravityFunctionCallingMode([]byte(payloadStr)))
+		} else {
+			payloadStr, _ = sjson.Delete(payloadStr, "request.generationConfig.maxOutputTokens")
+		}
+
+		return applyAntigravityNativeSignatureReplayIfNeeded(modelName, []byte(payloadStr))
+	}
+
+	if strings.Contains(modelName, "claude") {
+		payload = defaultAntigravityFunctionCallingMode(payload)
+	} else {
+		payload, _ = sjson.DeleteBytes(payload, "request.generationConfig.maxOutputTokens")
+	}
+	return applyAntigravityNativeSignatureReplayIfNeeded(modelName, payload)
+}
+
+// defaultAntigravityFunctionCallingMode selects VALIDATED function calling for Claude models
+// unless the client's tool choice or a payload rule already set a functionCallingConfig.
+func defaultAntigravityFunctionCallingMode(payload []byte) []byte {
+	if gjson.GetBytes(payload, "request.toolConfig.functionCallingConfig").Exists() {
+		return payload
+	}
+	payload, _ = sjson.SetBytes(payload, "request.toolConfig.functionCallingConfig.mode", "VALIDATED")
+	return payload
+}
+
 func antigravityRequestNeedsSchemaSanitization(payload []byte) bool {
 	if gjson.GetBytes(payload, "request.tools.0").Exists() {
 		return true
@@ -2775,9 +3077,15 @@ func geminiToAntigravity(modelName string, payload []byte, projectID string) []b
 
 	if isImageModel {
 		template, _ = sjson.SetBytes(template, "requestId", generateImageGenRequestID())
+		template, _ = sjson.DeleteBytes(template, "request.sessionId")
 	} else if reqType != "web_search" {
 		template, _ = sjson.SetBytes(template, "requestId", generateRequestID())
-		template, _ = sjson.SetBytes(template, "request.sessionId", generateStableSessionID(payload))
+		// A session pinned by translateAntigravityRequest wins over the content hash.
+		if gjson.GetBytes(template, "request.sessionId").String() == "" {
+			template, _ = sjson.SetBytes(template, "request.sessionId", generateStableSessionID(payload))
+		}
+	} else {
+		template, _ = sjson.DeleteBytes(template, "request.sessionId")
 	}
 
 	template, _ = sjson.DeleteBytes(template, "request.safetySettings")
@@ -2810,12 +3118,34 @@ func generateStableSessionID(payload []byte) string {
 			if content.Get("role").String() == "user" {
 				text := content.Get("parts.0.text").String()
 				if text != "" {
-					h := sha256.Sum256([]byte(text))
-					n := int64(binary.BigEndian.Uint64(h[:8])) & 0x7FFFFFFFFFFFFFFF
-					return "-" + strconv.FormatInt(n, 10)
+					return hashAntigravitySessionID(text)
 				}
 			}
 		}
 	}
 	return generateSessionID()
 }
+
+// hashAntigravitySessionID maps text to a session ID of the shape Antigravity expects: a
+// minus sign followed by a non-negative int64.
+func hashAntigravitySessionID(text string) string {
+	h := sha256.Sum256([]byte(text))
+	n := int64(binary.BigEndian.Uint64(h[:8])) & 0x7FFFFFFFFFFFFFFF
+	return "-" + strconv.FormatInt(n, 10)
+}
+
+// antigravityPinnedSessionID returns the session ID the client pinned through
+// metadata.session_id, or "" when it did not. IDs already of the expected shape are kept;
+// any other string is hashed into it.
+func antigravityPinnedSessionID(opts cliproxyexecutor.Options) string {
+	pinned := metadataString(opts.Metadata, cliproxyexecutor.SessionIDMetadataKey)
+	if pinned == "" {
+		return ""
+	}
+	if digits, ok := strings.CutPrefix(pinned, "-"); ok && digits != "" && digits[0] != '+' {
+		if n, errParse := strconv.ParseInt(digits, 10, 64); errParse == nil && n >= 0 {
+			return pinned
+		}
+	}
+	return hashAntigravitySessionID(pinned)
+}
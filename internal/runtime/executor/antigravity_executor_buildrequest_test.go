@@ -3,14 +3,18 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/sjson"
 )
 
 func TestAntigravityBuildRequest_SanitizesGeminiToolSchema(t *testing.T) {
@@ -436,3 +440,112 @@ func assertSchemaSanitizedAndPropertyPreserved(t *testing.T, params map[string]a
 		t.Fatalf("deprecated should be removed from nested schema")
 	}
 }
+
+func translatedAntigravityClaudeRequest(t *testing.T, cfg *config.Config, toolChoice string) map[string]any {
+	t.Helper()
+	executor := NewAntigravityExecutor(cfg)
+	payload := []byte(`{"model":"claude-sonnet-4-6","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{"name":"lookup","parameters":{"type":"object","properties":{"q":{"type":"string"}}}}},{"type":"function","function":{"name":"search","parameters":{"type":"object","properties":{"q":{"type":"string"}}}}}]}`)
+	if toolChoice != "" {
+		payload, _ = sjson.SetRawBytes(payload, "tool_choice", []byte(toolChoice))
+	}
+	req := cliproxyexecutor.Request{Model: "claude-sonnet-4-6", Payload: payload}
+	translated, errTranslate := executor.translateAntigravityRequest(context.Background(), sdktranslator.FromString("openai"), "claude-sonnet-4-6", req, cliproxyexecutor.Options{}, false)
+	if errTranslate != nil {
+		t.Fatalf("translateAntigravityRequest error: %v", errTranslate)
+	}
+	return buildRequestBodyFromRawPayload(t, "claude-sonnet-4-6", translated)
+}
+
+func functionCallingConfig(t *testing.T, body map[string]any) map[string]any {
+	t.Helper()
+	request, _ := body["request"].(map[string]any)
+	toolConfig, _ := request["toolConfig"].(map[string]any)
+	callingConfig, ok := toolConfig["functionCallingConfig"].(map[string]any)
+	if !ok {
+		t.Fatalf("functionCallingConfig missing: %v", request["toolConfig"])
+	}
+	return callingConfig
+}
+
+func TestAntigravityBuildRequest_DefaultsClaudeFunctionCallingToValidated(t *testing.T) {
+	callingConfig := functionCallingConfig(t, translatedAntigravityClaudeRequest(t, nil, ""))
+	if callingConfig["mode"] != "VALIDATED" {
+		t.Fatalf("mode = %v, want VALIDATED", callingConfig["mode"])
+	}
+}
+
+func TestAntigravityBuildRequest_PreservesClientFunctionCallingConfig(t *testing.T) {
+	callingConfig := functionCallingConfig(t, translatedAntigravityClaudeRequest(t, nil, `{"type":"function","function":{"name":"search"}}`))
+	if callingConfig["mode"] != "ANY" {
+		t.Fatalf("mode = %v, want the client's ANY", callingConfig["mode"])
+	}
+	names, _ := callingConfig["allowedFunctionNames"].([]any)
+	if len(names) != 1 || names[0] != "search" {
+		t.Fatalf("allowedFunctionNames = %v, want [search]", callingConfig["allowedFunctionNames"])
+	}
+}
+
+func TestAntigravityBuildRequest_PayloadOverrideSelectsFunctionCallingMode(t *testing.T) {
+	cfg := &config.Config{Payload: config.PayloadConfig{Override: []config.PayloadRule{{
+		Models: []config.PayloadModelRule{{Name: "claude-*", Protocol: "antigravity"}},
+		Params: map[string]any{"toolConfig.functionCallingConfig.mode": "AUTO"},
+	}}}}
+	callingConfig := functionCallingConfig(t, translatedAntigravityClaudeRequest(t, cfg, ""))
+	if callingConfig["mode"] != "AUTO" {
+		t.Fatalf("mode = %v, want the payload rule's AUTO", callingConfig["mode"])
+	}
+}
+
+func TestAntigravityTranslateRequest_PreservesGeminiSeed(t *testing.T) {
+	executor := NewAntigravityExecutor(&config.Config{})
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"seed":42,"temperature":0.2,"responseLogprobs":false}}`)
+	req := cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload}
+	translated, errTranslate := executor.translateAntigravityRequest(context.Background(), sdktranslator.FormatGemini, "gemini-2.5-flash", req, cliproxyexecutor.Options{}, false)
+	if errTranslate != nil {
+		t.Fatalf("translateAntigravityRequest error: %v", errTranslate)
+	}
+	body := buildRequestBodyFromRawPayload(t, "gemini-2.5-flash", translated)
+
+	request, _ := body["request"].(map[string]any)
+	generationConfig, _ := request["generationConfig"].(map[string]any)
+	if seed, _ := generationConfig["seed"].(float64); seed != 42 {
+		t.Fatalf("request.generationConfig.seed = %v, want 42; body = %v", generationConfig["seed"], body)
+	}
+}
+
+func TestAntigravityTranslateRequest_RejectsGeminiLogprobs(t *testing.T) {
+	tests := []struct {
+		name             string
+		generationConfig string
+		wantParam        string
+	}{
+		{name: "response logprobs", generationConfig: `{"responseLogprobs":true}`, wantParam: "generationConfig.responseLogprobs"},
+		{name: "logprobs count", generationConfig: `{"logprobs":3}`, wantParam: "generationConfig.logprobs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewAntigravityExecutor(&config.Config{})
+			payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+			payload, _ = sjson.SetRawBytes(payload, "generationConfig", []byte(tt.generationConfig))
+			req := cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload}
+
+			_, errTranslate := executor.translateAntigravityRequest(context.Background(), sdktranslator.FormatGemini, "gemini-2.5-flash", req, cliproxyexecutor.Options{}, false)
+			var status statusErr
+			if !errors.As(errTranslate, &status) || status.StatusCode() != http.StatusBadRequest {
+				t.Fatalf("translateAntigravityRequest error = %v, want 400 statusErr", errTranslate)
+			}
+			var body struct {
+				Error struct {
+					Code  string `json:"code"`
+					Param string `json:"param"`
+				} `json:"error"`
+			}
+			if errUnmarshal := json.Unmarshal([]byte(status.Error()), &body); errUnmarshal != nil {
+				t.Fatalf("unmarshal error body: %v", errUnmarshal)
+			}
+			if body.Error.Code != "unsupported_parameter" || body.Error.Param != tt.wantParam {
+				t.Fatalf("error = %s, want code unsupported_parameter and param %s", status.Error(), tt.wantParam)
+			}
+		})
+	}
+}
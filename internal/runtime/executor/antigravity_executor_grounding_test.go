@@ -0,0 +1,26 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestAntigravityConvertStreamToNonStream_RetainsGroundingMetadata(t *testing.T) {
+	grounding := `{"webSearchQueries":["who won euro 2024"],"groundingChunks":[{"web":{"uri":"https://vertexaisearch.cloud.google.com/grounding-api-redirect/abc","title":"uefa.com"}}],"groundingSupports":[{"segment":{"startIndex":0,"endIndex":20,"text":"Spain won Euro 2024."},"groundingChunkIndices":[0]}]}`
+	stream := strings.Join([]string{
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"Spain won "}]}}],"responseId":"resp-grounded"},"traceId":"trace-1"}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"Euro 2024."}]},"finishReason":"STOP","groundingMetadata":` + grounding + `}]}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[]}}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":6,"totalTokenCount":14}}}`,
+	}, "\n")
+
+	out := (&AntigravityExecutor{}).convertStreamToNonStream([]byte(stream))
+
+	if got := gjson.GetBytes(out, "response.candidates.0.groundingMetadata").Raw; got != grounding {
+		t.Fatalf("groundingMetadata = %s, want %s", got, grounding)
+	}
+	if got := gjson.GetBytes(out, "response.candidates.0.content.parts.0.text").String(); got != "Spain won Euro 2024." {
+		t.Fatalf("text = %q, want aggregated text", got)
+	}
+}
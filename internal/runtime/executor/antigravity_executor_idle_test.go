@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func TestAntigravityExecutorExecuteStreamCancelsIdleUpstream(t *testing.T) {
+	upstreamGone := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, text := range []string{"hel", "lo"} {
+			_, _ = w.Write([]byte(`data: {"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"` + text + `"}]}}]}}` + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+		// Keep the connection open without sending anything until the proxy gives up.
+		select {
+		case <-r.Context().Done():
+			close(upstreamGone)
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{RequestRetry: 1}
+	cfg.Streaming.IdleTimeoutSeconds = 1
+	exec := NewAntigravityExecutor(cfg)
+	auth := &cliproxyauth.Auth{
+		ID:       "idle-antigravity-stream-auth",
+		Provider: "antigravity",
+		Attributes: map[string]string{
+			"base_url": server.URL,
+		},
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	result, errExecute := exec.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash",
+		Payload: payload,
+	}, cliproxyexecutor.Options{
+		SourceFormat:    sdktranslator.FormatGemini,
+		Stream:          true,
+		OriginalRequest: payload,
+	})
+	if errExecute != nil {
+		t.Fatalf("ExecuteStream() error = %v", errExecute)
+	}
+
+	var payloads int
+	var streamErr error
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case chunk, ok := <-result.Chunks:
+			if !ok {
+				done = true
+				break
+			}
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+				continue
+			}
+			payloads++
+		case <-deadline:
+			t.Fatal("stream did not end after the upstream went idle")
+		}
+	}
+
+	if payloads != 2 {
+		t.Fatalf("payload chunks = %d, want the 2 sent before the stall", payloads)
+	}
+	var status statusErr
+	if !errors.As(streamErr, &status) || status.StatusCode() != http.StatusGatewayTimeout {
+		t.Fatalf("stream error = %v, want a 504 statusErr", streamErr)
+	}
+	select {
+	case <-upstreamGone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request was not cancelled")
+	}
+}
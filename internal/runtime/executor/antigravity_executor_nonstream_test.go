@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func antigravityStreamLine(parts string) string {
+	return `{"response":{"candidates":[{"content":{"role":"model","parts":[` + parts + `]}}]}}`
+}
+
+func TestAntigravityConvertStreamToNonStream_KeepsPartOrder(t *testing.T) {
+	cases := []struct {
+		name   string
+		stream []string
+		want   string
+	}{
+		{
+			name: "text call text in one chunk",
+			stream: []string{
+				antigravityStreamLine(`{"text":"Let me check. "},{"functionCall":{"name":"lookup","args":{"q":"a"}}},{"text":"Done."}`),
+			},
+			want: `[{"text":"Let me check. "},{"functionCall":{"args":{"q":"a"},"name":"lookup"}},{"text":"Done."}]`,
+		},
+		{
+			name: "text call text across chunks",
+			stream: []string{
+				antigravityStreamLine(`{"text":"Let me "}`),
+				antigravityStreamLine(`{"text":"check. "},{"functionCall":{"name":"lookup","args":{}}}`),
+				antigravityStreamLine(`{"text":"Do"}`),
+				antigravityStreamLine(`{"text":"ne."}`),
+			},
+			want: `[{"text":"Let me check. "},{"functionCall":{"args":{},"name":"lookup"}},{"text":"Done."}]`,
+		},
+		{
+			name: "trailing text then call in next chunk",
+			stream: []string{
+				antigravityStreamLine(`{"functionCall":{"name":"a","args":{}}},{"text":"between"}`),
+				antigravityStreamLine(`{"functionCall":{"name":"b","args":{}}}`),
+				`{"response":{"candidates":[{"content":{"role":"model","parts":[]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":2,"totalTokenCount":3}}}`,
+			},
+			want: `[{"functionCall":{"args":{},"name":"a"}},{"text":"between"},{"functionCall":{"args":{},"name":"b"}}]`,
+		},
+		{
+			name: "thought text inline data text",
+			stream: []string{
+				antigravityStreamLine(`{"thought":true,"text":"plan"},{"thought":true,"text":"","thoughtSignature":"sig-1"},{"text":"Here "}`),
+				antigravityStreamLine(`{"text":"it is"},{"inline_data":{"mimeType":"image/png","data":"AAAA"}},{"text":" "},{"text":"after"}`),
+			},
+			want: `[{"text":"plan","thought":true,"thoughtSignature":"sig-1"},{"text":"Here it is"},{"inlineData":{"data":"AAAA","mimeType":"image/png"}},{"text":" after"}]`,
+		},
+		{
+			name: "whitespace text between calls is dropped in place",
+			stream: []string{
+				antigravityStreamLine(`{"functionCall":{"name":"a","args":{}}},{"text":"\n"},{"functionCall":{"name":"b","args":{}}}`),
+			},
+			want: `[{"functionCall":{"args":{},"name":"a"}},{"functionCall":{"args":{},"name":"b"}}]`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := (&AntigravityExecutor{}).convertStreamToNonStream([]byte(strings.Join(tc.stream, "\n")))
+			if got := gjson.GetBytes(out, "response.candidates.0.content.parts").Raw; got != tc.want {
+				t.Fatalf("parts = %s\nwant    %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -145,3 +145,201 @@ func TestAntigravityRefresh_DeduplicatesConcurrentRefresh(t *testing.T) {
 		t.Fatalf("expected both refresh callers to share a single upstream token call, got %d", got)
 	}
 }
+
+func TestAntigravityPrepareRequestAuth_RediscoversInvalidProject(t *testing.T) {
+	t.Cleanup(func() { antigravityInvalidProjectByAuth = sync.Map{} })
+	resetAntigravityCreditsRetryState()
+	t.Cleanup(resetAntigravityCreditsRetryState)
+
+	var loadCalls int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1internal:loadCodeAssist":
+			atomic.AddInt32(&loadCalls, 1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"cloudaicompanionProject":"fresh-project"}`)
+		default:
+			t.Errorf("unexpected antigravity test request path: %s", r.URL.Path)
+			http.Error(w, "unexpected path", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, errParse := url.Parse(server.URL)
+	if errParse != nil {
+		t.Fatalf("parse test server URL: %v", errParse)
+	}
+	useAntigravityRefreshTestTransport(t, serverURL.Host)
+
+	executor := &AntigravityExecutor{}
+	auth := &cliproxyauth.Auth{
+		ID:       "auth-stale-project",
+		Provider: "antigravity",
+		Metadata: map[string]any{
+			"access_token": "access",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+			"project_id":   "stale-project",
+		},
+	}
+	if executor.ShouldPrepareRequestAuth(auth) {
+		t.Fatal("expected auth with a project id to skip preparation")
+	}
+
+	markAntigravityProjectInvalidOnError(auth, http.StatusForbidden, []byte(`{"error":{"code":403,"message":"Permission denied on resource project stale-project.","status":"PERMISSION_DENIED"}}`))
+	if !executor.ShouldPrepareRequestAuth(auth) {
+		t.Fatal("expected rejected project id to trigger preparation")
+	}
+
+	updated, errPrepare := executor.PrepareRequestAuth(context.Background(), auth)
+	if errPrepare != nil {
+		t.Fatalf("PrepareRequestAuth() error = %v", errPrepare)
+	}
+	if updated == nil {
+		t.Fatal("expected updated auth, got nil")
+	}
+	if got := metaStringValue(updated.Metadata, "project_id"); got != "fresh-project" {
+		t.Fatalf("project_id = %q, want fresh-project", got)
+	}
+	if got := metaStringValue(auth.Metadata, "project_id"); got != "stale-project" {
+		t.Fatalf("original auth project_id = %q, want stale-project", got)
+	}
+	if executor.ShouldPrepareRequestAuth(updated) {
+		t.Fatal("expected rediscovered project id to clear the invalid marker")
+	}
+	if got := atomic.LoadInt32(&loadCalls); got != 1 {
+		t.Fatalf("loadCodeAssist calls = %d, want 1", got)
+	}
+}
+
+func TestAntigravityIsInvalidProjectError(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"permission denied on project", http.StatusForbidden, `{"error":{"message":"Permission denied on resource project foo.","status":"PERMISSION_DENIED"}}`, true},
+		{"invalid project", http.StatusBadRequest, `{"error":{"message":"Invalid project resource name projects/foo","status":"INVALID_ARGUMENT"}}`, true},
+		{"generic permission denied", http.StatusForbidden, `{"error":{"message":"The caller does not have permission","status":"PERMISSION_DENIED"}}`, false},
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"message":"quota exceeded for project foo","status":"RESOURCE_EXHAUSTED"}}`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := antigravityIsInvalidProjectError(tc.status, []byte(tc.body)); got != tc.want {
+				t.Fatalf("antigravityIsInvalidProjectError() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAntigravityEnsureAccessToken_RefreshesOnceForConcurrentCallers(t *testing.T) {
+	resetAntigravityRefreshGroupForTest()
+	t.Cleanup(resetAntigravityRefreshGroupForTest)
+	t.Cleanup(func() { antigravityRefreshedTokenByAuth = sync.Map{} })
+	resetAntigravityCreditsRetryState()
+	t.Cleanup(resetAntigravityCreditsRetryState)
+
+	var tokenCalls int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			atomic.AddInt32(&tokenCalls, 1)
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"access_token":"fresh-access","refresh_token":"fresh-refresh","token_type":"Bearer","expires_in":3600}`)
+		case "/v1internal:loadCodeAssist":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"paidTier":{"id":"tier","availableCredits":[]}}`)
+		default:
+			t.Errorf("unexpected antigravity test request path: %s", r.URL.Path)
+			http.Error(w, "unexpected path", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, errParse := url.Parse(server.URL)
+	if errParse != nil {
+		t.Fatalf("parse test server URL: %v", errParse)
+	}
+	useAntigravityRefreshTestTransport(t, serverURL.Host)
+
+	executor := &AntigravityExecutor{}
+	expired := &cliproxyauth.Auth{
+		ID:       "auth-expired",
+		Provider: "antigravity",
+		Metadata: map[string]any{
+			"access_token":  "stale-access",
+			"refresh_token": "stale-refresh",
+			"expired":       time.Now().Add(-time.Minute).Format(time.RFC3339),
+			"project_id":    "project-expired",
+		},
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	tokens := make(chan string, callers)
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(auth *cliproxyauth.Auth) {
+			defer wg.Done()
+			<-start
+			token, _, errToken := executor.ensureAccessToken(context.Background(), auth)
+			tokens <- token
+			errs <- errToken
+		}(expired.Clone())
+	}
+	close(start)
+	wg.Wait()
+	close(tokens)
+	close(errs)
+
+	for errToken := range errs {
+		if errToken != nil {
+			t.Fatalf("ensureAccessToken() error = %v", errToken)
+		}
+	}
+	for token := range tokens {
+		if token != "fresh-access" {
+			t.Fatalf("token = %q, want fresh-access", token)
+		}
+	}
+	if got := atomic.LoadInt32(&tokenCalls); got != 1 {
+		t.Fatalf("token endpoint calls = %d, want 1", got)
+	}
+
+	// A caller that still holds the stale copy after the refresh completed reuses the fresh token.
+	token, updated, errToken := executor.ensureAccessToken(context.Background(), expired.Clone())
+	if errToken != nil {
+		t.Fatalf("ensureAccessToken() error = %v", errToken)
+	}
+	if token != "fresh-access" || updated == nil || metaStringValue(updated.Metadata, "refresh_token") != "fresh-refresh" {
+		t.Fatalf("expected stale caller to receive refreshed auth, token=%q updated=%v", token, updated)
+	}
+	if got := atomic.LoadInt32(&tokenCalls); got != 1 {
+		t.Fatalf("token endpoint calls after late caller = %d, want 1", got)
+	}
+}
+
+func TestStoreAntigravityRefreshedTokenDropsExpiredEntries(t *testing.T) {
+	t.Cleanup(func() { antigravityRefreshedTokenByAuth = sync.Map{} })
+	now := time.Now()
+	storeAntigravityRefreshedToken(&cliproxyauth.Auth{ID: "auth-removed"}, &antigravityTokenRefreshData{AccessToken: "old", ExpiresIn: 3600}, now.Add(-2*time.Hour))
+	storeAntigravityRefreshedToken(&cliproxyauth.Auth{ID: "auth-live"}, &antigravityTokenRefreshData{AccessToken: "fresh", ExpiresIn: 3600}, now)
+
+	if _, ok := antigravityRefreshedTokenByAuth.Load("auth-removed"); ok {
+		t.Fatal("expired refreshed token was kept after storing another auth's token")
+	}
+	if _, ok := antigravityRefreshedTokenByAuth.Load("auth-live"); !ok {
+		t.Fatal("fresh refreshed token was not stored")
+	}
+
+	antigravityRefreshedTokenByAuth.Store("auth-stale", &antigravityRefreshedToken{AccessToken: "old", ExpiresIn: 60, RefreshedAt: now.Add(-time.Hour)})
+	if updated := antigravityAuthWithRefreshedToken(&cliproxyauth.Auth{ID: "auth-stale"}); updated != nil {
+		t.Fatalf("expired refreshed token was applied: %+v", updated.Metadata)
+	}
+	if _, ok := antigravityRefreshedTokenByAuth.Load("auth-stale"); ok {
+		t.Fatal("expired refreshed token was kept after a lookup")
+	}
+}
@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+var antigravitySessionIDShape = regexp.MustCompile(`^-[0-9]+$`)
+
+// antigravityRequestSessionID builds the upstream payload of a Gemini request asking "hi"
+// with metadata and returns its request.sessionId.
+func antigravityRequestSessionID(t *testing.T, metadata map[string]any) string {
+	t.Helper()
+	executor := NewAntigravityExecutor(&config.Config{})
+	req := cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, Metadata: metadata}
+	translated, errTranslate := executor.translateAntigravityRequest(context.Background(), sdktranslator.FormatGemini, "gemini-2.5-flash", req, opts, false)
+	if errTranslate != nil {
+		t.Fatalf("translateAntigravityRequest() error = %v", errTranslate)
+	}
+	payload := executor.finalizeAntigravityPayload(context.Background(), "gemini-2.5-flash", translated, "project-1")
+	return gjson.GetBytes(payload, "request.sessionId").String()
+}
+
+func TestAntigravitySessionIDUsesPinnedSession(t *testing.T) {
+	got := antigravityRequestSessionID(t, map[string]any{cliproxyexecutor.SessionIDMetadataKey: "-1234567890"})
+	if got != "-1234567890" {
+		t.Fatalf("sessionId = %q, want the pinned session verbatim", got)
+	}
+}
+
+func TestAntigravitySessionIDHashesArbitraryPinnedSession(t *testing.T) {
+	metadata := map[string]any{cliproxyexecutor.SessionIDMetadataKey: "conversation/abc 42"}
+	got := antigravityRequestSessionID(t, metadata)
+	if !antigravitySessionIDShape.MatchString(got) {
+		t.Fatalf("sessionId = %q, want a minus sign followed by digits", got)
+	}
+	if again := antigravityRequestSessionID(t, metadata); again != got {
+		t.Fatalf("sessionId = %q then %q, want the same hash for the same pinned session", got, again)
+	}
+	if unpinned := antigravityRequestSessionID(t, nil); unpinned == got {
+		t.Fatalf("sessionId = %q, want the pinned session to replace the content hash", got)
+	}
+
+	for _, pinned := range []string{"-", "-+12", "--12", "-99999999999999999999", "12"} {
+		if got := antigravityPinnedSessionID(cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.SessionIDMetadataKey: pinned}}); got == pinned || !antigravitySessionIDShape.MatchString(got) {
+			t.Fatalf("antigravityPinnedSessionID(%q) = %q, want it hashed into the expected shape", pinned, got)
+		}
+	}
+}
+
+func TestAntigravitySessionIDFallsBackToContentHash(t *testing.T) {
+	got := antigravityRequestSessionID(t, nil)
+	if want := hashAntigravitySessionID("hi"); got != want {
+		t.Fatalf("sessionId = %q, want the first user text hash %q", got, want)
+	}
+	if again := antigravityRequestSessionID(t, map[string]any{cliproxyexecutor.SessionIDMetadataKey: "  "}); again != got {
+		t.Fatalf("sessionId = %q with a blank pinned session, want the content hash %q", again, got)
+	}
+}
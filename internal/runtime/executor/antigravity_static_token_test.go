@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/watcher/synthesizer"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+const antigravityStaticTestResponse = `{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":1,"totalTokenCount":2}}}`
+
+// antigravityStaticTokenUpstream fakes a gateway that fronts the Antigravity API with a
+// static bearer token, and fails the test if the OAuth token endpoint is called.
+type antigravityStaticTokenUpstream struct {
+	server      *httptest.Server
+	oauthCalls  atomic.Int32
+	mu          sync.Mutex
+	paths       []string
+	bearers     []string
+	projectSeen []string
+}
+
+func newAntigravityStaticTokenUpstream(t *testing.T) *antigravityStaticTokenUpstream {
+	t.Helper()
+	upstream := &antigravityStaticTokenUpstream{}
+	upstream.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		upstream.mu.Lock()
+		upstream.paths = append(upstream.paths, r.URL.Path)
+		upstream.bearers = append(upstream.bearers, r.Header.Get("Authorization"))
+		upstream.projectSeen = append(upstream.projectSeen, gjson.GetBytes(body, "project").String())
+		upstream.mu.Unlock()
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, antigravityCountTokensPath):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"totalTokens":5}`))
+		case strings.HasSuffix(r.URL.Path, antigravityStreamPath):
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: " + antigravityStaticTestResponse + "\n\n"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(antigravityStaticTestResponse))
+		}
+	}))
+	t.Cleanup(upstream.server.Close)
+	return upstream
+}
+
+// context routes upstream requests to the fake gateway and counts OAuth token requests.
+func (u *antigravityStaticTokenUpstream) context() context.Context {
+	return context.WithValue(context.Background(), "cliproxy.roundtripper", roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "oauth2.googleapis.com" {
+			u.oauthCalls.Add(1)
+			return &http.Response{StatusCode: http.StatusBadRequest, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	}))
+}
+
+func (u *antigravityStaticTokenUpstream) auth() *cliproxyauth.Auth {
+	return &cliproxyauth.Auth{
+		ID:       "antigravity-static-token",
+		Provider: "antigravity",
+		Attributes: map[string]string{
+			"api_key":  "static-gateway-key",
+			"base_url": u.server.URL,
+		},
+	}
+}
+
+func (u *antigravityStaticTokenUpstream) assertStaticBearer(t *testing.T, wantPath string) {
+	t.Helper()
+	if calls := u.oauthCalls.Load(); calls != 0 {
+		t.Fatalf("OAuth token endpoint calls = %d, want 0", calls)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.paths) == 0 {
+		t.Fatal("gateway was not called")
+	}
+	for i, path := range u.paths {
+		if !strings.HasSuffix(path, wantPath) {
+			t.Fatalf("gateway path = %s, want suffix %s", path, wantPath)
+		}
+		if u.bearers[i] != "Bearer static-gateway-key" {
+			t.Fatalf("Authorization = %q, want static bearer", u.bearers[i])
+		}
+	}
+}
+
+func antigravityStaticTestRequest() (cliproxyexecutor.Request, cliproxyexecutor.Options) {
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	return cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload},
+		cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: payload}
+}
+
+func TestAntigravityExecuteUsesStaticToken(t *testing.T) {
+	upstream := newAntigravityStaticTokenUpstream(t)
+	req, opts := antigravityStaticTestRequest()
+
+	resp, errExecute := NewAntigravityExecutor(&config.Config{}).Execute(upstream.context(), upstream.auth(), req, opts)
+	if errExecute != nil {
+		t.Fatalf("Execute() error = %v", errExecute)
+	}
+	if got := gjson.GetBytes(resp.Payload, "candidates.0.content.parts.0.text").String(); got != "ok" {
+		t.Fatalf("response text = %q, want ok; payload = %s", got, resp.Payload)
+	}
+	upstream.assertStaticBearer(t, "")
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+	if got := upstream.projectSeen[0]; got != "" {
+		t.Fatalf("project = %q, want none for a static token gateway", got)
+	}
+}
+
+func TestAntigravityExecuteStreamUsesStaticToken(t *testing.T) {
+	upstream := newAntigravityStaticTokenUpstream(t)
+	req, opts := antigravityStaticTestRequest()
+	opts.Stream = true
+
+	result, errExecute := NewAntigravityExecutor(&config.Config{}).ExecuteStream(upstream.context(), upstream.auth(), req, opts)
+	if errExecute != nil {
+		t.Fatalf("ExecuteStream() error = %v", errExecute)
+	}
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error: %v", chunk.Err)
+		}
+	}
+	upstream.assertStaticBearer(t, antigravityStreamPath)
+}
+
+func TestAntigravityCountTokensUsesStaticToken(t *testing.T) {
+	upstream := newAntigravityStaticTokenUpstream(t)
+	req, opts := antigravityStaticTestRequest()
+
+	resp, errCount := NewAntigravityExecutor(&config.Config{}).CountTokens(upstream.context(), upstream.auth(), req, opts)
+	if errCount != nil {
+		t.Fatalf("CountTokens() error = %v", errCount)
+	}
+	if got := gjson.GetBytes(resp.Payload, "totalTokens").Int(); got != 5 {
+		t.Fatalf("totalTokens = %d, want 5; payload = %s", got, resp.Payload)
+	}
+	upstream.assertStaticBearer(t, antigravityCountTokensPath)
+}
+
+func TestAntigravityStaticTokenAuthIsNotRefreshed(t *testing.T) {
+	upstream := newAntigravityStaticTokenUpstream(t)
+	executor := NewAntigravityExecutor(&config.Config{})
+	auth := upstream.auth()
+
+	refreshed, errRefresh := executor.Refresh(upstream.context(), auth)
+	if errRefresh != nil {
+		t.Fatalf("Refresh() error = %v", errRefresh)
+	}
+	if refreshed != auth {
+		t.Fatal("Refresh() should return the static token auth unchanged")
+	}
+	if executor.ShouldPrepareRequestAuth(auth) {
+		t.Fatal("ShouldPrepareRequestAuth() = true, want false for a static token auth")
+	}
+	if calls := upstream.oauthCalls.Load(); calls != 0 {
+		t.Fatalf("OAuth token endpoint calls = %d, want 0", calls)
+	}
+}
+
+func TestAntigravityExecuteUsesStaticTokenFromAuthFile(t *testing.T) {
+	upstream := newAntigravityStaticTokenUpstream(t)
+	authDir := t.TempDir()
+	authFile := `{"type":"antigravity","email":"gateway@example.com","base_url":"` + upstream.server.URL + `","api_key":"static-gateway-key"}`
+	if errWrite := os.WriteFile(filepath.Join(authDir, "antigravity-gateway.json"), []byte(authFile), 0o600); errWrite != nil {
+		t.Fatalf("write auth file: %v", errWrite)
+	}
+	auths, errSynth := synthesizer.NewFileSynthesizer().Synthesize(&synthesizer.SynthesisContext{
+		Config:      &config.Config{},
+		AuthDir:     authDir,
+		Now:         time.Now(),
+		IDGenerator: synthesizer.NewStableIDGenerator(),
+	})
+	if errSynth != nil || len(auths) != 1 {
+		t.Fatalf("Synthesize() = %d auths, error %v; want one auth", len(auths), errSynth)
+	}
+	req, opts := antigravityStaticTestRequest()
+
+	if _, errExecute := NewAntigravityExecutor(&config.Config{}).Execute(upstream.context(), auths[0], req, opts); errExecute != nil {
+		t.Fatalf("Execute() error = %v", errExecute)
+	}
+	upstream.assertStaticBearer(t, "")
+}
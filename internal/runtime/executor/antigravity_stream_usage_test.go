@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// antigravityThinkingStream reports thoughtsTokenCount and the cache breakdown on the
+// intermediate chunks only; the terminal chunk carries the plain totals.
+var antigravityThinkingStream = []string{
+	`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"Let me think.","thought":true}]}}],"usageMetadata":{"promptTokenCount":120,"candidatesTokenCount":0,"thoughtsTokenCount":48,"totalTokenCount":168,"cacheTokensDetails":[{"modality":"TEXT","tokenCount":64}]},"responseId":"resp-1"},"traceId":"trace-thinking-usage"}`,
+	`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello"}]}}],"usageMetadata":{"promptTokenCount":120,"candidatesTokenCount":1,"thoughtsTokenCount":48,"totalTokenCount":169,"cacheTokensDetails":[{"modality":"TEXT","tokenCount":64}]},"responseId":"resp-1"},"traceId":"trace-thinking-usage"}`,
+	`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"!"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":120,"candidatesTokenCount":2,"totalTokenCount":170},"responseId":"resp-1"},"traceId":"trace-thinking-usage"}`,
+}
+
+func streamAntigravityThinkingFixture(t *testing.T, model string, format sdktranslator.Format, payload string) []byte {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range antigravityThinkingStream {
+			_, _ = w.Write([]byte("data: " + event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+	withAntigravityBaseURLs(t, server.URL)
+
+	auth := &cliproxyauth.Auth{
+		ID:       fmt.Sprintf("antigravity-stream-usage-%d", time.Now().UnixNano()),
+		Provider: "antigravity",
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	result, errExecute := NewAntigravityExecutor(&config.Config{}).ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   model,
+		Payload: []byte(payload),
+	}, cliproxyexecutor.Options{SourceFormat: format, Stream: true, OriginalRequest: []byte(payload)})
+	if errExecute != nil {
+		t.Fatalf("ExecuteStream() error = %v", errExecute)
+	}
+	var out bytes.Buffer
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error: %v", chunk.Err)
+		}
+		out.Write(chunk.Payload)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+func TestAntigravityStreamReportsReasoningTokensToOpenAI(t *testing.T) {
+	out := streamAntigravityThinkingFixture(t, "gemini-2.5-pro", sdktranslator.FormatOpenAI,
+		`{"model":"gemini-2.5-pro","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+
+	var usage gjson.Result
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:")))
+		if node := gjson.GetBytes(line, "usage"); node.Exists() && gjson.GetBytes(line, "choices.0.finish_reason").String() != "" {
+			usage = node
+		}
+	}
+	if !usage.Exists() {
+		t.Fatalf("no usage on the finish chunk in stream:\n%s", out)
+	}
+	if got := usage.Get("completion_tokens_details.reasoning_tokens").Int(); got != 48 {
+		t.Fatalf("reasoning_tokens = %d, want 48; usage = %s", got, usage.Raw)
+	}
+	if got := usage.Get("prompt_tokens_details.cached_tokens").Int(); got != 64 {
+		t.Fatalf("cached_tokens = %d, want 64; usage = %s", got, usage.Raw)
+	}
+}
+
+func TestAntigravityStreamReportsThinkingTokensToClaude(t *testing.T) {
+	out := streamAntigravityThinkingFixture(t, "gemini-2.5-pro", sdktranslator.FormatClaude,
+		`{"model":"gemini-2.5-pro","stream":true,"max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`)
+
+	var usage gjson.Result
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if gjson.GetBytes(data, "type").String() == "message_delta" {
+			usage = gjson.GetBytes(data, "usage")
+		}
+	}
+	if !usage.Exists() {
+		t.Fatalf("no message_delta usage in stream:\n%s", out)
+	}
+	// Output tokens include the thinking tokens; cache reads are split from the input.
+	if got := usage.Get("output_tokens").Int(); got != 50 {
+		t.Fatalf("output_tokens = %d, want 50; usage = %s", got, usage.Raw)
+	}
+	if got := usage.Get("cache_read_input_tokens").Int(); got != 64 {
+		t.Fatalf("cache_read_input_tokens = %d, want 64; usage = %s", got, usage.Raw)
+	}
+	if got := usage.Get("input_tokens").Int(); got != 56 {
+		t.Fatalf("input_tokens = %d, want 56; usage = %s", got, usage.Raw)
+	}
+}
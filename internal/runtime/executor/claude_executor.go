@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -47,6 +48,24 @@ type ClaudeExecutor struct {
 // Previously "proxy_" was used but this is a detectable fingerprint difference.
 const claudeToolPrefix = ""
 
+// claudeLegacyToolPrefix is the prefix older releases added to OAuth tool names.
+// Histories recorded with it are rewritten to the current prefix when the bare
+// name matches a declared tool.
+const claudeLegacyToolPrefix = "proxy_"
+
+// claudeToolPrefixForAuth resolves the OAuth tool name prefix for auth. The
+// per-auth tool_prefix setting overrides the default, and an empty result
+// disables prefixing; only legacy-prefixed history entries are still rewritten.
+func claudeToolPrefixForAuth(auth *cliproxyauth.Auth) string {
+	if auth.ToolPrefixDisabled() {
+		return ""
+	}
+	if prefix, ok := auth.ToolPrefix(); ok {
+		return prefix
+	}
+	return claudeToolPrefix
+}
+
 func shouldSanitizeClaudeMessagesForUpstream(baseModel string) bool {
 	return sigcompat.SignatureProviderFromModelName(baseModel) == sigcompat.SignatureProviderClaude
 }
@@ -268,7 +287,6 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 		return resp, statusErr{code: http.StatusNotImplemented, msg: "/responses/compact not supported"}
 	}
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
-	upstreamModel := e.upstreamModel(baseModel)
 
 	apiKey, baseURL := claudeCreds(auth)
 	if baseURL == "" {
@@ -282,67 +300,21 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	to := sdktranslator.FromString("claude")
 	// Use streaming translation to preserve function calling, except for claude.
 	stream := from != to
-	originalPayloadSource := req.Payload
-	if len(opts.OriginalRequest) > 0 {
-		originalPayloadSource = opts.OriginalRequest
-	}
-	originalPayload := originalPayloadSource
-	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayload, stream)
-	body := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, stream)
-	body = helps.SetStringIfDifferent(body, "model", upstreamModel)
-
-	body, err = thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	body, extraBetas, err := e.prepareRequestBody(ctx, auth, req, opts, apiKey, stream)
 	if err != nil {
 		return resp, err
 	}
-	if rebuildMidSystemMessageEnabled(e.cfg, auth) {
-		body = rebuildMidSystemMessagesToTopLevel(body)
-	}
-
-	// Apply cloaking (system prompt injection, fake user ID, sensitive word obfuscation)
-	// based on client type and configuration.
-	body, err = applyCloaking(ctx, e.cfg, auth, body, baseModel, apiKey)
-	if err != nil {
-		return resp, err
-	}
-
-	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
-	requestPath := helps.PayloadRequestPath(opts)
-	body = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
-	body = ensureModelMaxTokens(body, baseModel)
-
-	// Disable thinking if tool_choice forces tool use (Anthropic API constraint)
-	body = disableThinkingIfToolChoiceForced(body)
-	body = normalizeClaudeSamplingForUpstream(body)
-	// Claude OAuth (and this executor's redact-thinking beta) returns signature-only
-	// thinking blocks unless display is set to "summarized".
-	body = ensureClaudeThinkingDisplay(body)
-
-	// Auto-inject cache_control if missing (optimization for ClawdBot/clients without caching support)
-	if countCacheControls(body) == 0 {
-		body = ensureCacheControl(body)
-	}
-
-	// Enforce Anthropic's cache_control block limit (max 4 breakpoints per request).
-	// Cloaking and ensureCacheControl may push the total over 4 when the client
-	// already sends multiple cache_control blocks.
-	body = enforceCacheControlLimit(body, 4)
-
-	// Normalize TTL values to prevent ordering violations under prompt-caching-scope-2026-01-05.
-	// A 1h-TTL block must not appear after a 5m-TTL block in evaluation order (tools→system→messages).
-	body = normalizeCacheControlTTL(body)
-
-	// Extract betas from body and convert to header
-	var extraBetas []string
-	extraBetas, body = extractAndRemoveBetas(body)
 	bodyForTranslation := body
 	bodyForUpstream := body
 	oauthToken := isClaudeOAuthToken(apiKey)
 	var oauthToolNamesReverseMap map[string]string
 	if oauthToken {
-		bodyForUpstream, oauthToolNamesReverseMap = prepareClaudeOAuthToolNamesForUpstream(bodyForUpstream, claudeToolPrefix, auth.ToolPrefixDisabled())
+		bodyForUpstream, oauthToolNamesReverseMap = prepareClaudeOAuthToolNamesForUpstream(bodyForUpstream, claudeToolPrefixForAuth(auth))
 	}
 	bodyForUpstream = sanitizeClaudeMessagesForClaudeUpstreamWithDebug(ctx, bodyForUpstream, baseModel)
+	if systemAsStringEnabled(e.cfg, auth) {
+		bodyForUpstream, _ = flattenClaudeSystem(bodyForUpstream)
+	}
 	// Enable cch signing by default for OAuth tokens (not just experimental flag).
 	// Claude Code always computes cch; missing or invalid cch is a detectable fingerprint.
 	if oauthToken || experimentalCCHSigningEnabled(e.cfg, auth) {
@@ -364,7 +336,7 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 		authLabel = auth.Label
 		authType, authValue = auth.AccountInfo()
 	}
-	helps.RecordAPIRequest(ctx, e.cfg, helps.UpstreamRequestLog{
+	requestLog := helps.UpstreamRequestLog{
 		URL:       url,
 		Method:    http.MethodPost,
 		Headers:   httpReq.Header.Clone(),
@@ -374,11 +346,12 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 		AuthLabel: authLabel,
 		AuthType:  authType,
 		AuthValue: authValue,
-	})
+	}
+	helps.RecordAPIRequest(ctx, e.cfg, requestLog)
 
-	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, 0)
+	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 	httpClient = reporter.TrackHTTPClient(httpClient)
-	httpResp, err := httpClient.Do(httpReq)
+	httpResp, err := e.doClaudeRequest(ctx, httpClient, httpReq, bodyForUpstream, requestLog)
 	if err != nil {
 		helps.RecordAPIResponseError(ctx, e.cfg, err)
 		return resp, err
@@ -404,7 +377,7 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 		}
 		helps.AppendAPIResponseChunk(ctx, e.cfg, b)
 		helps.LogWithRequestID(ctx).Debugf("request error, error status: %d, error message: %s", httpResp.StatusCode, helps.SummarizeErrorBody(httpResp.Header.Get("Content-Type"), b))
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		err = claudeStatusErr(httpResp.StatusCode, httpResp.Header, b)
 		if errClose := errBody.Close(); errClose != nil {
 			log.Errorf("response body close error: %v", errClose)
 		}
@@ -423,13 +396,14 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 			log.Errorf("response body close error: %v", errClose)
 		}
 	}()
-	data, err := io.ReadAll(decodedBody)
+	data, err := readUpstreamResponseBody(e.cfg, decodedBody)
 	if err != nil {
 		helps.RecordAPIResponseError(ctx, e.cfg, err)
 		return resp, err
 	}
 	helps.AppendAPIResponseChunk(ctx, e.cfg, data)
 	if stream {
+		data = helps.NormalizeSSE(data)
 		if errValidate := validateClaudeStreamingResponse(data); errValidate != nil {
 			helps.RecordAPIResponseError(ctx, e.cfg, errValidate)
 			return resp, errValidate
@@ -443,8 +417,9 @@ func (e *ClaudeExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, r
 	} else {
 		reporter.Publish(ctx, helps.ParseClaudeUsage(data))
 	}
-	data = restoreClaudeOAuthToolNamesFromResponse(data, claudeToolPrefix, auth.ToolPrefixDisabled(), oauthToolNamesReverseMap)
+	data = restoreClaudeOAuthToolNamesFromResponse(data, claudeToolPrefixForAuth(auth), oauthToolNamesReverseMap)
 	data = e.restoreResponseModel(data, req.Model)
+	data = resolveClaudeCloakSettings(e.cfg, auth).ResponseWordMatcher(getClientUserAgent(ctx)).RestoreSensitiveWords(data)
 	var param any
 	out := sdktranslator.TranslateNonStream(
 		ctx,
@@ -465,7 +440,6 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		return nil, statusErr{code: http.StatusNotImplemented, msg: "/responses/compact not supported"}
 	}
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
-	upstreamModel := e.upstreamModel(baseModel)
 
 	apiKey, baseURL := claudeCreds(auth)
 	if baseURL == "" {
@@ -474,67 +448,23 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 
 	reporter := helps.NewExecutorUsageReporter(ctx, e, baseModel, auth)
 	defer reporter.TrackFailure(ctx, &err)
-	from := opts.SourceFormat
 	responseFormat := cliproxyexecutor.ResponseFormatOrSource(opts)
 	to := sdktranslator.FromString("claude")
-	originalPayloadSource := req.Payload
-	if len(opts.OriginalRequest) > 0 {
-		originalPayloadSource = opts.OriginalRequest
-	}
-	originalPayload := originalPayloadSource
-	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayload, true)
-	body := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, true)
-	body = helps.SetStringIfDifferent(body, "model", upstreamModel)
-
-	body, err = thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	body, extraBetas, err := e.prepareRequestBody(ctx, auth, req, opts, apiKey, true)
 	if err != nil {
 		return nil, err
 	}
-	if rebuildMidSystemMessageEnabled(e.cfg, auth) {
-		body = rebuildMidSystemMessagesToTopLevel(body)
-	}
-
-	// Apply cloaking (system prompt injection, fake user ID, sensitive word obfuscation)
-	// based on client type and configuration.
-	body, err = applyCloaking(ctx, e.cfg, auth, body, baseModel, apiKey)
-	if err != nil {
-		return nil, err
-	}
-
-	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
-	requestPath := helps.PayloadRequestPath(opts)
-	body = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
-	body = ensureModelMaxTokens(body, baseModel)
-
-	// Disable thinking if tool_choice forces tool use (Anthropic API constraint)
-	body = disableThinkingIfToolChoiceForced(body)
-	body = normalizeClaudeSamplingForUpstream(body)
-	// Claude OAuth (and this executor's redact-thinking beta) returns signature-only
-	// thinking blocks unless display is set to "summarized".
-	body = ensureClaudeThinkingDisplay(body)
-
-	// Auto-inject cache_control if missing (optimization for ClawdBot/clients without caching support)
-	if countCacheControls(body) == 0 {
-		body = ensureCacheControl(body)
-	}
-
-	// Enforce Anthropic's cache_control block limit (max 4 breakpoints per request).
-	body = enforceCacheControlLimit(body, 4)
-
-	// Normalize TTL values to prevent ordering violations under prompt-caching-scope-2026-01-05.
-	body = normalizeCacheControlTTL(body)
-
-	// Extract betas from body and convert to header
-	var extraBetas []string
-	extraBetas, body = extractAndRemoveBetas(body)
 	bodyForTranslation := body
 	bodyForUpstream := body
 	oauthToken := isClaudeOAuthToken(apiKey)
 	var oauthToolNamesReverseMap map[string]string
 	if oauthToken {
-		bodyForUpstream, oauthToolNamesReverseMap = prepareClaudeOAuthToolNamesForUpstream(bodyForUpstream, claudeToolPrefix, auth.ToolPrefixDisabled())
+		bodyForUpstream, oauthToolNamesReverseMap = prepareClaudeOAuthToolNamesForUpstream(bodyForUpstream, claudeToolPrefixForAuth(auth))
 	}
 	bodyForUpstream = sanitizeClaudeMessagesForClaudeUpstreamWithDebug(ctx, bodyForUpstream, baseModel)
+	if systemAsStringEnabled(e.cfg, auth) {
+		bodyForUpstream, _ = flattenClaudeSystem(bodyForUpstream)
+	}
 	// Enable cch signing by default for OAuth tokens (not just experimental flag).
 	if oauthToken || experimentalCCHSigningEnabled(e.cfg, auth) {
 		bodyForUpstream = signAnthropicMessagesBody(bodyForUpstream)
@@ -555,7 +485,7 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		authLabel = auth.Label
 		authType, authValue = auth.AccountInfo()
 	}
-	helps.RecordAPIRequest(ctx, e.cfg, helps.UpstreamRequestLog{
+	requestLog := helps.UpstreamRequestLog{
 		URL:       url,
 		Method:    http.MethodPost,
 		Headers:   httpReq.Header.Clone(),
@@ -565,11 +495,12 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		AuthLabel: authLabel,
 		AuthType:  authType,
 		AuthValue: authValue,
-	})
+	}
+	helps.RecordAPIRequest(ctx, e.cfg, requestLog)
 
 	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, 0)
 	httpClient = reporter.TrackHTTPClient(httpClient)
-	httpResp, err := httpClient.Do(httpReq)
+	httpResp, err := e.doClaudeRequest(ctx, httpClient, httpReq, bodyForUpstream, requestLog)
 	if err != nil {
 		helps.RecordAPIResponseError(ctx, e.cfg, err)
 		return nil, err
@@ -598,7 +529,7 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		if errClose := errBody.Close(); errClose != nil {
 			log.Errorf("response body close error: %v", errClose)
 		}
-		err = statusErr{code: httpResp.StatusCode, msg: string(b)}
+		err = claudeStatusErr(httpResp.StatusCode, httpResp.Header, b)
 		return nil, err
 	}
 	decodedBody, err := decodeResponseBody(httpResp.Body, httpResp.Header.Get("Content-Encoding"))
@@ -609,6 +540,7 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		}
 		return nil, err
 	}
+	wordMatcher := resolveClaudeCloakSettings(e.cfg, auth).ResponseWordMatcher(getClientUserAgent(ctx))
 	out := make(chan cliproxyexecutor.StreamChunk)
 	go func() {
 		defer close(out)
@@ -620,36 +552,42 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 
 		// If the response target is Claude, directly forward complete SSE events without translation.
 		if responseFormat == to {
-			scanner := bufio.NewScanner(decodedBody)
+			scanner := bufio.NewScanner(helps.NewSSEReader(decodedBody))
 			scanner.Buffer(nil, 52_428_800) // 50MB
 			var event bytes.Buffer
+			var streamUsage helps.StreamUsageBuffer
+			forwarding := true
 			flushEvent := func() bool {
 				if event.Len() == 0 {
 					return true
 				}
 				cloned := bytes.Clone(event.Bytes())
 				event.Reset()
-				select {
-				case out <- cliproxyexecutor.StreamChunk{Payload: cloned}:
-					return true
-				case <-ctx.Done():
-					return false
-				}
+				return helps.SendStreamChunks(ctx, out, [][]byte{cloned})
 			}
 			for scanner.Scan() {
 				line := scanner.Bytes()
 				helps.AppendAPIResponseChunk(ctx, e.cfg, line)
+				streamUsage.ObserveClaudeStream(line)
+				if !forwarding {
+					// The client is gone; keep draining buffered events for usage only.
+					continue
+				}
 				if detail, ok := helps.ParseClaudeStreamUsage(line); ok {
 					reporter.Publish(ctx, detail)
 				}
-				line = restoreClaudeOAuthToolNamesFromStreamLine(line, claudeToolPrefix, auth.ToolPrefixDisabled(), oauthToolNamesReverseMap)
+				line = restoreClaudeOAuthToolNamesFromStreamLine(line, claudeToolPrefixForAuth(auth), oauthToolNamesReverseMap)
 				line = e.restoreResponseModel(line, req.Model)
+				line = wordMatcher.RestoreSensitiveWords(line)
 				event.Write(line)
 				event.WriteByte('\n')
-				if len(bytes.TrimSpace(line)) == 0 && !flushEvent() {
-					return
+				if len(bytes.TrimSpace(line)) == 0 {
+					forwarding = flushEvent()
 				}
 			}
+			if reporter.PublishClientCancelled(ctx, &streamUsage) {
+				return
+			}
 			if !flushEvent() {
 				return
 			}
@@ -665,17 +603,25 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 		}
 
 		// For other formats, use translation
-		scanner := bufio.NewScanner(decodedBody)
+		scanner := bufio.NewScanner(helps.NewSSEReader(decodedBody))
 		scanner.Buffer(nil, 52_428_800) // 50MB
 		var param any
+		var streamUsage helps.StreamUsageBuffer
+		forwarding := true
 		for scanner.Scan() {
 			line := scanner.Bytes()
 			helps.AppendAPIResponseChunk(ctx, e.cfg, line)
+			streamUsage.ObserveClaudeStream(line)
+			if !forwarding {
+				// The client is gone; keep draining buffered events for usage only.
+				continue
+			}
 			if detail, ok := helps.ParseClaudeStreamUsage(line); ok {
 				reporter.Publish(ctx, detail)
 			}
-			line = restoreClaudeOAuthToolNamesFromStreamLine(line, claudeToolPrefix, auth.ToolPrefixDisabled(), oauthToolNamesReverseMap)
+			line = restoreClaudeOAuthToolNamesFromStreamLine(line, claudeToolPrefixForAuth(auth), oauthToolNamesReverseMap)
 			line = e.restoreResponseModel(line, req.Model)
+			line = wordMatcher.RestoreSensitiveWords(line)
 			chunks := sdktranslator.TranslateStream(
 				ctx,
 				to,
@@ -686,13 +632,10 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 				bytes.Clone(line),
 				&param,
 			)
-			for i := range chunks {
-				select {
-				case out <- cliproxyexecutor.StreamChunk{Payload: chunks[i]}:
-				case <-ctx.Done():
-					return
-				}
-			}
+			forwarding = helps.SendStreamChunks(ctx, out, chunks)
+		}
+		if reporter.PublishClientCancelled(ctx, &streamUsage) {
+			return
 		}
 		if errScan := scanner.Err(); errScan != nil {
 			helps.RecordAPIResponseError(ctx, e.cfg, errScan)
@@ -706,6 +649,35 @@ func (e *ClaudeExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.A
 	return &cliproxyexecutor.StreamResult{Headers: httpResp.Header.Clone(), Chunks: out}, nil
 }
 
+const (
+	// claudeStatusOverloaded is the non-standard status Anthropic answers with while
+	// the API is overloaded.
+	claudeStatusOverloaded = 529
+	// claudeOverloadedErrorType is the error type of overloaded responses.
+	claudeOverloadedErrorType = "overloaded_error"
+)
+
+// claudeStatusErr builds the error of a failed upstream response. Overloaded responses
+// are reported as 529 whatever status they came with, so the auth manager and handlers
+// treat them as transient, and keep the upstream Retry-After hint.
+func claudeStatusErr(code int, header http.Header, body []byte) statusErr {
+	err := statusErr{code: code, msg: string(body)}
+	if code != claudeStatusOverloaded && gjson.GetBytes(body, "error.type").String() != claudeOverloadedErrorType {
+		return err
+	}
+	err.code = claudeStatusOverloaded
+	if raw := strings.TrimSpace(header.Get("Retry-After")); raw != "" {
+		if seconds, errParse := strconv.Atoi(raw); errParse == nil && seconds >= 0 {
+			retryAfter := time.Duration(seconds) * time.Second
+			err.retryAfter = &retryAfter
+		} else if when, errParse := http.ParseTime(raw); errParse == nil {
+			retryAfter := max(time.Until(when), 0)
+			err.retryAfter = &retryAfter
+		}
+	}
+	return err
+}
+
 func validateClaudeStreamingResponse(data []byte) error {
 	scanner := bufio.NewScanner(bytes.NewReader(data))
 	scanner.Buffer(nil, 52_428_800)
@@ -764,39 +736,112 @@ func validateClaudeStreamingResponse(data []byte) error {
 	return nil
 }
 
-func (e *ClaudeExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+// prepareRequestBody translates req into a Claude Messages body and applies
+// the request shaping shared by generation and count_tokens requests: thinking,
+// cloaking, payload rules, forced tool choice and cache_control limits. It
+// returns the body with betas moved out into extraBetas.
+func (e *ClaudeExecutor) prepareRequestBody(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, apiKey string, stream bool) ([]byte, []string, error) {
 	baseModel := thinking.ParseSuffix(req.Model).ModelName
-	upstreamModel := e.upstreamModel(baseModel)
-
-	apiKey, baseURL := claudeCreds(auth)
-	if baseURL == "" {
-		baseURL = "https://api.anthropic.com"
-	}
-
 	from := opts.SourceFormat
-	responseFormat := cliproxyexecutor.ResponseFormatOrSource(opts)
 	to := sdktranslator.FromString("claude")
-	// Use streaming translation to preserve function calling, except for claude.
-	stream := from != to
-	body := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, stream)
-	body = helps.SetStringIfDifferent(body, "model", upstreamModel)
+	originalPayloadSource := req.Payload
+	if len(opts.OriginalRequest) > 0 {
+		originalPayloadSource = opts.OriginalRequest
+	}
+	originalTranslated := sdktranslator.TranslateRequest(from, to, baseModel, originalPayloadSource, stream)
+	body := sdktranslator.TranslateRequestWithContext(ctx, from, to, baseModel, req.Payload, stream)
+	body = helps.SetStringIfDifferent(body, "model", e.upstreamModel(baseModel))
+
+	body, err := thinking.ApplyThinkingWithTrace(ctx, body, req.Model, from.String(), to.String(), e.Identifier())
+	if err != nil {
+		return nil, nil, err
+	}
 	if rebuildMidSystemMessageEnabled(e.cfg, auth) {
 		body = rebuildMidSystemMessagesToTopLevel(body)
 	}
 
-	if !strings.HasPrefix(baseModel, "claude-3-5-haiku") {
-		body = checkSystemInstructions(body)
+	// Apply cloaking (system prompt injection, fake user ID, sensitive word obfuscation)
+	// based on client type and configuration.
+	body, err = applyCloaking(ctx, e.cfg, auth, body, baseModel, apiKey)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Keep count_tokens requests compatible with Anthropic cache-control constraints too.
+	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
+	requestPath := helps.PayloadRequestPath(opts)
+	body = helps.ApplyPayloadConfigWithTrace(ctx, e.cfg, e.Identifier(), baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
+	body = ensureModelMaxTokens(body, baseModel)
+
+	// Disable thinking if tool_choice forces tool use (Anthropic API constraint)
+	body = disableThinkingIfToolChoiceForced(body)
+	body = normalizeClaudeSamplingForUpstream(body)
+	// Claude OAuth (and this executor's redact-thinking beta) returns signature-only
+	// thinking blocks unless display is set to "summarized".
+	body = ensureClaudeThinkingDisplay(body)
+
+	// Auto-inject cache_control if missing (optimization for ClawdBot/clients without caching support)
+	if countCacheControls(body) == 0 {
+		body = ensureCacheControl(body)
+	}
+
+	// Enforce Anthropic's cache_control block limit (max 4 breakpoints per request).
+	// Cloaking and ensureCacheControl may push the total over 4 when the client
+	// already sends multiple cache_control blocks.
 	body = enforceCacheControlLimit(body, 4)
+
+	// Normalize TTL values to prevent ordering violations under prompt-caching-scope-2026-01-05.
+	// A 1h-TTL block must not appear after a 5m-TTL block in evaluation order (tools→system→messages).
 	body = normalizeCacheControlTTL(body)
 
-	// Extract betas from body and convert to header (for count_tokens too)
-	var extraBetas []string
-	extraBetas, body = extractAndRemoveBetas(body)
+	// Extract betas from body and convert to header
+	extraBetas, body := extractAndRemoveBetas(body)
+	return body, extraBetas, nil
+}
+
+// claudeCountTokensUnsupportedFields are generation-only Messages fields that
+// the count_tokens endpoint rejects.
+var claudeCountTokensUnsupportedFields = []string{
+	"max_tokens",
+	"metadata",
+	"stream",
+	"temperature",
+	"top_p",
+	"top_k",
+	"stop_sequences",
+	"service_tier",
+}
+
+// stripClaudeCountTokensFields removes generation-only fields from a shaped
+// Messages body before it is sent to count_tokens. None of them affect the count.
+func stripClaudeCountTokensFields(body []byte) []byte {
+	for _, field := range claudeCountTokensUnsupportedFields {
+		if gjson.GetBytes(body, field).Exists() {
+			body, _ = sjson.DeleteBytes(body, field)
+		}
+	}
+	return body
+}
+
+func (e *ClaudeExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+
+	apiKey, baseURL := claudeCreds(auth)
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+
+	from := opts.SourceFormat
+	responseFormat := cliproxyexecutor.ResponseFormatOrSource(opts)
+	to := sdktranslator.FromString("claude")
+	// Shape the body exactly like a generation request so the count reflects what
+	// Execute would send, then drop the fields count_tokens does not accept.
+	body, extraBetas, err := e.prepareRequestBody(ctx, auth, req, opts, apiKey, from != to)
+	if err != nil {
+		return cliproxyexecutor.Response{}, err
+	}
+	body = stripClaudeCountTokensFields(body)
 	if isClaudeOAuthToken(apiKey) {
-		body, _ = prepareClaudeOAuthToolNamesForUpstream(body, claudeToolPrefix, auth.ToolPrefixDisabled())
+		body, _ = prepareClaudeOAuthToolNamesForUpstream(body, claudeToolPrefixForAuth(auth))
 	}
 	body = sanitizeClaudeMessagesForClaudeUpstreamWithDebug(ctx, body, baseModel)
 
@@ -826,7 +871,7 @@ func (e *ClaudeExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Aut
 		AuthValue: authValue,
 	})
 
-	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, 0)
+	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 	resp, err := httpClient.Do(httpReq)
 	if err != nil {
 		helps.RecordAPIResponseError(ctx, e.cfg, err)
@@ -1233,11 +1278,16 @@ func applyClaudeHeaders(r *http.Request, auth *cliproxyauth.Auth, apiKey string,
 	} else {
 		helps.ApplyClaudeLegacyDeviceHeaders(r, incomingHeaders, cfg)
 	}
+	// OAuth requests are cloaked as Claude Code, which never sends a request ID.
+	if !isClaudeOAuthToken(apiKey) {
+		helps.ApplyRequestIDHeader(r)
+	}
 	var attrs map[string]string
 	if auth != nil {
 		attrs = auth.Attributes
 	}
 	util.ApplyCustomHeadersFromAttrs(r, attrs)
+	helps.ApplyForwardedClientHeaders(r.Context(), r.Header, attrs)
 	// Re-enforce Accept-Encoding: identity after ApplyCustomHeadersFromAttrs, which
 	// may override it with a user-configured value.  Compressed SSE breaks the line
 	// scanner regardless of user preference, so this is non-negotiable for streams.
@@ -1263,10 +1313,6 @@ func claudeCreds(a *cliproxyauth.Auth) (apiKey, baseURL string) {
 	return
 }
 
-func checkSystemInstructions(payload []byte) []byte {
-	return checkSystemInstructionsWithSigningMode(payload, false, false, false, "2.1.63", "", "")
-}
-
 func rebuildMidSystemMessagesToTopLevel(payload []byte) []byte {
 	messages := gjson.GetBytes(payload, "messages")
 	if !messages.IsArray() {
@@ -1358,31 +1404,41 @@ func isClaudeOAuthToken(apiKey string) bool {
 
 // prepareClaudeOAuthToolNamesForUpstream applies the Claude OAuth tool-name
 // transforms in the same order across request paths. Remap runs before prefixing
-// so any future non-empty prefix still composes correctly with the per-request
-// reverse map.
-func prepareClaudeOAuthToolNamesForUpstream(body []byte, prefix string, prefixDisabled bool) ([]byte, map[string]string) {
+// so a non-empty prefix still composes correctly with the per-request reverse map.
+// An empty prefix only rewrites legacy-prefixed history entries. Strict tool flags are dropped as well
+// because OAuth traffic rejects them; API-key requests keep them.
+func prepareClaudeOAuthToolNamesForUpstream(body []byte, prefix string) ([]byte, map[string]string) {
 	body, reverseMap := remapOAuthToolNames(body)
-	if !prefixDisabled {
-		body = applyClaudeToolPrefix(body, prefix)
-	}
+	body = applyClaudeToolPrefix(body, prefix)
+	body = stripClaudeToolStrict(body)
 	return body, reverseMap
 }
 
+// stripClaudeToolStrict removes tools[].strict from a Claude Messages body.
+func stripClaudeToolStrict(body []byte) []byte {
+	tools := gjson.GetBytes(body, "tools")
+	if !tools.IsArray() {
+		return body
+	}
+	for i, tool := range tools.Array() {
+		if tool.Get("strict").Exists() {
+			body, _ = sjson.DeleteBytes(body, fmt.Sprintf("tools.%d.strict", i))
+		}
+	}
+	return body
+}
+
 // restoreClaudeOAuthToolNamesFromResponse undoes the Claude OAuth tool-name
 // transforms for non-stream responses in reverse order.
-func restoreClaudeOAuthToolNamesFromResponse(body []byte, prefix string, prefixDisabled bool, reverseMap map[string]string) []byte {
-	if !prefixDisabled {
-		body = stripClaudeToolPrefixFromResponse(body, prefix)
-	}
+func restoreClaudeOAuthToolNamesFromResponse(body []byte, prefix string, reverseMap map[string]string) []byte {
+	body = stripClaudeToolPrefixFromResponse(body, prefix)
 	return reverseRemapOAuthToolNames(body, reverseMap)
 }
 
 // restoreClaudeOAuthToolNamesFromStreamLine undoes the Claude OAuth tool-name
 // transforms for SSE lines in reverse order.
-func restoreClaudeOAuthToolNamesFromStreamLine(line []byte, prefix string, prefixDisabled bool, reverseMap map[string]string) []byte {
-	if !prefixDisabled {
-		line = stripClaudeToolPrefixFromStreamLine(line, prefix)
-	}
+func restoreClaudeOAuthToolNamesFromStreamLine(line []byte, prefix string, reverseMap map[string]string) []byte {
+	line = stripClaudeToolPrefixFromStreamLine(line, prefix)
 	return reverseRemapOAuthToolNamesFromStreamLine(line, reverseMap)
 }
 
@@ -1624,14 +1680,14 @@ func reverseRemapOAuthToolNamesFromStreamLine(line []byte, reverseMap map[string
 	return updated
 }
 
+// applyClaudeToolPrefix adds prefix to the declared and historical tool names of
+// a Claude Messages body. An empty prefix adds nothing, but history entries
+// recorded with the legacy prefix are still rewritten to their declared names.
 func applyClaudeToolPrefix(body []byte, prefix string) []byte {
-	if prefix == "" {
-		return body
-	}
-
 	// Collect built-in tool names from the authoritative fallback seed list and
 	// augment it with any typed built-ins present in the current request body.
 	builtinTools := helps.AugmentClaudeBuiltinToolRegistry(body, nil)
+	declaredTools := make(map[string]bool)
 
 	if tools := gjson.GetBytes(body, "tools"); tools.Exists() && tools.IsArray() {
 		tools.ForEach(func(index, tool gjson.Result) bool {
@@ -1644,6 +1700,9 @@ func applyClaudeToolPrefix(body []byte, prefix string) []byte {
 				return true
 			}
 			name := tool.Get("name").String()
+			if name != "" {
+				declaredTools[name] = true
+			}
 			if name == "" || strings.HasPrefix(name, prefix) {
 				return true
 			}
@@ -1660,6 +1719,21 @@ func applyClaudeToolPrefix(body []byte, prefix string) []byte {
 		}
 	}
 
+	// prefixHistoryName returns the upstream name for a tool referenced in the
+	// message history. Entries recorded with the legacy prefix are matched
+	// leniently against the declared tools before the current prefix is applied.
+	prefixHistoryName := func(name string) string {
+		if prefix != claudeLegacyToolPrefix && strings.HasPrefix(name, claudeLegacyToolPrefix) {
+			if bare := strings.TrimPrefix(name, claudeLegacyToolPrefix); declaredTools[bare] {
+				name = bare
+			}
+		}
+		if name == "" || strings.HasPrefix(name, prefix) || builtinTools[name] {
+			return name
+		}
+		return prefix + name
+	}
+
 	if messages := gjson.GetBytes(body, "messages"); messages.Exists() && messages.IsArray() {
 		messages.ForEach(func(msgIndex, msg gjson.Result) bool {
 			content := msg.Get("content")
@@ -1671,18 +1745,16 @@ func applyClaudeToolPrefix(body []byte, prefix string) []byte {
 				switch partType {
 				case "tool_use":
 					name := part.Get("name").String()
-					if name == "" || strings.HasPrefix(name, prefix) || builtinTools[name] {
-						return true
+					if updated := prefixHistoryName(name); updated != name {
+						path := fmt.Sprintf("messages.%d.content.%d.name", msgIndex.Int(), contentIndex.Int())
+						body, _ = sjson.SetBytes(body, path, updated)
 					}
-					path := fmt.Sprintf("messages.%d.content.%d.name", msgIndex.Int(), contentIndex.Int())
-					body, _ = sjson.SetBytes(body, path, prefix+name)
 				case "tool_reference":
 					toolName := part.Get("tool_name").String()
-					if toolName == "" || strings.HasPrefix(toolName, prefix) || builtinTools[toolName] {
-						return true
+					if updated := prefixHistoryName(toolName); updated != toolName {
+						path := fmt.Sprintf("messages.%d.content.%d.tool_name", msgIndex.Int(), contentIndex.Int())
+						body, _ = sjson.SetBytes(body, path, updated)
 					}
-					path := fmt.Sprintf("messages.%d.content.%d.tool_name", msgIndex.Int(), contentIndex.Int())
-					body, _ = sjson.SetBytes(body, path, prefix+toolName)
 				case "tool_result":
 					// Handle nested tool_reference blocks inside tool_result.content[]
 					nestedContent := part.Get("content")
@@ -1690,9 +1762,9 @@ func applyClaudeToolPrefix(body []byte, prefix string) []byte {
 						nestedContent.ForEach(func(nestedIndex, nestedPart gjson.Result) bool {
 							if nestedPart.Get("type").String() == "tool_reference" {
 								nestedToolName := nestedPart.Get("tool_name").String()
-								if nestedToolName != "" && !strings.HasPrefix(nestedToolName, prefix) && !builtinTools[nestedToolName] {
+								if updated := prefixHistoryName(nestedToolName); updated != nestedToolName {
 									nestedPath := fmt.Sprintf("messages.%d.content.%d.content.%d.tool_name", msgIndex.Int(), contentIndex.Int(), nestedIndex.Int())
-									body, _ = sjson.SetBytes(body, nestedPath, prefix+nestedToolName)
+									body, _ = sjson.SetBytes(body, nestedPath, updated)
 								}
 							}
 							return true
@@ -1841,53 +1913,29 @@ func getWorkloadFromContext(ctx context.Context) string {
 	return ""
 }
 
-// getCloakConfigFromAuth extracts cloak configuration from the auth's attributes,
-// falling back to its stored metadata (the raw OAuth/token JSON). Returns
-// (cloakMode, strictMode, sensitiveWords, cacheUserID); an empty cloakMode means
-// the credential did not explicitly configure a mode.
-func getCloakConfigFromAuth(auth *cliproxyauth.Auth) (cloakMode string, strictMode bool, sensitiveWords []string, cacheUserID bool) {
-	if auth == nil {
-		return "", false, nil, false
-	}
-
-	// lookupCloakAttr prefers the executor-facing Attributes, then falls back to the
-	// raw metadata blob (e.g. the OAuth/token JSON) so file-based credentials can
-	// carry cloak settings without a matching claude-api-key config entry.
-	lookupCloakAttr := func(key string) string {
-		if auth.Attributes != nil {
-			if value := strings.TrimSpace(auth.Attributes[key]); value != "" {
-				return value
-			}
-		}
-		if auth.Metadata != nil {
-			if value, ok := auth.Metadata[key].(string); ok {
-				return strings.TrimSpace(value)
-			}
-		}
-		return ""
-	}
-
-	// An empty cloakMode means this credential did not explicitly configure a mode,
-	// allowing the caller to fall back to the global/default behavior.
-	cloakMode = lookupCloakAttr("cloak_mode")
-
-	strictMode = strings.EqualFold(lookupCloakAttr("cloak_strict_mode"), "true")
-
-	if wordsStr := lookupCloakAttr("cloak_sensitive_words"); wordsStr != "" {
-		sensitiveWords = strings.Split(wordsStr, ",")
-		for i := range sensitiveWords {
-			sensitiveWords[i] = strings.TrimSpace(sensitiveWords[i])
+// resolveClaudeCloakSettings merges the cloak settings that apply to a Claude credential.
+// Precedence (low -> high):
+//
+//	built-in "auto" default
+//	-> global disable-claude-cloak-mode switch (forces "never")
+//	-> per-credential settings from auth attributes/metadata
+//	-> per claude-api-key cloak config
+func resolveClaudeCloakSettings(cfg *config.Config, auth *cliproxyauth.Auth) helps.CloakSettings {
+	settings := helps.CloakSettingsFromAuth(auth)
+	if settings.Mode == "" {
+		settings.Mode = "auto"
+		if cfg != nil && cfg.DisableClaudeCloakMode {
+			settings.Mode = "never"
 		}
 	}
-
-	cacheUserID = strings.EqualFold(lookupCloakAttr("cloak_cache_user_id"), "true")
-
-	return cloakMode, strictMode, sensitiveWords, cacheUserID
+	return settings.WithConfig(resolveClaudeKeyCloakConfig(cfg, auth))
 }
 
 // injectFakeUserID generates and injects a fake user ID into the request metadata.
-// When useCache is false, a new user ID is generated for every call.
-func injectFakeUserID(ctx context.Context, payload []byte, apiKey string, useCache bool) ([]byte, error) {
+// When useCache is false, a new user ID is generated for every call. When preserve is
+// true, a client user ID that is not in Claude Code format is replaced by its hash
+// instead of a random ID. It is only called for cloaked requests.
+func injectFakeUserID(ctx context.Context, payload []byte, apiKey string, useCache, preserve bool) ([]byte, error) {
 	generateID := func() (string, error) {
 		if useCache {
 			return helps.CachedUserIDRequired(ctx, apiKey)
@@ -1906,6 +1954,10 @@ func injectFakeUserID(ctx context.Context, payload []byte, apiKey string, useCac
 	}
 
 	existingUserID := gjson.GetBytes(payload, "metadata.user_id").String()
+	if existingUserID != "" && preserve && !helps.IsValidUserID(existingUserID) {
+		payload, _ = sjson.SetBytes(payload, "metadata.user_id", helps.DeriveUserID(existingUserID))
+		return payload, nil
+	}
 	if existingUserID == "" || !helps.IsValidUserID(existingUserID) {
 		userID, errUserID := generateID()
 		if errUserID != nil {
@@ -2138,65 +2190,30 @@ func applyCloaking(ctx context.Context, cfg *config.Config, auth *cliproxyauth.A
 	oauthToken := isClaudeOAuthToken(apiKey)
 	useCCHSigning := oauthToken || experimentalCCHSigningEnabled(cfg, auth)
 
-	// Get cloak config from ClaudeKey configuration
-	cloakCfg := resolveClaudeKeyCloakConfig(cfg, auth)
-	attrMode, attrStrict, attrWords, attrCache := getCloakConfigFromAuth(auth)
-
-	// Determine cloak settings. Precedence (low -> high):
-	//   built-in "auto" default
-	//   -> global disable-claude-cloak-mode switch (forces "never")
-	//   -> per-credential settings from auth attributes/metadata
-	//   -> per claude-api-key cloak config
-	cloakMode := "auto"
-	if cfg != nil && cfg.DisableClaudeCloakMode {
-		cloakMode = "never"
-	}
-	strictMode := attrStrict
-	sensitiveWords := attrWords
-	cacheUserID := attrCache
-
-	if attrMode != "" {
-		cloakMode = attrMode
-	}
-
-	if cloakCfg != nil {
-		if mode := strings.TrimSpace(cloakCfg.Mode); mode != "" {
-			cloakMode = mode
-		}
-		if cloakCfg.StrictMode {
-			strictMode = true
-		}
-		if len(cloakCfg.SensitiveWords) > 0 {
-			sensitiveWords = cloakCfg.SensitiveWords
-		}
-		if cloakCfg.CacheUserID != nil {
-			cacheUserID = *cloakCfg.CacheUserID
-		}
-	}
-
-	// Determine if cloaking should be applied
-	if !helps.ShouldCloak(cloakMode, clientUserAgent) {
+	settings := resolveClaudeCloakSettings(cfg, auth)
+	if !settings.Applies(clientUserAgent) {
 		return payload, nil
 	}
+	helps.TraceCloaking(ctx, settings, clientUserAgent)
 
 	// Skip system instructions for claude-3-5-haiku models
 	if !strings.HasPrefix(model, "claude-3-5-haiku") {
 		billingVersion := helps.DefaultClaudeVersion(cfg)
 		entrypoint := parseEntrypointFromUA(clientUserAgent)
 		workload := getWorkloadFromContext(ctx)
-		payload = checkSystemInstructionsWithSigningMode(payload, strictMode, useCCHSigning, oauthToken, billingVersion, entrypoint, workload)
+		payload = checkSystemInstructionsWithSigningMode(payload, settings.StrictMode, useCCHSigning, oauthToken, billingVersion, entrypoint, workload)
 	}
 
 	// Inject fake user ID
 	var errFakeUserID error
-	payload, errFakeUserID = injectFakeUserID(ctx, payload, apiKey, cacheUserID)
+	payload, errFakeUserID = injectFakeUserID(ctx, payload, apiKey, settings.CacheUserID, settings.PreserveUserID)
 	if errFakeUserID != nil {
 		return nil, errFakeUserID
 	}
 
 	// Apply sensitive word obfuscation
-	if len(sensitiveWords) > 0 {
-		matcher := helps.BuildSensitiveWordMatcher(sensitiveWords)
+	if len(settings.SensitiveWords) > 0 {
+		matcher := helps.BuildSensitiveWordMatcher(settings.SensitiveWords)
 		payload = helps.ObfuscateSensitiveWords(payload, matcher)
 	}
 
@@ -1438,6 +1438,71 @@ func TestClaudeExecutor_GeneratesNewUserIDByDefault(t *testing.T) {
 	}
 }
 
+func TestClaudeExecutor_ClientUserIDHandling(t *testing.T) {
+	const clientUserID = "team-abuse-42"
+	sendTwice := func(t *testing.T, cloak *config.CloakConfig) []string {
+		t.Helper()
+		var userIDs []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			userIDs = append(userIDs, gjson.GetBytes(body, "metadata.user_id").String())
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","model":"claude-3-5-sonnet","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+		}))
+		defer server.Close()
+
+		executor := NewClaudeExecutor(&config.Config{
+			ClaudeKey: []config.ClaudeKey{{APIKey: "key-123", BaseURL: server.URL, Cloak: cloak}},
+		})
+		auth := &cliproxyauth.Auth{Attributes: map[string]string{
+			"api_key":  "key-123",
+			"base_url": server.URL,
+		}}
+		payload := []byte(`{"model":"claude-3-5-sonnet","metadata":{"user_id":"` + clientUserID + `"},"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`)
+		for i := 0; i < 2; i++ {
+			if _, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+				Model:   "claude-3-5-sonnet",
+				Payload: payload,
+			}, cliproxyexecutor.Options{
+				SourceFormat: sdktranslator.FromString("claude"),
+			}); err != nil {
+				t.Fatalf("Execute call %d error: %v", i, err)
+			}
+		}
+		if len(userIDs) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(userIDs))
+		}
+		return userIDs
+	}
+
+	t.Run("cloaking off", func(t *testing.T) {
+		userIDs := sendTwice(t, &config.CloakConfig{Mode: "never", PreserveUserID: true})
+		if userIDs[0] != clientUserID || userIDs[1] != clientUserID {
+			t.Fatalf("user_id should be untouched without cloaking, got %q and %q", userIDs[0], userIDs[1])
+		}
+	})
+
+	t.Run("cloaking on with preserve", func(t *testing.T) {
+		userIDs := sendTwice(t, &config.CloakConfig{Mode: "always", PreserveUserID: true})
+		if userIDs[0] != helps.DeriveUserID(clientUserID) || userIDs[1] != userIDs[0] {
+			t.Fatalf("user_id should be the stable hash %q, got %q and %q", helps.DeriveUserID(clientUserID), userIDs[0], userIDs[1])
+		}
+		if !helps.IsValidUserID(userIDs[0]) {
+			t.Fatalf("user_id %q is not valid", userIDs[0])
+		}
+	})
+
+	t.Run("cloaking on without preserve", func(t *testing.T) {
+		userIDs := sendTwice(t, &config.CloakConfig{Mode: "always"})
+		if userIDs[0] == clientUserID || userIDs[0] == userIDs[1] {
+			t.Fatalf("user_id should be random per request, got %q and %q", userIDs[0], userIDs[1])
+		}
+		if !helps.IsValidUserID(userIDs[0]) || !helps.IsValidUserID(userIDs[1]) {
+			t.Fatalf("user_ids should be valid, got %q and %q", userIDs[0], userIDs[1])
+		}
+	})
+}
+
 func TestClaudeExecutor_ExecuteOpenAINonStreamRejectsEmptyClaudeStream(t *testing.T) {
 	_, err := executeOpenAIChatCompletionThroughClaude(t, "")
 	if err == nil {
@@ -2886,7 +2951,7 @@ func TestPrepareClaudeOAuthToolNamesForUpstream_MixedCaseWithPrefix(t *testing.T
 		`{"type":"tool_use","id":"toolu_02","name":"glob","input":{}}` +
 		`]}]}`)
 
-	out, reverseMap := prepareClaudeOAuthToolNamesForUpstream(body, "proxy_", false)
+	out, reverseMap := prepareClaudeOAuthToolNamesForUpstream(body, "proxy_")
 
 	if got := gjson.GetBytes(out, "tools.0.name").String(); got != "proxy_Bash" {
 		t.Fatalf("tools.0.name = %q, want %q", got, "proxy_Bash")
@@ -2905,6 +2970,22 @@ func TestPrepareClaudeOAuthToolNamesForUpstream_MixedCaseWithPrefix(t *testing.T
 	}
 }
 
+func TestPrepareClaudeOAuthToolNamesForUpstream_StripsStrictTools(t *testing.T) {
+	body := []byte(`{"tools":[` +
+		`{"name":"get_weather","strict":true,"input_schema":{"type":"object","properties":{"city":{"type":"string"}}}},` +
+		`{"name":"lookup","input_schema":{"type":"object","properties":{}}}` +
+		`]}`)
+
+	out, _ := prepareClaudeOAuthToolNamesForUpstream(body, "")
+
+	if gjson.GetBytes(out, "tools.0.strict").Exists() {
+		t.Fatalf("tools.0.strict must be stripped for OAuth: %s", out)
+	}
+	if got := gjson.GetBytes(out, "tools.0.input_schema.properties.city.type").String(); got != "string" {
+		t.Fatalf("input_schema changed: %s", out)
+	}
+}
+
 func TestRestoreClaudeOAuthToolNamesFromResponse_MixedCaseWithPrefix(t *testing.T) {
 	reverseMap := map[string]string{"Glob": "glob"}
 	resp := []byte(`{"content":[` +
@@ -2912,7 +2993,7 @@ func TestRestoreClaudeOAuthToolNamesFromResponse_MixedCaseWithPrefix(t *testing.
 		`{"type":"tool_use","id":"toolu_02","name":"proxy_Glob","input":{}}` +
 		`]}`)
 
-	out := restoreClaudeOAuthToolNamesFromResponse(resp, "proxy_", false, reverseMap)
+	out := restoreClaudeOAuthToolNamesFromResponse(resp, "proxy_", reverseMap)
 
 	if got := gjson.GetBytes(out, "content.0.name").String(); got != "Bash" {
 		t.Fatalf("content.0.name = %q, want %q", got, "Bash")
@@ -2926,7 +3007,7 @@ func TestRestoreClaudeOAuthToolNamesFromStreamLine_MixedCaseWithPrefix(t *testin
 	reverseMap := map[string]string{"Glob": "glob"}
 
 	bashLine := []byte(`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_01","name":"proxy_Bash","input":{}}}`)
-	out := restoreClaudeOAuthToolNamesFromStreamLine(bashLine, "proxy_", false, reverseMap)
+	out := restoreClaudeOAuthToolNamesFromStreamLine(bashLine, "proxy_", reverseMap)
 	if !bytes.Contains(out, []byte(`"name":"Bash"`)) {
 		t.Fatalf("Bash should be preserved, got: %s", string(out))
 	}
@@ -2935,7 +3016,7 @@ func TestRestoreClaudeOAuthToolNamesFromStreamLine_MixedCaseWithPrefix(t *testin
 	}
 
 	globLine := []byte(`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_02","name":"proxy_Glob","input":{}}}`)
-	out = restoreClaudeOAuthToolNamesFromStreamLine(globLine, "proxy_", false, reverseMap)
+	out = restoreClaudeOAuthToolNamesFromStreamLine(globLine, "proxy_", reverseMap)
 	if !bytes.Contains(out, []byte(`"name":"glob"`)) {
 		t.Fatalf("Glob should be restored to glob, got: %s", string(out))
 	}
@@ -2979,3 +3060,182 @@ func TestEnsureClaudeThinkingDisplay_SkipsWhenThinkingMissing(t *testing.T) {
 		t.Fatalf("thinking should remain absent: %s", out)
 	}
 }
+
+func TestClaudeToolPrefixForAuth(t *testing.T) {
+	if got := claudeToolPrefixForAuth(nil); got != claudeToolPrefix {
+		t.Fatalf("nil auth prefix = %q, want default %q", got, claudeToolPrefix)
+	}
+	custom := &cliproxyauth.Auth{Attributes: map[string]string{"tool_prefix": "mcp_"}}
+	if got := claudeToolPrefixForAuth(custom); got != "mcp_" {
+		t.Fatalf("custom prefix = %q, want mcp_", got)
+	}
+	disabled := &cliproxyauth.Auth{
+		Attributes: map[string]string{"tool_prefix": "mcp_"},
+		Metadata:   map[string]any{"tool_prefix_disabled": true},
+	}
+	if got := claudeToolPrefixForAuth(disabled); got != "" {
+		t.Fatalf("disabled prefix = %q, want empty", got)
+	}
+}
+
+func TestClaudeOAuthToolNamesCustomPrefixRoundTrip(t *testing.T) {
+	body := []byte(`{"tools":[{"name":"lookup"}],"tool_choice":{"type":"tool","name":"lookup"},"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"lookup","input":{}}]}]}`)
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"tool_prefix": "mcp_"}}
+	prefix := claudeToolPrefixForAuth(auth)
+
+	out, reverseMap := prepareClaudeOAuthToolNamesForUpstream(body, prefix)
+	for _, path := range []string{"tools.0.name", "tool_choice.name", "messages.0.content.0.name"} {
+		if got := gjson.GetBytes(out, path).String(); got != "mcp_lookup" {
+			t.Fatalf("%s = %q, want mcp_lookup", path, got)
+		}
+	}
+
+	resp := []byte(`{"content":[{"type":"tool_use","id":"t2","name":"mcp_lookup","input":{}}]}`)
+	if got := gjson.GetBytes(restoreClaudeOAuthToolNamesFromResponse(resp, prefix, reverseMap), "content.0.name").String(); got != "lookup" {
+		t.Fatalf("response tool name = %q, want lookup", got)
+	}
+	line := []byte(`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t2","name":"mcp_lookup","input":{}}}`)
+	if out := restoreClaudeOAuthToolNamesFromStreamLine(line, prefix, reverseMap); !bytes.Contains(out, []byte(`"name":"lookup"`)) {
+		t.Fatalf("stream tool name not restored: %s", out)
+	}
+}
+
+func TestClaudeOAuthToolNamesDisabledPrefixLeavesNamesUntouched(t *testing.T) {
+	body := []byte(`{"tools":[{"name":"lookup"}],"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"proxy_lookup","input":{}}]}]}`)
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"tool_prefix": ""}}
+	prefix := claudeToolPrefixForAuth(auth)
+
+	out, _ := prepareClaudeOAuthToolNamesForUpstream(body, prefix)
+	if got := gjson.GetBytes(out, "tools.0.name").String(); got != "lookup" {
+		t.Fatalf("tools.0.name = %q, want lookup", got)
+	}
+	if got := gjson.GetBytes(out, "messages.0.content.0.name").String(); got != "lookup" {
+		t.Fatalf("history name = %q, want the legacy prefix stripped to lookup", got)
+	}
+
+	resp := []byte(`{"content":[{"type":"tool_use","id":"t2","name":"proxy_lookup","input":{}}]}`)
+	if got := gjson.GetBytes(restoreClaudeOAuthToolNamesFromResponse(resp, prefix, nil), "content.0.name").String(); got != "proxy_lookup" {
+		t.Fatalf("response tool name = %q, want proxy_lookup untouched", got)
+	}
+	line := []byte(`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t2","name":"proxy_lookup","input":{}}}`)
+	if out := restoreClaudeOAuthToolNamesFromStreamLine(line, prefix, nil); !bytes.Equal(out, line) {
+		t.Fatalf("stream line changed with disabled prefix: %s", out)
+	}
+}
+
+func TestApplyClaudeToolPrefix_RewritesLegacyPrefixedHistory(t *testing.T) {
+	body := []byte(`{"tools":[{"name":"lookup"},{"name":"proxy_native"}],"messages":[` +
+		`{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"proxy_lookup","input":{}},{"type":"tool_use","id":"t2","name":"proxy_unknown","input":{}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"tool_reference","tool_name":"proxy_lookup"}]}]},` +
+		`{"role":"assistant","content":[{"type":"tool_use","id":"t3","name":"lookup","input":{}},{"type":"tool_use","id":"t4","name":"proxy_native","input":{}}]}` +
+		`]}`)
+
+	out := applyClaudeToolPrefix(body, "mcp_")
+
+	cases := map[string]string{
+		"tools.0.name":                             "mcp_lookup",
+		"tools.1.name":                             "mcp_proxy_native",
+		"messages.0.content.0.name":                "mcp_lookup",
+		"messages.0.content.1.name":                "mcp_proxy_unknown",
+		"messages.1.content.0.content.0.tool_name": "mcp_lookup",
+		"messages.2.content.0.name":                "mcp_lookup",
+		"messages.2.content.1.name":                "mcp_proxy_native",
+	}
+	for path, want := range cases {
+		if got := gjson.GetBytes(out, path).String(); got != want {
+			t.Fatalf("%s = %q, want %q; body=%s", path, got, want, out)
+		}
+	}
+}
+
+func TestApplyClaudeToolPrefix_EmptyPrefixRewritesLegacyPrefixedHistory(t *testing.T) {
+	body := []byte(`{"tools":[{"name":"lookup"}],"tool_choice":{"type":"tool","name":"lookup"},"messages":[` +
+		`{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"proxy_lookup","input":{}},{"type":"tool_use","id":"t2","name":"proxy_unknown","input":{}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"tool_reference","tool_name":"proxy_lookup"}]}]}` +
+		`]}`)
+
+	out := applyClaudeToolPrefix(body, "")
+
+	cases := map[string]string{
+		"tools.0.name":                             "lookup",
+		"tool_choice.name":                         "lookup",
+		"messages.0.content.0.name":                "lookup",
+		"messages.0.content.1.name":                "proxy_unknown",
+		"messages.1.content.0.content.0.tool_name": "lookup",
+	}
+	for path, want := range cases {
+		if got := gjson.GetBytes(out, path).String(); got != want {
+			t.Fatalf("%s = %q, want %q; body=%s", path, got, want, out)
+		}
+	}
+}
+
+func TestClaudeExecutor_CountTokensMatchesExecuteRequestShaping(t *testing.T) {
+	tests := []struct {
+		name         string
+		toolChoice   string
+		wantThinking bool
+	}{
+		{name: "suffix thinking", toolChoice: `{"type":"auto"}`, wantThinking: true},
+		{name: "forced tool drops thinking", toolChoice: `{"type":"tool","name":"lookup"}`, wantThinking: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := make(map[string][]byte)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies[r.URL.Path] = bytes.Clone(body)
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/count_tokens") {
+					_, _ = w.Write([]byte(`{"input_tokens":42}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+			}))
+			defer server.Close()
+
+			executor := NewClaudeExecutor(&config.Config{})
+			auth := &cliproxyauth.Auth{Attributes: map[string]string{
+				"api_key":  "key-123",
+				"base_url": server.URL,
+			}}
+			req := cliproxyexecutor.Request{
+				Model: "claude-sonnet-4-5-20250929(16384)",
+				Payload: []byte(`{
+					"model": "claude-sonnet-4-5-20250929(16384)",
+					"max_tokens": 32000,
+					"tools": [{"name":"lookup","description":"Look up","input_schema":{"type":"object","properties":{}}}],
+					"tool_choice": ` + tt.toolChoice + `,
+					"messages": [{"role":"user","content":[{"type":"text","text":"hi"}]}]
+				}`),
+			}
+			opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")}
+
+			if _, errExecute := executor.Execute(context.Background(), auth, req, opts); errExecute != nil {
+				t.Fatalf("Execute() error = %v", errExecute)
+			}
+			if _, errCount := executor.CountTokens(context.Background(), auth, req, opts); errCount != nil {
+				t.Fatalf("CountTokens() error = %v", errCount)
+			}
+
+			executeBody := bodies["/v1/messages"]
+			countBody := bodies["/v1/messages/count_tokens"]
+			if len(executeBody) == 0 || len(countBody) == 0 {
+				t.Fatalf("captured bodies = %v, want both Execute and CountTokens requests", bodies)
+			}
+			if got := gjson.GetBytes(executeBody, "thinking").Exists(); got != tt.wantThinking {
+				t.Fatalf("execute thinking present = %v, want %v: %s", got, tt.wantThinking, executeBody)
+			}
+			for _, path := range []string{"model", "system", "thinking", "tools", "tool_choice", "messages"} {
+				if got, want := gjson.GetBytes(countBody, path).Raw, gjson.GetBytes(executeBody, path).Raw; got != want {
+					t.Errorf("%s differs\ncount_tokens: %s\nexecute:      %s", path, got, want)
+				}
+			}
+			for _, field := range claudeCountTokensUnsupportedFields {
+				if gjson.GetBytes(countBody, field).Exists() {
+					t.Errorf("count_tokens body kept generation-only field %q: %s", field, countBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// claudeBatchToolNamesTTL is how long the tool-name maps of a batch are kept. Anthropic
+// keeps batch results for 29 days.
+const claudeBatchToolNamesTTL = 29 * 24 * time.Hour
+
+// claudeBatchToolNamesByBatch keeps the OAuth tool-name reverse maps of each
+// created batch, keyed by batch ID and then by request custom_id, so results
+// can be restored to the names the client originally sent.
+var claudeBatchToolNamesByBatch claudeBatchToolNames
+
+// claudeBatchToolNames holds tool-name reverse maps per batch until their batch results
+// expire upstream.
+type claudeBatchToolNames struct {
+	mu      sync.Mutex
+	entries map[string]claudeBatchToolNamesEntry
+}
+
+type claudeBatchToolNamesEntry struct {
+	maps    map[string]map[string]string
+	expires time.Time
+}
+
+func (c *claudeBatchToolNames) store(batchID string, maps map[string]map[string]string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]claudeBatchToolNamesEntry)
+	}
+	for id, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[batchID] = claudeBatchToolNamesEntry{maps: maps, expires: now.Add(claudeBatchToolNamesTTL)}
+}
+
+func (c *claudeBatchToolNames) load(batchID string, now time.Time) map[string]map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[batchID]
+	if !ok {
+		return nil
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, batchID)
+		return nil
+	}
+	return entry.maps
+}
+
+// MessageBatchRequest proxies an Anthropic message batches API call. path is
+// relative to /v1/messages/batches (for example "", "/msgbatch_x" or
+// "/msgbatch_x/results"). Batch creation bodies are transformed per request
+// item the same way as /v1/messages; responses are returned with their
+// content encoding already decoded. Callers must close the response body.
+func (e *ClaudeExecutor) MessageBatchRequest(ctx context.Context, auth *cliproxyauth.Auth, method, path, rawQuery string, body []byte, headers http.Header) (*http.Response, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	apiKey, baseURL := claudeCreds(auth)
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	oauthToken := isClaudeOAuthToken(apiKey)
+	var extraBetas []string
+	var toolNameMaps map[string]map[string]string
+	creating := method == http.MethodPost && path == ""
+	if creating {
+		var err error
+		body, extraBetas, toolNameMaps, err = e.prepareMessageBatchBody(ctx, auth, apiKey, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	url := baseURL + "/v1/messages/batches" + path
+	if rawQuery != "" {
+		url += "?" + rawQuery
+	}
+	var reader io.Reader
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if errHeaders := applyClaudeHeaders(httpReq, auth, apiKey, false, extraBetas, e.cfg, headers); errHeaders != nil {
+		return nil, errHeaders
+	}
+	if strings.HasSuffix(path, "/results") {
+		httpReq.Header.Set("Accept", "*/*")
+	}
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+	helps.RecordAPIRequest(ctx, e.cfg, helps.UpstreamRequestLog{
+		URL:       url,
+		Method:    method,
+		Headers:   httpReq.Header.Clone(),
+		Body:      body,
+		Provider:  e.upstreamRequestLogProvider(),
+		AuthID:    authID,
+		AuthLabel: authLabel,
+		AuthType:  authType,
+		AuthValue: authValue,
+	})
+
+	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, 0)
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		helps.RecordAPIResponseError(ctx, e.cfg, err)
+		return nil, err
+	}
+	helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+
+	decodedBody, err := decodeResponseBody(httpResp.Body, httpResp.Header.Get("Content-Encoding"))
+	if err != nil {
+		helps.RecordAPIResponseError(ctx, e.cfg, err)
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("response body close error: %v", errClose)
+		}
+		return nil, err
+	}
+	httpResp.Body = decodedBody
+	httpResp.Header.Del("Content-Encoding")
+	httpResp.Header.Del("Content-Length")
+	httpResp.ContentLength = -1
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return httpResp, nil
+	}
+	if creating && oauthToken {
+		data, errRead := io.ReadAll(decodedBody)
+		if errClose := decodedBody.Close(); errClose != nil {
+			log.Errorf("response body close error: %v", errClose)
+		}
+		if errRead != nil {
+			helps.RecordAPIResponseError(ctx, e.cfg, errRead)
+			return nil, errRead
+		}
+		if batchID := gjson.GetBytes(data, "id").String(); batchID != "" && len(toolNameMaps) > 0 {
+			claudeBatchToolNamesByBatch.store(batchID, toolNameMaps, time.Now())
+		}
+		httpResp.Body = io.NopCloser(bytes.NewReader(data))
+		return httpResp, nil
+	}
+	if oauthToken && strings.HasSuffix(path, "/results") {
+		batchID := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/results")
+		reverseMaps := claudeBatchToolNamesByBatch.load(batchID, time.Now())
+		httpResp.Body = restoreClaudeMessageBatchResults(decodedBody, claudeToolPrefixForAuth(auth), reverseMaps)
+	}
+	return httpResp, nil
+}
+
+// prepareMessageBatchBody applies the /v1/messages request transforms to each
+// requests[].params entry of a batch creation body. It returns the merged
+// betas of all items and, for OAuth tokens, the tool-name reverse map of each
+// custom_id.
+func (e *ClaudeExecutor) prepareMessageBatchBody(ctx context.Context, auth *cliproxyauth.Auth, apiKey string, body []byte) ([]byte, []string, map[string]map[string]string, error) {
+	requests := gjson.GetBytes(body, "requests")
+	if !requests.IsArray() {
+		return body, nil, nil, nil
+	}
+	oauthToken := isClaudeOAuthToken(apiKey)
+	var extraBetas []string
+	seenBetas := make(map[string]struct{})
+	var toolNameMaps map[string]map[string]string
+
+	for i, item := range requests.Array() {
+		params := item.Get("params")
+		if !params.IsObject() {
+			continue
+		}
+		itemBody, itemBetas, reverseMap, err := e.prepareMessageBatchItem(ctx, auth, apiKey, []byte(params.Raw))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, beta := range itemBetas {
+			if _, exists := seenBetas[beta]; exists {
+				continue
+			}
+			seenBetas[beta] = struct{}{}
+			extraBetas = append(extraBetas, beta)
+		}
+		if oauthToken && len(reverseMap) > 0 {
+			if toolNameMaps == nil {
+				toolNameMaps = make(map[string]map[string]string)
+			}
+			toolNameMaps[item.Get("custom_id").String()] = reverseMap
+		}
+		body, err = sjson.SetRawBytes(body, fmt.Sprintf("requests.%d.params", i), itemBody)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return body, extraBetas, toolNameMaps, nil
+}
+
+func (e *ClaudeExecutor) prepareMessageBatchItem(ctx context.Context, auth *cliproxyauth.Auth, apiKey string, body []byte) ([]byte, []string, map[string]string, error) {
+	baseModel := thinking.ParseSuffix(gjson.GetBytes(body, "model").String()).ModelName
+	body = helps.SetStringIfDifferent(body, "model", e.upstreamModel(baseModel))
+	if rebuildMidSystemMessageEnabled(e.cfg, auth) {
+		body = rebuildMidSystemMessagesToTopLevel(body)
+	}
+
+	body, err := applyCloaking(ctx, e.cfg, auth, body, baseModel, apiKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	body = disableThinkingIfToolChoiceForced(body)
+	body = normalizeClaudeSamplingForUpstream(body)
+	body = enforceCacheControlLimit(body, 4)
+	body = normalizeCacheControlTTL(body)
+
+	var extraBetas []string
+	extraBetas, body = extractAndRemoveBetas(body)
+	var reverseMap map[string]string
+	if isClaudeOAuthToken(apiKey) {
+		body, reverseMap = prepareClaudeOAuthToolNamesForUpstream(body, claudeToolPrefixForAuth(auth))
+	}
+	body = sanitizeClaudeMessagesForClaudeUpstreamWithDebug(ctx, body, baseModel)
+	return body, extraBetas, reverseMap, nil
+}
+
+// restoreClaudeMessageBatchResults undoes the OAuth tool-name transforms on each
+// JSONL line of a batch results stream.
+func restoreClaudeMessageBatchResults(body io.ReadCloser, prefix string, reverseMaps map[string]map[string]string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer func() {
+			if errClose := body.Close(); errClose != nil {
+				log.Errorf("response body close error: %v", errClose)
+			}
+		}()
+		reader := bufio.NewReader(body)
+		for {
+			line, errRead := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				trimmed := bytes.TrimRight(line, "\r\n")
+				if message := gjson.GetBytes(trimmed, "result.message"); message.IsObject() {
+					reverseMap := reverseMaps[gjson.GetBytes(trimmed, "custom_id").String()]
+					restored := restoreClaudeOAuthToolNamesFromResponse([]byte(message.Raw), prefix, reverseMap)
+					if updated, errSet := sjson.SetRawBytes(trimmed, "result.message", restored); errSet == nil {
+						line = append(updated, line[len(trimmed):]...)
+					}
+				}
+				if _, errWrite := pw.Write(line); errWrite != nil {
+					return
+				}
+			}
+			if errRead != nil {
+				if errRead == io.EOF {
+					_ = pw.Close()
+				} else {
+					_ = pw.CloseWithError(errRead)
+				}
+				return
+			}
+		}
+	}()
+	return pr
+}
@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+func TestClaudeExecutorMessageBatchRequest_TransformsEachItem(t *testing.T) {
+	var seenBody []byte
+	var seenBeta, seenPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenBody, _ = io.ReadAll(r.Body)
+		seenBeta = r.Header.Get("Anthropic-Beta")
+		seenPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msgbatch_transform","type":"message_batch","processing_status":"in_progress"}`))
+	}))
+	defer server.Close()
+
+	executor := NewClaudeExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{ID: "claude-oauth", Attributes: map[string]string{
+		"api_key":  "sk-ant-oat01-batch",
+		"base_url": server.URL,
+	}}
+	body := []byte(`{"requests":[
+		{"custom_id":"a","params":{"model":"claude-sonnet-4-5","max_tokens":16,"betas":["batch-beta-a"],"tools":[{"name":"bash","input_schema":{"type":"object"}}],"messages":[{"role":"user","content":"hi"}]}},
+		{"custom_id":"b","params":{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[
+			{"role":"assistant","content":[{"type":"thinking","thinking":"x","signature":"gAAAAABopenai-encrypted-content"},{"type":"text","text":"Answer"}]},
+			{"role":"user","content":"next"}
+		]}}
+	]}`)
+
+	resp, err := executor.MessageBatchRequest(context.Background(), auth, http.MethodPost, "", "", body, nil)
+	if err != nil {
+		t.Fatalf("MessageBatchRequest() error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	out, _ := io.ReadAll(resp.Body)
+	if gjson.GetBytes(out, "id").String() != "msgbatch_transform" {
+		t.Fatalf("response = %s", out)
+	}
+
+	if seenPath != "/v1/messages/batches" {
+		t.Fatalf("upstream path = %q", seenPath)
+	}
+	if !strings.Contains(seenBeta, "batch-beta-a") {
+		t.Fatalf("Anthropic-Beta = %q, want item betas merged", seenBeta)
+	}
+	if gjson.GetBytes(seenBody, "requests.0.params.betas").Exists() {
+		t.Fatalf("item betas should be moved to headers: %s", seenBody)
+	}
+	if got := gjson.GetBytes(seenBody, "requests.0.params.tools.0.name").String(); got != "Bash" {
+		t.Fatalf("tool name = %q, want Bash", got)
+	}
+	if got := gjson.GetBytes(seenBody, "requests.0.custom_id").String(); got != "a" {
+		t.Fatalf("custom_id = %q, want a", got)
+	}
+	if strings.Contains(string(seenBody), "gAAAAABopenai-encrypted-content") {
+		t.Fatalf("foreign thinking signature should be sanitized: %s", seenBody)
+	}
+}
+
+func TestClaudeExecutorMessageBatchRequest_DecodesAndRestoresResults(t *testing.T) {
+	results := `{"custom_id":"a","result":{"type":"succeeded","message":{"content":[{"type":"tool_use","id":"t1","name":"Bash","input":{}}]}}}` + "\n" +
+		`{"custom_id":"b","result":{"type":"errored","error":{"type":"invalid_request"}}}` + "\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/messages/batches":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"msgbatch_results","type":"message_batch"}`))
+		case "/v1/messages/batches/msgbatch_results":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"msgbatch_results","type":"message_batch","results_url":"https://example.com/r","opaque":{"k":1}}`))
+		case "/v1/messages/batches/msgbatch_results/results":
+			if r.URL.RawQuery != "after_id=x" {
+				t.Errorf("raw query = %q, want after_id=x", r.URL.RawQuery)
+			}
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			_, _ = gz.Write([]byte(results))
+			_ = gz.Close()
+			w.Header().Set("Content-Type", "application/binary")
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	executor := NewClaudeExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{ID: "claude-oauth", Attributes: map[string]string{
+		"api_key":  "sk-ant-oat01-batch",
+		"base_url": server.URL,
+	}}
+	create := []byte(`{"requests":[{"custom_id":"a","params":{"model":"claude-sonnet-4-5","max_tokens":16,"tools":[{"name":"bash","input_schema":{"type":"object"}}],"messages":[{"role":"user","content":"hi"}]}}]}`)
+	resp, err := executor.MessageBatchRequest(context.Background(), auth, http.MethodPost, "", "", create, nil)
+	if err != nil {
+		t.Fatalf("create error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	resp, err = executor.MessageBatchRequest(context.Background(), auth, http.MethodGet, "/msgbatch_results", "", nil, nil)
+	if err != nil {
+		t.Fatalf("get error = %v", err)
+	}
+	meta, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(meta) != `{"id":"msgbatch_results","type":"message_batch","results_url":"https://example.com/r","opaque":{"k":1}}` {
+		t.Fatalf("batch metadata should pass through unchanged, got %s", meta)
+	}
+
+	resp, err = executor.MessageBatchRequest(context.Background(), auth, http.MethodGet, "/msgbatch_results/results", "after_id=x", nil, nil)
+	if err != nil {
+		t.Fatalf("results error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("Content-Encoding = %q, want decoded body", resp.Header.Get("Content-Encoding"))
+	}
+	out, _ := io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %d, want 2: %s", len(lines), out)
+	}
+	if got := gjson.Get(lines[0], "result.message.content.0.name").String(); got != "bash" {
+		t.Fatalf("restored tool name = %q, want bash", got)
+	}
+	if lines[1] != `{"custom_id":"b","result":{"type":"errored","error":{"type":"invalid_request"}}}` {
+		t.Fatalf("errored line changed: %s", lines[1])
+	}
+}
+
+func TestClaudeBatchToolNamesExpire(t *testing.T) {
+	var names claudeBatchToolNames
+	now := time.Now()
+	maps := map[string]map[string]string{"a": {"proxy_read": "read"}}
+	names.store("msgbatch_old", maps, now)
+	if got := names.load("msgbatch_old", now.Add(claudeBatchToolNamesTTL-time.Minute)); got == nil {
+		t.Fatal("tool names dropped before the batch results expire")
+	}
+	if got := names.load("msgbatch_old", now.Add(claudeBatchToolNamesTTL)); got != nil {
+		t.Fatalf("expired tool names = %v, want none", got)
+	}
+
+	names.store("msgbatch_a", maps, now)
+	names.store("msgbatch_b", maps, now.Add(claudeBatchToolNamesTTL))
+	names.mu.Lock()
+	defer names.mu.Unlock()
+	if _, ok := names.entries["msgbatch_a"]; ok || len(names.entries) != 1 {
+		t.Fatalf("entries = %v, want the expired batch evicted on store", names.entries)
+	}
+}
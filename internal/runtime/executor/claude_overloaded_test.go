@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+const claudeOverloadedBody = `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`
+
+// newClaudeOverloadedManager registers two Claude API key auths against an upstream that
+// answers the first request with 529 and every later one with 200. It returns the API
+// keys in the order the upstream saw them.
+func newClaudeOverloadedManager(t *testing.T, stream bool) (*cliproxyauth.Manager, string, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seenKeys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seenKeys = append(seenKeys, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		first := len(seenKeys) == 1
+		mu.Unlock()
+		if first {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(529)
+			_, _ = w.Write([]byte(claudeOverloadedBody))
+			return
+		}
+		if stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-sonnet-4-5\",\"role\":\"assistant\",\"content\":[]}}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","model":"claude-sonnet-4-5","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	model := "claude-sonnet-4-5"
+	manager := cliproxyauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(NewClaudeExecutor(&config.Config{}))
+	reg := registry.GetGlobalRegistry()
+	for i := 0; i < 2; i++ {
+		auth := &cliproxyauth.Auth{
+			ID:       fmt.Sprintf("claude-overloaded-%d-%d", i, time.Now().UnixNano()),
+			Provider: "claude",
+			Attributes: map[string]string{
+				"api_key":  fmt.Sprintf("key-%d", i),
+				"base_url": server.URL,
+			},
+		}
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("Register() error = %v", errRegister)
+		}
+		reg.RegisterClient(auth.ID, "claude", []*registry.ModelInfo{{ID: model}})
+		authID := auth.ID
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+	return manager, model, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seenKeys...)
+	}
+}
+
+func assertOverloadedAuthSwitched(t *testing.T, manager *cliproxyauth.Manager, model string, seenKeys []string) {
+	t.Helper()
+	if len(seenKeys) != 2 || seenKeys[0] == seenKeys[1] {
+		t.Fatalf("upstream API keys = %v, want the retry on the other auth", seenKeys)
+	}
+	for _, auth := range manager.List() {
+		if auth.Attributes["api_key"] != seenKeys[0] {
+			continue
+		}
+		state := auth.ModelStates[model]
+		if state == nil || !state.NextRetryAfter.After(time.Now()) {
+			t.Fatalf("overloaded auth model state = %+v, want a transient cooldown", state)
+		}
+		return
+	}
+	t.Fatalf("overloaded auth with key %s not found", seenKeys[0])
+}
+
+func TestClaudeOverloadedRetriesOnAnotherAuth(t *testing.T) {
+	manager, model, seenKeys := newClaudeOverloadedManager(t, false)
+	payload := []byte(`{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`)
+	resp, errExecute := manager.Execute(context.Background(), []string{"claude"}, cliproxyexecutor.Request{
+		Model:   model,
+		Payload: payload,
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: payload})
+	if errExecute != nil {
+		t.Fatalf("Execute() error = %v", errExecute)
+	}
+	if len(resp.Payload) == 0 {
+		t.Fatal("Execute() returned an empty payload")
+	}
+	assertOverloadedAuthSwitched(t, manager, model, seenKeys())
+}
+
+func TestClaudeOverloadedStreamRetriesOnAnotherAuth(t *testing.T) {
+	manager, model, seenKeys := newClaudeOverloadedManager(t, true)
+	payload := []byte(`{"model":"claude-sonnet-4-5","max_tokens":16,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	result, errExecute := manager.ExecuteStream(context.Background(), []string{"claude"}, cliproxyexecutor.Request{
+		Model:   model,
+		Payload: payload,
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), Stream: true, OriginalRequest: payload})
+	if errExecute != nil {
+		t.Fatalf("ExecuteStream() error = %v", errExecute)
+	}
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error: %v", chunk.Err)
+		}
+	}
+	assertOverloadedAuthSwitched(t, manager, model, seenKeys())
+}
+
+func TestClaudeStatusErrMarksOverloadedResponses(t *testing.T) {
+	header := http.Header{"Retry-After": []string{"12"}}
+	err := claudeStatusErr(529, header, []byte(claudeOverloadedBody))
+	if err.StatusCode() != 529 {
+		t.Fatalf("status = %d, want 529", err.StatusCode())
+	}
+	if err.RetryAfter() == nil || *err.RetryAfter() != 12*time.Second {
+		t.Fatalf("retry after = %v, want 12s", err.RetryAfter())
+	}
+
+	err = claudeStatusErr(http.StatusInternalServerError, nil, []byte(claudeOverloadedBody))
+	if err.StatusCode() != 529 {
+		t.Fatalf("overloaded_error body status = %d, want 529", err.StatusCode())
+	}
+
+	err = claudeStatusErr(http.StatusTooManyRequests, header, []byte(`{"type":"error","error":{"type":"rate_limit_error"}}`))
+	if err.StatusCode() != http.StatusTooManyRequests || err.RetryAfter() != nil {
+		t.Fatalf("rate limit error = %d %v, want 429 without retry hint", err.StatusCode(), err.RetryAfter())
+	}
+}
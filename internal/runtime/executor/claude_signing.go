@@ -80,6 +80,14 @@ func experimentalCCHSigningEnabled(cfg *config.Config, auth *cliproxyauth.Auth)
 	return entry != nil && entry.ExperimentalCCHSigning
 }
 
+func systemAsStringEnabled(cfg *config.Config, auth *cliproxyauth.Auth) bool {
+	if auth != nil && auth.Attributes != nil && strings.EqualFold(strings.TrimSpace(auth.Attributes["system_as_string"]), "true") {
+		return true
+	}
+	entry := resolveClaudeKeyConfig(cfg, auth)
+	return entry != nil && entry.SystemAsString
+}
+
 func rebuildMidSystemMessageEnabled(cfg *config.Config, auth *cliproxyauth.Auth) bool {
 	if auth != nil && auth.Attributes != nil && strings.EqualFold(strings.TrimSpace(auth.Attributes["rebuild_mid_system_message"]), "true") {
 		return true
@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor/helps"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// flattenClaudeSystem replaces a content-block system field with the text of its blocks
+// joined by newlines. It reports false and returns body unchanged when system is not an array.
+func flattenClaudeSystem(body []byte) ([]byte, bool) {
+	system := gjson.GetBytes(body, "system")
+	if !system.IsArray() {
+		return body, false
+	}
+	var texts []string
+	for _, block := range system.Array() {
+		if block.Type == gjson.String {
+			texts = append(texts, block.String())
+			continue
+		}
+		if text := block.Get("text"); text.Exists() {
+			texts = append(texts, text.String())
+		}
+	}
+	flattened, errSet := sjson.SetBytes(body, "system", strings.Join(texts, "\n"))
+	if errSet != nil {
+		return body, false
+	}
+	return flattened, true
+}
+
+// isClaudeSystemStringRejection reports whether an upstream error response rejected the
+// content-block form of the system field, e.g. `system: expected string`.
+func isClaudeSystemStringRejection(statusCode int, body []byte) bool {
+	if statusCode != http.StatusBadRequest {
+		return false
+	}
+	message := gjson.GetBytes(body, "error.message").String()
+	if message == "" {
+		message = string(body)
+	}
+	message = strings.ToLower(message)
+	return strings.Contains(message, "system") && strings.Contains(message, "string")
+}
+
+// doClaudeRequest sends httpReq. Some gateways in front of claude-3-5-haiku reject system
+// content blocks with a 400; on that error the request is sent once more with system
+// flattened to a string. requestLog describes httpReq and is recorded again for the retry.
+func (e *ClaudeExecutor) doClaudeRequest(ctx context.Context, httpClient *http.Client, httpReq *http.Request, body []byte, requestLog helps.UpstreamRequestLog) (*http.Response, error) {
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil || httpResp.StatusCode != http.StatusBadRequest {
+		return httpResp, err
+	}
+	model := gjson.GetBytes(body, "model").String()
+	if !strings.HasPrefix(model, "claude-3-5-haiku") {
+		return httpResp, nil
+	}
+	flattened, ok := flattenClaudeSystem(body)
+	if !ok {
+		return httpResp, nil
+	}
+
+	// Buffer the error so the caller can still read it when no retry happens.
+	raw, errRead := io.ReadAll(httpResp.Body)
+	_ = httpResp.Body.Close()
+	httpResp.Body = io.NopCloser(bytes.NewReader(raw))
+	if errRead != nil {
+		return httpResp, nil
+	}
+	decoded, errDecode := decodeResponseBody(io.NopCloser(bytes.NewReader(raw)), httpResp.Header.Get("Content-Encoding"))
+	if errDecode != nil {
+		return httpResp, nil
+	}
+	errBody, _ := io.ReadAll(decoded)
+	_ = decoded.Close()
+	if !isClaudeSystemStringRejection(httpResp.StatusCode, errBody) {
+		return httpResp, nil
+	}
+
+	helps.LogWithRequestID(ctx).Infof("claude executor: upstream rejected system content blocks for %s, retrying with a string system prompt", model)
+	helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+	helps.AppendAPIResponseChunk(ctx, e.cfg, errBody)
+
+	retryReq := httpReq.Clone(ctx)
+	retryReq.Body = io.NopCloser(bytes.NewReader(flattened))
+	retryReq.ContentLength = int64(len(flattened))
+	retryReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(flattened)), nil
+	}
+	requestLog.Headers = retryReq.Header.Clone()
+	requestLog.Body = flattened
+	helps.RecordAPIRequest(ctx, e.cfg, requestLog)
+	return httpClient.Do(retryReq)
+}
@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// newStringSystemOnlyServer fakes a gateway that rejects system content blocks and records
+// the system field of every request.
+func newStringSystemOnlyServer(t *testing.T) (*httptest.Server, *[]gjson.Result) {
+	t.Helper()
+	var systems []gjson.Result
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		system := gjson.GetBytes(body, "system")
+		systems = append(systems, system)
+		w.Header().Set("Content-Type", "application/json")
+		if system.IsArray() {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"system: expected string"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","model":"claude-3-5-haiku-20241022","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &systems
+}
+
+func executeClaudeWithSystemBlocks(t *testing.T, auth *cliproxyauth.Auth, model string) error {
+	t.Helper()
+	_, err := NewClaudeExecutor(&config.Config{}).Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   model,
+		Payload: []byte(`{"system":[{"type":"text","text":"You are terse."},{"type":"text","text":"Answer in English."}],"messages":[{"role":"user","content":"hi"}]}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")})
+	return err
+}
+
+func TestClaudeExecutor_RetriesHaikuWithStringSystem(t *testing.T) {
+	server, systems := newStringSystemOnlyServer(t)
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "key-123", "base_url": server.URL}}
+
+	if err := executeClaudeWithSystemBlocks(t, auth, "claude-3-5-haiku-20241022"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(*systems) != 2 {
+		t.Fatalf("upstream calls = %d, want the rejected call and one retry", len(*systems))
+	}
+	if !(*systems)[0].IsArray() {
+		t.Fatalf("first system = %s, want the original content blocks", (*systems)[0].Raw)
+	}
+	if got := (*systems)[1]; got.Type != gjson.String || got.String() != "You are terse.\nAnswer in English." {
+		t.Fatalf("retried system = %s, want the blocks joined by a newline", got.Raw)
+	}
+}
+
+func TestClaudeExecutor_DoesNotRetryStringSystemForOtherModels(t *testing.T) {
+	server, systems := newStringSystemOnlyServer(t)
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "key-123", "base_url": server.URL}}
+
+	err := executeClaudeWithSystemBlocks(t, auth, "claude-3-5-sonnet-20241022")
+	if err == nil {
+		t.Fatal("Execute() error = nil, want the upstream 400")
+	}
+	if status, ok := err.(statusErr); !ok || status.code != http.StatusBadRequest {
+		t.Fatalf("Execute() error = %v, want a 400 status error", err)
+	}
+	if len(*systems) != 1 {
+		t.Fatalf("upstream calls = %d, want 1", len(*systems))
+	}
+}
+
+func TestClaudeExecutor_SystemAsStringAttributeFlattensUpfront(t *testing.T) {
+	server, systems := newStringSystemOnlyServer(t)
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "key-123", "base_url": server.URL, "system_as_string": "true"}}
+
+	if err := executeClaudeWithSystemBlocks(t, auth, "claude-3-5-sonnet-20241022"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(*systems) != 1 || (*systems)[0].Type != gjson.String {
+		t.Fatalf("upstream systems = %v, want one call with a string system", *systems)
+	}
+}
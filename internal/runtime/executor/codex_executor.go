@@ -6,10 +6,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -124,6 +126,58 @@ func patchCodexCompletedOutput(eventData []byte, outputItemsByIndex map[int64][]
 	return completedDataPatched
 }
 
+// collectCodexReasoningSummary records the reasoning summary text streamed for each
+// reasoning item, keyed by item ID and summary index. Deltas are appended; the done event
+// carries the full text and replaces them.
+func collectCodexReasoningSummary(eventType string, eventData []byte, summaries map[string]map[int64]string) {
+	itemID := gjson.GetBytes(eventData, "item_id").String()
+	if itemID == "" {
+		return
+	}
+	parts := summaries[itemID]
+	if parts == nil {
+		parts = make(map[int64]string)
+		summaries[itemID] = parts
+	}
+	index := gjson.GetBytes(eventData, "summary_index").Int()
+	switch eventType {
+	case "response.reasoning_summary_text.delta":
+		parts[index] += gjson.GetBytes(eventData, "delta").String()
+	case "response.reasoning_summary_text.done":
+		parts[index] = gjson.GetBytes(eventData, "text").String()
+	}
+}
+
+// foldCodexReasoningSummaries fills the summary of completed reasoning items that came
+// back without one with the summary text streamed for them, so non-stream clients that
+// asked for reasoning.summary receive it.
+func foldCodexReasoningSummaries(eventData []byte, summaries map[string]map[int64]string) []byte {
+	if len(summaries) == 0 {
+		return eventData
+	}
+	for i, item := range gjson.GetBytes(eventData, "response.output").Array() {
+		if item.Get("type").String() != "reasoning" || len(item.Get("summary").Array()) > 0 {
+			continue
+		}
+		parts := summaries[item.Get("id").String()]
+		if len(parts) == 0 {
+			continue
+		}
+		indexes := make([]int64, 0, len(parts))
+		for index := range parts {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(a, b int) bool { return indexes[a] < indexes[b] })
+		summary := []byte("[]")
+		for _, index := range indexes {
+			part, _ := sjson.SetBytes([]byte(`{"type":"summary_text","text":""}`), "text", parts[index])
+			summary, _ = sjson.SetRawBytes(summary, "-1", part)
+		}
+		eventData, _ = sjson.SetRawBytes(eventData, "response.output."+strconv.Itoa(i)+".summary", summary)
+	}
+	return eventData
+}
+
 func codexTerminalStreamContextLengthErr(eventData []byte) (statusErr, bool) {
 	streamErr, body, ok := codexTerminalStreamErr(eventData)
 	if !ok || !codexTerminalErrorIsContextLength(body) {
@@ -291,13 +345,13 @@ func NewCodexExecutor(cfg *config.Config) *CodexExecutor { return &CodexExecutor
 
 func (e *CodexExecutor) Identifier() string { return "codex" }
 
-func translateCodexRequestPair(from, to sdktranslator.Format, model string, originalPayload, payload []byte, stream bool) ([]byte, []byte) {
+func translateCodexRequestPair(ctx context.Context, from, to sdktranslator.Format, model string, originalPayload, payload []byte, stream bool) ([]byte, []byte) {
 	if bytes.Equal(originalPayload, payload) {
-		body := sdktranslator.TranslateRequest(from, to, model, payload, stream)
+		body := sdktranslator.TranslateRequestWithContext(ctx, from, to, model, payload, stream)
 		return body, body
 	}
 	originalTranslated := sdktranslator.TranslateRequest(from, to, model, originalPayload, stream)
-	body := sdktranslator.TranslateRequest(from, to, model, payload, stream)
+	body := sdktranslator.TranslateRequestWithContext(ctx, from, to, model, payload, stream)
 	return originalTranslated, body
 }
 
@@ -1126,16 +1180,16 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		originalPayloadSource = opts.OriginalRequest
 	}
 	originalPayload := originalPayloadSource
-	originalTranslated, body := translateCodexRequestPair(from, to, baseModel, originalPayload, req.Payload, false)
+	originalTranslated, body := translateCodexRequestPair(ctx, from, to, baseModel, originalPayload, req.Payload, false)
 
-	body, err = thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	body, err = thinking.ApplyThinkingWithTrace(ctx, body, req.Model, from.String(), to.String(), e.Identifier())
 	if err != nil {
 		return resp, err
 	}
 
 	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
 	requestPath := helps.PayloadRequestPath(opts)
-	body = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
+	body = helps.ApplyPayloadConfigWithTrace(ctx, e.cfg, e.Identifier(), baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
 	body = helps.SetStringIfDifferent(body, "model", baseModel)
 	body = helps.SetBoolIfDifferent(body, "stream", true)
 	body, _ = sjson.DeleteBytes(body, "previous_response_id")
@@ -1181,7 +1235,7 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		AuthType:  authType,
 		AuthValue: authValue,
 	})
-	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, 0)
+	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 	httpClient = reporter.TrackHTTPClient(httpClient)
 	httpResp, err := httpClient.Do(httpReq)
 	if err != nil {
@@ -1205,13 +1259,22 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		err = newCodexStatusErr(httpResp.StatusCode, b)
 		return resp, err
 	}
-	data, errRead := io.ReadAll(httpResp.Body)
+	// A transport error after response.completed is ignored below, so only the size limit
+	// fails the read here.
+	data, errRead := readUpstreamResponseBody(e.cfg, httpResp.Body)
+	var errLimit responseTooLargeError
+	if errors.As(errRead, &errLimit) {
+		helps.RecordAPIResponseError(ctx, e.cfg, errLimit)
+		err = errLimit
+		return resp, err
+	}
 	upstreamData := applyCodexIdentityConfuseResponsePayload(data, identityState)
 	helps.AppendAPIResponseChunk(ctx, e.cfg, upstreamData)
 
-	lines := bytes.Split(upstreamData, []byte("\n"))
+	lines := bytes.Split(helps.NormalizeSSE(upstreamData), []byte("\n"))
 	outputItemsByIndex := make(map[int64][]byte)
 	var outputItemsFallback [][]byte
+	reasoningSummaries := make(map[string]map[int64]string)
 	for _, line := range lines {
 		if !bytes.HasPrefix(line, dataTag) {
 			continue
@@ -1228,6 +1291,11 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 			return resp, err
 		}
 
+		if eventType == "response.reasoning_summary_text.delta" || eventType == "response.reasoning_summary_text.done" {
+			collectCodexReasoningSummary(eventType, eventData, reasoningSummaries)
+			continue
+		}
+
 		if eventType == "response.output_item.done" {
 			itemResult := gjson.GetBytes(eventData, "item")
 			if !itemResult.Exists() || itemResult.Type != gjson.JSON {
@@ -1252,6 +1320,7 @@ func (e *CodexExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth, re
 		publishCodexImageToolUsage(ctx, reporter, body, eventData)
 
 		completedData := patchCodexCompletedOutput(eventData, outputItemsByIndex, outputItemsFallback)
+		completedData = foldCodexReasoningSummaries(completedData, reasoningSummaries)
 		if eventType == "response.completed" {
 			cacheCodexReasoningReplayFromCompleted(replayScope, completedData)
 		}
@@ -1293,16 +1362,16 @@ func (e *CodexExecutor) executeCompact(ctx context.Context, auth *cliproxyauth.A
 		originalPayloadSource = opts.OriginalRequest
 	}
 	originalPayload := originalPayloadSource
-	originalTranslated, body := translateCodexRequestPair(from, to, baseModel, originalPayload, req.Payload, false)
+	originalTranslated, body := translateCodexRequestPair(ctx, from, to, baseModel, originalPayload, req.Payload, false)
 
-	body, err = thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	body, err = thinking.ApplyThinkingWithTrace(ctx, body, req.Model, from.String(), to.String(), e.Identifier())
 	if err != nil {
 		return resp, err
 	}
 
 	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
 	requestPath := helps.PayloadRequestPath(opts)
-	body = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
+	body = helps.ApplyPayloadConfigWithTrace(ctx, e.cfg, e.Identifier(), baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
 	body = helps.SetStringIfDifferent(body, "model", baseModel)
 	body, _ = sjson.DeleteBytes(body, "stream")
 	body = normalizeCodexInstructions(body)
@@ -1336,7 +1405,7 @@ func (e *CodexExecutor) executeCompact(ctx context.Context, auth *cliproxyauth.A
 		AuthType:  authType,
 		AuthValue: authValue,
 	})
-	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, 0)
+	httpClient := helps.NewUtlsHTTPClient(ctx, e.cfg, auth, helps.ProviderRequestTimeout(e.cfg, auth))
 	httpClient = reporter.TrackHTTPClient(httpClient)
 	httpResp, err := httpClient.Do(httpReq)
 	if err != nil {
@@ -1357,7 +1426,7 @@ func (e *CodexExecutor) executeCompact(ctx context.Context, auth *cliproxyauth.A
 		err = newCodexStatusErr(httpResp.StatusCode, b)
 		return resp, err
 	}
-	data, err := io.ReadAll(httpResp.Body)
+	data, err := readUpstreamResponseBody(e.cfg, httpResp.Body)
 	if err != nil {
 		helps.RecordAPIResponseError(ctx, e.cfg, err)
 		return resp, err
@@ -1398,16 +1467,16 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 		originalPayloadSource = opts.OriginalRequest
 	}
 	originalPayload := originalPayloadSource
-	originalTranslated, body := translateCodexRequestPair(from, to, baseModel, originalPayload, req.Payload, true)
+	originalTranslated, body := translateCodexRequestPair(ctx, from, to, baseModel, originalPayload, req.Payload, true)
 
-	body, err = thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	body, err = thinking.ApplyThinkingWithTrace(ctx, body, req.Model, from.String(), to.String(), e.Identifier())
 	if err != nil {
 		return nil, err
 	}
 
 	requestedModel := helps.PayloadRequestedModel(opts, req.Model)
 	requestPath := helps.PayloadRequestPath(opts)
-	body = helps.ApplyPayloadConfigWithRequest(e.cfg, baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
+	body = helps.ApplyPayloadConfigWithTrace(ctx, e.cfg, e.Identifier(), baseModel, to.String(), from.String(), "", body, originalTranslated, requestedModel, requestPath, opts.Headers)
 	body, _ = sjson.DeleteBytes(body, "previous_response_id")
 	body, _ = sjson.DeleteBytes(body, "generate")
 	body, _ = sjson.DeleteBytes(body, "prompt_cache_retention")
@@ -1487,15 +1556,24 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 				log.Errorf("codex executor: close response body error: %v", errClose)
 			}
 		}()
-		scanner := bufio.NewScanner(httpResp.Body)
+		scanner := bufio.NewScanner(helps.NewSSEReader(httpResp.Body))
 		scanner.Buffer(nil, 52_428_800) // 50MB
 		claudeInputTokens := helps.NewClaudeInputTokenState(from, to, responseFormat, originalPayload)
 		var param any
 		outputItemsByIndex := make(map[int64][]byte)
 		var outputItemsFallback [][]byte
+		var streamUsage helps.StreamUsageBuffer
+		forwarding := true
 		for scanner.Scan() {
 			line := applyCodexIdentityConfuseResponsePayload(scanner.Bytes(), identityState)
 			helps.AppendAPIResponseChunk(ctx, e.cfg, line)
+			if !forwarding {
+				// The client is gone; keep draining buffered events for usage only.
+				if bytes.HasPrefix(line, dataTag) {
+					streamUsage.Observe(helps.ParseCodexUsage(bytes.TrimSpace(line[5:])))
+				}
+				continue
+			}
 			translatedLine := bytes.Clone(line)
 			terminalSuccess := false
 
@@ -1539,17 +1617,14 @@ func (e *CodexExecutor) ExecuteStream(ctx context.Context, auth *cliproxyauth.Au
 
 			translatedLine = applyCodexIdentityExposeResponsePayload(translatedLine, identityState)
 			chunks := helps.TranslateStreamWithClaudeInputTokens(ctx, to, responseFormat, req.Model, originalPayload, body, translatedLine, &param, claudeInputTokens)
-			for i := range chunks {
-				select {
-				case out <- cliproxyexecutor.StreamChunk{Payload: chunks[i]}:
-				case <-ctx.Done():
-					return
-				}
-			}
+			forwarding = helps.SendStreamChunks(ctx, out, chunks)
 			if terminalSuccess {
 				return
 			}
 		}
+		if reporter.PublishClientCancelled(ctx, &streamUsage) {
+			return
+		}
 		if errScan := scanner.Err(); errScan != nil {
 			if ctx.Err() != nil {
 				return
@@ -1573,9 +1648,9 @@ func (e *CodexExecutor) CountTokens(ctx context.Context, auth *cliproxyauth.Auth
 	from := opts.SourceFormat
 	responseFormat := cliproxyexecutor.ResponseFormatOrSource(opts)
 	to := sdktranslator.FromString("codex")
-	body := sdktranslator.TranslateRequest(from, to, baseModel, req.Payload, false)
+	body := sdktranslator.TranslateRequestWithContext(ctx, from, to, baseModel, req.Payload, false)
 
-	body, err := thinking.ApplyThinking(body, req.Model, from.String(), to.String(), e.Identifier())
+	body, err := thinking.ApplyThinkingWithTrace(ctx, body, req.Model, from.String(), to.String(), e.Identifier())
 	if err != nil {
 		return cliproxyexecutor.Response{}, err
 	}
@@ -2034,6 +2109,7 @@ func applyCodexHeadersFromSources(r *http.Request, auth *cliproxyauth.Auth, toke
 		attrs = auth.Attributes
 	}
 	util.ApplyCustomHeadersFromAttrs(r, attrs)
+	helps.ApplyForwardedClientHeaders(r.Context(), r.Header, attrs)
 }
 
 func newCodexStatusErr(statusCode int, body []byte) statusErr {
@@ -0,0 +1,132 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+// codexReasoningSummaryStream streams a reasoning summary in two deltas, then a reasoning
+// item without summary and a completion with empty output, as the Codex backend does.
+const codexReasoningSummaryStream = `data: {"type":"response.reasoning_summary_part.added","item_id":"rs_1","output_index":0,"summary_index":0,"part":{"type":"summary_text","text":""}}
+
+data: {"type":"response.reasoning_summary_text.delta","item_id":"rs_1","output_index":0,"summary_index":0,"delta":"Weighing "}
+
+data: {"type":"response.reasoning_summary_text.delta","item_id":"rs_1","output_index":0,"summary_index":0,"delta":"the options."}
+
+data: {"type":"response.output_item.done","output_index":0,"item":{"id":"rs_1","type":"reasoning","summary":[],"encrypted_content":"enc"}}
+
+data: {"type":"response.output_item.done","output_index":1,"item":{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"output_text","text":"ok"}]}}
+
+data: {"type":"response.completed","response":{"id":"resp_1","object":"response","status":"completed","model":"gpt-5.5","output":[],"usage":{"input_tokens":8,"output_tokens":20,"total_tokens":28}}}
+
+`
+
+func newCodexReasoningSummaryServer(t *testing.T, upstreamBody *[]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(codexReasoningSummaryStream))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCodexExecutorExecuteKeepsStoreAndReasoningSummary(t *testing.T) {
+	var upstreamBody []byte
+	server := newCodexReasoningSummaryServer(t, &upstreamBody)
+
+	executor := NewCodexExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"base_url": server.URL, "api_key": "test"}}
+	resp, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gpt-5.5",
+		Payload: []byte(`{"model":"gpt-5.5","store":false,"reasoning":{"effort":"high","summary":"detailed"},"input":"hello"}`),
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FromString("openai-response"),
+		Stream:       false,
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+
+	if store := gjson.GetBytes(upstreamBody, "store"); store.Type != gjson.False {
+		t.Fatalf("upstream store = %s, want false. Body: %s", store.Raw, upstreamBody)
+	}
+	if got := gjson.GetBytes(upstreamBody, "reasoning.summary").String(); got != "detailed" {
+		t.Fatalf("upstream reasoning.summary = %q, want detailed. Body: %s", got, upstreamBody)
+	}
+	if got := gjson.GetBytes(resp.Payload, "output.0.summary.0.text").String(); got != "Weighing the options." {
+		t.Fatalf("output.0.summary.0.text = %q, want the streamed summary. Payload: %s", got, resp.Payload)
+	}
+	if got := gjson.GetBytes(resp.Payload, "output.1.content.0.text").String(); got != "ok" {
+		t.Fatalf("output.1 text = %q, want ok. Payload: %s", got, resp.Payload)
+	}
+}
+
+func TestCodexExecutorExecuteFoldsReasoningSummaryForChatClients(t *testing.T) {
+	var upstreamBody []byte
+	server := newCodexReasoningSummaryServer(t, &upstreamBody)
+
+	executor := NewCodexExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"base_url": server.URL, "api_key": "test"}}
+	resp, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gpt-5.5",
+		Payload: []byte(`{"model":"gpt-5.5","reasoning_effort":"high","messages":[{"role":"user","content":"hello"}]}`),
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FromString("openai"),
+		Stream:       false,
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if got := gjson.GetBytes(resp.Payload, "choices.0.message.reasoning_content").String(); got != "Weighing the options." {
+		t.Fatalf("reasoning_content = %q, want the streamed summary. Payload: %s", got, resp.Payload)
+	}
+}
+
+func TestCodexExecutorExecuteStreamForwardsReasoningSummaryDeltas(t *testing.T) {
+	var upstreamBody []byte
+	server := newCodexReasoningSummaryServer(t, &upstreamBody)
+
+	executor := NewCodexExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"base_url": server.URL, "api_key": "test"}}
+	result, err := executor.ExecuteStream(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gpt-5.5",
+		Payload: []byte(`{"model":"gpt-5.5","store":false,"reasoning":{"summary":"detailed"},"input":"hello"}`),
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FromString("openai-response"),
+		Stream:       true,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream error: %v", err)
+	}
+	var deltas []string
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		for _, line := range strings.Split(string(chunk.Payload), "\n") {
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if gjson.Get(data, "type").String() == "response.reasoning_summary_text.delta" {
+				deltas = append(deltas, gjson.Get(data, "delta").String())
+			}
+		}
+	}
+	if got := strings.Join(deltas, ""); got != "Weighing the options." {
+		t.Fatalf("streamed summary deltas = %q, want the upstream summary", got)
+	}
+	if got := gjson.GetBytes(upstreamBody, "reasoning.summary").String(); got != "detailed" {
+		t.Fatalf("upstream reasoning.summary = %q, want detailed", got)
+	}
+}
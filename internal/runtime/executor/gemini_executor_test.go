@@ -3,12 +3,16 @@ package executor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
 	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
@@ -992,3 +996,178 @@ func TestGeminiExecutorNativeInteractionsResponsesStreamEmitsDone(t *testing.T)
 		t.Fatal("Responses [DONE] chunk not found")
 	}
 }
+
+func TestGeminiExecutorRejectsOpenAILogitBias(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "test-key", "base_url": server.URL},
+	}
+	req := cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash",
+		Payload: []byte(`{"model":"gemini-2.5-flash","messages":[{"role":"user","content":"hi"}],"logit_bias":{"50256":-100}}`),
+	}
+
+	_, errExecute := exec.Execute(context.Background(), auth, req, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI})
+	if errExecute == nil {
+		t.Fatal("Execute() error = nil, want unsupported parameter error")
+	}
+	var status statusErr
+	if !errors.As(errExecute, &status) || status.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("Execute() error = %v, want 400 statusErr", errExecute)
+	}
+	if got := gjson.Get(status.Error(), "error.param").String(); got != "logit_bias" {
+		t.Fatalf("error.param = %q, want logit_bias; error = %s", got, status.Error())
+	}
+	if got := gjson.Get(status.Error(), "error.type").String(); got != "invalid_request_error" {
+		t.Fatalf("error.type = %q, want invalid_request_error", got)
+	}
+	if called {
+		t.Fatal("upstream must not be called for unsupported parameters")
+	}
+}
+
+func TestGeminiExecutorForwardsSeedAndLogprobs(t *testing.T) {
+	var upstreamBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "test-key", "base_url": server.URL},
+	}
+	req := cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash",
+		Payload: []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"seed":42,"responseLogprobs":true,"logprobs":3}}`),
+	}
+
+	if _, errExecute := exec.Execute(context.Background(), auth, req, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini}); errExecute != nil {
+		t.Fatalf("Execute() error = %v", errExecute)
+	}
+	if got := gjson.GetBytes(upstreamBody, "generationConfig.seed").Int(); got != 42 {
+		t.Fatalf("generationConfig.seed = %d, want 42; body = %s", got, upstreamBody)
+	}
+	if !gjson.GetBytes(upstreamBody, "generationConfig.responseLogprobs").Bool() {
+		t.Fatalf("generationConfig.responseLogprobs missing; body = %s", upstreamBody)
+	}
+	if got := gjson.GetBytes(upstreamBody, "generationConfig.logprobs").Int(); got != 3 {
+		t.Fatalf("generationConfig.logprobs = %d, want 3; body = %s", got, upstreamBody)
+	}
+}
+
+func TestGeminiExecutorRecordsTransformTrace(t *testing.T) {
+	var upstreamBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":1,"totalTokenCount":2}}`))
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{
+		SDKConfig: config.SDKConfig{RequestLog: true},
+		Payload: config.PayloadConfig{
+			Default: []config.PayloadRule{
+				{
+					Models: []config.PayloadModelRule{{Name: "gemini-2.5-flash"}},
+					Params: map[string]any{"generationConfig.topK": 10},
+				},
+				{
+					Models: []config.PayloadModelRule{{Name: "gemini-2.5-pro", Protocol: "gemini"}},
+					Params: map[string]any{"generationConfig.temperature": 0.5},
+				},
+			},
+			Override: []config.PayloadRule{
+				{
+					Models: []config.PayloadModelRule{{Name: "gemini-2.5-*"}},
+					Params: map[string]any{"generationConfig.topP": 0.9},
+				},
+			},
+		},
+	})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{
+		"api_key":  "test-key",
+		"base_url": server.URL,
+	}}
+	req := cliproxyexecutor.Request{
+		Model:   "gemini-2.5-pro(100000)",
+		Payload: []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`),
+	}
+
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	trace := transformtrace.New()
+	ctx := transformtrace.NewContext(context.WithValue(context.Background(), "gin", ginCtx), trace)
+	if _, err := exec.Execute(ctx, auth, req, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []string{
+		"thinking: suffix budget=100000 clamped to budget=32768",
+		"payload.default[1]",
+		"payload.override[0]",
+	}
+	if got := trace.Steps(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("trace = %q, want %q", got, want)
+	}
+	if got := gjson.GetBytes(upstreamBody, "generationConfig.thinkingConfig.thinkingBudget").Int(); got != 32768 {
+		t.Fatalf("thinkingBudget = %d, want 32768; body=%s", got, upstreamBody)
+	}
+	apiRequest, _ := ginCtx.Get("API_REQUEST")
+	logged, _ := apiRequest.([]byte)
+	for _, step := range want {
+		if !bytes.Contains(logged, []byte("  - "+step+"\n")) {
+			t.Fatalf("request log misses %q:\n%s", step, logged)
+		}
+	}
+}
+
+func TestGeminiExecutorForwardsCachedContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  sdktranslator.Format
+		payload string
+	}{
+		{name: "gemini", format: sdktranslator.FormatGemini, payload: `{"cachedContent":"cachedContents/abc123","contents":[{"role":"user","parts":[{"text":"hi"}]}]}`},
+		{name: "openai", format: sdktranslator.FormatOpenAI, payload: `{"model":"gemini-2.5-flash","messages":[{"role":"user","content":"hi"}],"extra_body":{"google":{"cached_content":"cachedContents/abc123"}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upstreamBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				upstreamBody, _ = io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":1000,"cachedContentTokenCount":900,"candidatesTokenCount":1,"totalTokenCount":1001}}`))
+			}))
+			defer server.Close()
+
+			exec := NewGeminiExecutor(&config.Config{})
+			auth := &cliproxyauth.Auth{Attributes: map[string]string{
+				"api_key":  "test-key",
+				"base_url": server.URL,
+			}}
+			_, err := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{
+				Model:   "gemini-2.5-flash",
+				Payload: []byte(tt.payload),
+			}, cliproxyexecutor.Options{SourceFormat: tt.format})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := gjson.GetBytes(upstreamBody, "cachedContent").String(); got != "cachedContents/abc123" {
+				t.Fatalf("cachedContent = %q, want cachedContents/abc123; body=%s", got, upstreamBody)
+			}
+		})
+	}
+}
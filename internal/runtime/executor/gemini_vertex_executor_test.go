@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"golang.org/x/oauth2"
+)
+
+// newVertexTokenServer verifies the JWT bearer assertion of every token request against
+// key and answers with a numbered access token.
+func newVertexTokenServer(t *testing.T, key *rsa.PrivateKey, clientEmail string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var mints atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if errParse := r.ParseForm(); errParse != nil {
+			t.Errorf("parse token form: %v", errParse)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", got)
+		}
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("assertion has %d parts, want 3", len(parts))
+			http.Error(w, "bad assertion", http.StatusBadRequest)
+			return
+		}
+		signature, errDecode := base64.RawURLEncoding.DecodeString(parts[2])
+		if errDecode != nil {
+			t.Errorf("decode signature: %v", errDecode)
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if errVerify := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); errVerify != nil {
+			t.Errorf("assertion signature does not verify: %v", errVerify)
+		}
+		var header, claims map[string]any
+		headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		_ = json.Unmarshal(headerJSON, &header)
+		_ = json.Unmarshal(claimsJSON, &claims)
+		if header["alg"] != "RS256" {
+			t.Errorf("assertion alg = %v, want RS256", header["alg"])
+		}
+		if claims["iss"] != clientEmail {
+			t.Errorf("assertion iss = %v, want %s", claims["iss"], clientEmail)
+		}
+		if claims["scope"] != "https://www.googleapis.com/auth/cloud-platform" {
+			t.Errorf("assertion scope = %v", claims["scope"])
+		}
+		if claims["aud"] != server.URL+"/token" {
+			t.Errorf("assertion aud = %v, want %s/token", claims["aud"], server.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"vertex-token-%d","token_type":"Bearer","expires_in":3600}`, mints.Add(1))
+	}))
+	t.Cleanup(server.Close)
+	return server, &mints
+}
+
+const testVertexClientEmail = "vertex@test-project.iam.gserviceaccount.com"
+
+func testVertexServiceAccount(t *testing.T, key *rsa.PrivateKey, tokenURI string) []byte {
+	t.Helper()
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	saJSON, errMarshal := json.Marshal(map[string]any{
+		"type":         "service_account",
+		"project_id":   "test-project",
+		"private_key":  string(keyPEM),
+		"client_email": testVertexClientEmail,
+		"token_uri":    tokenURI,
+	})
+	if errMarshal != nil {
+		t.Fatalf("marshal service account: %v", errMarshal)
+	}
+	return saJSON
+}
+
+func testVertexKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, errKey := rsa.GenerateKey(rand.Reader, 2048)
+	if errKey != nil {
+		t.Fatalf("generate key: %v", errKey)
+	}
+	return key
+}
+
+func TestVertexAccessTokenSignsAssertionAndCachesToken(t *testing.T) {
+	key := testVertexKey(t)
+	server, mints := newVertexTokenServer(t, key, testVertexClientEmail)
+	saJSON := testVertexServiceAccount(t, key, server.URL+"/token")
+
+	cfg := &config.Config{}
+	token, errToken := vertexAccessToken(context.Background(), cfg, nil, saJSON)
+	if errToken != nil {
+		t.Fatalf("vertexAccessToken() error = %v", errToken)
+	}
+	if token != "vertex-token-1" {
+		t.Fatalf("token = %q, want vertex-token-1", token)
+	}
+	if token, _ = vertexAccessToken(context.Background(), cfg, nil, saJSON); token != "vertex-token-1" || mints.Load() != 1 {
+		t.Fatalf("second call returned %q after %d mints, want the cached token", token, mints.Load())
+	}
+
+	// A token inside the refresh skew is renewed.
+	sum := sha256.Sum256(saJSON)
+	vertexTokensMu.Lock()
+	vertexTokens[hex.EncodeToString(sum[:])].Expiry = time.Now().Add(vertexTokenRefreshSkew / 2)
+	vertexTokensMu.Unlock()
+	if token, _ = vertexAccessToken(context.Background(), cfg, nil, saJSON); token != "vertex-token-2" || mints.Load() != 2 {
+		t.Fatalf("token near expiry returned %q after %d mints, want a renewed token", token, mints.Load())
+	}
+}
+
+func TestVertexAccessTokenMintIgnoresCallerCancellationAndPrunesExpired(t *testing.T) {
+	key := testVertexKey(t)
+	server, mints := newVertexTokenServer(t, key, testVertexClientEmail)
+	saJSON := testVertexServiceAccount(t, key, server.URL+"/token")
+
+	vertexTokensMu.Lock()
+	vertexTokens["rotated-service-account"] = &oauth2.Token{AccessToken: "old", Expiry: time.Now().Add(-time.Minute)}
+	vertexTokensMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	token, errToken := vertexAccessToken(ctx, &config.Config{}, nil, saJSON)
+	if errToken != nil || token != "vertex-token-1" || mints.Load() != 1 {
+		t.Fatalf("vertexAccessToken() = %q, %v after %d mints, want the shared mint to finish for a cancelled caller", token, errToken, mints.Load())
+	}
+	vertexTokensMu.Lock()
+	_, stale := vertexTokens["rotated-service-account"]
+	vertexTokensMu.Unlock()
+	if stale {
+		t.Fatal("expired token of another service account was kept")
+	}
+}
+
+func TestVertexModelURL(t *testing.T) {
+	cases := []struct {
+		location string
+		want     string
+	}{
+		{"us-central1", "https://us-central1-aiplatform.googleapis.com/v1/projects/proj/locations/us-central1/publishers/google/models/gemini-2.5-pro:generateContent"},
+		{"europe-west4", "https://europe-west4-aiplatform.googleapis.com/v1/projects/proj/locations/europe-west4/publishers/google/models/gemini-2.5-pro:generateContent"},
+		{"global", "https://aiplatform.googleapis.com/v1/projects/proj/locations/global/publishers/google/models/gemini-2.5-pro:generateContent"},
+	}
+	for _, tc := range cases {
+		if got := vertexModelURL("proj", tc.location, "gemini-2.5-pro", "generateContent"); got != tc.want {
+			t.Errorf("vertexModelURL(%q) = %s, want %s", tc.location, got, tc.want)
+		}
+	}
+}
+
+func TestVertexCredsPreferAuthAttributes(t *testing.T) {
+	saJSON := testVertexServiceAccount(t, testVertexKey(t), "https://oauth2.googleapis.com/token")
+	var sa map[string]any
+	_ = json.Unmarshal(saJSON, &sa)
+	auth := &cliproxyauth.Auth{
+		Metadata: map[string]any{"project_id": "file-project", "location": "us-east1", "service_account": sa},
+	}
+
+	projectID, location, _, errCreds := vertexCreds(auth)
+	if errCreds != nil || projectID != "file-project" || location != "us-east1" {
+		t.Fatalf("vertexCreds() = %q, %q, %v; want the file values", projectID, location, errCreds)
+	}
+
+	auth.Attributes = map[string]string{"project_id": "attr-project", "location": "asia-northeast1"}
+	projectID, location, _, errCreds = vertexCreds(auth)
+	if errCreds != nil || projectID != "attr-project" || location != "asia-northeast1" {
+		t.Fatalf("vertexCreds() = %q, %q, %v; want the attribute values", projectID, location, errCreds)
+	}
+}
@@ -0,0 +1,66 @@
+package helps
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// forwardClientHeadersAttr is the auth attribute holding the comma-separated
+// forward-client-headers allowlist of the provider entry the auth was built from.
+const forwardClientHeadersAttr = "forward_client_headers"
+
+// deniedForwardHeaders are never copied from clients, whatever the allowlist says: they
+// carry credentials, describe the body the executor built, or belong to the connection.
+var deniedForwardHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"X-Api-Key":           {},
+	"X-Goog-Api-Key":      {},
+	"Api-Key":             {},
+	"Cookie":              {},
+	"Set-Cookie":          {},
+	"Host":                {},
+	"Content-Type":        {},
+	"Content-Length":      {},
+	"Content-Encoding":    {},
+	"Accept-Encoding":     {},
+	"Transfer-Encoding":   {},
+	"Connection":          {},
+	"Keep-Alive":          {},
+	"Proxy-Connection":    {},
+	"Te":                  {},
+	"Trailer":             {},
+	"Upgrade":             {},
+}
+
+// ForwardHeaderDenied reports whether name may never be forwarded from clients.
+func ForwardHeaderDenied(name string) bool {
+	_, denied := deniedForwardHeaders[http.CanonicalHeaderKey(strings.TrimSpace(name))]
+	return denied
+}
+
+// ApplyForwardedClientHeaders copies the inbound client headers allowlisted in attrs
+// onto headers. Call it after the executor has set its own headers: allowlisted
+// headers replace them, except for the denied ones, which are always kept.
+func ApplyForwardedClientHeaders(ctx context.Context, headers http.Header, attrs map[string]string) {
+	allowlist := strings.TrimSpace(attrs[forwardClientHeadersAttr])
+	if headers == nil || allowlist == "" || ctx == nil {
+		return
+	}
+	ginCtx := ginContextFrom(ctx)
+	if ginCtx == nil || ginCtx.Request == nil {
+		return
+	}
+	for _, name := range strings.Split(allowlist, ",") {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" || ForwardHeaderDenied(name) {
+			continue
+		}
+		values := ginCtx.Request.Header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		headers[name] = append([]string(nil), values...)
+	}
+}
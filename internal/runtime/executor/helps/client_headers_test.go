@@ -0,0 +1,65 @@
+package helps
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestApplyForwardedClientHeaders(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ginCtx.Request.Header.Add("X-Tenant-Id", "tenant-a")
+	ginCtx.Request.Header.Add("X-Routing-Hint", "eu")
+	ginCtx.Request.Header.Add("X-Routing-Hint", "low-latency")
+	ginCtx.Request.Header.Set("X-Unlisted", "nope")
+	ginCtx.Request.Header.Set("Authorization", "Bearer client-key")
+	ginCtx.Request.Header.Set("Content-Type", "text/plain")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer upstream-key")
+	headers.Set("Content-Type", "application/json")
+	headers.Set("X-Tenant-Id", "configured")
+	attrs := map[string]string{forwardClientHeadersAttr: "x-tenant-id, X-Routing-Hint,Authorization,content-type,X-Missing"}
+
+	ApplyForwardedClientHeaders(ctx, headers, attrs)
+
+	if got := headers.Values("X-Tenant-Id"); len(got) != 1 || got[0] != "tenant-a" {
+		t.Fatalf("X-Tenant-Id = %q, want the client value", got)
+	}
+	if got := headers.Values("X-Routing-Hint"); len(got) != 2 || got[0] != "eu" || got[1] != "low-latency" {
+		t.Fatalf("X-Routing-Hint = %q, want both client values", got)
+	}
+	if headers.Get("X-Unlisted") != "" {
+		t.Fatal("X-Unlisted was forwarded although it is not allowlisted")
+	}
+	if _, ok := headers["X-Missing"]; ok {
+		t.Fatal("X-Missing was set although the client did not send it")
+	}
+	if got := headers.Get("Authorization"); got != "Bearer upstream-key" {
+		t.Fatalf("Authorization = %q, want the executor value", got)
+	}
+	if got := headers.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want the executor value", got)
+	}
+}
+
+func TestApplyForwardedClientHeadersWithoutAllowlist(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	ginCtx.Request.Header.Set("X-Tenant-Id", "tenant-a")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	headers := http.Header{}
+	ApplyForwardedClientHeaders(ctx, headers, nil)
+	ApplyForwardedClientHeaders(context.Background(), headers, map[string]string{forwardClientHeadersAttr: "X-Tenant-Id"})
+	if len(headers) != 0 {
+		t.Fatalf("headers = %v, want none forwarded", headers)
+	}
+}
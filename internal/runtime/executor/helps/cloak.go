@@ -0,0 +1,181 @@
+package helps
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// CloakSettings is the effective cloaking configuration of one request. It mirrors
+// config.CloakConfig after credential and per-key settings have been merged.
+type CloakSettings struct {
+	// Mode is "auto", "always" or "never"; empty means the credential did not set one.
+	Mode                  string
+	StrictMode            bool
+	SensitiveWords        []string
+	CacheUserID           bool
+	PreserveUserID        bool
+	SystemPrompt          string
+	ReverseSensitiveWords bool
+}
+
+// CloakSettingsFromAuth reads the cloak settings carried by a credential. Attributes take
+// precedence over the stored metadata (the raw OAuth/token JSON), so file-based credentials
+// can carry cloak settings without a matching API key config entry.
+func CloakSettingsFromAuth(auth *cliproxyauth.Auth) CloakSettings {
+	if auth == nil {
+		return CloakSettings{}
+	}
+	lookup := func(key string) string {
+		if auth.Attributes != nil {
+			if value := strings.TrimSpace(auth.Attributes[key]); value != "" {
+				return value
+			}
+		}
+		if auth.Metadata != nil {
+			if value, ok := auth.Metadata[key].(string); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+		return ""
+	}
+
+	settings := CloakSettings{
+		Mode:                  lookup("cloak_mode"),
+		StrictMode:            strings.EqualFold(lookup("cloak_strict_mode"), "true"),
+		CacheUserID:           strings.EqualFold(lookup("cloak_cache_user_id"), "true"),
+		PreserveUserID:        strings.EqualFold(lookup("cloak_preserve_user_id"), "true"),
+		SystemPrompt:          lookup("cloak_system_prompt"),
+		ReverseSensitiveWords: strings.EqualFold(lookup("cloak_reverse_sensitive_words"), "true"),
+	}
+	if words := lookup("cloak_sensitive_words"); words != "" {
+		settings.SensitiveWords = strings.Split(words, ",")
+		for i := range settings.SensitiveWords {
+			settings.SensitiveWords[i] = strings.TrimSpace(settings.SensitiveWords[i])
+		}
+	}
+	return settings
+}
+
+// WithConfig overlays a per-key cloak config onto s. Set fields of cfg win.
+func (s CloakSettings) WithConfig(cfg *config.CloakConfig) CloakSettings {
+	if cfg == nil {
+		return s
+	}
+	if mode := strings.TrimSpace(cfg.Mode); mode != "" {
+		s.Mode = mode
+	}
+	if cfg.StrictMode {
+		s.StrictMode = true
+	}
+	if len(cfg.SensitiveWords) > 0 {
+		s.SensitiveWords = cfg.SensitiveWords
+	}
+	if cfg.CacheUserID != nil {
+		s.CacheUserID = *cfg.CacheUserID
+	}
+	if cfg.PreserveUserID {
+		s.PreserveUserID = true
+	}
+	if prompt := strings.TrimSpace(cfg.SystemPrompt); prompt != "" {
+		s.SystemPrompt = prompt
+	}
+	if cfg.ReverseSensitiveWords {
+		s.ReverseSensitiveWords = true
+	}
+	return s
+}
+
+// Applies reports whether cloaking applies to a client with userAgent.
+func (s CloakSettings) Applies(userAgent string) bool {
+	return ShouldCloak(s.Mode, userAgent)
+}
+
+// ResponseWordMatcher returns the matcher used to restore sensitive words in responses,
+// or nil when cloaking does not apply to userAgent or reversal is not configured.
+func (s CloakSettings) ResponseWordMatcher(userAgent string) *SensitiveWordMatcher {
+	if !s.ReverseSensitiveWords || len(s.SensitiveWords) == 0 || !s.Applies(userAgent) {
+		return nil
+	}
+	return BuildSensitiveWordMatcher(s.SensitiveWords)
+}
+
+// TraceCloaking records in the transformation trace of ctx that cloaking applies to a
+// request from userAgent, together with the mode that enabled it.
+func TraceCloaking(ctx context.Context, settings CloakSettings, userAgent string) {
+	if !settings.Applies(userAgent) {
+		return
+	}
+	mode := strings.ToLower(strings.TrimSpace(settings.Mode))
+	if mode == "" {
+		mode = "auto"
+	}
+	if settings.StrictMode {
+		transformtrace.Record(ctx, "cloaking: mode=%s strict", mode)
+		return
+	}
+	transformtrace.Record(ctx, "cloaking: mode=%s", mode)
+}
+
+// TraceGeminiCloaking is TraceCloaking for Gemini requests, where cloaking only changes
+// the request when a system prompt or sensitive words are configured.
+func TraceGeminiCloaking(ctx context.Context, settings CloakSettings, userAgent string) {
+	if strings.TrimSpace(settings.SystemPrompt) == "" && len(settings.SensitiveWords) == 0 {
+		return
+	}
+	TraceCloaking(ctx, settings, userAgent)
+}
+
+// ApplyGeminiCloaking applies cloaking to a Gemini request: the configured system prompt is
+// injected into the system instruction and sensitive words are obfuscated. Strict mode
+// replaces the client's system instruction with the prompt instead of prepending it.
+// root is the path of the request object inside payload ("request" for Antigravity).
+func ApplyGeminiCloaking(payload []byte, root, userAgent string, settings CloakSettings) []byte {
+	if !settings.Applies(userAgent) {
+		return payload
+	}
+	if prompt := strings.TrimSpace(settings.SystemPrompt); prompt != "" {
+		payload = injectGeminiCloakSystemPrompt(payload, root, prompt, settings.StrictMode)
+	}
+	if len(settings.SensitiveWords) > 0 {
+		payload = ObfuscateGeminiSensitiveWords(payload, root, BuildSensitiveWordMatcher(settings.SensitiveWords))
+	}
+	return payload
+}
+
+// GeminiSystemInstructionKey returns the system instruction field used by a Gemini request,
+// preferring the snake_case form when the translator produced it.
+func GeminiSystemInstructionKey(payload []byte, root string) string {
+	prefix := ""
+	if root != "" {
+		prefix = root + "."
+	}
+	if gjson.GetBytes(payload, prefix+"system_instruction").Exists() {
+		return "system_instruction"
+	}
+	return "systemInstruction"
+}
+
+func injectGeminiCloakSystemPrompt(payload []byte, root, prompt string, strict bool) []byte {
+	path := GeminiSystemInstructionKey(payload, root)
+	if root != "" {
+		path = root + "." + path
+	}
+	part, _ := sjson.SetBytes([]byte(`{}`), "text", prompt)
+	parts := gjson.GetBytes(payload, path+".parts")
+	if strict || !parts.IsArray() || len(parts.Array()) == 0 {
+		instruction, _ := sjson.SetRawBytes([]byte(`{"role":"user","parts":[]}`), "parts.-1", part)
+		payload, _ = sjson.SetRawBytes(payload, path, instruction)
+		return payload
+	}
+	merged := append([]byte("["), part...)
+	merged = append(merged, ',')
+	merged = append(merged, parts.Raw[1:]...)
+	payload, _ = sjson.SetRawBytes(payload, path+".parts", merged)
+	return payload
+}
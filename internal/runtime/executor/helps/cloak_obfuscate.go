@@ -1,6 +1,7 @@
 package helps
 
 import (
+	"bytes"
 	"regexp"
 	"sort"
 	"strings"
@@ -16,6 +17,9 @@ const zeroWidthSpace = "\u200B"
 // SensitiveWordMatcher holds the compiled regex for matching sensitive words.
 type SensitiveWordMatcher struct {
 	regex *regexp.Regexp
+	// restore matches the obfuscated form of the words, with the zero-width space written
+	// either as raw UTF-8 or as a JSON escape.
+	restore *regexp.Regexp
 }
 
 // BuildSensitiveWordMatcher compiles a regex from the word list.
@@ -45,8 +49,11 @@ func BuildSensitiveWordMatcher(words []string) *SensitiveWordMatcher {
 
 	// Escape and join
 	escaped := make([]string, len(validWords))
+	obfuscated := make([]string, len(validWords))
 	for i, w := range validWords {
 		escaped[i] = regexp.QuoteMeta(w)
+		_, size := utf8.DecodeRuneInString(w)
+		obfuscated[i] = regexp.QuoteMeta(w[:size]) + `(?:\x{200B}|\\u200[bB])` + regexp.QuoteMeta(w[size:])
 	}
 
 	pattern := "(?i)" + strings.Join(escaped, "|")
@@ -54,8 +61,12 @@ func BuildSensitiveWordMatcher(words []string) *SensitiveWordMatcher {
 	if err != nil {
 		return nil
 	}
+	restore, err := regexp.Compile("(?i)" + strings.Join(obfuscated, "|"))
+	if err != nil {
+		return nil
+	}
 
-	return &SensitiveWordMatcher{regex: re}
+	return &SensitiveWordMatcher{regex: re, restore: restore}
 }
 
 // obfuscateWord inserts a zero-width space after the first grapheme.
@@ -81,6 +92,27 @@ func (m *SensitiveWordMatcher) obfuscateText(text string) string {
 	return m.regex.ReplaceAllStringFunc(text, obfuscateWord)
 }
 
+// RestoreSensitiveWords removes the zero-width spaces that obfuscation inserted into
+// sensitive words, so responses echoing those words show them unchanged. It works on raw
+// response bytes and handles both literal and JSON-escaped zero-width spaces. A word split
+// across two stream chunks is left as is.
+func (m *SensitiveWordMatcher) RestoreSensitiveWords(data []byte) []byte {
+	if m == nil || m.restore == nil || len(data) == 0 {
+		return data
+	}
+	if !bytes.Contains(data, []byte(zeroWidthSpace)) && !bytes.Contains(data, []byte(`\u200`)) {
+		return data
+	}
+	return m.restore.ReplaceAllFunc(data, func(match []byte) []byte {
+		for _, marker := range [][]byte{[]byte(zeroWidthSpace), []byte(`\u200b`), []byte(`\u200B`)} {
+			if index := bytes.Index(match, marker); index >= 0 {
+				return append(append([]byte(nil), match[:index]...), match[index+len(marker):]...)
+			}
+		}
+		return match
+	})
+}
+
 // ObfuscateSensitiveWords processes the payload and obfuscates sensitive words
 // in system blocks and message content.
 func ObfuscateSensitiveWords(payload []byte, matcher *SensitiveWordMatcher) []byte {
@@ -174,3 +206,44 @@ func obfuscateMessages(payload []byte, matcher *SensitiveWordMatcher) []byte {
 
 	return payload
 }
+
+// ObfuscateGeminiSensitiveWords obfuscates sensitive words in the text parts of a Gemini
+// request: the system instruction and every content turn. root is the path of the request
+// object inside payload, e.g. "request" for Antigravity, or empty for the Gemini API.
+func ObfuscateGeminiSensitiveWords(payload []byte, root string, matcher *SensitiveWordMatcher) []byte {
+	if matcher == nil || matcher.regex == nil {
+		return payload
+	}
+	prefix := ""
+	if root != "" {
+		prefix = root + "."
+	}
+	payload = obfuscateGeminiParts(payload, prefix+GeminiSystemInstructionKey(payload, root)+".parts", matcher)
+	contents := gjson.GetBytes(payload, prefix+"contents")
+	if !contents.IsArray() {
+		return payload
+	}
+	contents.ForEach(func(key, _ gjson.Result) bool {
+		payload = obfuscateGeminiParts(payload, prefix+"contents."+key.String()+".parts", matcher)
+		return true
+	})
+	return payload
+}
+
+func obfuscateGeminiParts(payload []byte, partsPath string, matcher *SensitiveWordMatcher) []byte {
+	parts := gjson.GetBytes(payload, partsPath)
+	if !parts.IsArray() {
+		return payload
+	}
+	parts.ForEach(func(key, part gjson.Result) bool {
+		text := part.Get("text")
+		if text.Type != gjson.String {
+			return true
+		}
+		if obfuscated := matcher.obfuscateText(text.String()); obfuscated != text.String() {
+			payload, _ = sjson.SetBytes(payload, partsPath+"."+key.String()+".text", obfuscated)
+		}
+		return true
+	})
+	return payload
+}
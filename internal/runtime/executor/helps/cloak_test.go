@@ -0,0 +1,126 @@
+package helps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"github.com/tidwall/gjson"
+)
+
+var cloakTestWords = []string{"proxy", "API"}
+
+func assertCloakedText(t *testing.T, payload []byte, path string) {
+	t.Helper()
+	got := gjson.GetBytes(payload, path).String()
+	if !strings.Contains(got, "p\u200Broxy") || !strings.Contains(got, "A\u200BPI") {
+		t.Fatalf("%s = %q, want both sensitive words obfuscated", path, got)
+	}
+}
+
+func TestSensitiveWordsObfuscatedInEveryPayloadShape(t *testing.T) {
+	matcher := BuildSensitiveWordMatcher(cloakTestWords)
+
+	claude := ObfuscateSensitiveWords([]byte(`{"system":"proxy API","messages":[{"role":"user","content":[{"type":"text","text":"proxy API"}]}]}`), matcher)
+	assertCloakedText(t, claude, "system")
+	assertCloakedText(t, claude, "messages.0.content.0.text")
+
+	settings := CloakSettings{Mode: "always", SensitiveWords: cloakTestWords}
+	gemini := ApplyGeminiCloaking([]byte(`{"systemInstruction":{"parts":[{"text":"proxy API"}]},"contents":[{"role":"user","parts":[{"text":"proxy API"}]}]}`), "", "", settings)
+	assertCloakedText(t, gemini, "systemInstruction.parts.0.text")
+	assertCloakedText(t, gemini, "contents.0.parts.0.text")
+
+	antigravity := ApplyGeminiCloaking([]byte(`{"model":"m","request":{"system_instruction":{"parts":[{"text":"proxy API"}]},"contents":[{"role":"user","parts":[{"text":"proxy API"}]}]}}`), "request", "", settings)
+	assertCloakedText(t, antigravity, "request.system_instruction.parts.0.text")
+	assertCloakedText(t, antigravity, "request.contents.0.parts.0.text")
+}
+
+func TestApplyGeminiCloakingSystemPrompt(t *testing.T) {
+	payload := []byte(`{"systemInstruction":{"parts":[{"text":"client rules"}]},"contents":[]}`)
+
+	out := ApplyGeminiCloaking(payload, "", "", CloakSettings{SystemPrompt: "cloak prompt"})
+	parts := gjson.GetBytes(out, "systemInstruction.parts").Array()
+	if len(parts) != 2 || parts[0].Get("text").String() != "cloak prompt" || parts[1].Get("text").String() != "client rules" {
+		t.Fatalf("non-strict mode should prepend the prompt, got %s", gjson.GetBytes(out, "systemInstruction").Raw)
+	}
+
+	out = ApplyGeminiCloaking(payload, "", "", CloakSettings{SystemPrompt: "cloak prompt", StrictMode: true})
+	parts = gjson.GetBytes(out, "systemInstruction.parts").Array()
+	if len(parts) != 1 || parts[0].Get("text").String() != "cloak prompt" {
+		t.Fatalf("strict mode should replace the system instruction, got %s", gjson.GetBytes(out, "systemInstruction").Raw)
+	}
+
+	out = ApplyGeminiCloaking([]byte(`{"request":{"contents":[]}}`), "request", "", CloakSettings{SystemPrompt: "cloak prompt"})
+	if got := gjson.GetBytes(out, "request.systemInstruction.parts.0.text").String(); got != "cloak prompt" {
+		t.Fatalf("missing system instruction should be created under the root, got %s", out)
+	}
+}
+
+func TestApplyGeminiCloakingRespectsMode(t *testing.T) {
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"proxy"}]}]}`)
+	if out := ApplyGeminiCloaking(payload, "", "", CloakSettings{Mode: "never", SensitiveWords: cloakTestWords}); string(out) != string(payload) {
+		t.Fatalf("mode never changed the payload: %s", out)
+	}
+	if out := ApplyGeminiCloaking(payload, "", "claude-cli/2.1.0 (external, cli)", CloakSettings{SensitiveWords: cloakTestWords}); string(out) != string(payload) {
+		t.Fatalf("auto mode cloaked a Claude Code client: %s", out)
+	}
+}
+
+func TestRestoreSensitiveWords(t *testing.T) {
+	matcher := BuildSensitiveWordMatcher(cloakTestWords)
+	cases := map[string]string{
+		"literal":       "data: {\"text\":\"p\u200Broxy and A\u200BPI\"}",
+		"escaped lower": `data: {"text":"p\u200broxy and A\u200bPI"}`,
+		"escaped upper": `data: {"text":"P\u200BROXY and a\u200Bpi"}`,
+	}
+	want := map[string]string{
+		"literal":       `data: {"text":"proxy and API"}`,
+		"escaped lower": `data: {"text":"proxy and API"}`,
+		"escaped upper": `data: {"text":"PROXY and api"}`,
+	}
+	for name, input := range cases {
+		if got := string(matcher.RestoreSensitiveWords([]byte(input))); got != want[name] {
+			t.Errorf("%s: got %q, want %q", name, got, want[name])
+		}
+	}
+
+	unrelated := []byte("keep \u200B here")
+	if got := matcher.RestoreSensitiveWords(unrelated); string(got) != string(unrelated) {
+		t.Fatalf("unrelated zero-width space was removed: %q", got)
+	}
+	var nilMatcher *SensitiveWordMatcher
+	if got := nilMatcher.RestoreSensitiveWords([]byte("p\u200Broxy")); string(got) != "p\u200Broxy" {
+		t.Fatalf("nil matcher changed data: %q", got)
+	}
+}
+
+func TestCloakSettingsPrecedenceAndResponseMatcher(t *testing.T) {
+	auth := &cliproxyauth.Auth{
+		Attributes: map[string]string{"cloak_mode": "always"},
+		Metadata: map[string]any{
+			"cloak_sensitive_words":         "proxy, API",
+			"cloak_system_prompt":           "from auth",
+			"cloak_reverse_sensitive_words": "true",
+		},
+	}
+	settings := CloakSettingsFromAuth(auth)
+	if settings.Mode != "always" || len(settings.SensitiveWords) != 2 || settings.SensitiveWords[1] != "API" || settings.SystemPrompt != "from auth" || !settings.ReverseSensitiveWords {
+		t.Fatalf("unexpected settings from auth: %+v", settings)
+	}
+
+	merged := settings.WithConfig(&config.CloakConfig{Mode: "never", SystemPrompt: "from key"})
+	if merged.Mode != "never" || merged.SystemPrompt != "from key" || len(merged.SensitiveWords) != 2 {
+		t.Fatalf("per-key config should override set fields only: %+v", merged)
+	}
+	if merged.ResponseWordMatcher("") != nil {
+		t.Fatal("response matcher should be nil when cloaking does not apply")
+	}
+	if settings.ResponseWordMatcher("") == nil {
+		t.Fatal("response matcher should be built when reversal is configured")
+	}
+	settings.ReverseSensitiveWords = false
+	if settings.ResponseWordMatcher("") != nil {
+		t.Fatal("response matcher should be nil without reverse-sensitive-words")
+	}
+}
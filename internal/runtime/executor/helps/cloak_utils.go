@@ -2,6 +2,7 @@ package helps
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"regexp"
 	"strings"
@@ -28,6 +29,15 @@ func isValidUserID(userID string) bool {
 	return userIDPattern.MatchString(userID)
 }
 
+// DeriveUserID hashes a client-supplied user ID into a Claude Code format user ID. The
+// result depends only on original, so the same client ID always yields the same value.
+func DeriveUserID(original string) string {
+	sum := sha256.Sum256([]byte(original))
+	accountUUID := uuid.NewSHA1(uuid.NameSpaceOID, []byte("account:"+original)).String()
+	sessionUUID := uuid.NewSHA1(uuid.NameSpaceOID, []byte("session:"+original)).String()
+	return "user_" + hex.EncodeToString(sum[:]) + "_account_" + accountUUID + "_session_" + sessionUUID
+}
+
 func GenerateFakeUserID() string {
 	return generateFakeUserID()
 }
@@ -0,0 +1,73 @@
+package helps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// GeminiStreamAltJSON is the alt value asking a Gemini-family upstream for
+// streamGenerateContent as one JSON array instead of SSE.
+const GeminiStreamAltJSON = "json"
+
+// GeminiStreamBody returns a reader for a streamGenerateContent response body
+// requested with alt. A JSON-array body (alt=json) is rewritten into one
+// "data: {...}" line per array element, so stream loops built around SSE
+// scanning handle both upstream formats. Other bodies are returned unchanged.
+func GeminiStreamBody(body io.Reader, alt string) io.Reader {
+	if alt != GeminiStreamAltJSON {
+		return body
+	}
+	return &geminiJSONArrayReader{dec: json.NewDecoder(body)}
+}
+
+// geminiJSONArrayReader decodes array elements as they arrive, so chunks are
+// forwarded while the upstream is still writing the array.
+type geminiJSONArrayReader struct {
+	dec     *json.Decoder
+	buf     bytes.Buffer
+	started bool
+	err     error
+}
+
+func (r *geminiJSONArrayReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	return r.buf.Read(p)
+}
+
+// next buffers the SSE line for the next array element. It returns io.EOF
+// after the closing bracket.
+func (r *geminiJSONArrayReader) next() error {
+	if !r.started {
+		tok, errToken := r.dec.Token()
+		if errToken != nil {
+			return errToken
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return fmt.Errorf("gemini stream: expected JSON array, got %v", tok)
+		}
+		r.started = true
+	}
+	if !r.dec.More() {
+		if _, errToken := r.dec.Token(); errToken != nil {
+			return errToken
+		}
+		return io.EOF
+	}
+	var element json.RawMessage
+	if errDecode := r.dec.Decode(&element); errDecode != nil {
+		return errDecode
+	}
+	r.buf.WriteString("data: ")
+	if errCompact := json.Compact(&r.buf, element); errCompact != nil {
+		return errCompact
+	}
+	r.buf.WriteString("\n\n")
+	return nil
+}
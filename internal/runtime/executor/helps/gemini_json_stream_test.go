@@ -0,0 +1,40 @@
+package helps
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestGeminiStreamBodyRewritesJSONArrayAsSSE(t *testing.T) {
+	upstream := "[{\n  \"candidates\": [{\"content\": {\"parts\": [{\"text\": \"hel\"}]}}]\n}\n,\r\n{\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"lo\"}]}}]}\n]"
+	got, errRead := io.ReadAll(GeminiStreamBody(strings.NewReader(upstream), GeminiStreamAltJSON))
+	if errRead != nil {
+		t.Fatalf("read: %v", errRead)
+	}
+	want := "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"hel\"}]}}]}\n\n" +
+		"data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"lo\"}]}}]}\n\n"
+	if string(got) != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+}
+
+func TestGeminiStreamBodyEmptyArray(t *testing.T) {
+	got, errRead := io.ReadAll(GeminiStreamBody(strings.NewReader("[]"), GeminiStreamAltJSON))
+	if errRead != nil || len(got) != 0 {
+		t.Fatalf("body = %q, err = %v; want empty", got, errRead)
+	}
+}
+
+func TestGeminiStreamBodyRejectsNonArray(t *testing.T) {
+	if _, errRead := io.ReadAll(GeminiStreamBody(strings.NewReader(`{"error":{}}`), GeminiStreamAltJSON)); errRead == nil {
+		t.Fatal("expected an error for a non-array body")
+	}
+}
+
+func TestGeminiStreamBodyLeavesSSEUntouched(t *testing.T) {
+	sse := strings.NewReader("data: {}\n\n")
+	if GeminiStreamBody(sse, "") != io.Reader(sse) {
+		t.Fatal("SSE body was wrapped")
+	}
+}
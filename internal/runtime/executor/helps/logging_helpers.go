@@ -14,6 +14,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/requeststore"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
@@ -40,6 +42,11 @@ type UpstreamRequestLog struct {
 	AuthLabel string
 	AuthType  string
 	AuthValue string
+	// RequestID is the proxy request ID; RecordAPIRequest fills it from ctx when empty.
+	RequestID string
+	// Transforms lists the request shaping steps of the attempt; RecordAPIRequest fills it
+	// from the transformation trace in ctx when empty.
+	Transforms []string
 }
 
 type upstreamAttempt struct {
@@ -62,6 +69,14 @@ func requestLogCaptureEnabled(cfg *config.Config) bool {
 
 // RecordAPIRequest stores the upstream request metadata in Gin context for request logging.
 func RecordAPIRequest(ctx context.Context, cfg *config.Config, info UpstreamRequestLog) {
+	if steps := transformtrace.EndAttempt(ctx); len(info.Transforms) == 0 {
+		info.Transforms = steps
+	}
+	authLabel := strings.TrimSpace(info.AuthLabel)
+	if authLabel == "" {
+		authLabel = strings.TrimSpace(info.AuthID)
+	}
+	requeststore.CaptureRequest(ctx, authLabel, info.Body)
 	if cfg == nil || cfg.CommercialMode {
 		return
 	}
@@ -69,6 +84,9 @@ func RecordAPIRequest(ctx context.Context, cfg *config.Config, info UpstreamRequ
 	if ginCtx == nil {
 		return
 	}
+	if info.RequestID == "" {
+		info.RequestID = requestIDFrom(ctx)
+	}
 	if !cfg.RequestLog {
 		deferAPIRequest(ginCtx, info)
 		return
@@ -177,9 +195,13 @@ func newAPIRequestLogBuilder(index int, info UpstreamRequestLog, timestamp time.
 	if info.Method != "" {
 		builder.WriteString(fmt.Sprintf("HTTP Method: %s\n", info.Method))
 	}
+	if info.RequestID != "" {
+		builder.WriteString(fmt.Sprintf("Request ID: %s\n", info.RequestID))
+	}
 	if auth := formatAuthInfo(info); auth != "" {
 		builder.WriteString(fmt.Sprintf("Auth: %s\n", auth))
 	}
+	writeTransforms(builder, info.Transforms)
 	builder.WriteString("\nHeaders:\n")
 	writeHeaders(builder, info.Headers)
 	builder.WriteString("\nBody:\n")
@@ -189,6 +211,7 @@ func newAPIRequestLogBuilder(index int, info UpstreamRequestLog, timestamp time.
 // RecordAPIResponseMetadata captures upstream response status/header information for the latest attempt.
 func RecordAPIResponseMetadata(ctx context.Context, cfg *config.Config, status int, headers http.Header) {
 	logging.SetResponseHeaders(ctx, headers)
+	requeststore.CaptureResponseStatus(ctx, status)
 	if !requestLogCaptureEnabled(cfg) {
 		return
 	}
@@ -242,13 +265,14 @@ func RecordAPIResponseError(ctx context.Context, cfg *config.Config, err error)
 
 // AppendAPIResponseChunk appends an upstream response chunk to Gin context for request logging.
 func AppendAPIResponseChunk(ctx context.Context, cfg *config.Config, chunk []byte) {
-	if !requestLogCaptureEnabled(cfg) {
-		return
-	}
 	data := bytes.TrimSpace(chunk)
 	if len(data) == 0 {
 		return
 	}
+	requeststore.CaptureResponseChunk(ctx, data)
+	if !requestLogCaptureEnabled(cfg) {
+		return
+	}
 	ginCtx := ginContextFrom(ctx)
 	if ginCtx == nil {
 		return
@@ -286,6 +310,9 @@ func AppendAPIResponseChunk(ctx context.Context, cfg *config.Config, chunk []byt
 
 // RecordAPIWebsocketRequest stores an upstream websocket request event in Gin context.
 func RecordAPIWebsocketRequest(ctx context.Context, cfg *config.Config, info UpstreamRequestLog) {
+	if steps := transformtrace.EndAttempt(ctx); len(info.Transforms) == 0 {
+		info.Transforms = steps
+	}
 	if !requestLogCaptureEnabled(cfg) {
 		return
 	}
@@ -303,6 +330,7 @@ func RecordAPIWebsocketRequest(ctx context.Context, cfg *config.Config, info Ups
 	if auth := formatAuthInfo(info); auth != "" {
 		builder.WriteString(fmt.Sprintf("Auth: %s\n", auth))
 	}
+	writeTransforms(builder, info.Transforms)
 	builder.WriteString("Headers:\n")
 	writeHeaders(builder, info.Headers)
 	builder.WriteString("\nBody:\n")
@@ -421,6 +449,27 @@ func RecordAPIWebsocketError(ctx context.Context, cfg *config.Config, stage stri
 	appendAPIWebsocketTimeline(ginCtx, []byte(builder.String()))
 }
 
+// ApplyRequestIDHeader sends the proxy request ID of r's context upstream in the
+// X-Request-Id header. Only providers known to tolerate extra headers should call it.
+func ApplyRequestIDHeader(r *http.Request) {
+	if r == nil {
+		return
+	}
+	if requestID := requestIDFrom(r.Context()); requestID != "" {
+		r.Header.Set(logging.RequestIDHeader, requestID)
+	}
+}
+
+func requestIDFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if requestID := logging.GetRequestID(ctx); requestID != "" {
+		return requestID
+	}
+	return logging.GetGinRequestID(ginContextFrom(ctx))
+}
+
 func ginContextFrom(ctx context.Context) *gin.Context {
 	ginCtx, _ := ctx.Value("gin").(*gin.Context)
 	return ginCtx
@@ -634,6 +683,17 @@ func writeHeaders(builder *strings.Builder, headers http.Header) {
 	}
 }
 
+// writeTransforms lists the request shaping steps of an attempt, if any were applied.
+func writeTransforms(builder *strings.Builder, steps []string) {
+	if len(steps) == 0 {
+		return
+	}
+	builder.WriteString("Transforms:\n")
+	for _, step := range steps {
+		builder.WriteString(fmt.Sprintf("  - %s\n", step))
+	}
+}
+
 func formatAuthInfo(info UpstreamRequestLog) string {
 	var parts []string
 	if trimmed := strings.TrimSpace(info.Provider); trimmed != "" {
@@ -53,3 +53,23 @@ func TestRecordAPIResponseMetadataStoresHeadersWhenRequestLogDisabled(t *testing
 		t.Fatalf("response header = %q, want %q", got.Get("X-Upstream-Request-Id"), "upstream-req-1")
 	}
 }
+
+func TestRecordAPIRequestIncludesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx := logging.WithRequestID(context.WithValue(context.Background(), "gin", ginCtx), "req-1234")
+
+	RecordAPIRequest(ctx, &config.Config{}, UpstreamRequestLog{
+		URL:    "https://api.example.com/v1/messages",
+		Method: http.MethodPost,
+	})
+
+	value, _ := ginCtx.Get(logging.DeferredAPIRequestContextKey)
+	requests, _ := value.([]logging.DeferredAPIRequest)
+	if len(requests) != 1 {
+		t.Fatalf("deferred API requests = %#v, want one request", value)
+	}
+	if captured := string(requests[0]()); !strings.Contains(captured, "Request ID: req-1234\n") {
+		t.Fatalf("captured API request = %q, want the request ID", captured)
+	}
+}
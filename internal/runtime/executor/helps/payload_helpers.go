@@ -1,6 +1,7 @@
 package helps
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"reflect"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -26,6 +29,22 @@ func ApplyPayloadConfigWithRoot(cfg *config.Config, model, protocol, root string
 
 // ApplyPayloadConfigWithRequest applies payload config using source protocol and request header gates.
 func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProtocol, root string, payload, original []byte, requestedModel string, requestPath string, headers http.Header) []byte {
+	return ApplyPayloadConfigForProvider(cfg, "", model, protocol, fromProtocol, root, payload, original, requestedModel, requestPath, headers)
+}
+
+// ApplyPayloadConfigForProvider behaves like ApplyPayloadConfigWithRequest and additionally
+// evaluates provider-scoped rules against provider, the executor the request is routed to.
+// Provider-wide rules (providers without models) rank below model rules: their defaults
+// only fill fields no model default set, and model overrides are applied after theirs.
+func ApplyPayloadConfigForProvider(cfg *config.Config, provider, model, protocol, fromProtocol, root string, payload, original []byte, requestedModel string, requestPath string, headers http.Header) []byte {
+	return ApplyPayloadConfigWithTrace(context.Background(), cfg, provider, model, protocol, fromProtocol, root, payload, original, requestedModel, requestPath, headers)
+}
+
+// ApplyPayloadConfigWithTrace behaves like ApplyPayloadConfigForProvider and records the
+// payload rules that matched, by kind and config index, in the transformation trace of ctx.
+// Output tokens are capped at the WithOutputTokenCeiling of ctx when that is higher than
+// the registry limit.
+func ApplyPayloadConfigWithTrace(ctx context.Context, cfg *config.Config, provider, model, protocol, fromProtocol, root string, payload, original []byte, requestedModel string, requestPath string, headers http.Header) []byte {
 	if cfg == nil || len(payload) == 0 {
 		return payload
 	}
@@ -36,6 +55,7 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 	if shouldStripImageGeneration(cfg.DisableImageGeneration, requestPath) {
 		out = removeToolTypeFromPayloadWithRoot(out, root, "image_generation")
 		out = removeToolChoiceFromPayloadWithRoot(out, root, "image_generation")
+		transformtrace.Record(ctx, "disable-image-generation: image_generation tool removed")
 	}
 
 	rules := cfg.Payload
@@ -51,11 +71,12 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 			}
 			appliedDefaults := make(map[string]struct{})
 			// Apply default rules: first write wins per field across all matching rules.
-			for i := range rules.Default {
+			for _, i := range payloadRuleOrder(rules.Default, false) {
 				rule := &rules.Default[i]
-				if !payloadModelRulesMatch(rule.Models, protocol, fromProtocol, headers, out, root, candidates) {
+				if !payloadRuleMatches(rule.Models, rule.Providers, provider, protocol, fromProtocol, headers, out, root, candidates) {
 					continue
 				}
+				transformtrace.Record(ctx, "payload.default[%d]", i)
 				for path, value := range rule.Params {
 					fullPath := buildPayloadPath(root, path)
 					if fullPath == "" {
@@ -78,11 +99,12 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 				}
 			}
 			// Apply default raw rules: first write wins per field across all matching rules.
-			for i := range rules.DefaultRaw {
+			for _, i := range payloadRuleOrder(rules.DefaultRaw, false) {
 				rule := &rules.DefaultRaw[i]
-				if !payloadModelRulesMatch(rule.Models, protocol, fromProtocol, headers, out, root, candidates) {
+				if !payloadRuleMatches(rule.Models, rule.Providers, provider, protocol, fromProtocol, headers, out, root, candidates) {
 					continue
 				}
+				transformtrace.Record(ctx, "payload.default-raw[%d]", i)
 				for path, value := range rule.Params {
 					fullPath := buildPayloadPath(root, path)
 					if fullPath == "" {
@@ -109,11 +131,12 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 				}
 			}
 			// Apply override rules: last write wins per field across all matching rules.
-			for i := range rules.Override {
+			for _, i := range payloadRuleOrder(rules.Override, true) {
 				rule := &rules.Override[i]
-				if !payloadModelRulesMatch(rule.Models, protocol, fromProtocol, headers, out, root, candidates) {
+				if !payloadRuleMatches(rule.Models, rule.Providers, provider, protocol, fromProtocol, headers, out, root, candidates) {
 					continue
 				}
+				transformtrace.Record(ctx, "payload.override[%d]", i)
 				for path, value := range rule.Params {
 					fullPath := buildPayloadPath(root, path)
 					if fullPath == "" {
@@ -125,11 +148,12 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 				}
 			}
 			// Apply override raw rules: last write wins per field across all matching rules.
-			for i := range rules.OverrideRaw {
+			for _, i := range payloadRuleOrder(rules.OverrideRaw, true) {
 				rule := &rules.OverrideRaw[i]
-				if !payloadModelRulesMatch(rule.Models, protocol, fromProtocol, headers, out, root, candidates) {
+				if !payloadRuleMatches(rule.Models, rule.Providers, provider, protocol, fromProtocol, headers, out, root, candidates) {
 					continue
 				}
+				transformtrace.Record(ctx, "payload.override-raw[%d]", i)
 				for path, value := range rule.Params {
 					fullPath := buildPayloadPath(root, path)
 					if fullPath == "" {
@@ -147,9 +171,10 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 			// Apply filter rules: remove matching paths from payload.
 			for i := range rules.Filter {
 				rule := &rules.Filter[i]
-				if !payloadModelRulesMatch(rule.Models, protocol, fromProtocol, headers, out, root, candidates) {
+				if !payloadRuleMatches(rule.Models, rule.Providers, provider, protocol, fromProtocol, headers, out, root, candidates) {
 					continue
 				}
+				transformtrace.Record(ctx, "payload.filter[%d]", i)
 				for _, path := range rule.Params {
 					fullPath := buildPayloadPath(root, path)
 					if fullPath == "" {
@@ -168,7 +193,7 @@ func ApplyPayloadConfigWithRequest(cfg *config.Config, model, protocol, fromProt
 			}
 		}
 	}
-	return out
+	return normalizePayloadMaxTokens(out, provider, model, requestedModel, protocol, root, rules.FillMaxTokens, outputTokenCeilingFromContext(ctx))
 }
 
 func isImagesEndpointRequestPath(path string) bool {
@@ -206,6 +231,54 @@ func shouldStripImageGeneration(mode config.DisableImageGenerationMode, requestP
 	}
 }
 
+// payloadRuleOrder returns the evaluation order of payload rules. Model rules keep their
+// configured order; provider-wide rules run after them when providerFirst is false
+// (defaults: first write wins) and before them otherwise (overrides: last write wins).
+func payloadRuleOrder(rules []config.PayloadRule, providerFirst bool) []int {
+	modelRules := make([]int, 0, len(rules))
+	providerRules := make([]int, 0)
+	for i := range rules {
+		if isProviderWidePayloadRule(rules[i].Models, rules[i].Providers) {
+			providerRules = append(providerRules, i)
+			continue
+		}
+		modelRules = append(modelRules, i)
+	}
+	if providerFirst {
+		return append(providerRules, modelRules...)
+	}
+	return append(modelRules, providerRules...)
+}
+
+func isProviderWidePayloadRule(models []config.PayloadModelRule, providers []string) bool {
+	return len(models) == 0 && len(providers) > 0
+}
+
+// payloadRuleMatches reports whether a rule applies. Providers, when configured, must
+// include provider; a rule without models then matches any model of that provider.
+func payloadRuleMatches(models []config.PayloadModelRule, providers []string, provider string, protocol string, fromProtocol string, headers http.Header, payload []byte, root string, candidates []string) bool {
+	if len(providers) > 0 && !payloadProviderMatches(providers, provider) {
+		return false
+	}
+	if isProviderWidePayloadRule(models, providers) {
+		return true
+	}
+	return payloadModelRulesMatch(models, protocol, fromProtocol, headers, payload, root, candidates)
+}
+
+func payloadProviderMatches(providers []string, provider string) bool {
+	provider = strings.TrimSpace(provider)
+	if provider == "" {
+		return false
+	}
+	for _, candidate := range providers {
+		if strings.EqualFold(strings.TrimSpace(candidate), provider) {
+			return true
+		}
+	}
+	return false
+}
+
 func payloadModelRulesMatch(rules []config.PayloadModelRule, protocol string, fromProtocol string, headers http.Header, payload []byte, root string, models []string) bool {
 	if len(rules) == 0 || len(models) == 0 {
 		return false
@@ -913,47 +986,8 @@ func PayloadRequestPath(opts cliproxyexecutor.Options) string {
 	}
 }
 
-// matchModelPattern performs simple wildcard matching where '*' matches zero or more characters.
-// Examples:
-//
-//	"*-5" matches "gpt-5"
-//	"gpt-*" matches "gpt-5" and "gpt-4"
-//	"gemini-*-pro" matches "gemini-2.5-pro" and "gemini-3-pro".
+// matchModelPattern reports whether model matches a payload rule pattern; see
+// util.MatchModelPattern.
 func matchModelPattern(pattern, model string) bool {
-	pattern = strings.TrimSpace(pattern)
-	model = strings.TrimSpace(model)
-	if pattern == "" {
-		return false
-	}
-	if pattern == "*" {
-		return true
-	}
-	// Iterative glob-style matcher supporting only '*' wildcard.
-	pi, si := 0, 0
-	starIdx := -1
-	matchIdx := 0
-	for si < len(model) {
-		if pi < len(pattern) && (pattern[pi] == model[si]) {
-			pi++
-			si++
-			continue
-		}
-		if pi < len(pattern) && pattern[pi] == '*' {
-			starIdx = pi
-			matchIdx = si
-			pi++
-			continue
-		}
-		if starIdx != -1 {
-			pi = starIdx + 1
-			matchIdx++
-			si = matchIdx
-			continue
-		}
-		return false
-	}
-	for pi < len(pattern) && pattern[pi] == '*' {
-		pi++
-	}
-	return pi == len(pattern)
+	return util.MatchModelPattern(pattern, model)
 }
@@ -0,0 +1,78 @@
+package helps
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestApplyPayloadConfigForProviderAppliesProviderWideDefaultWithRoot(t *testing.T) {
+	cfg := &config.Config{Payload: config.PayloadConfig{
+		Default: []config.PayloadRule{{
+			Providers: []string{"antigravity"},
+			Params:    map[string]any{"generationConfig.candidateCount": 1},
+		}},
+	}}
+	payload := []byte(`{"request":{"contents":[]}}`)
+
+	out := ApplyPayloadConfigForProvider(cfg, "antigravity", "gemini-3-pro", "antigravity", "openai", "request", payload, nil, "", "", nil)
+	if got := gjson.GetBytes(out, "request.generationConfig.candidateCount").Int(); got != 1 {
+		t.Fatalf("candidateCount = %d, want 1. Output: %s", got, out)
+	}
+	if gjson.GetBytes(out, "generationConfig").Exists() {
+		t.Fatalf("provider default must be written under root. Output: %s", out)
+	}
+
+	out = ApplyPayloadConfigForProvider(cfg, "gemini", "gemini-3-pro", "gemini", "openai", "request", payload, nil, "", "", nil)
+	if gjson.GetBytes(out, "request.generationConfig.candidateCount").Exists() {
+		t.Fatalf("provider default applied to another provider. Output: %s", out)
+	}
+}
+
+func TestApplyPayloadConfigForProviderModelRulesOutrankProviderWideRules(t *testing.T) {
+	cfg := &config.Config{Payload: config.PayloadConfig{
+		Default: []config.PayloadRule{
+			{Providers: []string{"claude"}, Params: map[string]any{"max_tokens": 4096, "temperature": 0.5}},
+			{Models: []config.PayloadModelRule{{Name: "claude-opus-*"}}, Params: map[string]any{"max_tokens": 32000}},
+		},
+		Override: []config.PayloadRule{
+			{Models: []config.PayloadModelRule{{Name: "claude-opus-*"}}, Params: map[string]any{"temperature": 1}},
+			{Providers: []string{"claude"}, Params: map[string]any{"temperature": 0.2}},
+		},
+	}}
+
+	out := ApplyPayloadConfigForProvider(cfg, "claude", "claude-opus-4-5", "claude", "claude", "", []byte(`{"messages":[]}`), nil, "", "", nil)
+	if got := gjson.GetBytes(out, "max_tokens").Int(); got != 32000 {
+		t.Fatalf("max_tokens = %d, want per-model default 32000. Output: %s", got, out)
+	}
+	if got := gjson.GetBytes(out, "temperature").Float(); got != 1 {
+		t.Fatalf("temperature = %v, want per-model override 1. Output: %s", got, out)
+	}
+
+	out = ApplyPayloadConfigForProvider(cfg, "claude", "claude-sonnet-4-5", "claude", "claude", "", []byte(`{"messages":[],"max_tokens":100}`), nil, "", "", nil)
+	if got := gjson.GetBytes(out, "max_tokens").Int(); got != 100 {
+		t.Fatalf("max_tokens = %d, want client value 100. Output: %s", got, out)
+	}
+	if got := gjson.GetBytes(out, "temperature").Float(); got != 0.2 {
+		t.Fatalf("temperature = %v, want provider override 0.2. Output: %s", got, out)
+	}
+}
+
+func TestApplyPayloadConfigForProviderRestrictsModelRulesToProviders(t *testing.T) {
+	cfg := &config.Config{Payload: config.PayloadConfig{
+		Filter: []config.PayloadFilterRule{{
+			Models:    []config.PayloadModelRule{{Name: "*"}},
+			Providers: []string{"codex"},
+			Params:    []string{"service_tier"},
+		}},
+	}}
+	payload := []byte(`{"service_tier":"priority"}`)
+
+	if out := ApplyPayloadConfigForProvider(cfg, "xai", "grok-4", "openai", "openai", "", payload, nil, "", "", nil); !gjson.GetBytes(out, "service_tier").Exists() {
+		t.Fatalf("filter applied to another provider. Output: %s", out)
+	}
+	if out := ApplyPayloadConfigForProvider(cfg, "codex", "gpt-5", "codex", "openai", "", payload, nil, "", "", nil); gjson.GetBytes(out, "service_tier").Exists() {
+		t.Fatalf("filter not applied to codex. Output: %s", out)
+	}
+}
@@ -0,0 +1,171 @@
+package helps
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// payloadMaxTokensFields lists the output token fields of a target protocol,
+// relative to the payload root. The first entry is the one filled when the
+// client omitted the limit; protocols without a fill field are only capped.
+func payloadMaxTokensFields(protocol string) (fields []string, fill bool) {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "claude":
+		return []string{"max_tokens"}, true
+	case "openai":
+		return []string{"max_tokens", "max_completion_tokens"}, true
+	case "gemini", "antigravity":
+		return []string{"generationConfig.maxOutputTokens"}, true
+	case "codex", "openai-response":
+		return []string{"max_output_tokens"}, false
+	default:
+		return nil, false
+	}
+}
+
+// payloadThinkingBudgetField returns the thinking budget field that must stay
+// below the output token limit and the thinking object removed when no valid
+// budget fits, relative to the payload root.
+func payloadThinkingBudgetField(protocol string) (budget, config string) {
+	switch strings.ToLower(strings.TrimSpace(protocol)) {
+	case "claude":
+		return "thinking.budget_tokens", "thinking"
+	case "gemini", "antigravity":
+		return "generationConfig.thinkingConfig.thinkingBudget", "generationConfig.thinkingConfig"
+	default:
+		return "", ""
+	}
+}
+
+// lookupPayloadModelInfo returns the registry entry with an output limit for
+// model, trying the upstream model name first and the client-visible name second.
+func lookupPayloadModelInfo(provider, model, requestedModel string) *registry.ModelInfo {
+	for _, name := range []string{model, requestedModel} {
+		if info := registry.LookupModelInfo(name, provider); modelMaxOutputTokens(info) > 0 {
+			return info
+		}
+	}
+	return nil
+}
+
+// modelMaxOutputTokens returns the registry output limit of info.
+func modelMaxOutputTokens(info *registry.ModelInfo) int {
+	if info == nil {
+		return 0
+	}
+	if info.MaxCompletionTokens > 0 {
+		return info.MaxCompletionTokens
+	}
+	return info.OutputTokenLimit
+}
+
+type outputTokenCeilingKey struct{}
+
+// WithOutputTokenCeiling returns a context in which the payload step caps requested output
+// tokens at ceiling rather than at a lower registry limit, for requests that unlock more
+// output than the model's default, such as Claude's output-128k beta.
+func WithOutputTokenCeiling(ctx context.Context, ceiling int) context.Context {
+	if ceiling <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, outputTokenCeilingKey{}, ceiling)
+}
+
+func outputTokenCeilingFromContext(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	ceiling, _ := ctx.Value(outputTokenCeilingKey{}).(int)
+	return ceiling
+}
+
+// NormalizePayloadMaxTokens caps the requested output tokens of payload to the
+// model's registry limit so clients asking for more than a model supports get
+// a working request instead of an upstream 400. When fill is set and the client
+// omitted the limit, the model default is written. When either happens, the
+// thinking budget is kept below the resulting limit.
+func NormalizePayloadMaxTokens(payload []byte, provider, model, requestedModel, protocol, root string, fill bool) []byte {
+	return normalizePayloadMaxTokens(payload, provider, model, requestedModel, protocol, root, fill, 0)
+}
+
+// normalizePayloadMaxTokens is NormalizePayloadMaxTokens with the cap raised to ceiling
+// when that is above the registry limit. Omitted limits are still filled with the
+// registry limit.
+func normalizePayloadMaxTokens(payload []byte, provider, model, requestedModel, protocol, root string, fill bool, ceiling int) []byte {
+	fields, canFill := payloadMaxTokensFields(protocol)
+	if len(fields) == 0 || len(payload) == 0 {
+		return payload
+	}
+	info := lookupPayloadModelInfo(provider, strings.TrimSpace(model), strings.TrimSpace(requestedModel))
+	fillLimit := modelMaxOutputTokens(info)
+	if fillLimit <= 0 {
+		return payload
+	}
+	limit := max(fillLimit, ceiling)
+
+	out := payload
+	effective := 0
+	present, changed := false, false
+	for _, field := range fields {
+		path := buildPayloadPath(root, field)
+		current := gjson.GetBytes(out, path)
+		if !current.Exists() || current.Type != gjson.Number {
+			continue
+		}
+		present = true
+		requested := int(current.Int())
+		if requested > limit {
+			if updated, errSet := sjson.SetBytes(out, path, limit); errSet == nil {
+				out = updated
+				changed = true
+				log.Infof("capped %s for model %s from %d to %d", field, model, requested, limit)
+			}
+			requested = limit
+		}
+		if effective == 0 || requested < effective {
+			effective = requested
+		}
+	}
+	if !present && fill && canFill {
+		if updated, errSet := sjson.SetBytes(out, buildPayloadPath(root, fields[0]), fillLimit); errSet == nil {
+			out = updated
+			effective = fillLimit
+			changed = true
+		}
+	}
+	if !changed || effective <= 0 {
+		return out
+	}
+
+	budgetField, configField := payloadThinkingBudgetField(protocol)
+	if budgetField == "" {
+		return out
+	}
+	path := buildPayloadPath(root, budgetField)
+	budget := gjson.GetBytes(out, path)
+	if budget.Type != gjson.Number || budget.Int() < int64(effective) {
+		return out
+	}
+	var support *registry.ThinkingSupport
+	if info != nil {
+		support = info.Thinking
+	}
+	fitted, enabled := thinking.FitBudgetToMaxTokens(int(budget.Int()), effective, support)
+	if !enabled {
+		if updated, errDelete := sjson.DeleteBytes(out, buildPayloadPath(root, configField)); errDelete == nil {
+			out = updated
+			log.Infof("removed thinking for model %s: no budget fits below %d output tokens", model, effective)
+		}
+		return out
+	}
+	if updated, errSet := sjson.SetBytes(out, path, fitted); errSet == nil {
+		out = updated
+	}
+	return out
+}
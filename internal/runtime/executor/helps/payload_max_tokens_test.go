@@ -0,0 +1,190 @@
+package helps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/thinking/provider/antigravity"
+	"github.com/tidwall/gjson"
+)
+
+func registerMaxTokensTestModel(t *testing.T, provider, modelID string, maxCompletionTokens int, support *registry.ThinkingSupport) {
+	t.Helper()
+	reg := registry.GetGlobalRegistry()
+	clientID := "test-max-tokens-" + provider + "-" + modelID
+	reg.RegisterClient(clientID, provider, []*registry.ModelInfo{{
+		ID:                  modelID,
+		Object:              "model",
+		OwnedBy:             provider,
+		Type:                provider,
+		MaxCompletionTokens: maxCompletionTokens,
+		Thinking:            support,
+		UserDefined:         true,
+	}})
+	t.Cleanup(func() { reg.UnregisterClient(clientID) })
+}
+
+func TestApplyPayloadConfigCapsMaxTokensPerFormat(t *testing.T) {
+	registerMaxTokensTestModel(t, "claude", "test-max-tokens-claude", 8192, nil)
+	registerMaxTokensTestModel(t, "openai", "test-max-tokens-openai", 8192, nil)
+	registerMaxTokensTestModel(t, "gemini", "test-max-tokens-gemini", 8192, nil)
+	registerMaxTokensTestModel(t, "antigravity", "test-max-tokens-antigravity", 8192, nil)
+	registerMaxTokensTestModel(t, "codex", "test-max-tokens-codex", 8192, nil)
+
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		protocol string
+		root     string
+		payload  string
+		paths    map[string]int64
+	}{
+		{
+			name: "claude", provider: "claude", model: "test-max-tokens-claude", protocol: "claude",
+			payload: `{"max_tokens":200000,"thinking":{"type":"enabled","budget_tokens":100000}}`,
+			paths:   map[string]int64{"max_tokens": 8192, "thinking.budget_tokens": 8191},
+		},
+		{
+			name: "openai", provider: "openai", model: "test-max-tokens-openai", protocol: "openai",
+			payload: `{"max_tokens":50000,"max_completion_tokens":60000}`,
+			paths:   map[string]int64{"max_tokens": 8192, "max_completion_tokens": 8192},
+		},
+		{
+			name: "gemini", provider: "gemini", model: "test-max-tokens-gemini", protocol: "gemini",
+			payload: `{"generationConfig":{"maxOutputTokens":65536,"thinkingConfig":{"thinkingBudget":32768}}}`,
+			paths:   map[string]int64{"generationConfig.maxOutputTokens": 8192, "generationConfig.thinkingConfig.thinkingBudget": 8191},
+		},
+		{
+			name: "antigravity", provider: "antigravity", model: "test-max-tokens-antigravity", protocol: "antigravity", root: "request",
+			payload: `{"request":{"generationConfig":{"maxOutputTokens":65536}}}`,
+			paths:   map[string]int64{"request.generationConfig.maxOutputTokens": 8192},
+		},
+		{
+			name: "codex", provider: "codex", model: "test-max-tokens-codex", protocol: "codex",
+			payload: `{"max_output_tokens":65536}`,
+			paths:   map[string]int64{"max_output_tokens": 8192},
+		},
+		{
+			name: "within limit", provider: "claude", model: "test-max-tokens-claude", protocol: "claude",
+			payload: `{"max_tokens":1024,"thinking":{"type":"enabled","budget_tokens":2048}}`,
+			paths:   map[string]int64{"max_tokens": 1024, "thinking.budget_tokens": 2048},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := ApplyPayloadConfigForProvider(&config.Config{}, tt.provider, tt.model, tt.protocol, "", tt.root, []byte(tt.payload), nil, "", "", nil)
+			for path, want := range tt.paths {
+				if got := gjson.GetBytes(out, path).Int(); got != want {
+					t.Fatalf("%s = %d, want %d. Output: %s", path, got, want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPayloadConfigFillsMaxTokensWhenConfigured(t *testing.T) {
+	registerMaxTokensTestModel(t, "gemini", "test-fill-max-tokens-gemini", 8192, nil)
+	payload := []byte(`{"contents":[]}`)
+
+	out := ApplyPayloadConfigForProvider(&config.Config{}, "gemini", "test-fill-max-tokens-gemini", "gemini", "", "", payload, nil, "", "", nil)
+	if gjson.GetBytes(out, "generationConfig.maxOutputTokens").Exists() {
+		t.Fatalf("maxOutputTokens filled without payload.fill-max-tokens. Output: %s", out)
+	}
+
+	cfg := &config.Config{Payload: config.PayloadConfig{FillMaxTokens: true}}
+	out = ApplyPayloadConfigForProvider(cfg, "gemini", "test-fill-max-tokens-gemini", "gemini", "", "", payload, nil, "", "", nil)
+	if got := gjson.GetBytes(out, "generationConfig.maxOutputTokens").Int(); got != 8192 {
+		t.Fatalf("maxOutputTokens = %d, want 8192. Output: %s", got, out)
+	}
+
+	out = ApplyPayloadConfigForProvider(cfg, "codex", "test-fill-max-tokens-gemini", "codex", "", "", []byte(`{"input":[]}`), nil, "", "", nil)
+	if gjson.GetBytes(out, "max_output_tokens").Exists() {
+		t.Fatalf("max_output_tokens filled for codex. Output: %s", out)
+	}
+}
+
+func TestApplyPayloadConfigRaisesMaxTokensCapToContextCeiling(t *testing.T) {
+	registerMaxTokensTestModel(t, "antigravity", "test-ceiling-antigravity", 8192, nil)
+	ctx := WithOutputTokenCeiling(context.Background(), 16000)
+	apply := func(payload string, fill bool) []byte {
+		cfg := &config.Config{Payload: config.PayloadConfig{FillMaxTokens: fill}}
+		return ApplyPayloadConfigWithTrace(ctx, cfg, "antigravity", "test-ceiling-antigravity", "antigravity", "", "request", []byte(payload), nil, "", "", nil)
+	}
+
+	cases := []struct {
+		payload string
+		fill    bool
+		want    int64
+	}{
+		{payload: `{"request":{"generationConfig":{"maxOutputTokens":12000}}}`, want: 12000},
+		{payload: `{"request":{"generationConfig":{"maxOutputTokens":20000}}}`, want: 16000},
+		{payload: `{"request":{"contents":[]}}`, fill: true, want: 8192},
+	}
+	for _, tc := range cases {
+		out := apply(tc.payload, tc.fill)
+		if got := gjson.GetBytes(out, "request.generationConfig.maxOutputTokens").Int(); got != tc.want {
+			t.Fatalf("maxOutputTokens = %d, want %d. Output: %s", got, tc.want, out)
+		}
+	}
+}
+
+func TestApplyPayloadConfigKeepsAntigravityThinkingBudgetBelowCappedMax(t *testing.T) {
+	modelID := "test-max-tokens-antigravity-claude"
+	registerMaxTokensTestModel(t, "antigravity", modelID, 16000, &registry.ThinkingSupport{Min: 1024, Max: 64000})
+
+	body := []byte(`{"request":{"contents":[],"generationConfig":{"maxOutputTokens":100000,"thinkingConfig":{"thinkingBudget":32000}}}}`)
+	translated, errThinking := thinking.ApplyThinking(body, modelID, "antigravity", "antigravity", "antigravity")
+	if errThinking != nil {
+		t.Fatalf("ApplyThinking() error = %v", errThinking)
+	}
+	if got := gjson.GetBytes(translated, "request.generationConfig.thinkingConfig.thinkingBudget").Int(); got != 15999 {
+		t.Fatalf("thinking budget = %d, want 15999 under the model max. Output: %s", got, translated)
+	}
+
+	out := ApplyPayloadConfigForProvider(&config.Config{}, "antigravity", modelID, "antigravity", "antigravity", "request", translated, body, "", "", nil)
+	maxOut := gjson.GetBytes(out, "request.generationConfig.maxOutputTokens").Int()
+	budget := gjson.GetBytes(out, "request.generationConfig.thinkingConfig.thinkingBudget").Int()
+	if maxOut != 16000 || budget >= maxOut {
+		t.Fatalf("maxOutputTokens = %d, thinkingBudget = %d; want 16000 with the budget below it. Output: %s", maxOut, budget, out)
+	}
+}
+
+func TestApplyPayloadConfigDropsThinkingWhenCappedMaxIsBelowMinimum(t *testing.T) {
+	registerMaxTokensTestModel(t, "claude", "test-max-tokens-claude-min", 1000, &registry.ThinkingSupport{Min: 1024, Max: 64000, ZeroAllowed: true})
+	registerMaxTokensTestModel(t, "gemini", "test-max-tokens-gemini-min", 100, &registry.ThinkingSupport{Min: 128, Max: 24576})
+
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		payload  string
+		maxPath  string
+		absent   string
+	}{
+		{
+			name: "claude", provider: "claude", model: "test-max-tokens-claude-min",
+			payload: `{"max_tokens":32000,"thinking":{"type":"enabled","budget_tokens":16000}}`,
+			maxPath: "max_tokens", absent: "thinking",
+		},
+		{
+			name: "gemini", provider: "gemini", model: "test-max-tokens-gemini-min",
+			payload: `{"generationConfig":{"maxOutputTokens":4096,"thinkingConfig":{"thinkingBudget":2048}}}`,
+			maxPath: "generationConfig.maxOutputTokens", absent: "generationConfig.thinkingConfig",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := ApplyPayloadConfigForProvider(&config.Config{}, tt.provider, tt.model, tt.provider, "", "", []byte(tt.payload), nil, "", "", nil)
+			if !gjson.GetBytes(out, tt.maxPath).Exists() {
+				t.Fatalf("%s missing. Output: %s", tt.maxPath, out)
+			}
+			if gjson.GetBytes(out, tt.absent).Exists() {
+				t.Fatalf("%s kept although no budget at or above the minimum fits. Output: %s", tt.absent, out)
+			}
+		})
+	}
+}
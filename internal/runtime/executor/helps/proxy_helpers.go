@@ -4,9 +4,11 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/proxyutil"
 	log "github.com/sirupsen/logrus"
@@ -17,19 +19,31 @@ import (
 // 2. Use cfg.ProxyURL if auth proxy is not configured
 // 3. Use RoundTripper from context if neither are configured
 //
+// Transport settings from providers.<auth.Provider> (response header and TLS handshake
+// timeouts, idle connections per host) are applied to the selected transport.
+//
 // Parameters:
 //   - ctx: The context containing optional RoundTripper
 //   - cfg: The application configuration
 //   - auth: The authentication information
-//   - timeout: The client timeout (0 means no timeout)
+//   - timeout: The client timeout (0 means no timeout); non-streaming calls pass ProviderRequestTimeout
+//
+// When tracing is enabled, the transport records every upstream HTTP call as a client span.
 //
 // Returns:
 //   - *http.Client: An HTTP client with configured proxy or transport
 func NewProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
+	return TracedHTTPClient(NewUntracedProxyAwareHTTPClient(ctx, cfg, auth, timeout))
+}
+
+// NewUntracedProxyAwareHTTPClient is NewProxyAwareHTTPClient without the tracing transport,
+// for callers that adjust the transport before passing the client to TracedHTTPClient.
+func NewUntracedProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
 	httpClient := &http.Client{}
 	if timeout > 0 {
 		httpClient.Timeout = timeout
 	}
+	settings := ProviderHTTPSettings(cfg, auth)
 
 	// Priority 1: Use auth.ProxyURL if configured
 	var proxyURL string
@@ -46,6 +60,8 @@ func NewProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *clip
 	if proxyURL != "" {
 		transport := buildProxyTransport(proxyURL)
 		if transport != nil {
+			// The proxy transport is built per call, so it is tuned in place.
+			applyProviderTransportSettings(transport, settings)
 			httpClient.Transport = transport
 			return httpClient
 		}
@@ -55,12 +71,95 @@ func NewProxyAwareHTTPClient(ctx context.Context, cfg *config.Config, auth *clip
 
 	// Priority 3: Use RoundTripper from context (typically from RoundTripperFor)
 	if rt, ok := ctx.Value("cliproxy.roundtripper").(http.RoundTripper); ok && rt != nil {
+		if transport, isTransport := rt.(*http.Transport); isTransport {
+			rt = TunedTransport(transport, settings)
+		}
 		httpClient.Transport = rt
+	} else if settings.TransportConfigured() {
+		if transport, isTransport := http.DefaultTransport.(*http.Transport); isTransport {
+			httpClient.Transport = TunedTransport(transport, settings)
+		}
 	}
 
 	return httpClient
 }
 
+// TracedHTTPClient wraps the transport of client with the tracing transport when tracing
+// is enabled.
+func TracedHTTPClient(client *http.Client) *http.Client {
+	if client != nil && tracing.Enabled() {
+		client.Transport = tracing.Transport(client.Transport)
+	}
+	return client
+}
+
+// ProviderHTTPSettings returns the providers.<name> HTTP settings for the auth's provider.
+func ProviderHTTPSettings(cfg *config.Config, auth *cliproxyauth.Auth) config.ProviderHTTPConfig {
+	if auth == nil {
+		return config.ProviderHTTPConfig{}
+	}
+	return cfg.ProviderHTTP(auth.Provider)
+}
+
+// ProviderRequestTimeout returns the overall timeout configured for non-streaming calls of
+// the auth's provider, or 0 when unset. Streaming calls pass 0 to NewProxyAwareHTTPClient
+// instead so an active stream is never cut off.
+func ProviderRequestTimeout(cfg *config.Config, auth *cliproxyauth.Auth) time.Duration {
+	if seconds := ProviderHTTPSettings(cfg, auth).RequestTimeoutSeconds; seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+type tunedTransportKey struct {
+	base                  *http.Transport
+	responseHeaderTimeout int
+	tlsHandshakeTimeout   int
+	maxIdleConnsPerHost   int
+}
+
+// tunedTransports caches tuned clones of shared transports so connection pools are reused.
+var tunedTransports sync.Map
+
+// TunedTransport returns a shared clone of base with the provider transport settings
+// applied, or base itself when none are configured.
+func TunedTransport(base *http.Transport, settings config.ProviderHTTPConfig) *http.Transport {
+	if base == nil || !settings.TransportConfigured() {
+		return base
+	}
+	key := tunedTransportKey{
+		base:                  base,
+		responseHeaderTimeout: settings.ResponseHeaderTimeoutSeconds,
+		tlsHandshakeTimeout:   settings.TLSHandshakeTimeoutSeconds,
+		maxIdleConnsPerHost:   settings.MaxIdleConnsPerHost,
+	}
+	if cached, ok := tunedTransports.Load(key); ok {
+		return cached.(*http.Transport)
+	}
+	transport := base.Clone()
+	applyProviderTransportSettings(transport, settings)
+	actual, _ := tunedTransports.LoadOrStore(key, transport)
+	return actual.(*http.Transport)
+}
+
+func applyProviderTransportSettings(transport *http.Transport, settings config.ProviderHTTPConfig) {
+	if transport == nil {
+		return
+	}
+	if settings.ResponseHeaderTimeoutSeconds > 0 {
+		transport.ResponseHeaderTimeout = time.Duration(settings.ResponseHeaderTimeoutSeconds) * time.Second
+	}
+	if settings.TLSHandshakeTimeoutSeconds > 0 {
+		transport.TLSHandshakeTimeout = time.Duration(settings.TLSHandshakeTimeoutSeconds) * time.Second
+	}
+	if settings.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+		if transport.MaxIdleConns > 0 && transport.MaxIdleConns < settings.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = settings.MaxIdleConnsPerHost
+		}
+	}
+}
+
 // buildProxyTransport creates an HTTP transport configured for the given proxy URL.
 // It supports SOCKS5, HTTP, and HTTPS proxy protocols.
 //
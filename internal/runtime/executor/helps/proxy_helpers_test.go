@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
@@ -28,3 +29,43 @@ func TestNewProxyAwareHTTPClientDirectBypassesGlobalProxy(t *testing.T) {
 		t.Fatal("expected direct transport to disable proxy function")
 	}
 }
+
+func TestNewProxyAwareHTTPClientAppliesProviderTransportSettings(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Providers: map[string]config.ProviderHTTPConfig{
+		"Gemini": {RequestTimeoutSeconds: 30, ResponseHeaderTimeoutSeconds: 5, TLSHandshakeTimeoutSeconds: 3, MaxIdleConnsPerHost: 200},
+	}}
+	auth := &cliproxyauth.Auth{Provider: "gemini"}
+
+	client := NewProxyAwareHTTPClient(context.Background(), cfg, auth, ProviderRequestTimeout(cfg, auth))
+	if client.Timeout != 30*time.Second {
+		t.Fatalf("client timeout = %v, want 30s", client.Timeout)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport type = %T, want *http.Transport", client.Transport)
+	}
+	if transport == http.DefaultTransport {
+		t.Fatal("default transport must not be mutated")
+	}
+	if transport.ResponseHeaderTimeout != 5*time.Second || transport.TLSHandshakeTimeout != 3*time.Second {
+		t.Fatalf("timeouts = %v/%v, want 5s/3s", transport.ResponseHeaderTimeout, transport.TLSHandshakeTimeout)
+	}
+	if transport.MaxIdleConnsPerHost != 200 || transport.MaxIdleConns < 200 {
+		t.Fatalf("idle conns = %d per host, %d total", transport.MaxIdleConnsPerHost, transport.MaxIdleConns)
+	}
+
+	again := NewProxyAwareHTTPClient(context.Background(), cfg, auth, 0)
+	if again.Transport != client.Transport {
+		t.Fatal("tuned transport should be shared between clients to keep the connection pool")
+	}
+	if again.Timeout != 0 {
+		t.Fatalf("streaming client timeout = %v, want 0", again.Timeout)
+	}
+
+	other := NewProxyAwareHTTPClient(context.Background(), cfg, &cliproxyauth.Auth{Provider: "codex"}, 0)
+	if other.Transport != nil {
+		t.Fatalf("unconfigured provider transport = %T, want default", other.Transport)
+	}
+}
@@ -0,0 +1,178 @@
+package helps
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+var sseDataField = []byte("data:")
+
+// StreamScannerBuffer is the largest SSE line the stream loops scan.
+const StreamScannerBuffer = 52_428_800
+
+// sseMaxLineSize caps a line and a reassembled event read by NewSSEReader, so an upstream
+// that never ends a line fails with bufio.ErrTooLong instead of growing memory without bound.
+var sseMaxLineSize = StreamScannerBuffer
+
+// NewSSEReader returns a reader for an upstream SSE body that stream loops can scan line by
+// line, whatever line endings and data field layout the upstream or an intermediary proxy
+// used:
+//
+//   - CRLF line endings are rewritten to LF.
+//   - An event whose payload is split across consecutive data lines is rewritten into a
+//     single data line holding the reassembled payload. The lines are joined with LF as the
+//     SSE spec says; when that does not give valid JSON they are concatenated instead.
+//
+// A data line that already holds a complete JSON document or [DONE] is passed through
+// unchanged and ends the event's data, so well-formed streams are read as before and no
+// chunk waits for the blank line ending its event. Lines and reassembled events longer
+// than StreamScannerBuffer fail the read with bufio.ErrTooLong.
+func NewSSEReader(body io.Reader) io.Reader {
+	return &sseReader{src: bufio.NewReader(body)}
+}
+
+// NormalizeSSE applies NewSSEReader to a complete SSE body.
+func NormalizeSSE(data []byte) []byte {
+	normalized, _ := io.ReadAll(NewSSEReader(bytes.NewReader(data)))
+	return normalized
+}
+
+type sseReader struct {
+	src *bufio.Reader
+	out bytes.Buffer
+	err error
+
+	// first is the first data line of the event being reassembled, and parts holds the
+	// values of all its data lines.
+	first     []byte
+	parts     [][]byte
+	partsSize int
+}
+
+func (r *sseReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.next()
+	}
+	return r.out.Read(p)
+}
+
+// next reads one line and buffers its rewritten form. It returns the read error once the
+// source is drained, after flushing a partial event.
+func (r *sseReader) next() error {
+	line, errRead := r.readLine()
+	if line != nil {
+		errRead = r.handleLine(line, errRead)
+	}
+	if errors.Is(errRead, bufio.ErrTooLong) {
+		r.first, r.parts, r.partsSize = nil, nil, 0
+		return errRead
+	}
+	if errRead != nil {
+		r.flushData()
+		return errRead
+	}
+	return nil
+}
+
+// readLine returns the next line without its LF or CRLF ending. It returns a nil line when
+// the source ended without further content.
+func (r *sseReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, errRead := r.src.ReadSlice('\n')
+		// Allow for the CRLF ending on top of the line itself.
+		if len(line)+len(chunk) > sseMaxLineSize+2 {
+			return nil, bufio.ErrTooLong
+		}
+		line = append(line, chunk...)
+		if errors.Is(errRead, bufio.ErrBufferFull) {
+			continue
+		}
+		if len(line) == 0 {
+			return nil, errRead
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) > sseMaxLineSize {
+			return nil, bufio.ErrTooLong
+		}
+		return line, errRead
+	}
+}
+
+// handleLine buffers the rewritten form of line. It returns bufio.ErrTooLong when the
+// event being reassembled outgrows sseMaxLineSize, and errRead otherwise.
+func (r *sseReader) handleLine(line []byte, errRead error) error {
+	value, isData := sseDataValue(line)
+	if !isData {
+		r.flushData()
+		r.writeLine(line)
+		return errRead
+	}
+	if len(r.parts) == 0 && sseDataComplete(value) {
+		r.writeLine(line)
+		return errRead
+	}
+	if r.partsSize+len(value) > sseMaxLineSize {
+		return bufio.ErrTooLong
+	}
+	if len(r.parts) == 0 {
+		r.first = line
+	}
+	r.parts = append(r.parts, value)
+	r.partsSize += len(value)
+	if len(r.parts) > 1 && sseDataComplete(bytes.Join(r.parts, []byte("\n"))) {
+		r.flushData()
+	}
+	return errRead
+}
+
+// flushData writes the data lines of the event being reassembled as one data line.
+func (r *sseReader) flushData() {
+	switch len(r.parts) {
+	case 0:
+		return
+	case 1:
+		r.writeLine(r.first)
+	default:
+		joined := bytes.Join(r.parts, []byte("\n"))
+		r.out.WriteString("data: ")
+		if json.Valid(joined) {
+			_ = json.Compact(&r.out, joined)
+		} else {
+			r.out.Write(bytes.Join(r.parts, nil))
+		}
+		r.out.WriteByte('\n')
+	}
+	r.first, r.parts, r.partsSize = nil, nil, 0
+}
+
+func (r *sseReader) writeLine(line []byte) {
+	r.out.Write(line)
+	r.out.WriteByte('\n')
+}
+
+// sseDataValue returns the value of a data field line, without the single space that may
+// follow the colon.
+func sseDataValue(line []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(line, sseDataField) {
+		return nil, false
+	}
+	value := line[len(sseDataField):]
+	if len(value) > 0 && value[0] == ' ' {
+		value = value[1:]
+	}
+	return value, true
+}
+
+// sseDataComplete reports whether a data value can be parsed on its own.
+func sseDataComplete(value []byte) bool {
+	trimmed := bytes.TrimSpace(value)
+	return bytes.Equal(trimmed, []byte("[DONE]")) || len(trimmed) > 0 && json.Valid(trimmed)
+}
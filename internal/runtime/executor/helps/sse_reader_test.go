@@ -0,0 +1,87 @@
+package helps
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNormalizeSSE(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "well-formed stream is unchanged",
+			in:   "event: delta\ndata: {\"a\":1}\n\ndata:{\"b\":2}\n\ndata: [DONE]\n\n",
+			want: "event: delta\ndata: {\"a\":1}\n\ndata:{\"b\":2}\n\ndata: [DONE]\n\n",
+		},
+		{
+			name: "CRLF line endings",
+			in:   "event: delta\r\ndata: {\"a\":1}\r\n\r\ndata: [DONE]\r\n\r\n",
+			want: "event: delta\ndata: {\"a\":1}\n\ndata: [DONE]\n\n",
+		},
+		{
+			name: "data split between JSON tokens",
+			in:   "data: {\"a\":1,\ndata: \"b\":[2,3]}\n\n",
+			want: "data: {\"a\":1,\"b\":[2,3]}\n\n",
+		},
+		{
+			name: "data split inside a string",
+			in:   "data: {\"text\":\"hel\r\ndata: lo\"}\r\n\r\ndata: {\"n\":1}\r\n\r\n",
+			want: "data: {\"text\":\"hello\"}\n\ndata: {\"n\":1}\n\n",
+		},
+		{
+			name: "unterminated event at end of stream",
+			in:   "data: {\"a\":\ndata: 1}",
+			want: "data: {\"a\":1}\n",
+		},
+		{
+			name: "non-JSON data waits for the end of the event",
+			in:   "data: hello\n\ndata: {\"a\":1}\n",
+			want: "data: hello\n\ndata: {\"a\":1}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(NormalizeSSE([]byte(tt.in))); got != tt.want {
+				t.Fatalf("NormalizeSSE() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSSEReaderSmallReads(t *testing.T) {
+	in := "data: {\"text\":\r\ndata: \"hi\"}\r\n\r\ndata: [DONE]\r\n\r\n"
+	got, errRead := io.ReadAll(iotest.OneByteReader(NewSSEReader(iotest.HalfReader(strings.NewReader(in)))))
+	if errRead != nil {
+		t.Fatalf("ReadAll() error = %v", errRead)
+	}
+	if want := "data: {\"text\":\"hi\"}\n\ndata: [DONE]\n\n"; string(got) != want {
+		t.Fatalf("read = %q, want %q", got, want)
+	}
+}
+
+func TestNewSSEReaderRejectsOverlongLines(t *testing.T) {
+	original := sseMaxLineSize
+	sseMaxLineSize = 16
+	t.Cleanup(func() { sseMaxLineSize = original })
+
+	for name, in := range map[string]string{
+		"unterminated line": "data: " + strings.Repeat("x", 64),
+		"reassembled event": "data: {\"a\":\"0123456789\ndata: 0123456789\"}\n\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, errRead := io.ReadAll(NewSSEReader(strings.NewReader(in))); !errors.Is(errRead, bufio.ErrTooLong) {
+				t.Fatalf("ReadAll() error = %v, want bufio.ErrTooLong", errRead)
+			}
+		})
+	}
+	if got, errRead := io.ReadAll(NewSSEReader(strings.NewReader("data: {\"a\":1}\r\n"))); errRead != nil || string(got) != "data: {\"a\":1}\n" {
+		t.Fatalf("ReadAll() = %q, %v; want the line within the limit", got, errRead)
+	}
+}
@@ -0,0 +1,106 @@
+package helps
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+)
+
+// StreamIdleTimeoutError reports that an upstream stream sent no bytes for Timeout.
+type StreamIdleTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *StreamIdleTimeoutError) Error() string {
+	return fmt.Sprintf("upstream stream idle for %s, request cancelled (streaming.idle-timeout-seconds)", e.Timeout)
+}
+
+// IsStreamIdleTimeout reports whether err was caused by an idle stream watchdog.
+func IsStreamIdleTimeout(err error) bool {
+	var idleErr *StreamIdleTimeoutError
+	return errors.As(err, &idleErr)
+}
+
+// StreamIdleTimeout returns the streaming idle limit configured in cfg.
+func StreamIdleTimeout(cfg *config.Config) time.Duration {
+	if cfg == nil {
+		return config.StreamingConfig{}.IdleTimeout()
+	}
+	return cfg.Streaming.IdleTimeout()
+}
+
+// NewIdleTimeoutReader wraps an upstream response body with an inactivity watchdog. When no
+// bytes arrive for timeout the body is closed, which cancels the upstream request, and reads
+// fail with *StreamIdleTimeoutError. Every read that returns data resets the watchdog. A
+// timeout <= 0 returns body unchanged.
+func NewIdleTimeoutReader(body io.ReadCloser, timeout time.Duration) io.ReadCloser {
+	if body == nil || timeout <= 0 {
+		return body
+	}
+	r := &idleTimeoutReader{body: body, timeout: timeout, lastRead: time.Now()}
+	r.mu.Lock()
+	r.timer = time.AfterFunc(timeout, r.expire)
+	r.mu.Unlock()
+	return r
+}
+
+type idleTimeoutReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	mu       sync.Mutex
+	lastRead time.Time
+	expired  bool
+	closed   bool
+}
+
+func (r *idleTimeoutReader) expire() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	// Data that arrived since the timer was armed pushes the deadline out.
+	if idle := time.Since(r.lastRead); idle < r.timeout {
+		r.timer.Reset(r.timeout - idle)
+		r.mu.Unlock()
+		return
+	}
+	r.expired = true
+	r.mu.Unlock()
+	_ = r.body.Close()
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, errRead := r.body.Read(p)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.expired {
+		return n, &StreamIdleTimeoutError{Timeout: r.timeout}
+	}
+	if n > 0 {
+		r.lastRead = time.Now()
+	}
+	return n, errRead
+}
+
+func (r *idleTimeoutReader) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	expired := r.expired
+	r.mu.Unlock()
+	r.timer.Stop()
+	if expired {
+		return nil
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,64 @@
+package helps
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestIdleTimeoutReaderFailsAfterSilence(t *testing.T) {
+	pr, pw := io.Pipe()
+	reader := NewIdleTimeoutReader(pr, 50*time.Millisecond)
+	defer func() { _ = reader.Close() }()
+
+	go func() {
+		_, _ = pw.Write([]byte("data: 1\n"))
+	}()
+	buf := make([]byte, 64)
+	if n, errRead := reader.Read(buf); errRead != nil || string(buf[:n]) != "data: 1\n" {
+		t.Fatalf("first read = %q, %v", buf[:n], errRead)
+	}
+
+	start := time.Now()
+	_, errRead := reader.Read(buf)
+	if !IsStreamIdleTimeout(errRead) {
+		t.Fatalf("read error = %v, want idle timeout", errRead)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("watchdog fired after %s", elapsed)
+	}
+	var idleErr *StreamIdleTimeoutError
+	if !errors.As(errRead, &idleErr) || idleErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("error = %#v, want timeout 50ms", errRead)
+	}
+}
+
+func TestIdleTimeoutReaderResetsOnData(t *testing.T) {
+	pr, pw := io.Pipe()
+	reader := NewIdleTimeoutReader(pr, 150*time.Millisecond)
+	defer func() { _ = reader.Close() }()
+
+	go func() {
+		// Pauses shorter than the threshold add up to well beyond it.
+		for i := 0; i < 6; i++ {
+			time.Sleep(50 * time.Millisecond)
+			_, _ = pw.Write([]byte("x"))
+		}
+		_ = pw.Close()
+	}()
+	data, errRead := io.ReadAll(reader)
+	if errRead != nil {
+		t.Fatalf("ReadAll() error = %v", errRead)
+	}
+	if string(data) != "xxxxxx" {
+		t.Fatalf("data = %q, want xxxxxx", data)
+	}
+}
+
+func TestNewIdleTimeoutReaderDisabled(t *testing.T) {
+	pr, _ := io.Pipe()
+	if got := NewIdleTimeoutReader(pr, 0); got != io.ReadCloser(pr) {
+		t.Fatalf("disabled watchdog wrapped the body")
+	}
+}
@@ -14,8 +14,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	internallogging "github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/requeststore"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -203,7 +207,7 @@ func (r *UsageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 	}
 	detail = normalizeUsageDetailTotal(detail, r.provider, r.executorType)
 	r.once.Do(func() {
-		r.publishRecord(ctx, r.buildRecord(detail, failed, fail))
+		r.publishPrimaryRecord(ctx, r.buildRecord(detail, failed, fail))
 	})
 }
 
@@ -231,13 +235,20 @@ func (r *UsageReporter) EnsurePublished(ctx context.Context) {
 		return
 	}
 	r.once.Do(func() {
-		r.publishRecord(ctx, r.buildRecord(usage.Detail{}, false, usage.Failure{}))
+		r.publishPrimaryRecord(ctx, r.buildRecord(usage.Detail{}, false, usage.Failure{}))
 	})
 }
 
+// publishPrimaryRecord publishes the record that completes the reporter's request.
+// Unlike additional-model records it also counts the request in metrics.
+func (r *UsageReporter) publishPrimaryRecord(ctx context.Context, record usage.Record) {
+	usage.RunWhenPublished(ctx, func() { metrics.ObserveUsage(ctx, record) })
+	r.publishRecord(ctx, record)
+}
+
 func (r *UsageReporter) publishRecord(ctx context.Context, record usage.Record) {
 	record.ResponseHeaders = internallogging.GetResponseHeaders(ctx)
-	usage.PublishRecord(ctx, record)
+	usage.PublishRecord(requeststore.FreezeCapture(ctx), record)
 }
 
 func (r *UsageReporter) buildRecord(detail usage.Detail, failed bool, failures ...usage.Failure) usage.Record {
@@ -514,6 +525,79 @@ func (b *StreamUsageBuffer) Detail() (usage.Detail, bool) {
 	return b.detail, true
 }
 
+// ObserveClaudeStream records the latest usage from a Claude SSE line. The
+// message_start usage is kept as well so a stream cut short before the final
+// message_delta still reports its input tokens.
+func (b *StreamUsageBuffer) ObserveClaudeStream(line []byte) {
+	if b == nil || !bytes.Contains(line, claudeStreamUsageMarker) {
+		return
+	}
+	if detail, ok := ParseClaudeStreamUsage(line); ok {
+		b.Observe(detail, true)
+		return
+	}
+	payload := jsonPayload(line)
+	if len(payload) == 0 || !gjson.ValidBytes(payload) {
+		return
+	}
+	if usageNode := gjson.GetBytes(payload, "message.usage"); usageNode.Exists() {
+		b.Observe(parseClaudeUsageNode(usageNode), true)
+	}
+}
+
+// ObserveGeminiStream records the latest usageMetadata from a Gemini-family
+// stream line, including the Antigravity response envelope. It must be fed the
+// raw line, before FilterSSEUsageMetadata strips intermediate usage.
+func (b *StreamUsageBuffer) ObserveGeminiStream(line []byte) {
+	if b == nil {
+		return
+	}
+	if !bytes.Contains(line, geminiStreamUsageMarker) && !bytes.Contains(line, geminiStreamUsageSnakeMarker) {
+		return
+	}
+	b.Observe(ParseAntigravityStreamUsage(line))
+}
+
+var (
+	claudeStreamUsageMarker      = []byte(`"usage"`)
+	geminiStreamUsageMarker      = []byte(`"usageMetadata"`)
+	geminiStreamUsageSnakeMarker = []byte(`"usage_metadata"`)
+)
+
+// ClientCancelled reports whether ctx ended because the downstream client went away.
+func ClientCancelled(ctx context.Context) bool {
+	return ctx != nil && errors.Is(ctx.Err(), context.Canceled)
+}
+
+// PublishClientCancelled publishes the latest usage observed by streamUsage as a
+// successful record when the client cancelled the request. The upstream already
+// generated (and billed) those tokens, so the disconnect must not be counted as
+// an upstream failure. It reports false, publishing nothing, when ctx is still
+// live or ended for another reason.
+func (r *UsageReporter) PublishClientCancelled(ctx context.Context, streamUsage *StreamUsageBuffer) bool {
+	if !ClientCancelled(ctx) {
+		return false
+	}
+	detail, _ := streamUsage.Detail()
+	LogWithRequestID(ctx).WithField("category", "client_cancelled").Debugf("client cancelled stream, publishing partial usage (total tokens: %d)", detail.TotalTokens)
+	r.Publish(ctx, detail)
+	return true
+}
+
+// SendStreamChunks forwards payloads to out in order. It reports false once the
+// client has gone away; callers should stop forwarding but keep draining the
+// upstream body so the usage already received is still accounted for.
+func SendStreamChunks(ctx context.Context, out chan<- cliproxyexecutor.StreamChunk, payloads [][]byte) bool {
+	for i := range payloads {
+		select {
+		case out <- cliproxyexecutor.StreamChunk{Payload: payloads[i]}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
 func ParseCodexUsage(data []byte) (usage.Detail, bool) {
 	responseServiceTier := extractResponseServiceTier(data)
 	usageNode := gjson.ParseBytes(data).Get("response.usage")
@@ -711,7 +795,7 @@ func parseClaudeUsageNode(usageNode gjson.Result) usage.Detail {
 }
 
 func parseGeminiFamilyUsageDetail(node gjson.Result) usage.Detail {
-	cachedTokens := node.Get("cachedContentTokenCount").Int()
+	cachedTokens := translatorcommon.GeminiCachedTokenCount(node)
 	toolUseTokens := firstExistingUsageNode(node, "toolUsePromptTokenCount", "tool_use_prompt_token_count").Int()
 	inputTokens, okInput := safeUsageTokenSum(node.Get("promptTokenCount").Int(), toolUseTokens)
 	detail := usage.Detail{
@@ -941,8 +1025,10 @@ func rememberStopWithoutUsage(traceID string) {
 }
 
 // FilterSSEUsageMetadata removes usageMetadata from SSE events that are not
-// terminal (finishReason != "stop"). Stop chunks are left untouched. This
-// function is shared between aistudio and antigravity executors.
+// terminal (finishReason != "stop"). The usageMetadata of stop chunks is kept whole,
+// with detail fields such as thoughtsTokenCount restored from earlier chunks of the
+// stream when the terminal chunk omits them. This function is shared between
+// aistudio and antigravity executors.
 func FilterSSEUsageMetadata(payload []byte) []byte {
 	if len(payload) == 0 {
 		return payload
@@ -962,19 +1048,7 @@ func FilterSSEUsageMetadata(payload []byte) []byte {
 			continue
 		}
 		rawJSON := bytes.TrimSpace(line[dataIdx+5:])
-		traceID := gjson.GetBytes(rawJSON, "traceId").String()
-		if isStopChunkWithoutUsage(rawJSON) && traceID != "" {
-			rememberStopWithoutUsage(traceID)
-			continue
-		}
-		if traceID != "" {
-			if _, ok := stopChunkWithoutUsage.Load(traceID); ok && hasUsageMetadata(rawJSON) {
-				stopChunkWithoutUsage.Delete(traceID)
-				continue
-			}
-		}
-
-		cleaned, changed := StripUsageMetadataFromJSON(rawJSON)
+		cleaned, changed := filterUsageMetadataJSON(rawJSON)
 		if !changed {
 			continue
 		}
@@ -992,7 +1066,7 @@ func FilterSSEUsageMetadata(payload []byte) []byte {
 		if !foundData {
 			// Handle payloads that are raw JSON without SSE data: prefix.
 			trimmed := bytes.TrimSpace(payload)
-			cleaned, changed := StripUsageMetadataFromJSON(trimmed)
+			cleaned, changed := filterUsageMetadataJSON(trimmed)
 			if !changed {
 				return payload
 			}
@@ -1003,6 +1077,167 @@ func FilterSSEUsageMetadata(payload []byte) []byte {
 	return bytes.Join(lines, []byte("\n"))
 }
 
+// filterUsageMetadataJSON applies FilterSSEUsageMetadata to a single JSON event.
+func filterUsageMetadataJSON(rawJSON []byte) ([]byte, bool) {
+	traceID := gjson.GetBytes(rawJSON, "traceId").String()
+	if isStopChunkWithoutUsage(rawJSON) && traceID != "" {
+		rememberStopWithoutUsage(traceID)
+		return rawJSON, false
+	}
+	terminal := hasFinishReason(rawJSON)
+	if traceID != "" && !terminal {
+		// Usage following a stop chunk that had none is the terminal usage.
+		if _, ok := stopChunkWithoutUsage.Load(traceID); ok && hasUsageMetadata(rawJSON) {
+			stopChunkWithoutUsage.Delete(traceID)
+			terminal = true
+		}
+	}
+	if terminal {
+		return completeTerminalUsage(rawJSON, traceID)
+	}
+	if traceID != "" {
+		if usageMetadata := usageMetadataNode(rawJSON); usageMetadata.Exists() {
+			rememberIntermediateUsage(traceID, usageMetadata)
+		}
+	}
+	return StripUsageMetadataFromJSON(rawJSON)
+}
+
+// usageDetailFields are the usageMetadata fields beyond the plain token totals. Upstream
+// terminal chunks sometimes omit them although earlier chunks of the stream reported them.
+var usageDetailFields = []string{
+	"thoughtsTokenCount",
+	"cachedContentTokenCount",
+	"toolUsePromptTokenCount",
+	"promptTokensDetails",
+	"cacheTokensDetails",
+	"candidatesTokensDetails",
+	"toolUsePromptTokensDetails",
+}
+
+type intermediateUsageEntry struct {
+	raw    string
+	expire time.Time
+}
+
+// intermediateUsage holds, per traceId, the usageMetadata last stripped from a
+// non-terminal chunk. Entries expire once their stream has been idle for
+// intermediateUsageTTL, and at most intermediateUsageMaxEntries streams are tracked.
+var (
+	intermediateUsage            = make(map[string]intermediateUsageEntry)
+	intermediateUsageMu          sync.Mutex
+	intermediateUsageCleanupOnce sync.Once
+)
+
+const (
+	intermediateUsageTTL           = 10 * time.Minute
+	intermediateUsageCleanupPeriod = 5 * time.Minute
+	intermediateUsageMaxEntries    = 4096
+)
+
+func rememberIntermediateUsage(traceID string, usageMetadata gjson.Result) {
+	intermediateUsageCleanupOnce.Do(startIntermediateUsageCleanup)
+	now := time.Now()
+	intermediateUsageMu.Lock()
+	defer intermediateUsageMu.Unlock()
+	if _, ok := intermediateUsage[traceID]; !ok && len(intermediateUsage) >= intermediateUsageMaxEntries {
+		purgeExpiredIntermediateUsageLocked(now)
+		if len(intermediateUsage) >= intermediateUsageMaxEntries {
+			return
+		}
+	}
+	intermediateUsage[traceID] = intermediateUsageEntry{raw: usageMetadata.Raw, expire: now.Add(intermediateUsageTTL)}
+}
+
+func takeIntermediateUsage(traceID string) (string, bool) {
+	intermediateUsageMu.Lock()
+	defer intermediateUsageMu.Unlock()
+	entry, ok := intermediateUsage[traceID]
+	if !ok {
+		return "", false
+	}
+	delete(intermediateUsage, traceID)
+	if !entry.expire.After(time.Now()) {
+		return "", false
+	}
+	return entry.raw, true
+}
+
+func startIntermediateUsageCleanup() {
+	go func() {
+		ticker := time.NewTicker(intermediateUsageCleanupPeriod)
+		defer ticker.Stop()
+		for range ticker.C {
+			intermediateUsageMu.Lock()
+			purgeExpiredIntermediateUsageLocked(time.Now())
+			intermediateUsageMu.Unlock()
+		}
+	}()
+}
+
+func purgeExpiredIntermediateUsageLocked(now time.Time) {
+	for traceID, entry := range intermediateUsage {
+		if !entry.expire.After(now) {
+			delete(intermediateUsage, traceID)
+		}
+	}
+}
+
+// completeTerminalUsage keeps the terminal usageMetadata of rawJSON whole: detail fields
+// it lacks are taken from the usage the stream reported last, and cachedContentTokenCount
+// is derived from cacheTokensDetails when only the breakdown is present.
+func completeTerminalUsage(rawJSON []byte, traceID string) ([]byte, bool) {
+	var previous gjson.Result
+	if traceID != "" {
+		if raw, ok := takeIntermediateUsage(traceID); ok {
+			previous = gjson.Parse(raw)
+		}
+	}
+	path := "usageMetadata"
+	usageMetadata := gjson.GetBytes(rawJSON, path)
+	if !usageMetadata.Exists() {
+		path = "response.usageMetadata"
+		usageMetadata = gjson.GetBytes(rawJSON, path)
+	}
+	if !usageMetadata.IsObject() {
+		return rawJSON, false
+	}
+
+	out := rawJSON
+	changed := false
+	for _, field := range usageDetailFields {
+		if usageMetadata.Get(field).Exists() {
+			continue
+		}
+		if value := previous.Get(field); value.Exists() {
+			out, _ = sjson.SetRawBytes(out, path+"."+field, []byte(value.Raw))
+			changed = true
+		}
+	}
+	if !gjson.GetBytes(out, path+".cachedContentTokenCount").Exists() {
+		if cached := translatorcommon.GeminiCachedTokenCount(gjson.GetBytes(out, path)); cached > 0 {
+			out, _ = sjson.SetBytes(out, path+".cachedContentTokenCount", cached)
+			changed = true
+		}
+	}
+	return out, changed
+}
+
+func usageMetadataNode(jsonBytes []byte) gjson.Result {
+	if usageMetadata := gjson.GetBytes(jsonBytes, "usageMetadata"); usageMetadata.Exists() {
+		return usageMetadata
+	}
+	return gjson.GetBytes(jsonBytes, "response.usageMetadata")
+}
+
+func hasFinishReason(jsonBytes []byte) bool {
+	finishReason := gjson.GetBytes(jsonBytes, "candidates.0.finishReason")
+	if !finishReason.Exists() {
+		finishReason = gjson.GetBytes(jsonBytes, "response.candidates.0.finishReason")
+	}
+	return strings.TrimSpace(finishReason.String()) != ""
+}
+
 // StripUsageMetadataFromJSON drops usageMetadata unless finishReason is present (terminal).
 // It handles both formats:
 // - Aistudio: candidates.0.finishReason
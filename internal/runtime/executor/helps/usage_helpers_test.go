@@ -2,6 +2,7 @@ package helps
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+	"github.com/tidwall/gjson"
 )
 
 func TestParseOpenAIUsageChatCompletions(t *testing.T) {
@@ -625,3 +627,60 @@ type TestUsageExecutor struct{}
 func (TestUsageExecutor) Identifier() string {
 	return "test-provider"
 }
+
+func TestFilterSSEUsageMetadataKeepsTerminalUsageDetails(t *testing.T) {
+	intermediate := []byte(`data: {"response":{"candidates":[{"content":{"parts":[{"text":"a"}]}}],"usageMetadata":{"promptTokenCount":10,"thoughtsTokenCount":7,"totalTokenCount":17,"promptTokensDetails":[{"modality":"TEXT","tokenCount":10}],"cacheTokensDetails":[{"modality":"TEXT","tokenCount":4}]}},"traceId":"trace-filter-details"}`)
+	terminal := []byte(`data: {"response":{"candidates":[{"content":{"parts":[{"text":"b"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":3,"totalTokenCount":20}},"traceId":"trace-filter-details"}`)
+
+	filtered := FilterSSEUsageMetadata(intermediate)
+	if _, ok := ParseAntigravityStreamUsage(filtered); ok {
+		t.Fatalf("intermediate chunk kept its usage: %s", filtered)
+	}
+	detail, ok := ParseAntigravityStreamUsage(FilterSSEUsageMetadata(terminal))
+	if !ok {
+		t.Fatal("terminal chunk lost its usage")
+	}
+	if detail.ReasoningTokens != 7 || detail.CachedTokens != 4 || detail.OutputTokens != 3 {
+		t.Fatalf("usage = %+v, want reasoning 7, cached 4, output 3", detail)
+	}
+
+	// Terminal chunks reporting the details themselves are left as they are.
+	complete := []byte(`data: {"response":{"candidates":[{"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":3,"thoughtsTokenCount":2,"cachedContentTokenCount":1,"totalTokenCount":15}}}`)
+	if got := FilterSSEUsageMetadata(complete); string(got) != string(complete) {
+		t.Fatalf("complete terminal chunk changed: %s", got)
+	}
+}
+
+func TestIntermediateUsageIsBounded(t *testing.T) {
+	intermediateUsageMu.Lock()
+	saved := intermediateUsage
+	intermediateUsage = make(map[string]intermediateUsageEntry)
+	intermediateUsageMu.Unlock()
+	t.Cleanup(func() {
+		intermediateUsageMu.Lock()
+		intermediateUsage = saved
+		intermediateUsageMu.Unlock()
+	})
+
+	usageMetadata := gjson.Parse(`{"thoughtsTokenCount":7}`)
+	rememberIntermediateUsage("trace-expired", usageMetadata)
+	intermediateUsageMu.Lock()
+	intermediateUsage["trace-expired"] = intermediateUsageEntry{raw: usageMetadata.Raw, expire: time.Now().Add(-time.Second)}
+	intermediateUsageMu.Unlock()
+	if _, ok := takeIntermediateUsage("trace-expired"); ok {
+		t.Fatal("expected expired intermediate usage to be ignored")
+	}
+
+	for i := 0; i < intermediateUsageMaxEntries+10; i++ {
+		rememberIntermediateUsage(fmt.Sprintf("trace-%d", i), usageMetadata)
+	}
+	intermediateUsageMu.Lock()
+	size := len(intermediateUsage)
+	intermediateUsageMu.Unlock()
+	if size != intermediateUsageMaxEntries {
+		t.Fatalf("tracked streams = %d, want capped at %d", size, intermediateUsageMaxEntries)
+	}
+	if _, ok := takeIntermediateUsage("trace-0"); !ok {
+		t.Fatal("expected usage of a tracked stream to be kept")
+	}
+}
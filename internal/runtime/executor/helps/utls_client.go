@@ -2,6 +2,8 @@ package helps
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -24,9 +26,14 @@ type utlsRoundTripper struct {
 	connections map[string]*http2.ClientConn
 	pending     map[string]*sync.Cond
 	dialer      proxy.Dialer
+
+	// tlsHandshakeTimeout and responseHeaderTimeout mirror the http.Transport fields of
+	// the same name; zero means no limit.
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
 }
 
-func newUtlsRoundTripper(proxyURL string) *utlsRoundTripper {
+func newUtlsRoundTripper(proxyURL string, settings config.ProviderHTTPConfig) *utlsRoundTripper {
 	var dialer proxy.Dialer = proxy.Direct
 	if proxyURL != "" {
 		proxyDialer, mode, errBuild := proxyutil.BuildDialer(proxyURL)
@@ -37,9 +44,11 @@ func newUtlsRoundTripper(proxyURL string) *utlsRoundTripper {
 		}
 	}
 	return &utlsRoundTripper{
-		connections: make(map[string]*http2.ClientConn),
-		pending:     make(map[string]*sync.Cond),
-		dialer:      dialer,
+		connections:           make(map[string]*http2.ClientConn),
+		pending:               make(map[string]*sync.Cond),
+		dialer:                dialer,
+		tlsHandshakeTimeout:   time.Duration(settings.TLSHandshakeTimeoutSeconds) * time.Second,
+		responseHeaderTimeout: time.Duration(settings.ResponseHeaderTimeoutSeconds) * time.Second,
 	}
 }
 
@@ -88,10 +97,16 @@ func (t *utlsRoundTripper) createConnection(host, addr string) (*http2.ClientCon
 	tlsConfig := &tls.Config{ServerName: host}
 	tlsConn := tls.UClient(conn, tlsConfig, tls.HelloChrome_Auto)
 
+	if t.tlsHandshakeTimeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(t.tlsHandshakeTimeout))
+	}
 	if err := tlsConn.Handshake(); err != nil {
 		conn.Close()
 		return nil, err
 	}
+	if t.tlsHandshakeTimeout > 0 {
+		_ = conn.SetDeadline(time.Time{})
+	}
 
 	tr := &http2.Transport{}
 	h2Conn, err := tr.NewClientConn(tlsConn)
@@ -116,7 +131,7 @@ func (t *utlsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 		return nil, err
 	}
 
-	resp, err := h2Conn.RoundTrip(req)
+	resp, err := roundTripWithHeaderTimeout(h2Conn.RoundTrip, req, t.responseHeaderTimeout)
 	if err != nil {
 		t.mu.Lock()
 		if cached, ok := t.connections[hostname]; ok && cached == h2Conn {
@@ -129,6 +144,47 @@ func (t *utlsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
+// errUtlsResponseHeaderTimeout is returned when the upstream sends no response headers
+// within the response header timeout.
+var errUtlsResponseHeaderTimeout = errors.New("utls: timeout awaiting response headers")
+
+// roundTripWithHeaderTimeout runs roundTrip and cancels the request when no response
+// headers arrive within timeout. The body of a response received in time stays readable
+// for as long as the caller needs it.
+func roundTripWithHeaderTimeout(roundTrip func(*http.Request) (*http.Response, error), req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return roundTrip(req)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	resp, err := roundTrip(req.WithContext(ctx))
+	if !timer.Stop() {
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		cancel()
+		return nil, errUtlsResponseHeaderTimeout
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnCloseBody releases the request context of a response once its body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // utlsProtectedHosts contains the hosts that should use utls Chrome TLS fingerprint
 // to bypass Cloudflare's TLS fingerprinting.
 var utlsProtectedHosts = map[string]struct{}{
@@ -154,7 +210,8 @@ func (f *fallbackRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 
 // NewUtlsHTTPClient creates an HTTP client using utls Chrome TLS fingerprint.
 // Use this for provider requests that need a Chrome-like TLS fingerprint.
-// Falls back to standard transport for non-HTTPS requests.
+// Falls back to standard transport for non-HTTPS requests. Transport settings from
+// providers.<auth.Provider> apply to both, as in NewProxyAwareHTTPClient.
 func NewUtlsHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyauth.Auth, timeout time.Duration) *http.Client {
 	var proxyURL string
 	if auth != nil {
@@ -169,13 +226,21 @@ func NewUtlsHTTPClient(ctx context.Context, cfg *config.Config, auth *cliproxyau
 		ctxRoundTripper, _ = ctx.Value("cliproxy.roundtripper").(http.RoundTripper)
 	}
 
-	var utlsRT http.RoundTripper = newUtlsRoundTripper(proxyURL)
+	settings := ProviderHTTPSettings(cfg, auth)
+	var utlsRT http.RoundTripper = newUtlsRoundTripper(proxyURL, settings)
 	var standardTransport http.RoundTripper = http.DefaultTransport
+	if transport, isTransport := http.DefaultTransport.(*http.Transport); isTransport {
+		standardTransport = TunedTransport(transport, settings)
+	}
 	if proxyURL != "" {
 		if transport := buildProxyTransport(proxyURL); transport != nil {
+			applyProviderTransportSettings(transport, settings)
 			standardTransport = transport
 		}
 	} else if ctxRoundTripper != nil {
+		if transport, isTransport := ctxRoundTripper.(*http.Transport); isTransport {
+			ctxRoundTripper = TunedTransport(transport, settings)
+		}
 		utlsRT = ctxRoundTripper
 		standardTransport = ctxRoundTripper
 	}
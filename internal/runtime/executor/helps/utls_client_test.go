@@ -2,10 +2,12 @@ package helps
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 type utlsClientRoundTripFunc func(*http.Request) (*http.Response, error)
@@ -43,3 +45,38 @@ func TestNewUtlsHTTPClientUsesContextRoundTripperForProtectedHost(t *testing.T)
 		t.Fatal("expected context RoundTripper to handle protected host request")
 	}
 }
+
+func TestRoundTripWithHeaderTimeout(t *testing.T) {
+	t.Parallel()
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.anthropic.com/v1/messages", nil)
+	stalled := func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	start := time.Now()
+	if _, err := roundTripWithHeaderTimeout(stalled, req, 50*time.Millisecond); !errors.Is(err, errUtlsResponseHeaderTimeout) {
+		t.Fatalf("roundTripWithHeaderTimeout() error = %v, want response header timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("header timeout took %v", elapsed)
+	}
+
+	var bodyCtx context.Context
+	answered := func(req *http.Request) (*http.Response, error) {
+		bodyCtx = req.Context()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+	}
+	resp, err := roundTripWithHeaderTimeout(answered, req, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("roundTripWithHeaderTimeout() error = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if data, errRead := io.ReadAll(resp.Body); errRead != nil || string(data) != "ok" || bodyCtx.Err() != nil {
+		t.Fatalf("body = %q, %v; want it readable after the header timeout", data, errRead)
+	}
+	_ = resp.Body.Close()
+	if bodyCtx.Err() == nil {
+		t.Fatal("expected closing the body to release the request context")
+	}
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	internallogging "github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func enableTestMetrics(t *testing.T) {
+	t.Helper()
+	metrics.Reset()
+	metrics.SetEnabled(true)
+	t.Cleanup(func() {
+		metrics.SetEnabled(false)
+		metrics.Reset()
+	})
+}
+
+func gatherMetrics(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if errWrite := metrics.Write(&buf); errWrite != nil {
+		t.Fatalf("metrics.Write() error = %v", errWrite)
+	}
+	return buf.String()
+}
+
+func assertSeries(t *testing.T, exposition string, series ...string) {
+	t.Helper()
+	for _, want := range series {
+		if !strings.Contains(exposition, want) {
+			t.Errorf("missing series %q in:\n%s", want, exposition)
+		}
+	}
+}
+
+func TestMetricsRecordGeminiRequest(t *testing.T) {
+	enableTestMetrics(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`))
+	}))
+	defer server.Close()
+
+	exec := NewGeminiExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "test-key", "base_url": server.URL},
+	}
+	ctx := internallogging.WithEndpoint(context.Background(), "POST /v1/chat/completions")
+	_, errExecute := exec.Execute(ctx, auth, cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash(8192)",
+		Payload: []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini})
+	if errExecute != nil {
+		t.Fatalf("Execute() error = %v", errExecute)
+	}
+
+	assertSeries(t, gatherMetrics(t),
+		`cliproxy_requests_total{handler="POST /v1/chat/completions",model="gemini-2.5-flash",provider="gemini",status="200"} 1`,
+		`cliproxy_upstream_latency_seconds_count{provider="gemini"} 1`,
+		`cliproxy_tokens_total{model="gemini-2.5-flash",type="prompt"} 3`,
+		`cliproxy_tokens_total{model="gemini-2.5-flash",type="completion"} 2`,
+	)
+}
+
+func TestMetricsRecordAntigravityBaseURLFallback(t *testing.T) {
+	enableTestMetrics(t)
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":429,"message":"rate limited","status":"RESOURCE_EXHAUSTED"}}`))
+	}))
+	defer limited.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]}}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":1,"totalTokenCount":2}}}`))
+	}))
+	defer healthy.Close()
+
+	originalOrder := antigravityBaseURLFallbackOrder
+	antigravityBaseURLFallbackOrder = func(*cliproxyauth.Auth) []string {
+		return []string{limited.URL, healthy.URL}
+	}
+	t.Cleanup(func() { antigravityBaseURLFallbackOrder = originalOrder })
+
+	exec := NewAntigravityExecutor(&config.Config{})
+	auth := &cliproxyauth.Auth{
+		ID: fmt.Sprintf("auth-metrics-fallback-%d", time.Now().UnixNano()),
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	_, errExecute := exec.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash",
+		Payload: []byte(`{"request":{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatAntigravity})
+	if errExecute != nil {
+		t.Fatalf("Execute() error = %v", errExecute)
+	}
+
+	assertSeries(t, gatherMetrics(t),
+		fmt.Sprintf(`cliproxy_antigravity_fallbacks_total{base_url=%q} 1`, limited.URL),
+		`cliproxy_requests_total{handler="unknown",model="gemini-2.5-flash",provider="antigravity",status="200"} 1`,
+	)
+}
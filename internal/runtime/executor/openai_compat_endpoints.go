@@ -0,0 +1,324 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor/helps"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	log "github.com/sirupsen/logrus"
+)
+
+const openAICompatEndpointRoundRobin = "round-robin"
+
+var (
+	// openAICompatEndpointFailureThreshold is the number of consecutive 5xx or
+	// connection errors after which an endpoint is demoted.
+	openAICompatEndpointFailureThreshold = 3
+	// openAICompatEndpointCooldown is how long a demoted endpoint is only used
+	// as a last resort.
+	openAICompatEndpointCooldown = 30 * time.Second
+	// openAICompatEndpointPools holds endpoint health per pooled auth. It lives
+	// outside the executor so health survives config reloads, which rebuild
+	// executors but keep the auth ID of an unchanged provider.
+	openAICompatEndpointPools = &openAICompatEndpointRegistry{pools: make(map[string]*openAICompatEndpointPool)}
+)
+
+// openAICompatEndpoint is one base URL and API key an OpenAI-compatible
+// request can be sent to.
+type openAICompatEndpoint struct {
+	baseURL  string
+	apiKey   string
+	proxyURL string
+	weight   int
+}
+
+func (ep openAICompatEndpoint) healthKey() string {
+	return ep.baseURL + "\x00" + ep.apiKey
+}
+
+type openAICompatEndpointRegistry struct {
+	mu    sync.Mutex
+	pools map[string]*openAICompatEndpointPool
+}
+
+func (r *openAICompatEndpointRegistry) pool(authID string) *openAICompatEndpointPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.pools[authID]
+	if p == nil {
+		p = &openAICompatEndpointPool{health: make(map[string]*openAICompatEndpointHealth)}
+		r.pools[authID] = p
+	}
+	return p
+}
+
+// forget drops the pool of authID.
+func (r *openAICompatEndpointRegistry) forget(authID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pools, authID)
+}
+
+// ForgetOpenAICompatEndpointHealthForAuthID drops the endpoint health tracked for a
+// pooled OpenAI-compatible auth once the auth is removed.
+func ForgetOpenAICompatEndpointHealthForAuthID(authID string) {
+	authID = strings.TrimSpace(authID)
+	if authID == "" {
+		return
+	}
+	openAICompatEndpointPools.forget(authID)
+}
+
+// openAICompatEndpointPool orders the endpoints of one pooled auth and tracks
+// their consecutive failures.
+type openAICompatEndpointPool struct {
+	mu     sync.Mutex
+	health map[string]*openAICompatEndpointHealth
+	cursor uint64
+}
+
+type openAICompatEndpointHealth struct {
+	failures     int
+	demotedUntil time.Time
+}
+
+// order returns endpoints in the order they should be tried. Healthy endpoints
+// come first, picked by weighted random draw or round-robin depending on mode;
+// demoted endpoints follow, soonest recovery first, so a request still goes out
+// when every endpoint is cooling down. Health for endpoints no longer
+// configured is dropped.
+func (p *openAICompatEndpointPool) order(mode string, endpoints []openAICompatEndpoint, now time.Time) []openAICompatEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	configured := make(map[string]struct{}, len(endpoints))
+	healthy := make([]openAICompatEndpoint, 0, len(endpoints))
+	demoted := make([]openAICompatEndpoint, 0)
+	for _, ep := range endpoints {
+		key := ep.healthKey()
+		configured[key] = struct{}{}
+		if h := p.health[key]; h != nil && now.Before(h.demotedUntil) {
+			demoted = append(demoted, ep)
+			continue
+		}
+		healthy = append(healthy, ep)
+	}
+	for key := range p.health {
+		if _, ok := configured[key]; !ok {
+			delete(p.health, key)
+		}
+	}
+
+	ordered := make([]openAICompatEndpoint, 0, len(endpoints))
+	if mode == openAICompatEndpointRoundRobin {
+		if len(healthy) > 0 {
+			start := int(p.cursor % uint64(len(healthy)))
+			p.cursor++
+			ordered = append(ordered, healthy[start:]...)
+			ordered = append(ordered, healthy[:start]...)
+		}
+	} else {
+		ordered = append(ordered, weightedEndpointOrder(healthy)...)
+	}
+	sort.SliceStable(demoted, func(i, j int) bool {
+		return p.health[demoted[i].healthKey()].demotedUntil.Before(p.health[demoted[j].healthKey()].demotedUntil)
+	})
+	return append(ordered, demoted...)
+}
+
+// markFailure counts a 5xx or connection error and demotes the endpoint once
+// the threshold is reached. A demoted endpoint that fails again after its
+// cooldown is demoted again right away.
+func (p *openAICompatEndpointPool) markFailure(ep openAICompatEndpoint, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.health[ep.healthKey()]
+	if h == nil {
+		h = &openAICompatEndpointHealth{}
+		p.health[ep.healthKey()] = h
+	}
+	h.failures++
+	if h.failures >= openAICompatEndpointFailureThreshold {
+		h.demotedUntil = now.Add(openAICompatEndpointCooldown)
+	}
+}
+
+func (p *openAICompatEndpointPool) markSuccess(ep openAICompatEndpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.health, ep.healthKey())
+}
+
+// weightedEndpointOrder draws endpoints without replacement, each draw weighted
+// by the remaining endpoints' weights.
+func weightedEndpointOrder(endpoints []openAICompatEndpoint) []openAICompatEndpoint {
+	remaining := append([]openAICompatEndpoint(nil), endpoints...)
+	ordered := make([]openAICompatEndpoint, 0, len(endpoints))
+	for len(remaining) > 0 {
+		total := 0
+		for _, ep := range remaining {
+			total += ep.weight
+		}
+		pick := rand.IntN(total)
+		idx := 0
+		for i, ep := range remaining {
+			if pick < ep.weight {
+				idx = i
+				break
+			}
+			pick -= ep.weight
+		}
+		ordered = append(ordered, remaining[idx])
+		remaining = append(remaining[:idx], remaining[idx+1:]...)
+	}
+	return ordered
+}
+
+// resolveEndpoints returns the endpoints a request for auth may use in the
+// order they should be tried, plus the pool tracking their health. Auths
+// without endpoint selection resolve to their own credentials and a nil pool.
+func (e *OpenAICompatExecutor) resolveEndpoints(auth *cliproxyauth.Auth) ([]openAICompatEndpoint, *openAICompatEndpointPool) {
+	baseURL, apiKey := e.resolveCredentials(auth)
+	var mode string
+	if auth != nil && auth.Attributes != nil {
+		mode = strings.TrimSpace(auth.Attributes["endpoint_selection"])
+	}
+	compat := e.resolveCompatConfig(auth)
+	if mode == "" || compat == nil || len(compat.APIKeyEntries) == 0 {
+		if baseURL == "" {
+			return nil, nil
+		}
+		single := openAICompatEndpoint{baseURL: baseURL, apiKey: apiKey, weight: 1}
+		if auth != nil {
+			single.proxyURL = auth.ProxyURL
+		}
+		return []openAICompatEndpoint{single}, nil
+	}
+
+	endpoints := make([]openAICompatEndpoint, 0, len(compat.APIKeyEntries))
+	for i := range compat.APIKeyEntries {
+		entry := &compat.APIKeyEntries[i]
+		ep := openAICompatEndpoint{
+			baseURL:  strings.TrimSpace(entry.BaseURL),
+			apiKey:   strings.TrimSpace(entry.APIKey),
+			proxyURL: strings.TrimSpace(entry.ProxyURL),
+			weight:   entry.Weight,
+		}
+		if ep.baseURL == "" {
+			ep.baseURL = strings.TrimSpace(compat.BaseURL)
+		}
+		if ep.baseURL == "" {
+			continue
+		}
+		if ep.weight <= 0 {
+			ep.weight = 1
+		}
+		endpoints = append(endpoints, ep)
+	}
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+	pool := openAICompatEndpointPools.pool(auth.ID)
+	return pool.order(mode, endpoints, time.Now()), pool
+}
+
+// doRequest posts body to path on the auth's endpoints, moving on to the next
+// endpoint after a connection error or 5xx response. The response of the last
+// attempt is returned as-is, so callers keep handling non-2xx statuses. header
+// is applied after the provider's custom headers.
+func (e *OpenAICompatExecutor) doRequest(ctx context.Context, auth *cliproxyauth.Auth, reporter *helps.UsageReporter, path string, body []byte, header http.Header, timeout time.Duration) (*http.Response, error) {
+	endpoints, pool := e.resolveEndpoints(auth)
+	if len(endpoints) == 0 {
+		return nil, statusErr{code: http.StatusUnauthorized, msg: "missing provider baseURL"}
+	}
+	var attrs map[string]string
+	var authID, authLabel, authType, authValue string
+	if auth != nil {
+		attrs = auth.Attributes
+		authID = auth.ID
+		authLabel = auth.Label
+		authType, authValue = auth.AccountInfo()
+	}
+
+	for i, ep := range endpoints {
+		last := i == len(endpoints)-1
+		url := strings.TrimSuffix(ep.baseURL, "/") + path
+		httpReq, errReq := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if errReq != nil {
+			return nil, errReq
+		}
+		if ep.apiKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+ep.apiKey)
+		}
+		httpReq.Header.Set("User-Agent", "cli-proxy-openai-compat")
+		helps.ApplyRequestIDHeader(httpReq)
+		util.ApplyCustomHeadersFromAttrs(httpReq, attrs)
+		helps.ApplyForwardedClientHeaders(ctx, httpReq.Header, attrs)
+		for key, values := range header {
+			httpReq.Header[key] = append([]string(nil), values...)
+		}
+
+		attemptAuth := auth
+		if pool != nil {
+			// Log the endpoint that actually served the attempt.
+			authType, authValue = "api_key", ep.apiKey
+			attemptAuth = auth.Clone()
+			attemptAuth.ProxyURL = ep.proxyURL
+		}
+		helps.RecordAPIRequest(ctx, e.cfg, helps.UpstreamRequestLog{
+			URL:       url,
+			Method:    http.MethodPost,
+			Headers:   httpReq.Header.Clone(),
+			Body:      body,
+			Provider:  e.Identifier(),
+			AuthID:    authID,
+			AuthLabel: authLabel,
+			AuthType:  authType,
+			AuthValue: authValue,
+		})
+
+		httpClient := helps.NewProxyAwareHTTPClient(ctx, e.cfg, attemptAuth, timeout)
+		httpClient = reporter.TrackHTTPClient(httpClient)
+		httpResp, errDo := httpClient.Do(httpReq)
+		if errDo != nil {
+			helps.RecordAPIResponseError(ctx, e.cfg, errDo)
+			if pool == nil || ctx.Err() != nil {
+				return nil, errDo
+			}
+			pool.markFailure(ep, time.Now())
+			if last {
+				return nil, errDo
+			}
+			helps.LogWithRequestID(ctx).Debugf("openai compat executor: endpoint %s failed, trying next: %v", ep.baseURL, errDo)
+			continue
+		}
+		helps.RecordAPIResponseMetadata(ctx, e.cfg, httpResp.StatusCode, httpResp.Header.Clone())
+		if pool == nil {
+			return httpResp, nil
+		}
+		if httpResp.StatusCode < http.StatusInternalServerError {
+			pool.markSuccess(ep)
+			return httpResp, nil
+		}
+		pool.markFailure(ep, time.Now())
+		if last {
+			return httpResp, nil
+		}
+		b, _ := io.ReadAll(httpResp.Body)
+		if errClose := httpResp.Body.Close(); errClose != nil {
+			log.Errorf("openai compat executor: close response body error: %v", errClose)
+		}
+		helps.AppendAPIResponseChunk(ctx, e.cfg, b)
+		helps.LogWithRequestID(ctx).Debugf("openai compat executor: endpoint %s returned status %d, trying next", ep.baseURL, httpResp.StatusCode)
+	}
+	return nil, statusErr{code: http.StatusBadGateway, msg: "no openai compat endpoint available"}
+}
@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func setOpenAICompatEndpointHealthSettings(t *testing.T, threshold int, cooldown time.Duration) {
+	t.Helper()
+	originalThreshold := openAICompatEndpointFailureThreshold
+	originalCooldown := openAICompatEndpointCooldown
+	openAICompatEndpointFailureThreshold = threshold
+	openAICompatEndpointCooldown = cooldown
+	t.Cleanup(func() {
+		openAICompatEndpointFailureThreshold = originalThreshold
+		openAICompatEndpointCooldown = originalCooldown
+	})
+}
+
+func TestOpenAICompatEndpointPoolShiftsTrafficFromDeadEndpointAndRecovers(t *testing.T) {
+	setOpenAICompatEndpointHealthSettings(t, 2, 200*time.Millisecond)
+
+	var deadHits, liveHits atomic.Int32
+	var deadDown atomic.Bool
+	deadDown.Store(true)
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadHits.Add(1)
+		if deadDown.Load() {
+			http.Error(w, `{"error":"replica down"}`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl_dead","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer dead.Close()
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		liveHits.Add(1)
+		if got := r.Header.Get("Authorization"); got != "Bearer live-key" {
+			t.Errorf("live Authorization = %q, want live key", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl_live","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer live.Close()
+
+	cfg := &config.Config{OpenAICompatibility: []config.OpenAICompatibility{{
+		Name:              "vllm",
+		BaseURL:           dead.URL,
+		EndpointSelection: "round-robin",
+		APIKeyEntries: []config.OpenAICompatibilityAPIKey{
+			{APIKey: "dead-key"},
+			{APIKey: "live-key", BaseURL: live.URL},
+		},
+	}}}
+	auth := &cliproxyauth.Auth{
+		ID:       fmt.Sprintf("openai-compat-endpoint-pool-%d", time.Now().UnixNano()),
+		Provider: "openai-compatible-vllm",
+		Attributes: map[string]string{
+			"base_url":           dead.URL,
+			"api_key":            "dead-key",
+			"compat_name":        "vllm",
+			"endpoint_selection": "round-robin",
+		},
+	}
+	executor := NewOpenAICompatExecutor("openai-compatible-vllm", cfg)
+	execute := func() {
+		t.Helper()
+		_, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+			Model:   "qwen",
+			Payload: []byte(`{"model":"qwen","messages":[{"role":"user","content":"hi"}]}`),
+		}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai")})
+		if err != nil {
+			t.Fatalf("Execute() error = %v, want failover to the live endpoint", err)
+		}
+	}
+
+	for i := 0; i < 6; i++ {
+		execute()
+	}
+	if got := deadHits.Load(); got != 2 {
+		t.Fatalf("dead endpoint hits = %d, want 2 before it is demoted", got)
+	}
+	if got := liveHits.Load(); got != 6 {
+		t.Fatalf("live endpoint hits = %d, want every request served by it", got)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	deadDown.Store(false)
+	for i := 0; i < 4; i++ {
+		execute()
+	}
+	if got := deadHits.Load(); got <= 2 {
+		t.Fatalf("dead endpoint hits = %d, want traffic back after the cooldown", got)
+	}
+	if got := liveHits.Load(); got >= 10 {
+		t.Fatalf("live endpoint hits = %d, want the recovered endpoint to share traffic", got)
+	}
+}
+
+func TestOpenAICompatEndpointPoolKeepsHealthForUnchangedEntries(t *testing.T) {
+	setOpenAICompatEndpointHealthSettings(t, 1, time.Minute)
+	now := time.Now()
+	pool := &openAICompatEndpointPool{health: make(map[string]*openAICompatEndpointHealth)}
+	dead := openAICompatEndpoint{baseURL: "https://a.example.com/v1", apiKey: "a", weight: 1}
+	removed := openAICompatEndpoint{baseURL: "https://b.example.com/v1", apiKey: "b", weight: 1}
+	pool.markFailure(dead, now)
+	pool.markFailure(removed, now)
+
+	// Reload: b is removed and c is added, a is unchanged.
+	added := openAICompatEndpoint{baseURL: "https://c.example.com/v1", apiKey: "c", weight: 1}
+	ordered := pool.order("weighted", []openAICompatEndpoint{dead, added}, now)
+	if len(ordered) != 2 || ordered[0] != added || ordered[1] != dead {
+		t.Fatalf("order = %+v, want healthy new entry before the still-demoted one", ordered)
+	}
+	if _, ok := pool.health[removed.healthKey()]; ok {
+		t.Fatal("health for a removed entry was kept")
+	}
+
+	ordered = pool.order("weighted", []openAICompatEndpoint{dead, added}, now.Add(2*time.Minute))
+	if len(ordered) != 2 {
+		t.Fatalf("order after cooldown = %+v, want both endpoints", ordered)
+	}
+	pool.markSuccess(dead)
+	if _, ok := pool.health[dead.healthKey()]; ok {
+		t.Fatal("success did not clear the failure count")
+	}
+}
+
+func TestForgetOpenAICompatEndpointHealthForAuthID(t *testing.T) {
+	authID := fmt.Sprintf("compat-pool-removed-%d", time.Now().UnixNano())
+	pool := openAICompatEndpointPools.pool(authID)
+	pool.markFailure(openAICompatEndpoint{baseURL: "https://a.example.com/v1", apiKey: "a", weight: 1}, time.Now())
+
+	ForgetOpenAICompatEndpointHealthForAuthID(" " + authID + " ")
+	openAICompatEndpointPools.mu.Lock()
+	_, ok := openAICompatEndpointPools.pools[authID]
+	openAICompatEndpointPools.mu.Unlock()
+	if ok {
+		t.Fatal("endpoint pool of a removed auth was kept")
+	}
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestOpenAICompatExecutorExtraBodyPassthrough(t *testing.T) {
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	executor := NewOpenAICompatExecutor("openai-compatibility", &config.Config{
+		OpenAICompatibility: []config.OpenAICompatibility{{
+			Name:                 "vllm",
+			BaseURL:              server.URL + "/v1",
+			ExtraBodyPassthrough: []string{"top_k", "repetition_penalty", "guided_json", "min_p"},
+		}},
+		Payload: config.PayloadConfig{
+			Default: []config.PayloadRule{{
+				Models: []config.PayloadModelRule{{Name: "local-model", Protocol: "openai"}},
+				Params: map[string]any{"top_k": 5, "min_p": 0.1},
+			}},
+			Override: []config.PayloadRule{{
+				Models: []config.PayloadModelRule{{Name: "local-model", Protocol: "openai"}},
+				Params: map[string]any{"repetition_penalty": 1.5},
+			}},
+		},
+	})
+	auth := &cliproxyauth.Auth{Provider: "vllm", Attributes: map[string]string{
+		"base_url":    server.URL + "/v1",
+		"api_key":     "test",
+		"compat_name": "vllm",
+	}}
+	payload := []byte(`{"model":"local-model","input":"hi","top_k":40,"repetition_penalty":1.1,"guided_json":{"type":"object"},"best_of":3}`)
+	_, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "local-model",
+		Payload: payload,
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FromString("openai-response"),
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+
+	if got := gjson.GetBytes(gotBody, "top_k").Int(); got != 40 {
+		t.Fatalf("top_k = %d, want the client value 40 over the default rule; body=%s", got, gotBody)
+	}
+	if got := gjson.GetBytes(gotBody, "guided_json").Raw; got != `{"type":"object"}` {
+		t.Fatalf("guided_json = %s, want the client object; body=%s", got, gotBody)
+	}
+	if got := gjson.GetBytes(gotBody, "repetition_penalty").Float(); got != 1.5 {
+		t.Fatalf("repetition_penalty = %v, want the override rule value 1.5; body=%s", got, gotBody)
+	}
+	if got := gjson.GetBytes(gotBody, "min_p").Float(); got != 0.1 {
+		t.Fatalf("min_p = %v, want the default rule value for a listed field the client omitted; body=%s", got, gotBody)
+	}
+	if gjson.GetBytes(gotBody, "best_of").Exists() {
+		t.Fatalf("unlisted field best_of reached the upstream; body=%s", gotBody)
+	}
+}
+
+func TestOpenAICompatExecutorForwardsAllowlistedClientHeaders(t *testing.T) {
+	var gotHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	recorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(recorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ginCtx.Request.Header.Set("X-Tenant-Id", "tenant-a")
+	ginCtx.Request.Header.Set("X-Other", "dropped")
+	ginCtx.Request.Header.Set("Authorization", "Bearer client-key")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	executor := NewOpenAICompatExecutor("openai-compatibility", &config.Config{})
+	auth := &cliproxyauth.Auth{Provider: "vllm", Attributes: map[string]string{
+		"base_url":               server.URL + "/v1",
+		"api_key":                "upstream-key",
+		"compat_name":            "vllm",
+		"forward_client_headers": "X-Tenant-Id,Authorization",
+	}}
+	_, err := executor.Execute(ctx, auth, cliproxyexecutor.Request{
+		Model:   "local-model",
+		Payload: []byte(`{"model":"local-model","messages":[{"role":"user","content":"hi"}]}`),
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FromString("openai"),
+	})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+
+	if got := gotHeaders.Get("X-Tenant-Id"); got != "tenant-a" {
+		t.Fatalf("X-Tenant-Id = %q, want the client value", got)
+	}
+	if got := gotHeaders.Get("X-Other"); got != "" {
+		t.Fatalf("X-Other = %q, want unlisted headers dropped", got)
+	}
+	if got := gotHeaders.Get("Authorization"); got != "Bearer upstream-key" {
+		t.Fatalf("Authorization = %q, want the upstream key", got)
+	}
+}
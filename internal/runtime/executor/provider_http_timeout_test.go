@@ -0,0 +1,207 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+const providerTimeoutChunk = `{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]}}]}`
+
+func newProviderTimeoutExecutor(serverURL string) (*GeminiExecutor, *cliproxyauth.Auth) {
+	cfg := &config.Config{Providers: map[string]config.ProviderHTTPConfig{
+		"gemini": {RequestTimeoutSeconds: 1, ResponseHeaderTimeoutSeconds: 1},
+	}}
+	auth := &cliproxyauth.Auth{
+		Provider:   "gemini",
+		Attributes: map[string]string{"api_key": "test-key", "base_url": serverURL},
+	}
+	return NewGeminiExecutor(cfg), auth
+}
+
+func providerTimeoutRequest() (cliproxyexecutor.Request, cliproxyexecutor.Options) {
+	return cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash",
+		Payload: []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`),
+	}, cliproxyexecutor.Options{
+		SourceFormat: sdktranslator.FormatGemini,
+	}
+}
+
+func TestProviderResponseHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2500 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = w.Write([]byte(providerTimeoutChunk))
+	}))
+	defer server.Close()
+
+	exec, auth := newProviderTimeoutExecutor(server.URL)
+	req, opts := providerTimeoutRequest()
+
+	start := time.Now()
+	if _, errExecute := exec.Execute(context.Background(), auth, req, opts); errExecute == nil {
+		t.Fatal("Execute() error = nil, want response header timeout")
+	}
+	opts.Stream = true
+	if result, errStream := exec.ExecuteStream(context.Background(), auth, req, opts); errStream == nil {
+		var chunkErr error
+		for chunk := range result.Chunks {
+			if chunk.Err != nil {
+				chunkErr = chunk.Err
+			}
+		}
+		if chunkErr == nil {
+			t.Fatal("ExecuteStream() succeeded, want response header timeout")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Fatalf("header timeouts took %v, want both calls to fail after about 1s", elapsed)
+	}
+}
+
+func TestProviderRequestTimeoutSparesActiveStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 2; i++ {
+			_, _ = w.Write([]byte("data: " + providerTimeoutChunk + "\n\n"))
+			if flusher != nil {
+				flusher.Flush()
+			}
+			select {
+			case <-time.After(1500 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	exec, auth := newProviderTimeoutExecutor(server.URL)
+	req, opts := providerTimeoutRequest()
+	opts.Stream = true
+
+	result, errStream := exec.ExecuteStream(context.Background(), auth, req, opts)
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	var payloads int
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error = %v, want slow body to be tolerated", chunk.Err)
+		}
+		if strings.Contains(string(chunk.Payload), `"ok"`) {
+			payloads++
+		}
+	}
+	if payloads != 2 {
+		t.Fatalf("received %d payload chunks, want 2", payloads)
+	}
+}
+
+func TestProviderRequestTimeoutAppliesToUtlsClients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Providers: map[string]config.ProviderHTTPConfig{
+		"claude": {RequestTimeoutSeconds: 1},
+		"codex":  {RequestTimeoutSeconds: 1},
+	}}
+	claudePayload := []byte(`{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`)
+	codexPayload := []byte(`{"model":"gpt-5.4-mini","input":"hi"}`)
+	tests := []struct {
+		name    string
+		execute func(auth *cliproxyauth.Auth) error
+	}{
+		{name: "claude", execute: func(auth *cliproxyauth.Auth) error {
+			_, err := NewClaudeExecutor(cfg).Execute(context.Background(), auth,
+				cliproxyexecutor.Request{Model: "claude-sonnet-4-5", Payload: claudePayload},
+				cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: claudePayload})
+			return err
+		}},
+		{name: "claude count tokens", execute: func(auth *cliproxyauth.Auth) error {
+			_, err := NewClaudeExecutor(cfg).CountTokens(context.Background(), auth,
+				cliproxyexecutor.Request{Model: "claude-sonnet-4-5", Payload: claudePayload},
+				cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: claudePayload})
+			return err
+		}},
+		{name: "codex", execute: func(auth *cliproxyauth.Auth) error {
+			_, err := NewCodexExecutor(cfg).Execute(context.Background(), auth,
+				cliproxyexecutor.Request{Model: "gpt-5.4-mini", Payload: codexPayload},
+				cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai-response"), OriginalRequest: codexPayload})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := strings.Fields(tt.name)[0]
+			auth := &cliproxyauth.Auth{
+				Provider:   provider,
+				Attributes: map[string]string{"api_key": "test-key", "base_url": server.URL},
+			}
+			start := time.Now()
+			if errExecute := tt.execute(auth); errExecute == nil {
+				t.Fatal("Execute() error = nil, want request timeout")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("request took %v, want it cut off after about 1s", elapsed)
+			}
+		})
+	}
+}
+
+func TestProviderResponseHeaderTimeoutAppliesToUtlsClients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2500 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Providers: map[string]config.ProviderHTTPConfig{
+		"claude": {ResponseHeaderTimeoutSeconds: 1},
+	}}
+	auth := &cliproxyauth.Auth{
+		Provider:   "claude",
+		Attributes: map[string]string{"api_key": "test-key", "base_url": server.URL},
+	}
+	payload := []byte(`{"model":"claude-sonnet-4-5","max_tokens":16,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+
+	start := time.Now()
+	result, errStream := NewClaudeExecutor(cfg).ExecuteStream(context.Background(), auth,
+		cliproxyexecutor.Request{Model: "claude-sonnet-4-5", Payload: payload},
+		cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: payload, Stream: true})
+	if errStream == nil {
+		var chunkErr error
+		for chunk := range result.Chunks {
+			if chunk.Err != nil {
+				chunkErr = chunk.Err
+			}
+		}
+		if chunkErr == nil {
+			t.Fatal("ExecuteStream() succeeded, want response header timeout")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("stream took %v, want it cut off after about 1s", elapsed)
+	}
+}
@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func TestExecutorsSendRequestIDUpstream(t *testing.T) {
+	var gotRequestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequestID = r.Header.Get(logging.RequestIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","model":"claude-3-5-sonnet","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl_1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		execute func(ctx context.Context) error
+	}{
+		{
+			name: "claude",
+			execute: func(ctx context.Context) error {
+				auth := &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "key-123", "base_url": server.URL}}
+				_, err := NewClaudeExecutor(&config.Config{}).Execute(ctx, auth, cliproxyexecutor.Request{
+					Model:   "claude-3-5-sonnet-20241022",
+					Payload: []byte(`{"messages":[{"role":"user","content":"hi"}]}`),
+				}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")})
+				return err
+			},
+		},
+		{
+			name: "openai-compatibility",
+			execute: func(ctx context.Context) error {
+				auth := &cliproxyauth.Auth{Provider: "local", Attributes: map[string]string{"base_url": server.URL + "/v1", "api_key": "test"}}
+				_, err := NewOpenAICompatExecutor("openai-compatibility", &config.Config{}).Execute(ctx, auth, cliproxyexecutor.Request{
+					Model:   "local-model",
+					Payload: []byte(`{"model":"local-model","messages":[{"role":"user","content":"hi"}]}`),
+				}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai")})
+				return err
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotRequestID = ""
+			if err := tc.execute(logging.WithRequestID(context.Background(), "gw-req-42")); err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+			if gotRequestID != "gw-req-42" {
+				t.Fatalf("upstream %s = %q, want gw-req-42", logging.RequestIDHeader, gotRequestID)
+			}
+
+			gotRequestID = "unset"
+			if err := tc.execute(context.Background()); err != nil {
+				t.Fatalf("Execute error: %v", err)
+			}
+			if gotRequestID != "" {
+				t.Fatalf("upstream %s = %q without a request ID, want none", logging.RequestIDHeader, gotRequestID)
+			}
+		})
+	}
+}
+
+func TestClaudeOAuthRequestOmitsRequestID(t *testing.T) {
+	gotRequestID := "unset"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRequestID = r.Header.Get(logging.RequestIDHeader)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","model":"claude-3-5-sonnet","role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "sk-ant-oat01-token", "base_url": server.URL}}
+	_, err := NewClaudeExecutor(&config.Config{}).Execute(logging.WithRequestID(context.Background(), "gw-req-42"), auth, cliproxyexecutor.Request{
+		Model:   "claude-3-5-sonnet-20241022",
+		Payload: []byte(`{"messages":[{"role":"user","content":"hi"}]}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude")})
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if gotRequestID != "" {
+		t.Fatalf("upstream %s = %q on a cloaked OAuth request, want none", logging.RequestIDHeader, gotRequestID)
+	}
+}
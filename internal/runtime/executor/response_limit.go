@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+)
+
+// responseTooLargeError reports an upstream response body over the buffering limit.
+// The size is a property of the request, not of the credential, so it is request
+// scoped: the conductor neither cools the auth down nor retries another one.
+type responseTooLargeError struct {
+	statusErr
+}
+
+func (responseTooLargeError) IsRequestScoped() bool {
+	return true
+}
+
+// readUpstreamResponseBody buffers a non-streaming upstream response body. It fails
+// with a 502 responseTooLargeError when the body exceeds server.max-response-body-bytes
+// so a runaway upstream cannot balloon memory.
+func readUpstreamResponseBody(cfg *config.Config, body io.Reader) ([]byte, error) {
+	var limit int64
+	if cfg != nil {
+		limit = cfg.Server.EffectiveMaxResponseBodyBytes()
+	} else {
+		limit = config.DefaultMaxResponseBodyBytes
+	}
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return data, err
+	}
+	if int64(len(data)) > limit {
+		return nil, responseTooLargeError{statusErr: statusErr{code: http.StatusBadGateway, msg: fmt.Sprintf("upstream response exceeds the maximum buffered size of %d bytes", limit)}}
+	}
+	return data, nil
+}
@@ -0,0 +1,125 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+func TestOpenAICompatExecutorRejectsOversizedUpstreamResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"message":{"content":"` + strings.Repeat("x", 256) + `"}}]}`))
+	}))
+	defer server.Close()
+
+	executor := NewOpenAICompatExecutor("openai-compatibility", &config.Config{Server: config.ServerConfig{MaxResponseBodyBytes: 64}})
+	auth := &cliproxyauth.Auth{Attributes: map[string]string{
+		"base_url": server.URL + "/v1",
+		"api_key":  "test",
+	}}
+	_, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gpt-4o",
+		Payload: []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai")})
+	if err == nil {
+		t.Fatal("expected oversized upstream response to fail")
+	}
+	status, ok := err.(responseTooLargeError)
+	if !ok || status.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("error = %#v, want 502 responseTooLargeError", err)
+	}
+	if !strings.Contains(status.Error(), "64 bytes") {
+		t.Fatalf("error message = %q, want limit mentioned", status.Error())
+	}
+}
+
+func TestCodexExecutorRejectsOversizedUpstreamResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"type":"response.output_text.delta","delta":"` + strings.Repeat("x", 256) + `"}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	executor := NewCodexExecutor(&config.Config{Server: config.ServerConfig{MaxResponseBodyBytes: 64}})
+	auth := &cliproxyauth.Auth{Provider: "codex", Attributes: map[string]string{
+		"base_url": server.URL,
+		"api_key":  "test",
+	}}
+	payload := []byte(`{"model":"gpt-5.4-mini","input":"hi"}`)
+	_, err := executor.Execute(context.Background(), auth, cliproxyexecutor.Request{
+		Model:   "gpt-5.4-mini",
+		Payload: payload,
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai-response"), OriginalRequest: payload})
+	status, ok := err.(responseTooLargeError)
+	if !ok || status.StatusCode() != http.StatusBadGateway {
+		t.Fatalf("error = %#v, want 502 responseTooLargeError", err)
+	}
+}
+
+func TestOversizedUpstreamResponseDoesNotCoolDownAuth(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"` + strings.Repeat("x", 256) + `"}]}`))
+	}))
+	defer server.Close()
+
+	model := "claude-sonnet-4-5"
+	manager := cliproxyauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(NewClaudeExecutor(&config.Config{Server: config.ServerConfig{MaxResponseBodyBytes: 64}}))
+	reg := registry.GetGlobalRegistry()
+	for i := 0; i < 2; i++ {
+		auth := &cliproxyauth.Auth{
+			ID:         fmt.Sprintf("claude-oversized-%d-%d", i, time.Now().UnixNano()),
+			Provider:   "claude",
+			Attributes: map[string]string{"api_key": fmt.Sprintf("key-%d", i), "base_url": server.URL},
+		}
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("Register() error = %v", errRegister)
+		}
+		reg.RegisterClient(auth.ID, "claude", []*registry.ModelInfo{{ID: model}})
+		authID := auth.ID
+		t.Cleanup(func() { reg.UnregisterClient(authID) })
+	}
+
+	payload := []byte(`{"model":"claude-sonnet-4-5","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`)
+	_, errExecute := manager.Execute(context.Background(), []string{"claude"}, cliproxyexecutor.Request{
+		Model:   model,
+		Payload: payload,
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), OriginalRequest: payload})
+	if errExecute == nil {
+		t.Fatal("expected oversized upstream response to fail")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("upstream requests = %d, want 1 without retrying another auth", got)
+	}
+	for _, auth := range manager.List() {
+		if state := auth.ModelStates[model]; state != nil && (state.Unavailable || state.NextRetryAfter.After(time.Now())) {
+			t.Fatalf("auth %s model state = %+v, want no cooldown", auth.ID, state)
+		}
+	}
+}
+
+func TestReadUpstreamResponseBodyWithinLimit(t *testing.T) {
+	data, err := readUpstreamResponseBody(&config.Config{Server: config.ServerConfig{MaxResponseBodyBytes: 4}}, strings.NewReader("abcd"))
+	if err != nil || string(data) != "abcd" {
+		t.Fatalf("data = %q, err = %v", data, err)
+	}
+	data, err = readUpstreamResponseBody(&config.Config{Server: config.ServerConfig{MaxResponseBodyBytes: -1}}, strings.NewReader(strings.Repeat("y", 1024)))
+	if err != nil || len(data) != 1024 {
+		t.Fatalf("disabled limit: len = %d, err = %v", len(data), err)
+	}
+}
@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+// malformSSE rewrites a single-line SSE stream the way some proxies do: CRLF line endings,
+// and every long data payload split across several data lines, mid-token.
+func malformSSE(stream string) string {
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(stream, "\n"), "\n") {
+		value, isData := strings.CutPrefix(line, "data: ")
+		if !isData || len(value) <= 12 {
+			out.WriteString(line + "\r\n")
+			continue
+		}
+		for len(value) > 12 {
+			out.WriteString("data: " + value[:12] + "\r\n")
+			value = value[12:]
+		}
+		out.WriteString("data: " + value + "\r\n")
+	}
+	return out.String()
+}
+
+// collectSSEStream runs stream against an upstream answering with body and returns the
+// concatenated chunk payloads.
+func collectSSEStream(t *testing.T, body string, stream func(baseURL string) (*cliproxyexecutor.StreamResult, error)) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	result, errStream := stream(server.URL)
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	var out strings.Builder
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error: %v", chunk.Err)
+		}
+		out.Write(chunk.Payload)
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+func TestExecuteStreamToleratesMalformedSSE(t *testing.T) {
+	apiKeyAuth := func(baseURL string) *cliproxyauth.Auth {
+		return &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "test-key", "base_url": baseURL}}
+	}
+	toolCallChunk := `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{"q":"weather in Paris"}}}]}}]}`
+	finalChunk := `{"candidates":[{"content":{"role":"model","parts":[{"text":"done"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`
+	geminiPayload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+
+	tests := []struct {
+		name   string
+		body   string
+		stream func(baseURL string) (*cliproxyexecutor.StreamResult, error)
+	}{
+		{
+			name: "antigravity",
+			body: "data: {\"response\":" + toolCallChunk + "}\n\ndata: {\"response\":" + finalChunk + "}\n\n",
+			stream: func(baseURL string) (*cliproxyexecutor.StreamResult, error) {
+				return NewAntigravityExecutor(&config.Config{}).ExecuteStream(context.Background(), apiKeyAuth(baseURL),
+					cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: geminiPayload},
+					cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: geminiPayload, Stream: true})
+			},
+		},
+		{
+			name: "gemini",
+			body: "data: " + toolCallChunk + "\n\ndata: " + finalChunk + "\n\n",
+			stream: func(baseURL string) (*cliproxyexecutor.StreamResult, error) {
+				return NewGeminiExecutor(&config.Config{}).ExecuteStream(context.Background(), apiKeyAuth(baseURL),
+					cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: geminiPayload},
+					cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: geminiPayload, Stream: true})
+			},
+		},
+		{
+			name: "claude",
+			body: "event: content_block_start\n" +
+				"data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"lookup\",\"input\":{}}}\n\n" +
+				"event: content_block_delta\n" +
+				"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"q\\\":\\\"weather in Paris\\\"}\"}}\n\n" +
+				"event: message_stop\n" +
+				"data: {\"type\":\"message_stop\"}\n\n",
+			stream: func(baseURL string) (*cliproxyexecutor.StreamResult, error) {
+				payload := []byte(`{"messages":[{"role":"user","content":"hi"}]}`)
+				return NewClaudeExecutor(&config.Config{}).ExecuteStream(context.Background(), apiKeyAuth(baseURL),
+					cliproxyexecutor.Request{Model: "claude-3-5-sonnet-20241022", Payload: payload},
+					cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatClaude, OriginalRequest: payload, Stream: true})
+			},
+		},
+		{
+			name: "codex",
+			body: "event: response.output_item.done\n" +
+				"data: {\"type\":\"response.output_item.done\",\"output_index\":0,\"item\":{\"type\":\"function_call\",\"call_id\":\"call_1\",\"name\":\"lookup\",\"arguments\":\"{\\\"q\\\":\\\"weather in Paris\\\"}\"}}\n\n" +
+				"event: response.completed\n" +
+				"data: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_1\",\"object\":\"response\",\"status\":\"completed\",\"model\":\"gpt-5.4-mini\",\"output\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":2,\"total_tokens\":5}}}\n\n",
+			stream: func(baseURL string) (*cliproxyexecutor.StreamResult, error) {
+				payload := []byte(`{"model":"gpt-5.4-mini","input":"hi"}`)
+				return NewCodexExecutor(&config.Config{}).ExecuteStream(context.Background(), apiKeyAuth(baseURL),
+					cliproxyexecutor.Request{Model: "gpt-5.4-mini", Payload: payload},
+					cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai-response"), OriginalRequest: payload, Stream: true})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := collectSSEStream(t, tt.body, tt.stream)
+			if !strings.Contains(want, "lookup") {
+				t.Fatalf("single-line stream output lacks the tool call: %s", want)
+			}
+			if got := collectSSEStream(t, malformSSE(tt.body), tt.stream); got != want {
+				t.Fatalf("malformed stream output = %s\nwant the single-line output %s", got, want)
+			}
+		})
+	}
+}
+
+func TestClaudeExecuteToleratesMalformedSSE(t *testing.T) {
+	body := "event: message_start\n" +
+		"data: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-3-5-sonnet-20241022\",\"content\":[],\"usage\":{\"input_tokens\":3,\"output_tokens\":0}}}\n\n" +
+		"event: content_block_start\n" +
+		"data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"lookup\",\"input\":{}}}\n\n" +
+		"event: content_block_delta\n" +
+		"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"q\\\":\\\"weather in Paris\\\"}\"}}\n\n" +
+		"event: content_block_stop\n" +
+		"data: {\"type\":\"content_block_stop\",\"index\":0}\n\n" +
+		"event: message_delta\n" +
+		"data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"tool_use\"},\"usage\":{\"output_tokens\":2}}\n\n" +
+		"event: message_stop\n" +
+		"data: {\"type\":\"message_stop\"}\n\n"
+	execute := func(body string) string {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte(body))
+		}))
+		defer server.Close()
+
+		auth := &cliproxyauth.Auth{Attributes: map[string]string{"api_key": "test-key", "base_url": server.URL}}
+		payload := []byte(`{"model":"claude-3-5-sonnet-20241022","messages":[{"role":"user","content":"hi"}]}`)
+		resp, errExecute := NewClaudeExecutor(&config.Config{}).Execute(context.Background(), auth,
+			cliproxyexecutor.Request{Model: "claude-3-5-sonnet-20241022", Payload: payload},
+			cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatOpenAI, OriginalRequest: payload})
+		if errExecute != nil {
+			t.Fatalf("Execute() error = %v", errExecute)
+		}
+		return string(resp.Payload)
+	}
+
+	want := execute(body)
+	if !strings.Contains(want, "lookup") {
+		t.Fatalf("single-line response lacks the tool call: %s", want)
+	}
+	if got := execute(malformSSE(body)); got != want {
+		t.Fatalf("malformed response = %s\nwant the single-line response %s", got, want)
+	}
+}
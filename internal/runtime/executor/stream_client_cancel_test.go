@@ -0,0 +1,224 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+type captureStreamCancelUsagePlugin struct {
+	authID  string
+	records chan usage.Record
+}
+
+func (p *captureStreamCancelUsagePlugin) HandleUsage(_ context.Context, record usage.Record) {
+	if p == nil || record.AuthID != p.authID {
+		return
+	}
+	select {
+	case p.records <- record:
+	default:
+	}
+}
+
+// newStalledStreamServer writes events, then holds the response open until the
+// client goes away, like an upstream still generating when the client cancels.
+func newStalledStreamServer(t *testing.T, events ...string) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range events {
+			_, _ = w.Write([]byte(event))
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return server
+}
+
+// cancelAfterChunks reads n payload chunks, cancels the request and drains the
+// rest of the stream, failing on any forwarded error.
+func cancelAfterChunks(t *testing.T, cancel context.CancelFunc, chunks <-chan cliproxyexecutor.StreamChunk, n int) {
+	t.Helper()
+	received := 0
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error = %v, want client cancellation to end the stream quietly", chunk.Err)
+		}
+		if len(chunk.Payload) == 0 {
+			continue
+		}
+		received++
+		if received == n {
+			cancel()
+		}
+	}
+	if received < n {
+		t.Fatalf("received %d chunks, want at least %d", received, n)
+	}
+}
+
+func waitForStreamCancelUsageRecord(t *testing.T, records <-chan usage.Record) usage.Record {
+	t.Helper()
+	select {
+	case record := <-records:
+		return record
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for usage record")
+		return usage.Record{}
+	}
+}
+
+func TestAntigravityStreamClientCancelPublishesPartialUsage(t *testing.T) {
+	server := newStalledStreamServer(t,
+		"data: {\"response\":{\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"a\"}]}}],\"usageMetadata\":{\"promptTokenCount\":7,\"candidatesTokenCount\":1,\"totalTokenCount\":8}}}\n\n",
+		"data: {\"response\":{\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"b\"}]}}],\"usageMetadata\":{\"promptTokenCount\":7,\"candidatesTokenCount\":2,\"totalTokenCount\":9}}}\n\n",
+	)
+
+	originalOrder := antigravityBaseURLFallbackOrder
+	antigravityBaseURLFallbackOrder = func(*cliproxyauth.Auth) []string { return []string{server.URL} }
+	t.Cleanup(func() { antigravityBaseURLFallbackOrder = originalOrder })
+
+	auth := &cliproxyauth.Auth{
+		ID: fmt.Sprintf("auth-stream-cancel-antigravity-%d", time.Now().UnixNano()),
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	plugin := &captureStreamCancelUsagePlugin{authID: auth.ID, records: make(chan usage.Record, 4)}
+	usage.RegisterPlugin(plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result, errStream := NewAntigravityExecutor(&config.Config{}).ExecuteStream(ctx, auth, cliproxyexecutor.Request{
+		Model:   "gemini-2.5-flash",
+		Payload: []byte(`{"request":{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatAntigravity, Stream: true})
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	cancelAfterChunks(t, cancel, result.Chunks, 2)
+
+	record := waitForStreamCancelUsageRecord(t, plugin.records)
+	if record.Failed {
+		t.Fatalf("record failed = true (%+v), want client cancellation published as success", record.Fail)
+	}
+	if record.Detail.InputTokens != 7 || record.Detail.OutputTokens != 2 {
+		t.Fatalf("usage = %+v, want last seen usage (input 7, output 2)", record.Detail)
+	}
+}
+
+func TestClaudeStreamClientCancelPublishesPartialUsage(t *testing.T) {
+	server := newStalledStreamServer(t,
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"usage\":{\"input_tokens\":11,\"output_tokens\":1}}}\n\n",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hi\"}}\n\n",
+	)
+
+	auth := &cliproxyauth.Auth{
+		ID:         fmt.Sprintf("auth-stream-cancel-claude-%d", time.Now().UnixNano()),
+		Attributes: map[string]string{"api_key": "key-123", "base_url": server.URL},
+	}
+	plugin := &captureStreamCancelUsagePlugin{authID: auth.ID, records: make(chan usage.Record, 4)}
+	usage.RegisterPlugin(plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result, errStream := NewClaudeExecutor(&config.Config{}).ExecuteStream(ctx, auth, cliproxyexecutor.Request{
+		Model:   "claude-3-5-sonnet-20241022",
+		Payload: []byte(`{"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`),
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("claude"), Stream: true})
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	cancelAfterChunks(t, cancel, result.Chunks, 2)
+
+	record := waitForStreamCancelUsageRecord(t, plugin.records)
+	if record.Failed {
+		t.Fatalf("record failed = true (%+v), want client cancellation published as success", record.Fail)
+	}
+	if record.Detail.InputTokens != 11 {
+		t.Fatalf("usage = %+v, want message_start input tokens", record.Detail)
+	}
+}
+
+func TestStreamClientCancelPublishesUsageRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		stream func(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyexecutor.StreamResult, error)
+	}{
+		{
+			name: "codex",
+			events: []string{
+				"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"a\"}\n\n",
+				"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"b\"}\n\n",
+			},
+			stream: func(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyexecutor.StreamResult, error) {
+				payload := []byte(`{"model":"gpt-5.4-mini","input":"hi"}`)
+				return NewCodexExecutor(&config.Config{}).ExecuteStream(ctx, auth,
+					cliproxyexecutor.Request{Model: "gpt-5.4-mini", Payload: payload},
+					cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai-response"), OriginalRequest: payload, Stream: true})
+			},
+		},
+		{
+			name: "vertex",
+			events: []string{
+				"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"a\"}]}}]}\n\n",
+				"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"b\"}]}}]}\n\n",
+			},
+			stream: func(ctx context.Context, auth *cliproxyauth.Auth) (*cliproxyexecutor.StreamResult, error) {
+				payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+				return NewGeminiVertexExecutor(&config.Config{}).ExecuteStream(ctx, auth,
+					cliproxyexecutor.Request{Model: "gemini-2.5-flash", Payload: payload},
+					cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, OriginalRequest: payload, Stream: true})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStalledStreamServer(t, tt.events...)
+			auth := &cliproxyauth.Auth{
+				ID:         fmt.Sprintf("auth-stream-cancel-%s-%d", tt.name, time.Now().UnixNano()),
+				Attributes: map[string]string{"api_key": "key-123", "base_url": server.URL},
+			}
+			plugin := &captureStreamCancelUsagePlugin{authID: auth.ID, records: make(chan usage.Record, 4)}
+			usage.RegisterPlugin(plugin)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			result, errStream := tt.stream(ctx, auth)
+			if errStream != nil {
+				t.Fatalf("ExecuteStream() error = %v", errStream)
+			}
+			cancelAfterChunks(t, cancel, result.Chunks, 2)
+
+			record := waitForStreamCancelUsageRecord(t, plugin.records)
+			if record.Failed {
+				t.Fatalf("record failed = true (%+v), want client cancellation published as success", record.Fail)
+			}
+		})
+	}
+}
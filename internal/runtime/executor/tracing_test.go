@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
+	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestManagerStreamTracesBaseURLFallback(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { tracing.SetTracerProvider(nil) })
+
+	limited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`rate limited`))
+	}))
+	defer limited.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"ok"}]},"finishReason":"STOP"}]}}` + "\n\n"))
+	}))
+	defer healthy.Close()
+	withAntigravityBaseURLs(t, limited.URL, healthy.URL)
+
+	model := "gemini-2.5-flash"
+	auth := &cliproxyauth.Auth{
+		ID:       fmt.Sprintf("tracing-antigravity-%d", time.Now().UnixNano()),
+		Provider: "antigravity",
+		Label:    "tracing@example.com",
+		Metadata: map[string]any{
+			"access_token": "token",
+			"project_id":   "project-1",
+			"expired":      time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+	}
+	manager := cliproxyauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(NewAntigravityExecutor(&config.Config{}))
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("Register() error = %v", errRegister)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(auth.ID, "antigravity", []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() { reg.UnregisterClient(auth.ID) })
+
+	ctx, root := tracing.Start(context.Background(), "request")
+	payload := []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)
+	result, errExecute := manager.ExecuteStream(ctx, []string{"antigravity"}, cliproxyexecutor.Request{
+		Model:   model,
+		Payload: payload,
+	}, cliproxyexecutor.Options{SourceFormat: sdktranslator.FormatGemini, Stream: true, OriginalRequest: payload})
+	if errExecute != nil {
+		t.Fatalf("ExecuteStream() error = %v", errExecute)
+	}
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			t.Fatalf("stream chunk error: %v", chunk.Err)
+		}
+	}
+	root.End()
+
+	rootID := root.SpanContext().SpanID()
+	var selectSpan, attemptSpan *tracetest.SpanStub
+	var upstream []tracetest.SpanStub
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		spans := exporter.GetSpans()
+		selectSpan, attemptSpan, upstream = nil, nil, nil
+		for i := range spans {
+			switch {
+			case spans[i].Name == "auth.select":
+				selectSpan = &spans[i]
+			case spans[i].Name == "upstream.attempt":
+				attemptSpan = &spans[i]
+			case strings.HasPrefix(spans[i].Name, "upstream "):
+				upstream = append(upstream, spans[i])
+			}
+		}
+		if (selectSpan != nil && attemptSpan != nil && len(upstream) == 2) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if selectSpan == nil || selectSpan.Parent.SpanID() != rootID {
+		t.Fatalf("auth.select span missing or not a child of the request span: %+v", selectSpan)
+	}
+	if attemptSpan == nil || attemptSpan.Parent.SpanID() != rootID {
+		t.Fatalf("upstream.attempt span missing or not a child of the request span: %+v", attemptSpan)
+	}
+	if !hasAttribute(attemptSpan.Attributes, tracing.AttrAuthLabel, "tracing@example.com") {
+		t.Fatalf("upstream.attempt attributes = %v, want auth label", attemptSpan.Attributes)
+	}
+	if len(upstream) != 2 {
+		t.Fatalf("upstream HTTP spans = %d, want 2 (429 then fallback)", len(upstream))
+	}
+	wantHosts := []string{strings.TrimPrefix(limited.URL, "http://"), strings.TrimPrefix(healthy.URL, "http://")}
+	wantStatus := []int64{http.StatusTooManyRequests, http.StatusOK}
+	for i, span := range upstream {
+		if span.Parent.SpanID() != attemptSpan.SpanContext.SpanID() {
+			t.Fatalf("upstream span %q is not a child of the attempt span", span.Name)
+		}
+		if !strings.HasSuffix(span.Name, wantHosts[i]) {
+			t.Fatalf("upstream span %d = %q, want host %s", i, span.Name, wantHosts[i])
+		}
+		if !hasIntAttribute(span.Attributes, tracing.AttrStatus, wantStatus[i]) {
+			t.Fatalf("upstream span %q attributes = %v, want status %d", span.Name, span.Attributes, wantStatus[i])
+		}
+	}
+	if upstream[0].SpanContext.TraceID() != trace.SpanContextFromContext(ctx).TraceID() {
+		t.Fatal("upstream spans are not in the request trace")
+	}
+}
+
+func hasAttribute(attrs []attribute.KeyValue, key attribute.Key, value string) bool {
+	for _, attr := range attrs {
+		if attr.Key == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasIntAttribute(attrs []attribute.KeyValue, key attribute.Key, value int64) bool {
+	for _, attr := range attrs {
+		if attr.Key == key && attr.Value.AsInt64() == value {
+			return true
+		}
+	}
+	return false
+}
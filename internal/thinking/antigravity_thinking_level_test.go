@@ -0,0 +1,66 @@
+package thinking_test
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/thinking/provider/antigravity"
+	"github.com/tidwall/gjson"
+)
+
+// The level-vs-budget decision for Antigravity must follow registry metadata,
+// not the model name, so aliases with opaque upstream names keep thinkingLevel.
+func TestAntigravityThinkingLevelDecision(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	clientID := "test-antigravity-thinking-level"
+	reg.RegisterClient(clientID, "antigravity", []*registry.ModelInfo{
+		{
+			ID:       "rev19-uic3-1p",
+			Object:   "model",
+			OwnedBy:  "antigravity",
+			Type:     "antigravity",
+			Thinking: &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true, Levels: []string{"low", "high"}},
+		},
+		{
+			ID:       "test-antigravity-budget-only",
+			Object:   "model",
+			OwnedBy:  "antigravity",
+			Type:     "antigravity",
+			Thinking: &registry.ThinkingSupport{Min: 128, Max: 32768, DynamicAllowed: true},
+		},
+	})
+	t.Cleanup(func() { reg.UnregisterClient(clientID) })
+
+	body := []byte(`{"request":{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"thinkingConfig":{"thinkingLevel":"high"}}}}`)
+	tests := []struct {
+		name      string
+		model     string
+		wantLevel bool
+	}{
+		{name: "alias with levels", model: "rev19-uic3-1p", wantLevel: true},
+		{name: "budget-only model", model: "test-antigravity-budget-only", wantLevel: false},
+		{name: "unregistered gemini 3 model", model: "gemini-3.5-user-defined", wantLevel: true},
+		{name: "unregistered older model", model: "gemini-2.0-user-defined", wantLevel: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := thinking.ApplyThinking(body, tt.model, "antigravity", "antigravity", "antigravity")
+			if err != nil {
+				t.Fatalf("ApplyThinking returned error: %v", err)
+			}
+			thinkingConfig := gjson.GetBytes(out, "request.generationConfig.thinkingConfig")
+			level := thinkingConfig.Get("thinkingLevel")
+			budget := thinkingConfig.Get("thinkingBudget")
+			if tt.wantLevel {
+				if level.String() != "high" || budget.Exists() {
+					t.Fatalf("thinkingConfig = %s, want thinkingLevel high without budget", thinkingConfig.Raw)
+				}
+				return
+			}
+			if level.Exists() || !budget.Exists() {
+				t.Fatalf("thinkingConfig = %s, want thinkingBudget without level", thinkingConfig.Raw)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@
 package thinking
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"sync"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 )
@@ -162,6 +166,12 @@ func IsUserDefinedModel(modelInfo *registry.ModelInfo) bool {
 //	// Without suffix - uses body config
 //	result, err := thinking.ApplyThinking(body, "gemini-2.5-pro", "gemini", "gemini", "gemini")
 func ApplyThinking(body []byte, model string, fromFormat string, toFormat string, providerKey string) ([]byte, error) {
+	return ApplyThinkingWithTrace(context.Background(), body, model, fromFormat, toFormat, providerKey)
+}
+
+// ApplyThinkingWithTrace behaves like ApplyThinking and records the thinking decision,
+// including clamps and conversions made during validation, in the transformation trace of ctx.
+func ApplyThinkingWithTrace(ctx context.Context, body []byte, model string, fromFormat string, toFormat string, providerKey string) ([]byte, error) {
 	providerFormat := strings.ToLower(strings.TrimSpace(toFormat))
 	providerKey = strings.ToLower(strings.TrimSpace(providerKey))
 	if providerKey == "" {
@@ -191,7 +201,7 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 	// Unknown models are treated as user-defined so thinking config can still be applied.
 	// The upstream service is responsible for validating the configuration.
 	if IsUserDefinedModel(modelInfo) {
-		return applyUserDefinedModel(body, modelInfo, fromFormat, providerFormat, suffixResult)
+		return applyUserDefinedModel(ctx, body, modelInfo, fromFormat, providerFormat, suffixResult)
 	}
 	if modelInfo.Thinking == nil {
 		config := extractThinkingConfig(body, providerFormat)
@@ -200,6 +210,7 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 				"model":    baseModel,
 				"provider": providerFormat,
 			}).Debug("thinking: model does not support thinking, stripping config |")
+			transformtrace.Record(ctx, "thinking: %s config stripped, model does not support thinking", thinkingConfigSource(suffixResult.HasSuffix))
 			return StripThinkingConfig(body, providerFormat), nil
 		}
 		log.WithFields(log.Fields{
@@ -212,7 +223,7 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 	// 4. Get config: suffix priority over body
 	var config ThinkingConfig
 	if suffixResult.HasSuffix {
-		config = parseSuffixToConfig(suffixResult.RawSuffix, providerFormat, model)
+		config = parseSuffixToConfig(suffixResult.RawSuffix, providerFormat, model, modelInfo)
 		log.WithFields(log.Fields{
 			"provider": providerFormat,
 			"model":    model,
@@ -249,6 +260,7 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 			"model":    modelInfo.ID,
 			"error":    err.Error(),
 		}).Warn("thinking: validation failed |")
+		transformtrace.Record(ctx, "thinking: %s %s rejected: %s", thinkingConfigSource(suffixResult.HasSuffix), describeThinkingConfig(config), err.Error())
 		// Return original body on validation failure (defensive programming).
 		// This ensures callers who ignore the error won't receive nil body.
 		// The upstream service will decide how to handle the unmodified request.
@@ -271,6 +283,7 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 		"budget":   validated.Budget,
 		"level":    validated.Level,
 	}).Debug("thinking: processed config to apply |")
+	recordThinkingDecision(ctx, suffixResult.HasSuffix, config, *validated)
 
 	// 6. Apply configuration using provider-specific applier
 	return applier.Apply(body, *validated, modelInfo)
@@ -281,10 +294,13 @@ func ApplyThinking(body []byte, model string, fromFormat string, toFormat string
 // Parsing priority:
 //  1. Special values: "none" → ModeNone, "auto"/"-1" → ModeAuto
 //  2. Level names: "minimal", "low", "medium", "high", "xhigh" → ModeLevel
-//  3. Numeric values: positive integers → ModeBudget, 0 → ModeNone
+//  3. Numeric values: positive integers, optionally with a "k" multiplier → ModeBudget, 0 → ModeNone
+//  4. Percentages: "50%" → ModeBudget with that share of modelInfo's maximum budget
 //
 // If none of the above match, returns empty ThinkingConfig (treated as no config).
-func parseSuffixToConfig(rawSuffix, provider, model string) ThinkingConfig {
+// A percentage for a model without a maximum budget is logged and also returns an
+// empty ThinkingConfig.
+func parseSuffixToConfig(rawSuffix, provider, model string, modelInfo *registry.ModelInfo) ThinkingConfig {
 	// 1. Try special values first (none, auto, -1)
 	if mode, ok := ParseSpecialSuffix(rawSuffix); ok {
 		switch mode {
@@ -308,6 +324,23 @@ func parseSuffixToConfig(rawSuffix, provider, model string) ThinkingConfig {
 		return ThinkingConfig{Mode: ModeBudget, Budget: budget}
 	}
 
+	// 4. Try percentage parsing against the model's maximum budget
+	if percent, ok := ParsePercentSuffix(rawSuffix); ok {
+		if modelInfo == nil || modelInfo.Thinking == nil || modelInfo.Thinking.Max <= 0 {
+			log.WithFields(log.Fields{
+				"provider":   provider,
+				"model":      model,
+				"raw_suffix": rawSuffix,
+			}).Error("thinking: percentage suffix needs a model with a maximum thinking budget, treating as no config |")
+			return ThinkingConfig{}
+		}
+		budget := modelInfo.Thinking.Max * percent / 100
+		if budget == 0 {
+			return ThinkingConfig{Mode: ModeNone, Budget: 0}
+		}
+		return ThinkingConfig{Mode: ModeBudget, Budget: budget}
+	}
+
 	// Unknown suffix format - return empty config
 	log.WithFields(log.Fields{
 		"provider":   provider,
@@ -319,7 +352,7 @@ func parseSuffixToConfig(rawSuffix, provider, model string) ThinkingConfig {
 
 // applyUserDefinedModel applies thinking configuration for user-defined models
 // without ThinkingSupport validation.
-func applyUserDefinedModel(body []byte, modelInfo *registry.ModelInfo, fromFormat, toFormat string, suffixResult SuffixResult) ([]byte, error) {
+func applyUserDefinedModel(ctx context.Context, body []byte, modelInfo *registry.ModelInfo, fromFormat, toFormat string, suffixResult SuffixResult) ([]byte, error) {
 	// Get model ID for logging
 	modelID := ""
 	if modelInfo != nil {
@@ -331,7 +364,7 @@ func applyUserDefinedModel(body []byte, modelInfo *registry.ModelInfo, fromForma
 	// Get config: suffix priority over body
 	var config ThinkingConfig
 	if suffixResult.HasSuffix {
-		config = parseSuffixToConfig(suffixResult.RawSuffix, toFormat, modelID)
+		config = parseSuffixToConfig(suffixResult.RawSuffix, toFormat, modelID, modelInfo)
 		log.WithFields(log.Fields{
 			"provider": toFormat,
 			"model":    modelID,
@@ -372,7 +405,9 @@ func applyUserDefinedModel(body []byte, modelInfo *registry.ModelInfo, fromForma
 		return body, nil
 	}
 
-	config = normalizeUserDefinedConfig(config, fromFormat, toFormat)
+	requested := config
+	config = normalizeUserDefinedConfig(config, modelInfo, modelID, toFormat)
+	recordThinkingDecision(ctx, suffixResult.HasSuffix, requested, config)
 	log.WithFields(log.Fields{
 		"provider": toFormat,
 		"model":    modelID,
@@ -383,13 +418,49 @@ func applyUserDefinedModel(body []byte, modelInfo *registry.ModelInfo, fromForma
 	return applier.Apply(body, config, modelInfo)
 }
 
-func normalizeUserDefinedConfig(config ThinkingConfig, fromFormat, toFormat string) ThinkingConfig {
+// recordThinkingDecision records the thinking config taken from the model suffix or request
+// and, when validation changed it, what it was clamped or converted to.
+func recordThinkingDecision(ctx context.Context, fromSuffix bool, requested, applied ThinkingConfig) {
+	source := thinkingConfigSource(fromSuffix)
+	from, to := describeThinkingConfig(requested), describeThinkingConfig(applied)
+	switch {
+	case from == to:
+		transformtrace.Record(ctx, "thinking: %s %s applied", source, from)
+	case requested.Mode == applied.Mode:
+		transformtrace.Record(ctx, "thinking: %s %s clamped to %s", source, from, to)
+	default:
+		transformtrace.Record(ctx, "thinking: %s %s converted to %s", source, from, to)
+	}
+}
+
+func thinkingConfigSource(fromSuffix bool) string {
+	if fromSuffix {
+		return "suffix"
+	}
+	return "request"
+}
+
+func describeThinkingConfig(config ThinkingConfig) string {
+	switch config.Mode {
+	case ModeBudget:
+		return fmt.Sprintf("budget=%d", config.Budget)
+	case ModeLevel:
+		return fmt.Sprintf("level=%s", config.Level)
+	default:
+		return config.Mode.String()
+	}
+}
+
+func normalizeUserDefinedConfig(config ThinkingConfig, modelInfo *registry.ModelInfo, modelID, toFormat string) ThinkingConfig {
 	if config.Mode != ModeLevel {
 		return config
 	}
 	if toFormat == "claude" {
 		return config
 	}
+	if isGeminiFamily(toFormat) && usesThinkingLevels(modelInfo, modelID) {
+		return config
+	}
 	if !isBudgetCapableProvider(toFormat) {
 		return config
 	}
@@ -403,6 +474,17 @@ func normalizeUserDefinedConfig(config ThinkingConfig, fromFormat, toFormat stri
 	return config
 }
 
+// usesThinkingLevels reports whether a user-defined model expects a discrete
+// thinkingLevel rather than a budget. Registry thinking metadata decides when
+// present; the Gemini 3 name heuristic is only a fallback for models the
+// registry knows nothing about, so aliases with opaque upstream names work.
+func usesThinkingLevels(modelInfo *registry.ModelInfo, modelID string) bool {
+	if modelInfo != nil && modelInfo.Thinking != nil {
+		return len(modelInfo.Thinking.Levels) > 0
+	}
+	return util.IsGemini3Model(modelID)
+}
+
 // extractThinkingConfig extracts provider-specific thinking config from request body.
 func extractThinkingConfig(body []byte, provider string) ThinkingConfig {
 	if len(body) == 0 || !gjson.ValidBytes(body) {
@@ -473,7 +555,7 @@ func reasoningEffortFromSuffix(suffix SuffixResult) string {
 	if !suffix.HasSuffix {
 		return ""
 	}
-	return reasoningEffortFromConfig(parseSuffixToConfig(suffix.RawSuffix, "", suffix.ModelName))
+	return reasoningEffortFromConfig(parseSuffixToConfig(suffix.RawSuffix, "", suffix.ModelName, registry.LookupModelInfo(suffix.ModelName)))
 }
 
 func reasoningEffortFromConfig(config ThinkingConfig) string {
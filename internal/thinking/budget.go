@@ -0,0 +1,72 @@
+package thinking
+
+import "github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+
+// Budget clamping policy.
+//
+// Every thinking budget adjustment goes through ClampBudget and FitBudgetToMaxTokens, so
+// the provider appliers and the executor payload helpers agree on the outcome for the
+// same input.
+//
+// ClampBudget fits a budget into the model range during ValidateConfig, for every
+// provider:
+//   - -1 (auto) is kept.
+//   - 0 is kept when ZeroAllowed; otherwise it is raised to Min, because thinking cannot
+//     be turned off for the model.
+//   - A budget below Min is raised to Min and one above Max is lowered to Max.
+//   - Models without a numeric range (level-only) keep the value.
+//
+// FitBudgetToMaxTokens applies the output limit of targets that need the budget below
+// max tokens: Claude, Claude models on Antigravity, and the output caps the executors
+// write for Claude and Gemini-family payloads:
+//   - A budget at or above the limit is lowered to limit-1.
+//   - When no budget in [Min, limit) exists, thinking is removed from the request
+//     instead of sending a budget the upstream rejects.
+
+// ClampBudget fits value into the budget range of support. See the policy above.
+func ClampBudget(value int, support *registry.ThinkingSupport) int {
+	if support == nil || value == -1 {
+		return value
+	}
+	min, max := support.Min, support.Max
+	if value == 0 {
+		if support.ZeroAllowed {
+			return 0
+		}
+		return min
+	}
+	if min == 0 && max == 0 {
+		return value
+	}
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+// FitBudgetToMaxTokens keeps budget below maxTokens for providers with that constraint.
+// It returns the budget to send and whether thinking stays enabled. A false result means
+// the thinking configuration must be removed: the budget is 0, or lowering it below
+// maxTokens would fall under the model minimum. Auto (-1) and a non-positive maxTokens
+// leave the budget unchanged.
+func FitBudgetToMaxTokens(budget, maxTokens int, support *registry.ThinkingSupport) (int, bool) {
+	if budget == -1 {
+		return budget, true
+	}
+	if budget == 0 {
+		return 0, false
+	}
+	if maxTokens > 0 && budget >= maxTokens {
+		budget = maxTokens - 1
+	}
+	if support != nil && support.Min > 0 && budget < support.Min {
+		return 0, false
+	}
+	if budget <= 0 {
+		return 0, false
+	}
+	return budget, true
+}
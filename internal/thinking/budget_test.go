@@ -0,0 +1,63 @@
+package thinking
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+)
+
+func TestClampBudget(t *testing.T) {
+	budget := &registry.ThinkingSupport{Min: 1024, Max: 32000}
+	toggle := &registry.ThinkingSupport{Min: 1024, Max: 32000, ZeroAllowed: true}
+	levels := &registry.ThinkingSupport{Levels: []string{"low", "high"}}
+
+	tests := []struct {
+		name    string
+		value   int
+		support *registry.ThinkingSupport
+		want    int
+	}{
+		{name: "auto kept", value: -1, support: budget, want: -1},
+		{name: "zero raised to min", value: 0, support: budget, want: 1024},
+		{name: "zero allowed", value: 0, support: toggle, want: 0},
+		{name: "below min", value: 100, support: toggle, want: 1024},
+		{name: "above max", value: 64000, support: budget, want: 32000},
+		{name: "in range", value: 8192, support: budget, want: 8192},
+		{name: "level-only model", value: 8192, support: levels, want: 8192},
+		{name: "no support", value: 8192, support: nil, want: 8192},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClampBudget(tt.value, tt.support); got != tt.want {
+				t.Fatalf("ClampBudget(%d) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitBudgetToMaxTokens(t *testing.T) {
+	support := &registry.ThinkingSupport{Min: 1024, Max: 128000, ZeroAllowed: true}
+
+	tests := []struct {
+		name      string
+		budget    int
+		maxTokens int
+		want      int
+		enabled   bool
+	}{
+		{name: "below limit", budget: 8192, maxTokens: 16000, want: 8192, enabled: true},
+		{name: "lowered below limit", budget: 8192, maxTokens: 4096, want: 4095, enabled: true},
+		{name: "limit below min", budget: 8192, maxTokens: 1000, enabled: false},
+		{name: "zero disables", budget: 0, maxTokens: 16000, enabled: false},
+		{name: "auto kept", budget: -1, maxTokens: 1000, want: -1, enabled: true},
+		{name: "no limit", budget: 8192, maxTokens: 0, want: 8192, enabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, enabled := FitBudgetToMaxTokens(tt.budget, tt.maxTokens, support)
+			if enabled != tt.enabled || (enabled && got != tt.want) {
+				t.Fatalf("FitBudgetToMaxTokens(%d, %d) = (%d, %v), want (%d, %v)", tt.budget, tt.maxTokens, got, enabled, tt.want, tt.enabled)
+			}
+		})
+	}
+}
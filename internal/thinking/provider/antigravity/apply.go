@@ -201,22 +201,15 @@ func (a *Applier) normalizeClaudeBudget(budget int, payload []byte, modelInfo *r
 		return budget, payload
 	}
 
-	// Get effective max tokens
+	// Fit the budget below the effective max tokens; remove the thinking config when no
+	// budget at or above the model minimum fits.
 	effectiveMax, setDefaultMax := a.effectiveMaxTokens(payload, modelInfo)
-	if effectiveMax > 0 && budget >= effectiveMax {
-		budget = effectiveMax - 1
-	}
-
-	// Check minimum budget
-	minBudget := 0
-	if modelInfo.Thinking != nil {
-		minBudget = modelInfo.Thinking.Min
-	}
-	if minBudget > 0 && budget >= 0 && budget < minBudget {
-		// Budget is below minimum, remove thinking config entirely
+	fitted, enabled := thinking.FitBudgetToMaxTokens(budget, effectiveMax, modelInfo.Thinking)
+	if !enabled {
 		payload, _ = sjson.DeleteBytes(payload, "request.generationConfig.thinkingConfig")
 		return -2, payload
 	}
+	budget = fitted
 
 	// Set default max tokens if needed
 	if setDefaultMax && effectiveMax > 0 {
@@ -227,10 +220,15 @@ func (a *Applier) normalizeClaudeBudget(budget int, payload []byte, modelInfo *r
 }
 
 // effectiveMaxTokens returns the max tokens to cap thinking:
-// prefer request-provided maxOutputTokens; otherwise fall back to model default.
+// prefer request-provided maxOutputTokens (capped to the model maximum); otherwise fall back to model default.
 // The boolean indicates whether the value came from the model default (and thus should be written back).
 func (a *Applier) effectiveMaxTokens(payload []byte, modelInfo *registry.ModelInfo) (max int, fromModel bool) {
 	if maxTok := gjson.GetBytes(payload, "request.generationConfig.maxOutputTokens"); maxTok.Exists() && maxTok.Int() > 0 {
+		// The payload step later caps requested limits to the model maximum, so the
+		// budget has to fit under the capped value.
+		if modelInfo != nil && modelInfo.MaxCompletionTokens > 0 && int(maxTok.Int()) > modelInfo.MaxCompletionTokens {
+			return modelInfo.MaxCompletionTokens, false
+		}
 		return int(maxTok.Int()), false
 	}
 	if modelInfo != nil && modelInfo.MaxCompletionTokens > 0 {
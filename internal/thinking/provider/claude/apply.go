@@ -173,28 +173,22 @@ func (a *Applier) normalizeClaudeBudget(body []byte, budgetTokens int, modelInfo
 
 	// Ensure the request satisfies Claude constraints:
 	//  1) Determine effective max_tokens (request overrides model default)
-	//  2) If budget_tokens >= max_tokens, reduce budget_tokens to max_tokens-1
-	//  3) If the adjusted budget falls below the model minimum, leave the request unchanged
-	//  4) If max_tokens came from model default, write it back into the request
+	//  2) If max_tokens came from model default, write it back into the request
+	//  3) Fit budget_tokens below max_tokens with thinking.FitBudgetToMaxTokens; when no
+	//     budget at or above the model minimum fits, thinking is removed from the request
 
 	effectiveMax, setDefaultMax := a.effectiveMaxTokens(body, modelInfo)
 	if setDefaultMax && effectiveMax > 0 {
 		body, _ = sjson.SetBytes(body, "max_tokens", effectiveMax)
 	}
 
-	// Compute the budget we would apply after enforcing budget_tokens < max_tokens.
-	adjustedBudget := budgetTokens
-	if effectiveMax > 0 && adjustedBudget >= effectiveMax {
-		adjustedBudget = effectiveMax - 1
+	var support *registry.ThinkingSupport
+	if modelInfo != nil {
+		support = modelInfo.Thinking
 	}
-
-	minBudget := 0
-	if modelInfo != nil && modelInfo.Thinking != nil {
-		minBudget = modelInfo.Thinking.Min
-	}
-	if minBudget > 0 && adjustedBudget > 0 && adjustedBudget < minBudget {
-		// If enforcing the max_tokens constraint would push the budget below the model minimum,
-		// leave the request unchanged.
+	adjustedBudget, enabled := thinking.FitBudgetToMaxTokens(budgetTokens, effectiveMax, support)
+	if !enabled {
+		body, _ = sjson.DeleteBytes(body, "thinking")
 		return body
 	}
 
@@ -206,10 +200,15 @@ func (a *Applier) normalizeClaudeBudget(body []byte, budgetTokens int, modelInfo
 }
 
 // effectiveMaxTokens returns the max tokens to cap thinking:
-// prefer request-provided max_tokens; otherwise fall back to model default.
+// prefer request-provided max_tokens (capped to the model maximum); otherwise fall back to model default.
 // The boolean indicates whether the value came from the model default (and thus should be written back).
 func (a *Applier) effectiveMaxTokens(body []byte, modelInfo *registry.ModelInfo) (max int, fromModel bool) {
 	if maxTok := gjson.GetBytes(body, "max_tokens"); maxTok.Exists() && maxTok.Int() > 0 {
+		// The payload step later caps requested limits to the model maximum, so the
+		// budget has to fit under the capped value.
+		if modelInfo != nil && modelInfo.MaxCompletionTokens > 0 && int(maxTok.Int()) > modelInfo.MaxCompletionTokens {
+			return modelInfo.MaxCompletionTokens, false
+		}
 		return int(maxTok.Int()), false
 	}
 	if modelInfo != nil && modelInfo.MaxCompletionTokens > 0 {
@@ -5,6 +5,7 @@
 package thinking
 
 import (
+	"math"
 	"strconv"
 	"strings"
 )
@@ -14,11 +15,12 @@ import (
 // The suffix format is: model-name(value)
 // Examples:
 //   - "claude-sonnet-4-5(16384)" -> ModelName="claude-sonnet-4-5", RawSuffix="16384"
+//   - "claude-sonnet-4-5(50%)" -> ModelName="claude-sonnet-4-5", RawSuffix="50%"
 //   - "gpt-5.2(high)" -> ModelName="gpt-5.2", RawSuffix="high"
 //   - "gemini-2.5-pro" -> ModelName="gemini-2.5-pro", HasSuffix=false
 //
 // This function only extracts the suffix; it does not validate or interpret
-// the suffix content. Use ParseNumericSuffix, ParseLevelSuffix, etc. for
+// the suffix content. Use ParseNumericSuffix, ParsePercentSuffix, ParseLevelSuffix, etc. for
 // content interpretation.
 func ParseSuffix(model string) SuffixResult {
 	// Find the last opening parenthesis
@@ -54,8 +56,13 @@ func ParseSuffix(model string) SuffixResult {
 //
 // Leading zeros are accepted: "08192" parses as 8192.
 //
+// A trailing "k" or "K" multiplies the value by 1024, so "16k" is 16384. The binary
+// multiplier matches the power-of-two budgets used by the model registry (8192, 16384,
+// 32768), so "32k" lands exactly on a common maximum instead of just below it.
+//
 // Examples:
 //   - "8192" -> budget=8192, ok=true
+//   - "16k" -> budget=16384, ok=true
 //   - "0" -> budget=0, ok=true (represents ModeNone)
 //   - "08192" -> budget=8192, ok=true (leading zeros accepted)
 //   - "-1" -> budget=0, ok=false (negative numbers are not valid numeric suffixes)
@@ -68,6 +75,12 @@ func ParseNumericSuffix(rawSuffix string) (budget int, ok bool) {
 		return 0, false
 	}
 
+	multiplier := 1
+	if last := rawSuffix[len(rawSuffix)-1]; last == 'k' || last == 'K' {
+		multiplier = 1024
+		rawSuffix = rawSuffix[:len(rawSuffix)-1]
+	}
+
 	value, err := strconv.Atoi(rawSuffix)
 	if err != nil {
 		return 0, false
@@ -78,7 +91,33 @@ func ParseNumericSuffix(rawSuffix string) (budget int, ok bool) {
 	if value < 0 {
 		return 0, false
 	}
+	if value > math.MaxInt/multiplier {
+		return 0, false
+	}
+
+	return value * multiplier, true
+}
 
+// ParsePercentSuffix attempts to parse a raw suffix as a percentage of the model's
+// maximum thinking budget.
+//
+// Only whole percentages from 0 to 100 followed by "%" are valid. The caller resolves
+// the percentage against ThinkingSupport.Max of the target model.
+//
+// Examples:
+//   - "50%" -> percent=50, ok=true
+//   - "100%" -> percent=100, ok=true
+//   - "150%" -> percent=0, ok=false (above 100)
+//   - "50" -> percent=0, ok=false (numeric, use ParseNumericSuffix)
+func ParsePercentSuffix(rawSuffix string) (percent int, ok bool) {
+	digits, found := strings.CutSuffix(rawSuffix, "%")
+	if !found || digits == "" {
+		return 0, false
+	}
+	value, err := strconv.Atoi(digits)
+	if err != nil || value < 0 || value > 100 {
+		return 0, false
+	}
 	return value, true
 }
 
@@ -284,7 +284,8 @@ func clampLevel(level ThinkingLevel, modelInfo *registry.ModelInfo, provider str
 	return level
 }
 
-// clampBudget clamps a budget value to the model's supported range.
+// clampBudget clamps a budget value to the model's supported range with ClampBudget and
+// logs the adjustment.
 func clampBudget(value int, modelInfo *registry.ModelInfo, provider string) int {
 	model := "unknown"
 	support := (*registry.ThinkingSupport)(nil)
@@ -294,45 +295,25 @@ func clampBudget(value int, modelInfo *registry.ModelInfo, provider string) int
 		}
 		support = modelInfo.Thinking
 	}
-	if support == nil {
-		return value
-	}
-
-	// Auto value (-1) passes through without clamping.
-	if value == -1 {
+	clamped := ClampBudget(value, support)
+	if clamped == value {
 		return value
 	}
 
 	min, max := support.Min, support.Max
-	if value == 0 && !support.ZeroAllowed {
+	if value == 0 {
 		log.WithFields(log.Fields{
 			"provider":       provider,
 			"model":          model,
 			"original_value": value,
-			"clamped_to":     min,
+			"clamped_to":     clamped,
 			"min":            min,
 			"max":            max,
 		}).Warn("thinking: budget zero not allowed |")
-		return min
-	}
-
-	// Some models are level-only and do not define numeric budget ranges.
-	if min == 0 && max == 0 {
-		return value
-	}
-
-	if value < min {
-		if value == 0 && support.ZeroAllowed {
-			return 0
-		}
-		logClamp(provider, model, value, min, min, max)
-		return min
-	}
-	if value > max {
-		logClamp(provider, model, value, max, min, max)
-		return max
+		return clamped
 	}
-	return value
+	logClamp(provider, model, value, clamped, min, max)
+	return clamped
 }
 
 func isLevelSupported(level string, supported []string) bool {
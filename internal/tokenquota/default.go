@@ -0,0 +1,76 @@
+package tokenquota
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+)
+
+const defaultFileName = "token-quota.json"
+
+var (
+	defaultMu      sync.RWMutex
+	defaultTracker *Tracker
+)
+
+// Default returns the process-wide tracker, or nil when no token budget is configured.
+func Default() *Tracker {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultTracker
+}
+
+// PathFromConfig resolves the counter file for cfg.
+func PathFromConfig(cfg *config.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	if path := strings.TrimSpace(cfg.TokenQuota.Path); path != "" {
+		return path
+	}
+	return filepath.Join(logging.ResolveLogDirectory(cfg), defaultFileName)
+}
+
+// Configure opens, updates or closes the process-wide tracker to match cfg. The current
+// tracker and its counters are kept when the counter file is unchanged.
+func Configure(cfg *config.Config) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if cfg == nil || !cfg.TokenQuota.Enabled() {
+		return closeDefaultLocked()
+	}
+	path := PathFromConfig(cfg)
+	if defaultTracker != nil && defaultTracker.Path() == path {
+		defaultTracker.SetRules(cfg.TokenQuota.Auths)
+		return nil
+	}
+	if errClose := closeDefaultLocked(); errClose != nil {
+		return errClose
+	}
+	tracker, errOpen := Open(path, cfg.TokenQuota.Auths)
+	if errOpen != nil {
+		return errOpen
+	}
+	defaultTracker = tracker
+	return nil
+}
+
+// CloseDefault writes pending counter updates and releases the process-wide tracker.
+func CloseDefault() error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return closeDefaultLocked()
+}
+
+func closeDefaultLocked() error {
+	if defaultTracker == nil {
+		return nil
+	}
+	tracker := defaultTracker
+	defaultTracker = nil
+	return tracker.Close()
+}
@@ -0,0 +1,36 @@
+package tokenquota
+
+import (
+	"context"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(&tokenQuotaPlugin{})
+}
+
+// tokenQuotaPlugin feeds the tokens of usage records into the default tracker.
+type tokenQuotaPlugin struct{}
+
+func (p *tokenQuotaPlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	tracker := Default()
+	if p == nil || tracker == nil {
+		return
+	}
+	at := record.RequestedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	tracker.Add(record.AuthID, record.AuthIndex, []string{record.Model, record.Alias}, recordTokens(record.Detail), at)
+}
+
+// recordTokens returns the total tokens of detail, summing its parts when the upstream
+// reported no total.
+func recordTokens(detail coreusage.Detail) int64 {
+	if detail.TotalTokens > 0 {
+		return detail.TotalTokens
+	}
+	return detail.InputTokens + detail.OutputTokens
+}
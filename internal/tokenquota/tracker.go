@@ -0,0 +1,391 @@
+// Package tokenquota counts the tokens used by each credential in the current UTC day and
+// month, persists the counters to a JSON file, and reports credentials that exhausted the
+// budgets configured under token-quota.
+package tokenquota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Budget periods reported by Exhaustion.
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// saveDelay batches counter updates into one file write.
+const saveDelay = time.Second
+
+// Counter holds the tokens counted in one day and one month.
+type Counter struct {
+	Day         string `json:"day,omitempty"`
+	DayTokens   int64  `json:"day_tokens"`
+	Month       string `json:"month,omitempty"`
+	MonthTokens int64  `json:"month_tokens"`
+}
+
+func (c *Counter) add(tokens int64, day, month string) {
+	if c.Day != day {
+		c.Day, c.DayTokens = day, 0
+	}
+	if c.Month != month {
+		c.Month, c.MonthTokens = month, 0
+	}
+	c.DayTokens += tokens
+	c.MonthTokens += tokens
+}
+
+// current returns the counter with periods other than day and month zeroed.
+func (c Counter) current(day, month string) Counter {
+	out := Counter{Day: day, Month: month}
+	if c.Day == day {
+		out.DayTokens = c.DayTokens
+	}
+	if c.Month == month {
+		out.MonthTokens = c.MonthTokens
+	}
+	return out
+}
+
+type authCounters struct {
+	AuthIndex string `json:"auth_index,omitempty"`
+	Counter
+	Models map[string]*Counter `json:"models,omitempty"`
+}
+
+// Exhaustion describes a budget that was reached.
+type Exhaustion struct {
+	// Model is the model the budget applies to; empty for the budget of every model.
+	Model   string    `json:"model,omitempty"`
+	Period  string    `json:"period"`
+	Limit   int64     `json:"limit"`
+	Used    int64     `json:"used"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// ModelUsage is the current consumption of one model.
+type ModelUsage struct {
+	Counter
+	Limits config.TokenQuotaLimits `json:"limits"`
+}
+
+// Usage is the current consumption of one credential.
+type Usage struct {
+	AuthID    string `json:"auth_id"`
+	AuthIndex string `json:"auth_index,omitempty"`
+	Counter
+	Limits    config.TokenQuotaLimits `json:"limits"`
+	Models    map[string]ModelUsage   `json:"models,omitempty"`
+	Exhausted *Exhaustion             `json:"exhausted,omitempty"`
+}
+
+// Tracker counts tokens per credential and checks them against the configured budgets.
+type Tracker struct {
+	mu    sync.Mutex
+	path  string
+	rules []config.TokenQuotaRule
+	auths map[string]*authCounters
+
+	saveTimer *time.Timer
+	closed    bool
+}
+
+// Open loads the counters stored at path, if any, and applies rules.
+func Open(path string, rules []config.TokenQuotaRule) (*Tracker, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("tokenquota: path is required")
+	}
+	t := &Tracker{path: path, auths: make(map[string]*authCounters)}
+	data, errRead := os.ReadFile(path)
+	switch {
+	case errRead == nil:
+		if len(strings.TrimSpace(string(data))) > 0 {
+			if errUnmarshal := json.Unmarshal(data, &t.auths); errUnmarshal != nil {
+				return nil, fmt.Errorf("tokenquota: decode %s: %w", path, errUnmarshal)
+			}
+		}
+	case !errors.Is(errRead, os.ErrNotExist):
+		return nil, fmt.Errorf("tokenquota: read %s: %w", path, errRead)
+	}
+	if t.auths == nil {
+		t.auths = make(map[string]*authCounters)
+	}
+	t.SetRules(rules)
+	return t, nil
+}
+
+// Path returns the file the counters are persisted to.
+func (t *Tracker) Path() string {
+	if t == nil {
+		return ""
+	}
+	return t.path
+}
+
+// SetRules replaces the configured budgets.
+func (t *Tracker) SetRules(rules []config.TokenQuotaRule) {
+	if t == nil {
+		return
+	}
+	normalized := make([]config.TokenQuotaRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.Auth = strings.TrimSpace(rule.Auth)
+		if rule.Auth == "" {
+			continue
+		}
+		models := make(map[string]config.TokenQuotaLimits, len(rule.Models))
+		for model, limits := range rule.Models {
+			if key := modelKey(model); key != "" {
+				models[key] = limits
+			}
+		}
+		rule.Models = models
+		normalized = append(normalized, rule)
+	}
+	t.mu.Lock()
+	t.rules = normalized
+	t.mu.Unlock()
+}
+
+// Add counts tokens used by the credential at time at. Each model name is counted
+// separately in addition to the credential total.
+func (t *Tracker) Add(authID, authIndex string, models []string, tokens int64, at time.Time) {
+	authID = strings.TrimSpace(authID)
+	if t == nil || authID == "" || tokens <= 0 {
+		return
+	}
+	day, month := periodKeys(at)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counters := t.auths[authID]
+	if counters == nil {
+		counters = &authCounters{}
+		t.auths[authID] = counters
+	}
+	if authIndex = strings.TrimSpace(authIndex); authIndex != "" {
+		counters.AuthIndex = authIndex
+	}
+	counters.add(tokens, day, month)
+	seen := make(map[string]struct{}, len(models))
+	for _, model := range models {
+		key := modelKey(model)
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		if counters.Models == nil {
+			counters.Models = make(map[string]*Counter)
+		}
+		counter := counters.Models[key]
+		if counter == nil {
+			counter = &Counter{}
+			counters.Models[key] = counter
+		}
+		counter.add(tokens, day, month)
+	}
+	t.scheduleSaveLocked()
+}
+
+// Check reports whether the credential reached a budget that applies to model at time now.
+// When several budgets are exhausted it returns the one that resets last.
+func (t *Tracker) Check(authID, authIndex, model string, now time.Time) (Exhaustion, bool) {
+	if t == nil {
+		return Exhaustion{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rule, ok := t.ruleLocked(authID, authIndex)
+	if !ok {
+		return Exhaustion{}, false
+	}
+	return t.checkLocked(strings.TrimSpace(authID), rule, modelKey(model), now)
+}
+
+func (t *Tracker) checkLocked(authID string, rule config.TokenQuotaRule, model string, now time.Time) (Exhaustion, bool) {
+	day, month := periodKeys(now)
+	var total Counter
+	var modelCounter Counter
+	if counters := t.auths[authID]; counters != nil {
+		total = counters.current(day, month)
+		if model != "" && counters.Models[model] != nil {
+			modelCounter = counters.Models[model].current(day, month)
+		}
+	}
+	var worst Exhaustion
+	found := false
+	consider := func(name string, counter Counter, limits config.TokenQuotaLimits) {
+		if limits.DailyTokenLimit > 0 && counter.DayTokens >= limits.DailyTokenLimit {
+			candidate := Exhaustion{Model: name, Period: PeriodDaily, Limit: limits.DailyTokenLimit, Used: counter.DayTokens, ResetAt: nextDay(now)}
+			if !found || candidate.ResetAt.After(worst.ResetAt) {
+				worst, found = candidate, true
+			}
+		}
+		if limits.MonthlyTokenLimit > 0 && counter.MonthTokens >= limits.MonthlyTokenLimit {
+			candidate := Exhaustion{Model: name, Period: PeriodMonthly, Limit: limits.MonthlyTokenLimit, Used: counter.MonthTokens, ResetAt: nextMonth(now)}
+			if !found || candidate.ResetAt.After(worst.ResetAt) {
+				worst, found = candidate, true
+			}
+		}
+	}
+	consider("", total, rule.TokenQuotaLimits)
+	if model != "" {
+		if limits, ok := rule.Models[model]; ok {
+			consider(model, modelCounter, limits)
+		}
+	}
+	return worst, found
+}
+
+// Snapshot returns the current consumption of every counted credential, sorted by auth ID.
+func (t *Tracker) Snapshot(now time.Time) []Usage {
+	if t == nil {
+		return nil
+	}
+	day, month := periodKeys(now)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Usage, 0, len(t.auths))
+	for authID, counters := range t.auths {
+		entry := Usage{
+			AuthID:    authID,
+			AuthIndex: counters.AuthIndex,
+			Counter:   counters.current(day, month),
+		}
+		rule, hasRule := t.ruleLocked(authID, counters.AuthIndex)
+		if hasRule {
+			entry.Limits = rule.TokenQuotaLimits
+			if exhaustion, exhausted := t.checkLocked(authID, rule, "", now); exhausted {
+				entry.Exhausted = &exhaustion
+			}
+		}
+		if len(counters.Models) > 0 {
+			entry.Models = make(map[string]ModelUsage, len(counters.Models))
+			for model, counter := range counters.Models {
+				usage := ModelUsage{Counter: counter.current(day, month)}
+				if hasRule {
+					usage.Limits = rule.Models[model]
+				}
+				entry.Models[model] = usage
+			}
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AuthID < out[j].AuthID })
+	return out
+}
+
+// Reset clears the counters of the credential and reports whether it had any.
+func (t *Tracker) Reset(authID string) (bool, error) {
+	authID = strings.TrimSpace(authID)
+	if t == nil || authID == "" {
+		return false, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.auths[authID]; !ok {
+		return false, nil
+	}
+	delete(t.auths, authID)
+	return true, t.saveLocked()
+}
+
+// Flush writes pending counter updates to disk.
+func (t *Tracker) Flush() error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.saveTimer == nil {
+		return nil
+	}
+	t.saveTimer.Stop()
+	t.saveTimer = nil
+	return t.saveLocked()
+}
+
+// Close writes pending counter updates; later updates are kept in memory only.
+func (t *Tracker) Close() error {
+	if t == nil {
+		return nil
+	}
+	errFlush := t.Flush()
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+	return errFlush
+}
+
+func (t *Tracker) ruleLocked(authID, authIndex string) (config.TokenQuotaRule, bool) {
+	authID = strings.TrimSpace(authID)
+	authIndex = strings.TrimSpace(authIndex)
+	for _, rule := range t.rules {
+		if (authID != "" && rule.Auth == authID) || (authIndex != "" && rule.Auth == authIndex) {
+			return rule, true
+		}
+	}
+	return config.TokenQuotaRule{}, false
+}
+
+func (t *Tracker) scheduleSaveLocked() {
+	if t.closed || t.saveTimer != nil {
+		return
+	}
+	t.saveTimer = time.AfterFunc(saveDelay, func() {
+		if errFlush := t.Flush(); errFlush != nil {
+			log.Warnf("tokenquota: %v", errFlush)
+		}
+	})
+}
+
+func (t *Tracker) saveLocked() error {
+	data, errMarshal := json.Marshal(t.auths)
+	if errMarshal != nil {
+		return fmt.Errorf("tokenquota: encode counters: %w", errMarshal)
+	}
+	if errMkdir := os.MkdirAll(filepath.Dir(t.path), 0o755); errMkdir != nil {
+		return fmt.Errorf("tokenquota: create directory: %w", errMkdir)
+	}
+	tmp := t.path + ".tmp"
+	if errWrite := os.WriteFile(tmp, data, 0o600); errWrite != nil {
+		return fmt.Errorf("tokenquota: write %s: %w", tmp, errWrite)
+	}
+	if errRename := os.Rename(tmp, t.path); errRename != nil {
+		return fmt.Errorf("tokenquota: replace %s: %w", t.path, errRename)
+	}
+	return nil
+}
+
+func modelKey(model string) string {
+	return strings.ToLower(strings.TrimSpace(model))
+}
+
+func periodKeys(at time.Time) (day, month string) {
+	at = at.UTC()
+	return at.Format("2006-01-02"), at.Format("2006-01")
+}
+
+func nextDay(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func nextMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
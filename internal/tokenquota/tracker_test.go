@@ -0,0 +1,92 @@
+package tokenquota
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+)
+
+func openTestTracker(t *testing.T, path string, rules []config.TokenQuotaRule) *Tracker {
+	t.Helper()
+	tracker, errOpen := Open(path, rules)
+	if errOpen != nil {
+		t.Fatalf("Open() error = %v", errOpen)
+	}
+	t.Cleanup(func() { _ = tracker.Close() })
+	return tracker
+}
+
+func TestTrackerCheckReportsExhaustedBudgets(t *testing.T) {
+	tracker := openTestTracker(t, filepath.Join(t.TempDir(), "quota.json"), []config.TokenQuotaRule{
+		{
+			Auth:             "idx-1",
+			TokenQuotaLimits: config.TokenQuotaLimits{DailyTokenLimit: 1000, MonthlyTokenLimit: 5000},
+			Models:           map[string]config.TokenQuotaLimits{"GPT-5": {DailyTokenLimit: 300}},
+		},
+	})
+	now := time.Date(2026, time.March, 14, 10, 0, 0, 0, time.UTC)
+
+	tracker.Add("auth-1", "idx-1", []string{"gpt-5", "gpt-5"}, 200, now)
+	if _, exhausted := tracker.Check("auth-1", "idx-1", "gpt-5", now); exhausted {
+		t.Fatal("auth exhausted below every limit")
+	}
+
+	tracker.Add("auth-1", "idx-1", []string{"gpt-5"}, 100, now)
+	got, exhausted := tracker.Check("auth-1", "idx-1", "gpt-5", now)
+	if !exhausted || got.Model != "gpt-5" || got.Period != PeriodDaily || got.Used != 300 {
+		t.Fatalf("Check(gpt-5) = %+v, %v; want the daily gpt-5 budget", got, exhausted)
+	}
+	if !got.ResetAt.Equal(time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ResetAt = %s, want the next UTC midnight", got.ResetAt)
+	}
+	if _, exhausted = tracker.Check("auth-1", "idx-1", "gpt-5-mini", now); exhausted {
+		t.Fatal("model budget applied to another model")
+	}
+
+	tracker.Add("auth-1", "idx-1", []string{"gpt-5-mini"}, 4800, now)
+	got, exhausted = tracker.Check("auth-1", "", "gpt-5-mini", now)
+	if exhausted {
+		t.Fatalf("Check without auth index matched a rule keyed by index: %+v", got)
+	}
+	got, exhausted = tracker.Check("auth-1", "idx-1", "gpt-5-mini", now)
+	if !exhausted || got.Period != PeriodMonthly || !got.ResetAt.Equal(time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Check = %+v, %v; want the monthly budget, which resets last", got, exhausted)
+	}
+}
+
+func TestTrackerCountersRollOverAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	rules := []config.TokenQuotaRule{{Auth: "auth-1", TokenQuotaLimits: config.TokenQuotaLimits{DailyTokenLimit: 100}}}
+	day := time.Date(2026, time.January, 31, 23, 0, 0, 0, time.UTC)
+
+	tracker, errOpen := Open(path, rules)
+	if errOpen != nil {
+		t.Fatalf("Open() error = %v", errOpen)
+	}
+	tracker.Add("auth-1", "", nil, 150, day)
+	if errClose := tracker.Close(); errClose != nil {
+		t.Fatalf("Close() error = %v", errClose)
+	}
+
+	reopened := openTestTracker(t, path, rules)
+	if _, exhausted := reopened.Check("auth-1", "", "", day); !exhausted {
+		t.Fatal("counters were not restored from disk")
+	}
+	nextDay := day.Add(2 * time.Hour)
+	if _, exhausted := reopened.Check("auth-1", "", "", nextDay); exhausted {
+		t.Fatal("daily budget still exhausted on the next day")
+	}
+	snapshot := reopened.Snapshot(nextDay)
+	if len(snapshot) != 1 || snapshot[0].DayTokens != 0 || snapshot[0].MonthTokens != 0 || snapshot[0].Month != "2026-02" {
+		t.Fatalf("Snapshot = %+v, want zeroed counters for February", snapshot)
+	}
+
+	if reset, errReset := reopened.Reset("auth-1"); errReset != nil || !reset {
+		t.Fatalf("Reset() = %v, %v; want true", reset, errReset)
+	}
+	if got := reopened.Snapshot(day); len(got) != 0 {
+		t.Fatalf("Snapshot after reset = %+v, want empty", got)
+	}
+}
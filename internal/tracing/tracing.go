@@ -0,0 +1,232 @@
+// Package tracing records OpenTelemetry spans for the request lifecycle and exports
+// them over OTLP/HTTP.
+//
+// Spans are only recorded after Configure enabled tracing; until then the tracer is a
+// no-op, so the instrumentation in the handlers, the auth manager and the executors
+// costs nothing when tracing is disabled.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/buildinfo"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies the spans of the proxy.
+const instrumentationName = "github.com/router-for-me/CLIProxyAPI/v7"
+
+// Span attribute keys shared by the instrumented packages.
+const (
+	AttrHandler   = attribute.Key("cliproxy.handler")
+	AttrModel     = attribute.Key("cliproxy.model")
+	AttrProvider  = attribute.Key("cliproxy.provider")
+	AttrAuthID    = attribute.Key("cliproxy.auth.id")
+	AttrAuthLabel = attribute.Key("cliproxy.auth.label")
+	AttrStatus    = attribute.Key("http.response.status_code")
+)
+
+var (
+	enabled atomic.Bool
+
+	// current holds the active trace.TracerProvider.
+	current atomic.Value
+
+	mu       sync.Mutex
+	applied  config.TracingConfig
+	shutdown func(context.Context) error
+)
+
+func init() {
+	current.Store(providerHolder{provider: noop.NewTracerProvider()})
+}
+
+// providerHolder keeps the stored type of current stable across providers.
+type providerHolder struct {
+	provider trace.TracerProvider
+}
+
+// Configure applies the tracing config: it installs an OTLP/HTTP exporter when tracing is
+// enabled and shuts the previous exporter down. An unchanged config is a no-op.
+func Configure(cfg config.TracingConfig) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if shutdown != nil && reflect.DeepEqual(applied, cfg) {
+		return nil
+	}
+	if !cfg.Enable {
+		applied = cfg
+		return swapLocked(nil, nil)
+	}
+
+	opts := []otlptracehttp.Option{}
+	if endpoint := strings.TrimSpace(cfg.Endpoint); endpoint != "" {
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, errExporter := otlptracehttp.New(context.Background(), opts...)
+	if errExporter != nil {
+		return fmt.Errorf("tracing: create OTLP exporter: %w", errExporter)
+	}
+	res, errResource := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.EffectiveServiceName()),
+		attribute.String("service.version", buildinfo.Version),
+	))
+	if errResource != nil {
+		res = resource.Default()
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.EffectiveSamplingRatio()))),
+	)
+	applied = cfg
+	return swapLocked(provider, provider.Shutdown)
+}
+
+// SetTracerProvider installs provider for the spans of the proxy, e.g. an SDK provider
+// with an in-memory exporter in tests. nil disables tracing.
+func SetTracerProvider(provider trace.TracerProvider) {
+	mu.Lock()
+	defer mu.Unlock()
+	applied = config.TracingConfig{}
+	_ = swapLocked(provider, nil)
+}
+
+// Shutdown flushes and stops the active exporter.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if shutdown == nil {
+		return nil
+	}
+	stop := shutdown
+	shutdown = nil
+	enabled.Store(false)
+	current.Store(providerHolder{provider: noop.NewTracerProvider()})
+	return stop(ctx)
+}
+
+func swapLocked(provider trace.TracerProvider, stop func(context.Context) error) error {
+	previous := shutdown
+	shutdown = stop
+	if provider == nil {
+		enabled.Store(false)
+		current.Store(providerHolder{provider: noop.NewTracerProvider()})
+	} else {
+		current.Store(providerHolder{provider: provider})
+		enabled.Store(true)
+	}
+	if previous != nil {
+		if errShutdown := previous(context.Background()); errShutdown != nil && !errors.Is(errShutdown, context.Canceled) {
+			return fmt.Errorf("tracing: shut down previous exporter: %w", errShutdown)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether spans are recorded.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+func tracerProvider() trace.TracerProvider {
+	return current.Load().(providerHolder).provider
+}
+
+// Start starts a span named name as a child of the span in ctx. It returns ctx and a
+// non-recording span when tracing is disabled.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !Enabled() {
+		return ctx, noop.Span{}
+	}
+	return tracerProvider().Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, and the HTTP status it carries, on span and ends it.
+func End(span trace.Span, err error) {
+	if span == nil || !span.IsRecording() {
+		return
+	}
+	RecordError(span, err)
+	span.End()
+}
+
+// RecordError sets the error status of span from err. Errors with a StatusCode method,
+// such as the executors' status errors, also record the HTTP status.
+func RecordError(span trace.Span, err error) {
+	if span == nil || err == nil || !span.IsRecording() {
+		return
+	}
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.StatusCode() > 0 {
+		span.SetAttributes(AttrStatus.Int(statusErr.StatusCode()))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// SetAttributes adds attrs to the span in ctx.
+func SetAttributes(ctx context.Context, attrs ...attribute.KeyValue) {
+	if ctx == nil || !Enabled() {
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
+
+// ContextWithSpanFrom returns ctx carrying the span of from, so work started from a
+// detached context stays in the trace of the inbound request.
+func ContextWithSpanFrom(ctx, from context.Context) context.Context {
+	if ctx == nil || from == nil || !Enabled() {
+		return ctx
+	}
+	span := trace.SpanFromContext(from)
+	if !span.SpanContext().IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, span)
+}
+
+// Transport wraps rt so every upstream HTTP request, including base URL fallbacks and
+// retries, is recorded as a client span of the span in the request context. No trace
+// headers are injected into upstream requests. rt is returned as is when tracing is
+// disabled.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	if !Enabled() {
+		return rt
+	}
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(rt,
+		otelhttp.WithTracerProvider(tracerProvider()),
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "upstream " + r.Method + " " + r.URL.Host
+		}),
+	)
+}
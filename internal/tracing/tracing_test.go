@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type statusError struct{ code int }
+
+func (e statusError) Error() string   { return http.StatusText(e.code) }
+func (e statusError) StatusCode() int { return e.code }
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { SetTracerProvider(nil) })
+	return recorder
+}
+
+func TestStartIsNoopWhenDisabled(t *testing.T) {
+	SetTracerProvider(nil)
+	ctx := context.Background()
+	gotCtx, span := Start(ctx, "noop")
+	if gotCtx != ctx {
+		t.Fatal("Start() changed the context while tracing is disabled")
+	}
+	if span.IsRecording() {
+		t.Fatal("Start() returned a recording span while tracing is disabled")
+	}
+	if rt := Transport(http.DefaultTransport); rt != http.DefaultTransport {
+		t.Fatal("Transport() wrapped the transport while tracing is disabled")
+	}
+}
+
+func TestEndRecordsStatusFromError(t *testing.T) {
+	recorder := useRecorder(t)
+	_, span := Start(context.Background(), "attempt")
+	End(span, errors.Join(errors.New("upstream failed"), statusError{code: http.StatusTooManyRequests}))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("ended spans = %d, want 1", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Fatalf("status = %v, want error", spans[0].Status().Code)
+	}
+	var status int64
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == AttrStatus {
+			status = attr.Value.AsInt64()
+		}
+	}
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("status attribute = %d, want %d", status, http.StatusTooManyRequests)
+	}
+}
+
+func TestMiddlewareContinuesIncomingTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := useRecorder(t)
+
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		_, child := Start(c.Request.Context(), "child")
+		child.End()
+		c.Status(http.StatusBadGateway)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	child, server := spans[0], spans[1]
+	if server.Name() != "POST /v1/chat/completions" {
+		t.Fatalf("server span name = %q", server.Name())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("server span trace id = %s, want the incoming trace", got)
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatal("handler span is not a child of the server span")
+	}
+	if server.Status().Code != codes.Error {
+		t.Fatalf("server span status = %v, want error for 502", server.Status().Code)
+	}
+}
+
+func TestConfigureDisabledKeepsTracingOff(t *testing.T) {
+	if err := Configure(config.TracingConfig{}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if Enabled() {
+		t.Fatal("Enabled() = true for a disabled config")
+	}
+}
@@ -0,0 +1,116 @@
+// Package transformtrace records the transformations applied while a request is shaped for
+// its upstream, such as matched payload rules, thinking normalization, schema cleaning and
+// cloaking, so they can be logged and echoed to clients that ask for them.
+package transformtrace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// DebugHeader is the request header a client sets to DebugTransforms to receive the trace.
+	DebugHeader = "X-CLIProxy-Debug"
+	// DebugTransforms is the DebugHeader value asking for the trace.
+	DebugTransforms = "transforms"
+	// ResponseField is the field the trace is added under in non-streaming JSON responses.
+	ResponseField = "_cliproxy_transforms"
+	// GinKey is the Gin context key holding the request's *Trace.
+	GinKey = "CLIPROXY_TRANSFORM_TRACE"
+)
+
+type traceKey struct{}
+
+// Trace collects the transformation steps of one request. Steps recorded by an upstream
+// attempt replace those of the previous attempt once the attempt sends its request.
+type Trace struct {
+	mu      sync.Mutex
+	pending []string
+	latest  []string
+	ended   bool
+}
+
+// New returns an empty trace.
+func New() *Trace {
+	return &Trace{}
+}
+
+// NewContext returns a context carrying trace.
+func NewContext(ctx context.Context, trace *Trace) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if trace == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// FromContext returns the trace carried by ctx, or nil.
+func FromContext(ctx context.Context) *Trace {
+	if ctx == nil {
+		return nil
+	}
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+// Record appends a step to the trace carried by ctx. It is a no-op without one.
+func Record(ctx context.Context, format string, args ...any) {
+	trace := FromContext(ctx)
+	if trace == nil {
+		return
+	}
+	step := format
+	if len(args) > 0 {
+		step = fmt.Sprintf(format, args...)
+	}
+	trace.mu.Lock()
+	trace.pending = append(trace.pending, step)
+	trace.mu.Unlock()
+}
+
+// EndAttempt marks the steps recorded since the previous attempt as those of the attempt
+// now sending its upstream request and returns them. Calling it again before any new step
+// is recorded keeps the current attempt's steps.
+func EndAttempt(ctx context.Context) []string {
+	trace := FromContext(ctx)
+	if trace == nil {
+		return nil
+	}
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	if len(trace.pending) > 0 || !trace.ended {
+		trace.latest = trace.pending
+		trace.pending = nil
+		trace.ended = true
+	}
+	return append([]string(nil), trace.latest...)
+}
+
+// Steps returns the steps of the latest attempt, or the steps recorded so far when no
+// attempt has sent its request yet.
+func (t *Trace) Steps() []string {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	steps := t.latest
+	if !t.ended {
+		steps = t.pending
+	}
+	return append([]string{}, steps...)
+}
+
+// Requested reports whether a DebugHeader value asks for the transformation trace.
+func Requested(header string) bool {
+	for _, value := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(value), DebugTransforms) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package transformtrace
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTraceKeepsStepsOfLatestAttempt(t *testing.T) {
+	trace := New()
+	ctx := NewContext(context.Background(), trace)
+
+	Record(ctx, "payload.default[%d]", 0)
+	if got := trace.Steps(); !reflect.DeepEqual(got, []string{"payload.default[0]"}) {
+		t.Fatalf("steps before the first attempt = %q", got)
+	}
+	if got := EndAttempt(ctx); !reflect.DeepEqual(got, []string{"payload.default[0]"}) {
+		t.Fatalf("first attempt = %q", got)
+	}
+	if got := EndAttempt(ctx); !reflect.DeepEqual(got, []string{"payload.default[0]"}) {
+		t.Fatalf("repeated end of the first attempt = %q", got)
+	}
+
+	Record(ctx, "payload.override[1]")
+	Record(ctx, "cloaking: mode=always")
+	EndAttempt(ctx)
+	want := []string{"payload.override[1]", "cloaking: mode=always"}
+	if got := trace.Steps(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps after the second attempt = %q, want %q", got, want)
+	}
+}
+
+func TestRecordWithoutTraceIsNoop(t *testing.T) {
+	Record(context.Background(), "payload.default[0]")
+	if got := EndAttempt(context.Background()); got != nil {
+		t.Fatalf("EndAttempt without trace = %q, want nil", got)
+	}
+}
+
+func TestRequested(t *testing.T) {
+	for header, want := range map[string]bool{
+		"transforms":         true,
+		" Transforms ":       true,
+		"timing, transforms": true,
+		"":                   false,
+		"transform":          false,
+	} {
+		if got := Requested(header); got != want {
+			t.Fatalf("Requested(%q) = %t, want %t", header, got, want)
+		}
+	}
+}
@@ -344,11 +344,10 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	// contents
 	contentItems := translatorcommon.NewRawArrayItems(gjson.GetBytes(rawJSON, "messages.#").Int())
 
-	// tool_use_id → tool_name lookup, populated incrementally during the main loop.
+	// tool_use_id → tool_name lookup gathered from the assistant tool_use blocks.
 	// Claude's tool_result references tool_use by ID; Gemini requires functionResponse.name.
-	toolNameByID := make(map[string]string)
-
 	messagesResult := gjson.GetBytes(rawJSON, "messages")
+	toolNameByID := translatorcommon.ClaudeToolNamesByID(messagesResult)
 	if messagesResult.IsArray() {
 		messageResults := messagesResult.Array()
 		numMessages := len(messageResults)
@@ -436,10 +435,6 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 						argsResult := contentResult.Get("input")
 						functionID := contentResult.Get("id").String()
 
-						if functionID != "" && originalFunctionName != "" {
-							toolNameByID[functionID] = originalFunctionName
-						}
-
 						// Handle both object and string input formats
 						var argsRaw string
 						if argsResult.IsObject() {
@@ -474,17 +469,9 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 						if toolCallID != "" {
 							funcName, ok := toolNameByID[toolCallID]
 							if !ok {
-								// Fallback: derive a semantic name from the ID by stripping
-								// the last two dash-separated segments (e.g. "get_weather-call-123" → "get_weather").
-								// Only use the raw ID as a last resort when the heuristic produces an empty string.
-								parts := strings.Split(toolCallID, "-")
-								if len(parts) > 2 {
-									funcName = strings.Join(parts[:len(parts)-2], "-")
-								}
-								if funcName == "" {
-									funcName = toolCallID
-								}
-								log.Warnf("antigravity claude request: tool_result references unknown tool_use_id=%s, derived function name=%s", toolCallID, funcName)
+								// Ids are opaque; the id itself is the only name left when the call is not in history.
+								funcName = toolCallID
+								log.Warnf("antigravity claude request: tool_result references unknown tool_use_id=%s, using it as the function name", toolCallID)
 							}
 							functionResponseResult := contentResult.Get("content")
 
@@ -501,18 +488,10 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 								nonImageItems := make([][]byte, 0, len(frResults))
 								imagePartItems := make([][]byte, 0, 2)
 								for _, fr := range frResults {
-									if fr.Get("type").String() == "image" && fr.Get("source.type").String() == "base64" {
-										inlineDataJSON := []byte(`{}`)
-										if mimeType := fr.Get("source.media_type").String(); mimeType != "" {
-											inlineDataJSON, _ = sjson.SetBytes(inlineDataJSON, "mimeType", mimeType)
+									if imagePartJSON, isImage := antigravityToolResultImagePart(fr); isImage {
+										if imagePartJSON != nil {
+											imagePartItems = append(imagePartItems, imagePartJSON)
 										}
-										if data := fr.Get("source.data").String(); data != "" {
-											inlineDataJSON, _ = sjson.SetBytes(inlineDataJSON, "data", data)
-										}
-
-										imagePartJSON := []byte(`{}`)
-										imagePartJSON, _ = sjson.SetRawBytes(imagePartJSON, "inlineData", inlineDataJSON)
-										imagePartItems = append(imagePartItems, imagePartJSON)
 										continue
 									}
 
@@ -535,18 +514,10 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 								}
 
 							} else if functionResponseResult.IsObject() {
-								if functionResponseResult.Get("type").String() == "image" && functionResponseResult.Get("source.type").String() == "base64" {
-									inlineDataJSON := []byte(`{}`)
-									if mimeType := functionResponseResult.Get("source.media_type").String(); mimeType != "" {
-										inlineDataJSON, _ = sjson.SetBytes(inlineDataJSON, "mimeType", mimeType)
-									}
-									if data := functionResponseResult.Get("source.data").String(); data != "" {
-										inlineDataJSON, _ = sjson.SetBytes(inlineDataJSON, "data", data)
+								if imagePartJSON, isImage := antigravityToolResultImagePart(functionResponseResult); isImage {
+									if imagePartJSON != nil {
+										functionResponseJSON, _ = sjson.SetRawBytes(functionResponseJSON, "parts", translatorcommon.JoinRawArray([][]byte{imagePartJSON}))
 									}
-
-									imagePartJSON := []byte(`{}`)
-									imagePartJSON, _ = sjson.SetRawBytes(imagePartJSON, "inlineData", inlineDataJSON)
-									functionResponseJSON, _ = sjson.SetRawBytes(functionResponseJSON, "parts", translatorcommon.JoinRawArray([][]byte{imagePartJSON}))
 									functionResponseJSON, _ = sjson.SetBytes(functionResponseJSON, "response.result", "")
 								} else {
 									functionResponseJSON, _ = sjson.SetRawBytes(functionResponseJSON, "response.result", []byte(functionResponseResult.Raw))
@@ -633,10 +604,18 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 	toolsResult := gjson.GetBytes(rawJSON, "tools")
 	if toolsResult.IsArray() {
 		var functionDeclarations [][]byte
+		// Built-in Claude tools reaching this point have no Antigravity equivalent; web_search
+		// is only served by the dedicated search request built above.
+		var unavailableTools []string
 		toolsResults := toolsResult.Array()
 		for i := 0; i < len(toolsResults); i++ {
 			toolResult := toolsResults[i]
-			if isClaudeTypedWebSearchToolType(toolResult.Get("type").String()) {
+			if translatorcommon.IsClaudeServerTool(toolResult) {
+				name := toolResult.Get("name").String()
+				if name == "" {
+					name = toolResult.Get("type").String()
+				}
+				unavailableTools = append(unavailableTools, name)
 				continue
 			}
 			inputSchemaResult := toolResult.Get("input_schema")
@@ -669,6 +648,11 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 				toolsJSON = translatorcommon.JoinRawArray([][]byte{functionToolNode})
 			}
 		}
+		if len(unavailableTools) > 0 {
+			log.Warnf("antigravity: dropping Claude built-in tools without an Antigravity equivalent: %s", strings.Join(unavailableTools, ", "))
+			notePart, _ := sjson.SetBytes([]byte(`{"text":""}`), "text", translatorcommon.ClaudeUnavailableToolsNote(unavailableTools))
+			systemParts = append(systemParts, notePart)
+		}
 	}
 
 	// Build output Antigravity request JSON
@@ -753,15 +737,7 @@ func ConvertClaudeRequestToAntigravity(modelName string, inputRawJSON []byte, _
 			out, _ = sjson.SetBytes(out, "request.generationConfig.thinkingConfig.includeThoughts", true)
 		}
 	}
-	if v := gjson.GetBytes(rawJSON, "temperature"); v.Exists() && v.Type == gjson.Number {
-		out, _ = sjson.SetBytes(out, "request.generationConfig.temperature", v.Num)
-	}
-	if v := gjson.GetBytes(rawJSON, "top_p"); v.Exists() && v.Type == gjson.Number {
-		out, _ = sjson.SetBytes(out, "request.generationConfig.topP", v.Num)
-	}
-	if v := gjson.GetBytes(rawJSON, "top_k"); v.Exists() && v.Type == gjson.Number {
-		out, _ = sjson.SetBytes(out, "request.generationConfig.topK", v.Num)
-	}
+	out = common.ApplyClaudeSamplingParams(out, rawJSON, "request.generationConfig")
 	if v := gjson.GetBytes(rawJSON, "max_tokens"); v.Exists() && v.Type == gjson.Number {
 		out, _ = sjson.SetBytes(out, "request.generationConfig.maxOutputTokens", v.Num)
 	}
@@ -777,3 +753,37 @@ func antigravityClaudeContent(role string, parts [][]byte) []byte {
 	content, _ = sjson.SetRawBytes(content, "parts", translatorcommon.JoinRawArray(parts))
 	return content
 }
+
+// antigravityToolResultImagePart converts a Claude image block nested in a tool_result
+// into a functionResponse part. Base64 sources become inlineData and URL sources become
+// fileData. The second return value reports whether the block is an image block at all;
+// the part is nil when the image carries no URL to reference.
+func antigravityToolResultImagePart(block gjson.Result) ([]byte, bool) {
+	if block.Get("type").String() != "image" {
+		return nil, false
+	}
+	switch block.Get("source.type").String() {
+	case "base64":
+		inlineDataJSON := []byte(`{}`)
+		if mimeType := block.Get("source.media_type").String(); mimeType != "" {
+			inlineDataJSON, _ = sjson.SetBytes(inlineDataJSON, "mimeType", mimeType)
+		}
+		if data := block.Get("source.data").String(); data != "" {
+			inlineDataJSON, _ = sjson.SetBytes(inlineDataJSON, "data", data)
+		}
+		imagePartJSON := []byte(`{}`)
+		imagePartJSON, _ = sjson.SetRawBytes(imagePartJSON, "inlineData", inlineDataJSON)
+		return imagePartJSON, true
+	case "url":
+		image := util.ConvertClaudeToolResultContent(block)
+		if len(image.Images) == 0 {
+			return nil, true
+		}
+		imagePartJSON := []byte(`{"fileData":{"mimeType":"","fileUri":""}}`)
+		imagePartJSON, _ = sjson.SetBytes(imagePartJSON, "fileData.mimeType", image.Images[0].MimeType)
+		imagePartJSON, _ = sjson.SetBytes(imagePartJSON, "fileData.fileUri", image.Images[0].URL)
+		return imagePartJSON, true
+	default:
+		return nil, false
+	}
+}
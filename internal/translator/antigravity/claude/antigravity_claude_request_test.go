@@ -1769,7 +1769,7 @@ func TestConvertClaudeRequestToAntigravity_ToolResultName_CustomFormat(t *testin
 	}
 }
 
-func TestConvertClaudeRequestToAntigravity_ToolResultName_NoMatchingToolUse_Heuristic(t *testing.T) {
+func TestConvertClaudeRequestToAntigravity_ToolResultName_NoMatchingToolUse_FullID(t *testing.T) {
 	inputJSON := []byte(`{
 		"model": "claude-sonnet-4-5",
 		"messages": [
@@ -1793,8 +1793,44 @@ func TestConvertClaudeRequestToAntigravity_ToolResultName_NoMatchingToolUse_Heur
 	if !funcResp.Exists() {
 		t.Fatal("functionResponse should exist")
 	}
-	if got := funcResp.Get("name").String(); got != "get_weather" {
-		t.Errorf("Expected heuristic-derived name 'get_weather', got '%s'", got)
+	if got := funcResp.Get("name").String(); got != "get_weather-call-123" {
+		t.Errorf("Expected the full id as name, got '%s'", got)
+	}
+}
+
+func TestConvertClaudeRequestToAntigravity_ToolResultName_HyphenatedIDs(t *testing.T) {
+	cases := []struct {
+		name     string
+		toolID   string
+		toolName string
+	}{
+		{name: "uuid id", toolID: "3f2b8c1e-9d4a-4e6b-a1c2-7f8e9d0a1b2c", toolName: "Read"},
+		{name: "mcp tool name", toolID: "mcp-server-tool-abc123", toolName: "mcp__github-server__create-pull-request"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inputJSON := []byte(`{
+				"model": "claude-sonnet-4-5",
+				"messages": [
+					{"role": "assistant", "content": [{"type": "tool_use", "id": "` + tc.toolID + `", "name": "` + tc.toolName + `", "input": {}}]},
+					{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "` + tc.toolID + `", "content": "ok"}]}
+				]
+			}`)
+
+			output := ConvertClaudeRequestToAntigravity("claude-sonnet-4-5", inputJSON, false)
+
+			call := gjson.GetBytes(output, "request.contents.0.parts.0.functionCall")
+			funcResp := gjson.GetBytes(output, "request.contents.1.parts.0.functionResponse")
+			if !funcResp.Exists() {
+				t.Fatalf("functionResponse should exist; output=%s", output)
+			}
+			if got := funcResp.Get("id").String(); got != tc.toolID {
+				t.Errorf("functionResponse.id = %q, want %q", got, tc.toolID)
+			}
+			if got, want := funcResp.Get("name").String(), call.Get("name").String(); got != want || want == "" {
+				t.Errorf("functionResponse.name = %q, want the functionCall name %q", got, want)
+			}
+		})
 	}
 }
 
@@ -2486,8 +2522,8 @@ func TestConvertClaudeRequestToAntigravity_ToolResultWithOnlyMultipleImages(t *t
 	}
 }
 
-func TestConvertClaudeRequestToAntigravity_ToolResultImageNotBase64(t *testing.T) {
-	// image with source.type != "base64" should be treated as non-image (falls through)
+func TestConvertClaudeRequestToAntigravity_ToolResultImageURL(t *testing.T) {
+	// image with source.type=url is referenced through fileData instead of inlined
 	inputJSON := []byte(`{
 		"model": "claude-3-5-sonnet-20240620",
 		"messages": [
@@ -2522,20 +2558,64 @@ func TestConvertClaudeRequestToAntigravity_ToolResultImageNotBase64(t *testing.T
 		t.Fatal("functionResponse should exist")
 	}
 
-	// Non-base64 image is treated as non-image, so it goes into the filtered results
-	// along with the text item. Since there are 2 non-image items, result is array.
-	resultArr := funcResp.Get("response.result")
-	if !resultArr.IsArray() {
-		t.Fatalf("Expected response.result to be an array (2 non-image items), got: %s", resultArr.Raw)
+	// Only the text item remains in the result.
+	if got := funcResp.Get("response.result.text").String(); got != "some output" {
+		t.Fatalf("Expected response.result to be the text item, got: %s", funcResp.Get("response.result").Raw)
 	}
-	results := resultArr.Array()
-	if len(results) != 2 {
-		t.Fatalf("Expected 2 result items, got %d", len(results))
+
+	fileData := funcResp.Get("parts.0.fileData")
+	if got := fileData.Get("fileUri").String(); got != "https://example.com/img.png" {
+		t.Errorf("Expected fileUri 'https://example.com/img.png', got '%s'", got)
+	}
+	if got := fileData.Get("mimeType").String(); got != "image/png" {
+		t.Errorf("Expected mimeType 'image/png', got '%s'", got)
 	}
+}
+
+func TestConvertClaudeRequestToAntigravity_ToolResultTextAndTwoImages(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "claude-3-5-sonnet-20240620",
+		"messages": [
+			{
+				"role": "user",
+				"content": [
+					{
+						"type": "tool_result",
+						"tool_use_id": "Screenshot-001",
+						"content": [
+							{"type": "text", "text": "two screenshots"},
+							{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aGVsbG8="}},
+							{"type": "image", "source": {"type": "url", "url": "https://example.com/shot"}}
+						]
+					}
+				]
+			}
+		]
+	}`)
 
-	// No functionResponse.parts (no base64 images collected)
-	if funcResp.Get("parts").Exists() {
-		t.Error("functionResponse.parts should NOT exist when no base64 images")
+	output := ConvertClaudeRequestToAntigravity("claude-sonnet-4-5", inputJSON, false)
+	outputStr := string(output)
+
+	parts := gjson.Get(outputStr, "request.contents.0.parts").Array()
+	if len(parts) != 1 {
+		t.Fatalf("Expected images to stay inside the functionResponse, got %d parts: %s", len(parts), outputStr)
+	}
+	funcResp := parts[0].Get("functionResponse")
+	if got := funcResp.Get("response.result.text").String(); got != "two screenshots" {
+		t.Fatalf("Expected text-only result, got: %s", funcResp.Get("response.result").Raw)
+	}
+	imageParts := funcResp.Get("parts").Array()
+	if len(imageParts) != 2 {
+		t.Fatalf("Expected 2 functionResponse parts, got %d", len(imageParts))
+	}
+	if got := imageParts[0].Get("inlineData.data").String(); got != "aGVsbG8=" {
+		t.Errorf("Expected first part inlineData.data 'aGVsbG8=', got '%s'", got)
+	}
+	if got := imageParts[1].Get("fileData.fileUri").String(); got != "https://example.com/shot" {
+		t.Errorf("Expected second part fileData.fileUri, got '%s'", imageParts[1].Raw)
+	}
+	if got := imageParts[1].Get("fileData.mimeType").String(); got != "image/jpeg" {
+		t.Errorf("Expected extensionless URL to default to image/jpeg, got '%s'", got)
 	}
 }
 
@@ -2894,3 +2974,43 @@ func TestConvertClaudeRequestToAntigravity_ToolAndThinking_NoExistingSystem(t *t
 		t.Errorf("Interleaved thinking hint should be in created systemInstruction, got: %v", sysInstruction.Raw)
 	}
 }
+
+func TestConvertClaudeRequestToAntigravity_NotesUnavailableServerTools(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "claude-sonnet-4-5",
+		"messages": [{"role": "user", "content": "Search current weather"}],
+		"tools": [
+			{"type": "web_search_20250305", "name": "web_search"},
+			{"type": "code_execution_20250522", "name": "code_execution"},
+			{"name": "lookup", "description": "Lookup local data", "input_schema": {"type": "object", "properties": {}}}
+		]
+	}`)
+
+	output := ConvertClaudeRequestToAntigravity("claude-sonnet-4-5", inputJSON, false)
+	if got := gjson.GetBytes(output, `request.tools.0.functionDeclarations.#.name`).Raw; got != `["lookup"]` {
+		t.Fatalf("functionDeclarations names = %s, want [\"lookup\"]; output %s", got, output)
+	}
+	note := gjson.GetBytes(output, "request.systemInstruction.parts.@reverse.0.text").String()
+	if !strings.Contains(note, "web_search, code_execution") {
+		t.Fatalf("system note %q does not name the dropped tools; output %s", note, output)
+	}
+}
+
+func TestConvertClaudeRequestToAntigravity_MapsStopSequencesAndTopK(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "gemini-3-flash",
+		"max_tokens": 256,
+		"top_k": 40,
+		"stop_sequences": ["END", "", "STOP", "a", "b", "c", "d"],
+		"messages": [{"role": "user", "content": [{"type": "text", "text": "Hello"}]}]
+	}`)
+
+	output := ConvertClaudeRequestToAntigravity("gemini-3-flash", inputJSON, false)
+
+	if got := gjson.GetBytes(output, "request.generationConfig.topK").Raw; got != "40" {
+		t.Fatalf("request.generationConfig.topK = %s, want 40. Output: %s", got, output)
+	}
+	if got := gjson.GetBytes(output, "request.generationConfig.stopSequences").Raw; got != `["END","STOP","a","b","c"]` {
+		t.Fatalf("request.generationConfig.stopSequences = %s, want the first five non-empty sequences", got)
+	}
+}
@@ -178,7 +178,7 @@ func ConvertAntigravityResponseToClaude(ctx context.Context, _ string, originalR
 	if webSearchStreamMode && !params.HasWebSearchTool {
 		root := gjson.ParseBytes(rawJSON)
 		if groundingMetadata := antigravityGroundingMetadata(root); groundingMetadata.Exists() {
-			toolUseID := newClaudeWebSearchToolUseID()
+			toolUseID := translatorcommon.NewClaudeWebSearchToolUseID()
 			textContent := params.WebSearchTextBuffer.String() + antigravityTextContent(root)
 			params.WebSearchTextBuffer.Reset()
 			params.ResponseIndex = appendClaudeWebSearchStreamBlocks(appendEvent, params.ResponseIndex, toolUseID, textContent, groundingMetadata)
@@ -318,7 +318,7 @@ func ConvertAntigravityResponseToClaude(ctx context.Context, _ string, originalR
 
 	if usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata"); usageResult.Exists() {
 		params.HasUsageMetadata = true
-		params.CachedTokenCount = usageResult.Get("cachedContentTokenCount").Int()
+		params.CachedTokenCount = translatorcommon.GeminiCachedTokenCount(usageResult)
 		params.PromptTokenCount = usageResult.Get("promptTokenCount").Int() - params.CachedTokenCount
 		params.CandidatesTokenCount = usageResult.Get("candidatesTokenCount").Int()
 		params.ThoughtsTokenCount = usageResult.Get("thoughtsTokenCount").Int()
@@ -412,17 +412,12 @@ func appendFinalEvents(params *Params, output *[]byte, force bool) {
 }
 
 func resolveStopReason(params *Params) string {
+	if stopReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonClaude, params.FinishReason, params.HasToolUse); stopReason != "" {
+		return stopReason
+	}
 	if params.HasToolUse {
 		return "tool_use"
 	}
-
-	switch params.FinishReason {
-	case "MAX_TOKENS":
-		return "max_tokens"
-	case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
-		return "end_turn"
-	}
-
 	return "end_turn"
 }
 
@@ -445,7 +440,7 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 	candidateTokens := root.Get("response.usageMetadata.candidatesTokenCount").Int()
 	thoughtTokens := root.Get("response.usageMetadata.thoughtsTokenCount").Int()
 	totalTokens := root.Get("response.usageMetadata.totalTokenCount").Int()
-	cachedTokens := root.Get("response.usageMetadata.cachedContentTokenCount").Int()
+	cachedTokens := translatorcommon.GeminiCachedTokenCount(root.Get("response.usageMetadata"))
 	outputTokens := candidateTokens + thoughtTokens
 	if outputTokens == 0 && totalTokens > 0 {
 		outputTokens = totalTokens - promptTokens
@@ -470,7 +465,7 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 
 	if shouldTranslateWebSearchGrounding(originalRequestRawJSON, requestRawJSON) {
 		if groundingMetadata := antigravityGroundingMetadata(root); groundingMetadata.Exists() {
-			toolUseID := newClaudeWebSearchToolUseID()
+			toolUseID := translatorcommon.NewClaudeWebSearchToolUseID()
 			responseJSON, _ = sjson.SetRawBytes(responseJSON, "content", buildClaudeWebSearchContent(toolUseID, antigravityTextContent(root), groundingMetadata))
 			responseJSON, _ = sjson.SetBytes(responseJSON, "stop_reason", "end_turn")
 			responseJSON, _ = sjson.SetBytes(responseJSON, "usage.server_tool_use.web_search_requests", 1)
@@ -569,19 +564,11 @@ func ConvertAntigravityResponseToClaudeNonStream(_ context.Context, _ string, or
 	flushThinking()
 	flushText()
 
-	stopReason := "end_turn"
-	if hasToolCall {
-		stopReason = "tool_use"
-	} else {
-		if finish := root.Get("response.candidates.0.finishReason"); finish.Exists() {
-			switch finish.String() {
-			case "MAX_TOKENS":
-				stopReason = "max_tokens"
-			case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
-				stopReason = "end_turn"
-			default:
-				stopReason = "end_turn"
-			}
+	stopReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonClaude, root.Get("response.candidates.0.finishReason").String(), hasToolCall)
+	if stopReason == "" {
+		stopReason = "end_turn"
+		if hasToolCall {
+			stopReason = "tool_use"
 		}
 	}
 	responseJSON, _ = sjson.SetBytes(responseJSON, "stop_reason", stopReason)
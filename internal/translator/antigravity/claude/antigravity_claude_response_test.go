@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/cache"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/tidwall/gjson"
 )
 
@@ -258,7 +259,7 @@ func TestWebSearchResultsFromGrounding_DeduplicatesAndSkipsEmptyURLs(t *testing.
 		]
 	}`)
 
-	results := webSearchResultsFromGrounding(groundingMetadata)
+	results := translatorcommon.ClaudeWebSearchResults(groundingMetadata)
 
 	if got := gjson.GetBytes(results, "#").Int(); got != 2 {
 		t.Fatalf("result count = %d, want 2: %s", got, string(results))
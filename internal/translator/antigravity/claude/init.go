@@ -3,6 +3,7 @@ package claude
 import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/translator"
 )
 
@@ -11,10 +12,10 @@ func init() {
 		Claude,
 		Antigravity,
 		ConvertClaudeRequestToAntigravity,
-		interfaces.TranslateResponse{
+		translatorcommon.SuppressGeminiThoughts(interfaces.TranslateResponse{
 			Stream:     ConvertAntigravityResponseToClaude,
 			NonStream:  ConvertAntigravityResponseToClaudeNonStream,
 			TokenCount: ClaudeTokenCount,
-		},
+		}),
 	)
 }
@@ -4,9 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -30,23 +30,6 @@ func antigravitySupportsNativeGoogleSearch(model string) bool {
 	return registry.AntigravityWebSearchModelFor(model) != ""
 }
 
-func isClaudeTypedWebSearchToolType(toolType string) bool {
-	return toolType == "web_search_20250305" || toolType == "web_search_20260209"
-}
-
-func hasClaudeTypedWebSearchTool(payload []byte) bool {
-	tools := gjson.GetBytes(payload, "tools")
-	if !tools.IsArray() {
-		return false
-	}
-	for _, tool := range tools.Array() {
-		if isClaudeTypedWebSearchToolType(tool.Get("type").String()) {
-			return true
-		}
-	}
-	return false
-}
-
 func hasOnlyClaudeTypedWebSearchTools(payload []byte) bool {
 	tools := gjson.GetBytes(payload, "tools")
 	if !tools.IsArray() {
@@ -54,7 +37,7 @@ func hasOnlyClaudeTypedWebSearchTools(payload []byte) bool {
 	}
 	hasWebSearch := false
 	for _, tool := range tools.Array() {
-		if isClaudeTypedWebSearchToolType(tool.Get("type").String()) {
+		if translatorcommon.IsClaudeWebSearchToolType(tool.Get("type").String()) {
 			hasWebSearch = true
 			continue
 		}
@@ -122,7 +105,7 @@ func extractClaudeWebSearchMaxUses(payload []byte) int64 {
 		return defaultMaxResultCount
 	}
 	for _, tool := range tools.Array() {
-		if !isClaudeTypedWebSearchToolType(tool.Get("type").String()) {
+		if !translatorcommon.IsClaudeWebSearchToolType(tool.Get("type").String()) {
 			continue
 		}
 		maxUses := tool.Get("max_uses").Int()
@@ -139,7 +122,7 @@ func extractClaudeWebSearchAllowedDomains(payload []byte) []string {
 		return nil
 	}
 	for _, tool := range tools.Array() {
-		if !isClaudeTypedWebSearchToolType(tool.Get("type").String()) {
+		if !translatorcommon.IsClaudeWebSearchToolType(tool.Get("type").String()) {
 			continue
 		}
 		allowedDomains := tool.Get("allowed_domains")
@@ -211,7 +194,7 @@ func hasAntigravityGoogleSearchTool(payload []byte) bool {
 }
 
 func shouldTranslateWebSearchGrounding(originalRequestRawJSON, requestRawJSON []byte) bool {
-	return hasClaudeTypedWebSearchTool(originalRequestRawJSON) && hasAntigravityGoogleSearchTool(requestRawJSON)
+	return translatorcommon.HasClaudeWebSearchTool(originalRequestRawJSON) && hasAntigravityGoogleSearchTool(requestRawJSON)
 }
 
 func antigravityGroundingMetadata(root gjson.Result) gjson.Result {
@@ -257,44 +240,6 @@ func antigravityUsageTokens(root gjson.Result) (int64, int64) {
 	return inputTokens, outputTokens
 }
 
-func webSearchQueryFromGrounding(groundingMetadata gjson.Result) string {
-	if queries := groundingMetadata.Get("webSearchQueries"); queries.IsArray() && len(queries.Array()) > 0 {
-		return queries.Array()[0].String()
-	}
-	return ""
-}
-
-func webSearchResultsFromGrounding(groundingMetadata gjson.Result) []byte {
-	results := []byte(`[]`)
-	groundingChunks := groundingMetadata.Get("groundingChunks")
-	if !groundingChunks.IsArray() {
-		return results
-	}
-	seenURLs := make(map[string]struct{})
-	for _, chunk := range groundingChunks.Array() {
-		web := chunk.Get("web")
-		if !web.Exists() {
-			continue
-		}
-		uri := strings.TrimSpace(web.Get("uri").String())
-		if uri == "" {
-			continue
-		}
-		if _, ok := seenURLs[uri]; ok {
-			continue
-		}
-		seenURLs[uri] = struct{}{}
-
-		result := []byte(`{"type":"web_search_result","page_age":null}`)
-		if title := web.Get("title"); title.Exists() {
-			result, _ = sjson.SetBytes(result, "title", title.String())
-		}
-		result, _ = sjson.SetBytes(result, "url", uri)
-		results, _ = sjson.SetRawBytes(results, "-1", result)
-	}
-	return results
-}
-
 func parseWebSearchGroundingSupports(groundingMetadata gjson.Result) []webSearchGroundingSupport {
 	groundingChunks := groundingMetadata.Get("groundingChunks")
 	if !groundingChunks.IsArray() {
@@ -403,19 +348,7 @@ func buildWebSearchCitedTextBlocks(textContent string, supports []webSearchGroun
 }
 
 func buildClaudeWebSearchContent(toolUseID string, textContent string, groundingMetadata gjson.Result) []byte {
-	content := []byte(`[]`)
-
-	serverToolUse := []byte(`{"type":"server_tool_use","id":"","name":"web_search","input":{}}`)
-	serverToolUse, _ = sjson.SetBytes(serverToolUse, "id", toolUseID)
-	if query := webSearchQueryFromGrounding(groundingMetadata); query != "" {
-		serverToolUse, _ = sjson.SetBytes(serverToolUse, "input.query", query)
-	}
-	content, _ = sjson.SetRawBytes(content, "-1", serverToolUse)
-
-	webSearchToolResult := []byte(`{"type":"web_search_tool_result","tool_use_id":"","content":[]}`)
-	webSearchToolResult, _ = sjson.SetBytes(webSearchToolResult, "tool_use_id", toolUseID)
-	webSearchToolResult, _ = sjson.SetRawBytes(webSearchToolResult, "content", webSearchResultsFromGrounding(groundingMetadata))
-	content, _ = sjson.SetRawBytes(content, "-1", webSearchToolResult)
+	content := translatorcommon.JoinRawArray(translatorcommon.ClaudeWebSearchBlocks(toolUseID, groundingMetadata))
 
 	for _, block := range buildWebSearchCitedTextBlocks(textContent, parseWebSearchGroundingSupports(groundingMetadata)) {
 		if block.Text == "" {
@@ -434,26 +367,7 @@ func buildClaudeWebSearchContent(toolUseID string, textContent string, grounding
 }
 
 func appendClaudeWebSearchStreamBlocks(appendEvent func(string, string), startIndex int, toolUseID string, textContent string, groundingMetadata gjson.Result) int {
-	contentIndex := startIndex
-
-	serverToolUseStart := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"server_tool_use","id":"%s","name":"web_search","input":{}}}`,
-		contentIndex, toolUseID)
-	appendEvent("content_block_start", serverToolUseStart)
-	if query := webSearchQueryFromGrounding(groundingMetadata); query != "" {
-		queryJSON, _ := sjson.Set(`{}`, "query", query)
-		inputDelta := fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"input_json_delta","partial_json":""}}`, contentIndex)
-		inputDelta, _ = sjson.Set(inputDelta, "delta.partial_json", queryJSON)
-		appendEvent("content_block_delta", inputDelta)
-	}
-	appendEvent("content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, contentIndex))
-	contentIndex++
-
-	webSearchToolResultStart := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"web_search_tool_result","tool_use_id":"%s","content":[]}}`,
-		contentIndex, toolUseID)
-	webSearchToolResultStart, _ = sjson.SetRaw(webSearchToolResultStart, "content_block.content", string(webSearchResultsFromGrounding(groundingMetadata)))
-	appendEvent("content_block_start", webSearchToolResultStart)
-	appendEvent("content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, contentIndex))
-	contentIndex++
+	contentIndex := translatorcommon.AppendClaudeWebSearchStreamBlocks(appendEvent, startIndex, toolUseID, groundingMetadata)
 
 	for _, block := range buildWebSearchCitedTextBlocks(textContent, parseWebSearchGroundingSupports(groundingMetadata)) {
 		if block.Text == "" {
@@ -496,7 +410,3 @@ func splitRunesForWebSearch(text string, chunkSize int) []string {
 	}
 	return chunks
 }
-
-func newClaudeWebSearchToolUseID() string {
-	return fmt.Sprintf("srvtoolu_%d", time.Now().UnixNano())
-}
@@ -37,28 +37,22 @@ func ConvertAntigravityResponseToGemini(ctx context.Context, _ string, originalR
 		rawJSON = bytes.TrimSpace(rawJSON[5:])
 	}
 
-	if alt, ok := ctx.Value("alt").(string); ok {
+	if _, ok := ctx.Value("alt").(string); ok {
+		// The executor feeds one envelope per call for both SSE and JSON-array
+		// upstream streams; the handler applies the client's framing.
 		var chunk []byte
-		if alt == "" {
-			responseResult := gjson.GetBytes(rawJSON, "response")
-			if responseResult.Exists() {
-				chunk = []byte(responseResult.Raw)
-				chunk = restoreUsageMetadata(chunk)
-				chunk = restoreGeminiFunctionNames(chunk, originalRequestRawJSON)
-			}
-		} else {
-			chunkTemplate := []byte("[]")
-			responseResult := gjson.ParseBytes(chunk)
-			if responseResult.IsArray() {
-				responseResultItems := responseResult.Array()
-				for i := 0; i < len(responseResultItems); i++ {
-					responseResultItem := responseResultItems[i]
-					if responseResultItem.Get("response").Exists() {
-						chunkTemplate, _ = sjson.SetRawBytes(chunkTemplate, "-1", []byte(responseResultItem.Get("response").Raw))
-					}
+		responseResult := gjson.ParseBytes(rawJSON)
+		if responseResult.IsArray() {
+			chunk = []byte("[]")
+			for _, item := range responseResult.Array() {
+				if response := item.Get("response"); response.Exists() {
+					chunk, _ = sjson.SetRawBytes(chunk, "-1", restoreGeminiFunctionNames(restoreUsageMetadata([]byte(response.Raw)), originalRequestRawJSON))
 				}
 			}
-			chunk = chunkTemplate
+		} else if response := responseResult.Get("response"); response.Exists() {
+			chunk = []byte(response.Raw)
+			chunk = restoreUsageMetadata(chunk)
+			chunk = restoreGeminiFunctionNames(chunk, originalRequestRawJSON)
 		}
 		return [][]byte{chunk}
 	}
@@ -3,6 +3,7 @@ package gemini
 import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/translator"
 )
 
@@ -11,10 +12,10 @@ func init() {
 		Gemini,
 		Antigravity,
 		ConvertGeminiRequestToAntigravity,
-		interfaces.TranslateResponse{
+		translatorcommon.SuppressGeminiThoughts(interfaces.TranslateResponse{
 			Stream:     ConvertAntigravityResponseToGemini,
 			NonStream:  ConvertAntigravityResponseToGeminiNonStream,
 			TokenCount: GeminiTokenCount,
-		},
+		}),
 	)
 }
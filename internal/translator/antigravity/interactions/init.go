@@ -3,6 +3,7 @@ package interactions
 import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/translator"
 )
 
@@ -11,9 +12,9 @@ func init() {
 		Interactions,
 		Antigravity,
 		ConvertInteractionsRequestToAntigravity,
-		interfaces.TranslateResponse{
+		translatorcommon.SuppressGeminiThoughts(interfaces.TranslateResponse{
 			Stream:    ConvertAntigravityResponseToInteractions,
 			NonStream: ConvertAntigravityResponseToInteractionsNonStream,
-		},
+		}),
 	)
 }
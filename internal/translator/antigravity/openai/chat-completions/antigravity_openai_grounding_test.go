@@ -0,0 +1,27 @@
+package chat_completions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestConvertAntigravityResponseToOpenAI_PreservesGroundingMetadata(t *testing.T) {
+	grounding := `{"webSearchQueries":["who won euro 2024"],"groundingChunks":[{"web":{"uri":"https://vertexaisearch.cloud.google.com/grounding-api-redirect/abc","title":"uefa.com"}}]}`
+	raw := []byte(`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"Spain won Euro 2024."}]},"finishReason":"STOP","groundingMetadata":` + grounding + `}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":6,"totalTokenCount":14},"responseId":"resp-grounded"}}`)
+
+	var param any
+	chunks := ConvertAntigravityResponseToOpenAI(context.Background(), "model", nil, nil, raw, &param)
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if got := gjson.GetBytes(chunks[0], "choices.0.grounding_metadata").Raw; got != grounding {
+		t.Fatalf("stream grounding_metadata = %s, want %s", got, grounding)
+	}
+
+	nonStream := ConvertAntigravityResponseToOpenAINonStream(context.Background(), "model", nil, nil, raw, nil)
+	if got := gjson.GetBytes(nonStream, "choices.0.grounding_metadata").Raw; got != grounding {
+		t.Fatalf("non-stream grounding_metadata = %s, want %s", got, grounding)
+	}
+}
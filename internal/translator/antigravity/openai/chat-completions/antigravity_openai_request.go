@@ -73,6 +73,14 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 		out, _ = sjson.SetBytes(out, "request.generationConfig.maxOutputTokens", maxTok.Num)
 	}
 
+	// Presence/frequency penalties and seed; Claude models served by Antigravity do not accept them.
+	if !strings.Contains(strings.ToLower(modelName), "claude") {
+		out = common.ApplyOpenAIPenaltiesAndSeed(out, rawJSON, "request.generationConfig")
+	}
+
+	// Stop sequences
+	out = common.ApplyOpenAIStopSequences(out, rawJSON, "request.generationConfig")
+
 	// Candidate count (OpenAI 'n' parameter)
 	if n := gjson.GetBytes(rawJSON, "n"); n.Exists() && n.Type == gjson.Number {
 		if val := n.Int(); val > 1 {
@@ -108,11 +116,19 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 		}
 	}
 
+	// OpenAI response_format -> Antigravity request.generationConfig.responseMimeType/responseSchema
+	out = common.ApplyOpenAIResponseFormat(out, gjson.GetBytes(rawJSON, "response_format"), "request.generationConfig")
+
+	// top-level system + system/developer messages -> systemInstruction, top-level first
+	systemParts := make([][]byte, 0, 2)
+	for _, text := range common.SystemInstructionTexts(gjson.GetBytes(rawJSON, "system")) {
+		systemParts = append(systemParts, antigravityOpenAITextPart(text))
+	}
+
 	// messages -> systemInstruction + contents
 	messages := gjson.GetBytes(rawJSON, "messages")
 	if messages.IsArray() {
 		arr := messages.Array()
-		systemParts := make([][]byte, 0, 2)
 		contentItems := make([][]byte, 0, len(arr))
 		// First pass: assistant tool_calls id->name map
 		tcID2Name := map[string]string{}
@@ -153,18 +169,12 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 			role := m.Get("role").String()
 			content := m.Get("content")
 
-			if (role == "system" || role == "developer") && len(arr) > 1 {
+			if common.IsOpenAISystemRole(role) && len(arr) > 1 {
 				// system -> request.systemInstruction as a user message style
-				if content.Type == gjson.String {
-					systemParts = append(systemParts, antigravityOpenAITextPart(content.String()))
-				} else if content.IsObject() && content.Get("type").String() == "text" {
-					systemParts = append(systemParts, antigravityOpenAITextPart(content.Get("text").String()))
-				} else if content.IsArray() {
-					for _, contentPart := range content.Array() {
-						systemParts = append(systemParts, antigravityOpenAITextPart(contentPart.Get("text").String()))
-					}
+				for _, text := range common.SystemInstructionTexts(content) {
+					systemParts = append(systemParts, antigravityOpenAITextPart(text))
 				}
-			} else if role == "user" || ((role == "system" || role == "developer") && len(arr) == 1) {
+			} else if role == "user" || (common.IsOpenAISystemRole(role) && len(arr) == 1) {
 				partItems := make([][]byte, 0, 4)
 				if content.Type == gjson.String {
 					partItems = append(partItems, antigravityOpenAITextPart(content.String()))
@@ -294,11 +304,11 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 				}
 			}
 		}
-		if len(systemParts) > 0 {
-			out, _ = sjson.SetRawBytes(out, "request.systemInstruction", antigravityOpenAIContent("user", systemParts))
-		}
 		out = translatorcommon.SetRawArrayItems(out, "request.contents", contentItems)
 	}
+	if len(systemParts) > 0 {
+		out, _ = sjson.SetRawBytes(out, "request.systemInstruction", antigravityOpenAIContent("user", systemParts))
+	}
 
 	// tools -> request.tools[].functionDeclarations + request.tools[].googleSearch/codeExecution/urlContext passthrough
 	tools := gjson.GetBytes(rawJSON, "tools")
@@ -355,6 +365,13 @@ func ConvertOpenAIRequestToAntigravity(modelName string, inputRawJSON []byte, _
 					if nameResult.Type != gjson.String || mappedName != originalName {
 						fnRawBytes, _ = sjson.SetBytes(fnRawBytes, "name", mappedName)
 					}
+					// Antigravity has no strict flag; the executor cleans the schema, so tighten it
+					// to the OpenAI strict contract here and keep the constraints as hints.
+					if fn.Get("strict").Bool() {
+						if parameters := gjson.GetBytes(fnRawBytes, "parametersJsonSchema"); parameters.IsObject() {
+							fnRawBytes, _ = sjson.SetRawBytes(fnRawBytes, "parametersJsonSchema", []byte(util.TightenStrictJSONSchema(parameters.Raw)))
+						}
+					}
 					if gjson.GetBytes(fnRawBytes, "strict").Exists() {
 						fnRawBytes, _ = sjson.DeleteBytes(fnRawBytes, "strict")
 					}
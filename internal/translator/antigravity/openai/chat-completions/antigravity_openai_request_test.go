@@ -264,3 +264,129 @@ func TestConvertOpenAIRequestToAntigravityMapsToolChoiceModes(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertOpenAIRequestToAntigravityMapsPenaltiesAndSeed(t *testing.T) {
+	input := []byte(`{"messages":[{"role":"user","content":"hi"}],"presence_penalty":2,"frequency_penalty":-3,"seed":7}`)
+
+	result := ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", input, false)
+	if got := gjson.GetBytes(result, "request.generationConfig.presencePenalty").Float(); got != 1.99 {
+		t.Fatalf("presencePenalty = %v, want 1.99. output=%s", got, result)
+	}
+	if got := gjson.GetBytes(result, "request.generationConfig.frequencyPenalty").Float(); got != -2 {
+		t.Fatalf("frequencyPenalty = %v, want -2. output=%s", got, result)
+	}
+	if got := gjson.GetBytes(result, "request.generationConfig.seed").Int(); got != 7 {
+		t.Fatalf("seed = %d, want 7. output=%s", got, result)
+	}
+
+	result = ConvertOpenAIRequestToAntigravity("claude-sonnet-4-5", input, false)
+	for _, path := range []string{"presencePenalty", "frequencyPenalty", "seed"} {
+		if gjson.GetBytes(result, "request.generationConfig."+path).Exists() {
+			t.Fatalf("%s should not be set for Claude models: %s", path, result)
+		}
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravityMapsStopSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "string", input: `{"messages":[{"role":"user","content":"hi"}],"stop":"END","seed":3}`, want: `["END"]`},
+		{name: "array truncated", input: `{"messages":[{"role":"user","content":"hi"}],"stop":["a","b","c","d","e","f","g"],"seed":3}`, want: `["a","b","c","d","e"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", []byte(tt.input), false)
+			if got := gjson.GetBytes(result, "request.generationConfig.stopSequences").Raw; got != tt.want {
+				t.Fatalf("stopSequences = %s, want %s. output=%s", got, tt.want, result)
+			}
+			if got := gjson.GetBytes(result, "request.generationConfig.seed").Int(); got != 3 {
+				t.Fatalf("seed = %d, want 3. output=%s", got, result)
+			}
+		})
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravityMergesSystemSources(t *testing.T) {
+	inputJSON := `{
+		"system": "top-level",
+		"messages": [
+			{"role": "user", "content": "hello"},
+			{"role": "system", "content": {"type": "text", "text": "late system"}},
+			{"role": "developer", "content": ""},
+			{"role": "user", "content": "again"}
+		]
+	}`
+
+	result := gjson.ParseBytes(ConvertOpenAIRequestToAntigravity("gemini-2.5-pro", []byte(inputJSON), false))
+
+	parts := result.Get("request.systemInstruction.parts").Array()
+	if len(parts) != 2 || parts[0].Get("text").String() != "top-level" || parts[1].Get("text").String() != "late system" {
+		t.Fatalf("systemInstruction parts = %s, want top-level then late system", result.Get("request.systemInstruction.parts").Raw)
+	}
+	contents := result.Get("request.contents").Array()
+	if len(contents) != 2 {
+		t.Fatalf("contents length = %d, want 2. contents=%s", len(contents), result.Get("request.contents").Raw)
+	}
+	for _, content := range contents {
+		if role := content.Get("role").String(); role != "user" {
+			t.Fatalf("contents role = %q, want user. contents=%s", role, result.Get("request.contents").Raw)
+		}
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravityMapsResponseFormat(t *testing.T) {
+	jsonObject := `{
+		"model": "gemini-3-flash",
+		"messages": [{"role": "user", "content": "List three colors as JSON"}],
+		"response_format": {"type": "json_object"}
+	}`
+	output := ConvertOpenAIRequestToAntigravity("gemini-3-flash", []byte(jsonObject), false)
+	if got := gjson.GetBytes(output, "request.generationConfig.responseMimeType").String(); got != "application/json" {
+		t.Fatalf("responseMimeType = %q, want application/json. Output: %s", got, output)
+	}
+	if gjson.GetBytes(output, "request.generationConfig.responseSchema").Exists() {
+		t.Fatalf("json_object must not set a responseSchema. Output: %s", output)
+	}
+
+	jsonSchema := `{
+		"model": "gemini-3-flash",
+		"messages": [{"role": "user", "content": "Describe a company"}],
+		"response_format": {"type": "json_schema", "json_schema": {"name": "company", "schema": {"type": "object", "additionalProperties": false, "properties": {"name": {"type": "string"}}}}}
+	}`
+	output = ConvertOpenAIRequestToAntigravity("gemini-3-flash", []byte(jsonSchema), false)
+	if got := gjson.GetBytes(output, "request.generationConfig.responseMimeType").String(); got != "application/json" {
+		t.Fatalf("responseMimeType = %q, want application/json. Output: %s", got, output)
+	}
+	schema := gjson.GetBytes(output, "request.generationConfig.responseSchema")
+	if !schema.Get("properties.name").Exists() || schema.Get("additionalProperties").Exists() {
+		t.Fatalf("responseSchema = %s, want the cleaned schema", schema.Raw)
+	}
+}
+
+func TestConvertOpenAIRequestToAntigravityTightensStrictToolSchema(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-3-flash",
+		"messages": [{"role": "user", "content": "Weather in Paris?"}],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "strict": true, "parameters": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}, "unit": {"type": "string"}},
+			"required": ["city"]
+		}}}]
+	}`
+
+	output := ConvertOpenAIRequestToAntigravity("gemini-3-flash", []byte(inputJSON), false)
+
+	tool := gjson.GetBytes(output, "request.tools.0.functionDeclarations.0")
+	if tool.Get("strict").Exists() {
+		t.Fatalf("strict must not reach Antigravity: %s", tool.Raw)
+	}
+	if got := tool.Get("parametersJsonSchema.required").Raw; got != `["city","unit"]` {
+		t.Fatalf("required = %s, want every property", got)
+	}
+	if tool.Get("parametersJsonSchema.additionalProperties").Type != gjson.False {
+		t.Fatalf("additionalProperties = %s, want false for the executor cleaner to turn into a hint", tool.Get("parametersJsonSchema.additionalProperties").Raw)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	log "github.com/sirupsen/logrus"
 
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/gemini/openai/chat-completions"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -28,6 +29,9 @@ type convertCliResponseToOpenAIChatParams struct {
 	SawToolCall          bool   // Tracks if any tool call was seen in the entire stream
 	UpstreamFinishReason string // Caches the upstream finish reason for final chunk
 	SanitizedNameMap     map[string]string
+	ResponseID           string
+	Model                string
+	Usage                []byte // Latest OpenAI usage object, emitted on [DONE] when stream_options.include_usage is set
 }
 
 // functionCallIDCounter provides a process-wide unique counter for function call identifiers.
@@ -59,8 +63,21 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 		(*param).(*convertCliResponseToOpenAIChatParams).SanitizedNameMap = util.DisambiguatedToolNameMap(originalRequestRawJSON)
 	}
 
+	includeUsage := gjson.GetBytes(originalRequestRawJSON, "stream_options.include_usage").Bool()
 	if bytes.Equal(rawJSON, []byte("[DONE]")) {
-		return [][]byte{}
+		params := (*param).(*convertCliResponseToOpenAIChatParams)
+		if !includeUsage || params.Usage == nil {
+			return [][]byte{}
+		}
+		// OpenAI clients requesting include_usage expect a final chunk with empty choices.
+		usageChunk := []byte(`{"id":"","object":"chat.completion.chunk","created":0,"model":"model","choices":[]}`)
+		usageChunk, _ = sjson.SetBytes(usageChunk, "id", params.ResponseID)
+		usageChunk, _ = sjson.SetBytes(usageChunk, "created", params.UnixTimestamp)
+		if params.Model != "" {
+			usageChunk, _ = sjson.SetBytes(usageChunk, "model", params.Model)
+		}
+		usageChunk, _ = sjson.SetRawBytes(usageChunk, "usage", params.Usage)
+		return [][]byte{usageChunk}
 	}
 
 	// Initialize the OpenAI SSE template.
@@ -69,6 +86,7 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 	// Extract and set the model version.
 	if modelVersionResult := gjson.GetBytes(rawJSON, "response.modelVersion"); modelVersionResult.Exists() {
 		template, _ = sjson.SetBytes(template, "model", modelVersionResult.String())
+		(*param).(*convertCliResponseToOpenAIChatParams).Model = modelVersionResult.String()
 	}
 
 	// Extract and set the creation timestamp.
@@ -85,6 +103,7 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 	// Extract and set the response ID.
 	if responseIDResult := gjson.GetBytes(rawJSON, "response.responseId"); responseIDResult.Exists() {
 		template, _ = sjson.SetBytes(template, "id", responseIDResult.String())
+		(*param).(*convertCliResponseToOpenAIChatParams).ResponseID = responseIDResult.String()
 	}
 
 	// Cache the finish reason - do NOT set it in output yet (will be set on final chunk)
@@ -94,7 +113,7 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 
 	// Extract and set usage metadata (token counts).
 	if usageResult := gjson.GetBytes(rawJSON, "response.usageMetadata"); usageResult.Exists() {
-		cachedTokenCount := usageResult.Get("cachedContentTokenCount").Int()
+		cachedTokenCount := translatorcommon.GeminiCachedTokenCount(usageResult)
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
 			template, _ = sjson.SetBytes(template, "usage.completion_tokens", candidatesTokenCountResult.Int())
 		}
@@ -115,6 +134,11 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 				log.Warnf("antigravity openai response: failed to set cached_tokens: %v", err)
 			}
 		}
+		// With include_usage the totals move to the dedicated final chunk instead.
+		if includeUsage {
+			(*param).(*convertCliResponseToOpenAIChatParams).Usage = []byte(gjson.GetBytes(template, "usage").Raw)
+			template, _ = sjson.DeleteBytes(template, "usage")
+		}
 	}
 
 	// Process the main content part of the response.
@@ -201,6 +225,11 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 		}
 	}
 
+	// Expose Google Search grounding (queries, sources, supports) as a vendor extension.
+	if groundingMetadata := gjson.GetBytes(rawJSON, "response.candidates.0.groundingMetadata"); groundingMetadata.IsObject() {
+		template, _ = sjson.SetRawBytes(template, "choices.0.grounding_metadata", []byte(groundingMetadata.Raw))
+	}
+
 	// Determine finish_reason only on the final chunk (has both finishReason and usage metadata)
 	params := (*param).(*convertCliResponseToOpenAIChatParams)
 	upstreamFinishReason := params.UpstreamFinishReason
@@ -210,18 +239,16 @@ func ConvertAntigravityResponseToOpenAI(_ context.Context, _ string, originalReq
 	isFinalChunk := upstreamFinishReason != "" && usageExists
 
 	if isFinalChunk {
-		var finishReason string
-		if sawToolCall {
-			finishReason = "tool_calls"
-		} else if upstreamFinishReason == "MAX_TOKENS" {
-			finishReason = "max_tokens"
-		} else {
-			finishReason = "stop"
-		}
+		finishReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonOpenAI, upstreamFinishReason, sawToolCall)
 		template, _ = sjson.SetBytes(template, "choices.0.finish_reason", finishReason)
 		template, _ = sjson.SetBytes(template, "choices.0.native_finish_reason", strings.ToLower(upstreamFinishReason))
 	}
 
+	// Thought-signature-only chunks leave nothing to forward; clients such as the
+	// Vercel AI SDK treat runs of empty deltas as a stalled stream.
+	if !OpenAIStreamChunkHasPayload(template) {
+		return [][]byte{}
+	}
 	return [][]byte{template}
 }
 
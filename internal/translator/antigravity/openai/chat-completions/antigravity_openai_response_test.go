@@ -77,10 +77,10 @@ func TestFinishReasonMaxTokens(t *testing.T) {
 	chunk2 := []byte(`{"response":{"candidates":[{"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":100,"totalTokenCount":110}}}`)
 	result2 := ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, chunk2, &param)
 
-	// Verify finish_reason is "max_tokens"
+	// Verify MAX_TOKENS maps to OpenAI's "length"
 	fr := gjson.GetBytes(result2[0], "choices.0.finish_reason").String()
-	if fr != "max_tokens" {
-		t.Errorf("Expected finish_reason 'max_tokens', got: %s", fr)
+	if fr != "length" {
+		t.Errorf("Expected finish_reason 'length', got: %s", fr)
 	}
 }
 
@@ -180,3 +180,99 @@ func TestConvertAntigravityResponseToOpenAINonStreamIncludesReasoningContent(t *
 		t.Fatalf("reasoning_tokens = %d, want 42. Output: %s", got, output)
 	}
 }
+
+func TestStreamIncludeUsageEmitsFinalUsageChunk(t *testing.T) {
+	ctx := context.Background()
+	var param any
+	request := []byte(`{"model":"gemini-3-pro","stream":true,"stream_options":{"include_usage":true}}`)
+
+	chunk1 := []byte(`{"response":{"responseId":"resp-1","modelVersion":"gemini-3-pro","candidates":[{"content":{"parts":[{"text":"Hi"}]}}]}}`)
+	ConvertAntigravityResponseToOpenAI(ctx, "model", request, nil, chunk1, &param)
+	chunk2 := []byte(`{"response":{"candidates":[{"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}}`)
+	result2 := ConvertAntigravityResponseToOpenAI(ctx, "model", request, nil, chunk2, &param)
+	if len(result2) != 1 {
+		t.Fatalf("Expected 1 result from final chunk, got %d", len(result2))
+	}
+	if gjson.GetBytes(result2[0], "usage").Exists() {
+		t.Fatalf("Expected usage to move to the dedicated chunk, got: %s", result2[0])
+	}
+	if fr := gjson.GetBytes(result2[0], "choices.0.finish_reason").String(); fr != "stop" {
+		t.Fatalf("Expected finish_reason 'stop', got: %s", fr)
+	}
+
+	done := ConvertAntigravityResponseToOpenAI(ctx, "model", request, nil, []byte("[DONE]"), &param)
+	if len(done) != 1 {
+		t.Fatalf("Expected 1 usage chunk on [DONE], got %d", len(done))
+	}
+	usageChunk := gjson.ParseBytes(done[0])
+	if !usageChunk.Get("choices").IsArray() || len(usageChunk.Get("choices").Array()) != 0 {
+		t.Fatalf("Expected empty choices in usage chunk, got: %s", done[0])
+	}
+	if usageChunk.Get("usage.prompt_tokens").Int() != 10 || usageChunk.Get("usage.completion_tokens").Int() != 5 || usageChunk.Get("usage.total_tokens").Int() != 15 {
+		t.Fatalf("Unexpected usage chunk: %s", done[0])
+	}
+	if usageChunk.Get("id").String() != "resp-1" || usageChunk.Get("model").String() != "gemini-3-pro" {
+		t.Fatalf("Expected usage chunk to carry id and model, got: %s", done[0])
+	}
+}
+
+func TestStreamWithoutIncludeUsageKeepsUsageOnFinalChunk(t *testing.T) {
+	ctx := context.Background()
+	var param any
+	request := []byte(`{"model":"gemini-3-pro","stream":true}`)
+
+	chunk := []byte(`{"response":{"candidates":[{"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}}`)
+	result := ConvertAntigravityResponseToOpenAI(ctx, "model", request, nil, chunk, &param)
+	if len(result) != 1 || gjson.GetBytes(result[0], "usage.total_tokens").Int() != 15 {
+		t.Fatalf("Expected usage on final chunk, got: %q", result)
+	}
+	if done := ConvertAntigravityResponseToOpenAI(ctx, "model", request, nil, []byte("[DONE]"), &param); len(done) != 0 {
+		t.Fatalf("Expected no usage chunk without include_usage, got: %q", done)
+	}
+}
+
+func TestStreamThoughtHeavyEmitsNoEmptyDeltas(t *testing.T) {
+	ctx := context.Background()
+	var param any
+
+	// Captured shape of a thinking model stream: signature-only and empty thought parts
+	// interleaved with reasoning, then the answer and a final chunk carrying only a signature.
+	stream := []string{
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"thought":true,"thoughtSignature":"c2lnLTE="}]}}],"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"thought":true,"text":"Planning the answer."}]}}],"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"thought":true,"text":""}]}}],"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"thought":true,"thoughtSignature":"c2lnLTI="}]}}],"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"thoughtSignature":"c2lnLTM="}]}}],"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"The answer is 42."}]}}],"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+		`{"response":{"candidates":[{"content":{"role":"model","parts":[{"text":"","thoughtSignature":"c2lnLTQ="}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"thoughtsTokenCount":40,"totalTokenCount":55},"responseId":"resp-1","modelVersion":"gemini-3-pro"}}`,
+	}
+
+	var chunks [][]byte
+	for _, raw := range stream {
+		chunks = append(chunks, ConvertAntigravityResponseToOpenAI(ctx, "model", nil, nil, []byte(raw), &param)...)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks (reasoning, content, final), got %d: %q", len(chunks), chunks)
+	}
+	for i, chunk := range chunks {
+		delta := gjson.GetBytes(chunk, "choices.0.delta")
+		finishReason := gjson.GetBytes(chunk, "choices.0.finish_reason")
+		hasDelta := delta.Get("content").String() != "" || delta.Get("reasoning_content").String() != ""
+		if !hasDelta && finishReason.Type == gjson.Null {
+			t.Fatalf("chunk %d has an empty delta and no finish_reason: %s", i, chunk)
+		}
+	}
+	if got := gjson.GetBytes(chunks[0], "choices.0.delta.reasoning_content").String(); got != "Planning the answer." {
+		t.Fatalf("reasoning_content = %q", got)
+	}
+	if got := gjson.GetBytes(chunks[1], "choices.0.delta.content").String(); got != "The answer is 42." {
+		t.Fatalf("content = %q", got)
+	}
+	if got := gjson.GetBytes(chunks[2], "choices.0.finish_reason").String(); got != "stop" {
+		t.Fatalf("finish_reason = %q, want stop", got)
+	}
+	if got := gjson.GetBytes(chunks[2], "usage.completion_tokens_details.reasoning_tokens").Int(); got != 40 {
+		t.Fatalf("reasoning_tokens = %d, want 40", got)
+	}
+}
@@ -0,0 +1,144 @@
+package responses
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+// replayAntigravityStream feeds every line of a captured Antigravity SSE stream through the
+// Responses stream translator and returns the emitted events.
+func replayAntigravityStream(t *testing.T, fixture string) []gjson.Result {
+	t.Helper()
+	file, errOpen := os.Open(fixture)
+	if errOpen != nil {
+		t.Fatalf("open fixture: %v", errOpen)
+	}
+	defer func() { _ = file.Close() }()
+
+	var param any
+	var events []gjson.Result
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		for _, chunk := range ConvertAntigravityResponseToOpenAIResponses(context.Background(), "gemini-3-pro-preview", nil, nil, scanner.Bytes(), &param) {
+			lines := strings.SplitN(string(chunk), "\n", 2)
+			data := strings.TrimSpace(strings.TrimPrefix(lines[1], "data:"))
+			if !gjson.Valid(data) {
+				t.Fatalf("invalid event data: %q", chunk)
+			}
+			event := gjson.Parse(data)
+			if got := strings.TrimSpace(strings.TrimPrefix(lines[0], "event:")); got != event.Get("type").String() {
+				t.Fatalf("event name %q does not match type %q", got, event.Get("type").String())
+			}
+			events = append(events, event)
+		}
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		t.Fatalf("read fixture: %v", errScan)
+	}
+	return events
+}
+
+func TestConvertAntigravityResponseToOpenAIResponses_TwoToolCallsEventSequence(t *testing.T) {
+	events := replayAntigravityStream(t, "testdata/stream_two_tool_calls.txt")
+
+	want := []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added 0 message",
+		"response.content_part.added 0",
+		"response.output_text.delta 0 Checking the weather ",
+		"response.output_text.delta 0 first.",
+		"response.output_text.done 0",
+		"response.content_part.done 0",
+		"response.output_item.done 0 message",
+		"response.output_item.added 1 function_call",
+		`response.function_call_arguments.delta 1 {"city":"Paris"}`,
+		"response.function_call_arguments.done 1",
+		"response.output_item.done 1 function_call",
+		"response.output_item.added 2 message",
+		"response.content_part.added 2",
+		"response.output_text.delta 2 Now the time.",
+		"response.output_text.done 2",
+		"response.content_part.done 2",
+		"response.output_item.done 2 message",
+		"response.output_item.added 3 function_call",
+		`response.function_call_arguments.delta 3 {"zone":"Europe/`,
+		`response.function_call_arguments.delta 3 Paris","utc_offset":true`,
+		`response.function_call_arguments.delta 3 }`,
+		"response.function_call_arguments.done 3",
+		"response.output_item.done 3 function_call",
+		"response.completed",
+	}
+	got := make([]string, 0, len(events))
+	for i, event := range events {
+		if seq := event.Get("sequence_number").Int(); seq != int64(i+1) {
+			t.Fatalf("event %d sequence_number = %d, want %d", i, seq, i+1)
+		}
+		desc := event.Get("type").String()
+		if index := event.Get("output_index"); index.Exists() {
+			desc += fmt.Sprintf(" %d", index.Int())
+		}
+		if itemType := event.Get("item.type"); itemType.Exists() {
+			desc += " " + itemType.String()
+		}
+		if delta := event.Get("delta"); delta.Exists() {
+			desc += " " + delta.String()
+		}
+		got = append(got, desc)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("event sequence:\n%s\n\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	byType := func(eventType string, outputIndex int64) gjson.Result {
+		for _, event := range events {
+			if event.Get("type").String() == eventType && event.Get("output_index").Int() == outputIndex {
+				return event
+			}
+		}
+		t.Fatalf("missing %s for output_index %d", eventType, outputIndex)
+		return gjson.Result{}
+	}
+	if got := byType("response.output_text.done", 2).Get("text").String(); got != "Now the time." {
+		t.Fatalf("second message text = %q, want only its own text", got)
+	}
+	secondCall := byType("response.output_item.done", 3)
+	if secondCall.Get("item.name").String() != "get_time" || secondCall.Get("item.arguments").String() != `{"zone":"Europe/Paris","utc_offset":true}` {
+		t.Fatalf("streamed call done item = %s", secondCall.Get("item").Raw)
+	}
+	firstCallID := byType("response.output_item.added", 1).Get("item.id").String()
+	secondCallID := byType("response.output_item.added", 3).Get("item.id").String()
+	if firstCallID == "" || firstCallID == secondCallID {
+		t.Fatalf("function call item ids = %q and %q, want distinct ids", firstCallID, secondCallID)
+	}
+	if got := byType("response.function_call_arguments.delta", 3).Get("item_id").String(); got != secondCallID {
+		t.Fatalf("streamed delta item_id = %q, want %q", got, secondCallID)
+	}
+
+	completed := events[len(events)-1].Get("response.output")
+	var outputs []string
+	for _, item := range completed.Array() {
+		switch item.Get("type").String() {
+		case "message":
+			outputs = append(outputs, "message:"+item.Get("content.0.text").String())
+		case "function_call":
+			outputs = append(outputs, "function_call:"+item.Get("name").String()+item.Get("arguments").String())
+		}
+	}
+	wantOutputs := []string{
+		"message:Checking the weather first.",
+		`function_call:get_weather{"city":"Paris"}`,
+		"message:Now the time.",
+		`function_call:get_time{"zone":"Europe/Paris","utc_offset":true}`,
+	}
+	if strings.Join(outputs, "|") != strings.Join(wantOutputs, "|") {
+		t.Fatalf("response.output = %v, want %v", outputs, wantOutputs)
+	}
+}
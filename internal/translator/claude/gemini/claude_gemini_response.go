@@ -13,6 +13,7 @@ import (
 	"time"
 
 	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -212,17 +213,8 @@ func ConvertClaudeResponseToGemini(_ context.Context, modelName string, original
 		// Handle message-level changes (like stop reason and usage information)
 		if delta := root.Get("delta"); delta.Exists() {
 			if stopReason := delta.Get("stop_reason"); stopReason.Exists() {
-				switch stopReason.String() {
-				case "end_turn":
-					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "STOP")
-				case "tool_use":
-					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "STOP")
-				case "max_tokens":
-					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "MAX_TOKENS")
-				case "stop_sequence":
-					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "STOP")
-				default:
-					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "STOP")
+				if finishReason := util.MapFinishReason(util.FinishReasonClaude, util.FinishReasonGemini, stopReason.String()); finishReason != "" {
+					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", finishReason)
 				}
 			}
 		}
@@ -256,7 +248,9 @@ func ConvertClaudeResponseToGemini(_ context.Context, modelName string, original
 			// Set traffic type (required by Gemini API)
 			template, _ = sjson.SetBytes(template, "usageMetadata.trafficType", "PROVISIONED_THROUGHPUT")
 		}
-		template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "STOP")
+		if !gjson.GetBytes(template, "candidates.0.finishReason").Exists() {
+			template, _ = sjson.SetBytes(template, "candidates.0.finishReason", "STOP")
+		}
 
 		return [][]byte{template}
 	case "message_stop":
@@ -454,6 +448,11 @@ func ConvertClaudeResponseToGeminiNonStream(_ context.Context, modelName string,
 			}
 
 		case "message_delta":
+			if stopReason := root.Get("delta.stop_reason"); stopReason.Exists() {
+				if finishReason := util.MapFinishReason(util.FinishReasonClaude, util.FinishReasonGemini, stopReason.String()); finishReason != "" {
+					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", finishReason)
+				}
+			}
 			// Extract final usage information using sjson for token counts and metadata
 			if usage := root.Get("usage"); usage.Exists() {
 				usageJSON := []byte(`{}`)
@@ -22,6 +22,12 @@ import (
 	"github.com/tidwall/sjson"
 )
 
+// claudeStructuredOutputToolName is the synthetic tool used to emulate OpenAI json_schema responses.
+const claudeStructuredOutputToolName = "json_response"
+
+// claudeJSONModeInstruction is appended to the system prompt for OpenAI JSON mode requests.
+const claudeJSONModeInstruction = "Respond only with a single valid JSON object. Do not wrap it in markdown code fences and do not add any text before or after it."
+
 var (
 	user    = ""
 	account = ""
@@ -200,6 +206,11 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 			case "user", "assistant":
 				contentBlocks := make([][]byte, 0, 4)
 
+				// Signed thinking blocks from a previous Claude response must lead the assistant turn.
+				if role == "assistant" {
+					contentBlocks = append(contentBlocks, convertOpenAIReasoningDetailsToClaude(message.Get("reasoning_details"))...)
+				}
+
 				// Handle content based on its type (string or array)
 				if contentResult.Exists() && contentResult.Type == gjson.String && contentResult.String() != "" {
 					part := []byte(`{"type":"text","text":""}`)
@@ -309,6 +320,11 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 				} else if parameters := function.Get("parametersJsonSchema"); parameters.Exists() {
 					anthropicTool, _ = sjson.SetRawBytes(anthropicTool, "input_schema", []byte(parameters.Raw))
 				}
+				// Claude enforces strict tool schemas natively. The flag is kept here and the
+				// executor strips it for OAuth credentials, which reject it.
+				if function.Get("strict").Bool() {
+					anthropicTool, _ = sjson.SetBytes(anthropicTool, "strict", true)
+				}
 				anthropicTool = common.AttachCacheControl(anthropicTool, tool)
 				if !gjson.GetBytes(anthropicTool, "cache_control").Exists() {
 					anthropicTool = common.AttachCacheControl(anthropicTool, function)
@@ -333,7 +349,10 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 			choice := toolChoice.String()
 			switch choice {
 			case "none":
-				// Don't set tool_choice, Claude Code will not use tools
+				// Tools stay defined so earlier tool_use turns remain valid; Claude must not call them.
+				if gjson.GetBytes(out, "tools").Exists() {
+					out, _ = sjson.SetRawBytes(out, "tool_choice", []byte(`{"type":"none"}`))
+				}
 			case "auto":
 				out, _ = sjson.SetRawBytes(out, "tool_choice", []byte(`{"type":"auto"}`))
 			case "required":
@@ -351,9 +370,154 @@ func ConvertOpenAIRequestToClaude(modelName string, inputRawJSON []byte, stream
 		}
 	}
 
+	// parallel_tool_calls=false maps to disable_parallel_tool_use, which Claude carries
+	// inside tool_choice. A "none" choice calls no tools at all and takes no such flag.
+	if parallel := root.Get("parallel_tool_calls"); parallel.Exists() && !parallel.Bool() && gjson.GetBytes(out, "tools").Exists() {
+		switch gjson.GetBytes(out, "tool_choice.type").String() {
+		case "":
+			out, _ = sjson.SetRawBytes(out, "tool_choice", []byte(`{"type":"auto","disable_parallel_tool_use":true}`))
+		case "auto", "any", "tool":
+			out, _ = sjson.SetBytes(out, "tool_choice.disable_parallel_tool_use", true)
+		}
+	}
+
+	// Structured outputs: force a synthetic tool whose input schema is the requested JSON schema.
+	// The response translator unwraps the tool arguments back into assistant content.
+	if responseFormat := root.Get("response_format"); responseFormat.Get("type").String() == "json_schema" {
+		out = applyOpenAIJSONSchemaToClaude(out, responseFormat.Get("json_schema"))
+	} else if isOpenAIJSONObjectRequest(rawJSON) {
+		out = applyOpenAIJSONObjectToClaude(out)
+	}
+
+	return out
+}
+
+// applyOpenAIJSONObjectToClaude emulates OpenAI JSON mode, which Claude has no native switch for.
+// A system instruction asks for a bare JSON object and stop sequences are dropped so they cannot
+// cut the object short; the response translator validates the result.
+func applyOpenAIJSONObjectToClaude(out []byte) []byte {
+	out, _ = sjson.DeleteBytes(out, "stop_sequences")
+
+	instruction := []byte(`{"type":"text","text":""}`)
+	instruction, _ = sjson.SetBytes(instruction, "text", claudeJSONModeInstruction)
+
+	systemBlocks := make([][]byte, 0, 2)
+	gjson.GetBytes(out, "system").ForEach(func(_, block gjson.Result) bool {
+		systemBlocks = append(systemBlocks, []byte(block.Raw))
+		return true
+	})
+	systemBlocks = append(systemBlocks, instruction)
+	out, _ = sjson.SetRawBytes(out, "system", common.JoinRawArray(systemBlocks))
+	return out
+}
+
+// isOpenAIJSONObjectRequest reports whether the original OpenAI request enabled JSON mode.
+func isOpenAIJSONObjectRequest(originalRequestRawJSON []byte) bool {
+	return gjson.GetBytes(originalRequestRawJSON, "response_format.type").String() == "json_object"
+}
+
+// applyOpenAIJSONSchemaToClaude appends the structured output tool and forces Claude to call it.
+func applyOpenAIJSONSchemaToClaude(out []byte, jsonSchema gjson.Result) []byte {
+	schema := jsonSchema.Get("schema")
+	if !schema.Exists() || !schema.IsObject() {
+		return out
+	}
+
+	tool := []byte(`{"name":"","description":"Respond with a JSON object that matches the input schema.","input_schema":{}}`)
+	tool, _ = sjson.SetBytes(tool, "name", claudeStructuredOutputToolName)
+	if description := jsonSchema.Get("description").String(); description != "" {
+		tool, _ = sjson.SetBytes(tool, "description", description)
+	}
+	tool, _ = sjson.SetRawBytes(tool, "input_schema", []byte(schema.Raw))
+
+	tools := make([][]byte, 0, 1)
+	gjson.GetBytes(out, "tools").ForEach(func(_, existing gjson.Result) bool {
+		tools = append(tools, []byte(existing.Raw))
+		return true
+	})
+	tools = append(tools, tool)
+	out, _ = sjson.SetRawBytes(out, "tools", common.JoinRawArray(tools))
+
+	toolChoice := []byte(`{"type":"tool","name":""}`)
+	toolChoice, _ = sjson.SetBytes(toolChoice, "name", claudeStructuredOutputToolName)
+	out, _ = sjson.SetRawBytes(out, "tool_choice", toolChoice)
 	return out
 }
 
+// isOpenAIJSONSchemaRequest reports whether the original OpenAI request asked for structured outputs
+// that were translated into the synthetic Claude tool.
+func isOpenAIJSONSchemaRequest(originalRequestRawJSON []byte) bool {
+	responseFormat := gjson.GetBytes(originalRequestRawJSON, "response_format")
+	return responseFormat.Get("type").String() == "json_schema" && responseFormat.Get("json_schema.schema").IsObject()
+}
+
+// convertOpenAIReasoningDetailsToClaude rebuilds Claude thinking blocks from the
+// reasoning_details of an assistant message. Items sharing an index are merged because
+// clients may echo streamed fragments as-is. Text without a signature is dropped since
+// Claude rejects unsigned thinking blocks in history.
+func convertOpenAIReasoningDetailsToClaude(details gjson.Result) [][]byte {
+	if !details.IsArray() {
+		return nil
+	}
+	type reasoningBlock struct {
+		redacted  bool
+		text      strings.Builder
+		signature string
+		data      string
+	}
+	var ordered []*reasoningBlock
+	byIndex := make(map[int64]*reasoningBlock)
+	details.ForEach(func(_, detail gjson.Result) bool {
+		detailType := detail.Get("type").String()
+		if detailType != "reasoning.text" && detailType != "reasoning.encrypted" {
+			return true
+		}
+		var block *reasoningBlock
+		if index := detail.Get("index"); index.Exists() {
+			block = byIndex[index.Int()]
+			if block == nil {
+				block = &reasoningBlock{}
+				byIndex[index.Int()] = block
+				ordered = append(ordered, block)
+			}
+		} else {
+			block = &reasoningBlock{}
+			ordered = append(ordered, block)
+		}
+		if detailType == "reasoning.encrypted" {
+			block.redacted = true
+			block.data += detail.Get("data").String()
+			return true
+		}
+		block.text.WriteString(detail.Get("text").String())
+		if signature := detail.Get("signature").String(); signature != "" {
+			block.signature = signature
+		}
+		return true
+	})
+
+	blocks := make([][]byte, 0, len(ordered))
+	for _, block := range ordered {
+		if block.redacted {
+			if block.data == "" {
+				continue
+			}
+			part := []byte(`{"type":"redacted_thinking","data":""}`)
+			part, _ = sjson.SetBytes(part, "data", block.data)
+			blocks = append(blocks, part)
+			continue
+		}
+		if block.signature == "" {
+			continue
+		}
+		part := []byte(`{"type":"thinking","thinking":"","signature":""}`)
+		part, _ = sjson.SetBytes(part, "thinking", block.text.String())
+		part, _ = sjson.SetBytes(part, "signature", block.signature)
+		blocks = append(blocks, part)
+	}
+	return blocks
+}
+
 func convertOpenAIContentPartToClaudePart(part gjson.Result) string {
 	var claudePart []byte
 	switch part.Get("type").String() {
@@ -406,3 +406,186 @@ func TestConvertOpenAIRequestToClaude_PartCacheControlWinsOverMessageLevel(t *te
 		t.Fatalf("part-level cache_control should win; unexpected ttl: %s", result)
 	}
 }
+
+func TestConvertOpenAIRequestToClaude_JSONSchemaResponseFormatForcesTool(t *testing.T) {
+	inputJSON := `{
+		"model": "gpt-4.1",
+		"messages": [{"role": "user", "content": "Describe a company"}],
+		"tools": [{"type": "function", "function": {"name": "lookup", "parameters": {"type": "object"}}}],
+		"tool_choice": "auto",
+		"response_format": {
+			"type": "json_schema",
+			"json_schema": {
+				"name": "company",
+				"schema": {
+					"type": "object",
+					"additionalProperties": false,
+					"properties": {
+						"name": {"type": "string", "minLength": 1},
+						"address": {
+							"type": "object",
+							"properties": {"city": {"type": "string", "pattern": "^[A-Z]"}},
+							"required": ["city"]
+						}
+					},
+					"required": ["name", "address"]
+				}
+			}
+		}
+	}`
+
+	result := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(inputJSON), false)
+	resultJSON := gjson.ParseBytes(result)
+
+	tools := resultJSON.Get("tools").Array()
+	if len(tools) != 2 {
+		t.Fatalf("tools length = %d, want 2. Output: %s", len(tools), string(result))
+	}
+	if got := tools[0].Get("name").String(); got != "lookup" {
+		t.Fatalf("tools[0].name = %q, want lookup", got)
+	}
+	if got := tools[1].Get("name").String(); got != claudeStructuredOutputToolName {
+		t.Fatalf("tools[1].name = %q, want %q", got, claudeStructuredOutputToolName)
+	}
+	if got := tools[1].Get("input_schema.properties.address.properties.city.pattern").String(); got != "^[A-Z]" {
+		t.Fatalf("input_schema should keep the requested schema, got %s", tools[1].Get("input_schema").Raw)
+	}
+	if got := resultJSON.Get("tool_choice.type").String(); got != "tool" {
+		t.Fatalf("tool_choice.type = %q, want tool", got)
+	}
+	if got := resultJSON.Get("tool_choice.name").String(); got != claudeStructuredOutputToolName {
+		t.Fatalf("tool_choice.name = %q, want %q", got, claudeStructuredOutputToolName)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ReasoningDetailsMergeFragmentsAndDropUnsigned(t *testing.T) {
+	input := []byte(`{"model":"claude-sonnet-4-5","messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":"hello","reasoning_details":[
+			{"type":"reasoning.text","text":"part one ","index":0},
+			{"type":"reasoning.text","text":"part two","signature":"sig-1","index":0},
+			{"type":"reasoning.text","text":"unsigned","index":1},
+			{"type":"reasoning.summary","summary":"ignored","index":2}
+		]},
+		{"role":"user","content":"again"}
+	]}`)
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", input, false)
+
+	content := gjson.GetBytes(out, "messages.1.content")
+	if n := len(content.Array()); n != 2 {
+		t.Fatalf("assistant content = %s, want thinking + text", content.Raw)
+	}
+	if content.Get("0.type").String() != "thinking" || content.Get("0.thinking").String() != "part one part two" || content.Get("0.signature").String() != "sig-1" {
+		t.Fatalf("thinking block = %s", content.Get("0").Raw)
+	}
+	if content.Get("1.text").String() != "hello" {
+		t.Fatalf("text block = %s", content.Get("1").Raw)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_JSONObjectResponseFormatAddsInstruction(t *testing.T) {
+	inputJSON := `{
+		"model": "gpt-4.1",
+		"messages": [
+			{"role": "system", "content": "You are terse."},
+			{"role": "user", "content": "List three colors as JSON"}
+		],
+		"stop": ["\n\n"],
+		"response_format": {"type": "json_object"}
+	}`
+
+	result := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(inputJSON), false)
+	resultJSON := gjson.ParseBytes(result)
+
+	system := resultJSON.Get("system").Array()
+	if len(system) != 2 {
+		t.Fatalf("system length = %d, want 2. Output: %s", len(system), result)
+	}
+	if got := system[0].Get("text").String(); got != "You are terse." {
+		t.Fatalf("system[0].text = %q, want the client system prompt first", got)
+	}
+	if got := system[1].Get("text").String(); got != claudeJSONModeInstruction {
+		t.Fatalf("system[1].text = %q, want the JSON mode instruction", got)
+	}
+	if resultJSON.Get("stop_sequences").Exists() {
+		t.Fatalf("stop_sequences must be dropped in JSON mode. Output: %s", result)
+	}
+	if resultJSON.Get("tools").Exists() || resultJSON.Get("tool_choice").Exists() {
+		t.Fatalf("json_object must not add the structured output tool. Output: %s", result)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_KeepsStrictToolFlag(t *testing.T) {
+	inputJSON := `{
+		"model": "gpt-4.1",
+		"messages": [{"role": "user", "content": "Weather in Paris?"}],
+		"tools": [
+			{"type": "function", "function": {"name": "get_weather", "strict": true, "parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"], "additionalProperties": false}}},
+			{"type": "function", "function": {"name": "lookup", "strict": false, "parameters": {"type": "object", "properties": {}}}}
+		]
+	}`
+
+	result := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(inputJSON), false)
+
+	if !gjson.GetBytes(result, "tools.0.strict").Bool() {
+		t.Fatalf("tools.0.strict missing: %s", result)
+	}
+	if got := gjson.GetBytes(result, "tools.0.input_schema.additionalProperties"); got.Type != gjson.False {
+		t.Fatalf("input_schema must be passed through unchanged, got additionalProperties %s", got.Raw)
+	}
+	if gjson.GetBytes(result, "tools.1.strict").Exists() {
+		t.Fatalf("tools.1.strict should be omitted when false: %s", result)
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_MapsToolChoiceAndParallelToolCalls(t *testing.T) {
+	tests := []struct {
+		name       string
+		toolChoice string
+		want       string
+	}{
+		{name: "none", toolChoice: `"none"`, want: `{"type":"none"}`},
+		{name: "auto", toolChoice: `"auto"`, want: `{"type":"auto","disable_parallel_tool_use":true}`},
+		{name: "required", toolChoice: `"required"`, want: `{"type":"any","disable_parallel_tool_use":true}`},
+		{name: "function", toolChoice: `{"type":"function","function":{"name":"get_weather"}}`, want: `{"type":"tool","name":"get_weather","disable_parallel_tool_use":true}`},
+		{name: "unset", want: `{"type":"auto","disable_parallel_tool_use":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputJSON := `{
+				"model": "gpt-4.1",
+				"messages": [{"role": "user", "content": "Weather in Paris?"}],
+				"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object", "properties": {}}}}],
+				"parallel_tool_calls": false`
+			if tt.toolChoice != "" {
+				inputJSON += `, "tool_choice": ` + tt.toolChoice
+			}
+			inputJSON += `}`
+
+			result := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(inputJSON), false)
+
+			if got := gjson.GetBytes(result, "tool_choice").Raw; got != tt.want {
+				t.Fatalf("tool_choice = %s, want %s. Output: %s", got, tt.want, result)
+			}
+			if got := gjson.GetBytes(result, "tools.#").Int(); got != 1 {
+				t.Fatalf("tools = %d, want the tool kept. Output: %s", got, result)
+			}
+		})
+	}
+}
+
+func TestConvertOpenAIRequestToClaude_ParallelToolCallsTrueKeepsToolChoice(t *testing.T) {
+	inputJSON := `{
+		"model": "gpt-4.1",
+		"messages": [{"role": "user", "content": "Weather in Paris?"}],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object", "properties": {}}}}],
+		"tool_choice": "required",
+		"parallel_tool_calls": true
+	}`
+
+	result := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(inputJSON), false)
+
+	if got := gjson.GetBytes(result, "tool_choice").Raw; got != `{"type":"any"}` {
+		t.Fatalf("tool_choice = %s, want {\"type\":\"any\"}. Output: %s", got, result)
+	}
+}
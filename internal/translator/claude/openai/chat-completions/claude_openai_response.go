@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -28,6 +30,44 @@ type ConvertAnthropicResponseToOpenAIParams struct {
 	Usage        claudeUsageTokens
 	// Tool calls accumulator for streaming
 	ToolCallsAccumulator map[int]*ToolCallAccumulator
+	// StructuredOutputBlocks tracks tool_use blocks of the synthetic json_schema tool,
+	// whose arguments are streamed back as assistant content.
+	StructuredOutputBlocks map[int]bool
+	// StructuredOutput is set once a structured output block has been streamed.
+	StructuredOutput bool
+	// ThinkingBlocks accumulates thinking text and signatures by content block index so
+	// each block can be replayed through reasoning_details.
+	ThinkingBlocks map[int]*ThinkingBlockAccumulator
+	// ReasoningDetailCount numbers the reasoning_details items emitted so far.
+	ReasoningDetailCount int
+}
+
+// ThinkingBlockAccumulator holds the text and signature of a streamed thinking block.
+type ThinkingBlockAccumulator struct {
+	Text      strings.Builder
+	Signature string
+}
+
+// claudeReasoningDetailFormat tags reasoning_details items carrying Claude thinking blocks.
+const claudeReasoningDetailFormat = "anthropic-claude-v1"
+
+// claudeThinkingReasoningDetail builds a reasoning_details item for a signed thinking block.
+func claudeThinkingReasoningDetail(text, signature string, index int) []byte {
+	detail := []byte(`{"type":"reasoning.text","text":"","signature":"","format":""}`)
+	detail, _ = sjson.SetBytes(detail, "text", text)
+	detail, _ = sjson.SetBytes(detail, "signature", signature)
+	detail, _ = sjson.SetBytes(detail, "format", claudeReasoningDetailFormat)
+	detail, _ = sjson.SetBytes(detail, "index", index)
+	return detail
+}
+
+// claudeRedactedReasoningDetail builds a reasoning_details item for a redacted_thinking block.
+func claudeRedactedReasoningDetail(data string, index int) []byte {
+	detail := []byte(`{"type":"reasoning.encrypted","data":"","format":""}`)
+	detail, _ = sjson.SetBytes(detail, "data", data)
+	detail, _ = sjson.SetBytes(detail, "format", claudeReasoningDetailFormat)
+	detail, _ = sjson.SetBytes(detail, "index", index)
+	return detail
 }
 
 type claudeUsageTokens struct {
@@ -144,6 +184,23 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		// Start of a content block (text, tool use, or reasoning)
 		if contentBlock := root.Get("content_block"); contentBlock.Exists() {
 			blockType := contentBlock.Get("type").String()
+			p := (*param).(*ConvertAnthropicResponseToOpenAIParams)
+
+			switch blockType {
+			case "thinking":
+				if p.ThinkingBlocks == nil {
+					p.ThinkingBlocks = make(map[int]*ThinkingBlockAccumulator)
+				}
+				p.ThinkingBlocks[int(root.Get("index").Int())] = &ThinkingBlockAccumulator{}
+				return [][]byte{}
+			case "redacted_thinking":
+				// Redacted thinking has no readable text; pass the opaque data through so the
+				// client can echo it back on the next turn.
+				detail := claudeRedactedReasoningDetail(contentBlock.Get("data").String(), p.ReasoningDetailCount)
+				p.ReasoningDetailCount++
+				template, _ = sjson.SetRawBytes(template, "choices.0.delta.reasoning_details", common.JoinRawArray([][]byte{detail}))
+				return [][]byte{template}
+			}
 
 			if blockType == "tool_use" {
 				// Start of tool call - initialize accumulator to track arguments
@@ -151,6 +208,15 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 				toolName := contentBlock.Get("name").String()
 				index := int(root.Get("index").Int())
 
+				if toolName == claudeStructuredOutputToolName && isOpenAIJSONSchemaRequest(originalRequestRawJSON) {
+					if (*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutputBlocks == nil {
+						(*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutputBlocks = make(map[int]bool)
+					}
+					(*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutputBlocks[index] = true
+					(*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutput = true
+					return [][]byte{}
+				}
+
 				if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator == nil {
 					(*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator = make(map[int]*ToolCallAccumulator)
 				}
@@ -183,12 +249,29 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 				// Accumulate reasoning/thinking content
 				if thinking := delta.Get("thinking"); thinking.Exists() {
 					template, _ = sjson.SetBytes(template, "choices.0.delta.reasoning_content", thinking.String())
+					if block := (*param).(*ConvertAnthropicResponseToOpenAIParams).ThinkingBlocks[int(root.Get("index").Int())]; block != nil {
+						block.Text.WriteString(thinking.String())
+					}
 					hasContent = true
 				}
+			case "signature_delta":
+				// The signature is emitted with the complete block on content_block_stop.
+				if block := (*param).(*ConvertAnthropicResponseToOpenAIParams).ThinkingBlocks[int(root.Get("index").Int())]; block != nil {
+					block.Signature += delta.Get("signature").String()
+				}
+				return [][]byte{}
 			case "input_json_delta":
 				// Tool use input delta - accumulate arguments for tool calls
 				if partialJSON := delta.Get("partial_json"); partialJSON.Exists() {
 					index := int(root.Get("index").Int())
+					if (*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutputBlocks[index] {
+						// Structured output arguments are the JSON answer itself.
+						if partialJSON.String() == "" {
+							return [][]byte{}
+						}
+						template, _ = sjson.SetBytes(template, "choices.0.delta.content", partialJSON.String())
+						return [][]byte{template}
+					}
 					if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator != nil {
 						if accumulator, exists := (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator[index]; exists {
 							accumulator.Arguments.WriteString(partialJSON.String())
@@ -208,6 +291,23 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 	case "content_block_stop":
 		// End of content block - output complete tool call if it's a tool_use block
 		index := int(root.Get("index").Int())
+		if (*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutputBlocks[index] {
+			delete((*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutputBlocks, index)
+			return [][]byte{}
+		}
+		if block, exists := (*param).(*ConvertAnthropicResponseToOpenAIParams).ThinkingBlocks[index]; exists {
+			// Emit the complete signed block so clients can send it back in reasoning_details;
+			// Claude rejects thinking blocks in history without their signature.
+			delete((*param).(*ConvertAnthropicResponseToOpenAIParams).ThinkingBlocks, index)
+			if block.Signature == "" {
+				return [][]byte{}
+			}
+			p := (*param).(*ConvertAnthropicResponseToOpenAIParams)
+			detail := claudeThinkingReasoningDetail(block.Text.String(), block.Signature, p.ReasoningDetailCount)
+			p.ReasoningDetailCount++
+			template, _ = sjson.SetRawBytes(template, "choices.0.delta.reasoning_details", common.JoinRawArray([][]byte{detail}))
+			return [][]byte{template}
+		}
 		if (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator != nil {
 			if accumulator, exists := (*param).(*ConvertAnthropicResponseToOpenAIParams).ToolCallsAccumulator[index]; exists {
 				// Build complete tool call with accumulated arguments
@@ -233,14 +333,22 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		// Handle message-level changes including stop reason and usage
 		if delta := root.Get("delta"); delta.Exists() {
 			if stopReason := delta.Get("stop_reason"); stopReason.Exists() {
-				(*param).(*ConvertAnthropicResponseToOpenAIParams).FinishReason = mapAnthropicStopReasonToOpenAI(stopReason.String())
-				template, _ = sjson.SetBytes(template, "choices.0.finish_reason", (*param).(*ConvertAnthropicResponseToOpenAIParams).FinishReason)
+				finishReason := util.MapFinishReason(util.FinishReasonClaude, util.FinishReasonOpenAI, stopReason.String())
+				if finishReason == "" || (finishReason == "tool_calls" && (*param).(*ConvertAnthropicResponseToOpenAIParams).StructuredOutput) {
+					finishReason = "stop"
+				}
+				(*param).(*ConvertAnthropicResponseToOpenAIParams).FinishReason = finishReason
+				template, _ = sjson.SetBytes(template, "choices.0.finish_reason", finishReason)
 			}
 		}
 
-		// Handle usage information for token counts
+		// Handle usage information for token counts. With stream_options.include_usage the
+		// totals are reported in a dedicated chunk on message_stop instead.
 		if usage := root.Get("usage"); usage.Exists() {
 			(*param).(*ConvertAnthropicResponseToOpenAIParams).Usage.Merge(usage)
+			if gjson.GetBytes(originalRequestRawJSON, "stream_options.include_usage").Bool() {
+				return [][]byte{template}
+			}
 			promptTokens, completionTokens, totalTokens, cachedTokens := (*param).(*ConvertAnthropicResponseToOpenAIParams).Usage.OpenAIUsage()
 			template, _ = sjson.SetBytes(template, "usage.prompt_tokens", promptTokens)
 			template, _ = sjson.SetBytes(template, "usage.completion_tokens", completionTokens)
@@ -250,8 +358,17 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 		return [][]byte{template}
 
 	case "message_stop":
-		// Final message event - no additional output needed
-		return [][]byte{}
+		// Final message event - emit the usage chunk when the client asked for it
+		if !gjson.GetBytes(originalRequestRawJSON, "stream_options.include_usage").Bool() {
+			return [][]byte{}
+		}
+		promptTokens, completionTokens, totalTokens, cachedTokens := (*param).(*ConvertAnthropicResponseToOpenAIParams).Usage.OpenAIUsage()
+		template, _ = sjson.SetRawBytes(template, "choices", []byte(`[]`))
+		template, _ = sjson.SetBytes(template, "usage.prompt_tokens", promptTokens)
+		template, _ = sjson.SetBytes(template, "usage.completion_tokens", completionTokens)
+		template, _ = sjson.SetBytes(template, "usage.total_tokens", totalTokens)
+		template, _ = sjson.SetBytes(template, "usage.prompt_tokens_details.cached_tokens", cachedTokens)
+		return [][]byte{template}
 
 	case "ping":
 		// Ping events for keeping connection alive - no output needed
@@ -273,22 +390,6 @@ func ConvertClaudeResponseToOpenAI(_ context.Context, modelName string, original
 	}
 }
 
-// mapAnthropicStopReasonToOpenAI maps Anthropic stop reasons to OpenAI stop reasons
-func mapAnthropicStopReasonToOpenAI(anthropicReason string) string {
-	switch anthropicReason {
-	case "end_turn":
-		return "stop"
-	case "tool_use":
-		return "tool_calls"
-	case "max_tokens":
-		return "length"
-	case "stop_sequence":
-		return "stop"
-	default:
-		return "stop"
-	}
-}
-
 // ConvertClaudeResponseToOpenAINonStream converts a non-streaming Claude Code response to a non-streaming OpenAI response.
 // This function processes the complete Claude Code response and transforms it into a single OpenAI-compatible
 // JSON response. It handles message content, tool calls, reasoning content, and usage metadata, combining all
@@ -322,6 +423,9 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 	var stopReason string
 	var contentParts []string
 	var reasoningParts []string
+	var reasoningDetails [][]byte
+	thinkingBlocks := make(map[int]*ThinkingBlockAccumulator)
+	structuredOutput := isOpenAIJSONSchemaRequest(originalRequestRawJSON)
 	usageTokens := claudeUsageTokens{}
 	toolCallsAccumulator := make(map[int]*ToolCallAccumulator)
 
@@ -344,8 +448,11 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 			if contentBlock := root.Get("content_block"); contentBlock.Exists() {
 				blockType := contentBlock.Get("type").String()
 				if blockType == "thinking" {
-					// Start of thinking/reasoning content - skip for now as it's handled in delta
+					// Start of thinking/reasoning content; text and signature arrive as deltas
+					thinkingBlocks[int(root.Get("index").Int())] = &ThinkingBlockAccumulator{}
 					continue
+				} else if blockType == "redacted_thinking" {
+					reasoningDetails = append(reasoningDetails, claudeRedactedReasoningDetail(contentBlock.Get("data").String(), len(reasoningDetails)))
 				} else if blockType == "tool_use" {
 					// Initialize tool call accumulator for this index
 					index := int(root.Get("index").Int())
@@ -370,6 +477,13 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 					// Accumulate reasoning/thinking content
 					if thinking := delta.Get("thinking"); thinking.Exists() {
 						reasoningParts = append(reasoningParts, thinking.String())
+						if block := thinkingBlocks[int(root.Get("index").Int())]; block != nil {
+							block.Text.WriteString(thinking.String())
+						}
+					}
+				case "signature_delta":
+					if block := thinkingBlocks[int(root.Get("index").Int())]; block != nil {
+						block.Signature += delta.Get("signature").String()
 					}
 				case "input_json_delta":
 					// Accumulate tool call arguments
@@ -385,6 +499,12 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 		case "content_block_stop":
 			// Finalize tool call arguments for this index when content block ends
 			index := int(root.Get("index").Int())
+			if block, exists := thinkingBlocks[index]; exists {
+				delete(thinkingBlocks, index)
+				if block.Signature != "" {
+					reasoningDetails = append(reasoningDetails, claudeThinkingReasoningDetail(block.Text.String(), block.Signature, len(reasoningDetails)))
+				}
+			}
 			if accumulator, exists := toolCallsAccumulator[index]; exists {
 				if accumulator.Arguments.Len() == 0 {
 					accumulator.Arguments.WriteString("{}")
@@ -427,6 +547,9 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 		// Add reasoning as a separate field in the message
 		out, _ = sjson.SetBytes(out, "choices.0.message.reasoning", reasoningContent)
 	}
+	if len(reasoningDetails) > 0 {
+		out, _ = sjson.SetRawBytes(out, "choices.0.message.reasoning_details", common.JoinRawArray(reasoningDetails))
+	}
 
 	// Set tool calls if any were accumulated during processing
 	if len(toolCallsAccumulator) > 0 {
@@ -446,6 +569,13 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 
 			arguments := accumulator.Arguments.String()
 
+			if structuredOutput && accumulator.Name == claudeStructuredOutputToolName {
+				// Unwrap the synthetic json_schema tool back into plain assistant content.
+				messageContent += arguments
+				out, _ = sjson.SetBytes(out, "choices.0.message.content", messageContent)
+				continue
+			}
+
 			idPath := fmt.Sprintf("choices.0.message.tool_calls.%d.id", toolCallsCount)
 			typePath := fmt.Sprintf("choices.0.message.tool_calls.%d.type", toolCallsCount)
 			namePath := fmt.Sprintf("choices.0.message.tool_calls.%d.function.name", toolCallsCount)
@@ -457,14 +587,58 @@ func ConvertClaudeResponseToOpenAINonStream(_ context.Context, _ string, origina
 			out, _ = sjson.SetBytes(out, argumentsPath, arguments)
 			toolCallsCount++
 		}
-		if toolCallsCount > 0 {
-			out, _ = sjson.SetBytes(out, "choices.0.finish_reason", "tool_calls")
-		} else if finishReason := mapAnthropicStopReasonToOpenAI(stopReason); finishReason != "stop" {
+		finishReason := util.MapFinishReasonWithToolCalls(util.FinishReasonClaude, util.FinishReasonOpenAI, stopReason, toolCallsCount > 0)
+		if toolCallsCount > 0 && finishReason == "" {
+			finishReason = "tool_calls"
+		} else if toolCallsCount == 0 && finishReason == "tool_calls" {
+			// Only the unwrapped structured output tool was called.
+			finishReason = "stop"
+		}
+		if finishReason != "" {
 			out, _ = sjson.SetBytes(out, "choices.0.finish_reason", finishReason)
 		}
-	} else if finishReason := mapAnthropicStopReasonToOpenAI(stopReason); finishReason != "stop" {
+	} else if finishReason := util.MapFinishReason(util.FinishReasonClaude, util.FinishReasonOpenAI, stopReason); finishReason != "" {
 		out, _ = sjson.SetBytes(out, "choices.0.finish_reason", finishReason)
 	}
 
+	if isOpenAIJSONObjectRequest(originalRequestRawJSON) && !gjson.GetBytes(out, "choices.0.message.tool_calls").Exists() {
+		out = enforceClaudeJSONModeContent(out, messageContent)
+	}
+
+	return out
+}
+
+// enforceClaudeJSONModeContent makes a JSON mode answer parse as JSON. Markdown code fences
+// are stripped first; prose that still does not parse is wrapped as {"text": ...} so clients
+// never receive plain text. Truncated output keeps its "length" finish reason untouched.
+func enforceClaudeJSONModeContent(out []byte, content string) []byte {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" || gjson.GetBytes(out, "choices.0.finish_reason").String() == "length" {
+		return out
+	}
+	if unfenced, ok := stripMarkdownCodeFence(trimmed); ok {
+		trimmed = unfenced
+	}
+	if gjson.Valid(trimmed) {
+		out, _ = sjson.SetBytes(out, "choices.0.message.content", trimmed)
+		return out
+	}
+	wrapped, _ := sjson.SetBytes([]byte(`{"text":""}`), "text", content)
+	out, _ = sjson.SetBytes(out, "choices.0.message.content", string(wrapped))
 	return out
 }
+
+// stripMarkdownCodeFence returns the body of a text consisting of a single ``` fenced block.
+func stripMarkdownCodeFence(text string) (string, bool) {
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text, false
+	}
+	body := strings.TrimSuffix(text[3:], "```")
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		// Drop the info string, e.g. ```json.
+		if info := strings.TrimSpace(body[:newline]); !strings.ContainsAny(info, "{[") {
+			body = body[newline+1:]
+		}
+	}
+	return strings.TrimSpace(body), true
+}
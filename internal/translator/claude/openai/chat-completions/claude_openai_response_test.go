@@ -2,9 +2,12 @@ package chat_completions
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 )
 
 func TestConvertClaudeResponseToOpenAI_StreamUsageIncludesCachedTokens(t *testing.T) {
@@ -114,3 +117,260 @@ func TestConvertClaudeResponseToOpenAINonStream_UsageMergesMessageStartUsage(t *
 		t.Fatalf("expected cached_tokens %d, got %d", 22000, gotCachedTokens)
 	}
 }
+
+func TestConvertClaudeResponseToOpenAI_UnwrapsStructuredOutputTool(t *testing.T) {
+	ctx := context.Background()
+	originalRequest := []byte(`{"response_format":{"type":"json_schema","json_schema":{"name":"company","schema":{"type":"object"}}}}`)
+	events := []string{
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":3,"output_tokens":1}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"json_response","input":{}}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"name\":"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"\"Acme\"}"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+	}
+
+	var param any
+	var content string
+	var finishReason string
+	for _, event := range events {
+		for _, chunk := range ConvertClaudeResponseToOpenAI(ctx, "claude-sonnet-4-5", originalRequest, nil, []byte(event), &param) {
+			if gjson.GetBytes(chunk, "choices.0.delta.tool_calls").Exists() {
+				t.Fatalf("structured output must not be emitted as tool call: %s", chunk)
+			}
+			content += gjson.GetBytes(chunk, "choices.0.delta.content").String()
+			if reason := gjson.GetBytes(chunk, "choices.0.finish_reason"); reason.Type == gjson.String {
+				finishReason = reason.String()
+			}
+		}
+	}
+	if content != `{"name":"Acme"}` {
+		t.Fatalf("stream content = %q, want %q", content, `{"name":"Acme"}`)
+	}
+	if finishReason != "stop" {
+		t.Fatalf("finish_reason = %q, want stop", finishReason)
+	}
+
+	var nonStream []byte
+	for _, event := range events {
+		nonStream = append(nonStream, []byte(event+"\n")...)
+	}
+	out := ConvertClaudeResponseToOpenAINonStream(ctx, "claude-sonnet-4-5", originalRequest, nil, nonStream, nil)
+	if got := gjson.GetBytes(out, "choices.0.message.content").String(); got != `{"name":"Acme"}` {
+		t.Fatalf("non-stream content = %q, want %q. Output: %s", got, `{"name":"Acme"}`, out)
+	}
+	if gjson.GetBytes(out, "choices.0.message.tool_calls").Exists() {
+		t.Fatalf("non-stream output must not include tool_calls: %s", out)
+	}
+	if got := gjson.GetBytes(out, "choices.0.finish_reason").String(); got != "stop" {
+		t.Fatalf("non-stream finish_reason = %q, want stop", got)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_StreamIncludeUsageEmitsUsageChunk(t *testing.T) {
+	ctx := context.Background()
+	var param any
+	request := []byte(`{"model":"claude-opus-4-6","stream":true,"stream_options":{"include_usage":true}}`)
+
+	ConvertClaudeResponseToOpenAI(ctx, "claude-opus-4-6", request, nil, []byte(`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":13,"output_tokens":1}}}`), &param)
+	finish := ConvertClaudeResponseToOpenAI(ctx, "claude-opus-4-6", request, nil, []byte(`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`), &param)
+	if len(finish) != 1 {
+		t.Fatalf("expected 1 finish chunk, got %d", len(finish))
+	}
+	if gjson.GetBytes(finish[0], "usage").Exists() {
+		t.Fatalf("expected usage to move to the dedicated chunk, got %s", finish[0])
+	}
+	if got := gjson.GetBytes(finish[0], "choices.0.finish_reason").String(); got != "stop" {
+		t.Fatalf("expected finish_reason stop, got %q", got)
+	}
+
+	stop := ConvertClaudeResponseToOpenAI(ctx, "claude-opus-4-6", request, nil, []byte(`data: {"type":"message_stop"}`), &param)
+	if len(stop) != 1 {
+		t.Fatalf("expected 1 usage chunk, got %d", len(stop))
+	}
+	usageChunk := gjson.ParseBytes(stop[0])
+	if !usageChunk.Get("choices").IsArray() || len(usageChunk.Get("choices").Array()) != 0 {
+		t.Fatalf("expected empty choices, got %s", stop[0])
+	}
+	if usageChunk.Get("usage.prompt_tokens").Int() != 13 || usageChunk.Get("usage.completion_tokens").Int() != 4 || usageChunk.Get("usage.total_tokens").Int() != 17 {
+		t.Fatalf("unexpected usage chunk %s", stop[0])
+	}
+	if usageChunk.Get("id").String() != "msg_1" {
+		t.Fatalf("expected usage chunk id msg_1, got %s", stop[0])
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_StreamWithoutIncludeUsageOmitsUsageChunk(t *testing.T) {
+	ctx := context.Background()
+	var param any
+	request := []byte(`{"model":"claude-opus-4-6","stream":true}`)
+
+	finish := ConvertClaudeResponseToOpenAI(ctx, "claude-opus-4-6", request, nil, []byte(`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"input_tokens":13,"output_tokens":4}}`), &param)
+	if len(finish) != 1 || gjson.GetBytes(finish[0], "usage.total_tokens").Int() != 17 {
+		t.Fatalf("expected usage on finish chunk, got %q", finish)
+	}
+	if stop := ConvertClaudeResponseToOpenAI(ctx, "claude-opus-4-6", request, nil, []byte(`data: {"type":"message_stop"}`), &param); len(stop) != 0 {
+		t.Fatalf("expected no usage chunk, got %q", stop)
+	}
+}
+
+const claudeInterleavedThinkingStream = `data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":10,"output_tokens":1}}}
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the "}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"weather."}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-abc"}}
+data: {"type":"content_block_stop","index":0}
+data: {"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"opaque-data"}}
+data: {"type":"content_block_stop","index":1}
+data: {"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}
+data: {"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Checking."}}
+data: {"type":"content_block_stop","index":2}
+data: {"type":"content_block_start","index":3,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+data: {"type":"content_block_delta","index":3,"delta":{"type":"input_json_delta","partial_json":"{\"city\":\"Paris\"}"}}
+data: {"type":"content_block_stop","index":3}
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}
+data: {"type":"message_stop"}`
+
+// assertClaudeThinkingRoundTrip converts the assistant message back into a Claude request
+// and checks that the signed and redacted thinking blocks lead the assistant turn.
+func assertClaudeThinkingRoundTrip(t *testing.T, assistant string) {
+	t.Helper()
+	request := `{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"weather in Paris?"},` + assistant +
+		`,{"role":"tool","tool_call_id":"toolu_1","content":"sunny"}]}`
+	out := ConvertOpenAIRequestToClaude("claude-sonnet-4-5", []byte(request), false)
+
+	content := gjson.GetBytes(out, "messages.1.content")
+	if got := content.Get("0.type").String(); got != "thinking" {
+		t.Fatalf("first assistant block = %q, want thinking; request = %s", got, out)
+	}
+	if got := content.Get("0.thinking").String(); got != "Need the weather." {
+		t.Fatalf("thinking text = %q", got)
+	}
+	if got := content.Get("0.signature").String(); got != "sig-abc" {
+		t.Fatalf("thinking signature = %q, want sig-abc", got)
+	}
+	if content.Get("1.type").String() != "redacted_thinking" || content.Get("1.data").String() != "opaque-data" {
+		t.Fatalf("second assistant block = %s, want redacted_thinking", content.Get("1").Raw)
+	}
+	if content.Get("2.type").String() != "text" || content.Get("3.type").String() != "tool_use" {
+		t.Fatalf("assistant blocks = %s", content.Raw)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_StreamThinkingSignatureRoundTrip(t *testing.T) {
+	var param any
+	var reasoning, text string
+	var details, toolCalls []string
+	for _, line := range strings.Split(claudeInterleavedThinkingStream, "\n") {
+		for _, chunk := range ConvertClaudeResponseToOpenAI(context.Background(), "claude-sonnet-4-5", nil, nil, []byte(line), &param) {
+			delta := gjson.GetBytes(chunk, "choices.0.delta")
+			reasoning += delta.Get("reasoning_content").String()
+			text += delta.Get("content").String()
+			delta.Get("reasoning_details").ForEach(func(_, detail gjson.Result) bool {
+				details = append(details, detail.Raw)
+				return true
+			})
+			delta.Get("tool_calls").ForEach(func(_, call gjson.Result) bool {
+				toolCalls = append(toolCalls, call.Raw)
+				return true
+			})
+		}
+	}
+	if reasoning != "Need the weather." {
+		t.Fatalf("reasoning_content = %q", reasoning)
+	}
+	if len(details) != 2 {
+		t.Fatalf("reasoning_details = %v, want 2 items", details)
+	}
+	if got := gjson.Get(details[0], "signature").String(); got != "sig-abc" {
+		t.Fatalf("reasoning_details[0].signature = %q", got)
+	}
+
+	assistant := `{"role":"assistant","content":` + strconv.Quote(text) + `,"reasoning_content":` + strconv.Quote(reasoning) +
+		`,"reasoning_details":[` + strings.Join(details, ",") + `],"tool_calls":[` + strings.Join(toolCalls, ",") + `]}`
+	assertClaudeThinkingRoundTrip(t, assistant)
+}
+
+func TestConvertClaudeResponseToOpenAINonStream_ThinkingSignatureRoundTrip(t *testing.T) {
+	out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", nil, nil, []byte(claudeInterleavedThinkingStream), nil)
+	message := gjson.GetBytes(out, "choices.0.message")
+	if got := message.Get("reasoning_details.0.signature").String(); got != "sig-abc" {
+		t.Fatalf("reasoning_details = %s", message.Get("reasoning_details").Raw)
+	}
+	assertClaudeThinkingRoundTrip(t, message.Raw)
+}
+
+func claudeJSONModeTextEvents(stopReason string, deltas ...string) []string {
+	events := []string{
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude-sonnet-4-5","usage":{"input_tokens":3,"output_tokens":1}}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+	}
+	for _, delta := range deltas {
+		event, _ := sjson.Set(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":""}}`, "delta.text", delta)
+		events = append(events, "data: "+event)
+	}
+	events = append(events,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"`+stopReason+`"},"usage":{"output_tokens":5}}`,
+	)
+	return events
+}
+
+func TestConvertClaudeResponseToOpenAINonStream_JSONModeValidatesContent(t *testing.T) {
+	originalRequest := []byte(`{"response_format":{"type":"json_object"}}`)
+	tests := []struct {
+		name       string
+		stopReason string
+		deltas     []string
+		want       string
+		wantFinish string
+	}{
+		{name: "valid json", stopReason: "end_turn", deltas: []string{`{"colors":`, `["red"]}`}, want: `{"colors":["red"]}`, wantFinish: "stop"},
+		{name: "fenced json", stopReason: "end_turn", deltas: []string{"```json\n{\"colors\":", "[\"red\"]}\n```"}, want: `{"colors":["red"]}`, wantFinish: "stop"},
+		{name: "prose", stopReason: "end_turn", deltas: []string{"Sure, here ", "you go."}, want: `{"text":"Sure, here you go."}`, wantFinish: "stop"},
+		{name: "truncated", stopReason: "max_tokens", deltas: []string{`{"colors":["re`}, want: `{"colors":["re`, wantFinish: "length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var raw []byte
+			for _, event := range claudeJSONModeTextEvents(tt.stopReason, tt.deltas...) {
+				raw = append(raw, []byte(event+"\n")...)
+			}
+			out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", originalRequest, nil, raw, nil)
+			if got := gjson.GetBytes(out, "choices.0.message.content").String(); got != tt.want {
+				t.Fatalf("content = %q, want %q. Output: %s", got, tt.want, out)
+			}
+			if got := gjson.GetBytes(out, "choices.0.finish_reason").String(); got != tt.wantFinish {
+				t.Fatalf("finish_reason = %q, want %q", got, tt.wantFinish)
+			}
+		})
+	}
+
+	var raw []byte
+	for _, event := range claudeJSONModeTextEvents("end_turn", "Sure, here you go.") {
+		raw = append(raw, []byte(event+"\n")...)
+	}
+	out := ConvertClaudeResponseToOpenAINonStream(context.Background(), "claude-sonnet-4-5", []byte(`{}`), nil, raw, nil)
+	if got := gjson.GetBytes(out, "choices.0.message.content").String(); got != "Sure, here you go." {
+		t.Fatalf("content without JSON mode = %q, want the prose unchanged", got)
+	}
+}
+
+func TestConvertClaudeResponseToOpenAI_JSONModeStreamKeepsDeltas(t *testing.T) {
+	ctx := context.Background()
+	originalRequest := []byte(`{"stream":true,"response_format":{"type":"json_object"}}`)
+	deltas := []string{`{"colors":`, `["red",`, `"blue"]}`}
+
+	var param any
+	var got []string
+	for _, event := range claudeJSONModeTextEvents("end_turn", deltas...) {
+		for _, chunk := range ConvertClaudeResponseToOpenAI(ctx, "claude-sonnet-4-5", originalRequest, nil, []byte(event), &param) {
+			if content := gjson.GetBytes(chunk, "choices.0.delta.content"); content.Exists() && content.String() != "" {
+				got = append(got, content.String())
+			}
+		}
+	}
+	if strings.Join(got, "|") != strings.Join(deltas, "|") {
+		t.Fatalf("stream deltas = %q, want %q", got, deltas)
+	}
+}
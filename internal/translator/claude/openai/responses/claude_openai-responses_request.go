@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
@@ -105,6 +106,15 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 		}
 	}
 
+	// A reasoning summary asks for readable thinking, which Claude returns when display is
+	// "summarized". store has no Claude equivalent and is dropped with the rest.
+	if summary := strings.TrimSpace(root.Get("reasoning.summary").String()); summary != "" && !strings.EqualFold(summary, "none") {
+		switch gjson.GetBytes(out, "thinking.type").String() {
+		case "enabled", "adaptive":
+			out, _ = sjson.SetBytes(out, "thinking.display", "summarized")
+		}
+	}
+
 	// Helper for generating tool call IDs when missing
 	genToolCallID := func() string {
 		const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -177,11 +187,16 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 
 	// input array processing
 	var pendingReasoningParts [][]byte
-	type pendingToolUseMessage struct {
+	// Consecutive function_call items form one assistant turn of parallel tool_use
+	// blocks, and the function_call_output items that follow form one user turn of
+	// tool_result blocks emitted in the same order as the calls.
+	type pendingToolBlock struct {
 		callID string
 		raw    []byte
 	}
-	var pendingToolUseMessages []pendingToolUseMessage
+	var pendingToolUses []pendingToolBlock
+	var pendingToolResults []pendingToolBlock
+	var lastToolUseOrder []string
 	appendMessage := func(msg []byte) {
 		messageBlocks = append(messageBlocks, msg)
 	}
@@ -195,23 +210,48 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 		pendingReasoningParts = nil
 	}
 	flushPendingToolUses := func() {
-		for _, pending := range pendingToolUseMessages {
-			appendMessage(pending.raw)
+		if len(pendingToolUses) == 0 {
+			return
 		}
-		pendingToolUseMessages = nil
+		parts := make([][]byte, 0, len(pendingToolUses))
+		lastToolUseOrder = lastToolUseOrder[:0]
+		for _, pending := range pendingToolUses {
+			parts = append(parts, pending.raw)
+			if pending.callID != "" {
+				lastToolUseOrder = append(lastToolUseOrder, pending.callID)
+			}
+		}
+		asst := []byte(`{"role":"assistant","content":[]}`)
+		asst, _ = sjson.SetRawBytes(asst, "content", common.JoinRawArray(parts))
+		appendMessage(asst)
+		pendingToolUses = nil
 	}
-	flushPendingToolUseFor := func(callID string) {
-		if len(pendingToolUseMessages) == 0 {
+	flushPendingToolResults := func() {
+		if len(pendingToolResults) == 0 {
 			return
 		}
-		for i, pending := range pendingToolUseMessages {
-			if pending.callID == callID {
-				appendMessage(pending.raw)
-				pendingToolUseMessages = append(pendingToolUseMessages[:i], pendingToolUseMessages[i+1:]...)
-				return
+		callOrder := make(map[string]int, len(lastToolUseOrder))
+		for i, callID := range lastToolUseOrder {
+			if _, exists := callOrder[callID]; !exists {
+				callOrder[callID] = i
+			}
+		}
+		sort.SliceStable(pendingToolResults, func(i, j int) bool {
+			oi, okI := callOrder[pendingToolResults[i].callID]
+			oj, okJ := callOrder[pendingToolResults[j].callID]
+			if !okI || !okJ {
+				return okI && !okJ
 			}
+			return oi < oj
+		})
+		parts := make([][]byte, 0, len(pendingToolResults))
+		for _, pending := range pendingToolResults {
+			parts = append(parts, pending.raw)
 		}
-		flushPendingToolUses()
+		usr := []byte(`{"role":"user","content":[]}`)
+		usr, _ = sjson.SetRawBytes(usr, "content", common.JoinRawArray(parts))
+		appendMessage(usr)
+		pendingToolResults = nil
 	}
 
 	if input := root.Get("input"); input.Exists() && input.IsArray() {
@@ -329,6 +369,7 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 				}
 
 				hasReasoningParts := false
+				flushPendingToolResults()
 				if role != "assistant" {
 					flushPendingToolUses()
 				}
@@ -394,33 +435,29 @@ func ConvertOpenAIResponsesRequestToClaude(modelName string, inputRawJSON []byte
 					}
 				}
 
-				asstParts := append(pendingReasoningParts, toolUse)
-				asst := []byte(`{"role":"assistant","content":[]}`)
-				asst, _ = sjson.SetRawBytes(asst, "content", common.JoinRawArray(asstParts))
+				flushPendingToolResults()
+				for _, reasoningPart := range pendingReasoningParts {
+					pendingToolUses = append(pendingToolUses, pendingToolBlock{raw: reasoningPart})
+				}
 				pendingReasoningParts = nil
-				pendingToolUseMessages = append(pendingToolUseMessages, pendingToolUseMessage{
-					callID: callID,
-					raw:    asst,
-				})
+				pendingToolUses = append(pendingToolUses, pendingToolBlock{callID: callID, raw: toolUse})
 
 			case "function_call_output":
 				flushPendingReasoning()
 				// Map to user tool_result
 				callID := item.Get("call_id").String()
 				callID = util.SanitizeClaudeToolID(callID)
-				flushPendingToolUseFor(callID)
+				flushPendingToolUses()
 				output := item.Get("output")
 				toolResult := []byte(`{"type":"tool_result","tool_use_id":"","content":""}`)
 				toolResult, _ = sjson.SetBytes(toolResult, "tool_use_id", callID)
 				toolResult = applyResponsesToolResultContent(toolResult, output)
-
-				usr := []byte(`{"role":"user","content":[]}`)
-				usr, _ = sjson.SetRawBytes(usr, "content", common.JoinRawArray([][]byte{toolResult}))
-				appendMessage(usr)
+				pendingToolResults = append(pendingToolResults, pendingToolBlock{callID: callID, raw: toolResult})
 			}
 			return true
 		})
 	}
+	flushPendingToolResults()
 	flushPendingReasoning()
 	flushPendingToolUses()
 	out = common.SetRawArrayItems(out, "messages", messageBlocks)
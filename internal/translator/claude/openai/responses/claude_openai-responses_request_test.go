@@ -2,6 +2,7 @@ package responses
 
 import (
 	"encoding/base64"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -250,6 +251,56 @@ func TestConvertOpenAIResponsesRequestToClaude_KeepsToolUseAdjacentToToolResult(
 	}
 }
 
+func TestConvertOpenAIResponsesRequestToClaude_GroupsParallelToolCallsInOrder(t *testing.T) {
+	raw := []byte(`{
+		"model":"claude-test",
+		"input":[
+			{"type":"message","role":"user","content":[{"type":"input_text","text":"read both files"}]},
+			{"type":"function_call","call_id":"call_a","name":"Read","arguments":"{\"path\":\"a.txt\"}"},
+			{"type":"function_call","call_id":"call_b","name":"Read","arguments":"{\"path\":\"b.txt\"}"},
+			{"type":"function_call_output","call_id":"call_b","output":"B"},
+			{"type":"function_call_output","call_id":"call_a","output":"A"},
+			{"type":"message","role":"assistant","content":[{"type":"output_text","text":"done"}]}
+		]
+	}`)
+
+	out := ConvertOpenAIResponsesRequestToClaude("claude-test", raw, false)
+	root := gjson.ParseBytes(out)
+
+	if got := root.Get("messages.#").Int(); got != 4 {
+		t.Fatalf("messages = %d, want 4. Output: %s", got, string(out))
+	}
+	toolUses := root.Get("messages.1")
+	if got := toolUses.Get("role").String(); got != "assistant" {
+		t.Fatalf("tool_use message role = %q, want assistant. Output: %s", got, string(out))
+	}
+	if got := toolUses.Get("content.#").Int(); got != 2 {
+		t.Fatalf("tool_use blocks = %d, want 2. Output: %s", got, string(out))
+	}
+	toolResults := root.Get("messages.2")
+	if got := toolResults.Get("role").String(); got != "user" {
+		t.Fatalf("tool_result message role = %q, want user. Output: %s", got, string(out))
+	}
+	if got := toolResults.Get("content.#").Int(); got != 2 {
+		t.Fatalf("tool_result blocks = %d, want 2. Output: %s", got, string(out))
+	}
+	for i, want := range []struct{ id, result string }{{"call_a", "A"}, {"call_b", "B"}} {
+		if got := toolUses.Get(fmt.Sprintf("content.%d.id", i)).String(); got != want.id {
+			t.Fatalf("tool_use %d id = %q, want %q. Output: %s", i, got, want.id, string(out))
+		}
+		result := toolResults.Get(fmt.Sprintf("content.%d", i))
+		if got := result.Get("tool_use_id").String(); got != want.id {
+			t.Fatalf("tool_result %d tool_use_id = %q, want %q. Output: %s", i, got, want.id, string(out))
+		}
+		if got := result.Get("content").String(); got != want.result {
+			t.Fatalf("tool_result %d content = %q, want %q. Output: %s", i, got, want.result, string(out))
+		}
+	}
+	if got := root.Get("messages.3.content").String(); got != "done" {
+		t.Fatalf("final assistant content = %q, want done. Output: %s", got, string(out))
+	}
+}
+
 func TestConvertOpenAIResponsesRequestToClaude_DropsApplyPatchCustomTool(t *testing.T) {
 	raw := []byte(`{
 		"model":"claude-test",
@@ -351,3 +402,23 @@ func TestConvertOpenAIResponsesRequestToClaude_PreservesContentPartCacheControl(
 		t.Fatalf("content.1 should not have cache_control. Output: %s", result)
 	}
 }
+
+func TestConvertOpenAIResponsesRequestToClaude_ReasoningSummaryShowsThinking(t *testing.T) {
+	inputJSON := `{"model":"gpt-5.4","store":false,"reasoning":{"effort":"high","summary":"detailed"},"input":"hello"}`
+	result := ConvertOpenAIResponsesRequestToClaude("claude-sonnet-4-5", []byte(inputJSON), false)
+
+	if got := gjson.GetBytes(result, "thinking.type").String(); got != "enabled" && got != "adaptive" {
+		t.Fatalf("thinking.type = %q, want thinking enabled. Result: %s", got, result)
+	}
+	if got := gjson.GetBytes(result, "thinking.display").String(); got != "summarized" {
+		t.Fatalf("thinking.display = %q, want summarized. Result: %s", got, result)
+	}
+	if gjson.GetBytes(result, "store").Exists() {
+		t.Fatalf("store was forwarded to Claude: %s", result)
+	}
+
+	withoutThinking := ConvertOpenAIResponsesRequestToClaude("claude-sonnet-4-5", []byte(`{"model":"gpt-5.4","reasoning":{"summary":"detailed"},"input":"hello"}`), false)
+	if gjson.GetBytes(withoutThinking, "thinking").Exists() {
+		t.Fatalf("summary without effort enabled thinking: %s", withoutThinking)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -678,6 +679,7 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 		reasoningSig    string
 		annotations     []any
 		usageTokens     claudeResponsesUsageTokens
+		stopReason      string
 	)
 
 	// Per-index tool call aggregation
@@ -772,6 +774,9 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 
 		case "message_delta":
 			usageTokens.Merge(root.Get("usage"))
+			if sr := root.Get("delta.stop_reason"); sr.Exists() && sr.String() != "" {
+				stopReason = sr.String()
+			}
 		}
 	}
 
@@ -920,5 +925,7 @@ func ConvertClaudeResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 		}
 	}
 
+	out = translatorcommon.SetResponsesIncomplete(out, util.FinishReasonClaude, stopReason)
+
 	return out
 }
@@ -483,18 +483,12 @@ func mapCodexStopReasonToClaude(stopReason string, hasToolCall bool) string {
 	}
 
 	switch stopReason {
-	case "", "stop", "completed":
-		return "end_turn"
-	case "max_tokens", "max_output_tokens":
-		return "max_tokens"
-	case "tool_use", "tool_calls", "function_call":
+	case "stop", "tool_use", "tool_calls", "function_call":
 		return "end_turn"
 	case "end_turn", "stop_sequence", "pause_turn", "refusal", "model_context_window_exceeded":
 		return stopReason
-	case "content_filter":
-		return "refusal"
 	default:
-		return "end_turn"
+		return util.MapFinishReason(util.FinishReasonCodex, util.FinishReasonClaude, stopReason)
 	}
 }
 
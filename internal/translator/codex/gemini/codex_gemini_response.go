@@ -12,6 +12,7 @@ import (
 	"time"
 
 	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -422,14 +423,11 @@ func setGeminiFunctionCallID(functionCall []byte, item gjson.Result) []byte {
 }
 
 func codexGeminiIncompleteFinishReason(reason string) string {
-	switch reason {
-	case "max_tokens", "max_output_tokens":
-		return "MAX_TOKENS"
-	case "content_filter":
-		return "SAFETY"
-	default:
+	if reason == "" {
+		// The response is incomplete without saying why.
 		return "OTHER"
 	}
+	return util.MapFinishReason(util.FinishReasonCodex, util.FinishReasonGemini, reason)
 }
 
 func GeminiTokenCount(ctx context.Context, count int64) []byte {
@@ -64,7 +64,12 @@ func ConvertOpenAIRequestToCodex(modelName string, inputRawJSON []byte, stream b
 		out, _ = sjson.SetBytes(out, "reasoning.effort", "medium")
 	}
 	out, _ = sjson.SetBytes(out, "parallel_tool_calls", true)
-	out, _ = sjson.SetBytes(out, "reasoning.summary", "auto")
+	// Chat clients that send a Responses-style reasoning object choose the summary detail.
+	if v := gjson.GetBytes(rawJSON, "reasoning.summary"); v.Type == gjson.String && strings.TrimSpace(v.String()) != "" {
+		out, _ = sjson.SetBytes(out, "reasoning.summary", strings.TrimSpace(v.String()))
+	} else {
+		out, _ = sjson.SetBytes(out, "reasoning.summary", "auto")
+	}
 	out, _ = sjson.SetBytes(out, "include", []string{"reasoning.encrypted_content"})
 
 	// Model
@@ -1109,3 +1109,18 @@ func TestToolsDefinitionTranslated(t *testing.T) {
 		t.Errorf("tool 'search' not found in output tools: %s", gjson.Get(result, "tools").Raw)
 	}
 }
+
+func TestReasoningSummaryFromClient(t *testing.T) {
+	withSummary := ConvertOpenAIRequestToCodex("gpt-5.5", []byte(`{"model":"gpt-5.5","store":false,"reasoning":{"summary":"detailed"},"messages":[{"role":"user","content":"hi"}]}`), true)
+	if got := gjson.GetBytes(withSummary, "reasoning.summary").String(); got != "detailed" {
+		t.Fatalf("reasoning.summary = %q, want detailed. Output: %s", got, withSummary)
+	}
+	if store := gjson.GetBytes(withSummary, "store"); store.Type != gjson.False {
+		t.Fatalf("store = %s, want false", store.Raw)
+	}
+
+	defaulted := ConvertOpenAIRequestToCodex("gpt-5.5", []byte(`{"model":"gpt-5.5","messages":[{"role":"user","content":"hi"}]}`), true)
+	if got := gjson.GetBytes(defaulted, "reasoning.summary").String(); got != "auto" {
+		t.Fatalf("default reasoning.summary = %q, want auto", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -168,12 +169,7 @@ func ConvertCodexResponseToOpenAI(_ context.Context, modelName string, originalR
 		nativeFinishReason := finishReason
 		if dataType == "response.incomplete" {
 			nativeFinishReason = rootResult.Get("response.incomplete_details.reason").String()
-			switch nativeFinishReason {
-			case "max_tokens", "max_output_tokens":
-				finishReason = "length"
-			case "content_filter":
-				finishReason = "content_filter"
-			}
+			finishReason = util.MapFinishReason(util.FinishReasonCodex, util.FinishReasonOpenAI, nativeFinishReason)
 		} else if (*param).(*ConvertCliToOpenAIParams).FunctionCallIndex != -1 {
 			finishReason = "tool_calls"
 			nativeFinishReason = finishReason
@@ -497,14 +493,7 @@ func ConvertCodexResponseToOpenAINonStream(_ context.Context, _ string, original
 			}
 		case "incomplete":
 			nativeFinishReason = responseResult.Get("incomplete_details.reason").String()
-			switch nativeFinishReason {
-			case "max_tokens", "max_output_tokens":
-				finishReason = "length"
-			case "content_filter":
-				finishReason = "content_filter"
-			default:
-				finishReason = "stop"
-			}
+			finishReason = util.MapFinishReason(util.FinishReasonCodex, util.FinishReasonOpenAI, nativeFinishReason)
 		}
 		if finishReason != "" {
 			template, _ = sjson.SetBytes(template, "choices.0.finish_reason", finishReason)
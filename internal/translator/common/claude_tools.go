@@ -0,0 +1,35 @@
+package common
+
+import "github.com/tidwall/gjson"
+
+// ClaudeToolNamesByID maps each tool_use id in the assistant messages of a Claude
+// conversation to the tool name it called. Claude tool_result blocks only carry the id,
+// while Gemini function responses must name the function they answer.
+func ClaudeToolNamesByID(messages gjson.Result) map[string]string {
+	names := make(map[string]string)
+	if !messages.IsArray() {
+		return names
+	}
+	messages.ForEach(func(_, message gjson.Result) bool {
+		if message.Get("role").String() != "assistant" {
+			return true
+		}
+		content := message.Get("content")
+		if !content.IsArray() {
+			return true
+		}
+		content.ForEach(func(_, block gjson.Result) bool {
+			if block.Get("type").String() != "tool_use" {
+				return true
+			}
+			id := block.Get("id").String()
+			name := block.Get("name").String()
+			if id != "" && name != "" {
+				names[id] = name
+			}
+			return true
+		})
+		return true
+	})
+	return names
+}
@@ -0,0 +1,131 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// IsClaudeWebSearchToolType reports whether toolType names a version of Claude's
+// built-in web_search server tool.
+func IsClaudeWebSearchToolType(toolType string) bool {
+	return toolType == "web_search_20250305" || toolType == "web_search_20260209"
+}
+
+// HasClaudeWebSearchTool reports whether a Claude request declares the built-in
+// web_search tool.
+func HasClaudeWebSearchTool(payload []byte) bool {
+	tools := gjson.GetBytes(payload, "tools")
+	if !tools.IsArray() {
+		return false
+	}
+	for _, tool := range tools.Array() {
+		if IsClaudeWebSearchToolType(tool.Get("type").String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClaudeServerTool reports whether tool is one of Claude's built-in tools, which are
+// declared by a versioned type instead of an input schema.
+func IsClaudeServerTool(tool gjson.Result) bool {
+	toolType := tool.Get("type").String()
+	return toolType != "" && toolType != "custom" && !tool.Get("input_schema").Exists()
+}
+
+// ClaudeUnavailableToolsNote returns the system note telling the model that the named
+// built-in tools were dropped because the upstream cannot provide them.
+func ClaudeUnavailableToolsNote(names []string) string {
+	return "The following tools are not available in this environment and cannot be used: " +
+		strings.Join(names, ", ") + ". Do not try to call them; answer with the remaining tools or your own knowledge."
+}
+
+// NewClaudeWebSearchToolUseID returns an id for a synthetic server_tool_use block.
+func NewClaudeWebSearchToolUseID() string {
+	return fmt.Sprintf("srvtoolu_%d", time.Now().UnixNano())
+}
+
+// ClaudeWebSearchQuery returns the first search query recorded in Gemini grounding
+// metadata, or "" when there is none.
+func ClaudeWebSearchQuery(groundingMetadata gjson.Result) string {
+	if queries := groundingMetadata.Get("webSearchQueries"); queries.IsArray() && len(queries.Array()) > 0 {
+		return queries.Array()[0].String()
+	}
+	return ""
+}
+
+// ClaudeWebSearchResults converts the web grounding chunks of Gemini grounding metadata
+// into the web_search_result items of a Claude web_search_tool_result block, one per URL.
+func ClaudeWebSearchResults(groundingMetadata gjson.Result) []byte {
+	results := []byte(`[]`)
+	groundingChunks := groundingMetadata.Get("groundingChunks")
+	if !groundingChunks.IsArray() {
+		return results
+	}
+	seenURLs := make(map[string]struct{})
+	for _, chunk := range groundingChunks.Array() {
+		web := chunk.Get("web")
+		if !web.Exists() {
+			continue
+		}
+		uri := strings.TrimSpace(web.Get("uri").String())
+		if uri == "" {
+			continue
+		}
+		if _, ok := seenURLs[uri]; ok {
+			continue
+		}
+		seenURLs[uri] = struct{}{}
+
+		result := []byte(`{"type":"web_search_result","page_age":null}`)
+		if title := web.Get("title"); title.Exists() {
+			result, _ = sjson.SetBytes(result, "title", title.String())
+		}
+		result, _ = sjson.SetBytes(result, "url", uri)
+		results, _ = sjson.SetRawBytes(results, "-1", result)
+	}
+	return results
+}
+
+// ClaudeWebSearchBlocks returns the server_tool_use and web_search_tool_result content
+// blocks describing the Google Search behind groundingMetadata.
+func ClaudeWebSearchBlocks(toolUseID string, groundingMetadata gjson.Result) [][]byte {
+	serverToolUse := []byte(`{"type":"server_tool_use","id":"","name":"web_search","input":{}}`)
+	serverToolUse, _ = sjson.SetBytes(serverToolUse, "id", toolUseID)
+	if query := ClaudeWebSearchQuery(groundingMetadata); query != "" {
+		serverToolUse, _ = sjson.SetBytes(serverToolUse, "input.query", query)
+	}
+
+	toolResult := []byte(`{"type":"web_search_tool_result","tool_use_id":"","content":[]}`)
+	toolResult, _ = sjson.SetBytes(toolResult, "tool_use_id", toolUseID)
+	toolResult, _ = sjson.SetRawBytes(toolResult, "content", ClaudeWebSearchResults(groundingMetadata))
+	return [][]byte{serverToolUse, toolResult}
+}
+
+// AppendClaudeWebSearchStreamBlocks emits the server_tool_use and web_search_tool_result
+// blocks of ClaudeWebSearchBlocks as Claude SSE events starting at index, and returns
+// the index of the next content block.
+func AppendClaudeWebSearchStreamBlocks(appendEvent func(event, payload string), index int, toolUseID string, groundingMetadata gjson.Result) int {
+	serverToolUseStart := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"server_tool_use","id":"","name":"web_search","input":{}}}`, index)
+	serverToolUseStart, _ = sjson.Set(serverToolUseStart, "content_block.id", toolUseID)
+	appendEvent("content_block_start", serverToolUseStart)
+	if query := ClaudeWebSearchQuery(groundingMetadata); query != "" {
+		queryJSON, _ := sjson.Set(`{}`, "query", query)
+		inputDelta := fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"input_json_delta","partial_json":""}}`, index)
+		inputDelta, _ = sjson.Set(inputDelta, "delta.partial_json", queryJSON)
+		appendEvent("content_block_delta", inputDelta)
+	}
+	appendEvent("content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, index))
+	index++
+
+	toolResultStart := fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"web_search_tool_result","tool_use_id":"","content":[]}}`, index)
+	toolResultStart, _ = sjson.Set(toolResultStart, "content_block.tool_use_id", toolUseID)
+	toolResultStart, _ = sjson.SetRaw(toolResultStart, "content_block.content", string(ClaudeWebSearchResults(groundingMetadata)))
+	appendEvent("content_block_start", toolResultStart)
+	appendEvent("content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, index))
+	return index + 1
+}
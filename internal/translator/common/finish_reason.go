@@ -0,0 +1,21 @@
+package common
+
+import (
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	"github.com/tidwall/sjson"
+)
+
+// SetResponsesIncomplete marks an OpenAI Responses object as incomplete when
+// the upstream finish reason, expressed in from's vocabulary, means the output
+// was cut short by the token limit or a content filter. Other reasons leave
+// the response untouched.
+func SetResponsesIncomplete(response []byte, from util.FinishReasonFormat, reason string) []byte {
+	incompleteReason := util.MapFinishReason(from, util.FinishReasonCodex, reason)
+	if incompleteReason == "" {
+		return response
+	}
+	response, _ = sjson.SetBytes(response, "status", "incomplete")
+	response, _ = sjson.SetRawBytes(response, "incomplete_details", []byte(`{"reason":""}`))
+	response, _ = sjson.SetBytes(response, "incomplete_details.reason", incompleteReason)
+	return response
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+func TestSetResponsesIncomplete(t *testing.T) {
+	base := []byte(`{"status":"completed","incomplete_details":null}`)
+	tests := []struct {
+		name       string
+		from       util.FinishReasonFormat
+		reason     string
+		wantStatus string
+		wantReason string
+	}{
+		{"gemini max tokens", util.FinishReasonGemini, "MAX_TOKENS", "incomplete", "max_output_tokens"},
+		{"gemini safety", util.FinishReasonGemini, "SAFETY", "incomplete", "content_filter"},
+		{"claude refusal", util.FinishReasonClaude, "refusal", "incomplete", "content_filter"},
+		{"openai length", util.FinishReasonOpenAI, "length", "incomplete", "max_output_tokens"},
+		{"openai stop", util.FinishReasonOpenAI, "stop", "completed", ""},
+		{"claude tool use", util.FinishReasonClaude, "tool_use", "completed", ""},
+		{"missing reason", util.FinishReasonGemini, "", "completed", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := SetResponsesIncomplete(base, tt.from, tt.reason)
+			if got := gjson.GetBytes(out, "status").String(); got != tt.wantStatus {
+				t.Fatalf("status = %q, want %q; out=%s", got, tt.wantStatus, out)
+			}
+			if got := gjson.GetBytes(out, "incomplete_details.reason").String(); got != tt.wantReason {
+				t.Fatalf("incomplete_details.reason = %q, want %q; out=%s", got, tt.wantReason, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// GeminiThoughtsSuppressed reports whether a Gemini-family upstream request, after the
+// thinking config was applied, asked for no thoughts with includeThoughts=false. The
+// config is read at the top level (Gemini) or under "request" (Antigravity).
+func GeminiThoughtsSuppressed(requestRawJSON []byte) bool {
+	for _, path := range []string{
+		"generationConfig.thinkingConfig.includeThoughts",
+		"generationConfig.thinkingConfig.include_thoughts",
+		"request.generationConfig.thinkingConfig.includeThoughts",
+		"request.generationConfig.thinkingConfig.include_thoughts",
+	} {
+		if value := gjson.GetBytes(requestRawJSON, path); value.Exists() {
+			return value.Type == gjson.False
+		}
+	}
+	return false
+}
+
+// StripGeminiThoughtParts removes the thought parts from the candidates of a Gemini-family
+// response, which may be an SSE data line, an Antigravity envelope or a JSON array of
+// either. usageMetadata is kept, so thoughtsTokenCount is still reported. empty is true when
+// only thought parts were removed from a chunk without a finish reason, i.e. nothing is
+// left for the client.
+func StripGeminiThoughtParts(rawJSON []byte) (out []byte, empty bool) {
+	if !bytes.Contains(rawJSON, []byte(`"thought"`)) {
+		return rawJSON, false
+	}
+	body := rawJSON
+	if bytes.HasPrefix(body, []byte("data:")) {
+		body = bytes.TrimSpace(body[5:])
+	}
+	if !gjson.ValidBytes(body) {
+		return rawJSON, false
+	}
+	root := gjson.ParseBytes(body)
+	if !root.IsArray() {
+		return stripGeminiThoughtPartsFromObject(body, root)
+	}
+	out, empty = []byte("[]"), true
+	for _, item := range root.Array() {
+		stripped, itemEmpty := stripGeminiThoughtPartsFromObject([]byte(item.Raw), item)
+		out, _ = sjson.SetRawBytes(out, "-1", stripped)
+		empty = empty && itemEmpty
+	}
+	return out, empty
+}
+
+func stripGeminiThoughtPartsFromObject(body []byte, root gjson.Result) ([]byte, bool) {
+	prefix := ""
+	candidates := root.Get("candidates")
+	if !candidates.Exists() {
+		prefix = "response."
+		candidates = root.Get("response.candidates")
+	}
+	removed, left := 0, 0
+	for i, candidate := range candidates.Array() {
+		if candidate.Get("finishReason").Exists() {
+			left++
+		}
+		parts := candidate.Get("content.parts").Array()
+		kept := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if !part.Get("thought").Bool() {
+				kept = append(kept, part.Raw)
+			}
+		}
+		left += len(kept)
+		if len(kept) == len(parts) {
+			continue
+		}
+		removed += len(parts) - len(kept)
+		path := prefix + "candidates." + strconv.Itoa(i) + ".content.parts"
+		body, _ = sjson.SetRawBytes(body, path, []byte("["+strings.Join(kept, ",")+"]"))
+	}
+	return body, removed > 0 && left == 0
+}
+
+// SuppressGeminiThoughts wraps the response translators of a Gemini-family upstream so
+// thought parts never reach clients whose request resolved to includeThoughts=false, even
+// when the upstream streams them anyway. Whether thoughts are suppressed is decided once
+// per stream and kept in the translation state next to the wrapped translator's own.
+func SuppressGeminiThoughts(response sdktranslator.ResponseTransform) sdktranslator.ResponseTransform {
+	stream, nonStream := response.Stream, response.NonStream
+	if stream != nil {
+		response.Stream = func(ctx context.Context, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) [][]byte {
+			inner := param
+			var suppress bool
+			if param != nil && *param == nil {
+				*param = &thoughtSuppressionState{suppress: GeminiThoughtsSuppressed(requestRawJSON)}
+			}
+			if state, ok := streamThoughtSuppressionState(param); ok {
+				inner, suppress = &state.inner, state.suppress
+			} else {
+				suppress = GeminiThoughtsSuppressed(requestRawJSON)
+			}
+			if suppress {
+				stripped, empty := StripGeminiThoughtParts(rawJSON)
+				if empty {
+					return [][]byte{}
+				}
+				rawJSON = stripped
+			}
+			return stream(ctx, model, originalRequestRawJSON, requestRawJSON, rawJSON, inner)
+		}
+	}
+	if nonStream != nil {
+		response.NonStream = func(ctx context.Context, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []byte {
+			if GeminiThoughtsSuppressed(requestRawJSON) {
+				rawJSON, _ = StripGeminiThoughtParts(rawJSON)
+			}
+			return nonStream(ctx, model, originalRequestRawJSON, requestRawJSON, rawJSON, param)
+		}
+	}
+	return response
+}
+
+// thoughtSuppressionState is the stream state of SuppressGeminiThoughts; inner holds the
+// state of the wrapped translator.
+type thoughtSuppressionState struct {
+	suppress bool
+	inner    any
+}
+
+func streamThoughtSuppressionState(param *any) (*thoughtSuppressionState, bool) {
+	if param == nil {
+		return nil, false
+	}
+	state, ok := (*param).(*thoughtSuppressionState)
+	return state, ok
+}
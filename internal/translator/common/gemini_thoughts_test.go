@@ -0,0 +1,78 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+func TestGeminiThoughtsSuppressed(t *testing.T) {
+	tests := []struct {
+		request string
+		want    bool
+	}{
+		{`{"generationConfig":{"thinkingConfig":{"includeThoughts":false}}}`, true},
+		{`{"generationConfig":{"thinkingConfig":{"include_thoughts":false}}}`, true},
+		{`{"request":{"generationConfig":{"thinkingConfig":{"includeThoughts":false,"thinkingBudget":128}}}}`, true},
+		{`{"generationConfig":{"thinkingConfig":{"includeThoughts":true}}}`, false},
+		{`{"generationConfig":{"thinkingConfig":{"thinkingBudget":0}}}`, false},
+		{`{"contents":[]}`, false},
+	}
+	for _, tt := range tests {
+		if got := GeminiThoughtsSuppressed([]byte(tt.request)); got != tt.want {
+			t.Errorf("GeminiThoughtsSuppressed(%s) = %t, want %t", tt.request, got, tt.want)
+		}
+	}
+}
+
+func TestStripGeminiThoughtParts(t *testing.T) {
+	chunk := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"plan","thought":true},{"text":"answer"}]}}],"usageMetadata":{"thoughtsTokenCount":9}}`
+	out, empty := StripGeminiThoughtParts([]byte(chunk))
+	if empty {
+		t.Fatal("chunk with an answer part reported as empty")
+	}
+	if parts := gjson.GetBytes(out, "candidates.0.content.parts"); len(parts.Array()) != 1 || parts.Get("0.text").String() != "answer" {
+		t.Fatalf("parts = %s, want only the answer", parts.Raw)
+	}
+	if got := gjson.GetBytes(out, "usageMetadata.thoughtsTokenCount").Int(); got != 9 {
+		t.Fatalf("thoughtsTokenCount = %d, want 9", got)
+	}
+
+	envelopes := `[{"response":{"candidates":[{"content":{"parts":[{"text":"plan","thought":true}]}}]}},{"response":{"candidates":[{"content":{"parts":[{"text":"more","thought":true}]}}]}}]`
+	out, empty = StripGeminiThoughtParts([]byte(envelopes))
+	if !empty {
+		t.Fatalf("thought-only envelopes not reported as empty: %s", out)
+	}
+
+	final := `{"candidates":[{"content":{"parts":[{"text":"plan","thought":true}]},"finishReason":"STOP"}]}`
+	if _, empty = StripGeminiThoughtParts([]byte(final)); empty {
+		t.Fatal("chunk with a finish reason reported as empty")
+	}
+}
+
+func TestSuppressGeminiThoughtsKeepsInnerState(t *testing.T) {
+	calls := 0
+	response := SuppressGeminiThoughts(sdktranslator.ResponseTransform{
+		Stream: func(_ context.Context, _ string, _, _, rawJSON []byte, param *any) [][]byte {
+			if *param == nil {
+				*param = &calls
+			}
+			*(*param).(*int)++
+			return [][]byte{rawJSON}
+		},
+	})
+	request := []byte(`{"generationConfig":{"thinkingConfig":{"includeThoughts":false}}}`)
+	var param any
+	thought := []byte(`{"candidates":[{"content":{"parts":[{"text":"plan","thought":true}]}}]}`)
+	answer := []byte(`{"candidates":[{"content":{"parts":[{"text":"answer"}]}}]}`)
+	if out := response.Stream(context.Background(), "m", nil, request, thought, &param); len(out) != 0 {
+		t.Fatalf("thought-only chunk produced %q", out)
+	}
+	response.Stream(context.Background(), "m", nil, request, answer, &param)
+	response.Stream(context.Background(), "m", nil, request, answer, &param)
+	if calls != 2 {
+		t.Fatalf("inner translator calls = %d, want 2 sharing one state", calls)
+	}
+}
@@ -0,0 +1,17 @@
+package common
+
+import "github.com/tidwall/gjson"
+
+// GeminiCachedTokenCount returns the cached prompt tokens of a Gemini usageMetadata
+// object: cachedContentTokenCount, or the sum of cacheTokensDetails when only the
+// per-modality breakdown is reported.
+func GeminiCachedTokenCount(usageMetadata gjson.Result) int64 {
+	if cached := usageMetadata.Get("cachedContentTokenCount"); cached.Exists() {
+		return cached.Int()
+	}
+	var cached int64
+	for _, detail := range usageMetadata.Get("cacheTokensDetails").Array() {
+		cached += detail.Get("tokenCount").Int()
+	}
+	return cached
+}
@@ -12,6 +12,7 @@ import (
 	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/gemini/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -64,6 +65,7 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 	// contents
 	if messagesResult := gjson.GetBytes(rawJSON, "messages"); messagesResult.IsArray() {
 		contentItems := translatorcommon.NewRawArrayItems(messagesResult.Get("#").Int())
+		toolNameByID := translatorcommon.ClaudeToolNamesByID(messagesResult)
 		messagesResult.ForEach(func(_, messageResult gjson.Result) bool {
 			roleResult := messageResult.Get("role")
 			if roleResult.Type != gjson.String {
@@ -100,13 +102,7 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 						partItems = append(partItems, part)
 
 					case "tool_use":
-						functionName := contentResult.Get("name").String()
-						if toolUseID := contentResult.Get("id").String(); toolUseID != "" {
-							if derived := toolNameFromClaudeToolUseID(toolUseID); derived != "" {
-								functionName = derived
-							}
-						}
-						functionName = util.SanitizeFunctionName(functionName)
+						functionName := util.SanitizeFunctionName(contentResult.Get("name").String())
 						functionArgs := contentResult.Get("input").String()
 						argsResult := gjson.Parse(functionArgs)
 						if argsResult.IsObject() && gjson.Valid(functionArgs) {
@@ -122,8 +118,9 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 						if toolCallID == "" {
 							return true
 						}
-						funcName := toolNameFromClaudeToolUseID(toolCallID)
-						if funcName == "" {
+						// Ids are opaque: only fall back to the id itself when the call is not in history.
+						funcName, ok := toolNameByID[toolCallID]
+						if !ok {
 							funcName = toolCallID
 						}
 						funcName = util.SanitizeFunctionName(funcName)
@@ -137,6 +134,13 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 						}
 						partItems = append(partItems, part)
 						for _, img := range toolResult.Images {
+							if img.URL != "" {
+								imagePart := []byte(`{"file_data":{"mime_type":"","file_uri":""}}`)
+								imagePart, _ = sjson.SetBytes(imagePart, "file_data.mime_type", img.MimeType)
+								imagePart, _ = sjson.SetBytes(imagePart, "file_data.file_uri", img.URL)
+								partItems = append(partItems, imagePart)
+								continue
+							}
 							imagePart := []byte(`{"inline_data":{"mime_type":"","data":""}}`)
 							imagePart, _ = sjson.SetBytes(imagePart, "inline_data.mime_type", img.MimeType)
 							imagePart, _ = sjson.SetBytes(imagePart, "inline_data.data", img.Data)
@@ -191,9 +195,29 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 	}
 
 	// tools
+	// Claude built-in tools have no function declaration: web_search maps to Gemini's
+	// googleSearch tool and the others are dropped, with a system note so the model
+	// does not expect them.
+	serverToolNames := make(map[string]struct{})
 	if toolsResult := gjson.GetBytes(rawJSON, "tools"); toolsResult.IsArray() {
 		var toolItems [][]byte
+		var unavailableTools []string
+		googleSearch := false
 		toolsResult.ForEach(func(_, toolResult gjson.Result) bool {
+			if translatorcommon.IsClaudeServerTool(toolResult) {
+				toolType := toolResult.Get("type").String()
+				name := toolResult.Get("name").String()
+				if name == "" {
+					name = toolType
+				}
+				serverToolNames[name] = struct{}{}
+				if translatorcommon.IsClaudeWebSearchToolType(toolType) {
+					googleSearch = true
+				} else {
+					unavailableTools = append(unavailableTools, name)
+				}
+				return true
+			}
 			inputSchemaResult := toolResult.Get("input_schema")
 			if inputSchemaResult.Exists() && inputSchemaResult.IsObject() {
 				inputSchema := util.CleanJSONSchemaForGemini(inputSchemaResult.Raw)
@@ -229,6 +253,13 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 			tools, _ = sjson.SetRawBytes(tools, "0.functionDeclarations", translatorcommon.JoinRawArray(toolItems))
 			out, _ = sjson.SetRawBytes(out, "tools", tools)
 		}
+		if googleSearch {
+			out, _ = sjson.SetRawBytes(out, "tools.-1", []byte(`{"googleSearch":{}}`))
+		}
+		if len(unavailableTools) > 0 {
+			log.Warnf("gemini: dropping Claude built-in tools without a Gemini equivalent: %s", strings.Join(unavailableTools, ", "))
+			out, _ = sjson.SetBytes(out, "system_instruction.parts.-1.text", translatorcommon.ClaudeUnavailableToolsNote(unavailableTools))
+		}
 	}
 
 	// tool_choice
@@ -251,6 +282,10 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 		case "any":
 			out, _ = sjson.SetBytes(out, "toolConfig.functionCallingConfig.mode", "ANY")
 		case "tool":
+			if _, serverTool := serverToolNames[toolChoiceName]; serverTool {
+				// Built-in tools are not functions Gemini could be forced to call.
+				break
+			}
 			out, _ = sjson.SetBytes(out, "toolConfig.functionCallingConfig.mode", "ANY")
 			if toolChoiceName != "" {
 				out, _ = sjson.SetBytes(out, "toolConfig.functionCallingConfig.allowedFunctionNames", []string{util.SanitizeFunctionName(toolChoiceName)})
@@ -293,15 +328,7 @@ func ConvertClaudeRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.includeThoughts", true)
 		}
 	}
-	if v := gjson.GetBytes(rawJSON, "temperature"); v.Exists() && v.Type == gjson.Number {
-		out, _ = sjson.SetBytes(out, "generationConfig.temperature", v.Num)
-	}
-	if v := gjson.GetBytes(rawJSON, "top_p"); v.Exists() && v.Type == gjson.Number {
-		out, _ = sjson.SetBytes(out, "generationConfig.topP", v.Num)
-	}
-	if v := gjson.GetBytes(rawJSON, "top_k"); v.Exists() && v.Type == gjson.Number {
-		out, _ = sjson.SetBytes(out, "generationConfig.topK", v.Num)
-	}
+	out = common.ApplyClaudeSamplingParams(out, rawJSON, "generationConfig")
 
 	result := out
 	result = common.AttachDefaultSafetySettings(result, "safetySettings")
@@ -315,11 +342,3 @@ func geminiContentWithParts(role string, parts [][]byte) []byte {
 	content, _ = sjson.SetRawBytes(content, "parts", translatorcommon.JoinRawArray(parts))
 	return content
 }
-
-func toolNameFromClaudeToolUseID(toolUseID string) string {
-	parts := strings.Split(toolUseID, "-")
-	if len(parts) <= 1 {
-		return ""
-	}
-	return strings.Join(parts[0:len(parts)-1], "-")
-}
@@ -1,8 +1,10 @@
 package claude
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 )
 
@@ -225,6 +227,53 @@ func TestConvertClaudeRequestToGemini_StructuredToolResult(t *testing.T) {
 	}
 }
 
+func TestConvertClaudeRequestToGemini_ToolResultTextAndTwoImages(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "gemini-3-flash-preview",
+		"messages": [
+			{
+				"role": "assistant",
+				"content": [
+					{"type": "tool_use", "id": "screenshot-call-1", "name": "screenshot", "input": {}}
+				]
+			},
+			{
+				"role": "user",
+				"content": [
+					{
+						"type": "tool_result",
+						"tool_use_id": "screenshot-call-1",
+						"content": [
+							{"type": "text", "text": "two screenshots"},
+							{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "aGVsbG8="}},
+							{"type": "image", "source": {"type": "url", "url": "https://example.com/shot.webp"}}
+						]
+					}
+				]
+			}
+		]
+	}`)
+
+	output := ConvertClaudeRequestToGemini("gemini-3-flash-preview", inputJSON, false)
+
+	parts := gjson.GetBytes(output, "contents.1.parts").Array()
+	if len(parts) != 3 {
+		t.Fatalf("expected functionResponse plus 2 image parts, got %d: %s", len(parts), gjson.GetBytes(output, "contents").Raw)
+	}
+	if got := parts[0].Get("functionResponse.response.result.text").String(); got != "two screenshots" {
+		t.Fatalf("expected text-only result, got result=%s", parts[0].Get("functionResponse.response.result").Raw)
+	}
+	if got := parts[1].Get("inline_data.data").String(); got != "aGVsbG8=" {
+		t.Fatalf("expected inline_data part, got %s", parts[1].Raw)
+	}
+	if got := parts[2].Get("file_data.file_uri").String(); got != "https://example.com/shot.webp" {
+		t.Fatalf("expected file_data part, got %s", parts[2].Raw)
+	}
+	if got := parts[2].Get("file_data.mime_type").String(); got != "image/webp" {
+		t.Fatalf("expected file_data mime type 'image/webp', got '%s'", got)
+	}
+}
+
 func TestConvertClaudeRequestToGemini_StringToolResult(t *testing.T) {
 	inputJSON := []byte(`{
 		"model": "gemini-3-flash-preview",
@@ -255,3 +304,144 @@ func TestConvertClaudeRequestToGemini_StringToolResult(t *testing.T) {
 		t.Fatalf("expected result 'alpha', got '%s' (raw=%s)", got, fr.Get("response.result").Raw)
 	}
 }
+
+func TestConvertClaudeRequestToGemini_MapsWebSearchToGoogleSearch(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "Who won Euro 2024?"}],
+		"tools": [
+			{"type": "web_search_20250305", "name": "web_search", "max_uses": 5},
+			{"name": "lookup", "description": "Lookup local data", "input_schema": {"type": "object", "properties": {}}}
+		],
+		"tool_choice": {"type": "tool", "name": "web_search"}
+	}`)
+
+	output := ConvertClaudeRequestToGemini("gemini-2.5-flash", inputJSON, false)
+
+	if got := gjson.GetBytes(output, `tools.#.functionDeclarations|0.#.name`).Raw; got != `["lookup"]` {
+		t.Fatalf("functionDeclarations names = %s, want [\"lookup\"]; output %s", got, output)
+	}
+	if !gjson.GetBytes(output, "tools.#(googleSearch)").Exists() {
+		t.Fatalf("expected a googleSearch tool: %s", output)
+	}
+	if gjson.GetBytes(output, "toolConfig").Exists() {
+		t.Fatalf("tool_choice naming web_search must not force a function call: %s", output)
+	}
+	if gjson.GetBytes(output, "system_instruction").Exists() {
+		t.Fatalf("web_search alone must not add an unavailable-tools note: %s", output)
+	}
+}
+
+func TestConvertClaudeRequestToGemini_NotesUnavailableServerTools(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "gemini-2.5-flash",
+		"system": "Be brief.",
+		"messages": [{"role": "user", "content": "Plot the data"}],
+		"tools": [{"type": "code_execution_20250522", "name": "code_execution"}]
+	}`)
+
+	output := ConvertClaudeRequestToGemini("gemini-2.5-flash", inputJSON, false)
+
+	if gjson.GetBytes(output, "tools").Exists() {
+		t.Fatalf("code_execution must not be declared to Gemini: %s", output)
+	}
+	parts := gjson.GetBytes(output, "system_instruction.parts").Array()
+	if len(parts) != 2 || parts[0].Get("text").String() != "Be brief." {
+		t.Fatalf("system parts = %s, want the original system text and a note", gjson.GetBytes(output, "system_instruction.parts").Raw)
+	}
+	if note := parts[1].Get("text").String(); !strings.Contains(note, "code_execution") {
+		t.Fatalf("note %q does not name the dropped tool", note)
+	}
+}
+
+func TestConvertClaudeRequestToGemini_ToolResultNameFromHistory(t *testing.T) {
+	cases := []struct {
+		name     string
+		toolID   string
+		toolName string
+	}{
+		{name: "uuid id", toolID: "3f2b8c1e-9d4a-4e6b-a1c2-7f8e9d0a1b2c", toolName: "Read"},
+		{name: "mcp tool name", toolID: "mcp-server-tool-abc123", toolName: "mcp__github-server__create-pull-request"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inputJSON := []byte(`{
+				"model": "gemini-2.5-pro",
+				"messages": [
+					{"role": "assistant", "content": [{"type": "tool_use", "id": "` + tc.toolID + `", "name": "` + tc.toolName + `", "input": {}}]},
+					{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "` + tc.toolID + `", "content": "ok"}]}
+				]
+			}`)
+
+			output := ConvertClaudeRequestToGemini("gemini-2.5-pro", inputJSON, false)
+
+			callName := gjson.GetBytes(output, "contents.0.parts.0.functionCall.name").String()
+			responseName := gjson.GetBytes(output, "contents.1.parts.0.functionResponse.name").String()
+			if callName != util.SanitizeFunctionName(tc.toolName) {
+				t.Fatalf("functionCall.name = %q, want the tool_use name %q", callName, tc.toolName)
+			}
+			if responseName != callName {
+				t.Fatalf("functionResponse.name = %q, want %q; output=%s", responseName, callName, output)
+			}
+		})
+	}
+
+	// A result whose call is not in history keeps the full id as the name.
+	output := ConvertClaudeRequestToGemini("gemini-2.5-pro", []byte(`{
+		"messages": [{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "get_weather-call-123", "content": "ok"}]}]
+	}`), false)
+	if got := gjson.GetBytes(output, "contents.0.parts.0.functionResponse.name").String(); got != "get_weather-call-123" {
+		t.Fatalf("functionResponse.name = %q, want the full id", got)
+	}
+}
+
+func TestConvertClaudeRequestToGemini_MapsStopSequencesAndTopK(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "gemini-2.5-pro",
+		"max_tokens": 256,
+		"top_k": 40,
+		"stop_sequences": ["END", "", "STOP", "a", "b", "c", "d"],
+		"messages": [{"role": "user", "content": "Hello"}]
+	}`)
+
+	output := ConvertClaudeRequestToGemini("gemini-2.5-pro", inputJSON, false)
+
+	if got := gjson.GetBytes(output, "generationConfig.topK").Raw; got != "40" {
+		t.Fatalf("generationConfig.topK = %s, want 40. Output: %s", got, output)
+	}
+	if got := gjson.GetBytes(output, "generationConfig.stopSequences").Raw; got != `["END","STOP","a","b","c"]` {
+		t.Fatalf("generationConfig.stopSequences = %s, want the first five non-empty sequences", got)
+	}
+}
+
+func TestConvertClaudeRequestToGeminiCleansUnsupportedSchemaKeywords(t *testing.T) {
+	inputJSON := []byte(`{
+		"model": "claude-sonnet-4-5",
+		"messages": [{"role": "user", "content": "hi"}],
+		"tools": [{"name": "configure", "input_schema": {
+				"type": "object",
+				"$defs": {"Unit": {"type": "string", "enum": ["c", "f"]}},
+				"properties": {
+					"unit": {"$ref": "#/$defs/Unit"},
+					"mode": {"const": "fast"},
+					"target": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+					"labels": {"type": "object", "propertyNames": {"pattern": "^[a-z]+$"}}
+				},
+				"required": ["mode"]
+			}}]
+	}`)
+
+	output := ConvertClaudeRequestToGemini("gemini-2.5-flash", inputJSON, false)
+	schema := gjson.GetBytes(output, "tools.0.functionDeclarations.0.parametersJsonSchema")
+	for _, keyword := range []string{`"$ref":`, `"$defs":`, `"const":`, `"oneOf":`, `"propertyNames":`} {
+		if strings.Contains(schema.Raw, keyword) {
+			t.Fatalf("schema still contains %s: %s", keyword, schema.Raw)
+		}
+	}
+	if got := schema.Get("properties.mode.enum").Raw; got != `["fast"]` {
+		t.Fatalf("const should become enum, got %s: %s", got, schema.Raw)
+	}
+	if !schema.Get("properties.target.type").Exists() {
+		t.Fatalf("oneOf should be flattened to a single type: %s", schema.Raw)
+	}
+}
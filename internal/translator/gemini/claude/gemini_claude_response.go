@@ -30,6 +30,10 @@ type Params struct {
 	SanitizedNameMap map[string]string
 	SawToolCall      bool
 	HasFinalEvents   bool
+	// GroundingMetadata holds the latest Google Search grounding seen in the stream.
+	GroundingMetadata []byte
+	// WebSearchReported records that the grounding was sent as web_search blocks.
+	WebSearchReported bool
 }
 
 // toolUseIDCounter provides a process-wide unique counter for tool use identifiers.
@@ -246,29 +250,52 @@ func ConvertGeminiResponseToClaude(_ context.Context, _ string, originalRequestR
 		}
 	}
 
+	if groundingMetadata := gjson.GetBytes(rawJSON, "candidates.0.groundingMetadata"); groundingMetadata.IsObject() {
+		(*param).(*Params).GroundingMetadata = []byte(groundingMetadata.Raw)
+		if !(*param).(*Params).WebSearchReported && reportsClaudeWebSearch(originalRequestRawJSON, groundingMetadata) {
+			if (*param).(*Params).ResponseType != 0 {
+				appendEvent("content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, (*param).(*Params).ResponseIndex))
+				(*param).(*Params).ResponseIndex++
+				(*param).(*Params).ResponseType = 0
+			}
+			(*param).(*Params).ResponseIndex = translatorcommon.AppendClaudeWebSearchStreamBlocks(appendEvent, (*param).(*Params).ResponseIndex, translatorcommon.NewClaudeWebSearchToolUseID(), groundingMetadata)
+			(*param).(*Params).WebSearchReported = true
+			(*param).(*Params).HasContent = true
+		}
+	}
+
 	usageResult := gjson.GetBytes(rawJSON, "usageMetadata")
 	if usageResult.Exists() && bytes.Contains(rawJSON, []byte(`"finishReason"`)) && !(*param).(*Params).HasFinalEvents {
-		// Only send final events if we have actually output content
-		if (*param).(*Params).HasContent {
+		stopReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonClaude, gjson.GetBytes(rawJSON, "candidates.0.finishReason").String(), (*param).(*Params).SawToolCall)
+		if stopReason == "" {
+			stopReason = "end_turn"
+		}
+		// Only send final events if we have actually output content, or the
+		// response was blocked before producing any.
+		if (*param).(*Params).HasContent || stopReason == "refusal" {
 			if (*param).(*Params).ResponseType != 0 {
 				appendEvent("content_block_stop", fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, (*param).(*Params).ResponseIndex))
 				(*param).(*Params).ResponseType = 0
 			}
 
 			template := []byte(`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`)
-			if (*param).(*Params).SawToolCall {
-				template = []byte(`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`)
-			} else if finish := gjson.GetBytes(rawJSON, "candidates.0.finishReason"); finish.Exists() && finish.String() == "MAX_TOKENS" {
-				template = []byte(`{"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"input_tokens":0,"output_tokens":0}}`)
-			}
+			template, _ = sjson.SetBytes(template, "delta.stop_reason", stopReason)
 
 			thoughtsTokenCount := usageResult.Get("thoughtsTokenCount").Int()
 			candidatesTokenCount := usageResult.Get("candidatesTokenCount").Int()
 			template, _ = sjson.SetBytes(template, "usage.output_tokens", candidatesTokenCount+thoughtsTokenCount)
 			template, _ = sjson.SetBytes(template, "usage.input_tokens", usageResult.Get("promptTokenCount").Int())
+			// Claude has no native field for Gemini grounding, so it travels as a vendor extension.
+			if groundingMetadata := (*param).(*Params).GroundingMetadata; len(groundingMetadata) > 0 {
+				template, _ = sjson.SetRawBytes(template, "grounding_metadata", groundingMetadata)
+			}
+			if (*param).(*Params).WebSearchReported {
+				template, _ = sjson.SetBytes(template, "usage.server_tool_use.web_search_requests", 1)
+			}
 
 			appendEvent("message_delta", string(template))
 			(*param).(*Params).HasFinalEvents = true
+			(*param).(*Params).HasContent = true
 		}
 	}
 
@@ -366,22 +393,25 @@ func ConvertGeminiResponseToClaudeNonStream(_ context.Context, _ string, origina
 	flushThinking()
 	flushText()
 
-	stopReason := "end_turn"
-	if hasToolCall {
-		stopReason = "tool_use"
-	} else {
-		if finish := root.Get("candidates.0.finishReason"); finish.Exists() {
-			switch finish.String() {
-			case "MAX_TOKENS":
-				stopReason = "max_tokens"
-			case "STOP", "FINISH_REASON_UNSPECIFIED", "UNKNOWN":
-				stopReason = "end_turn"
-			default:
-				stopReason = "end_turn"
-			}
+	stopReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonClaude, root.Get("candidates.0.finishReason").String(), hasToolCall)
+	if stopReason == "" {
+		stopReason = "end_turn"
+		if hasToolCall {
+			stopReason = "tool_use"
 		}
 	}
 	out, _ = sjson.SetBytes(out, "stop_reason", stopReason)
+	if groundingMetadata := root.Get("candidates.0.groundingMetadata"); groundingMetadata.IsObject() {
+		out, _ = sjson.SetRawBytes(out, "grounding_metadata", []byte(groundingMetadata.Raw))
+		if reportsClaudeWebSearch(originalRequestRawJSON, groundingMetadata) {
+			content := translatorcommon.ClaudeWebSearchBlocks(translatorcommon.NewClaudeWebSearchToolUseID(), groundingMetadata)
+			for _, block := range gjson.GetBytes(out, "content").Array() {
+				content = append(content, []byte(block.Raw))
+			}
+			out, _ = sjson.SetRawBytes(out, "content", translatorcommon.JoinRawArray(content))
+			out, _ = sjson.SetBytes(out, "usage.server_tool_use.web_search_requests", 1)
+		}
+	}
 
 	if inputTokens == int64(0) && outputTokens == int64(0) && !root.Get("usageMetadata").Exists() {
 		out, _ = sjson.DeleteBytes(out, "usage")
@@ -390,6 +420,16 @@ func ConvertGeminiResponseToClaudeNonStream(_ context.Context, _ string, origina
 	return out
 }
 
+// reportsClaudeWebSearch reports whether Google Search grounding answers a Claude
+// request that declared the built-in web_search tool, so it is returned as the
+// server_tool_use and web_search_tool_result blocks Claude clients expect.
+func reportsClaudeWebSearch(originalRequestRawJSON []byte, groundingMetadata gjson.Result) bool {
+	if !translatorcommon.HasClaudeWebSearchTool(originalRequestRawJSON) {
+		return false
+	}
+	return len(groundingMetadata.Get("webSearchQueries").Array()) > 0 || len(groundingMetadata.Get("groundingChunks").Array()) > 0
+}
+
 func ClaudeTokenCount(ctx context.Context, count int64) []byte {
 	return translatorcommon.ClaudeInputTokensJSON(count)
 }
@@ -5,6 +5,8 @@ import (
 	"context"
 	"strings"
 	"testing"
+
+	"github.com/tidwall/gjson"
 )
 
 func TestConvertGeminiResponseToClaude_SignatureOnlyPartDoesNotOpenEmptyTextBlock(t *testing.T) {
@@ -60,3 +62,82 @@ func TestConvertGeminiResponseToClaude_SignatureOnlyPartDoesNotOpenEmptyTextBloc
 		t.Fatalf("DONE chunk must still emit message_stop after final events: %s", outputText)
 	}
 }
+
+// geminiGroundedResponse is a Google Search grounded generateContent response.
+const geminiGroundedResponse = `{"candidates":[{"content":{"role":"model","parts":[{"text":"Spain won Euro 2024."}]},"finishReason":"STOP","index":0,"groundingMetadata":{"webSearchQueries":["who won euro 2024"],"groundingChunks":[{"web":{"uri":"https://vertexaisearch.cloud.google.com/grounding-api-redirect/abc","title":"uefa.com"}}],"groundingSupports":[{"segment":{"startIndex":0,"endIndex":20,"text":"Spain won Euro 2024."},"groundingChunkIndices":[0]}]}}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":6,"totalTokenCount":14},"modelVersion":"gemini-2.5-flash","responseId":"resp-grounded"}`
+
+func TestConvertGeminiResponseToClaude_PreservesGroundingMetadata(t *testing.T) {
+	requestJSON := []byte(`{"model":"gemini-test","messages":[{"role":"user","content":"who won euro 2024"}]}`)
+	want := gjson.Get(geminiGroundedResponse, "candidates.0.groundingMetadata").Raw
+
+	nonStream := ConvertGeminiResponseToClaudeNonStream(context.Background(), "", requestJSON, nil, []byte(geminiGroundedResponse), nil)
+	if got := gjson.GetBytes(nonStream, "grounding_metadata").Raw; got != want {
+		t.Fatalf("non-stream grounding_metadata = %s, want %s", got, want)
+	}
+
+	var param any
+	var messageDelta string
+	for _, chunk := range ConvertGeminiResponseToClaude(context.Background(), "", requestJSON, nil, []byte(geminiGroundedResponse), &param) {
+		for _, line := range strings.Split(string(chunk), "\n") {
+			data, ok := strings.CutPrefix(line, "data: ")
+			if ok && gjson.Get(data, "type").String() == "message_delta" {
+				messageDelta = data
+			}
+		}
+	}
+	if messageDelta == "" {
+		t.Fatal("expected a message_delta event")
+	}
+	if got := gjson.Get(messageDelta, "grounding_metadata").Raw; got != want {
+		t.Fatalf("message_delta grounding_metadata = %s, want %s", got, want)
+	}
+}
+
+func TestConvertGeminiResponseToClaude_ReportsGroundingAsWebSearch(t *testing.T) {
+	requestJSON := []byte(`{"model":"gemini-test","messages":[{"role":"user","content":"who won euro 2024"}],"tools":[{"type":"web_search_20250305","name":"web_search"}]}`)
+
+	nonStream := ConvertGeminiResponseToClaudeNonStream(context.Background(), "", requestJSON, nil, []byte(geminiGroundedResponse), nil)
+	content := gjson.GetBytes(nonStream, "content").Array()
+	if len(content) != 3 {
+		t.Fatalf("content = %s, want server_tool_use, web_search_tool_result and text", gjson.GetBytes(nonStream, "content").Raw)
+	}
+	if content[0].Get("type").String() != "server_tool_use" || content[0].Get("input.query").String() != "who won euro 2024" {
+		t.Fatalf("unexpected server_tool_use block: %s", content[0].Raw)
+	}
+	if content[1].Get("tool_use_id").String() != content[0].Get("id").String() || content[1].Get("content.0.url").String() == "" {
+		t.Fatalf("unexpected web_search_tool_result block: %s", content[1].Raw)
+	}
+	if content[2].Get("text").String() != "Spain won Euro 2024." {
+		t.Fatalf("unexpected text block: %s", content[2].Raw)
+	}
+	if got := gjson.GetBytes(nonStream, "usage.server_tool_use.web_search_requests").Int(); got != 1 {
+		t.Fatalf("web_search_requests = %d, want 1", got)
+	}
+
+	var param any
+	var blockTypes []string
+	var messageDelta string
+	for _, chunk := range ConvertGeminiResponseToClaude(context.Background(), "", requestJSON, nil, []byte(geminiGroundedResponse), &param) {
+		for _, line := range strings.Split(string(chunk), "\n") {
+			data, ok := strings.CutPrefix(line, "data: ")
+			if !ok {
+				continue
+			}
+			switch gjson.Get(data, "type").String() {
+			case "content_block_start":
+				if int(gjson.Get(data, "index").Int()) != len(blockTypes) {
+					t.Fatalf("content_block_start index %d out of order: %s", gjson.Get(data, "index").Int(), data)
+				}
+				blockTypes = append(blockTypes, gjson.Get(data, "content_block.type").String())
+			case "message_delta":
+				messageDelta = data
+			}
+		}
+	}
+	if got := strings.Join(blockTypes, ","); got != "text,server_tool_use,web_search_tool_result" {
+		t.Fatalf("stream blocks = %s", got)
+	}
+	if got := gjson.Get(messageDelta, "usage.server_tool_use.web_search_requests").Int(); got != 1 {
+		t.Fatalf("message_delta web_search_requests = %d, want 1; event %s", got, messageDelta)
+	}
+}
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ApplyClaudeSamplingParams maps the Claude Messages sampling fields temperature, top_p,
+// top_k and stop_sequences onto the Gemini generationConfig found at generationConfigPath,
+// so every Claude to Gemini-family translator maps them the same way.
+func ApplyClaudeSamplingParams(out, rawJSON []byte, generationConfigPath string) []byte {
+	if v := gjson.GetBytes(rawJSON, "temperature"); v.Exists() && v.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, generationConfigPath+".temperature", v.Num)
+	}
+	if v := gjson.GetBytes(rawJSON, "top_p"); v.Exists() && v.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, generationConfigPath+".topP", v.Num)
+	}
+	// Gemini takes topK as an integer.
+	if v := gjson.GetBytes(rawJSON, "top_k"); v.Exists() && v.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, generationConfigPath+".topK", v.Int())
+	}
+	return applyGeminiStopSequences(out, gjson.GetBytes(rawJSON, "stop_sequences"), generationConfigPath)
+}
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Gemini accepts presencePenalty and frequencyPenalty in [-2.0, 2.0); the upper bound is
+// exclusive, so OpenAI's maximum of 2.0 is clamped just below it.
+const (
+	minGeminiPenalty = -2.0
+	maxGeminiPenalty = 1.99
+)
+
+// maxGeminiStopSequences is the number of stop sequences Gemini accepts.
+const maxGeminiStopSequences = 5
+
+// ClampGeminiPenalty limits an OpenAI penalty value to the range Gemini accepts.
+func ClampGeminiPenalty(value float64) float64 {
+	if value < minGeminiPenalty {
+		return minGeminiPenalty
+	}
+	if value > maxGeminiPenalty {
+		return maxGeminiPenalty
+	}
+	return value
+}
+
+// ApplyOpenAIPenaltiesAndSeed maps OpenAI presence_penalty, frequency_penalty and seed onto
+// the Gemini generationConfig found at generationConfigPath (e.g. "generationConfig" or
+// "request.generationConfig").
+func ApplyOpenAIPenaltiesAndSeed(out, rawJSON []byte, generationConfigPath string) []byte {
+	if pp := gjson.GetBytes(rawJSON, "presence_penalty"); pp.Exists() && pp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, generationConfigPath+".presencePenalty", ClampGeminiPenalty(pp.Num))
+	}
+	if fp := gjson.GetBytes(rawJSON, "frequency_penalty"); fp.Exists() && fp.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, generationConfigPath+".frequencyPenalty", ClampGeminiPenalty(fp.Num))
+	}
+	if seed := gjson.GetBytes(rawJSON, "seed"); seed.Exists() && seed.Type == gjson.Number {
+		out, _ = sjson.SetBytes(out, generationConfigPath+".seed", seed.Int())
+	}
+	return out
+}
+
+// ApplyOpenAIStopSequences maps OpenAI stop, given as a string or an array, onto
+// stopSequences of the Gemini generationConfig found at generationConfigPath. Sequences
+// beyond Gemini's limit of five are dropped, and stopSequences already set by the client
+// are kept.
+func ApplyOpenAIStopSequences(out, rawJSON []byte, generationConfigPath string) []byte {
+	return applyGeminiStopSequences(out, gjson.GetBytes(rawJSON, "stop"), generationConfigPath)
+}
+
+// applyGeminiStopSequences sets stopSequences of the generationConfig at
+// generationConfigPath from stop, a string or an array of strings, unless it is set.
+func applyGeminiStopSequences(out []byte, stop gjson.Result, generationConfigPath string) []byte {
+	if gjson.GetBytes(out, generationConfigPath+".stopSequences").Exists() {
+		return out
+	}
+	var sequences []string
+	switch {
+	case stop.Type == gjson.String:
+		if stop.String() != "" {
+			sequences = append(sequences, stop.String())
+		}
+	case stop.IsArray():
+		stop.ForEach(func(_, value gjson.Result) bool {
+			if value.Type == gjson.String && value.String() != "" {
+				sequences = append(sequences, value.String())
+			}
+			return true
+		})
+	}
+	if len(sequences) == 0 {
+		return out
+	}
+	if len(sequences) > maxGeminiStopSequences {
+		log.Debugf("gemini translator: dropping %d stop sequences beyond the limit of %d", len(sequences)-maxGeminiStopSequences, maxGeminiStopSequences)
+		sequences = sequences[:maxGeminiStopSequences]
+	}
+	out, _ = sjson.SetBytes(out, generationConfigPath+".stopSequences", sequences)
+	return out
+}
+
+// UnsupportedOpenAIParam returns the name of the first OpenAI Chat Completions field that
+// Gemini cannot honor, or "" when the request can be translated. Gemini has no equivalent
+// of logit_bias, so a non-empty bias map is reported instead of being silently dropped.
+func UnsupportedOpenAIParam(rawJSON []byte) string {
+	if bias := gjson.GetBytes(rawJSON, "logit_bias"); bias.IsObject() && len(bias.Map()) > 0 {
+		return "logit_bias"
+	}
+	return ""
+}
+
+// ApplyOpenAIResponseFormat maps an OpenAI response_format onto the Gemini generationConfig
+// found at generationConfigPath. JSON mode and JSON schemas both request an application/json
+// response; schemas are cleaned so Gemini accepts them.
+func ApplyOpenAIResponseFormat(out []byte, responseFormat gjson.Result, generationConfigPath string) []byte {
+	if !responseFormat.Exists() || !responseFormat.IsObject() {
+		return out
+	}
+
+	switch strings.ToLower(strings.TrimSpace(responseFormat.Get("type").String())) {
+	case "json_object":
+		out, _ = sjson.SetBytes(out, generationConfigPath+".responseMimeType", "application/json")
+	case "json_schema":
+		out, _ = sjson.SetBytes(out, generationConfigPath+".responseMimeType", "application/json")
+		if schema := responseFormat.Get("json_schema.schema"); schema.Exists() && schema.IsObject() {
+			out, _ = sjson.SetRawBytes(out, generationConfigPath+".responseSchema", []byte(util.CleanJSONSchemaForGemini(schema.Raw)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,23 @@
+package common
+
+import "testing"
+
+func TestUnsupportedOpenAIParam(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "absent", input: `{"model":"m"}`, want: ""},
+		{name: "empty map", input: `{"logit_bias":{}}`, want: ""},
+		{name: "null", input: `{"logit_bias":null}`, want: ""},
+		{name: "non-empty map", input: `{"logit_bias":{"50256":-100}}`, want: "logit_bias"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnsupportedOpenAIParam([]byte(tt.input)); got != tt.want {
+				t.Fatalf("UnsupportedOpenAIParam() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// IsOpenAISystemRole reports whether an OpenAI message role carries system
+// instructions. Gemini has no such content role, so these messages must be
+// folded into systemInstruction instead of contents.
+func IsOpenAISystemRole(role string) bool {
+	return strings.EqualFold(role, "system") || strings.EqualFold(role, "developer")
+}
+
+// SystemInstructionTexts returns the text of a system prompt in any of the
+// shapes clients send: a plain string, a single text block, or an array of
+// text blocks or strings. Blank entries are dropped so they never become empty
+// systemInstruction parts.
+func SystemInstructionTexts(content gjson.Result) []string {
+	var texts []string
+	appendText := func(value gjson.Result) {
+		var text string
+		switch {
+		case value.Type == gjson.String:
+			text = value.String()
+		case value.IsObject():
+			text = value.Get("text").String()
+		}
+		if strings.TrimSpace(text) != "" {
+			texts = append(texts, text)
+		}
+	}
+	if content.IsArray() {
+		for _, item := range content.Array() {
+			appendText(item)
+		}
+		return texts
+	}
+	appendText(content)
+	return texts
+}
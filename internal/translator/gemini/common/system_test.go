@@ -0,0 +1,30 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestSystemInstructionTexts(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "absent", input: `{}`, want: ""},
+		{name: "string", input: `{"system":"be brief"}`, want: "be brief"},
+		{name: "blank string", input: `{"system":"  "}`, want: ""},
+		{name: "text block", input: `{"system":{"type":"text","text":"one"}}`, want: "one"},
+		{name: "mixed array", input: `{"system":[{"type":"text","text":"one"},"two",{"type":"image_url"},{"type":"input_text","text":"three"}]}`, want: "one|two|three"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(SystemInstructionTexts(gjson.Get(tt.input, "system")), "|")
+			if got != tt.want {
+				t.Fatalf("SystemInstructionTexts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package gemini
 import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/translator"
 )
 
@@ -13,10 +14,10 @@ func init() {
 		Gemini,
 		Gemini,
 		ConvertGeminiRequestToGemini,
-		interfaces.TranslateResponse{
+		translatorcommon.SuppressGeminiThoughts(interfaces.TranslateResponse{
 			Stream:     PassthroughGeminiResponseStream,
 			NonStream:  PassthroughGeminiResponseNonStream,
 			TokenCount: GeminiTokenCount,
-		},
+		}),
 	)
 }
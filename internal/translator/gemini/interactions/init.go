@@ -3,6 +3,7 @@ package interactions
 import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/translator"
 )
 
@@ -20,10 +21,10 @@ func init() {
 		Interactions,
 		Gemini,
 		ConvertInteractionsRequestToGemini,
-		interfaces.TranslateResponse{
+		translatorcommon.SuppressGeminiThoughts(interfaces.TranslateResponse{
 			Stream:    ConvertGeminiResponseToInteractions,
 			NonStream: ConvertGeminiResponseToInteractionsNonStream,
-		},
+		}),
 	)
 	translator.Register(
 		Gemini,
@@ -39,6 +39,16 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 		out, _ = sjson.SetRawBytes(out, "generationConfig", []byte(genConfig.Raw))
 	}
 
+	// Explicit context cache: cached_content, or extra_body.google.cached_content as sent to
+	// Google's OpenAI-compatible endpoint.
+	cachedContent := strings.TrimSpace(gjson.GetBytes(rawJSON, "cached_content").String())
+	if cachedContent == "" {
+		cachedContent = strings.TrimSpace(gjson.GetBytes(rawJSON, "extra_body.google.cached_content").String())
+	}
+	if cachedContent != "" {
+		out, _ = sjson.SetBytes(out, "cachedContent", cachedContent)
+	}
+
 	// Apply thinking configuration: convert OpenAI reasoning_effort to Gemini thinkingConfig.
 	// Inline translation-only mapping; capability checks happen later in ApplyThinking.
 	re := gjson.GetBytes(rawJSON, "reasoning_effort")
@@ -67,6 +77,12 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 		out, _ = sjson.SetBytes(out, "generationConfig.topK", tkr.Num)
 	}
 
+	// Presence/frequency penalties and seed
+	out = common.ApplyOpenAIPenaltiesAndSeed(out, rawJSON, "generationConfig")
+
+	// Stop sequences
+	out = common.ApplyOpenAIStopSequences(out, rawJSON, "generationConfig")
+
 	// OpenAI max_tokens / max_completion_tokens -> Gemini generationConfig.maxOutputTokens
 	if mt := gjson.GetBytes(rawJSON, "max_tokens"); mt.Exists() && mt.Type == gjson.Number {
 		out, _ = sjson.SetBytes(out, "generationConfig.maxOutputTokens", mt.Num)
@@ -109,11 +125,19 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 		}
 	}
 
+	// OpenAI response_format -> Gemini generationConfig.responseMimeType/responseSchema
+	out = common.ApplyOpenAIResponseFormat(out, gjson.GetBytes(rawJSON, "response_format"), "generationConfig")
+
+	// top-level system + system/developer messages -> systemInstruction, top-level first
+	systemParts := make([][]byte, 0, 2)
+	for _, text := range common.SystemInstructionTexts(gjson.GetBytes(rawJSON, "system")) {
+		systemParts = append(systemParts, geminiTextPart(text))
+	}
+
 	// messages -> systemInstruction + contents
 	messages := gjson.GetBytes(rawJSON, "messages")
 	if messages.IsArray() {
 		arr := messages.Array()
-		systemParts := make([][]byte, 0, 2)
 		contentItems := make([][]byte, 0, len(arr))
 		// First pass: assistant tool_calls id->name map
 		tcID2Name := map[string]string{}
@@ -154,19 +178,12 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 			role := m.Get("role").String()
 			content := m.Get("content")
 
-			if (role == "system" || role == "developer") && len(arr) > 1 {
+			if common.IsOpenAISystemRole(role) && len(arr) > 1 {
 				// system -> systemInstruction as a user message style
-				if content.Type == gjson.String {
-					systemParts = append(systemParts, geminiTextPart(content.String()))
-				} else if content.IsObject() && content.Get("type").String() == "text" {
-					systemParts = append(systemParts, geminiTextPart(content.Get("text").String()))
-				} else if content.IsArray() {
-					contents := content.Array()
-					for j := 0; j < len(contents); j++ {
-						systemParts = append(systemParts, geminiTextPart(contents[j].Get("text").String()))
-					}
+				for _, text := range common.SystemInstructionTexts(content) {
+					systemParts = append(systemParts, geminiTextPart(text))
 				}
-			} else if role == "user" || ((role == "system" || role == "developer") && len(arr) == 1) {
+			} else if role == "user" || (common.IsOpenAISystemRole(role) && len(arr) == 1) {
 				// Build single user content node to avoid splitting into multiple contents.
 				partItems := make([][]byte, 0, 4)
 				if content.Type == gjson.String {
@@ -291,15 +308,15 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 			}
 		}
 
-		if len(systemParts) > 0 {
-			systemInstruction := geminiContentNode("user", systemParts)
-			out, _ = sjson.SetRawBytes(out, "systemInstruction", systemInstruction)
-		}
 		if len(contentItems) > 0 && gjson.GetBytes(contentItems[len(contentItems)-1], "role").String() == "model" {
 			contentItems = contentItems[:len(contentItems)-1]
 		}
 		out = translatorcommon.SetRawArrayItems(out, "contents", contentItems)
 	}
+	if len(systemParts) > 0 {
+		systemInstruction := geminiContentNode("user", systemParts)
+		out, _ = sjson.SetRawBytes(out, "systemInstruction", systemInstruction)
+	}
 
 	// tools -> tools[].functionDeclarations + tools[].googleSearch/codeExecution/urlContext passthrough
 	tools := gjson.GetBytes(rawJSON, "tools")
@@ -357,7 +374,13 @@ func ConvertOpenAIRequestToGemini(modelName string, inputRawJSON []byte, _ bool)
 						fnRawBytes, _ = sjson.SetBytes(fnRawBytes, "name", sanitizedName)
 					}
 					if parameters := gjson.GetBytes(fnRawBytes, "parametersJsonSchema"); parameters.Exists() {
-						cleanedParameters := util.CleanJSONSchemaForGemini(parameters.Raw)
+						schema := parameters.Raw
+						// Gemini has no strict flag; compensate by tightening the schema to
+						// the OpenAI strict contract before cleaning it.
+						if fn.Get("strict").Bool() {
+							schema = util.TightenStrictJSONSchema(schema)
+						}
+						cleanedParameters := util.CleanJSONSchemaForGemini(schema)
 						if cleanedParameters != parameters.Raw {
 							fnRawBytes, _ = sjson.SetRawBytes(fnRawBytes, "parametersJsonSchema", []byte(cleanedParameters))
 						}
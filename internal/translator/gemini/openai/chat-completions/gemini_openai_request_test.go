@@ -1,6 +1,7 @@
 package chat_completions
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/tidwall/gjson"
@@ -299,3 +300,271 @@ func TestConvertOpenAIRequestToGeminiCleansToolSchemaRequiredFields(t *testing.T
 		t.Fatalf("required[1] = %q, want industry. Schema: %s", got, schema.Raw)
 	}
 }
+
+func TestConvertOpenAIRequestToGemini_MapsJSONSchemaResponseFormat(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "Describe a company"}],
+		"response_format": {
+			"type": "json_schema",
+			"json_schema": {
+				"name": "company",
+				"strict": true,
+				"schema": {
+					"$schema": "http://json-schema.org/draft-07/schema#",
+					"type": "object",
+					"additionalProperties": false,
+					"properties": {
+						"name": {"type": "string", "minLength": 1},
+						"address": {
+							"type": "object",
+							"additionalProperties": false,
+							"properties": {
+								"city": {"type": "string", "pattern": "^[A-Z]"},
+								"zip": {"type": ["string", "null"]}
+							},
+							"required": ["city"]
+						}
+					},
+					"required": ["name", "address"]
+				}
+			}
+		}
+	}`
+
+	output := ConvertOpenAIRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+
+	if got := gjson.GetBytes(output, "generationConfig.responseMimeType").String(); got != "application/json" {
+		t.Fatalf("responseMimeType = %q, want application/json. Output: %s", got, output)
+	}
+	schema := gjson.GetBytes(output, "generationConfig.responseSchema")
+	if !schema.IsObject() {
+		t.Fatalf("responseSchema missing. Output: %s", output)
+	}
+	for _, path := range []string{"$schema", "additionalProperties", "properties.name.minLength", "properties.address.additionalProperties", "properties.address.properties.city.pattern"} {
+		if schema.Get(path).Exists() {
+			t.Fatalf("unsupported keyword %q should be removed. Schema: %s", path, schema.Raw)
+		}
+	}
+	if got := schema.Get("properties.address.properties.city.type").String(); got != "string" {
+		t.Fatalf("nested city type = %q, want string. Schema: %s", got, schema.Raw)
+	}
+	if got := schema.Get("properties.address.properties.zip.type").String(); got != "string" {
+		t.Fatalf("nested zip type = %q, want string. Schema: %s", got, schema.Raw)
+	}
+	if gjson.GetBytes(output, "response_format").Exists() {
+		t.Fatalf("response_format should not leak into Gemini request. Output: %s", output)
+	}
+}
+
+func TestConvertOpenAIRequestToGeminiMapsPenaltiesAndSeed(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		wantPresence  float64
+		wantFrequency float64
+	}{
+		{name: "in range", input: `{"presence_penalty":0.5,"frequency_penalty":-0.25}`, wantPresence: 0.5, wantFrequency: -0.25},
+		{name: "upper bound clamped", input: `{"presence_penalty":2,"frequency_penalty":3.5}`, wantPresence: 1.99, wantFrequency: 1.99},
+		{name: "lower bound clamped", input: `{"presence_penalty":-2,"frequency_penalty":-7}`, wantPresence: -2, wantFrequency: -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(tt.input), false)
+			if got := gjson.GetBytes(result, "generationConfig.presencePenalty").Float(); got != tt.wantPresence {
+				t.Fatalf("presencePenalty = %v, want %v. output=%s", got, tt.wantPresence, result)
+			}
+			if got := gjson.GetBytes(result, "generationConfig.frequencyPenalty").Float(); got != tt.wantFrequency {
+				t.Fatalf("frequencyPenalty = %v, want %v. output=%s", got, tt.wantFrequency, result)
+			}
+		})
+	}
+
+	result := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"seed":42,"logit_bias":{"50256":-100}}`), false)
+	if got := gjson.GetBytes(result, "generationConfig.seed").Int(); got != 42 {
+		t.Fatalf("seed = %d, want 42. output=%s", got, result)
+	}
+	if gjson.GetBytes(result, "logit_bias").Exists() || gjson.GetBytes(result, "generationConfig.logitBias").Exists() {
+		t.Fatalf("logit_bias must not be forwarded: %s", result)
+	}
+}
+
+func TestConvertOpenAIRequestToGeminiMapsStopSequences(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "string", input: `{"stop":"END","seed":3}`, want: `["END"]`},
+		{name: "array truncated", input: `{"stop":["a","b","","c","d","e","f","g"],"seed":3}`, want: `["a","b","c","d","e"]`},
+		{name: "client stopSequences kept", input: `{"stop":["a"],"seed":3,"generationConfig":{"stopSequences":["x"]}}`, want: `["x"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(tt.input), false)
+			if got := gjson.GetBytes(result, "generationConfig.stopSequences").Raw; got != tt.want {
+				t.Fatalf("stopSequences = %s, want %s. output=%s", got, tt.want, result)
+			}
+			if got := gjson.GetBytes(result, "generationConfig.seed").Int(); got != 3 {
+				t.Fatalf("seed = %d, want 3. output=%s", got, result)
+			}
+		})
+	}
+
+	result := ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(`{"stop":null}`), false)
+	if gjson.GetBytes(result, "generationConfig.stopSequences").Exists() {
+		t.Fatalf("stopSequences should not be set for a null stop: %s", result)
+	}
+}
+
+func TestConvertOpenAIRequestToGeminiMergesSystemSources(t *testing.T) {
+	inputJSON := `{
+		"model": "gpt-5.4",
+		"system": [{"type": "text", "text": "top-level"}, {"type": "text", "text": " "}],
+		"messages": [
+			{"role": "system", "content": "first system"},
+			{"role": "user", "content": "hello"},
+			{"role": "developer", "content": [{"type": "text", "text": "developer note"}, "plain string"]},
+			{"role": "assistant", "content": "hi"},
+			{"role": "user", "content": "again"}
+		]
+	}`
+
+	result := gjson.ParseBytes(ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(inputJSON), false))
+
+	var got []string
+	for _, part := range result.Get("systemInstruction.parts").Array() {
+		got = append(got, part.Get("text").String())
+	}
+	want := []string{"top-level", "first system", "developer note", "plain string"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("systemInstruction parts = %q, want %q", got, want)
+	}
+	for _, content := range result.Get("contents").Array() {
+		if role := content.Get("role").String(); role != "user" && role != "model" {
+			t.Fatalf("contents role = %q, want only user/model. contents=%s", role, result.Get("contents").Raw)
+		}
+	}
+	if n := len(result.Get("contents").Array()); n != 3 {
+		t.Fatalf("contents length = %d, want 3. contents=%s", n, result.Get("contents").Raw)
+	}
+}
+
+func TestConvertOpenAIRequestToGeminiTopLevelSystemOnly(t *testing.T) {
+	inputJSON := `{"system": "be brief", "messages": [{"role": "user", "content": "hello"}]}`
+
+	result := gjson.ParseBytes(ConvertOpenAIRequestToGemini("gemini-2.5-pro", []byte(inputJSON), false))
+
+	if got := result.Get("systemInstruction.parts.0.text").String(); got != "be brief" {
+		t.Fatalf("systemInstruction text = %q, want %q. result=%s", got, "be brief", result.Raw)
+	}
+	if got := result.Get("contents.#").Int(); got != 1 {
+		t.Fatalf("contents length = %d, want 1", got)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_MapsJSONObjectResponseFormat(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "List three colors as JSON"}],
+		"response_format": {"type": "json_object"}
+	}`
+
+	output := ConvertOpenAIRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+
+	if got := gjson.GetBytes(output, "generationConfig.responseMimeType").String(); got != "application/json" {
+		t.Fatalf("responseMimeType = %q, want application/json. Output: %s", got, output)
+	}
+	if gjson.GetBytes(output, "generationConfig.responseSchema").Exists() {
+		t.Fatalf("json_object must not set a responseSchema. Output: %s", output)
+	}
+}
+
+func TestConvertOpenAIRequestToGemini_TightensStrictToolSchema(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "Weather in Paris?"}],
+		"tools": [
+			{"type": "function", "function": {"name": "get_weather", "strict": true, "parameters": {
+				"type": "object",
+				"properties": {"city": {"type": "string"}, "unit": {"type": "string", "enum": ["c", "f"]}},
+				"required": ["city"]
+			}}},
+			{"type": "function", "function": {"name": "lookup", "parameters": {
+				"type": "object",
+				"properties": {"query": {"type": "string"}, "limit": {"type": "integer"}},
+				"required": ["query"]
+			}}}
+		]
+	}`
+
+	output := ConvertOpenAIRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+
+	strictTool := gjson.GetBytes(output, "tools.0.functionDeclarations.0")
+	if strictTool.Get("strict").Exists() {
+		t.Fatalf("strict must not reach Gemini: %s", strictTool.Raw)
+	}
+	if got := strictTool.Get("parametersJsonSchema.required").Raw; got != `["city","unit"]` {
+		t.Fatalf("strict required = %s, want every property", got)
+	}
+	if desc := strictTool.Get("parametersJsonSchema.description").String(); !strings.Contains(desc, "No extra properties allowed") {
+		t.Fatalf("strict schema description = %q, want the no-extra-properties hint", desc)
+	}
+
+	looseTool := gjson.GetBytes(output, "tools.0.functionDeclarations.1")
+	if got := looseTool.Get("parametersJsonSchema.required").Raw; got != `["query"]` {
+		t.Fatalf("non-strict required = %s, want the client list unchanged", got)
+	}
+	if looseTool.Get("parametersJsonSchema.description").Exists() {
+		t.Fatalf("non-strict schema must not gain hints: %s", looseTool.Raw)
+	}
+}
+
+func TestConvertOpenAIRequestToGeminiMapsCachedContent(t *testing.T) {
+	for _, inputJSON := range []string{
+		`{"model":"gemini-2.5-flash","cached_content":"cachedContents/abc123","messages":[{"role":"user","content":"hi"}]}`,
+		`{"model":"gemini-2.5-flash","extra_body":{"google":{"cached_content":"cachedContents/abc123"}},"messages":[{"role":"user","content":"hi"}]}`,
+	} {
+		result := ConvertOpenAIRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+		if got := gjson.GetBytes(result, "cachedContent").String(); got != "cachedContents/abc123" {
+			t.Fatalf("cachedContent = %q, want cachedContents/abc123; result=%s", got, result)
+		}
+	}
+
+	result := ConvertOpenAIRequestToGemini("gemini-2.5-flash", []byte(`{"messages":[{"role":"user","content":"hi"}]}`), false)
+	if gjson.GetBytes(result, "cachedContent").Exists() {
+		t.Fatalf("cachedContent set without a cache reference: %s", result)
+	}
+}
+
+func TestConvertOpenAIRequestToGeminiCleansUnsupportedSchemaKeywords(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "hi"}],
+		"tools": [{"type": "function", "function": {"name": "configure", "parameters": {
+				"type": "object",
+				"$defs": {"Unit": {"type": "string", "enum": ["c", "f"]}},
+				"properties": {
+					"unit": {"$ref": "#/$defs/Unit"},
+					"mode": {"const": "fast"},
+					"target": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+					"labels": {"type": "object", "propertyNames": {"pattern": "^[a-z]+$"}}
+				},
+				"required": ["mode"]
+			}}}]
+	}`
+
+	output := ConvertOpenAIRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+	schema := gjson.GetBytes(output, "tools.0.functionDeclarations.0.parametersJsonSchema")
+	for _, keyword := range []string{`"$ref":`, `"$defs":`, `"const":`, `"oneOf":`, `"propertyNames":`} {
+		if strings.Contains(schema.Raw, keyword) {
+			t.Fatalf("schema still contains %s: %s", keyword, schema.Raw)
+		}
+	}
+	if got := schema.Get("properties.mode.enum").Raw; got != `["fast"]` {
+		t.Fatalf("const should become enum, got %s: %s", got, schema.Raw)
+	}
+	if !schema.Get("properties.target.type").Exists() {
+		t.Fatalf("oneOf should be flattened to a single type: %s", schema.Raw)
+	}
+}
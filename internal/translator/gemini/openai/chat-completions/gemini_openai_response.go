@@ -13,6 +13,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
@@ -109,7 +110,7 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 	// Extract and set usage metadata (token counts).
 	// Usage is applied to the base template so it appears in the chunks.
 	if usageResult := gjson.GetBytes(rawJSON, "usageMetadata"); usageResult.Exists() {
-		cachedTokenCount := usageResult.Get("cachedContentTokenCount").Int()
+		cachedTokenCount := translatorcommon.GeminiCachedTokenCount(usageResult)
 		if candidatesTokenCountResult := usageResult.Get("candidatesTokenCount"); candidatesTokenCountResult.Exists() {
 			baseTemplate, _ = sjson.SetBytes(baseTemplate, "usage.completion_tokens", candidatesTokenCountResult.Int())
 		}
@@ -243,24 +244,27 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 				}
 			}
 
+			// Expose Google Search grounding (queries, sources, supports) as a vendor extension.
+			if groundingMetadata := candidate.Get("groundingMetadata"); groundingMetadata.IsObject() {
+				template, _ = sjson.SetRawBytes(template, "choices.0.grounding_metadata", []byte(groundingMetadata.Raw))
+			}
+
 			upstreamFinishReason := p.UpstreamFinishReason[candidateIndex]
 			sawToolCall := p.SawToolCall[candidateIndex]
 			usageExists := gjson.GetBytes(rawJSON, "usageMetadata").Exists()
 			isFinalChunk := upstreamFinishReason != "" && usageExists
 
 			if isFinalChunk {
-				var finishReason string
-				if sawToolCall {
-					finishReason = "tool_calls"
-				} else if upstreamFinishReason == "MAX_TOKENS" {
-					finishReason = "max_tokens"
-				} else {
-					finishReason = "stop"
-				}
+				finishReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonOpenAI, upstreamFinishReason, sawToolCall)
 				template, _ = sjson.SetBytes(template, "choices.0.finish_reason", finishReason)
 				template, _ = sjson.SetBytes(template, "choices.0.native_finish_reason", strings.ToLower(upstreamFinishReason))
 			}
 
+			// Thought-signature-only parts leave nothing to forward; clients such as the
+			// Vercel AI SDK treat runs of empty deltas as a stalled stream.
+			if !OpenAIStreamChunkHasPayload(template) {
+				return true
+			}
 			responseStrings = append(responseStrings, template)
 			return true // continue loop
 		})
@@ -274,6 +278,30 @@ func ConvertGeminiResponseToOpenAI(_ context.Context, _ string, originalRequestR
 	return responseStrings
 }
 
+// OpenAIStreamChunkHasPayload reports whether an OpenAI chat completion chunk carries anything
+// for the client: delta text, reasoning, tool calls or images, a finish_reason, usage, or
+// grounding metadata. Chunks without any of these would reach the client as an empty delta.
+func OpenAIStreamChunkHasPayload(chunk []byte) bool {
+	if gjson.GetBytes(chunk, "usage").Exists() {
+		return true
+	}
+	choice := gjson.GetBytes(chunk, "choices.0")
+	if !choice.Exists() {
+		return false
+	}
+	if finishReason := choice.Get("finish_reason"); finishReason.Type != gjson.Null && finishReason.String() != "" {
+		return true
+	}
+	if choice.Get("grounding_metadata").Exists() {
+		return true
+	}
+	delta := choice.Get("delta")
+	if delta.Get("content").String() != "" || delta.Get("reasoning_content").String() != "" {
+		return true
+	}
+	return len(delta.Get("tool_calls").Array()) > 0 || len(delta.Get("images").Array()) > 0
+}
+
 // ConvertGeminiResponseToOpenAINonStream converts a non-streaming Gemini response to a non-streaming OpenAI response.
 // This function processes the complete Gemini response and transforms it into a single OpenAI-compatible
 // JSON response. It handles message content, tool calls, reasoning content, and usage metadata, combining all
@@ -320,7 +348,7 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 		}
 		promptTokenCount := usageResult.Get("promptTokenCount").Int()
 		thoughtsTokenCount := usageResult.Get("thoughtsTokenCount").Int()
-		cachedTokenCount := usageResult.Get("cachedContentTokenCount").Int()
+		cachedTokenCount := translatorcommon.GeminiCachedTokenCount(usageResult)
 		template, _ = sjson.SetBytes(template, "usage.prompt_tokens", promptTokenCount)
 		if thoughtsTokenCount > 0 {
 			template, _ = sjson.SetBytes(template, "usage.completion_tokens_details.reasoning_tokens", thoughtsTokenCount)
@@ -346,9 +374,9 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 			choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "index", candidate.Get("index").Int())
 
 			// Set finish reason.
-			if finishReasonResult := candidate.Get("finishReason"); finishReasonResult.Exists() {
-				choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "finish_reason", strings.ToLower(finishReasonResult.String()))
-				choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "native_finish_reason", strings.ToLower(finishReasonResult.String()))
+			upstreamFinishReason := candidate.Get("finishReason").String()
+			if upstreamFinishReason != "" {
+				choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "native_finish_reason", strings.ToLower(upstreamFinishReason))
 			}
 
 			partsResult := candidate.Get("content.parts")
@@ -413,10 +441,17 @@ func ConvertGeminiResponseToOpenAINonStream(_ context.Context, _ string, origina
 				}
 			}
 
-			if hasFunctionCall {
+			if finishReason := util.MapFinishReasonWithToolCalls(util.FinishReasonGemini, util.FinishReasonOpenAI, upstreamFinishReason, hasFunctionCall); finishReason != "" {
+				choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "finish_reason", finishReason)
+			} else if hasFunctionCall {
 				choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "finish_reason", "tool_calls")
+			}
+			if hasFunctionCall {
 				choiceTemplate, _ = sjson.SetBytes(choiceTemplate, "native_finish_reason", "tool_calls")
 			}
+			if groundingMetadata := candidate.Get("groundingMetadata"); groundingMetadata.IsObject() {
+				choiceTemplate, _ = sjson.SetRawBytes(choiceTemplate, "grounding_metadata", []byte(groundingMetadata.Raw))
+			}
 
 			// Append the constructed choice to the main choices array.
 			template, _ = sjson.SetRawBytes(template, "choices.-1", choiceTemplate)
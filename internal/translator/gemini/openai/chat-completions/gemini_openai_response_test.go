@@ -38,3 +38,72 @@ func TestGeminiFinishReasonOnlyOnFinalChunk(t *testing.T) {
 		t.Fatalf("expected native_finish_reason stop, got %s", nfr3)
 	}
 }
+
+func TestGeminiInlineDataImagesAreEmittedAsImageURLParts(t *testing.T) {
+	ctx := context.Background()
+	const pngBase64 = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+	rawJSON := []byte(`{"candidates":[{"content":{"parts":[{"text":"Here it is"},{"inlineData":{"mimeType":"image/png","data":"` + pngBase64 + `"}}]},"finishReason":"STOP"}]}`)
+
+	var param any
+	chunks := ConvertGeminiResponseToOpenAI(ctx, "gemini-3-pro-image-preview", nil, nil, rawJSON, &param)
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if got := gjson.GetBytes(chunks[0], "choices.0.delta.images.0.type").String(); got != "image_url" {
+		t.Fatalf("stream image type = %q, want image_url. Chunk: %s", got, chunks[0])
+	}
+	if got := gjson.GetBytes(chunks[0], "choices.0.delta.images.0.image_url.url").String(); got != "data:image/png;base64,"+pngBase64 {
+		t.Fatalf("stream image url = %q", got)
+	}
+
+	out := ConvertGeminiResponseToOpenAINonStream(ctx, "gemini-3-pro-image-preview", nil, nil, rawJSON, nil)
+	if got := gjson.GetBytes(out, "choices.0.message.content").String(); got != "Here it is" {
+		t.Fatalf("message content = %q, want %q", got, "Here it is")
+	}
+	if got := gjson.GetBytes(out, "choices.0.message.images.0.image_url.url").String(); got != "data:image/png;base64,"+pngBase64 {
+		t.Fatalf("non-stream image url = %q. Output: %s", got, out)
+	}
+}
+
+// geminiGroundedResponse is a Google Search grounded generateContent response.
+const geminiGroundedResponse = `{"candidates":[{"content":{"role":"model","parts":[{"text":"Spain won Euro 2024."}]},"finishReason":"STOP","index":0,"groundingMetadata":{"webSearchQueries":["who won euro 2024"],"searchEntryPoint":{"renderedContent":"<div></div>"},"groundingChunks":[{"web":{"uri":"https://vertexaisearch.cloud.google.com/grounding-api-redirect/abc","title":"uefa.com"}}],"groundingSupports":[{"segment":{"startIndex":0,"endIndex":20,"text":"Spain won Euro 2024."},"groundingChunkIndices":[0],"confidenceScores":[0.97]}]}}],"usageMetadata":{"promptTokenCount":8,"candidatesTokenCount":6,"totalTokenCount":14},"modelVersion":"gemini-2.5-flash","responseId":"resp-grounded"}`
+
+func TestConvertGeminiResponseToOpenAI_PreservesGroundingMetadata(t *testing.T) {
+	want := gjson.Get(geminiGroundedResponse, "candidates.0.groundingMetadata").Raw
+
+	nonStream := ConvertGeminiResponseToOpenAINonStream(context.Background(), "model", nil, nil, []byte(geminiGroundedResponse), nil)
+	if got := gjson.GetBytes(nonStream, "choices.0.grounding_metadata").Raw; got != want {
+		t.Fatalf("non-stream grounding_metadata = %s, want %s", got, want)
+	}
+
+	var param any
+	chunks := ConvertGeminiResponseToOpenAI(context.Background(), "model", nil, nil, []byte(geminiGroundedResponse), &param)
+	if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk, got %d", len(chunks))
+	}
+	if got := gjson.GetBytes(chunks[0], "choices.0.grounding_metadata").Raw; got != want {
+		t.Fatalf("stream grounding_metadata = %s, want %s", got, want)
+	}
+	if got := gjson.GetBytes(chunks[0], "choices.0.grounding_metadata.groundingChunks.0.web.title").String(); got != "uefa.com" {
+		t.Fatalf("grounding chunk title = %q, want uefa.com", got)
+	}
+}
+
+func TestGeminiStreamSuppressesSignatureOnlyChunks(t *testing.T) {
+	ctx := context.Background()
+	var param any
+
+	signatureOnly := []byte(`{"candidates":[{"content":{"parts":[{"thought":true,"thoughtSignature":"c2ln"}]}}]}`)
+	if result := ConvertGeminiResponseToOpenAI(ctx, "model", nil, nil, signatureOnly, &param); len(result) != 0 {
+		t.Fatalf("expected signature-only chunk to be suppressed, got %q", result)
+	}
+
+	final := []byte(`{"candidates":[{"content":{"parts":[{"thoughtSignature":"c2ln"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}`)
+	result := ConvertGeminiResponseToOpenAI(ctx, "model", nil, nil, final, &param)
+	if len(result) != 1 {
+		t.Fatalf("expected the terminal chunk to be kept, got %d chunks", len(result))
+	}
+	if got := gjson.GetBytes(result[0], "choices.0.finish_reason").String(); got != "stop" {
+		t.Fatalf("finish_reason = %q, want stop", got)
+	}
+}
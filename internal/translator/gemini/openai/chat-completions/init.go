@@ -3,6 +3,7 @@ package chat_completions
 import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/translator/translator"
 )
 
@@ -11,9 +12,9 @@ func init() {
 		OpenAI,
 		Gemini,
 		ConvertOpenAIRequestToGemini,
-		interfaces.TranslateResponse{
+		translatorcommon.SuppressGeminiThoughts(interfaces.TranslateResponse{
 			Stream:    ConvertGeminiResponseToOpenAI,
 			NonStream: ConvertGeminiResponseToOpenAINonStream,
-		},
+		}),
 	)
 }
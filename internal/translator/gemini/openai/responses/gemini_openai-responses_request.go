@@ -26,12 +26,11 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 
 	root := gjson.ParseBytes(rawJSON)
 
-	// Extract system instruction from OpenAI "instructions" field.
+	// Extract system instruction from OpenAI "instructions" field; system/developer
+	// input messages are appended after it.
 	systemParts := make([][]byte, 0, 2)
-	if instructions := root.Get("instructions"); instructions.Exists() {
-		part := []byte(`{"text":""}`)
-		part, _ = sjson.SetBytes(part, "text", instructions.String())
-		systemParts = append(systemParts, part)
+	for _, text := range common.SystemInstructionTexts(root.Get("instructions")) {
+		systemParts = append(systemParts, geminiTextPart(text))
 	}
 
 	// Convert input messages to Gemini contents format
@@ -132,20 +131,9 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 
 			switch itemType {
 			case "message":
-				if strings.EqualFold(itemRole, "system") || strings.EqualFold(itemRole, "developer") {
-					if contentArray := item.Get("content"); contentArray.Exists() {
-						if contentArray.IsArray() {
-							contentArray.ForEach(func(_, contentItem gjson.Result) bool {
-								part := []byte(`{"text":""}`)
-								part, _ = sjson.SetBytes(part, "text", contentItem.Get("text").String())
-								systemParts = append(systemParts, part)
-								return true
-							})
-						} else if contentArray.Type == gjson.String {
-							part := []byte(`{"text":""}`)
-							part, _ = sjson.SetBytes(part, "text", contentArray.String())
-							systemParts = append(systemParts, part)
-						}
+				if common.IsOpenAISystemRole(itemRole) {
+					for _, text := range common.SystemInstructionTexts(item.Get("content")) {
+						systemParts = append(systemParts, geminiTextPart(text))
 					}
 					continue
 				}
@@ -383,7 +371,13 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 					funcDecl, _ = sjson.SetBytes(funcDecl, "description", desc.String())
 				}
 				if params := tool.Get("parameters"); params.Exists() {
-					funcDecl, _ = sjson.SetRawBytes(funcDecl, "parametersJsonSchema", []byte(util.CleanJSONSchemaForGemini(params.Raw)))
+					schema := params.Raw
+					// Gemini has no strict flag; compensate by tightening the schema to
+					// the OpenAI strict contract before cleaning it.
+					if tool.Get("strict").Bool() {
+						schema = util.TightenStrictJSONSchema(schema)
+					}
+					funcDecl, _ = sjson.SetRawBytes(funcDecl, "parametersJsonSchema", []byte(util.CleanJSONSchemaForGemini(schema)))
 				}
 
 				functionDeclarations = append(functionDeclarations, funcDecl)
@@ -444,6 +438,13 @@ func ConvertOpenAIResponsesRequestToGemini(modelName string, inputRawJSON []byte
 			}
 		}
 	}
+	// A reasoning summary asks for the model's reasoning to be shown, which Gemini exposes
+	// as thought parts. store has no Gemini equivalent and is dropped with the rest.
+	if summary := strings.TrimSpace(root.Get("reasoning.summary").String()); summary != "" && !strings.EqualFold(summary, "none") {
+		if !strings.EqualFold(strings.TrimSpace(re.String()), "none") {
+			out, _ = sjson.SetBytes(out, "generationConfig.thinkingConfig.includeThoughts", true)
+		}
+	}
 
 	result := out
 	result = common.AttachDefaultSafetySettings(result, "safetySettings")
@@ -457,6 +458,12 @@ func geminiContent(role string, parts [][]byte) []byte {
 	return content
 }
 
+func geminiTextPart(text string) []byte {
+	part := []byte(`{"text":""}`)
+	part, _ = sjson.SetBytes(part, "text", text)
+	return part
+}
+
 func geminiSystemInstruction(parts [][]byte) []byte {
 	systemInstruction := []byte(`{"parts":[]}`)
 	systemInstruction, _ = sjson.SetRawBytes(systemInstruction, "parts", translatorcommon.JoinRawArray(parts))
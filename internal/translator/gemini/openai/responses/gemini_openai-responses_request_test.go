@@ -2,6 +2,7 @@ package responses
 
 import (
 	"encoding/base64"
+	"strings"
 	"testing"
 
 	"github.com/tidwall/gjson"
@@ -484,3 +485,107 @@ func validResponsesGPTReasoningSignature() string {
 	}
 	return base64.URLEncoding.EncodeToString(raw)
 }
+
+func TestConvertOpenAIResponsesRequestToGeminiMergesSystemSources(t *testing.T) {
+	inputJSON := `{
+		"instructions": "top-level",
+		"input": [
+			{"role": "developer", "content": [{"type": "input_text", "text": "developer note"}]},
+			{"role": "user", "content": [{"type": "input_text", "text": "hello"}]},
+			{"type": "message", "role": "system", "content": "late system"},
+			{"role": "system", "content": [{"type": "input_text", "text": ""}]}
+		]
+	}`
+
+	result := gjson.ParseBytes(ConvertOpenAIResponsesRequestToGemini("gemini-2.5-pro", []byte(inputJSON), false))
+
+	parts := result.Get("systemInstruction.parts").Array()
+	want := []string{"top-level", "developer note", "late system"}
+	if len(parts) != len(want) {
+		t.Fatalf("systemInstruction parts = %s, want %q", result.Get("systemInstruction.parts").Raw, want)
+	}
+	for i, part := range parts {
+		if got := part.Get("text").String(); got != want[i] {
+			t.Fatalf("systemInstruction part %d = %q, want %q", i, got, want[i])
+		}
+	}
+	contents := result.Get("contents").Array()
+	if len(contents) != 1 || contents[0].Get("role").String() != "user" {
+		t.Fatalf("contents = %s, want a single user content", result.Get("contents").Raw)
+	}
+}
+
+func TestConvertOpenAIResponsesRequestToGemini_ReasoningSummaryIncludesThoughts(t *testing.T) {
+	tests := []struct {
+		name      string
+		reasoning string
+		want      string
+	}{
+		{name: "summary only", reasoning: `{"summary":"detailed"}`, want: "true"},
+		{name: "summary with effort", reasoning: `{"effort":"low","summary":"auto"}`, want: "true"},
+		{name: "effort none wins", reasoning: `{"effort":"none","summary":"detailed"}`, want: "false"},
+		{name: "no summary", reasoning: `{}`, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputJSON := `{"model":"gpt-5.4","store":false,"reasoning":` + tt.reasoning + `,"input":"hello"}`
+			result := ConvertOpenAIResponsesRequestToGemini("gemini-2.5-pro", []byte(inputJSON), false)
+
+			if got := gjson.GetBytes(result, "generationConfig.thinkingConfig.includeThoughts").Raw; got != tt.want {
+				t.Fatalf("includeThoughts = %q, want %q. Result: %s", got, tt.want, result)
+			}
+			if gjson.GetBytes(result, "store").Exists() {
+				t.Fatalf("store was forwarded to Gemini: %s", result)
+			}
+		})
+	}
+}
+
+func TestConvertOpenAIResponsesRequestToGeminiCleansUnsupportedSchemaKeywords(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-2.5-flash",
+		"input": "hi",
+		"tools": [{"type": "function", "name": "configure", "parameters": {
+				"type": "object",
+				"$defs": {"Unit": {"type": "string", "enum": ["c", "f"]}},
+				"properties": {
+					"unit": {"$ref": "#/$defs/Unit"},
+					"mode": {"const": "fast"},
+					"target": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+					"labels": {"type": "object", "propertyNames": {"pattern": "^[a-z]+$"}}
+				},
+				"required": ["mode"]
+			}}]
+	}`
+
+	output := ConvertOpenAIResponsesRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+	schema := gjson.GetBytes(output, "tools.0.functionDeclarations.0.parametersJsonSchema")
+	for _, keyword := range []string{`"$ref":`, `"$defs":`, `"const":`, `"oneOf":`, `"propertyNames":`} {
+		if strings.Contains(schema.Raw, keyword) {
+			t.Fatalf("schema still contains %s: %s", keyword, schema.Raw)
+		}
+	}
+	if got := schema.Get("properties.mode.enum").Raw; got != `["fast"]` {
+		t.Fatalf("const should become enum, got %s: %s", got, schema.Raw)
+	}
+	if !schema.Get("properties.target.type").Exists() {
+		t.Fatalf("oneOf should be flattened to a single type: %s", schema.Raw)
+	}
+}
+
+func TestConvertOpenAIResponsesRequestToGeminiTightensStrictToolSchema(t *testing.T) {
+	inputJSON := `{
+		"model": "gemini-2.5-flash",
+		"input": "hi",
+		"tools": [{"type": "function", "name": "get_weather", "strict": true, "parameters": {
+			"type": "object",
+			"properties": {"city": {"type": "string"}, "unit": {"type": "string"}},
+			"required": ["city"]
+		}}]
+	}`
+
+	output := ConvertOpenAIResponsesRequestToGemini("gemini-2.5-flash", []byte(inputJSON), false)
+	if got := gjson.GetBytes(output, "tools.0.functionDeclarations.0.parametersJsonSchema.required").Raw; got != `["city","unit"]` {
+		t.Fatalf("strict required = %s, want every property", got)
+	}
+}
@@ -20,13 +20,15 @@ type geminiToResponsesState struct {
 	CreatedAt  int64
 	Started    bool
 
-	// message aggregation
+	// message aggregation; text after a function call opens a new message item
 	MsgOpened    bool
 	MsgClosed    bool
 	MsgIndex     int
+	MsgCount     int
 	CurrentMsgID string
-	TextBuf      strings.Builder
 	ItemTextBuf  strings.Builder
+	MsgIDs       map[int]string
+	MsgTexts     map[int]string
 
 	// reasoning aggregation
 	ReasoningOpened bool
@@ -43,6 +45,12 @@ type geminiToResponsesState struct {
 	FuncCallIDs      map[int]string
 	FuncDone         map[int]bool
 	SanitizedNameMap map[string]string
+
+	// streamed function call (functionCall.willContinue) whose arguments arrive as
+	// partialArgs over several parts; -1 when none is open
+	StreamingFunc     int
+	StreamingFuncArgs []byte
+	FuncArgsDiverged  bool
 }
 
 // responseIDCounter provides a process-wide unique counter for synthesized response identifiers.
@@ -88,6 +96,78 @@ func emitEvent(event string, payload []byte) []byte {
 	return translatorcommon.SSEEventData(event, payload)
 }
 
+func emitFuncArgsDelta(seq int, callID string, outputIndex int, delta string) []byte {
+	ad := []byte(`{"type":"response.function_call_arguments.delta","sequence_number":0,"item_id":"","output_index":0,"delta":""}`)
+	ad, _ = sjson.SetBytes(ad, "sequence_number", seq)
+	ad, _ = sjson.SetBytes(ad, "item_id", fmt.Sprintf("fc_%s", callID))
+	ad, _ = sjson.SetBytes(ad, "output_index", outputIndex)
+	ad, _ = sjson.SetBytes(ad, "delta", delta)
+	return emitEvent("response.function_call_arguments.delta", ad)
+}
+
+// appendStreamingFuncArgs merges Gemini partialArgs into the arguments of the open streamed
+// call and passes the newly settled prefix of the arguments JSON to emit. The closing quotes
+// and brackets at the end are held back because later chunks may still extend the value.
+func appendStreamingFuncArgs(st *geminiToResponsesState, partialArgs gjson.Result, emit func(string)) {
+	if !partialArgs.IsArray() {
+		return
+	}
+	args := st.StreamingFuncArgs
+	if len(args) == 0 {
+		args = []byte(`{}`)
+	}
+	for _, partial := range partialArgs.Array() {
+		path := geminiJSONPathToSJSON(partial.Get("jsonPath").String())
+		if path == "" {
+			continue
+		}
+		switch {
+		case partial.Get("stringValue").Exists():
+			value := partial.Get("stringValue").String()
+			if existing := gjson.GetBytes(args, path); existing.Type == gjson.String {
+				value = existing.String() + value
+			}
+			args, _ = sjson.SetBytes(args, path, value)
+		case partial.Get("numberValue").Exists():
+			args, _ = sjson.SetRawBytes(args, path, []byte(partial.Get("numberValue").Raw))
+		case partial.Get("boolValue").Exists():
+			args, _ = sjson.SetBytes(args, path, partial.Get("boolValue").Bool())
+		case partial.Get("nullValue").Exists():
+			args, _ = sjson.SetRawBytes(args, path, []byte("null"))
+		}
+	}
+	st.StreamingFuncArgs = args
+
+	sent := st.FuncArgsBuf[st.StreamingFunc]
+	if st.FuncArgsDiverged || sent == nil {
+		return
+	}
+	if !bytes.HasPrefix(args, []byte(sent.String())) {
+		// An earlier value changed; the remaining arguments only arrive in the done events.
+		st.FuncArgsDiverged = true
+		return
+	}
+	settled := len(args)
+	for settled > 0 && (args[settled-1] == '"' || args[settled-1] == '}' || args[settled-1] == ']') {
+		settled--
+	}
+	if settled > sent.Len() {
+		delta := string(args[sent.Len():settled])
+		sent.WriteString(delta)
+		emit(delta)
+	}
+}
+
+// geminiJSONPathToSJSON converts a partialArgs jsonPath such as "$.items[0].name" into the
+// sjson path "items.0.name".
+func geminiJSONPathToSJSON(jsonPath string) string {
+	path := strings.TrimPrefix(strings.TrimSpace(jsonPath), "$")
+	path = strings.TrimPrefix(path, ".")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	return strings.Trim(path, ".")
+}
+
 // ConvertGeminiResponseToOpenAIResponses converts Gemini SSE chunks into OpenAI Responses SSE events.
 func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) [][]byte {
 	if *param == nil {
@@ -97,9 +177,18 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 			FuncCallIDs:      make(map[int]string),
 			FuncDone:         make(map[int]bool),
 			SanitizedNameMap: util.SanitizedToolNameMap(originalRequestRawJSON),
+			MsgIDs:           make(map[int]string),
+			MsgTexts:         make(map[int]string),
+			StreamingFunc:    -1,
 		}
 	}
 	st := (*param).(*geminiToResponsesState)
+	if st.MsgIDs == nil {
+		st.MsgIDs = make(map[int]string)
+	}
+	if st.MsgTexts == nil {
+		st.MsgTexts = make(map[int]string)
+	}
 	if st.FuncArgsBuf == nil {
 		st.FuncArgsBuf = make(map[int]*strings.Builder)
 	}
@@ -194,9 +283,56 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 		final, _ = sjson.SetBytes(final, "item.content.0.text", fullText)
 		out = append(out, emitEvent("response.output_item.done", final))
 
+		st.MsgTexts[st.MsgIndex] = fullText
 		st.MsgClosed = true
 	}
 
+	// Helper to close a function call: it emits the argument bytes not yet streamed,
+	// response.function_call_arguments.done and response.output_item.done exactly once.
+	finalizeFunc := func(idx int) {
+		if st.FuncDone[idx] {
+			return
+		}
+		args := "{}"
+		if idx == st.StreamingFunc {
+			if len(st.StreamingFuncArgs) > 0 {
+				args = string(st.StreamingFuncArgs)
+			}
+			if sent := st.FuncArgsBuf[idx].String(); !st.FuncArgsDiverged && strings.HasPrefix(args, sent) && len(args) > len(sent) {
+				out = append(out, emitFuncArgsDelta(nextSeq(), st.FuncCallIDs[idx], idx, args[len(sent):]))
+			}
+			st.FuncArgsBuf[idx].Reset()
+			st.FuncArgsBuf[idx].WriteString(args)
+			st.StreamingFunc = -1
+			st.StreamingFuncArgs = nil
+			st.FuncArgsDiverged = false
+		} else if b := st.FuncArgsBuf[idx]; b != nil && b.Len() > 0 {
+			args = b.String()
+		}
+		fcDone := []byte(`{"type":"response.function_call_arguments.done","sequence_number":0,"item_id":"","output_index":0,"arguments":""}`)
+		fcDone, _ = sjson.SetBytes(fcDone, "sequence_number", nextSeq())
+		fcDone, _ = sjson.SetBytes(fcDone, "item_id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
+		fcDone, _ = sjson.SetBytes(fcDone, "output_index", idx)
+		fcDone, _ = sjson.SetBytes(fcDone, "arguments", args)
+		out = append(out, emitEvent("response.function_call_arguments.done", fcDone))
+
+		itemDone := []byte(`{"type":"response.output_item.done","sequence_number":0,"output_index":0,"item":{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}}`)
+		itemDone, _ = sjson.SetBytes(itemDone, "sequence_number", nextSeq())
+		itemDone, _ = sjson.SetBytes(itemDone, "output_index", idx)
+		itemDone, _ = sjson.SetBytes(itemDone, "item.id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
+		itemDone, _ = sjson.SetBytes(itemDone, "item.arguments", args)
+		itemDone, _ = sjson.SetBytes(itemDone, "item.call_id", st.FuncCallIDs[idx])
+		itemDone, _ = sjson.SetBytes(itemDone, "item.name", st.FuncNames[idx])
+		out = append(out, emitEvent("response.output_item.done", itemDone))
+
+		st.FuncDone[idx] = true
+	}
+	finalizeStreamingFunc := func() {
+		if st.StreamingFunc >= 0 {
+			finalizeFunc(st.StreamingFunc)
+		}
+	}
+
 	// Initialize per-response fields and emit created/in_progress once
 	if !st.Started {
 		st.ResponseID = root.Get("responseId").String()
@@ -246,6 +382,7 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 					st.ReasoningEnc = sig.String()
 				}
 				if !st.ReasoningOpened {
+					finalizeStreamingFunc()
 					st.ReasoningOpened = true
 					st.ReasoningIndex = st.NextIndex
 					st.NextIndex++
@@ -276,13 +413,18 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 
 			// Assistant visible text
 			if t := part.Get("text"); t.Exists() && t.String() != "" {
-				// Before emitting non-reasoning outputs, finalize reasoning if open.
+				// Before emitting non-reasoning outputs, finalize reasoning and any streamed call.
 				finalizeReasoning()
-				if !st.MsgOpened {
+				finalizeStreamingFunc()
+				if !st.MsgOpened || st.MsgClosed {
+					// Text after a function call continues in a new message item.
 					st.MsgOpened = true
+					st.MsgClosed = false
 					st.MsgIndex = st.NextIndex
 					st.NextIndex++
-					st.CurrentMsgID = fmt.Sprintf("msg_%s_0", st.ResponseID)
+					st.CurrentMsgID = fmt.Sprintf("msg_%s_%d", st.ResponseID, st.MsgCount)
+					st.MsgCount++
+					st.MsgIDs[st.MsgIndex] = st.CurrentMsgID
 					item := []byte(`{"type":"response.output_item.added","sequence_number":0,"output_index":0,"item":{"id":"","type":"message","status":"in_progress","content":[],"role":"assistant"}}`)
 					item, _ = sjson.SetBytes(item, "sequence_number", nextSeq())
 					item, _ = sjson.SetBytes(item, "output_index", st.MsgIndex)
@@ -295,7 +437,6 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 					out = append(out, emitEvent("response.content_part.added", partAdded))
 					st.ItemTextBuf.Reset()
 				}
-				st.TextBuf.WriteString(t.String())
 				st.ItemTextBuf.WriteString(t.String())
 				msg := []byte(`{"type":"response.output_text.delta","sequence_number":0,"item_id":"","output_index":0,"content_index":0,"delta":"","logprobs":[]}`)
 				msg, _ = sjson.SetBytes(msg, "sequence_number", nextSeq())
@@ -308,11 +449,23 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 
 			// Function call
 			if fc := part.Get("functionCall"); fc.Exists() {
-				// Before emitting function-call outputs, finalize reasoning and the message (if open).
-				// Responses streaming requires message done events before the next output_item.added.
+				name := util.RestoreSanitizedToolName(st.SanitizedNameMap, fc.Get("name").String())
+				// Parts without a name continue the streamed call that is still open.
+				if idx := st.StreamingFunc; idx >= 0 && (name == "" || name == st.FuncNames[idx]) {
+					appendStreamingFuncArgs(st, fc.Get("partialArgs"), func(delta string) {
+						out = append(out, emitFuncArgsDelta(nextSeq(), st.FuncCallIDs[idx], idx, delta))
+					})
+					if !fc.Get("willContinue").Bool() {
+						finalizeFunc(idx)
+					}
+					return true
+				}
+
+				// Before emitting function-call outputs, finalize reasoning, the message and any
+				// streamed call. Responses streaming requires done events before the next output_item.added.
 				finalizeReasoning()
 				finalizeMessage()
-				name := util.RestoreSanitizedToolName(st.SanitizedNameMap, fc.Get("name").String())
+				finalizeStreamingFunc()
 				idx := st.NextIndex
 				st.NextIndex++
 				// Ensure buffers
@@ -324,14 +477,6 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 				}
 				st.FuncNames[idx] = name
 
-				argsJSON := "{}"
-				if args := fc.Get("args"); args.Exists() {
-					argsJSON = args.Raw
-				}
-				if st.FuncArgsBuf[idx].Len() == 0 && argsJSON != "" {
-					st.FuncArgsBuf[idx].WriteString(argsJSON)
-				}
-
 				// Emit item.added for function call
 				item := []byte(`{"type":"response.output_item.added","sequence_number":0,"output_index":0,"item":{"id":"","type":"function_call","status":"in_progress","arguments":"","call_id":"","name":""}}`)
 				item, _ = sjson.SetBytes(item, "sequence_number", nextSeq())
@@ -341,38 +486,34 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 				item, _ = sjson.SetBytes(item, "item.name", name)
 				out = append(out, emitEvent("response.output_item.added", item))
 
+				// With streamed arguments the call stays open and each partialArgs chunk is
+				// forwarded as an arguments delta until a part without willContinue arrives.
+				if fc.Get("willContinue").Bool() {
+					st.StreamingFunc = idx
+					st.StreamingFuncArgs = nil
+					st.FuncArgsDiverged = false
+					if args := fc.Get("args"); args.IsObject() {
+						st.StreamingFuncArgs = []byte(args.Raw)
+					}
+					appendStreamingFuncArgs(st, fc.Get("partialArgs"), func(delta string) {
+						out = append(out, emitFuncArgsDelta(nextSeq(), st.FuncCallIDs[idx], idx, delta))
+					})
+					return true
+				}
+
 				// Emit arguments delta (full args in one chunk).
 				// When Gemini omits args, emit "{}" to keep Responses streaming event order consistent.
-				if argsJSON != "" {
-					ad := []byte(`{"type":"response.function_call_arguments.delta","sequence_number":0,"item_id":"","output_index":0,"delta":""}`)
-					ad, _ = sjson.SetBytes(ad, "sequence_number", nextSeq())
-					ad, _ = sjson.SetBytes(ad, "item_id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
-					ad, _ = sjson.SetBytes(ad, "output_index", idx)
-					ad, _ = sjson.SetBytes(ad, "delta", argsJSON)
-					out = append(out, emitEvent("response.function_call_arguments.delta", ad))
+				argsJSON := "{}"
+				if args := fc.Get("args"); args.Exists() {
+					argsJSON = args.Raw
 				}
-
-				// Gemini emits the full function call payload at once, so we can finalize it immediately.
-				if !st.FuncDone[idx] {
-					fcDone := []byte(`{"type":"response.function_call_arguments.done","sequence_number":0,"item_id":"","output_index":0,"arguments":""}`)
-					fcDone, _ = sjson.SetBytes(fcDone, "sequence_number", nextSeq())
-					fcDone, _ = sjson.SetBytes(fcDone, "item_id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
-					fcDone, _ = sjson.SetBytes(fcDone, "output_index", idx)
-					fcDone, _ = sjson.SetBytes(fcDone, "arguments", argsJSON)
-					out = append(out, emitEvent("response.function_call_arguments.done", fcDone))
-
-					itemDone := []byte(`{"type":"response.output_item.done","sequence_number":0,"output_index":0,"item":{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}}`)
-					itemDone, _ = sjson.SetBytes(itemDone, "sequence_number", nextSeq())
-					itemDone, _ = sjson.SetBytes(itemDone, "output_index", idx)
-					itemDone, _ = sjson.SetBytes(itemDone, "item.id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
-					itemDone, _ = sjson.SetBytes(itemDone, "item.arguments", argsJSON)
-					itemDone, _ = sjson.SetBytes(itemDone, "item.call_id", st.FuncCallIDs[idx])
-					itemDone, _ = sjson.SetBytes(itemDone, "item.name", st.FuncNames[idx])
-					out = append(out, emitEvent("response.output_item.done", itemDone))
-
-					st.FuncDone[idx] = true
+				if st.FuncArgsBuf[idx].Len() == 0 && argsJSON != "" {
+					st.FuncArgsBuf[idx].WriteString(argsJSON)
 				}
+				out = append(out, emitFuncArgsDelta(nextSeq(), st.FuncCallIDs[idx], idx, argsJSON))
 
+				// The full function call payload arrived at once, so it can be finalized immediately.
+				finalizeFunc(idx)
 				return true
 			}
 
@@ -401,30 +542,7 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 				}
 			}
 			for _, idx := range idxs {
-				if st.FuncDone[idx] {
-					continue
-				}
-				args := "{}"
-				if b := st.FuncArgsBuf[idx]; b != nil && b.Len() > 0 {
-					args = b.String()
-				}
-				fcDone := []byte(`{"type":"response.function_call_arguments.done","sequence_number":0,"item_id":"","output_index":0,"arguments":""}`)
-				fcDone, _ = sjson.SetBytes(fcDone, "sequence_number", nextSeq())
-				fcDone, _ = sjson.SetBytes(fcDone, "item_id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
-				fcDone, _ = sjson.SetBytes(fcDone, "output_index", idx)
-				fcDone, _ = sjson.SetBytes(fcDone, "arguments", args)
-				out = append(out, emitEvent("response.function_call_arguments.done", fcDone))
-
-				itemDone := []byte(`{"type":"response.output_item.done","sequence_number":0,"output_index":0,"item":{"id":"","type":"function_call","status":"completed","arguments":"","call_id":"","name":""}}`)
-				itemDone, _ = sjson.SetBytes(itemDone, "sequence_number", nextSeq())
-				itemDone, _ = sjson.SetBytes(itemDone, "output_index", idx)
-				itemDone, _ = sjson.SetBytes(itemDone, "item.id", fmt.Sprintf("fc_%s", st.FuncCallIDs[idx]))
-				itemDone, _ = sjson.SetBytes(itemDone, "item.arguments", args)
-				itemDone, _ = sjson.SetBytes(itemDone, "item.call_id", st.FuncCallIDs[idx])
-				itemDone, _ = sjson.SetBytes(itemDone, "item.name", st.FuncNames[idx])
-				out = append(out, emitEvent("response.output_item.done", itemDone))
-
-				st.FuncDone[idx] = true
+				finalizeFunc(idx)
 			}
 		}
 
@@ -511,10 +629,10 @@ func ConvertGeminiResponseToOpenAIResponses(_ context.Context, modelName string,
 				outputsWrapper, _ = sjson.SetRawBytes(outputsWrapper, "arr.-1", item)
 				continue
 			}
-			if st.MsgOpened && idx == st.MsgIndex {
+			if msgID, ok := st.MsgIDs[idx]; ok {
 				item := []byte(`{"id":"","type":"message","status":"completed","content":[{"type":"output_text","annotations":[],"logprobs":[],"text":""}],"role":"assistant"}`)
-				item, _ = sjson.SetBytes(item, "id", st.CurrentMsgID)
-				item, _ = sjson.SetBytes(item, "content.0.text", st.TextBuf.String())
+				item, _ = sjson.SetBytes(item, "id", msgID)
+				item, _ = sjson.SetBytes(item, "content.0.text", st.MsgTexts[idx])
 				outputsWrapper, _ = sjson.SetRawBytes(outputsWrapper, "arr.-1", item)
 				continue
 			}
@@ -762,5 +880,7 @@ func ConvertGeminiResponseToOpenAIResponsesNonStream(_ context.Context, _ string
 		}
 	}
 
+	resp = translatorcommon.SetResponsesIncomplete(resp, util.FinishReasonGemini, root.Get("candidates.0.finishReason").String())
+
 	return resp
 }
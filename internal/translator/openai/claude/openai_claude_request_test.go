@@ -869,3 +869,35 @@ func TestConvertClaudeRequestToOpenAI_StripsClaudeCodeAttribution(t *testing.T)
 		t.Fatalf("Unexpected system content: %q", got)
 	}
 }
+
+// TestConvertClaudeRequestToOpenAI_NoInjectedToolGuidance pins that the translator adds no
+// system message of its own, with or without declared tools.
+func TestConvertClaudeRequestToOpenAI_NoInjectedToolGuidance(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputJSON string
+		wantTools int64
+	}{
+		{
+			name:      "no tools",
+			inputJSON: `{"model":"claude-3-opus","messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:      "with tools",
+			inputJSON: `{"model":"claude-3-opus","messages":[{"role":"user","content":"hi"}],"tools":[{"name":"lookup","description":"Look up","input_schema":{"type":"object","properties":{"q":{"type":"string"}}}}]}`,
+			wantTools: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := ConvertClaudeRequestToOpenAI("gpt-4o", []byte(tt.inputJSON), false)
+			messages := gjson.GetBytes(out, "messages").Array()
+			if len(messages) != 1 || messages[0].Get("role").String() != "user" {
+				t.Fatalf("messages = %s, want only the user message", gjson.GetBytes(out, "messages").Raw)
+			}
+			if got := gjson.GetBytes(out, "tools.#").Int(); got != tt.wantTools {
+				t.Fatalf("tools count = %d, want %d", got, tt.wantTools)
+			}
+		})
+	}
+}
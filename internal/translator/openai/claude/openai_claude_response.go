@@ -487,22 +487,13 @@ func convertOpenAINonStreamingToAnthropic(rawJSON []byte) [][]byte {
 	return [][]byte{out}
 }
 
-// mapOpenAIFinishReasonToAnthropic maps OpenAI finish reasons to Anthropic equivalents
+// mapOpenAIFinishReasonToAnthropic maps OpenAI finish reasons to Anthropic
+// equivalents, defaulting to end_turn when no reason was reported.
 func mapOpenAIFinishReasonToAnthropic(openAIReason string) string {
-	switch openAIReason {
-	case "stop":
-		return "end_turn"
-	case "length":
-		return "max_tokens"
-	case "tool_calls":
-		return "tool_use"
-	case "content_filter":
-		return "end_turn" // Anthropic doesn't have direct equivalent
-	case "function_call": // Legacy OpenAI
-		return "tool_use"
-	default:
-		return "end_turn"
+	if reason := util.MapFinishReason(util.FinishReasonOpenAI, util.FinishReasonClaude, openAIReason); reason != "" {
+		return reason
 	}
+	return "end_turn"
 }
 
 func (p *ConvertOpenAIResponseToAnthropicParams) toolContentBlockIndex(openAIToolIndex int) int {
@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -202,8 +203,9 @@ func ConvertOpenAIResponseToGemini(_ context.Context, _ string, originalRequestR
 
 			// Handle finish reason
 			if finishReason := choice.Get("finish_reason"); finishReason.Exists() {
-				geminiFinishReason := mapOpenAIFinishReasonToGemini(finishReason.String())
-				template, _ = sjson.SetBytes(template, "candidates.0.finishReason", geminiFinishReason)
+				if geminiFinishReason := util.MapFinishReason(util.FinishReasonOpenAI, util.FinishReasonGemini, finishReason.String()); geminiFinishReason != "" {
+					template, _ = sjson.SetBytes(template, "candidates.0.finishReason", geminiFinishReason)
+				}
 
 				// If we have accumulated tool calls, output them now
 				if len((*param).(*ConvertOpenAIResponseToGeminiParams).ToolCallsAccumulator) > 0 {
@@ -242,22 +244,6 @@ func ConvertOpenAIResponseToGemini(_ context.Context, _ string, originalRequestR
 	return [][]byte{}
 }
 
-// mapOpenAIFinishReasonToGemini maps OpenAI finish reasons to Gemini finish reasons
-func mapOpenAIFinishReasonToGemini(openAIReason string) string {
-	switch openAIReason {
-	case "stop":
-		return "STOP"
-	case "length":
-		return "MAX_TOKENS"
-	case "tool_calls":
-		return "STOP" // Gemini doesn't have a specific tool_calls finish reason
-	case "content_filter":
-		return "SAFETY"
-	default:
-		return "STOP"
-	}
-}
-
 // parseArgsToObjectRaw safely parses a JSON string of function arguments into an object JSON string.
 // It returns "{}" if the input is empty or cannot be parsed as a JSON object.
 func parseArgsToObjectRaw(argsStr string) string {
@@ -596,8 +582,9 @@ func ConvertOpenAIResponseToGeminiNonStream(_ context.Context, _ string, origina
 
 			// Handle finish reason
 			if finishReason := choice.Get("finish_reason"); finishReason.Exists() {
-				geminiFinishReason := mapOpenAIFinishReasonToGemini(finishReason.String())
-				out, _ = sjson.SetBytes(out, "candidates.0.finishReason", geminiFinishReason)
+				if geminiFinishReason := util.MapFinishReason(util.FinishReasonOpenAI, util.FinishReasonGemini, finishReason.String()); geminiFinishReason != "" {
+					out, _ = sjson.SetBytes(out, "candidates.0.finishReason", geminiFinishReason)
+				}
 			}
 
 			// Set index
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	translatorcommon "github.com/router-for-me/CLIProxyAPI/v7/internal/translator/common"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
@@ -890,5 +891,7 @@ func ConvertOpenAIChatCompletionsResponseToOpenAIResponsesNonStream(_ context.Co
 		}
 	}
 
+	resp = translatorcommon.SetResponsesIncomplete(resp, util.FinishReasonOpenAI, root.Get("choices.0.finish_reason").String())
+
 	return resp
 }
@@ -0,0 +1,38 @@
+package usagestats
+
+import (
+	"context"
+	"strings"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+func init() {
+	coreusage.RegisterPlugin(&usageStatsPlugin{})
+}
+
+// usageStatsPlugin feeds usage records into the default aggregator.
+type usageStatsPlugin struct{}
+
+func (p *usageStatsPlugin) HandleUsage(_ context.Context, record coreusage.Record) {
+	aggregator := Default()
+	if p == nil || aggregator == nil {
+		return
+	}
+	tokens := record.Detail.TotalTokens
+	if tokens <= 0 {
+		tokens = record.Detail.InputTokens + record.Detail.OutputTokens
+	}
+	var failure *ErrorEntry
+	if record.Failed {
+		failure = &ErrorEntry{
+			Timestamp:  record.RequestedAt,
+			Provider:   strings.TrimSpace(record.Provider),
+			AuthID:     strings.TrimSpace(record.AuthID),
+			AuthIndex:  strings.TrimSpace(record.AuthIndex),
+			StatusCode: record.Fail.StatusCode,
+			Message:    strings.TrimSpace(record.Fail.Body),
+		}
+	}
+	aggregator.Record(record.Model, tokens, failure)
+}
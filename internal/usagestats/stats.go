@@ -0,0 +1,154 @@
+// Package usagestats keeps in-memory request counters per model and a short list of the
+// most recent failed requests, fed by usage records, for the management status page.
+package usagestats
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxErrors is the number of recent errors kept by the default aggregator.
+	DefaultMaxErrors = 50
+	// maxErrorMessageBytes truncates the upstream error bodies kept as messages.
+	maxErrorMessageBytes = 512
+)
+
+// ModelStats counts the requests served for one model.
+type ModelStats struct {
+	Model       string `json:"model"`
+	Requests    int64  `json:"requests"`
+	Failed      int64  `json:"failed"`
+	TotalTokens int64  `json:"total_tokens"`
+}
+
+// ErrorEntry describes a failed upstream request.
+type ErrorEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Provider   string    `json:"provider,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	AuthID     string    `json:"auth_id,omitempty"`
+	AuthIndex  string    `json:"auth_index,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Message    string    `json:"message,omitempty"`
+}
+
+// Aggregator counts requests per model and keeps the most recent errors.
+type Aggregator struct {
+	mu        sync.Mutex
+	since     time.Time
+	models    map[string]*ModelStats
+	errors    []ErrorEntry
+	next      int
+	maxErrors int
+}
+
+// NewAggregator returns an aggregator keeping up to maxErrors recent errors.
+func NewAggregator(maxErrors int) *Aggregator {
+	if maxErrors <= 0 {
+		maxErrors = DefaultMaxErrors
+	}
+	return &Aggregator{
+		since:     time.Now(),
+		models:    make(map[string]*ModelStats),
+		maxErrors: maxErrors,
+	}
+}
+
+var defaultAggregator = NewAggregator(DefaultMaxErrors)
+
+// Default returns the process-wide aggregator fed by the usage plugin.
+func Default() *Aggregator { return defaultAggregator }
+
+// Record counts one request for model. A non-nil failure is also kept as a recent error.
+func (a *Aggregator) Record(model string, tokens int64, failure *ErrorEntry) {
+	if a == nil {
+		return
+	}
+	model = strings.TrimSpace(model)
+	if model == "" {
+		model = "unknown"
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := a.models[model]
+	if stats == nil {
+		stats = &ModelStats{Model: model}
+		a.models[model] = stats
+	}
+	stats.Requests++
+	stats.TotalTokens += tokens
+	if failure == nil {
+		return
+	}
+	stats.Failed++
+	entry := *failure
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.Model == "" {
+		entry.Model = model
+	}
+	if len(entry.Message) > maxErrorMessageBytes {
+		entry.Message = strings.ToValidUTF8(entry.Message[:maxErrorMessageBytes], "")
+	}
+	if len(a.errors) < a.maxErrors {
+		a.errors = append(a.errors, entry)
+		return
+	}
+	a.errors[a.next] = entry
+	a.next = (a.next + 1) % a.maxErrors
+}
+
+// Snapshot is a point-in-time copy of the aggregator.
+type Snapshot struct {
+	// Since is when counting started.
+	Since time.Time `json:"since"`
+	// Models is sorted by request count, busiest first.
+	Models []ModelStats `json:"models"`
+	// RecentErrors is sorted newest first.
+	RecentErrors []ErrorEntry `json:"recent_errors"`
+}
+
+// Snapshot returns a copy of the counters and recent errors.
+func (a *Aggregator) Snapshot() Snapshot {
+	if a == nil {
+		return Snapshot{Models: []ModelStats{}, RecentErrors: []ErrorEntry{}}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := Snapshot{
+		Since:        a.since,
+		Models:       make([]ModelStats, 0, len(a.models)),
+		RecentErrors: make([]ErrorEntry, 0, len(a.errors)),
+	}
+	for _, stats := range a.models {
+		out.Models = append(out.Models, *stats)
+	}
+	sort.Slice(out.Models, func(i, j int) bool {
+		if out.Models[i].Requests != out.Models[j].Requests {
+			return out.Models[i].Requests > out.Models[j].Requests
+		}
+		return out.Models[i].Model < out.Models[j].Model
+	})
+	// a.next is the oldest entry once the ring is full.
+	for i := len(a.errors) - 1; i >= 0; i-- {
+		out.RecentErrors = append(out.RecentErrors, a.errors[(a.next+i)%len(a.errors)])
+	}
+	return out
+}
+
+// Reset clears all counters and errors and restarts counting now.
+func (a *Aggregator) Reset() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.since = time.Now()
+	a.models = make(map[string]*ModelStats)
+	a.errors = nil
+	a.next = 0
+}
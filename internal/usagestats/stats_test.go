@@ -0,0 +1,91 @@
+package usagestats
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+func TestAggregatorCountsModelsBusiestFirst(t *testing.T) {
+	a := NewAggregator(10)
+	a.Record("gemini-2.5-pro", 10, nil)
+	a.Record("claude-sonnet-4", 5, nil)
+	a.Record("gemini-2.5-pro", 7, &ErrorEntry{StatusCode: 429})
+	a.Record(" ", 0, nil)
+
+	snapshot := a.Snapshot()
+	if len(snapshot.Models) != 3 {
+		t.Fatalf("models = %+v, want 3 entries", snapshot.Models)
+	}
+	want := ModelStats{Model: "gemini-2.5-pro", Requests: 2, Failed: 1, TotalTokens: 17}
+	if snapshot.Models[0] != want {
+		t.Fatalf("models[0] = %+v, want %+v", snapshot.Models[0], want)
+	}
+	if snapshot.Models[1].Model != "claude-sonnet-4" || snapshot.Models[2].Model != "unknown" {
+		t.Fatalf("models = %+v, want ties ordered by name", snapshot.Models)
+	}
+	if len(snapshot.RecentErrors) != 1 || snapshot.RecentErrors[0].Model != "gemini-2.5-pro" {
+		t.Fatalf("recent errors = %+v, want the failed gemini request", snapshot.RecentErrors)
+	}
+}
+
+func TestAggregatorKeepsNewestErrors(t *testing.T) {
+	a := NewAggregator(3)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		a.Record("m", 0, &ErrorEntry{Timestamp: base.Add(time.Duration(i) * time.Minute), StatusCode: 500 + i})
+	}
+
+	errs := a.Snapshot().RecentErrors
+	if len(errs) != 3 {
+		t.Fatalf("recent errors = %d, want 3", len(errs))
+	}
+	for i, want := range []int{504, 503, 502} {
+		if errs[i].StatusCode != want {
+			t.Fatalf("recent errors[%d] = %d, want %d", i, errs[i].StatusCode, want)
+		}
+	}
+
+	a.Reset()
+	if snapshot := a.Snapshot(); len(snapshot.Models) != 0 || len(snapshot.RecentErrors) != 0 {
+		t.Fatalf("snapshot after reset = %+v, want empty", snapshot)
+	}
+}
+
+func TestUsageStatsPluginRecordsFailures(t *testing.T) {
+	Default().Reset()
+	t.Cleanup(Default().Reset)
+
+	plugin := &usageStatsPlugin{}
+	plugin.HandleUsage(context.Background(), coreusage.Record{
+		Provider: "codex",
+		Model:    "gpt-5",
+		Detail:   coreusage.Detail{InputTokens: 3, OutputTokens: 4},
+	})
+	plugin.HandleUsage(context.Background(), coreusage.Record{
+		Provider:  "codex",
+		Model:     "gpt-5",
+		AuthID:    "codex-auth",
+		AuthIndex: "1",
+		Failed:    true,
+		Fail:      coreusage.Failure{StatusCode: 503, Body: strings.Repeat("x", 2*maxErrorMessageBytes)},
+	})
+
+	snapshot := Default().Snapshot()
+	if len(snapshot.Models) != 1 || snapshot.Models[0].Requests != 2 || snapshot.Models[0].Failed != 1 || snapshot.Models[0].TotalTokens != 7 {
+		t.Fatalf("models = %+v, want 2 gpt-5 requests with 1 failure and 7 tokens", snapshot.Models)
+	}
+	if len(snapshot.RecentErrors) != 1 {
+		t.Fatalf("recent errors = %+v, want 1", snapshot.RecentErrors)
+	}
+	got := snapshot.RecentErrors[0]
+	if got.Provider != "codex" || got.AuthID != "codex-auth" || got.StatusCode != 503 || len(got.Message) != maxErrorMessageBytes {
+		t.Fatalf("recent error = %+v, want the codex 503 with a truncated message", got)
+	}
+	if got.Timestamp.IsZero() {
+		t.Fatal("recent error has no timestamp")
+	}
+}
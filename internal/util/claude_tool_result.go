@@ -1,16 +1,24 @@
 package util
 
 import (
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 )
 
-// ClaudeToolResultImage represents a base64-encoded image extracted from a Claude
-// tool_result content block. Callers emit it as a provider-specific inline data
-// part so that image bytes do not bloat the textual function response result.
+// ClaudeToolResultImage represents an image extracted from a Claude tool_result
+// content block. Callers emit it as a provider-specific inline data part, or a file
+// data part when URL is set, so the image does not bloat the textual function
+// response result.
 type ClaudeToolResultImage struct {
 	MimeType string
 	Data     string
+	// URL is set instead of Data for images with a "url" source.
+	URL string
 }
 
 // ClaudeToolResult is the normalized form of a Claude tool_result `content` field,
@@ -23,7 +31,7 @@ type ClaudeToolResult struct {
 	// sjson.Set as a string value would double-encode it, so callers must honor
 	// this flag.
 	ResultIsRaw bool
-	// Images holds base64 image blocks separated out of the content.
+	// Images holds base64 and URL image blocks separated out of the content.
 	Images []ClaudeToolResultImage
 }
 
@@ -41,6 +49,7 @@ type ClaudeToolResult struct {
 //   - single non-image   -> raw JSON result (structure preserved)
 //   - multiple non-image -> raw JSON array result
 //   - base64 image block -> separated into Images (emitted as inline data parts)
+//   - URL image block    -> separated into Images with URL set (emitted as file data parts)
 //   - object             -> raw JSON result, or image -> Images with empty result
 //   - absent/empty       -> empty string result
 //
@@ -56,7 +65,7 @@ func ConvertClaudeToolResultContent(content gjson.Result) ClaudeToolResult {
 		lastNonImageRaw := ""
 		filtered := []byte(`[]`)
 		content.ForEach(func(_, block gjson.Result) bool {
-			if isClaudeBase64Image(block) {
+			if isClaudeImage(block) {
 				if img, ok := claudeImageFromBlock(block); ok {
 					images = append(images, img)
 				}
@@ -76,7 +85,7 @@ func ConvertClaudeToolResultContent(content gjson.Result) ClaudeToolResult {
 			return ClaudeToolResult{Images: images}
 		}
 	case content.IsObject():
-		if isClaudeBase64Image(content) {
+		if isClaudeImage(content) {
 			if img, ok := claudeImageFromBlock(content); ok {
 				return ClaudeToolResult{Images: []ClaudeToolResultImage{img}}
 			}
@@ -90,14 +99,25 @@ func ConvertClaudeToolResultContent(content gjson.Result) ClaudeToolResult {
 	}
 }
 
-// isClaudeBase64Image reports whether a content block is a base64-encoded image block.
-func isClaudeBase64Image(block gjson.Result) bool {
-	return block.Get("type").String() == "image" && block.Get("source.type").String() == "base64"
+// isClaudeImage reports whether a content block is a base64 or URL image block.
+func isClaudeImage(block gjson.Result) bool {
+	if block.Get("type").String() != "image" {
+		return false
+	}
+	sourceType := block.Get("source.type").String()
+	return sourceType == "base64" || sourceType == "url"
 }
 
-// claudeImageFromBlock extracts image data from a base64 image block. It returns false
-// when the block carries no base64 data, so empty inline data parts are not emitted.
+// claudeImageFromBlock extracts image data from a base64 or URL image block. It returns
+// false when the block carries no data or URL, so empty parts are not emitted.
 func claudeImageFromBlock(block gjson.Result) (ClaudeToolResultImage, bool) {
+	if block.Get("source.type").String() == "url" {
+		imageURL := strings.TrimSpace(block.Get("source.url").String())
+		if imageURL == "" {
+			return ClaudeToolResultImage{}, false
+		}
+		return ClaudeToolResultImage{MimeType: claudeImageURLMimeType(imageURL), URL: imageURL}, true
+	}
 	data := block.Get("source.data").String()
 	if data == "" {
 		return ClaudeToolResultImage{}, false
@@ -107,3 +127,14 @@ func claudeImageFromBlock(block gjson.Result) (ClaudeToolResultImage, bool) {
 		Data:     data,
 	}, true
 }
+
+// claudeImageURLMimeType infers an image MIME type from the URL path extension,
+// defaulting to image/jpeg because URL image sources carry no media type.
+func claudeImageURLMimeType(imageURL string) string {
+	if parsed, errParse := url.Parse(imageURL); errParse == nil {
+		if mimeType := mime.TypeByExtension(strings.ToLower(path.Ext(parsed.Path))); strings.HasPrefix(mimeType, "image/") {
+			return mimeType
+		}
+	}
+	return "image/jpeg"
+}
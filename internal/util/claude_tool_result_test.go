@@ -56,6 +56,20 @@ func TestConvertClaudeToolResultContent(t *testing.T) {
 			wantRaw:    false,
 			wantImages: 0,
 		},
+		{
+			name:       "TextAndURLImage",
+			wrapper:    `{"content":[{"type":"text","text":"alpha"},{"type":"image","source":{"type":"url","url":"https://example.com/a.png"}}]}`,
+			wantResult: `{"type":"text","text":"alpha"}`,
+			wantRaw:    true,
+			wantImages: 1,
+		},
+		{
+			name:       "URLImageWithoutURLDropped",
+			wrapper:    `{"content":[{"type":"image","source":{"type":"url"}}]}`,
+			wantResult: "",
+			wantRaw:    false,
+			wantImages: 0,
+		},
 		{
 			name:       "ObjectContent",
 			wrapper:    `{"content":{"foo":"bar"}}`,
@@ -108,3 +122,20 @@ func TestConvertClaudeToolResultContent_ImageFields(t *testing.T) {
 		t.Errorf("Data = %q, want aGVsbG8=", got.Images[0].Data)
 	}
 }
+
+func TestConvertClaudeToolResultContent_URLImageFields(t *testing.T) {
+	content := gjson.Get(`{"content":[{"type":"image","source":{"type":"url","url":"https://example.com/a.PNG?size=large"}},{"type":"image","source":{"type":"url","url":"https://example.com/render"}}]}`, "content")
+	got := ConvertClaudeToolResultContent(content)
+	if len(got.Images) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(got.Images))
+	}
+	if got.Images[0].URL != "https://example.com/a.PNG?size=large" || got.Images[0].Data != "" {
+		t.Errorf("Images[0] = %+v, want URL reference without data", got.Images[0])
+	}
+	if got.Images[0].MimeType != "image/png" {
+		t.Errorf("Images[0].MimeType = %q, want image/png", got.Images[0].MimeType)
+	}
+	if got.Images[1].MimeType != "image/jpeg" {
+		t.Errorf("Images[1].MimeType = %q, want image/jpeg fallback", got.Images[1].MimeType)
+	}
+}
@@ -0,0 +1,181 @@
+package util
+
+import "strings"
+
+// FinishReasonFormat names the vocabulary a finish reason is expressed in.
+type FinishReasonFormat string
+
+const (
+	// FinishReasonOpenAI is Chat Completions choices[].finish_reason.
+	FinishReasonOpenAI FinishReasonFormat = "openai"
+	// FinishReasonClaude is Messages API stop_reason.
+	FinishReasonClaude FinishReasonFormat = "claude"
+	// FinishReasonGemini is generateContent candidates[].finishReason; Antigravity uses it too.
+	FinishReasonGemini FinishReasonFormat = "gemini"
+	// FinishReasonCodex is Responses API incomplete_details.reason. An empty
+	// value means the response completed normally.
+	FinishReasonCodex FinishReasonFormat = "codex"
+)
+
+// finishKind is the provider-neutral meaning of a finish reason.
+type finishKind int
+
+const (
+	finishStop finishKind = iota
+	finishStopSequence
+	finishMaxTokens
+	finishToolUse
+	finishSafety
+	finishPause
+	finishMalformedToolCall
+	finishOther
+)
+
+// finishReasonKinds classifies every known upstream reason per source format.
+// Reasons missing from a table are treated as finishOther.
+var finishReasonKinds = map[FinishReasonFormat]map[string]finishKind{
+	FinishReasonOpenAI: {
+		"stop":           finishStop,
+		"length":         finishMaxTokens,
+		"tool_calls":     finishToolUse,
+		"function_call":  finishToolUse,
+		"content_filter": finishSafety,
+	},
+	FinishReasonClaude: {
+		"end_turn":      finishStop,
+		"stop_sequence": finishStopSequence,
+		"max_tokens":    finishMaxTokens,
+		"tool_use":      finishToolUse,
+		"refusal":       finishSafety,
+		"pause_turn":    finishPause,
+	},
+	FinishReasonGemini: {
+		"STOP":                      finishStop,
+		"FINISH_REASON_UNSPECIFIED": finishStop,
+		"MAX_TOKENS":                finishMaxTokens,
+		"SAFETY":                    finishSafety,
+		"RECITATION":                finishSafety,
+		"BLOCKLIST":                 finishSafety,
+		"PROHIBITED_CONTENT":        finishSafety,
+		"SPII":                      finishSafety,
+		"IMAGE_SAFETY":              finishSafety,
+		"IMAGE_PROHIBITED_CONTENT":  finishSafety,
+		"MALFORMED_FUNCTION_CALL":   finishMalformedToolCall,
+		"UNEXPECTED_TOOL_CALL":      finishMalformedToolCall,
+		"TOO_MANY_TOOL_CALLS":       finishMalformedToolCall,
+		"LANGUAGE":                  finishOther,
+		"OTHER":                     finishOther,
+		"UNKNOWN":                   finishStop,
+		"IMAGE_OTHER":               finishOther,
+		"NO_IMAGE":                  finishOther,
+	},
+	FinishReasonCodex: {
+		"":                  finishStop,
+		"completed":         finishStop,
+		"max_output_tokens": finishMaxTokens,
+		"max_tokens":        finishMaxTokens,
+		"content_filter":    finishSafety,
+	},
+}
+
+// finishReasonValues is the reason each target format reports for a kind.
+var finishReasonValues = map[FinishReasonFormat]map[finishKind]string{
+	FinishReasonOpenAI: {
+		finishStop:              "stop",
+		finishStopSequence:      "stop",
+		finishMaxTokens:         "length",
+		finishToolUse:           "tool_calls",
+		finishSafety:            "content_filter",
+		finishPause:             "stop",
+		finishMalformedToolCall: "stop",
+		finishOther:             "stop",
+	},
+	FinishReasonClaude: {
+		finishStop:              "end_turn",
+		finishStopSequence:      "stop_sequence",
+		finishMaxTokens:         "max_tokens",
+		finishToolUse:           "tool_use",
+		finishSafety:            "refusal",
+		finishPause:             "pause_turn",
+		finishMalformedToolCall: "end_turn",
+		finishOther:             "end_turn",
+	},
+	FinishReasonGemini: {
+		finishStop:              "STOP",
+		finishStopSequence:      "STOP",
+		finishMaxTokens:         "MAX_TOKENS",
+		finishToolUse:           "STOP", // Gemini has no tool-call finish reason.
+		finishSafety:            "SAFETY",
+		finishPause:             "STOP",
+		finishMalformedToolCall: "MALFORMED_FUNCTION_CALL",
+		finishOther:             "OTHER",
+	},
+	FinishReasonCodex: {
+		finishStop:              "",
+		finishStopSequence:      "",
+		finishMaxTokens:         "max_output_tokens",
+		finishToolUse:           "",
+		finishSafety:            "content_filter",
+		finishPause:             "",
+		finishMalformedToolCall: "",
+		finishOther:             "",
+	},
+}
+
+// MapFinishReason translates an upstream finish reason from one format's
+// vocabulary into another's. Safety stops become content_filter for OpenAI and
+// refusal for Claude; unknown reasons map to the target's plain stop value
+// (OTHER for Gemini). An empty reason from any format other than codex returns
+// "", since the upstream has not finished yet.
+func MapFinishReason(from, to FinishReasonFormat, reason string) string {
+	return MapFinishReasonWithToolCalls(from, to, reason, false)
+}
+
+// MapFinishReasonWithToolCalls is MapFinishReason for responses that emitted
+// tool calls. Formats like Gemini finish tool turns with a plain stop, so stop
+// and length stops become the target's tool-use reason when sawToolCall is
+// true, letting clients run the calls. Safety stops are kept.
+func MapFinishReasonWithToolCalls(from, to FinishReasonFormat, reason string, sawToolCall bool) string {
+	reason = strings.TrimSpace(reason)
+	if reason == "" && from != FinishReasonCodex {
+		return ""
+	}
+	kind := finishReasonKind(from, reason)
+	if sawToolCall && (kind == finishStop || kind == finishStopSequence || kind == finishMaxTokens) {
+		kind = finishToolUse
+	}
+	values, ok := finishReasonValues[to]
+	if !ok {
+		return reason
+	}
+	return values[kind]
+}
+
+// NormalizeFinishReason returns reason spelled the way format spells it, such
+// as STOP for a Gemini "stop". Unknown reasons are returned trimmed but
+// otherwise unchanged, so the upstream value stays visible.
+func NormalizeFinishReason(format FinishReasonFormat, reason string) string {
+	reason = strings.TrimSpace(reason)
+	if _, ok := finishReasonKinds[format][canonicalFinishReasonCase(format, reason)]; ok {
+		return canonicalFinishReasonCase(format, reason)
+	}
+	return reason
+}
+
+func finishReasonKind(from FinishReasonFormat, reason string) finishKind {
+	kinds := finishReasonKinds[from]
+	if kind, ok := kinds[reason]; ok {
+		return kind
+	}
+	if kind, ok := kinds[canonicalFinishReasonCase(from, reason)]; ok {
+		return kind
+	}
+	return finishOther
+}
+
+func canonicalFinishReasonCase(format FinishReasonFormat, reason string) string {
+	if format == FinishReasonGemini {
+		return strings.ToUpper(reason)
+	}
+	return strings.ToLower(reason)
+}
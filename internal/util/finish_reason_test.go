@@ -0,0 +1,126 @@
+package util
+
+import "testing"
+
+func TestMapFinishReason(t *testing.T) {
+	// want lists the mapped reason for the openai, claude, gemini and codex
+	// targets, in that order.
+	tests := []struct {
+		from   FinishReasonFormat
+		reason string
+		want   [4]string
+	}{
+		{FinishReasonOpenAI, "stop", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonOpenAI, "length", [4]string{"length", "max_tokens", "MAX_TOKENS", "max_output_tokens"}},
+		{FinishReasonOpenAI, "tool_calls", [4]string{"tool_calls", "tool_use", "STOP", ""}},
+		{FinishReasonOpenAI, "function_call", [4]string{"tool_calls", "tool_use", "STOP", ""}},
+		{FinishReasonOpenAI, "content_filter", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+
+		{FinishReasonClaude, "end_turn", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonClaude, "stop_sequence", [4]string{"stop", "stop_sequence", "STOP", ""}},
+		{FinishReasonClaude, "max_tokens", [4]string{"length", "max_tokens", "MAX_TOKENS", "max_output_tokens"}},
+		{FinishReasonClaude, "tool_use", [4]string{"tool_calls", "tool_use", "STOP", ""}},
+		{FinishReasonClaude, "refusal", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonClaude, "pause_turn", [4]string{"stop", "pause_turn", "STOP", ""}},
+
+		{FinishReasonGemini, "STOP", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonGemini, "FINISH_REASON_UNSPECIFIED", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonGemini, "UNKNOWN", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonGemini, "MAX_TOKENS", [4]string{"length", "max_tokens", "MAX_TOKENS", "max_output_tokens"}},
+		{FinishReasonGemini, "SAFETY", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "RECITATION", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "BLOCKLIST", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "PROHIBITED_CONTENT", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "SPII", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "IMAGE_SAFETY", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "IMAGE_PROHIBITED_CONTENT", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+		{FinishReasonGemini, "MALFORMED_FUNCTION_CALL", [4]string{"stop", "end_turn", "MALFORMED_FUNCTION_CALL", ""}},
+		{FinishReasonGemini, "UNEXPECTED_TOOL_CALL", [4]string{"stop", "end_turn", "MALFORMED_FUNCTION_CALL", ""}},
+		{FinishReasonGemini, "TOO_MANY_TOOL_CALLS", [4]string{"stop", "end_turn", "MALFORMED_FUNCTION_CALL", ""}},
+		{FinishReasonGemini, "LANGUAGE", [4]string{"stop", "end_turn", "OTHER", ""}},
+		{FinishReasonGemini, "OTHER", [4]string{"stop", "end_turn", "OTHER", ""}},
+		{FinishReasonGemini, "IMAGE_OTHER", [4]string{"stop", "end_turn", "OTHER", ""}},
+		{FinishReasonGemini, "NO_IMAGE", [4]string{"stop", "end_turn", "OTHER", ""}},
+		{FinishReasonGemini, "max_tokens", [4]string{"length", "max_tokens", "MAX_TOKENS", "max_output_tokens"}},
+
+		{FinishReasonCodex, "", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonCodex, "completed", [4]string{"stop", "end_turn", "STOP", ""}},
+		{FinishReasonCodex, "max_output_tokens", [4]string{"length", "max_tokens", "MAX_TOKENS", "max_output_tokens"}},
+		{FinishReasonCodex, "max_tokens", [4]string{"length", "max_tokens", "MAX_TOKENS", "max_output_tokens"}},
+		{FinishReasonCodex, "content_filter", [4]string{"content_filter", "refusal", "SAFETY", "content_filter"}},
+	}
+	targets := [4]FinishReasonFormat{FinishReasonOpenAI, FinishReasonClaude, FinishReasonGemini, FinishReasonCodex}
+
+	for _, tt := range tests {
+		for i, to := range targets {
+			t.Run(string(tt.from)+"/"+tt.reason+"/"+string(to), func(t *testing.T) {
+				if got := MapFinishReason(tt.from, to, tt.reason); got != tt.want[i] {
+					t.Fatalf("MapFinishReason(%s, %s, %q) = %q, want %q", tt.from, to, tt.reason, got, tt.want[i])
+				}
+			})
+		}
+	}
+}
+
+func TestMapFinishReasonEmptyAndUnknown(t *testing.T) {
+	if got := MapFinishReason(FinishReasonGemini, FinishReasonOpenAI, ""); got != "" {
+		t.Fatalf("empty Gemini reason = %q, want empty while the stream is still running", got)
+	}
+	if got := MapFinishReason(FinishReasonOpenAI, FinishReasonClaude, "  "); got != "" {
+		t.Fatalf("blank OpenAI reason = %q, want empty", got)
+	}
+	if got := MapFinishReason(FinishReasonOpenAI, FinishReasonClaude, "something_new"); got != "end_turn" {
+		t.Fatalf("unknown reason = %q, want end_turn", got)
+	}
+	if got := MapFinishReason(FinishReasonClaude, FinishReasonGemini, "something_new"); got != "OTHER" {
+		t.Fatalf("unknown reason = %q, want OTHER", got)
+	}
+	if got := MapFinishReason(FinishReasonClaude, FinishReasonFormat("other"), "end_turn"); got != "end_turn" {
+		t.Fatalf("unknown target = %q, want the reason unchanged", got)
+	}
+}
+
+func TestMapFinishReasonWithToolCalls(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   FinishReasonFormat
+		to     FinishReasonFormat
+		reason string
+		want   string
+	}{
+		{"gemini stop becomes tool_calls", FinishReasonGemini, FinishReasonOpenAI, "STOP", "tool_calls"},
+		{"gemini stop becomes tool_use", FinishReasonGemini, FinishReasonClaude, "STOP", "tool_use"},
+		{"claude stop_sequence becomes tool_calls", FinishReasonClaude, FinishReasonOpenAI, "stop_sequence", "tool_calls"},
+		{"max tokens becomes tool_calls", FinishReasonGemini, FinishReasonOpenAI, "MAX_TOKENS", "tool_calls"},
+		{"safety is kept", FinishReasonGemini, FinishReasonClaude, "SAFETY", "refusal"},
+		{"empty stays empty", FinishReasonGemini, FinishReasonOpenAI, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapFinishReasonWithToolCalls(tt.from, tt.to, tt.reason, true); got != tt.want {
+				t.Fatalf("MapFinishReasonWithToolCalls(%s, %s, %q, true) = %q, want %q", tt.from, tt.to, tt.reason, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFinishReason(t *testing.T) {
+	tests := []struct {
+		format FinishReasonFormat
+		reason string
+		want   string
+	}{
+		{FinishReasonGemini, "stop", "STOP"},
+		{FinishReasonGemini, " max_tokens ", "MAX_TOKENS"},
+		{FinishReasonGemini, "SomethingNew", "SomethingNew"},
+		{FinishReasonOpenAI, "LENGTH", "length"},
+		{FinishReasonClaude, "End_Turn", "end_turn"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeFinishReason(tt.format, tt.reason); got != tt.want {
+			t.Fatalf("NormalizeFinishReason(%s, %q) = %q, want %q", tt.format, tt.reason, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// IsGemini3Model reports whether model names a Gemini 3 or later model, which
+// take a discrete thinkingLevel instead of a thinkingBudget. It is a name-based
+// heuristic for models missing from the registry; registered models should be
+// classified by their ThinkingSupport.Levels instead.
+func IsGemini3Model(model string) bool {
+	lower := strings.ToLower(strings.TrimSpace(model))
+	if idx := strings.LastIndex(lower, "/"); idx >= 0 {
+		lower = lower[idx+1:]
+	}
+	rest, ok := strings.CutPrefix(lower, "gemini-")
+	if !ok {
+		return false
+	}
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return false
+	}
+	major, errParse := strconv.Atoi(rest[:end])
+	return errParse == nil && major >= 3
+}
@@ -0,0 +1,24 @@
+package util
+
+import "testing"
+
+func TestIsGemini3Model(t *testing.T) {
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{model: "gemini-3-pro-preview", want: true},
+		{model: "gemini-3.1-flash-lite", want: true},
+		{model: "models/gemini-3-flash", want: true},
+		{model: "Gemini-4-ultra", want: true},
+		{model: "gemini-2.5-pro", want: false},
+		{model: "gemini-exp-1206", want: false},
+		{model: "rev19-uic3-1p", want: false},
+		{model: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsGemini3Model(tt.model); got != tt.want {
+			t.Errorf("IsGemini3Model(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
@@ -28,6 +28,46 @@ func CleanJSONSchemaForGemini(jsonStr string) string {
 	return cleanJSONSchema(jsonStr, false)
 }
 
+// TightenStrictJSONSchema applies OpenAI strict tool semantics to a schema bound for a platform
+// that cannot enforce them: every object schema requires all of its properties and forbids extra
+// ones. Run it before the Gemini/Antigravity cleaners, which keep the required lists and turn
+// additionalProperties:false into a "No extra properties allowed" description hint.
+func TightenStrictJSONSchema(jsonStr string) string {
+	paths := findPaths(jsonStr, "properties")
+	sortByDepth(paths)
+	for _, p := range paths {
+		parentPath := trimSuffix(p, ".properties")
+		// A property literally named "properties" is not a keyword.
+		if isPropertyDefinition(parentPath) {
+			continue
+		}
+		props := gjson.Get(jsonStr, p)
+		if !props.IsObject() {
+			continue
+		}
+
+		additionalPath := joinPath(parentPath, "additionalProperties")
+		if !gjson.Get(jsonStr, additionalPath).Exists() {
+			jsonStr, _ = sjson.Set(jsonStr, additionalPath, false)
+		}
+
+		requiredPath := joinPath(parentPath, "required")
+		required := getStrings(jsonStr, requiredPath)
+		changed := false
+		props.ForEach(func(key, _ gjson.Result) bool {
+			if !contains(required, key.String()) {
+				required = append(required, key.String())
+				changed = true
+			}
+			return true
+		})
+		if changed {
+			jsonStr, _ = sjson.Set(jsonStr, requiredPath, required)
+		}
+	}
+	return jsonStr
+}
+
 // cleanJSONSchema performs the core cleaning operations on the JSON schema.
 func cleanJSONSchema(jsonStr string, addPlaceholder bool) string {
 	// Phase 1: Convert and add hints
@@ -1089,3 +1089,52 @@ func TestCleanJSONSchemaForAntigravity_UniqueItemsStripped(t *testing.T) {
 		t.Errorf("uniqueItems hint missing in description")
 	}
 }
+
+func TestTightenStrictJSONSchema_RequiresAllPropertiesAndForbidsExtras(t *testing.T) {
+	input := `{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string"},
+			"unit": {"type": ["string", "null"], "enum": ["c", "f", null]},
+			"options": {
+				"type": "object",
+				"properties": {"verbose": {"type": "boolean"}},
+				"additionalProperties": true
+			},
+			"stops": {
+				"type": "array",
+				"items": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}
+			}
+		},
+		"required": ["city"]
+	}`
+
+	result := TightenStrictJSONSchema(input)
+
+	if got := getStrings(result, "required"); !reflect.DeepEqual(got, []string{"city", "unit", "options", "stops"}) {
+		t.Fatalf("required = %v, want every property with the client order first", got)
+	}
+	if gjson.Get(result, "additionalProperties").Type != gjson.False {
+		t.Fatalf("root additionalProperties = %s, want false", gjson.Get(result, "additionalProperties").Raw)
+	}
+	if gjson.Get(result, "properties.options.additionalProperties").Type != gjson.True {
+		t.Fatalf("explicit additionalProperties must be kept: %s", result)
+	}
+	if got := getStrings(result, "properties.options.required"); !reflect.DeepEqual(got, []string{"verbose"}) {
+		t.Fatalf("nested required = %v, want [verbose]", got)
+	}
+	if gjson.Get(result, "properties.stops.items.additionalProperties").Type != gjson.False {
+		t.Fatalf("array item schema must be tightened: %s", result)
+	}
+
+	cleaned := CleanJSONSchemaForGemini(result)
+	if gjson.Get(cleaned, "additionalProperties").Exists() {
+		t.Fatalf("cleaned schema must not keep additionalProperties: %s", cleaned)
+	}
+	if desc := gjson.Get(cleaned, "description").String(); !strings.Contains(desc, "No extra properties allowed") {
+		t.Fatalf("cleaned root description = %q, want the no-extra-properties hint", desc)
+	}
+	if got := getStrings(cleaned, "required"); !reflect.DeepEqual(got, []string{"city", "unit", "options", "stops"}) {
+		t.Fatalf("cleaned required = %v, want the tightened list", got)
+	}
+}
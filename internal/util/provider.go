@@ -4,6 +4,9 @@
 package util
 
 import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
 	"net/url"
 	"strings"
 
@@ -97,6 +100,101 @@ func ResolveAutoModel(modelName string) string {
 	return firstModel
 }
 
+// AutoModelOptions controls weighted resolution of the "auto" model name.
+type AutoModelOptions struct {
+	// Candidates lists the configured models in preference order.
+	Candidates []config.AutoModelCandidate
+
+	// StickyKey makes the weighted choice deterministic for a conversation. Empty
+	// means every request draws a new random choice.
+	StickyKey string
+
+	// Available reports whether a candidate can currently be served. Nil treats
+	// any model with a registered provider as available.
+	Available func(model string) bool
+}
+
+// ResolveAutoModelWithOptions resolves the "auto" model name using weighted candidates.
+// Unavailable candidates are skipped, and the weighted choice falls through the remaining
+// candidates in configured order. When no candidate is available it behaves like
+// ResolveAutoModel.
+//
+// Parameters:
+//   - modelName: The model name to check (should be "auto")
+//   - opts: Candidate weights, stickiness key and availability check
+//
+// Returns:
+//   - string: The resolved model name, or the original if not "auto" or resolution fails
+func ResolveAutoModelWithOptions(modelName string, opts AutoModelOptions) string {
+	if modelName != "auto" {
+		return modelName
+	}
+	if picked := pickAutoModelCandidate(opts); picked != "" {
+		log.Debugf("Resolved 'auto' model to weighted candidate: %s", picked)
+		return picked
+	}
+	return ResolveAutoModel(modelName)
+}
+
+func pickAutoModelCandidate(opts AutoModelOptions) string {
+	available := opts.Available
+	if available == nil {
+		available = func(model string) bool { return len(GetProviderName(model)) > 0 }
+	}
+
+	names := make([]string, 0, len(opts.Candidates))
+	weights := make([]uint64, 0, len(opts.Candidates))
+	var total uint64
+	for _, candidate := range opts.Candidates {
+		name := strings.TrimSpace(candidate.Name)
+		if name == "" || name == "auto" || !available(name) {
+			continue
+		}
+		weight := uint64(1)
+		if candidate.Weight > 0 {
+			weight = uint64(candidate.Weight)
+		}
+		names = append(names, name)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if total == 0 {
+		return ""
+	}
+
+	if key := strings.TrimSpace(opts.StickyKey); key != "" {
+		return pickStickyAutoModel(key, names, weights)
+	}
+	point := rand.Uint64N(total)
+	for i, weight := range weights {
+		if point < weight {
+			return names[i]
+		}
+		point -= weight
+	}
+	return names[len(names)-1]
+}
+
+// pickStickyAutoModel uses weighted rendezvous hashing, so a conversation only moves
+// to another candidate when its own candidate becomes unavailable.
+func pickStickyAutoModel(key string, names []string, weights []uint64) string {
+	best := ""
+	bestScore := math.Inf(-1)
+	for i, name := range names {
+		hasher := fnv.New64a()
+		_, _ = hasher.Write([]byte(key))
+		_, _ = hasher.Write([]byte{0})
+		_, _ = hasher.Write([]byte(name))
+		// Map the hash into (0, 1) and score it so each candidate wins in proportion to its weight.
+		unit := (float64(hasher.Sum64()>>11) + 0.5) / (1 << 53)
+		score := -float64(weights[i]) / math.Log(unit)
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
+
 // IsOpenAICompatibilityAlias checks if the given model name is an alias
 // configured for OpenAI compatibility routing.
 //
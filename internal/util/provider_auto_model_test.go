@@ -0,0 +1,96 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+)
+
+func allAutoModelsAvailable(string) bool { return true }
+
+func TestResolveAutoModelWithOptions_WeightDistribution(t *testing.T) {
+	opts := AutoModelOptions{
+		Candidates: []config.AutoModelCandidate{
+			{Name: "model-a", Weight: 3},
+			{Name: "model-b", Weight: 1},
+		},
+		Available: allAutoModelsAvailable,
+	}
+	const draws = 20000
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		counts[ResolveAutoModelWithOptions("auto", opts)]++
+	}
+	if len(counts) != 2 {
+		t.Fatalf("resolved models = %v, want only model-a and model-b", counts)
+	}
+	// Expected share of model-a is 0.75; the tolerance is far outside random noise.
+	if share := float64(counts["model-a"]) / draws; share < 0.72 || share > 0.78 {
+		t.Fatalf("model-a share = %.3f (%v), want about 0.75", share, counts)
+	}
+}
+
+func TestResolveAutoModelWithOptions_Sticky(t *testing.T) {
+	available := map[string]bool{"model-a": true, "model-b": true, "model-c": true}
+	opts := AutoModelOptions{
+		Candidates: []config.AutoModelCandidate{
+			{Name: "model-a", Weight: 1},
+			{Name: "model-b", Weight: 1},
+			{Name: "model-c", Weight: 1},
+		},
+		Available: func(model string) bool { return available[model] },
+	}
+
+	seen := map[string]bool{}
+	for session := 0; session < 30; session++ {
+		opts.StickyKey = fmt.Sprintf("session:%d", session)
+		first := ResolveAutoModelWithOptions("auto", opts)
+		seen[first] = true
+		for turn := 0; turn < 5; turn++ {
+			if got := ResolveAutoModelWithOptions("auto", opts); got != first {
+				t.Fatalf("session %d turn %d resolved %q, want sticky %q", session, turn, got, first)
+			}
+		}
+
+		// Losing a different candidate must not move the session.
+		for name := range available {
+			if name == first {
+				continue
+			}
+			available[name] = false
+			if got := ResolveAutoModelWithOptions("auto", opts); got != first {
+				t.Fatalf("session %d moved from %q to %q when %q became unavailable", session, first, got, name)
+			}
+			available[name] = true
+		}
+
+		// Losing its own candidate moves it to one that is still available.
+		available[first] = false
+		if got := ResolveAutoModelWithOptions("auto", opts); got == first || !available[got] {
+			t.Fatalf("session %d resolved %q after %q became unavailable", session, got, first)
+		}
+		available[first] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("sticky sessions all resolved to %v, want them spread across candidates", seen)
+	}
+}
+
+func TestResolveAutoModelWithOptions_SkipsUnavailable(t *testing.T) {
+	opts := AutoModelOptions{
+		Candidates: []config.AutoModelCandidate{
+			{Name: "model-a", Weight: 100},
+			{Name: "model-b"},
+		},
+		Available: func(model string) bool { return model == "model-b" },
+	}
+	for i := 0; i < 50; i++ {
+		if got := ResolveAutoModelWithOptions("auto", opts); got != "model-b" {
+			t.Fatalf("resolved %q, want model-b", got)
+		}
+	}
+	if got := ResolveAutoModelWithOptions("gemini-2.5-pro", opts); got != "gemini-2.5-pro" {
+		t.Fatalf("non-auto model resolved to %q", got)
+	}
+}
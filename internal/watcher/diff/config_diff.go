@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
@@ -27,12 +28,57 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Debug != newCfg.Debug {
 		changes = append(changes, fmt.Sprintf("debug: %t -> %t", oldCfg.Debug, newCfg.Debug))
 	}
+	if !reflect.DeepEqual(oldCfg.SecretReferences, newCfg.SecretReferences) {
+		changes = append(changes, "secret-references: updated")
+	}
 	if oldCfg.Pprof.Enable != newCfg.Pprof.Enable {
 		changes = append(changes, fmt.Sprintf("pprof.enable: %t -> %t", oldCfg.Pprof.Enable, newCfg.Pprof.Enable))
 	}
 	if strings.TrimSpace(oldCfg.Pprof.Addr) != strings.TrimSpace(newCfg.Pprof.Addr) {
 		changes = append(changes, fmt.Sprintf("pprof.addr: %s -> %s", strings.TrimSpace(oldCfg.Pprof.Addr), strings.TrimSpace(newCfg.Pprof.Addr)))
 	}
+	if oldCfg.Metrics.Enable != newCfg.Metrics.Enable {
+		changes = append(changes, fmt.Sprintf("metrics.enable: %t -> %t", oldCfg.Metrics.Enable, newCfg.Metrics.Enable))
+	}
+	if oldCfg.Tracing.Enable != newCfg.Tracing.Enable {
+		changes = append(changes, fmt.Sprintf("tracing.enable: %t -> %t", oldCfg.Tracing.Enable, newCfg.Tracing.Enable))
+	}
+	if strings.TrimSpace(oldCfg.Tracing.Endpoint) != strings.TrimSpace(newCfg.Tracing.Endpoint) {
+		changes = append(changes, fmt.Sprintf("tracing.endpoint: %s -> %s", strings.TrimSpace(oldCfg.Tracing.Endpoint), strings.TrimSpace(newCfg.Tracing.Endpoint)))
+	}
+	if !reflect.DeepEqual(oldCfg.Tracing.Headers, newCfg.Tracing.Headers) {
+		changes = append(changes, "tracing.headers: updated")
+	}
+	if oldCfg.Tracing.SamplingRatio != newCfg.Tracing.SamplingRatio {
+		changes = append(changes, fmt.Sprintf("tracing.sampling-ratio: %g -> %g", oldCfg.Tracing.SamplingRatio, newCfg.Tracing.SamplingRatio))
+	}
+	if strings.TrimSpace(oldCfg.Tracing.ServiceName) != strings.TrimSpace(newCfg.Tracing.ServiceName) {
+		changes = append(changes, fmt.Sprintf("tracing.service-name: %s -> %s", strings.TrimSpace(oldCfg.Tracing.ServiceName), strings.TrimSpace(newCfg.Tracing.ServiceName)))
+	}
+	if oldCfg.Server.MaxRequestBodyBytes != newCfg.Server.MaxRequestBodyBytes {
+		changes = append(changes, fmt.Sprintf("server.max-request-body-bytes: %d -> %d", oldCfg.Server.MaxRequestBodyBytes, newCfg.Server.MaxRequestBodyBytes))
+	}
+	if oldCfg.Server.MaxResponseBodyBytes != newCfg.Server.MaxResponseBodyBytes {
+		changes = append(changes, fmt.Sprintf("server.max-response-body-bytes: %d -> %d", oldCfg.Server.MaxResponseBodyBytes, newCfg.Server.MaxResponseBodyBytes))
+	}
+	if oldCfg.Server.ShutdownDrainSeconds != newCfg.Server.ShutdownDrainSeconds {
+		changes = append(changes, fmt.Sprintf("server.shutdown-drain-seconds: %d -> %d", oldCfg.Server.ShutdownDrainSeconds, newCfg.Server.ShutdownDrainSeconds))
+	}
+	if oldCfg.Server.ExposeAuthHeader != newCfg.Server.ExposeAuthHeader {
+		changes = append(changes, fmt.Sprintf("server.expose-auth-header: %t -> %t", oldCfg.Server.ExposeAuthHeader, newCfg.Server.ExposeAuthHeader))
+	}
+	if oldCfg.Server.DebugTransforms != newCfg.Server.DebugTransforms {
+		changes = append(changes, fmt.Sprintf("server.debug-transforms: %t -> %t", oldCfg.Server.DebugTransforms, newCfg.Server.DebugTransforms))
+	}
+	if !reflect.DeepEqual(trimStrings(oldCfg.Server.TrustedProxies), trimStrings(newCfg.Server.TrustedProxies)) {
+		changes = append(changes, fmt.Sprintf("server.trusted-proxies: %v -> %v", trimStrings(oldCfg.Server.TrustedProxies), trimStrings(newCfg.Server.TrustedProxies)))
+	}
+	if oldCfg.Server.Compression.Enable != newCfg.Server.Compression.Enable {
+		changes = append(changes, fmt.Sprintf("server.compression.enable: %t -> %t", oldCfg.Server.Compression.Enable, newCfg.Server.Compression.Enable))
+	}
+	if oldCfg.Server.Compression.MinBytes != newCfg.Server.Compression.MinBytes || !reflect.DeepEqual(oldCfg.Server.Compression.ContentTypes, newCfg.Server.Compression.ContentTypes) {
+		changes = append(changes, "server.compression: updated")
+	}
 	if oldCfg.LoggingToFile != newCfg.LoggingToFile {
 		changes = append(changes, fmt.Sprintf("logging-to-file: %t -> %t", oldCfg.LoggingToFile, newCfg.LoggingToFile))
 	}
@@ -42,6 +88,18 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.RedisUsageQueueRetentionSeconds != newCfg.RedisUsageQueueRetentionSeconds {
 		changes = append(changes, fmt.Sprintf("redis-usage-queue-retention-seconds: %d -> %d", oldCfg.RedisUsageQueueRetentionSeconds, newCfg.RedisUsageQueueRetentionSeconds))
 	}
+	if oldCfg.RequestStore.Enable != newCfg.RequestStore.Enable {
+		changes = append(changes, fmt.Sprintf("request-store.enable: %t -> %t", oldCfg.RequestStore.Enable, newCfg.RequestStore.Enable))
+	}
+	if oldCfg.RequestStore.Path != newCfg.RequestStore.Path {
+		changes = append(changes, fmt.Sprintf("request-store.path: %s -> %s", oldCfg.RequestStore.Path, newCfg.RequestStore.Path))
+	}
+	if oldCfg.RequestStore.MaxRows != newCfg.RequestStore.MaxRows || oldCfg.RequestStore.MaxAgeHours != newCfg.RequestStore.MaxAgeHours {
+		changes = append(changes, fmt.Sprintf("request-store retention: max-rows %d -> %d, max-age-hours %d -> %d", oldCfg.RequestStore.MaxRows, newCfg.RequestStore.MaxRows, oldCfg.RequestStore.MaxAgeHours, newCfg.RequestStore.MaxAgeHours))
+	}
+	if oldCfg.RequestStore.MaxBodyBytes != newCfg.RequestStore.MaxBodyBytes || oldCfg.RequestStore.BufferSize != newCfg.RequestStore.BufferSize {
+		changes = append(changes, fmt.Sprintf("request-store limits: max-body-bytes %d -> %d, buffer-size %d -> %d", oldCfg.RequestStore.MaxBodyBytes, newCfg.RequestStore.MaxBodyBytes, oldCfg.RequestStore.BufferSize, newCfg.RequestStore.BufferSize))
+	}
 	if oldCfg.DisableCooling != newCfg.DisableCooling {
 		changes = append(changes, fmt.Sprintf("disable-cooling: %t -> %t", oldCfg.DisableCooling, newCfg.DisableCooling))
 	}
@@ -78,6 +136,33 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.MaxRetryInterval != newCfg.MaxRetryInterval {
 		changes = append(changes, fmt.Sprintf("max-retry-interval: %d -> %d", oldCfg.MaxRetryInterval, newCfg.MaxRetryInterval))
 	}
+	if !reflect.DeepEqual(oldCfg.RequestHedging, newCfg.RequestHedging) {
+		changes = append(changes, "request-hedging: updated")
+	}
+	if oldCfg.Shadow != newCfg.Shadow {
+		changes = append(changes, "shadow: updated")
+	}
+	if !reflect.DeepEqual(oldCfg.ModelTimeouts, newCfg.ModelTimeouts) {
+		changes = append(changes, "model-timeouts: updated")
+	}
+	if !reflect.DeepEqual(oldCfg.RequestPriority, newCfg.RequestPriority) {
+		changes = append(changes, "request-priority: updated")
+	}
+	if !reflect.DeepEqual(oldCfg.TokenQuota, newCfg.TokenQuota) {
+		changes = append(changes, "token-quota: updated")
+	}
+	if oldCfg.Retry != newCfg.Retry {
+		changes = append(changes, "retry: updated")
+	}
+	if oldCfg.Cache != newCfg.Cache {
+		changes = append(changes, "cache: updated")
+	}
+	if !reflect.DeepEqual(oldCfg.ResponseRedaction, newCfg.ResponseRedaction) {
+		changes = append(changes, fmt.Sprintf("response-redaction: updated (%d -> %d rules)", len(oldCfg.ResponseRedaction), len(newCfg.ResponseRedaction)))
+	}
+	if !reflect.DeepEqual(oldCfg.Providers, newCfg.Providers) {
+		changes = append(changes, "providers: updated")
+	}
 	if oldCfg.ProxyURL != newCfg.ProxyURL {
 		changes = append(changes, fmt.Sprintf("proxy-url: %s -> %s", formatProxyURL(oldCfg.ProxyURL), formatProxyURL(newCfg.ProxyURL)))
 	}
@@ -87,9 +172,28 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.ForceModelPrefix != newCfg.ForceModelPrefix {
 		changes = append(changes, fmt.Sprintf("force-model-prefix: %t -> %t", oldCfg.ForceModelPrefix, newCfg.ForceModelPrefix))
 	}
+	if oldCfg.Streaming.IdleTimeoutSeconds != newCfg.Streaming.IdleTimeoutSeconds {
+		changes = append(changes, fmt.Sprintf("streaming.idle-timeout-seconds: %d -> %d", oldCfg.Streaming.IdleTimeoutSeconds, newCfg.Streaming.IdleTimeoutSeconds))
+	}
+	if oldCfg.Streaming.ResumeOnError != newCfg.Streaming.ResumeOnError {
+		changes = append(changes, fmt.Sprintf("streaming.resume-on-error: %t -> %t", oldCfg.Streaming.ResumeOnError, newCfg.Streaming.ResumeOnError))
+	}
+	if oldCfg.Streaming.CoalesceMs != newCfg.Streaming.CoalesceMs {
+		changes = append(changes, fmt.Sprintf("streaming.coalesce-ms: %d -> %d", oldCfg.Streaming.CoalesceMs, newCfg.Streaming.CoalesceMs))
+	}
 	if oldCfg.NonStreamKeepAliveInterval != newCfg.NonStreamKeepAliveInterval {
 		changes = append(changes, fmt.Sprintf("nonstream-keepalive-interval: %d -> %d", oldCfg.NonStreamKeepAliveInterval, newCfg.NonStreamKeepAliveInterval))
 	}
+	if oldCfg.AutoModel.Sticky != newCfg.AutoModel.Sticky {
+		changes = append(changes, fmt.Sprintf("auto-model.sticky: %t -> %t", oldCfg.AutoModel.Sticky, newCfg.AutoModel.Sticky))
+	}
+	if !reflect.DeepEqual(oldCfg.AutoModel.Models, newCfg.AutoModel.Models) {
+		changes = append(changes, fmt.Sprintf("auto-model.models: updated (%d -> %d entries)", len(oldCfg.AutoModel.Models), len(newCfg.AutoModel.Models)))
+	}
+
+	if oldCfg.AntigravityBaseURLStrategy != newCfg.AntigravityBaseURLStrategy {
+		changes = append(changes, fmt.Sprintf("antigravity-base-url-strategy: %s -> %s", oldCfg.AntigravityBaseURLStrategy, newCfg.AntigravityBaseURLStrategy))
+	}
 
 	// Quota-exceeded behavior
 	if oldCfg.QuotaExceeded.SwitchProject != newCfg.QuotaExceeded.SwitchProject {
@@ -109,6 +213,12 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
 		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
 	}
+	if oldCfg.Routing.SessionAffinityMaxEntries != newCfg.Routing.SessionAffinityMaxEntries {
+		changes = append(changes, fmt.Sprintf("routing.session-affinity-max-entries: %d -> %d", oldCfg.Routing.SessionAffinityMaxEntries, newCfg.Routing.SessionAffinityMaxEntries))
+	}
+	if !reflect.DeepEqual(oldCfg.Routing.APIKeyRoutes, newCfg.Routing.APIKeyRoutes) {
+		changes = append(changes, fmt.Sprintf("routing.api-key-routes: %d -> %d", len(oldCfg.Routing.APIKeyRoutes), len(newCfg.Routing.APIKeyRoutes)))
+	}
 	if !reflect.DeepEqual(oldCfg.Payload, newCfg.Payload) {
 		changes = appendPayloadConfigChanges(changes, oldCfg.Payload, newCfg.Payload)
 	}
@@ -140,6 +250,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("gemini[%d].headers: updated", i))
 			}
+			if !slices.Equal(o.ForwardClientHeaders, n.ForwardClientHeaders) {
+				changes = append(changes, fmt.Sprintf("gemini[%d].forward-client-headers: updated", i))
+			}
 			oldModels := SummarizeGeminiModels(o.Models)
 			newModels := SummarizeGeminiModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -150,6 +263,7 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if oldExcluded.hash != newExcluded.hash {
 				changes = append(changes, fmt.Sprintf("gemini[%d].excluded-models: updated (%d -> %d entries)", i, oldExcluded.count, newExcluded.count))
 			}
+			changes = appendCloakChanges(changes, fmt.Sprintf("gemini[%d]", i), o.Cloak, n.Cloak)
 		}
 	}
 	if len(oldCfg.InteractionsKey) != len(newCfg.InteractionsKey) {
@@ -173,6 +287,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("interactions[%d].headers: updated", i))
 			}
+			if !slices.Equal(o.ForwardClientHeaders, n.ForwardClientHeaders) {
+				changes = append(changes, fmt.Sprintf("interactions[%d].forward-client-headers: updated", i))
+			}
 			oldModels := SummarizeGeminiModels(o.Models)
 			newModels := SummarizeGeminiModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -208,6 +325,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("claude[%d].headers: updated", i))
 			}
+			if !slices.Equal(o.ForwardClientHeaders, n.ForwardClientHeaders) {
+				changes = append(changes, fmt.Sprintf("claude[%d].forward-client-headers: updated", i))
+			}
 			oldModels := SummarizeClaudeModels(o.Models)
 			newModels := SummarizeClaudeModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -221,17 +341,10 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if o.RebuildMidSystemMessage != n.RebuildMidSystemMessage {
 				changes = append(changes, fmt.Sprintf("claude[%d].rebuild-mid-system-message: %t -> %t", i, o.RebuildMidSystemMessage, n.RebuildMidSystemMessage))
 			}
-			if o.Cloak != nil && n.Cloak != nil {
-				if strings.TrimSpace(o.Cloak.Mode) != strings.TrimSpace(n.Cloak.Mode) {
-					changes = append(changes, fmt.Sprintf("claude[%d].cloak.mode: %s -> %s", i, o.Cloak.Mode, n.Cloak.Mode))
-				}
-				if o.Cloak.StrictMode != n.Cloak.StrictMode {
-					changes = append(changes, fmt.Sprintf("claude[%d].cloak.strict-mode: %t -> %t", i, o.Cloak.StrictMode, n.Cloak.StrictMode))
-				}
-				if len(o.Cloak.SensitiveWords) != len(n.Cloak.SensitiveWords) {
-					changes = append(changes, fmt.Sprintf("claude[%d].cloak.sensitive-words: %d -> %d", i, len(o.Cloak.SensitiveWords), len(n.Cloak.SensitiveWords)))
-				}
+			if o.SystemAsString != n.SystemAsString {
+				changes = append(changes, fmt.Sprintf("claude[%d].system-as-string: %t -> %t", i, o.SystemAsString, n.SystemAsString))
 			}
+			changes = appendCloakChanges(changes, fmt.Sprintf("claude[%d]", i), o.Cloak, n.Cloak)
 		}
 	}
 
@@ -260,6 +373,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("codex[%d].headers: updated", i))
 			}
+			if !slices.Equal(o.ForwardClientHeaders, n.ForwardClientHeaders) {
+				changes = append(changes, fmt.Sprintf("codex[%d].forward-client-headers: updated", i))
+			}
 			oldModels := SummarizeCodexModels(o.Models)
 			newModels := SummarizeCodexModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -304,6 +420,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("xai[%d].headers: updated", i))
 			}
+			if !slices.Equal(o.ForwardClientHeaders, n.ForwardClientHeaders) {
+				changes = append(changes, fmt.Sprintf("xai[%d].forward-client-headers: updated", i))
+			}
 			oldModels := SummarizeCodexModels(o.Models)
 			newModels := SummarizeCodexModels(n.Models)
 			if oldModels.hash != newModels.hash {
@@ -339,6 +458,12 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldPanelRepo != newPanelRepo {
 		changes = append(changes, fmt.Sprintf("remote-management.panel-github-repository: %s -> %s", oldPanelRepo, newPanelRepo))
 	}
+	if oldCfg.RemoteManagement.EnableStatusUI != newCfg.RemoteManagement.EnableStatusUI {
+		changes = append(changes, fmt.Sprintf("remote-management.enable-status-ui: %t -> %t", oldCfg.RemoteManagement.EnableStatusUI, newCfg.RemoteManagement.EnableStatusUI))
+	}
+	if !reflect.DeepEqual(trimStrings(oldCfg.RemoteManagement.AllowedCIDRs), trimStrings(newCfg.RemoteManagement.AllowedCIDRs)) {
+		changes = append(changes, fmt.Sprintf("remote-management.allowed-cidrs: %v -> %v", trimStrings(oldCfg.RemoteManagement.AllowedCIDRs), trimStrings(newCfg.RemoteManagement.AllowedCIDRs)))
+	}
 	if oldCfg.RemoteManagement.SecretKey != newCfg.RemoteManagement.SecretKey {
 		switch {
 		case oldCfg.RemoteManagement.SecretKey == "" && newCfg.RemoteManagement.SecretKey != "":
@@ -390,6 +515,9 @@ func BuildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if !equalStringMap(o.Headers, n.Headers) {
 				changes = append(changes, fmt.Sprintf("vertex[%d].headers: updated", i))
 			}
+			if !slices.Equal(o.ForwardClientHeaders, n.ForwardClientHeaders) {
+				changes = append(changes, fmt.Sprintf("vertex[%d].forward-client-headers: updated", i))
+			}
 		}
 	}
 
@@ -410,6 +538,9 @@ func appendPayloadConfigChanges(changes []string, oldPayload, newPayload config.
 	changes = appendPayloadRuleChanges(changes, "override", oldPayload.Override, newPayload.Override)
 	changes = appendPayloadRuleChanges(changes, "override-raw", oldPayload.OverrideRaw, newPayload.OverrideRaw)
 	changes = appendPayloadFilterRuleChanges(changes, "filter", oldPayload.Filter, newPayload.Filter)
+	if oldPayload.FillMaxTokens != newPayload.FillMaxTokens {
+		changes = append(changes, fmt.Sprintf("payload.fill-max-tokens: %t -> %t", oldPayload.FillMaxTokens, newPayload.FillMaxTokens))
+	}
 	return changes
 }
 
@@ -466,3 +597,33 @@ func formatProxyURL(raw string) string {
 	}
 	return scheme + "://" + host
 }
+
+// appendCloakChanges describes changes between two cloak blocks of the key labelled label.
+// The system prompt and sensitive words are summarised rather than printed.
+func appendCloakChanges(changes []string, label string, o, n *config.CloakConfig) []string {
+	if o == nil || n == nil {
+		if (o == nil) != (n == nil) {
+			changes = append(changes, fmt.Sprintf("%s.cloak: updated", label))
+		}
+		return changes
+	}
+	if strings.TrimSpace(o.Mode) != strings.TrimSpace(n.Mode) {
+		changes = append(changes, fmt.Sprintf("%s.cloak.mode: %s -> %s", label, o.Mode, n.Mode))
+	}
+	if o.StrictMode != n.StrictMode {
+		changes = append(changes, fmt.Sprintf("%s.cloak.strict-mode: %t -> %t", label, o.StrictMode, n.StrictMode))
+	}
+	if len(o.SensitiveWords) != len(n.SensitiveWords) {
+		changes = append(changes, fmt.Sprintf("%s.cloak.sensitive-words: %d -> %d", label, len(o.SensitiveWords), len(n.SensitiveWords)))
+	}
+	if strings.TrimSpace(o.SystemPrompt) != strings.TrimSpace(n.SystemPrompt) {
+		changes = append(changes, fmt.Sprintf("%s.cloak.system-prompt: updated", label))
+	}
+	if o.ReverseSensitiveWords != n.ReverseSensitiveWords {
+		changes = append(changes, fmt.Sprintf("%s.cloak.reverse-sensitive-words: %t -> %t", label, o.ReverseSensitiveWords, n.ReverseSensitiveWords))
+	}
+	if o.PreserveUserID != n.PreserveUserID {
+		changes = append(changes, fmt.Sprintf("%s.cloak.preserve-user-id: %t -> %t", label, o.PreserveUserID, n.PreserveUserID))
+	}
+	return changes
+}
@@ -116,6 +116,18 @@ func TestBuildConfigChangeDetails_GeminiVertexHeaders(t *testing.T) {
 	expectContains(t, details, "gemini[0].excluded-models: updated (1 -> 2 entries)")
 }
 
+func TestBuildConfigChangeDetails_ForwardClientHeaders(t *testing.T) {
+	oldCfg := &config.Config{
+		ClaudeKey: []config.ClaudeKey{{APIKey: "c1"}},
+	}
+	newCfg := &config.Config{
+		ClaudeKey: []config.ClaudeKey{{APIKey: "c1", ForwardClientHeaders: []string{"X-Tenant-Id"}}},
+	}
+
+	details := BuildConfigChangeDetails(oldCfg, newCfg)
+	expectContains(t, details, "claude[0].forward-client-headers: updated")
+}
+
 func TestBuildConfigChangeDetails_ModelPrefixes(t *testing.T) {
 	oldCfg := &config.Config{
 		GeminiKey: []config.GeminiKey{
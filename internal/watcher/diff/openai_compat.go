@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -75,9 +76,18 @@ func describeOpenAICompatibilityUpdate(oldEntry, newEntry config.OpenAICompatibi
 	if oldModelCount != newModelCount {
 		details = append(details, fmt.Sprintf("models %d -> %d", oldModelCount, newModelCount))
 	}
+	if oldEntry.EndpointSelection != newEntry.EndpointSelection {
+		details = append(details, fmt.Sprintf("endpoint-selection %q -> %q", oldEntry.EndpointSelection, newEntry.EndpointSelection))
+	}
 	if !equalStringMap(oldEntry.Headers, newEntry.Headers) {
 		details = append(details, "headers updated")
 	}
+	if !slices.Equal(oldEntry.ForwardClientHeaders, newEntry.ForwardClientHeaders) {
+		details = append(details, "forward-client-headers updated")
+	}
+	if !slices.Equal(oldEntry.ExtraBodyPassthrough, newEntry.ExtraBodyPassthrough) {
+		details = append(details, fmt.Sprintf("extra-body-passthrough %v -> %v", oldEntry.ExtraBodyPassthrough, newEntry.ExtraBodyPassthrough))
+	}
 	if len(details) == 0 {
 		return ""
 	}
@@ -173,6 +183,19 @@ func openAICompatSignature(entry config.OpenAICompatibility) string {
 		}
 	}
 
+	if len(entry.ForwardClientHeaders) > 0 {
+		names := make([]string, 0, len(entry.ForwardClientHeaders))
+		for _, name := range entry.ForwardClientHeaders {
+			if trimmed := strings.TrimSpace(name); trimmed != "" {
+				names = append(names, strings.ToLower(trimmed))
+			}
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			parts = append(parts, "forward-client-headers="+strings.Join(names, ","))
+		}
+	}
+
 	// Intentionally exclude API key material; only count non-empty entries.
 	if count := countAPIKeys(entry); count > 0 {
 		parts = append(parts, fmt.Sprintf("api_keys=%d", count))
@@ -90,6 +90,7 @@ func (s *ConfigSynthesizer) synthesizeGeminiKeyEntries(ctx *SynthesisContext, en
 			attrs["models_hash"] = hash
 		}
 		addConfigHeadersToAttrs(entry.Headers, attrs)
+		addForwardClientHeadersToAttrs(entry.ForwardClientHeaders, attrs)
 		a := &coreauth.Auth{
 			ID:         id,
 			Provider:   provider,
@@ -144,10 +145,14 @@ func (s *ConfigSynthesizer) synthesizeClaudeKeys(ctx *SynthesisContext) []*corea
 		if ck.RebuildMidSystemMessage {
 			attrs["rebuild_mid_system_message"] = "true"
 		}
+		if ck.SystemAsString {
+			attrs["system_as_string"] = "true"
+		}
 		if hash := diff.ComputeClaudeModelsHash(ck.Models); hash != "" {
 			attrs["models_hash"] = hash
 		}
 		addConfigHeadersToAttrs(ck.Headers, attrs)
+		addForwardClientHeadersToAttrs(ck.ForwardClientHeaders, attrs)
 		proxyURL := strings.TrimSpace(ck.ProxyURL)
 		a := &coreauth.Auth{
 			ID:         id,
@@ -216,6 +221,7 @@ func (s *ConfigSynthesizer) synthesizeCodexStyleKeys(ctx *SynthesisContext, entr
 			attrs["models_hash"] = hash
 		}
 		addConfigHeadersToAttrs(entry.Headers, attrs)
+		addForwardClientHeadersToAttrs(entry.ForwardClientHeaders, attrs)
 		a := &coreauth.Auth{
 			ID:         id,
 			Provider:   provider,
@@ -258,6 +264,51 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 		base := strings.TrimSpace(compat.BaseURL)
 		disableCooling := compat.DisableCooling
 
+		// Endpoint selection pools every entry behind one auth; the executor
+		// picks an entry (and its proxy) per request.
+		if compat.EndpointSelection != "" && len(compat.APIKeyEntries) > 0 {
+			first := &compat.APIKeyEntries[0]
+			firstBase := strings.TrimSpace(first.BaseURL)
+			if firstBase == "" {
+				firstBase = base
+			}
+			idKind := fmt.Sprintf("openai-compatibility:%s", providerName)
+			id, token := idGen.Next(idKind, "endpoint-pool", base)
+			attrs := map[string]string{
+				"source":             fmt.Sprintf("config:%s[%s]", providerName, token),
+				"base_url":           firstBase,
+				"compat_name":        compat.Name,
+				"provider_key":       internalProviderKey,
+				"endpoint_selection": compat.EndpointSelection,
+			}
+			if key := strings.TrimSpace(first.APIKey); key != "" {
+				attrs["api_key"] = key
+			}
+			if compat.Priority != 0 {
+				attrs["priority"] = strconv.Itoa(compat.Priority)
+			}
+			if hash := diff.ComputeOpenAICompatModelsHash(compat.Models); hash != "" {
+				attrs["models_hash"] = hash
+			}
+			addConfigHeadersToAttrs(compat.Headers, attrs)
+			addForwardClientHeadersToAttrs(compat.ForwardClientHeaders, attrs)
+			a := &coreauth.Auth{
+				ID:         id,
+				Provider:   internalProviderKey,
+				Label:      compat.Name,
+				Prefix:     prefix,
+				Status:     coreauth.StatusActive,
+				Attributes: attrs,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			}
+			if disableCooling {
+				a.Metadata = map[string]any{"disable_cooling": true}
+			}
+			out = append(out, a)
+			continue
+		}
+
 		// Handle new APIKeyEntries format (preferred)
 		createdEntries := 0
 		for j := range compat.APIKeyEntries {
@@ -286,6 +337,7 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 				attrs["models_hash"] = hash
 			}
 			addConfigHeadersToAttrs(compat.Headers, attrs)
+			addForwardClientHeadersToAttrs(compat.ForwardClientHeaders, attrs)
 			a := &coreauth.Auth{
 				ID:         id,
 				Provider:   internalProviderKey,
@@ -325,6 +377,7 @@ func (s *ConfigSynthesizer) synthesizeOpenAICompat(ctx *SynthesisContext) []*cor
 				attrs["models_hash"] = hash
 			}
 			addConfigHeadersToAttrs(compat.Headers, attrs)
+			addForwardClientHeadersToAttrs(compat.ForwardClientHeaders, attrs)
 			a := &coreauth.Auth{
 				ID:         id,
 				Provider:   internalProviderKey,
@@ -377,6 +430,7 @@ func (s *ConfigSynthesizer) synthesizeVertexCompat(ctx *SynthesisContext) []*cor
 			attrs["models_hash"] = hash
 		}
 		addConfigHeadersToAttrs(compat.Headers, attrs)
+		addForwardClientHeadersToAttrs(compat.ForwardClientHeaders, attrs)
 		a := &coreauth.Auth{
 			ID:         id,
 			Provider:   providerName,
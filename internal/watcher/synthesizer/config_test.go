@@ -541,6 +541,51 @@ func TestConfigSynthesizer_OpenAICompat_UsesNamespacedProviderKey(t *testing.T)
 	}
 }
 
+func TestConfigSynthesizer_OpenAICompat_EndpointSelectionPoolsEntries(t *testing.T) {
+	synth := NewConfigSynthesizer()
+	cfg := &config.Config{
+		OpenAICompatibility: []config.OpenAICompatibility{
+			{
+				Name:              "vllm",
+				BaseURL:           "https://replica-a.example.com/v1",
+				EndpointSelection: "weighted",
+				APIKeyEntries: []config.OpenAICompatibilityAPIKey{
+					{APIKey: "key-a", ProxyURL: "socks5://proxy.example.com:1080"},
+					{APIKey: "key-b", BaseURL: "https://replica-b.example.com/v1", Weight: 3},
+				},
+			},
+		},
+	}
+	synthesize := func() *coreauth.Auth {
+		t.Helper()
+		auths, err := synth.Synthesize(&SynthesisContext{Config: cfg, Now: time.Now(), IDGenerator: NewStableIDGenerator()})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(auths) != 1 {
+			t.Fatalf("expected 1 pooled auth, got %d", len(auths))
+		}
+		return auths[0]
+	}
+
+	auth := synthesize()
+	if auth.Attributes["endpoint_selection"] != "weighted" {
+		t.Fatalf("endpoint_selection = %q, want weighted", auth.Attributes["endpoint_selection"])
+	}
+	if auth.Attributes["base_url"] != "https://replica-a.example.com/v1" || auth.Attributes["api_key"] != "key-a" {
+		t.Fatalf("attributes = %v, want first entry credentials", auth.Attributes)
+	}
+	if auth.ProxyURL != "" {
+		t.Fatalf("proxy = %q, want per-entry proxies resolved by the executor", auth.ProxyURL)
+	}
+
+	// Changing entries keeps the auth ID, so endpoint health survives reloads.
+	cfg.OpenAICompatibility[0].APIKeyEntries = append(cfg.OpenAICompatibility[0].APIKeyEntries, config.OpenAICompatibilityAPIKey{APIKey: "key-c"})
+	if reloaded := synthesize(); reloaded.ID != auth.ID {
+		t.Fatalf("auth ID changed across reload: %q -> %q", auth.ID, reloaded.ID)
+	}
+}
+
 func TestConfigSynthesizer_VertexCompat(t *testing.T) {
 	synth := NewConfigSynthesizer()
 	ctx := &SynthesisContext{
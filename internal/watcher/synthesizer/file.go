@@ -13,6 +13,8 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/pluginapi"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/secrets"
+	log "github.com/sirupsen/logrus"
 )
 
 // FileSynthesizer generates Auth entries from OAuth JSON files.
@@ -128,6 +130,11 @@ func synthesizeFileAuths(ctx *SynthesisContext, fullPath string, data []byte) []
 	if provider == "" || provider == "gemini-cli" {
 		return nil
 	}
+	refs, errResolve := secrets.ResolveMetadata(context.Background(), metadata)
+	if errResolve != nil {
+		log.Warnf("auth file %s: %v", filepath.Base(fullPath), errResolve)
+		return nil
+	}
 	label := provider
 	if email, _ := metadata["email"].(string); email != "" {
 		label = email
@@ -204,6 +211,7 @@ func synthesizeFileAuths(ctx *SynthesisContext, fullPath string, data []byte) []
 			}
 		}
 	}
+	coreauth.SetSecretRefs(a, refs)
 	coreauth.ApplyCustomHeadersFromMetadata(a)
 	coreauth.SetOAuthModelAliasesAttribute(a, perAccountModelAliases)
 	ApplyAuthExcludedModelsMeta(a, cfg, perAccountExcluded, "oauth")
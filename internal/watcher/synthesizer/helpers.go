@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 
@@ -118,3 +119,27 @@ func addConfigHeadersToAttrs(headers map[string]string, attrs map[string]string)
 		attrs["header:"+key] = val
 	}
 }
+
+// addForwardClientHeadersToAttrs stores the forward-client-headers allowlist in auth
+// attributes as canonical, de-duplicated header names joined by commas.
+func addForwardClientHeadersToAttrs(names []string, attrs map[string]string) {
+	if len(names) == 0 || attrs == nil {
+		return
+	}
+	seen := make(map[string]struct{}, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	if len(out) > 0 {
+		attrs["forward_client_headers"] = strings.Join(out, ",")
+	}
+}
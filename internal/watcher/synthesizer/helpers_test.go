@@ -287,3 +287,17 @@ func TestAddConfigHeadersToAttrs(t *testing.T) {
 		})
 	}
 }
+
+func TestAddForwardClientHeadersToAttrs(t *testing.T) {
+	attrs := map[string]string{}
+	addForwardClientHeadersToAttrs([]string{" x-tenant-id ", "", "X-Routing-Hint", "X-TENANT-ID"}, attrs)
+	if got := attrs["forward_client_headers"]; got != "X-Tenant-Id,X-Routing-Hint" {
+		t.Fatalf("forward_client_headers = %q, want canonical de-duplicated names", got)
+	}
+
+	empty := map[string]string{}
+	addForwardClientHeadersToAttrs([]string{" "}, empty)
+	if _, ok := empty["forward_client_headers"]; ok {
+		t.Fatal("forward_client_headers set for an empty allowlist")
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+const authHeaderTestProvider = "auth-header-test"
+
+// authHeaderTestExecutor fails on auths marked with the "fail" attribute so the
+// headers have to follow the retry to the auth that finally served the request.
+type authHeaderTestExecutor struct{}
+
+func (authHeaderTestExecutor) Identifier() string { return authHeaderTestProvider }
+
+func (authHeaderTestExecutor) Execute(_ context.Context, auth *coreauth.Auth, _ coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	if auth.Attributes["fail"] == "1" {
+		return coreexecutor.Response{}, &coreauth.Error{Code: "unauthorized", Message: "unauthorized", HTTPStatus: http.StatusUnauthorized}
+	}
+	return coreexecutor.Response{Payload: []byte("ok")}, nil
+}
+
+func (authHeaderTestExecutor) ExecuteStream(_ context.Context, auth *coreauth.Auth, _ coreexecutor.Request, _ coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	ch := make(chan coreexecutor.StreamChunk, 1)
+	if auth.Attributes["fail"] == "1" {
+		ch <- coreexecutor.StreamChunk{Err: &coreauth.Error{Code: "unauthorized", Message: "unauthorized", HTTPStatus: http.StatusUnauthorized}}
+	} else {
+		ch <- coreexecutor.StreamChunk{Payload: []byte("ok")}
+	}
+	close(ch)
+	return &coreexecutor.StreamResult{Chunks: ch}, nil
+}
+
+func (authHeaderTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (authHeaderTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (authHeaderTestExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func newAuthHeaderTestRouter(t *testing.T, enabled bool) *gin.Engine {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(authHeaderTestExecutor{})
+	auths := []*coreauth.Auth{
+		{ID: t.Name() + "-bad", Provider: authHeaderTestProvider, Label: "bad@example.com", Status: coreauth.StatusActive, Attributes: map[string]string{"fail": "1"}},
+		{ID: t.Name() + "-good", Provider: authHeaderTestProvider, Label: "good@example.com", Status: coreauth.StatusActive},
+	}
+	for _, auth := range auths {
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("manager.Register(%s): %v", auth.ID, errRegister)
+		}
+		registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "auth-header-model"}})
+		authID := auth.ID
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(authID) })
+	}
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logging.AuthHeaderMiddleware(func() bool { return enabled }))
+	router.POST("/nonstream", func(c *gin.Context) {
+		ctx, cancel := h.GetContextWithCancel(nil, c, context.Background())
+		defer cancel()
+		resp, _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "auth-header-model", []byte(`{"model":"auth-header-model"}`), "")
+		if errMsg != nil {
+			c.Status(errMsg.StatusCode)
+			return
+		}
+		_, _ = c.Writer.Write(resp)
+	})
+	router.POST("/stream", func(c *gin.Context) {
+		ctx, cancel := h.GetContextWithCancel(nil, c, context.Background())
+		defer cancel()
+		data, _, errs := h.ExecuteStreamWithAuthManager(ctx, "openai", "auth-header-model", []byte(`{"model":"auth-header-model"}`), "")
+		for chunk := range data {
+			_, _ = c.Writer.Write(chunk)
+			c.Writer.Flush()
+		}
+		for errMsg := range errs {
+			if errMsg != nil {
+				t.Errorf("stream error: %+v", errMsg)
+			}
+		}
+	})
+	return router
+}
+
+func TestAuthHeadersNameFinalAuth(t *testing.T) {
+	for _, path := range []string{"/nonstream", "/stream"} {
+		t.Run(path, func(t *testing.T) {
+			router := newAuthHeaderTestRouter(t, true)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, nil))
+
+			if recorder.Body.String() != "ok" {
+				t.Fatalf("body = %q, want ok", recorder.Body.String())
+			}
+			if got := recorder.Header().Get(logging.AuthLabelHeader); got != "good@example.com" {
+				t.Fatalf("%s = %q, want the auth that served the request", logging.AuthLabelHeader, got)
+			}
+			if got := recorder.Header().Get(logging.AuthProviderHeader); got != authHeaderTestProvider {
+				t.Fatalf("%s = %q, want %q", logging.AuthProviderHeader, got, authHeaderTestProvider)
+			}
+		})
+	}
+}
+
+func TestAuthHeadersAbsentWhenDisabled(t *testing.T) {
+	for _, path := range []string{"/nonstream", "/stream"} {
+		t.Run(path, func(t *testing.T) {
+			router := newAuthHeaderTestRouter(t, false)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, nil))
+
+			if recorder.Body.String() != "ok" {
+				t.Fatalf("body = %q, want ok", recorder.Body.String())
+			}
+			if got := recorder.Header().Get(logging.AuthLabelHeader); got != "" {
+				t.Fatalf("%s = %q, want no header when disabled", logging.AuthLabelHeader, got)
+			}
+			if got := recorder.Header().Get(logging.AuthProviderHeader); got != "" {
+				t.Fatalf("%s = %q, want no header when disabled", logging.AuthProviderHeader, got)
+			}
+		})
+	}
+}
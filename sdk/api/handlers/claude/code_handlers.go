@@ -15,6 +15,7 @@ import (
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -149,19 +150,47 @@ func rewriteClaudeDDModelInBody(rawJSON []byte) []byte {
 	return updated
 }
 
+func claudeModelID(model map[string]any) string {
+	id, _ := model["id"].(string)
+	return id
+}
+
 // ClaudeModels handles the Claude models listing endpoint.
-// It returns a JSON response containing available Claude models and their specifications.
+// It returns the available models in the Anthropic models list format: every entry carries
+// type "model", id, display_name and an RFC 3339 created_at. The limit, after_id and
+// before_id query parameters page through the list; without limit the whole list is
+// returned.
 //
 // Parameters:
 //   - c: The Gin context for the request.
 func (h *ClaudeCodeAPIHandler) ClaudeModels(c *gin.Context) {
-	models := h.Models()
+	models := h.AllowedModels(c, h.Models(), claudeModelID)
 	for i := range models {
 		if id, ok := models[i]["id"].(string); ok {
 			models[i]["id"] = util.EnsureClaudeModelIDPrefix(id)
 		}
+		if _, ok := models[i]["created_at"]; !ok {
+			models[i]["created_at"] = claudeModelsUnknownCreatedAt
+		}
 	}
 	sortClaudeModelsByDisplayName(models)
+
+	limit := 0
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed < 1 || parsed > claudeModelsMaxLimit {
+			c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+				Error: handlers.ErrorDetail{
+					Message: fmt.Sprintf("limit must be an integer between 1 and %d", claudeModelsMaxLimit),
+					Type:    "invalid_request_error",
+				},
+			})
+			return
+		}
+		limit = parsed
+	}
+	models, hasMore := pageClaudeModels(models, limit, strings.TrimSpace(c.Query("after_id")), strings.TrimSpace(c.Query("before_id")))
+
 	firstID := ""
 	lastID := ""
 	if len(models) > 0 {
@@ -175,12 +204,61 @@ func (h *ClaudeCodeAPIHandler) ClaudeModels(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":     models,
-		"has_more": false,
+		"has_more": hasMore,
 		"first_id": firstID,
 		"last_id":  lastID,
 	})
 }
 
+const (
+	// claudeModelsMaxLimit is the largest page size the Anthropic models list accepts.
+	claudeModelsMaxLimit = 1000
+	// claudeModelsUnknownCreatedAt stands in for created_at when the registry has no
+	// creation time, since Anthropic clients expect the field on every model.
+	claudeModelsUnknownCreatedAt = "1970-01-01T00:00:00Z"
+)
+
+// pageClaudeModels cuts one page out of the sorted models. after_id returns the models
+// following that ID and before_id the models preceding it; limit caps the page from the
+// side of the cursor, as the Anthropic API does. hasMore reports whether models remain
+// beyond the page in the paging direction. An unknown cursor yields an empty page.
+func pageClaudeModels(models []map[string]any, limit int, afterID, beforeID string) (page []map[string]any, hasMore bool) {
+	indexOf := func(id string) int {
+		for i, model := range models {
+			if modelID, _ := model["id"].(string); modelID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if beforeID != "" {
+		end := indexOf(beforeID)
+		if end < 0 {
+			return []map[string]any{}, false
+		}
+		start := 0
+		if limit > 0 && end > limit {
+			start = end - limit
+		}
+		return models[start:end], start > 0
+	}
+
+	start := 0
+	if afterID != "" {
+		index := indexOf(afterID)
+		if index < 0 {
+			return []map[string]any{}, false
+		}
+		start = index + 1
+	}
+	models = models[start:]
+	if limit > 0 && len(models) > limit {
+		return models[:limit], true
+	}
+	return models, false
+}
+
 // sortClaudeModelsByDisplayName sorts models by display_name ascending.
 // When display_name is equal or missing, id is used as a stable tie-breaker.
 func sortClaudeModelsByDisplayName(models []map[string]any) {
@@ -355,6 +433,7 @@ func pendingClaudeStreamError(errs <-chan *interfaces.ErrorMessage) (*interfaces
 
 func (h *ClaudeCodeAPIHandler) forwardClaudeStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
+		Coalesce: Claude,
 		WriteChunk: func(chunk []byte) {
 			if len(chunk) == 0 {
 				return
@@ -416,6 +495,11 @@ func (h *ClaudeCodeAPIHandler) WriteErrorResponse(c *gin.Context, msg *interface
 	if msg != nil && msg.StatusCode > 0 {
 		status = msg.StatusCode
 	}
+	if msg != nil {
+		for _, value := range handlers.RetryAfterValues(msg.Error) {
+			c.Writer.Header().Add("Retry-After", value)
+		}
+	}
 	if msg != nil && msg.Addon != nil && handlers.PassthroughHeadersEnabled(h.Cfg) {
 		for key, values := range msg.Addon {
 			if len(values) == 0 || handlers.IsCPAReservedResponseHeader(key) {
@@ -1,13 +1,17 @@
 package claude
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
 	"github.com/tidwall/gjson"
 )
 
@@ -92,3 +96,36 @@ func TestPendingClaudeStreamErrorUsesBufferedError(t *testing.T) {
 		t.Fatalf("pending error = %p, want %p", gotErr, wantErr)
 	}
 }
+
+func TestForwardClaudeStreamWrapsHTMLErrorInJSONErrorEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewClaudeCodeAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil))
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	data := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage, 1)
+	errs <- &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadGateway,
+		Error:      errors.New("<html>\n<head><title>502 Bad Gateway</title></head>\n</html>"),
+	}
+	close(errs)
+
+	handler.forwardClaudeStream(c, c.Writer, func(error) {}, data, errs)
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "event: error\ndata: ") || !strings.HasSuffix(body, "\n\n") {
+		t.Fatalf("expected a single error event, got: %q", body)
+	}
+	payload := strings.TrimSuffix(strings.TrimPrefix(body, "event: error\ndata: "), "\n\n")
+	if strings.Contains(payload, "\n") || !json.Valid([]byte(payload)) {
+		t.Fatalf("expected one line of JSON in the error event, got: %q", payload)
+	}
+	if got := gjson.Get(payload, "type").String(); got != "error" {
+		t.Fatalf("type = %q, want error; payload=%s", got, payload)
+	}
+	if got := gjson.Get(payload, "error.type").String(); got == "" {
+		t.Fatalf("error.type is empty; payload=%s", payload)
+	}
+}
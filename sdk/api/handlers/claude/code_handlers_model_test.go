@@ -2,8 +2,11 @@ package claude
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
@@ -64,6 +67,132 @@ func TestClaudeModelsResponseUsesConfiguredDisplayName(t *testing.T) {
 	t.Fatalf("model %q not found in response", modelID)
 }
 
+func TestClaudeModelsResponseUsesAnthropicSchema(t *testing.T) {
+	const clientID = "claude-models-schema-antigravity-test"
+	const aliasID = "claude-models-schema-test-alias"
+	registryRef := registry.GetGlobalRegistry()
+	registryRef.RegisterClient(clientID, "antigravity", []*registry.ModelInfo{
+		{ID: aliasID, Object: "model", OwnedBy: "antigravity"},
+		{ID: aliasID + "-thinking", Object: "model", OwnedBy: "antigravity", Created: 1771372800},
+	})
+	t.Cleanup(func() {
+		registryRef.UnregisterClient(clientID)
+	})
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	NewClaudeCodeAPIHandler(&handlers.BaseAPIHandler{}).ClaudeModels(ctx)
+
+	body := recorder.Body.Bytes()
+	for _, field := range []string{"data", "has_more", "first_id", "last_id"} {
+		if !gjson.GetBytes(body, field).Exists() {
+			t.Fatalf("response is missing %s: %s", field, body)
+		}
+	}
+	var alias gjson.Result
+	gjson.GetBytes(body, "data").ForEach(func(_, model gjson.Result) bool {
+		for _, field := range []string{"type", "id", "display_name", "created_at"} {
+			if !model.Get(field).Exists() {
+				t.Fatalf("model is missing %s: %s", field, model.Raw)
+			}
+		}
+		if model.Get("id").String() == aliasID {
+			alias = model
+		}
+		return true
+	})
+	if !alias.Exists() {
+		t.Fatalf("antigravity claude alias %q not listed: %s", aliasID, body)
+	}
+	if got := alias.Get("type").String(); got != "model" {
+		t.Fatalf("type = %q, want model", got)
+	}
+	if got := alias.Get("display_name").String(); got != aliasID {
+		t.Fatalf("display_name = %q, want %q", got, aliasID)
+	}
+	if _, errParse := time.Parse(time.RFC3339, alias.Get("created_at").String()); errParse != nil {
+		t.Fatalf("created_at %q is not RFC 3339: %v", alias.Get("created_at").String(), errParse)
+	}
+}
+
+func TestClaudeModelsPagination(t *testing.T) {
+	const clientID = "claude-models-pagination-test"
+	registryRef := registry.GetGlobalRegistry()
+	registryRef.RegisterClient(clientID, "antigravity", []*registry.ModelInfo{
+		{ID: "claude-models-pagination-a", Object: "model", OwnedBy: "antigravity"},
+		{ID: "claude-models-pagination-b", Object: "model", OwnedBy: "antigravity"},
+	})
+	t.Cleanup(func() {
+		registryRef.UnregisterClient(clientID)
+	})
+
+	list := func(t *testing.T, query string) (int, []byte) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/v1/models"+query, nil)
+		NewClaudeCodeAPIHandler(&handlers.BaseAPIHandler{}).ClaudeModels(ctx)
+		return recorder.Code, recorder.Body.Bytes()
+	}
+
+	_, first := list(t, "?limit=1")
+	if got := gjson.GetBytes(first, "data.#").Int(); got != 1 {
+		t.Fatalf("data length = %d, want 1: %s", got, first)
+	}
+	firstID := gjson.GetBytes(first, "first_id").String()
+	if !gjson.GetBytes(first, "has_more").Bool() || firstID == "" || gjson.GetBytes(first, "last_id").String() != firstID {
+		t.Fatalf("unexpected first page: %s", first)
+	}
+
+	_, second := list(t, "?limit=1&after_id="+firstID)
+	if got := gjson.GetBytes(second, "first_id").String(); got == "" || got == firstID {
+		t.Fatalf("second page first_id = %q, want the model after %q", got, firstID)
+	}
+	_, back := list(t, "?limit=1&before_id="+gjson.GetBytes(second, "first_id").String())
+	if got := gjson.GetBytes(back, "first_id").String(); got != firstID {
+		t.Fatalf("before_id page first_id = %q, want %q", got, firstID)
+	}
+
+	if code, _ := list(t, "?limit=0"); code != http.StatusBadRequest {
+		t.Fatalf("limit=0 status = %d, want 400", code)
+	}
+}
+
+func TestPageClaudeModels(t *testing.T) {
+	models := []map[string]any{{"id": "a"}, {"id": "b"}, {"id": "c"}, {"id": "d"}}
+	ids := func(page []map[string]any) []string {
+		out := make([]string, 0, len(page))
+		for _, model := range page {
+			out = append(out, model["id"].(string))
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		limit    int
+		afterID  string
+		beforeID string
+		want     []string
+		wantMore bool
+	}{
+		{name: "all", want: []string{"a", "b", "c", "d"}},
+		{name: "limit", limit: 2, want: []string{"a", "b"}, wantMore: true},
+		{name: "after", limit: 2, afterID: "b", want: []string{"c", "d"}},
+		{name: "before", limit: 2, beforeID: "d", want: []string{"b", "c"}, wantMore: true},
+		{name: "before without limit", beforeID: "c", want: []string{"a", "b"}},
+		{name: "unknown cursor", afterID: "z", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, hasMore := pageClaudeModels(models, tt.limit, tt.afterID, tt.beforeID)
+			if got := ids(page); !reflect.DeepEqual(got, tt.want) || hasMore != tt.wantMore {
+				t.Fatalf("page = %v, has_more = %t, want %v, %t", got, hasMore, tt.want, tt.wantMore)
+			}
+		})
+	}
+}
+
 func TestRewriteClaudeDDModelInBody(t *testing.T) {
 	tests := []struct {
 		name      string
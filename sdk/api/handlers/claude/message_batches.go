@@ -0,0 +1,225 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// claudeBatchIDAuthSeparator joins an upstream batch ID and the index of the auth that
+// created it in the batch IDs returned to clients. Anthropic batch IDs never contain it.
+const claudeBatchIDAuthSeparator = "."
+
+// ClaudeMessageBatchesCreate handles POST /v1/messages/batches.
+func (h *ClaudeCodeAPIHandler) ClaudeMessageBatchesCreate(c *gin.Context) {
+	rawJSON, err := c.GetRawData()
+	if err != nil {
+		h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("Invalid request: %v", err)})
+		return
+	}
+	modelName := gjson.GetBytes(rawJSON, "requests.0.params.model").String()
+	h.forwardMessageBatch(c, http.MethodPost, "", "", c.Request.URL.RawQuery, modelName, rawJSON)
+}
+
+// ClaudeMessageBatchesList handles GET /v1/messages/batches. A page cursor taken from an
+// earlier page keeps the listing on the account that served it.
+func (h *ClaudeCodeAPIHandler) ClaudeMessageBatchesList(c *gin.Context) {
+	query := c.Request.URL.Query()
+	authIndex := ""
+	for _, key := range []string{"after_id", "before_id"} {
+		if cursor := query.Get(key); cursor != "" {
+			upstreamID, index := decodeClaudeBatchID(cursor)
+			query.Set(key, upstreamID)
+			if authIndex == "" {
+				authIndex = index
+			}
+		}
+	}
+	h.forwardMessageBatch(c, http.MethodGet, "", authIndex, query.Encode(), "", nil)
+}
+
+// ClaudeMessageBatchesGet handles GET /v1/messages/batches/{id}.
+func (h *ClaudeCodeAPIHandler) ClaudeMessageBatchesGet(c *gin.Context) {
+	upstreamID, authIndex := decodeClaudeBatchID(c.Param("batch_id"))
+	h.forwardMessageBatch(c, http.MethodGet, "/"+upstreamID, authIndex, c.Request.URL.RawQuery, "", nil)
+}
+
+// ClaudeMessageBatchesResults handles GET /v1/messages/batches/{id}/results.
+func (h *ClaudeCodeAPIHandler) ClaudeMessageBatchesResults(c *gin.Context) {
+	upstreamID, authIndex := decodeClaudeBatchID(c.Param("batch_id"))
+	h.forwardMessageBatch(c, http.MethodGet, "/"+upstreamID+"/results", authIndex, c.Request.URL.RawQuery, "", nil)
+}
+
+// ClaudeMessageBatchesCancel handles POST /v1/messages/batches/{id}/cancel.
+func (h *ClaudeCodeAPIHandler) ClaudeMessageBatchesCancel(c *gin.Context) {
+	upstreamID, authIndex := decodeClaudeBatchID(c.Param("batch_id"))
+	h.forwardMessageBatch(c, http.MethodPost, "/"+upstreamID+"/cancel", authIndex, c.Request.URL.RawQuery, "", nil)
+}
+
+func (h *ClaudeCodeAPIHandler) forwardMessageBatch(c *gin.Context, method, path, authIndex, rawQuery, modelName string, body []byte) {
+	if h.AuthManager == nil {
+		h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusServiceUnavailable, Error: errors.New("auth manager unavailable")})
+		return
+	}
+	exec, ok := h.AuthManager.Executor("claude")
+	if !ok {
+		h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusServiceUnavailable, Error: errors.New("claude executor unavailable")})
+		return
+	}
+	batchExec, ok := exec.(coreauth.MessageBatchExecutor)
+	if !ok {
+		h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusNotImplemented, Error: errors.New("message batches are not supported")})
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), "gin", c)
+	auth, err := h.messageBatchAuth(ctx, c, authIndex, modelName)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		if statusErr, ok := err.(interface{ StatusCode() int }); ok && statusErr.StatusCode() > 0 {
+			status = statusErr.StatusCode()
+		}
+		h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: status, Error: err})
+		return
+	}
+
+	resp, err := batchExec.MessageBatchRequest(ctx, auth, method, path, rawQuery, body, c.Request.Header)
+	if err != nil {
+		h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: err})
+		return
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("claude message batches: close response body error: %v", errClose)
+		}
+	}()
+
+	if !strings.HasSuffix(path, "/results") && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		data, errRead := io.ReadAll(resp.Body)
+		if errRead != nil {
+			h.WriteErrorResponse(c, &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: errRead})
+			return
+		}
+		writeMessageBatchResponse(c, resp, bytes.NewReader(encodeClaudeBatchIDs(data, auth, messageBatchesURL(c))))
+		return
+	}
+	writeMessageBatchResponse(c, resp, resp.Body)
+}
+
+// messageBatchAuth returns the auth with authIndex, the one that created the batch, when
+// it is still available, otherwise it selects a Claude auth through the regular scheduler.
+func (h *ClaudeCodeAPIHandler) messageBatchAuth(ctx context.Context, c *gin.Context, authIndex, modelName string) (*coreauth.Auth, error) {
+	if authIndex != "" {
+		for _, auth := range h.AuthManager.List() {
+			if auth == nil || auth.Disabled || !strings.EqualFold(auth.Provider, "claude") {
+				continue
+			}
+			if auth.EnsureIndex() == authIndex {
+				return auth, nil
+			}
+		}
+	}
+	opts := coreexecutor.Options{Headers: c.Request.Header.Clone()}
+	return h.AuthManager.SelectAuth(ctx, "claude", modelName, opts)
+}
+
+// encodeClaudeBatchID returns the batch ID handed to clients for the upstream batch
+// upstreamID of auth. It carries the auth index, so later calls for the batch reach the
+// account that owns it without any state kept by the proxy.
+func encodeClaudeBatchID(upstreamID string, auth *coreauth.Auth) string {
+	index := auth.EnsureIndex()
+	if upstreamID == "" || index == "" {
+		return upstreamID
+	}
+	return upstreamID + claudeBatchIDAuthSeparator + index
+}
+
+// decodeClaudeBatchID splits a batch ID built by encodeClaudeBatchID into the upstream
+// batch ID and the auth index. Other IDs are returned unchanged with no index.
+func decodeClaudeBatchID(batchID string) (upstreamID, authIndex string) {
+	batchID = strings.TrimSpace(batchID)
+	upstreamID, authIndex, found := strings.Cut(batchID, claudeBatchIDAuthSeparator)
+	if !found {
+		return batchID, ""
+	}
+	return upstreamID, authIndex
+}
+
+// messageBatchesURL returns the absolute URL of the proxy's /v1/messages/batches route as
+// seen by the client of c, or "" when the request path does not name it.
+func messageBatchesURL(c *gin.Context) string {
+	path := c.Request.URL.Path
+	end := strings.Index(path, "/messages/batches")
+	if end < 0 {
+		return ""
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := strings.ToLower(strings.TrimSpace(c.GetHeader("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + path[:end+len("/messages/batches")]
+}
+
+// encodeClaudeBatchIDs rewrites the batch IDs of a batch or batch list response body to
+// the IDs handed to clients. Results URLs are pointed at batchesURL, the proxy's own batches
+// route, and left unchanged when it is empty.
+func encodeClaudeBatchIDs(data []byte, auth *coreauth.Auth, batchesURL string) []byte {
+	if !gjson.ValidBytes(data) {
+		return data
+	}
+	if gjson.GetBytes(data, "type").String() == "message_batch" {
+		return encodeClaudeBatch(data, "", auth, batchesURL)
+	}
+	for i := range gjson.GetBytes(data, "data").Array() {
+		data = encodeClaudeBatch(data, fmt.Sprintf("data.%d.", i), auth, batchesURL)
+	}
+	for _, key := range []string{"first_id", "last_id"} {
+		if id := gjson.GetBytes(data, key).String(); id != "" {
+			data, _ = sjson.SetBytes(data, key, encodeClaudeBatchID(id, auth))
+		}
+	}
+	return data
+}
+
+// encodeClaudeBatch rewrites the id and results_url of the batch object at prefix.
+func encodeClaudeBatch(data []byte, prefix string, auth *coreauth.Auth, batchesURL string) []byte {
+	id := gjson.GetBytes(data, prefix+"id").String()
+	if id == "" {
+		return data
+	}
+	encoded := encodeClaudeBatchID(id, auth)
+	data, _ = sjson.SetBytes(data, prefix+"id", encoded)
+	if batchesURL != "" && gjson.GetBytes(data, prefix+"results_url").String() != "" {
+		data, _ = sjson.SetBytes(data, prefix+"results_url", batchesURL+"/"+url.PathEscape(encoded)+"/results")
+	}
+	return data
+}
+
+func writeMessageBatchResponse(c *gin.Context, resp *http.Response, body io.Reader) {
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	if requestID := resp.Header.Get("Request-Id"); requestID != "" {
+		c.Header("Request-Id", requestID)
+	}
+	c.Status(resp.StatusCode)
+	if _, errCopy := io.Copy(c.Writer, body); errCopy != nil {
+		log.Errorf("claude message batches: write response error: %v", errCopy)
+	}
+}
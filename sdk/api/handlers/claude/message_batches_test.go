@@ -0,0 +1,201 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+type messageBatchCall struct {
+	authID   string
+	method   string
+	path     string
+	rawQuery string
+}
+
+type messageBatchCaptureExecutor struct {
+	calls []messageBatchCall
+}
+
+func (e *messageBatchCaptureExecutor) Identifier() string { return "claude" }
+
+func (e *messageBatchCaptureExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *messageBatchCaptureExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *messageBatchCaptureExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *messageBatchCaptureExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *messageBatchCaptureExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *messageBatchCaptureExecutor) MessageBatchRequest(_ context.Context, auth *coreauth.Auth, method, path, rawQuery string, _ []byte, _ http.Header) (*http.Response, error) {
+	e.calls = append(e.calls, messageBatchCall{authID: auth.ID, method: method, path: path, rawQuery: rawQuery})
+	body := `{"id":"msgbatch_handler","type":"message_batch","results_url":"https://api.anthropic.com/v1/messages/batches/msgbatch_handler/results"}`
+	contentType := "application/json"
+	if strings.HasSuffix(path, "/results") {
+		body = `{"custom_id":"a","result":{"type":"succeeded"}}` + "\n"
+		contentType = "application/binary"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+// newMessageBatchTestRouter registers the Claude auths named by ids with a fresh manager
+// and returns a router serving the message batch endpoints through it.
+func newMessageBatchTestRouter(t *testing.T, executor *messageBatchCaptureExecutor, ids ...string) *gin.Engine {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	for _, id := range ids {
+		auth := &coreauth.Auth{ID: id, Provider: "claude", Status: coreauth.StatusActive}
+		if _, err := manager.Register(context.Background(), auth); err != nil {
+			t.Fatalf("Register auth: %v", err)
+		}
+		registry.GetGlobalRegistry().RegisterClient(id, "claude", []*registry.ModelInfo{{ID: "claude-batch-test"}})
+		authID := id
+		t.Cleanup(func() {
+			registry.GetGlobalRegistry().UnregisterClient(authID)
+		})
+	}
+
+	h := NewClaudeCodeAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.POST("/v1/messages/batches", h.ClaudeMessageBatchesCreate)
+	router.GET("/v1/messages/batches", h.ClaudeMessageBatchesList)
+	router.GET("/v1/messages/batches/:batch_id", h.ClaudeMessageBatchesGet)
+	router.GET("/v1/messages/batches/:batch_id/results", h.ClaudeMessageBatchesResults)
+	return router
+}
+
+func TestClaudeMessageBatchesRouteToCreatingAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &messageBatchCaptureExecutor{}
+	router := newMessageBatchTestRouter(t, executor, "claude-batch-a", "claude-batch-b")
+
+	create := httptest.NewRecorder()
+	router.ServeHTTP(create, httptest.NewRequest(http.MethodPost, "/v1/messages/batches", strings.NewReader(`{"requests":[{"custom_id":"a","params":{"model":"claude-batch-test","max_tokens":1,"messages":[]}}]}`)))
+	batchID := gjson.Get(create.Body.String(), "id").String()
+	if create.Code != http.StatusOK || !strings.HasPrefix(batchID, "msgbatch_handler.") {
+		t.Fatalf("create status = %d body = %s", create.Code, create.Body.String())
+	}
+
+	for i := 0; i < 3; i++ {
+		get := httptest.NewRecorder()
+		router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/v1/messages/batches/"+batchID, nil))
+		if get.Code != http.StatusOK || gjson.Get(get.Body.String(), "id").String() != batchID {
+			t.Fatalf("get status = %d body = %s", get.Code, get.Body.String())
+		}
+		if got := gjson.Get(get.Body.String(), "results_url").String(); got != "http://example.com/v1/messages/batches/"+batchID+"/results" {
+			t.Fatalf("results_url = %q, want the proxy's results route", got)
+		}
+	}
+	results := httptest.NewRecorder()
+	router.ServeHTTP(results, httptest.NewRequest(http.MethodGet, "/v1/messages/batches/"+batchID+"/results?limit=2", nil))
+	if results.Code != http.StatusOK || results.Header().Get("Content-Type") != "application/binary" {
+		t.Fatalf("results status = %d content-type = %q", results.Code, results.Header().Get("Content-Type"))
+	}
+
+	if len(executor.calls) != 5 {
+		t.Fatalf("calls = %d, want 5", len(executor.calls))
+	}
+	creator := executor.calls[0].authID
+	for _, call := range executor.calls[1:] {
+		if call.authID != creator {
+			t.Fatalf("call %+v routed to %s, want creating auth %s", call, call.authID, creator)
+		}
+	}
+	last := executor.calls[len(executor.calls)-1]
+	if last.path != "/msgbatch_handler/results" || last.rawQuery != "limit=2" {
+		t.Fatalf("results call = %+v", last)
+	}
+}
+
+func TestClaudeMessageBatchesRouteToCreatingAuthAfterRestart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ids := []string{"claude-restart-a", "claude-restart-b", "claude-restart-c"}
+	created := &messageBatchCaptureExecutor{}
+	create := httptest.NewRecorder()
+	newMessageBatchTestRouter(t, created, ids...).ServeHTTP(create, httptest.NewRequest(http.MethodPost, "/v1/messages/batches", strings.NewReader(`{"requests":[]}`)))
+	batchID := gjson.Get(create.Body.String(), "id").String()
+	if create.Code != http.StatusOK || batchID == "" {
+		t.Fatalf("create status = %d body = %s", create.Code, create.Body.String())
+	}
+	creator := created.calls[0].authID
+
+	// A new manager and handler keep no state from the first ones.
+	executor := &messageBatchCaptureExecutor{}
+	router := newMessageBatchTestRouter(t, executor, ids...)
+	for i := 0; i < len(ids); i++ {
+		get := httptest.NewRecorder()
+		router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/v1/messages/batches/"+batchID, nil))
+		if get.Code != http.StatusOK {
+			t.Fatalf("get status = %d", get.Code)
+		}
+	}
+	list := httptest.NewRecorder()
+	router.ServeHTTP(list, httptest.NewRequest(http.MethodGet, "/v1/messages/batches?limit=1&after_id="+batchID, nil))
+	if list.Code != http.StatusOK {
+		t.Fatalf("list status = %d", list.Code)
+	}
+	for _, call := range executor.calls {
+		if call.authID != creator {
+			t.Fatalf("call %+v routed to %s, want creating auth %s", call, call.authID, creator)
+		}
+	}
+	if last := executor.calls[len(executor.calls)-1]; last.rawQuery != "after_id=msgbatch_handler&limit=1" {
+		t.Fatalf("list query = %q, want the upstream cursor", last.rawQuery)
+	}
+}
+
+func TestEncodeClaudeBatchIDsRewritesBatchesAndLists(t *testing.T) {
+	auth := &coreauth.Auth{ID: "claude-encode", Provider: "claude"}
+	suffix := "." + auth.EnsureIndex()
+
+	upstreamBatch := []byte(`{"id":"msgbatch_1","type":"message_batch","results_url":"https://api.anthropic.com/v1/messages/batches/msgbatch_1/results"}`)
+	batch := encodeClaudeBatchIDs(upstreamBatch, auth, "http://proxy.local:8317/v1/messages/batches")
+	if got := gjson.GetBytes(batch, "id").String(); got != "msgbatch_1"+suffix {
+		t.Fatalf("id = %q", got)
+	}
+	if got := gjson.GetBytes(batch, "results_url").String(); got != "http://proxy.local:8317/v1/messages/batches/msgbatch_1"+suffix+"/results" {
+		t.Fatalf("results_url = %q, want the proxy's results route", got)
+	}
+	if got := gjson.GetBytes(encodeClaudeBatchIDs(upstreamBatch, auth, ""), "results_url").String(); got != "https://api.anthropic.com/v1/messages/batches/msgbatch_1/results" {
+		t.Fatalf("results_url without a proxy URL = %q, want it untouched", got)
+	}
+
+	list := encodeClaudeBatchIDs([]byte(`{"data":[{"id":"msgbatch_1","type":"message_batch"},{"id":"msgbatch_2","type":"message_batch"}],"first_id":"msgbatch_1","last_id":"msgbatch_2","has_more":true}`), auth, "")
+	for path, want := range map[string]string{"data.0.id": "msgbatch_1", "data.1.id": "msgbatch_2", "first_id": "msgbatch_1", "last_id": "msgbatch_2"} {
+		if got := gjson.GetBytes(list, path).String(); got != want+suffix {
+			t.Fatalf("%s = %q, want %q", path, got, want+suffix)
+		}
+	}
+	if upstreamID, index := decodeClaudeBatchID("msgbatch_2" + suffix); upstreamID != "msgbatch_2" || index != auth.EnsureIndex() {
+		t.Fatalf("decodeClaudeBatchID() = %q, %q", upstreamID, index)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const jsonRPCChatFormat = "jsonrpc-chat"
+
+// openAIEchoExecutor translates from the request's source format into OpenAI,
+// the way built-in executors do, and answers with a fixed OpenAI completion.
+type openAIEchoExecutor struct {
+	upstreamPayload []byte
+	sourceFormat    sdktranslator.Format
+}
+
+func (e *openAIEchoExecutor) Identifier() string { return "jsonrpc-test-provider" }
+
+func (e *openAIEchoExecutor) Execute(ctx context.Context, _ *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.sourceFormat = opts.SourceFormat
+	e.upstreamPayload = sdktranslator.TranslateRequest(opts.SourceFormat, sdktranslator.FormatOpenAI, req.Model, req.Payload, false)
+	upstream := []byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"hello back"}}]}`)
+	var param any
+	payload := sdktranslator.TranslateNonStream(ctx, sdktranslator.FormatOpenAI, opts.SourceFormat, req.Model, opts.OriginalRequest, e.upstreamPayload, upstream, &param)
+	return coreexecutor.Response{Payload: payload}, nil
+}
+
+func (e *openAIEchoExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "ExecuteStream not implemented"}
+}
+
+func (e *openAIEchoExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *openAIEchoExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *openAIEchoExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func registerJSONRPCChatTranslators(t *testing.T) {
+	t.Helper()
+	format := sdktranslator.FromString(jsonRPCChatFormat)
+	errRequest := sdktranslator.RegisterRequestTranslator(format, sdktranslator.FormatOpenAI, func(model string, rawJSON []byte, _ bool) []byte {
+		out := []byte(`{"model":"","messages":[{"role":"user","content":""}]}`)
+		out, _ = sjson.SetBytes(out, "model", model)
+		out, _ = sjson.SetBytes(out, "messages.0.content", gjson.GetBytes(rawJSON, "params.prompt").String())
+		return out
+	}, true)
+	if errRequest != nil {
+		t.Fatalf("RegisterRequestTranslator() error = %v", errRequest)
+	}
+	errResponse := sdktranslator.RegisterNonStreamResponseTranslator(format, sdktranslator.FormatOpenAI, func(_ context.Context, _ string, originalRequestRawJSON, _, rawJSON []byte, _ *any) []byte {
+		out := []byte(`{"jsonrpc":"2.0","result":{"text":""}}`)
+		out, _ = sjson.SetBytes(out, "id", gjson.GetBytes(originalRequestRawJSON, "id").Value())
+		out, _ = sjson.SetBytes(out, "result.text", gjson.GetBytes(rawJSON, "choices.0.message.content").String())
+		return out
+	}, true)
+	if errResponse != nil {
+		t.Fatalf("RegisterNonStreamResponseTranslator() error = %v", errResponse)
+	}
+}
+
+func TestExecuteWithAuthManagerCustomSourceFormat(t *testing.T) {
+	registerJSONRPCChatTranslators(t)
+
+	executor := &openAIEchoExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "jsonrpc-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register() error = %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "jsonrpc-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	body, _, errMsg := handler.ExecuteWithAuthManager(context.Background(), jsonRPCChatFormat, "jsonrpc-model",
+		[]byte(`{"jsonrpc":"2.0","id":7,"method":"chat","params":{"prompt":"hi there"}}`), "")
+	if errMsg != nil {
+		t.Fatalf("ExecuteWithAuthManager() error = %+v", errMsg)
+	}
+
+	if executor.sourceFormat != sdktranslator.FromString(jsonRPCChatFormat) {
+		t.Fatalf("executor source format = %q, want %q", executor.sourceFormat, jsonRPCChatFormat)
+	}
+	if got := gjson.GetBytes(executor.upstreamPayload, "messages.0.content").String(); got != "hi there" {
+		t.Fatalf("upstream payload = %s, want translated prompt", executor.upstreamPayload)
+	}
+	if got := gjson.GetBytes(body, "result.text").String(); got != "hello back" || gjson.GetBytes(body, "id").Int() != 7 {
+		t.Fatalf("response = %s, want JSON-RPC result for id 7", body)
+	}
+}
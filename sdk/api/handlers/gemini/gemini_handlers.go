@@ -46,9 +46,25 @@ func (h *GeminiAPIHandler) Models() []map[string]any {
 }
 
 // GeminiModels handles the Gemini models listing endpoint.
-// It returns a JSON response containing available Gemini models and their specifications.
+// It returns a JSON response containing available Gemini models and their specifications,
+// sorted by name. The provider, owned_by and search query parameters filter the list;
+// pageSize and pageToken return one page of it together with nextPageToken.
 func (h *GeminiAPIHandler) GeminiModels(c *gin.Context) {
-	rawModels := h.Models()
+	var opts handlers.ModelListOptions
+	if c.Request != nil {
+		var errOpts error
+		opts, errOpts = handlers.ParseGeminiModelListOptions(c.Request.URL.Query())
+		if errOpts != nil {
+			c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+				Error: handlers.ErrorDetail{
+					Message: fmt.Sprintf("Invalid request: %v", errOpts),
+					Type:    "invalid_request_error",
+				},
+			})
+			return
+		}
+	}
+	rawModels, hasMore := handlers.ListModels(h.AllowedModels(c, h.Models(), geminiModelID), geminiModelID, opts)
 	normalizedModels := make([]map[string]any, 0, len(rawModels))
 	defaultMethods := []string{"generateContent"}
 	for _, model := range rawModels {
@@ -72,9 +88,19 @@ func (h *GeminiAPIHandler) GeminiModels(c *gin.Context) {
 		}
 		normalizedModels = append(normalizedModels, normalizedModel)
 	}
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"models": normalizedModels,
-	})
+	}
+	if hasMore && len(rawModels) > 0 {
+		response["nextPageToken"] = geminiModelID(rawModels[len(rawModels)-1])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// geminiModelID returns a model's name without the models/ prefix.
+func geminiModelID(model map[string]any) string {
+	name, _ := model["name"].(string)
+	return strings.TrimPrefix(name, "models/")
 }
 
 // GeminiGetHandler handles GET requests for specific Gemini model information.
@@ -95,7 +121,7 @@ func (h *GeminiAPIHandler) GeminiGetHandler(c *gin.Context) {
 	action := strings.TrimPrefix(request.Action, "/")
 
 	// Get dynamic models from the global registry and find the matching one
-	availableModels := h.Models()
+	availableModels := h.AllowedModels(c, h.Models(), geminiModelID)
 	var targetModel map[string]any
 
 	for _, model := range availableModels {
@@ -163,17 +189,37 @@ func (h *GeminiAPIHandler) GeminiHandler(c *gin.Context) {
 	}
 }
 
+// StreamGenerateContentWebsocket serves models/{model}:streamGenerateContent
+// over a websocket. The client sends the usual request body as its first
+// message and receives each GenerateContentResponse chunk as a text frame.
+func (h *GeminiAPIHandler) StreamGenerateContentWebsocket(c *gin.Context) {
+	action := strings.Split(strings.TrimPrefix(c.Param("action"), "/"), ":")
+	if len(action) != 2 || action[1] != "streamGenerateContent" {
+		c.JSON(http.StatusNotFound, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("%s not found.", c.Request.URL.Path),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	modelName := action[0]
+	h.ServeStreamWebsocket(c, h, func(ctx context.Context, rawJSON []byte) (<-chan []byte, http.Header, <-chan *interfaces.ErrorMessage) {
+		return h.ExecuteStreamWithAuthManager(ctx, h.HandlerType(), modelName, rawJSON, "")
+	})
+}
+
 // handleStreamGenerateContent handles streaming content generation requests for Gemini models.
-// This function establishes a Server-Sent Events connection and streams the generated content
-// back to the client in real-time. It supports both SSE format and direct streaming based
-// on the 'alt' query parameter.
+// Like the Gemini API, the response is a JSON array of chunks written as they
+// arrive when the 'alt' query parameter is absent or "json", and Server-Sent
+// Events when it is "sse".
 //
 // Parameters:
 //   - c: The Gin context for the request
 //   - modelName: The name of the Gemini model to use for content generation
 //   - rawJSON: The raw JSON request body containing generation parameters
 func (h *GeminiAPIHandler) handleStreamGenerateContent(c *gin.Context, modelName string, rawJSON []byte) {
-	alt := h.GetAlt(c)
+	alt := h.streamAlt(c)
 
 	// Get the http.Flusher interface to manually flush the response.
 	flusher, ok := c.Writer.(http.Flusher)
@@ -189,13 +235,7 @@ func (h *GeminiAPIHandler) handleStreamGenerateContent(c *gin.Context, modelName
 
 	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
 	dataChan, upstreamHeaders, errChan := h.ExecuteStreamWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, alt)
-
-	setSSEHeaders := func() {
-		c.Header("Content-Type", "text/event-stream")
-		c.Header("Cache-Control", "no-cache")
-		c.Header("Connection", "keep-alive")
-		c.Header("Access-Control-Allow-Origin", "*")
-	}
+	framer := &geminiStreamFramer{w: c.Writer, alt: alt}
 
 	// Peek at the first chunk
 	for {
@@ -220,38 +260,41 @@ func (h *GeminiAPIHandler) handleStreamGenerateContent(c *gin.Context, modelName
 		case chunk, ok := <-dataChan:
 			if !ok {
 				// Closed without data
-				if alt == "" {
-					setSSEHeaders()
-				}
+				framer.setHeaders(c)
 				handlers.WriteUpstreamHeaders(c.Writer.Header(), upstreamHeaders)
+				framer.done()
 				flusher.Flush()
 				cliCancel(nil)
 				return
 			}
 
 			// Success! Set headers.
-			if alt == "" {
-				setSSEHeaders()
-			}
+			framer.setHeaders(c)
 			handlers.WriteUpstreamHeaders(c.Writer.Header(), upstreamHeaders)
 
 			// Write first chunk
-			if alt == "" {
-				_, _ = c.Writer.Write([]byte("data: "))
-				_, _ = c.Writer.Write(chunk)
-				_, _ = c.Writer.Write([]byte("\n\n"))
-			} else {
-				_, _ = c.Writer.Write(chunk)
-			}
+			framer.chunk(chunk)
 			flusher.Flush()
 
 			// Continue
-			h.forwardGeminiStream(c, flusher, alt, func(err error) { cliCancel(err) }, dataChan, errChan)
+			h.forwardGeminiStream(c, flusher, framer, func(err error) { cliCancel(err) }, dataChan, errChan)
 			return
 		}
 	}
 }
 
+// streamAlt resolves the alt parameter of a streamGenerateContent request.
+// A request without alt streams a JSON array, as the Gemini API does, so it
+// resolves to geminiStreamAltJSON; alt=sse resolves to "".
+func (h *GeminiAPIHandler) streamAlt(c *gin.Context) string {
+	_, hasAlt := c.GetQuery("alt")
+	_, hasDollarAlt := c.GetQuery("$alt")
+	if !hasAlt && !hasDollarAlt {
+		return geminiStreamAltJSON
+	}
+	return h.GetAlt(c)
+}
+
 // handleCountTokens handles token counting requests for Gemini models.
 // This function counts the number of tokens in the provided content without
 // generating a response. It's useful for quota management and content validation.
@@ -301,23 +344,15 @@ func (h *GeminiAPIHandler) handleGenerateContent(c *gin.Context, modelName strin
 	cliCancel()
 }
 
-func (h *GeminiAPIHandler) forwardGeminiStream(c *gin.Context, flusher http.Flusher, alt string, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
+func (h *GeminiAPIHandler) forwardGeminiStream(c *gin.Context, flusher http.Flusher, framer *geminiStreamFramer, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
 	var keepAliveInterval *time.Duration
-	if alt != "" {
+	if framer.alt != "" {
 		keepAliveInterval = new(time.Duration(0))
 	}
 
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
 		KeepAliveInterval: keepAliveInterval,
-		WriteChunk: func(chunk []byte) {
-			if alt == "" {
-				_, _ = c.Writer.Write([]byte("data: "))
-				_, _ = c.Writer.Write(chunk)
-				_, _ = c.Writer.Write([]byte("\n\n"))
-			} else {
-				_, _ = c.Writer.Write(chunk)
-			}
-		},
+		WriteChunk:        framer.chunk,
 		WriteTerminalError: func(errMsg *interfaces.ErrorMessage) {
 			if errMsg == nil {
 				return
@@ -330,12 +365,8 @@ func (h *GeminiAPIHandler) forwardGeminiStream(c *gin.Context, flusher http.Flus
 			if errMsg.Error != nil && errMsg.Error.Error() != "" {
 				errText = errMsg.Error.Error()
 			}
-			body := handlers.BuildErrorResponseBody(status, errText)
-			if alt == "" {
-				_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", string(body))
-			} else {
-				_, _ = c.Writer.Write(body)
-			}
+			framer.error(handlers.BuildStreamErrorResponseBody(status, errText))
 		},
+		WriteDone: framer.done,
 	})
 }
@@ -0,0 +1,78 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+)
+
+func TestGeminiModelsWalksPagesWithPageToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var want []string
+	var models []*registry.ModelInfo
+	for i := 1; i <= 32; i++ {
+		id := fmt.Sprintf("gemini-listing-walk-%02d", i)
+		want = append(want, "models/"+id)
+		models = append(models, &registry.ModelInfo{ID: id, Name: id})
+	}
+	registryRef := registry.GetGlobalRegistry()
+	registryRef.RegisterClient("gemini-listing-walk", "gemini", models)
+	t.Cleanup(func() {
+		registryRef.UnregisterClient("gemini-listing-walk")
+	})
+	h := NewGeminiAPIHandler(&handlers.BaseAPIHandler{})
+
+	var walked []string
+	query := url.Values{"search": {"gemini-listing-walk"}, "provider": {"gemini"}, "pageSize": {"6"}}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("pagination did not terminate; walked %v", walked)
+		}
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodGet, "/v1beta/models?"+query.Encode(), nil)
+		h.GeminiModels(c)
+
+		var response struct {
+			Models []struct {
+				Name string `json:"name"`
+			} `json:"models"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if errUnmarshal := json.Unmarshal(recorder.Body.Bytes(), &response); errUnmarshal != nil {
+			t.Fatalf("decode response: %v; body=%s", errUnmarshal, recorder.Body.String())
+		}
+		if len(response.Models) > 6 {
+			t.Fatalf("page %d has %d models, want at most 6", pages, len(response.Models))
+		}
+		for _, model := range response.Models {
+			walked = append(walked, model.Name)
+		}
+		if response.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", response.NextPageToken)
+	}
+
+	if fmt.Sprint(walked) != fmt.Sprint(want) {
+		t.Fatalf("walked %v, want %v", walked, want)
+	}
+}
+
+func TestGeminiModelsRejectsInvalidPageSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1beta/models?pageSize=-1", nil)
+	NewGeminiAPIHandler(&handlers.BaseAPIHandler{}).GeminiModels(c)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", recorder.Code)
+	}
+}
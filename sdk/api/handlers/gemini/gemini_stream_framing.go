@@ -0,0 +1,81 @@
+package gemini
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geminiStreamAltJSON is the alt value of a streamGenerateContent response
+// written as a JSON array.
+const geminiStreamAltJSON = "json"
+
+// geminiStreamFramer writes streamGenerateContent chunks in the framing the
+// client's alt parameter asked for: SSE events for "" (alt=sse), the elements
+// of a JSON array for geminiStreamAltJSON, and raw chunks otherwise.
+type geminiStreamFramer struct {
+	w     io.Writer
+	alt   string
+	wrote bool
+}
+
+func (f *geminiStreamFramer) setHeaders(c *gin.Context) {
+	switch f.alt {
+	case "":
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("Access-Control-Allow-Origin", "*")
+	case geminiStreamAltJSON:
+		c.Header("Content-Type", "application/json")
+	}
+}
+
+func (f *geminiStreamFramer) chunk(chunk []byte) {
+	switch f.alt {
+	case "":
+		_, _ = f.w.Write([]byte("data: "))
+		_, _ = f.w.Write(chunk)
+		_, _ = f.w.Write([]byte("\n\n"))
+	case geminiStreamAltJSON:
+		f.arrayElement(chunk)
+	default:
+		_, _ = f.w.Write(chunk)
+	}
+}
+
+// done closes the JSON array; an empty stream becomes "[]".
+func (f *geminiStreamFramer) done() {
+	if f.alt != geminiStreamAltJSON {
+		return
+	}
+	if !f.wrote {
+		_, _ = f.w.Write([]byte("["))
+	}
+	_, _ = f.w.Write([]byte("]"))
+}
+
+// error writes a mid-stream error. In a JSON array it becomes the last element
+// so the body stays well-formed.
+func (f *geminiStreamFramer) error(body []byte) {
+	switch f.alt {
+	case "":
+		_, _ = fmt.Fprintf(f.w, "event: error\ndata: %s\n\n", string(body))
+	case geminiStreamAltJSON:
+		f.arrayElement(body)
+		f.done()
+	default:
+		_, _ = f.w.Write(body)
+	}
+}
+
+func (f *geminiStreamFramer) arrayElement(element []byte) {
+	if f.wrote {
+		_, _ = f.w.Write([]byte(","))
+	} else {
+		_, _ = f.w.Write([]byte("["))
+		f.wrote = true
+	}
+	_, _ = f.w.Write(element)
+}
@@ -0,0 +1,175 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	geminiStreamChunkA = `{"candidates":[{"content":{"role":"model","parts":[{"text":"hel"}]}}]}`
+	geminiStreamChunkB = `{"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]}}]}`
+)
+
+// geminiStreamFramingExecutor streams fixed chunks and records the alt it was
+// asked for.
+type geminiStreamFramingExecutor struct {
+	chunks []string
+	alt    chan string
+}
+
+func (*geminiStreamFramingExecutor) Identifier() string { return "gemini-stream-framing-test" }
+
+func (*geminiStreamFramingExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *geminiStreamFramingExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, _ coreexecutor.Request, opts coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	e.alt <- opts.Alt
+	chunks := make(chan coreexecutor.StreamChunk, len(e.chunks))
+	for _, chunk := range e.chunks {
+		chunks <- coreexecutor.StreamChunk{Payload: []byte(chunk)}
+	}
+	close(chunks)
+	return &coreexecutor.StreamResult{Chunks: chunks}, nil
+}
+
+func (*geminiStreamFramingExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (*geminiStreamFramingExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (*geminiStreamFramingExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func streamGenerateContent(t *testing.T, query string, chunks ...string) (*http.Response, string, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	executor := &geminiStreamFramingExecutor{chunks: chunks, alt: make(chan string, 1)}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "gemini-stream-framing-auth-" + t.Name(), Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register() error = %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gemini-stream-framing-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	h := NewGeminiAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.POST("/v1beta/models/*action", h.GeminiHandler)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	resp, errPost := http.Post(server.URL+"/v1beta/models/gemini-stream-framing-model:streamGenerateContent"+query, "application/json", nil)
+	if errPost != nil {
+		t.Fatalf("post: %v", errPost)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, errRead := io.ReadAll(resp.Body)
+	if errRead != nil {
+		t.Fatalf("read body: %v", errRead)
+	}
+	return resp, string(body), <-executor.alt
+}
+
+func TestStreamGenerateContentFramesSSEForAltSSE(t *testing.T) {
+	resp, body, alt := streamGenerateContent(t, "?alt=sse", geminiStreamChunkA, geminiStreamChunkB)
+	if alt != "" {
+		t.Fatalf("upstream alt = %q, want SSE", alt)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", got)
+	}
+	want := "data: " + geminiStreamChunkA + "\n\n" + "data: " + geminiStreamChunkB + "\n\n"
+	if body != want {
+		t.Fatalf("body = %q, want %q", body, want)
+	}
+}
+
+func TestStreamGenerateContentFramesJSONArrayWithoutAlt(t *testing.T) {
+	for _, query := range []string{"", "?alt=json"} {
+		t.Run("query="+query, func(t *testing.T) {
+			resp, body, alt := streamGenerateContent(t, query, geminiStreamChunkA, geminiStreamChunkB)
+			if alt != geminiStreamAltJSON {
+				t.Fatalf("upstream alt = %q, want %q", alt, geminiStreamAltJSON)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/json" {
+				t.Fatalf("Content-Type = %q, want application/json", got)
+			}
+			want := "[" + geminiStreamChunkA + "," + geminiStreamChunkB + "]"
+			if body != want {
+				t.Fatalf("body = %q, want %q", body, want)
+			}
+			var parsed []map[string]any
+			if errUnmarshal := json.Unmarshal([]byte(body), &parsed); errUnmarshal != nil || len(parsed) != 2 {
+				t.Fatalf("body is not a two-element JSON array: %v", errUnmarshal)
+			}
+		})
+	}
+}
+
+func TestGeminiStreamFramerJSONArray(t *testing.T) {
+	var empty bytes.Buffer
+	(&geminiStreamFramer{w: &empty, alt: geminiStreamAltJSON}).done()
+	if empty.String() != "[]" {
+		t.Fatalf("empty stream = %q, want []", empty.String())
+	}
+
+	var failed bytes.Buffer
+	framer := &geminiStreamFramer{w: &failed, alt: geminiStreamAltJSON}
+	framer.chunk([]byte(`{"a":1}`))
+	framer.error([]byte(`{"error":{}}`))
+	if failed.String() != `[{"a":1},{"error":{}}]` {
+		t.Fatalf("errored stream = %q, want the error as the last element", failed.String())
+	}
+}
+
+func TestForwardGeminiStreamWrapsHTMLErrorInJSONErrorEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewGeminiAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil))
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1beta/models/gemini-2.5-pro:streamGenerateContent", nil)
+
+	data := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage, 1)
+	errs <- &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadGateway,
+		Error:      errors.New("<html>\n<head><title>502 Bad Gateway</title></head>\n</html>"),
+	}
+	close(errs)
+
+	h.forwardGeminiStream(c, c.Writer, &geminiStreamFramer{w: c.Writer}, func(error) {}, data, errs)
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "event: error\ndata: ") || !strings.HasSuffix(body, "\n\n") {
+		t.Fatalf("expected a single error event, got: %q", body)
+	}
+	payload := strings.TrimSuffix(strings.TrimPrefix(body, "event: error\ndata: "), "\n\n")
+	if strings.Contains(payload, "\n") || !json.Valid([]byte(payload)) {
+		t.Fatalf("expected one line of JSON in the error event, got: %q", payload)
+	}
+	if got := gjson.Get(payload, "error.status").Int(); got != http.StatusBadGateway {
+		t.Fatalf("error.status = %d, want 502; payload=%s", got, payload)
+	}
+}
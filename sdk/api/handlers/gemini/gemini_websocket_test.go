@@ -0,0 +1,97 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+type geminiWebsocketExecutor struct {
+	model string
+}
+
+func (*geminiWebsocketExecutor) Identifier() string { return "gemini-websocket-test" }
+
+func (*geminiWebsocketExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *geminiWebsocketExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	e.model = req.Model
+	chunks := make(chan coreexecutor.StreamChunk, 1)
+	chunks <- coreexecutor.StreamChunk{Payload: []byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`)}
+	close(chunks)
+	return &coreexecutor.StreamResult{Chunks: chunks}, nil
+}
+
+func (*geminiWebsocketExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (*geminiWebsocketExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (*geminiWebsocketExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestStreamGenerateContentWebsocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	executor := &geminiWebsocketExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "gemini-websocket-auth", Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register() error = %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gemini-websocket-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	h := NewGeminiAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.GET("/v1beta/models/*action", h.StreamGenerateContentWebsocket)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1beta/models/gemini-websocket-model:streamGenerateContent"
+	conn, _, errDial := websocket.DefaultDialer.Dial(wsURL, nil)
+	if errDial != nil {
+		t.Fatalf("dial websocket: %v", errDial)
+	}
+	defer func() { _ = conn.Close() }()
+	if errWrite := conn.WriteMessage(websocket.TextMessage, []byte(`{"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`)); errWrite != nil {
+		t.Fatalf("write request: %v", errWrite)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frames []string
+	for {
+		_, payload, errRead := conn.ReadMessage()
+		if errRead != nil {
+			if !websocket.IsCloseError(errRead, websocket.CloseNormalClosure) {
+				t.Fatalf("read frame: %v", errRead)
+			}
+			break
+		}
+		frames = append(frames, string(payload))
+	}
+	if len(frames) != 2 || !strings.Contains(frames[0], `"candidates"`) || frames[1] != handlers.WebsocketDoneFrame {
+		t.Fatalf("frames = %q, want one chunk and the done frame", frames)
+	}
+	if executor.model != "gemini-websocket-model" {
+		t.Fatalf("executor model = %q, want model from the URL", executor.model)
+	}
+}
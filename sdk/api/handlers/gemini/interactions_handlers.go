@@ -195,7 +195,7 @@ func (h *GeminiAPIHandler) forwardInteractionsStream(c *gin.Context, flusher htt
 			if errMsg.Error != nil && errMsg.Error.Error() != "" {
 				errText = errMsg.Error.Error()
 			}
-			body := handlers.BuildErrorResponseBody(status, errText)
+			body := handlers.BuildStreamErrorResponseBody(status, errText)
 			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", string(body))
 		},
 	})
@@ -11,8 +11,10 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,7 +22,10 @@ import (
 	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/requeststore"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/transformtrace"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
@@ -52,7 +57,7 @@ type ErrorDetail struct {
 	Code string `json:"code,omitempty"`
 }
 
-const idempotencyKeyMetadataKey = "idempotency_key"
+const idempotencyKeyMetadataKey = coreexecutor.IdempotencyKeyMetadataKey
 
 const (
 	defaultStreamingKeepAliveSeconds = 0
@@ -198,6 +203,45 @@ func BuildErrorResponseBody(status int, errText string) []byte {
 		return []byte(trimmed)
 	}
 
+	payload, err := json.Marshal(ErrorResponse{Error: errorDetailForStatus(status, errText)})
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"error":{"message":%q,"type":"server_error","code":"internal_server_error"}}`, errText))
+	}
+	return payload
+}
+
+// BuildStreamErrorResponseBody builds the JSON payload of an error event sent after a stream
+// has started. A JSON upstream body passes through as in BuildErrorResponseBody; anything
+// else, such as an HTML error page from a proxy, is wrapped into the error envelope together
+// with the upstream status so clients can still parse the event.
+func BuildStreamErrorResponseBody(status int, errText string) []byte {
+	if status <= 0 {
+		status = http.StatusInternalServerError
+	}
+	if strings.TrimSpace(errText) == "" {
+		errText = http.StatusText(status)
+	}
+
+	trimmed := strings.TrimSpace(errText)
+	if json.Valid([]byte(trimmed)) {
+		return []byte(trimmed)
+	}
+
+	type streamErrorDetail struct {
+		ErrorDetail
+		Status int `json:"status"`
+	}
+	payload, err := json.Marshal(map[string]streamErrorDetail{
+		"error": {ErrorDetail: errorDetailForStatus(status, trimmed), Status: status},
+	})
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"error":{"message":%q,"type":"server_error","code":"internal_server_error","status":%d}}`, trimmed, status))
+	}
+	return payload
+}
+
+// errorDetailForStatus describes errText with the OpenAI error type and code matching status.
+func errorDetailForStatus(status int, errText string) ErrorDetail {
 	errType := "invalid_request_error"
 	var code string
 	switch status {
@@ -220,17 +264,11 @@ func BuildErrorResponseBody(status int, errText string) []byte {
 		}
 	}
 
-	payload, err := json.Marshal(ErrorResponse{
-		Error: ErrorDetail{
-			Message: errText,
-			Type:    errType,
-			Code:    code,
-		},
-	})
-	if err != nil {
-		return []byte(fmt.Sprintf(`{"error":{"message":%q,"type":"server_error","code":"internal_server_error"}}`, errText))
+	return ErrorDetail{
+		Message: errText,
+		Type:    errType,
+		Code:    code,
 	}
-	return payload
 }
 
 // StreamingKeepAliveInterval returns the SSE keep-alive interval for this server.
@@ -271,6 +309,21 @@ func StreamingBootstrapRetries(cfg *config.SDKConfig) int {
 	return retries
 }
 
+// StreamingResumeOnError returns whether streams cut by a retryable error after the first
+// byte are resumed. Default is false.
+func StreamingResumeOnError(cfg *config.SDKConfig) bool {
+	return cfg != nil && cfg.Streaming.ResumeOnError
+}
+
+// StreamingCoalesceWindow returns how long text deltas are buffered before they are
+// written. Returning 0 disables coalescing (default when unset).
+func StreamingCoalesceWindow(cfg *config.SDKConfig) time.Duration {
+	if cfg == nil || cfg.Streaming.CoalesceMs <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.Streaming.CoalesceMs) * time.Millisecond
+}
+
 // PassthroughHeadersEnabled returns whether upstream response headers should be forwarded to clients.
 // Default is false.
 func PassthroughHeadersEnabled(cfg *config.SDKConfig) bool {
@@ -281,12 +334,14 @@ func requestExecutionMetadata(ctx context.Context) map[string]any {
 	// Idempotency-Key is an optional client-supplied header used to correlate retries.
 	// Only include it if the client explicitly provides it.
 	key := ""
+	sessionID := ""
 	requestPath := ""
 	var ginCtx *gin.Context
 	if ctx != nil {
 		if requestGinCtx, ok := ctx.Value("gin").(*gin.Context); ok && requestGinCtx != nil && requestGinCtx.Request != nil {
 			ginCtx = requestGinCtx
 			key = strings.TrimSpace(ginCtx.GetHeader("Idempotency-Key"))
+			sessionID = strings.TrimSpace(ginCtx.GetHeader("X-Session-Id"))
 			requestPath = strings.TrimSpace(ginCtx.FullPath())
 			if requestPath == "" && ginCtx.Request.URL != nil {
 				requestPath = strings.TrimSpace(ginCtx.Request.URL.Path)
@@ -298,6 +353,9 @@ func requestExecutionMetadata(ctx context.Context) map[string]any {
 	if key != "" {
 		meta[idempotencyKeyMetadataKey] = key
 	}
+	if sessionID != "" {
+		meta[coreexecutor.SessionIDMetadataKey] = sessionID
+	}
 	if requestPath != "" {
 		meta[coreexecutor.RequestPathMetadataKey] = requestPath
 	}
@@ -318,9 +376,88 @@ func requestExecutionMetadata(ctx context.Context) map[string]any {
 	if disallowFreeAuthFromContext(ctx) {
 		meta[coreexecutor.DisallowFreeAuthMetadataKey] = true
 	}
+	if ginCtx != nil {
+		if apiKey := ginCtx.GetString("userApiKey"); apiKey != "" {
+			meta[coreexecutor.ClientAPIKeyMetadataKey] = apiKey
+		}
+	}
+	return meta
+}
+
+// executionMetadata extends requestExecutionMetadata with the auth header callback, so
+// the credential used for the final attempt can be exposed in response headers.
+func (h *BaseAPIHandler) executionMetadata(ctx context.Context) map[string]any {
+	meta := requestExecutionMetadata(ctx)
+	if priority := h.requestPriority(ctx); priority != "" {
+		meta[coreexecutor.PriorityMetadataKey] = priority
+	}
+	if h == nil || h.AuthManager == nil || ctx == nil {
+		return meta
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return meta
+	}
+	recordAuth := logging.GinAuthHeaderCallback(ginCtx)
+	if recordAuth == nil {
+		return meta
+	}
+	selectedCallback, _ := meta[coreexecutor.SelectedAuthCallbackMetadataKey].(func(string))
+	manager := h.AuthManager
+	meta[coreexecutor.SelectedAuthCallbackMetadataKey] = func(authID string) {
+		if selectedCallback != nil {
+			selectedCallback(authID)
+		}
+		auth, found := manager.GetByID(authID)
+		if !found || auth == nil {
+			return
+		}
+		label := strings.TrimSpace(auth.Label)
+		if label == "" {
+			label = auth.ID
+		}
+		recordAuth(auth.Provider, label)
+	}
 	return meta
 }
 
+// PriorityHeader selects the priority class ("high", "normal" or "low") a request gets
+// while it waits for an upstream slot under the request-priority concurrency caps.
+const PriorityHeader = "X-CLIProxy-Priority"
+
+// requestPriority returns the priority class of the request: the class configured for
+// the client API key, lowered by a valid PriorityHeader value. The header cannot raise a
+// request above the key's class, or above normal for keys without one. It returns "" when
+// neither is set.
+func (h *BaseAPIHandler) requestPriority(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil || ginCtx.Request == nil {
+		return ""
+	}
+	keyPriority := ""
+	if h != nil && h.Cfg != nil && len(h.Cfg.RequestPriority.APIKeyPriorities) > 0 {
+		apiKey, _ := ginCtx.Get("userApiKey")
+		if key, _ := apiKey.(string); key != "" {
+			keyPriority, _ = config.NormalizeRequestPriority(h.Cfg.RequestPriority.APIKeyPriorities[key])
+		}
+	}
+	priority, valid := config.NormalizeRequestPriority(ginCtx.GetHeader(PriorityHeader))
+	if !valid {
+		return keyPriority
+	}
+	ceiling := keyPriority
+	if ceiling == "" {
+		ceiling = config.RequestPriorityNormal
+	}
+	if config.RequestPriorityRank(priority) > config.RequestPriorityRank(ceiling) {
+		return ceiling
+	}
+	return priority
+}
+
 func addAuthSelectionModelMetadata(meta map[string]any, model string) {
 	if meta == nil {
 		return
@@ -358,6 +495,23 @@ func setServiceTierMetadata(meta map[string]any, rawJSON []byte) {
 	meta[coreexecutor.ServiceTierMetadataKey] = serviceTier
 }
 
+// setSessionIDMetadata pins the session from the session_id or metadata.session_id body
+// field when the X-Session-Id header did not.
+func setSessionIDMetadata(meta map[string]any, rawJSON []byte) {
+	if meta == nil {
+		return
+	}
+	if _, ok := meta[coreexecutor.SessionIDMetadataKey]; ok {
+		return
+	}
+	for _, path := range []string{"session_id", "metadata.session_id"} {
+		if sessionID := strings.TrimSpace(gjson.GetBytes(rawJSON, path).String()); sessionID != "" {
+			meta[coreexecutor.SessionIDMetadataKey] = sessionID
+			return
+		}
+	}
+}
+
 func setGenerateMetadata(meta map[string]any, rawJSON []byte) {
 	if meta == nil {
 		return
@@ -463,6 +617,12 @@ type BaseAPIHandler struct {
 	// ModelRouterHost optionally routes matching requests to a plugin executor, the router's own
 	// executor, or a built-in provider before model-to-provider resolution and auth selection.
 	ModelRouterHost PluginModelRouterHost
+
+	// streams tracks active streaming responses for graceful shutdown.
+	streams *streamRegistry
+
+	// responseFilters post-process responses right before they are written.
+	responseFilters atomic.Pointer[[]ResponseFilter]
 }
 
 // NewBaseAPIHandlers creates a new API handlers instance.
@@ -478,6 +638,7 @@ func NewBaseAPIHandlers(cfg *config.SDKConfig, authManager *coreauth.Manager) *B
 	return &BaseAPIHandler{
 		Cfg:         cfg,
 		AuthManager: authManager,
+		streams:     newStreamRegistry(),
 	}
 }
 
@@ -608,6 +769,9 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 	}
 	newCtx = logging.WithResponseStatusHolder(newCtx)
 	newCtx = logging.WithResponseHeadersHolder(newCtx)
+	newCtx = requeststore.WithCapture(newCtx)
+	newCtx = transformtrace.NewContext(newCtx, requestTransformTrace(c))
+	newCtx = tracing.ContextWithSpanFrom(newCtx, requestCtx)
 
 	cancelCtx := newCtx
 	if requestCtx != nil && requestCtx != parentCtx {
@@ -671,6 +835,22 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 	}
 }
 
+// requestTransformTrace returns the transformation trace of the request behind c, creating it
+// on first use so every context derived for the request records into the same trace.
+func requestTransformTrace(c *gin.Context) *transformtrace.Trace {
+	if c == nil {
+		return transformtrace.New()
+	}
+	if existing, exists := c.Get(transformtrace.GinKey); exists {
+		if trace, ok := existing.(*transformtrace.Trace); ok && trace != nil {
+			return trace
+		}
+	}
+	trace := transformtrace.New()
+	c.Set(transformtrace.GinKey, trace)
+	return trace
+}
+
 // StartNonStreamingKeepAlive emits blank lines every 5 seconds while waiting for a non-streaming response.
 // It returns a stop function that must be called before writing the final response.
 func (h *BaseAPIHandler) StartNonStreamingKeepAlive(c *gin.Context, ctx context.Context) func() {
@@ -762,6 +942,7 @@ func (h *BaseAPIHandler) executeWithAuthManager(ctx context.Context, handlerType
 
 func (h *BaseAPIHandler) executeWithAuthManagerFormats(ctx context.Context, entryProtocol, exitProtocol, modelName string, rawJSON []byte, alt string, allowImageModel bool, execOptions modelExecutionOptions) ([]byte, http.Header, *interfaces.ErrorMessage) {
 	originalRequestedModel := modelName
+	tracing.SetAttributes(ctx, tracing.AttrHandler.String(entryProtocol), tracing.AttrModel.String(modelName))
 	routeDecision := h.applyModelRouter(ctx, entryProtocol, modelName, rawJSON, false, execOptions)
 	responseProtocol := modelExecutionResponseProtocol(entryProtocol, exitProtocol)
 	if errMsg := validateNativeInteractionsExecution(entryProtocol, execOptions, routeDecision); errMsg != nil {
@@ -770,17 +951,24 @@ func (h *BaseAPIHandler) executeWithAuthManagerFormats(ctx context.Context, entr
 	if routeDecision.ExecutorPluginID != "" {
 		return h.executeWithPluginExecutor(ctx, entryProtocol, responseProtocol, modelName, originalRequestedModel, rawJSON, alt, routeDecision.ExecutorPluginID, execOptions)
 	}
+	modelName = h.resolveAutoModelForRequest(ctx, modelName, rawJSON, routeDecision, execOptions)
 	providers, normalizedModel, errMsg := h.providersForExecution(modelName, originalRequestedModel, allowImageModel, routeDecision, execOptions)
 	if errMsg != nil {
 		return nil, nil, errMsg
 	}
+	providers, errMsg = h.applyModelPolicy(ctx, providers, normalizedModel)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	normalizedModel = modelWithThinkingHeader(ctx, normalizedModel, execOptions.Headers)
 	providers = adjustExecutionProvidersForEntryProtocol(entryProtocol, providers)
-	reqMeta := requestExecutionMetadata(ctx)
+	reqMeta := h.executionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = originalRequestedModel
 	addAuthSelectionModelMetadata(reqMeta, execOptions.AuthSelectionModel)
 	addModelExecutionSourceMetadata(reqMeta, execOptions.InternalSource)
 	setReasoningEffortMetadata(reqMeta, entryProtocol, normalizedModel, rawJSON)
 	setServiceTierMetadata(reqMeta, rawJSON)
+	setSessionIDMetadata(reqMeta, rawJSON)
 	setGenerateMetadata(reqMeta, rawJSON)
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -806,25 +994,13 @@ func (h *BaseAPIHandler) executeWithAuthManagerFormats(ctx context.Context, entr
 	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
 	if err != nil {
 		err = enrichAuthSelectionError(err, providers, normalizedModel)
-		status := http.StatusInternalServerError
-		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
-			if code := se.StatusCode(); code > 0 {
-				status = code
-			}
-		}
-		var addon http.Header
-		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
-			if hdr := he.Headers(); hdr != nil {
-				addon = hdr.Clone()
-			}
-		}
-		return nil, nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		return nil, nil, executionErrorMessage(err)
 	}
 	executedReq, executedOpts := afterAuthCapture.apply(req, opts)
 	rawResponseHeaders := cloneHeader(resp.Headers)
 	responseHeaders := downstreamHeadersFromExecutor(rawResponseHeaders, PassthroughHeadersEnabled(h.Cfg))
 	body, responseHeaders := h.applyResponseInterceptors(ctx, responseProtocol, normalizedModel, originalRequestedModel, executedOpts, rawResponseHeaders, responseHeaders, executedOpts.OriginalRequest, executedReq.Payload, resp.Payload, http.StatusOK, execOptions.SkipInterceptorPluginID)
-	return body, responseHeaders, nil
+	return h.filterNonStreamResponse(responseProtocol, originalRequestedModel, body), responseHeaders, nil
 }
 
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
@@ -835,20 +1011,28 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 
 func (h *BaseAPIHandler) executeCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string, execOptions modelExecutionOptions) ([]byte, http.Header, *interfaces.ErrorMessage) {
 	originalRequestedModel := modelName
+	tracing.SetAttributes(ctx, tracing.AttrHandler.String(handlerType), tracing.AttrModel.String(modelName))
 	routeDecision := h.applyModelRouter(ctx, handlerType, modelName, rawJSON, false, execOptions)
 	if routeDecision.ExecutorPluginID != "" {
 		return h.countWithPluginExecutor(ctx, handlerType, modelName, originalRequestedModel, rawJSON, alt, routeDecision.ExecutorPluginID, execOptions)
 	}
+	modelName = h.resolveAutoModelForRequest(ctx, modelName, rawJSON, routeDecision, execOptions)
 	providers, normalizedModel, errMsg := h.providersForExecution(modelName, originalRequestedModel, false, routeDecision, execOptions)
 	if errMsg != nil {
 		return nil, nil, errMsg
 	}
+	providers, errMsg = h.applyModelPolicy(ctx, providers, normalizedModel)
+	if errMsg != nil {
+		return nil, nil, errMsg
+	}
+	normalizedModel = modelWithThinkingHeader(ctx, normalizedModel, execOptions.Headers)
 	providers = adjustExecutionProvidersForEntryProtocol(handlerType, providers)
-	reqMeta := requestExecutionMetadata(ctx)
+	reqMeta := h.executionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = originalRequestedModel
 	addAuthSelectionModelMetadata(reqMeta, execOptions.AuthSelectionModel)
 	setReasoningEffortMetadata(reqMeta, handlerType, normalizedModel, rawJSON)
 	setServiceTierMetadata(reqMeta, rawJSON)
+	setSessionIDMetadata(reqMeta, rawJSON)
 	setGenerateMetadata(reqMeta, rawJSON)
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -873,19 +1057,7 @@ func (h *BaseAPIHandler) executeCountWithAuthManager(ctx context.Context, handle
 	resp, err := h.AuthManager.ExecuteCount(ctx, providers, req, opts)
 	if err != nil {
 		err = enrichAuthSelectionError(err, providers, normalizedModel)
-		status := http.StatusInternalServerError
-		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
-			if code := se.StatusCode(); code > 0 {
-				status = code
-			}
-		}
-		var addon http.Header
-		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
-			if hdr := he.Headers(); hdr != nil {
-				addon = hdr.Clone()
-			}
-		}
-		return nil, nil, &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		return nil, nil, executionErrorMessage(err)
 	}
 	executedReq, executedOpts := afterAuthCapture.apply(req, opts)
 	rawResponseHeaders := cloneHeader(resp.Headers)
@@ -912,7 +1084,7 @@ func (h *BaseAPIHandler) executeWithPluginExecutor(ctx context.Context, entryPro
 	rawResponseHeaders := cloneHeader(resp.Headers)
 	responseHeaders := downstreamHeadersFromExecutor(rawResponseHeaders, PassthroughHeadersEnabled(h.Cfg))
 	body, responseHeaders := h.applyResponseInterceptors(ctx, responseProtocol, modelName, originalRequestedModel, opts, rawResponseHeaders, responseHeaders, opts.OriginalRequest, req.Payload, resp.Payload, http.StatusOK, execOptions.SkipInterceptorPluginID)
-	return body, responseHeaders, nil
+	return h.filterNonStreamResponse(responseProtocol, originalRequestedModel, body), responseHeaders, nil
 }
 
 func (h *BaseAPIHandler) countWithPluginExecutor(ctx context.Context, handlerType, modelName, originalRequestedModel string, rawJSON []byte, alt, executorPluginID string, execOptions modelExecutionOptions) ([]byte, http.Header, *interfaces.ErrorMessage) {
@@ -937,12 +1109,13 @@ func (h *BaseAPIHandler) countWithPluginExecutor(ctx context.Context, handlerTyp
 }
 
 func (h *BaseAPIHandler) pluginExecutorRequest(ctx context.Context, entryProtocol, responseProtocol, modelName, originalRequestedModel string, rawJSON []byte, alt string, stream bool, execOptions modelExecutionOptions) (coreexecutor.Request, coreexecutor.Options) {
-	reqMeta := requestExecutionMetadata(ctx)
+	reqMeta := h.executionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = originalRequestedModel
 	addAuthSelectionModelMetadata(reqMeta, execOptions.AuthSelectionModel)
 	addModelExecutionSourceMetadata(reqMeta, execOptions.InternalSource)
 	setReasoningEffortMetadata(reqMeta, entryProtocol, modelName, rawJSON)
 	setServiceTierMetadata(reqMeta, rawJSON)
+	setSessionIDMetadata(reqMeta, rawJSON)
 	setGenerateMetadata(reqMeta, rawJSON)
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -997,6 +1170,11 @@ func executionErrorMessage(err error) *interfaces.ErrorMessage {
 			status = code
 		}
 	}
+	if status == statusOverloaded {
+		// Clients do not know the non-standard 529; the response carries a Retry-After
+		// instead, see RetryAfterValues.
+		status = http.StatusServiceUnavailable
+	}
 	var addon http.Header
 	if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
 		if hdr := he.Headers(); hdr != nil {
@@ -1147,7 +1325,7 @@ func (h *BaseAPIHandler) streamWithPluginExecutor(ctx context.Context, entryProt
 				}
 			}
 			select {
-			case dataChan <- payload:
+			case dataChan <- h.filterStreamChunk(responseProtocol, originalRequestedModel, payload):
 				if streamInterceptorsActive {
 					historyChunks = appendStreamInterceptorHistory(historyChunks, payload)
 				}
@@ -1165,6 +1343,7 @@ func (h *BaseAPIHandler) executeStreamWithAuthManager(ctx context.Context, handl
 
 func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context, entryProtocol, exitProtocol, modelName string, rawJSON []byte, alt string, allowImageModel bool, execOptions modelExecutionOptions) (<-chan []byte, http.Header, <-chan *interfaces.ErrorMessage) {
 	originalRequestedModel := modelName
+	tracing.SetAttributes(ctx, tracing.AttrHandler.String(entryProtocol), tracing.AttrModel.String(modelName))
 	routeDecision, preparedRoute := preparedModelRouteFromContext(ctx)
 	if !preparedRoute {
 		routeDecision = h.applyModelRouter(ctx, entryProtocol, modelName, rawJSON, true, execOptions)
@@ -1179,20 +1358,26 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 	if routeDecision.ExecutorPluginID != "" {
 		return h.streamWithPluginExecutor(ctx, entryProtocol, responseProtocol, modelName, originalRequestedModel, rawJSON, alt, routeDecision.ExecutorPluginID, execOptions)
 	}
+	modelName = h.resolveAutoModelForRequest(ctx, modelName, rawJSON, routeDecision, execOptions)
 	providers, normalizedModel, errMsg := h.providersForExecution(modelName, originalRequestedModel, allowImageModel, routeDecision, execOptions)
+	if errMsg == nil {
+		providers, errMsg = h.applyModelPolicy(ctx, providers, normalizedModel)
+	}
 	if errMsg != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
 		close(errChan)
 		return nil, nil, errChan
 	}
+	normalizedModel = modelWithThinkingHeader(ctx, normalizedModel, execOptions.Headers)
 	providers = adjustExecutionProvidersForEntryProtocol(entryProtocol, providers)
-	reqMeta := requestExecutionMetadata(ctx)
+	reqMeta := h.executionMetadata(ctx)
 	reqMeta[coreexecutor.RequestedModelMetadataKey] = originalRequestedModel
 	addAuthSelectionModelMetadata(reqMeta, execOptions.AuthSelectionModel)
 	addModelExecutionSourceMetadata(reqMeta, execOptions.InternalSource)
 	setReasoningEffortMetadata(reqMeta, entryProtocol, normalizedModel, rawJSON)
 	setServiceTierMetadata(reqMeta, rawJSON)
+	setSessionIDMetadata(reqMeta, rawJSON)
 	setGenerateMetadata(reqMeta, rawJSON)
 	payload := rawJSON
 	if len(payload) == 0 {
@@ -1219,19 +1404,7 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 	if err != nil {
 		err = enrichAuthSelectionError(err, providers, normalizedModel)
 		errChan := make(chan *interfaces.ErrorMessage, 1)
-		status := http.StatusInternalServerError
-		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
-			if code := se.StatusCode(); code > 0 {
-				status = code
-			}
-		}
-		var addon http.Header
-		if he, ok := err.(interface{ Headers() http.Header }); ok && he != nil {
-			if hdr := he.Headers(); hdr != nil {
-				addon = hdr.Clone()
-			}
-		}
-		errChan <- &interfaces.ErrorMessage{StatusCode: status, Error: err, Addon: addon}
+		errChan <- executionErrorMessage(err)
 		close(errChan)
 		return nil, nil, errChan
 	}
@@ -1366,20 +1539,6 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 		}
 	}
 
-	bootstrapEligible := func(err error) bool {
-		status := statusFromError(err)
-		if status == 0 {
-			return true
-		}
-		switch status {
-		case http.StatusUnauthorized, http.StatusForbidden, http.StatusPaymentRequired,
-			http.StatusRequestTimeout, http.StatusTooManyRequests:
-			return true
-		default:
-			return status >= http.StatusInternalServerError
-		}
-	}
-
 	maxBootstrapRetries := StreamingBootstrapRetries(h.Cfg)
 	if h.AuthManager.HomeEnabled() {
 		maxBootstrapRetries = 0
@@ -1389,7 +1548,7 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 		if streamCanceledBeforeRead || bootstrapErr != nil || bootstrapStreamErr == nil {
 			break
 		}
-		if bootstrapRetries >= maxBootstrapRetries || !bootstrapEligible(bootstrapStreamErr) {
+		if bootstrapRetries >= maxBootstrapRetries || !streamRetryEligible(bootstrapStreamErr) {
 			bootstrapErr = executionErrorMessage(bootstrapStreamErr)
 			break
 		}
@@ -1423,6 +1582,10 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 	if upstreamHeaders == nil && (passthroughHeadersEnabled || streamInterceptorsActive) {
 		upstreamHeaders = make(http.Header)
 	}
+	var resume *streamResume
+	if StreamingResumeOnError(h.Cfg) && !h.AuthManager.HomeEnabled() {
+		resume = newStreamResume(entryProtocol, responseProtocol)
+	}
 	dataChan := make(chan []byte)
 	errChan := make(chan *interfaces.ErrorMessage, 1)
 
@@ -1433,6 +1596,35 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 			return
 		}
 
+		// resumeStream re-issues the request after a retryable mid-stream failure, with
+		// the output sent so far as an assistant prefill, and switches to the new stream.
+		resumes := 0
+		resumeStream := func(errStream error) bool {
+			if resumes >= maxStreamResumes || !resume.canResume() || !streamRetryEligible(errStream) {
+				return false
+			}
+			resumedPayload, ok := resume.resumeRequest(req.Payload)
+			if !ok {
+				return false
+			}
+			resumes++
+			resumeReq, resumeOpts := req, opts
+			resumeReq.Payload = resumedPayload
+			resumeOpts.OriginalRequest = resumedPayload
+			resumeResult, errResume := h.AuthManager.ExecuteStream(ctx, providers, resumeReq, resumeOpts)
+			if errResume != nil || resumeResult == nil {
+				return false
+			}
+			streamClosedBeforeRead = false
+			chunks = resumeResult.Chunks
+			if chunks == nil {
+				closed := make(chan coreexecutor.StreamChunk)
+				close(closed)
+				chunks = closed
+			}
+			return true
+		}
+
 		sendErr := func(msg *interfaces.ErrorMessage) bool {
 			if ctx == nil {
 				errChan <- msg
@@ -1447,6 +1639,7 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 		}
 
 		sendData := func(chunk []byte) bool {
+			chunk = h.filterStreamChunk(responseProtocol, originalRequestedModel, chunk)
 			if ctx == nil {
 				dataChan <- chunk
 				return true
@@ -1467,7 +1660,7 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 		chunkIndex := bootstrapChunkIndex
 		historyChunks := bootstrapHistoryChunks
 		if bootstrapPayload != nil {
-			if okSendData := sendData(bootstrapPayload); !okSendData {
+			if okSendData := sendData(resume.process(bootstrapPayload)); !okSendData {
 				return
 			}
 			if streamInterceptorsActive {
@@ -1480,6 +1673,9 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 				return
 			}
 			if chunk.Err != nil {
+				if resumeStream(chunk.Err) {
+					continue
+				}
 				_ = sendErr(executionErrorMessage(chunk.Err))
 				return
 			}
@@ -1494,12 +1690,15 @@ func (h *BaseAPIHandler) executeStreamWithAuthManagerFormats(ctx context.Context
 			if !deliverable {
 				continue
 			}
-			if okSendData := sendData(payload); !okSendData {
-				return
-			}
 			if streamInterceptorsActive {
 				historyChunks = appendStreamInterceptorHistory(historyChunks, payload)
 			}
+			if payload = resume.process(payload); payload == nil {
+				continue
+			}
+			if okSendData := sendData(payload); !okSendData {
+				return
+			}
 		}
 	}()
 	return dataChan, upstreamHeaders, errChan
@@ -1596,6 +1795,26 @@ func excludeExecutionProvider(providers []string, excluded string) []string {
 	return out
 }
 
+// streamRetryEligible reports whether a stream error may be retried on another attempt:
+// transport errors, auth and quota failures, timeouts and upstream 5xx responses. A stream
+// that ran past its model's time limit is final.
+func streamRetryEligible(err error) bool {
+	if coreauth.IsModelTimeout(err) {
+		return false
+	}
+	status := statusFromError(err)
+	if status == 0 {
+		return true
+	}
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusPaymentRequired,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
+
 func statusFromError(err error) int {
 	if err == nil {
 		return 0
@@ -1667,6 +1886,92 @@ func (h *BaseAPIHandler) providersForExecution(modelName, originalRequestedModel
 	return h.getRequestDetailsWithOptions(modelName, allowImageModel)
 }
 
+// ResolvedModelHeader reports the concrete model chosen for an "auto" model request.
+const ResolvedModelHeader = "X-CLIProxy-Resolved-Model"
+
+// ThinkingHeader carries a thinking configuration for clients that cannot add a suffix to
+// the model name. It accepts the suffix values, e.g. "8192", "16k", "high" or "none".
+const ThinkingHeader = "X-CLIProxy-Thinking"
+
+// modelWithThinkingHeader appends the ThinkingHeader value to model as a thinking suffix,
+// so it reaches the executors the same way a client-supplied suffix does. An explicit
+// suffix in the model name wins over the header; like any suffix, the header in turn
+// overrides the thinking config of the request body. Values that are not valid suffixes
+// are ignored.
+func modelWithThinkingHeader(ctx context.Context, model string, headers http.Header) string {
+	if len(headers) == 0 {
+		headers = headersFromContext(ctx)
+	}
+	value := strings.TrimSpace(headers.Get(ThinkingHeader))
+	if value == "" || thinking.ParseSuffix(model).HasSuffix || !validThinkingSuffix(value) {
+		return model
+	}
+	return model + "(" + value + ")"
+}
+
+func validThinkingSuffix(value string) bool {
+	if _, ok := thinking.ParseSpecialSuffix(value); ok {
+		return true
+	}
+	if _, ok := thinking.ParseLevelSuffix(value); ok {
+		return true
+	}
+	if _, ok := thinking.ParseNumericSuffix(value); ok {
+		return true
+	}
+	_, ok := thinking.ParsePercentSuffix(value)
+	return ok
+}
+
+// resolveAutoModelForRequest resolves "auto" through the configured auto-model candidates,
+// keeping conversations sticky when enabled, and reports the choice in ResolvedModelHeader.
+// Requests already pinned by a model router or a forced provider are left untouched.
+func (h *BaseAPIHandler) resolveAutoModelForRequest(ctx context.Context, modelName string, rawJSON []byte, routeDecision modelRouteDecision, execOptions modelExecutionOptions) string {
+	if h == nil || routeDecision.Provider != "" || strings.TrimSpace(execOptions.ForcedProvider) != "" {
+		return modelName
+	}
+	if h.AuthManager != nil && h.AuthManager.HomeEnabled() {
+		return modelName
+	}
+	parsed := thinking.ParseSuffix(modelName)
+	if parsed.ModelName != "auto" {
+		return modelName
+	}
+	opts := util.AutoModelOptions{Available: h.autoModelAvailable}
+	if h.Cfg != nil {
+		opts.Candidates = h.Cfg.AutoModel.Models
+		if h.Cfg.AutoModel.Sticky {
+			opts.StickyKey = coreauth.ExtractConversationID(rawJSON)
+		}
+	}
+	resolved := util.ResolveAutoModelWithOptions(parsed.ModelName, opts)
+	if resolved == parsed.ModelName {
+		return modelName
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		ginCtx.Header(ResolvedModelHeader, resolved)
+	}
+	if parsed.HasSuffix {
+		return fmt.Sprintf("%s(%s)", resolved, parsed.RawSuffix)
+	}
+	return resolved
+}
+
+// autoModelAvailable reports whether any provider serving model has an auth that is
+// not disabled or cooling down for it.
+func (h *BaseAPIHandler) autoModelAvailable(model string) bool {
+	providers := util.GetProviderName(model)
+	if h == nil || h.AuthManager == nil {
+		return len(providers) > 0
+	}
+	for _, provider := range providers {
+		if h.AuthManager.HasAvailableAuthForModel(provider, model) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *BaseAPIHandler) getRequestDetailsWithOptions(modelName string, allowImageModel bool) (providers []string, normalizedModel string, err *interfaces.ErrorMessage) {
 	resolvedModelName := modelName
 	initialSuffix := thinking.ParseSuffix(modelName)
@@ -2254,14 +2559,41 @@ func enrichAuthSelectionError(err error, providers []string, model string) error
 	}
 }
 
+// statusOverloaded is the non-standard status Anthropic answers with while its API is
+// overloaded. It is retryable and reaches clients as 503.
+const statusOverloaded = 529
+
+// overloadedRetryAfter is the Retry-After sent for an overloaded upstream without a hint.
+const overloadedRetryAfter = 30 * time.Second
+
+// RetryAfterValues returns the Retry-After header values to send with err: the trusted
+// values of a busy Home, or the delay of an overloaded upstream.
+func RetryAfterValues(err error) []string {
+	if err == nil {
+		return nil
+	}
+	if values := coreauth.SafeResponseHeaders(err).Values("Retry-After"); len(values) > 0 {
+		return values
+	}
+	if statusFromError(err) != statusOverloaded {
+		return nil
+	}
+	retryAfter := overloadedRetryAfter
+	if ra, ok := err.(interface{ RetryAfter() *time.Duration }); ok && ra.RetryAfter() != nil {
+		retryAfter = *ra.RetryAfter()
+	}
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	return []string{strconv.FormatInt(max(seconds, 1), 10)}
+}
+
 // WriteErrorResponse writes an error message to the response writer using the HTTP status embedded in the message.
 func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.ErrorMessage) {
 	status := http.StatusInternalServerError
 	if msg != nil && msg.StatusCode > 0 {
 		status = msg.StatusCode
 	}
-	if msg != nil && msg.Error != nil {
-		for _, value := range coreauth.SafeResponseHeaders(msg.Error).Values("Retry-After") {
+	if msg != nil {
+		for _, value := range RetryAfterValues(msg.Error) {
 			c.Writer.Header().Add("Retry-After", value)
 		}
 	}
@@ -2309,9 +2641,14 @@ func (h *BaseAPIHandler) WriteErrorResponse(c *gin.Context, msg *interfaces.Erro
 	_, _ = c.Writer.Write(body)
 }
 
+// LoggingAPIResponseError records err for the request log. Client cancellations
+// are skipped: a disconnect is not an upstream error and must not surface as a 5xx.
 func (h *BaseAPIHandler) LoggingAPIResponseError(ctx context.Context, err *interfaces.ErrorMessage) {
 	if h.Cfg.RequestLog {
 		if ginContext, ok := ctx.Value("gin").(*gin.Context); ok {
+			if isClientCancellation(ginContext, err) {
+				return
+			}
 			if apiResponseErrors, isExist := ginContext.Get("API_RESPONSE_ERROR"); isExist {
 				if slicesAPIResponseError, isOk := apiResponseErrors.([]*interfaces.ErrorMessage); isOk {
 					slicesAPIResponseError = append(slicesAPIResponseError, err)
@@ -2325,6 +2662,15 @@ func (h *BaseAPIHandler) LoggingAPIResponseError(ctx context.Context, err *inter
 	}
 }
 
+// isClientCancellation reports whether msg was caused by the client going away
+// rather than by the upstream.
+func isClientCancellation(c *gin.Context, msg *interfaces.ErrorMessage) bool {
+	if msg != nil && msg.Error != nil && errors.Is(msg.Error, context.Canceled) {
+		return true
+	}
+	return c != nil && c.Request != nil && errors.Is(c.Request.Context().Err(), context.Canceled)
+}
+
 // APIHandlerCancelFunc is a function type for canceling an API handler's context.
 // It can optionally accept parameters, which are used for logging the response.
 type APIHandlerCancelFunc func(params ...interface{})
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+func newAutoModelTestHandler(t *testing.T, sticky bool) *BaseAPIHandler {
+	t.Helper()
+	modelRegistry := registry.GetGlobalRegistry()
+	modelRegistry.RegisterClient("test-auto-model-gemini", "gemini", []*registry.ModelInfo{{ID: "auto-test-gemini"}})
+	modelRegistry.RegisterClient("test-auto-model-claude", "claude", []*registry.ModelInfo{{ID: "auto-test-claude"}})
+	modelRegistry.RegisterClient("test-auto-model-codex", "codex", []*registry.ModelInfo{{ID: "auto-test-codex"}})
+	t.Cleanup(func() {
+		modelRegistry.UnregisterClient("test-auto-model-gemini")
+		modelRegistry.UnregisterClient("test-auto-model-claude")
+		modelRegistry.UnregisterClient("test-auto-model-codex")
+	})
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	auths := []*coreauth.Auth{
+		{
+			ID:       "test-auto-model-gemini",
+			Provider: "gemini",
+			ModelStates: map[string]*coreauth.ModelState{
+				"auto-test-gemini": {Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour)},
+			},
+		},
+		{ID: "test-auto-model-claude", Provider: "claude"},
+		{ID: "test-auto-model-codex", Provider: "codex"},
+	}
+	for _, auth := range auths {
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+	}
+
+	cfg := &sdkconfig.SDKConfig{AutoModel: sdkconfig.AutoModelConfig{
+		Sticky: sticky,
+		Models: []sdkconfig.AutoModelCandidate{
+			{Name: "auto-test-gemini", Weight: 100},
+			{Name: "auto-test-claude", Weight: 1},
+			{Name: "auto-test-codex", Weight: 1},
+		},
+	}}
+	return NewBaseAPIHandlers(cfg, manager)
+}
+
+func resolveAutoModelWithGin(handler *BaseAPIHandler, modelName string, rawJSON []byte) (string, string) {
+	rec := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(rec)
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+	resolved := handler.resolveAutoModelForRequest(ctx, modelName, rawJSON, modelRouteDecision{}, modelExecutionOptions{})
+	return resolved, rec.Header().Get(ResolvedModelHeader)
+}
+
+func TestResolveAutoModelForRequest_SkipsUnhealthyProviderAndSetsHeader(t *testing.T) {
+	handler := newAutoModelTestHandler(t, false)
+	for i := 0; i < 40; i++ {
+		resolved, header := resolveAutoModelWithGin(handler, "auto(high)", nil)
+		if header != "auto-test-claude" && header != "auto-test-codex" {
+			t.Fatalf("resolved header = %q, want a healthy candidate", header)
+		}
+		if resolved != header+"(high)" {
+			t.Fatalf("resolved model = %q, want %q with suffix preserved", resolved, header)
+		}
+	}
+
+	resolved, header := resolveAutoModelWithGin(handler, "auto-test-claude", nil)
+	if resolved != "auto-test-claude" || header != "" {
+		t.Fatalf("explicit model resolved to %q with header %q", resolved, header)
+	}
+}
+
+func TestResolveAutoModelForRequest_StickySession(t *testing.T) {
+	handler := newAutoModelTestHandler(t, true)
+	body := []byte(`{"metadata":{"session_id":"conversation-1"},"messages":[{"role":"user","content":"hi"}]}`)
+	first, _ := resolveAutoModelWithGin(handler, "auto", body)
+	for i := 0; i < 20; i++ {
+		if got, _ := resolveAutoModelWithGin(handler, "auto", body); got != first {
+			t.Fatalf("sticky session resolved %q, want %q", got, first)
+		}
+	}
+	if first == "auto-test-gemini" {
+		t.Fatal("sticky session resolved to a provider without a healthy auth")
+	}
+}
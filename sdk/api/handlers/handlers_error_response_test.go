@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -13,6 +17,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
 )
 
 func TestWriteErrorResponse_AddonHeadersDisabledByDefault(t *testing.T) {
@@ -168,3 +173,109 @@ func TestEnrichAuthSelectionError_IgnoresOtherErrors(t *testing.T) {
 		t.Fatalf("expected original error to be returned unchanged")
 	}
 }
+
+func TestLoggingAPIResponseErrorSkipsClientCancellation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx := context.WithValue(context.Background(), "gin", c)
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{RequestLog: true}, nil)
+	handler.LoggingAPIResponseError(ctx, &interfaces.ErrorMessage{
+		StatusCode: http.StatusInternalServerError,
+		Error:      fmt.Errorf("stream read: %w", context.Canceled),
+	})
+	if _, exists := c.Get("API_RESPONSE_ERROR"); exists {
+		t.Fatal("client cancellation recorded in API_RESPONSE_ERROR")
+	}
+
+	handler.LoggingAPIResponseError(ctx, &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadGateway,
+		Error:      errors.New("upstream reset"),
+	})
+	recorded, _ := c.Get("API_RESPONSE_ERROR")
+	if errs, ok := recorded.([]*interfaces.ErrorMessage); !ok || len(errs) != 1 {
+		t.Fatalf("API_RESPONSE_ERROR = %#v, want the upstream error only", recorded)
+	}
+}
+
+const htmlBadGatewayPage = "<!DOCTYPE html>\n<html><head><title>502 Bad Gateway</title></head>\n<body><h1>Bad Gateway</h1></body></html>\n"
+
+func TestBuildStreamErrorResponseBodyWrapsNonJSONWithStatus(t *testing.T) {
+	body := BuildStreamErrorResponseBody(http.StatusBadGateway, htmlBadGatewayPage)
+
+	if !json.Valid(body) {
+		t.Fatalf("body is not valid JSON: %s", body)
+	}
+	var got struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Status  int    `json:"status"`
+		} `json:"error"`
+	}
+	if errUnmarshal := json.Unmarshal(body, &got); errUnmarshal != nil {
+		t.Fatalf("unmarshal: %v", errUnmarshal)
+	}
+	if got.Error.Status != http.StatusBadGateway || got.Error.Type != "server_error" || got.Error.Code != "internal_server_error" {
+		t.Fatalf("error = %+v, want server_error with status 502", got.Error)
+	}
+	if !strings.Contains(got.Error.Message, "<title>502 Bad Gateway</title>") {
+		t.Fatalf("message = %q, want the upstream body", got.Error.Message)
+	}
+	if bytes.Contains(body, []byte("\n")) {
+		t.Fatalf("body contains a raw newline that would split the SSE data line: %q", body)
+	}
+}
+
+func TestBuildStreamErrorResponseBodyPassesJSONThrough(t *testing.T) {
+	upstream := `{"error":{"message":"quota","type":"rate_limit_error"}}`
+	if got := string(BuildStreamErrorResponseBody(http.StatusTooManyRequests, "  "+upstream+"\n")); got != upstream {
+		t.Fatalf("body = %s, want %s", got, upstream)
+	}
+	if got := string(BuildStreamErrorResponseBody(http.StatusTooManyRequests, "")); !strings.Contains(got, `"status":429`) || !strings.Contains(got, "Too Many Requests") {
+		t.Fatalf("empty error body = %s", got)
+	}
+}
+
+func TestBuildErrorResponseBodyKeepsNonStreamEnvelope(t *testing.T) {
+	if got := string(BuildErrorResponseBody(http.StatusBadGateway, "bad gateway")); strings.Contains(got, `"status"`) {
+		t.Fatalf("non-stream body = %s, want no status field", got)
+	}
+}
+
+func TestForwardStreamShutdownErrorWrapsAsJSONEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil)
+	data := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage, 1)
+	errs <- &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: errors.New(htmlBadGatewayPage)}
+	handler.ForwardStream(c, c.Writer, func(error) {}, data, errs, StreamForwardOptions{
+		WriteTerminalError: func(errMsg *interfaces.ErrorMessage) {
+			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", BuildStreamErrorResponseBody(errMsg.StatusCode, errMsg.Error.Error()))
+		},
+	})
+
+	assertSSEErrorEvent(t, recorder.Body.String(), "error", http.StatusBadGateway)
+}
+
+// assertSSEErrorEvent checks that body holds exactly one SSE event named event whose data is
+// valid JSON carrying status.
+func assertSSEErrorEvent(t *testing.T, body, event string, status int) {
+	t.Helper()
+	if !strings.HasPrefix(body, "event: "+event+"\ndata: ") || !strings.HasSuffix(body, "\n\n") {
+		t.Fatalf("frame = %q, want a single %q event", body, event)
+	}
+	data := strings.TrimSuffix(strings.TrimPrefix(body, "event: "+event+"\ndata: "), "\n\n")
+	if strings.Contains(data, "\n") || !json.Valid([]byte(data)) {
+		t.Fatalf("event data is not a single JSON line: %q", data)
+	}
+	if got := gjson.Get(data, "error.status").Int(); got != int64(status) {
+		t.Fatalf("error.status = %d, want %d; data=%s", got, status, data)
+	}
+}
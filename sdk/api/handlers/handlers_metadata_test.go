@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
 	"golang.org/x/net/context"
 )
 
@@ -137,3 +138,68 @@ func TestSetGenerateMetadataHonorsExplicitFalse(t *testing.T) {
 		t.Fatalf("GenerateMetadataKey = %v, want false", got)
 	}
 }
+
+func TestExecutionMetadataRequestPriority(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &BaseAPIHandler{Cfg: &sdkconfig.SDKConfig{RequestPriority: sdkconfig.RequestPriorityConfig{
+		APIKeyPriorities: map[string]string{"batch-key": "low", "premium-key": "high"},
+	}}}
+
+	cases := []struct {
+		name   string
+		header string
+		apiKey string
+		want   any
+	}{
+		{name: "header lowers api key class", header: "LOW", apiKey: "premium-key", want: "low"},
+		{name: "header keeps api key class", header: "high", apiKey: "premium-key", want: "high"},
+		{name: "header cannot raise api key class", header: "HIGH", apiKey: "batch-key", want: "low"},
+		{name: "header capped at normal for unmapped key", header: "high", apiKey: "other-key", want: "normal"},
+		{name: "header lowers unmapped key", header: "low", apiKey: "other-key", want: "low"},
+		{name: "api key default", apiKey: "batch-key", want: "low"},
+		{name: "invalid header falls back to api key", header: "urgent", apiKey: "batch-key", want: "low"},
+		{name: "unmapped api key", apiKey: "other-key", want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tc.header != "" {
+				ginCtx.Request.Header.Set(PriorityHeader, tc.header)
+			}
+			ginCtx.Set("userApiKey", tc.apiKey)
+			ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+			meta := h.executionMetadata(ctx)
+			if got := meta[coreexecutor.PriorityMetadataKey]; got != tc.want {
+				t.Fatalf("priority = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSessionIDMetadataFromHeaderAndBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	ginCtx.Request.Header.Set("X-Session-Id", " header-session ")
+	ctx := context.WithValue(context.Background(), "gin", ginCtx)
+
+	meta := requestExecutionMetadata(ctx)
+	setSessionIDMetadata(meta, []byte(`{"metadata":{"session_id":"body-session"}}`))
+	if got := meta[coreexecutor.SessionIDMetadataKey]; got != "header-session" {
+		t.Fatalf("SessionIDMetadataKey = %v, want the X-Session-Id header", got)
+	}
+
+	meta = requestExecutionMetadata(context.Background())
+	setSessionIDMetadata(meta, []byte(`{"metadata":{"session_id":"body-session"}}`))
+	if got := meta[coreexecutor.SessionIDMetadataKey]; got != "body-session" {
+		t.Fatalf("SessionIDMetadataKey = %v, want the metadata.session_id body field", got)
+	}
+
+	meta = requestExecutionMetadata(context.Background())
+	setSessionIDMetadata(meta, []byte(`{"model":"m"}`))
+	if _, ok := meta[coreexecutor.SessionIDMetadataKey]; ok {
+		t.Fatalf("unexpected session ID in metadata: %v", meta[coreexecutor.SessionIDMetadataKey])
+	}
+}
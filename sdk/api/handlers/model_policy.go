@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+)
+
+// ModelNotAllowedCode is the error code of requests for a model denied by a provider model policy.
+const ModelNotAllowedCode = "model_not_allowed"
+
+// requestAPIKey returns the client API key of the request in ctx.
+func requestAPIKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
+		return strings.TrimSpace(ginCtx.GetString("userApiKey"))
+	}
+	return ""
+}
+
+// applyModelPolicy drops the providers whose model policy denies model for the request.
+// When every provider is dropped, the request fails with 403 model_not_allowed.
+func (h *BaseAPIHandler) applyModelPolicy(ctx context.Context, providers []string, model string) ([]string, *interfaces.ErrorMessage) {
+	if h == nil || !h.AuthManager.ModelPolicyConfigured() || len(providers) == 0 {
+		return providers, nil
+	}
+	apiKey := requestAPIKey(ctx)
+	allowed := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if h.AuthManager.ModelAllowed(provider, model, apiKey) {
+			allowed = append(allowed, provider)
+		}
+	}
+	if len(allowed) > 0 {
+		return allowed, nil
+	}
+	return nil, modelNotAllowedError(model)
+}
+
+func modelNotAllowedError(model string) *interfaces.ErrorMessage {
+	message := fmt.Sprintf("model %s is not allowed", strings.TrimSpace(thinking.ParseSuffix(model).ModelName))
+	body, errMarshal := json.Marshal(ErrorResponse{Error: ErrorDetail{
+		Message: message,
+		Type:    "permission_error",
+		Code:    ModelNotAllowedCode,
+	}})
+	if errMarshal != nil {
+		return &interfaces.ErrorMessage{StatusCode: http.StatusForbidden, Error: fmt.Errorf("%s", message)}
+	}
+	return &interfaces.ErrorMessage{StatusCode: http.StatusForbidden, Error: fmt.Errorf("%s", body)}
+}
+
+// AllowedModels drops the models of a listing that the provider model policies deny for
+// the request of c. A model stays listed while at least one provider serving it allows it.
+func (h *BaseAPIHandler) AllowedModels(c *gin.Context, models []map[string]any, idOf func(map[string]any) string) []map[string]any {
+	if h == nil || !h.AuthManager.ModelPolicyConfigured() {
+		return models
+	}
+	apiKey := ""
+	if c != nil {
+		apiKey = strings.TrimSpace(c.GetString("userApiKey"))
+	}
+	modelRegistry := registry.GetGlobalRegistry()
+	out := make([]map[string]any, 0, len(models))
+	for _, model := range models {
+		if model == nil {
+			continue
+		}
+		id := idOf(model)
+		providers := modelRegistry.GetModelProviders(id)
+		if len(providers) == 0 {
+			out = append(out, model)
+			continue
+		}
+		for _, provider := range providers {
+			if h.AuthManager.ModelAllowed(provider, id, apiKey) {
+				out = append(out, model)
+				break
+			}
+		}
+	}
+	return out
+}
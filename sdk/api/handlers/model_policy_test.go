@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const modelPolicyTestProvider = "model-policy-test"
+
+type modelPolicyTestExecutor struct{}
+
+func (modelPolicyTestExecutor) Identifier() string { return modelPolicyTestProvider }
+
+func (modelPolicyTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{Payload: []byte(req.Model)}, nil
+}
+
+func (modelPolicyTestExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	ch := make(chan coreexecutor.StreamChunk, 1)
+	ch <- coreexecutor.StreamChunk{Payload: []byte(req.Model)}
+	close(ch)
+	return &coreexecutor.StreamResult{Chunks: ch}, nil
+}
+
+func (modelPolicyTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (modelPolicyTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (modelPolicyTestExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func newModelPolicyTestHandler(t *testing.T, policy internalconfig.ModelPolicy) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(modelPolicyTestExecutor{})
+	manager.SetConfig(&internalconfig.Config{
+		Providers: map[string]internalconfig.ProviderHTTPConfig{modelPolicyTestProvider: {ModelPolicy: policy}},
+		OAuthModelAlias: map[string][]internalconfig.OAuthModelAlias{
+			modelPolicyTestProvider: {{Name: "policy-premium-model", Alias: "policy-alias-model", Fork: true}},
+		},
+	})
+	auth := &coreauth.Auth{ID: t.Name() + "-auth", Provider: modelPolicyTestProvider, Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register(): %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, modelPolicyTestProvider, []*registry.ModelInfo{
+		{ID: "policy-basic-model"},
+		{ID: "policy-premium-model"},
+		{ID: "policy-alias-model"},
+	})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+}
+
+func newModelPolicyTestContext(apiKey string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	if apiKey != "" {
+		c.Set("userApiKey", apiKey)
+	}
+	return c
+}
+
+func TestAllowedModelsHidesDeniedModels(t *testing.T) {
+	h := newModelPolicyTestHandler(t, internalconfig.ModelPolicy{Deny: []string{"policy-premium-*"}})
+	models := []map[string]any{
+		{"id": "policy-basic-model"},
+		{"id": "policy-premium-model"},
+		{"id": "policy-alias-model"},
+		{"id": "policy-unregistered-model"},
+	}
+	got := h.AllowedModels(newModelPolicyTestContext(""), models, func(model map[string]any) string {
+		id, _ := model["id"].(string)
+		return id
+	})
+	var ids []string
+	for _, model := range got {
+		ids = append(ids, model["id"].(string))
+	}
+	if strings.Join(ids, ",") != "policy-basic-model,policy-unregistered-model" {
+		t.Fatalf("listed models = %v, want denied model and its alias hidden", ids)
+	}
+}
+
+func TestExecuteWithAuthManagerRejectsDeniedModel(t *testing.T) {
+	h := newModelPolicyTestHandler(t, internalconfig.ModelPolicy{Allow: []string{"policy-basic-*"}, APIKeys: []string{"limited-key"}})
+
+	tests := []struct {
+		name    string
+		model   string
+		apiKey  string
+		allowed bool
+	}{
+		{"allowed model", "policy-basic-model", "limited-key", true},
+		{"model outside allow list", "policy-premium-model", "limited-key", false},
+		{"alias of model outside allow list", "policy-alias-model", "limited-key", false},
+		{"other keys are not scoped", "policy-premium-model", "other-key", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newModelPolicyTestContext(tt.apiKey)
+			ctx, cancel := h.GetContextWithCancel(nil, c, context.Background())
+			defer cancel()
+			resp, _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", tt.model, []byte(`{}`), "")
+			if tt.allowed {
+				if errMsg != nil {
+					t.Fatalf("ExecuteWithAuthManager() error = %v, want success", errMsg.Error)
+				}
+				if len(resp) == 0 {
+					t.Fatal("ExecuteWithAuthManager() returned an empty response")
+				}
+				return
+			}
+			if errMsg == nil {
+				t.Fatalf("ExecuteWithAuthManager() succeeded with %q, want 403", resp)
+			}
+			if errMsg.StatusCode != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d", errMsg.StatusCode, http.StatusForbidden)
+			}
+			body := BuildErrorResponseBody(errMsg.StatusCode, errMsg.Error.Error())
+			if code := gjson.GetBytes(body, "error.code").String(); code != ModelNotAllowedCode {
+				t.Fatalf("error code = %q, want %q (body %s)", code, ModelNotAllowedCode, body)
+			}
+		})
+	}
+}
+
+func TestExecuteStreamWithAuthManagerRejectsDeniedModel(t *testing.T) {
+	h := newModelPolicyTestHandler(t, internalconfig.ModelPolicy{Deny: []string{"policy-premium-model"}})
+	ctx, cancel := h.GetContextWithCancel(nil, newModelPolicyTestContext(""), context.Background())
+	defer cancel()
+	data, _, errs := h.ExecuteStreamWithAuthManager(ctx, "openai", "policy-premium-model", []byte(`{}`), "")
+	if data != nil {
+		t.Fatal("stream returned a data channel for a denied model")
+	}
+	var got int
+	for errMsg := range errs {
+		if errMsg != nil {
+			got = errMsg.StatusCode
+		}
+	}
+	if got != http.StatusForbidden {
+		t.Fatalf("stream status = %d, want %d", got, http.StatusForbidden)
+	}
+}
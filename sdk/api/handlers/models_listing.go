@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+)
+
+// ModelListOptions holds the filters and page bounds of a model listing request.
+type ModelListOptions struct {
+	// Provider keeps models supplied by the given provider identifier, such as "antigravity".
+	Provider string
+	// OwnedBy keeps models whose owner matches, ignoring case.
+	OwnedBy string
+	// Search keeps models whose ID or display name contains the substring, ignoring case.
+	Search string
+	// Limit caps the page size; zero returns every remaining model.
+	Limit int
+	// After starts the page after the model with this ID.
+	After string
+}
+
+// Paginated reports whether the request asked for a page rather than the full list.
+func (o ModelListOptions) Paginated() bool {
+	return o.Limit > 0 || o.After != ""
+}
+
+// ParseOpenAIModelListOptions reads provider, owned_by, search, limit and after from an
+// OpenAI-style model listing query.
+func ParseOpenAIModelListOptions(query url.Values) (ModelListOptions, error) {
+	opts := modelListFilters(query)
+	opts.After = strings.TrimSpace(query.Get("after"))
+	limit, errLimit := parseModelListLimit(query, "limit")
+	if errLimit != nil {
+		return ModelListOptions{}, errLimit
+	}
+	opts.Limit = limit
+	return opts, nil
+}
+
+// ParseGeminiModelListOptions reads provider, owned_by, search, pageSize and pageToken from a
+// Gemini-style model listing query. Page tokens are the model names returned as nextPageToken.
+func ParseGeminiModelListOptions(query url.Values) (ModelListOptions, error) {
+	opts := modelListFilters(query)
+	opts.After = strings.TrimPrefix(strings.TrimSpace(query.Get("pageToken")), "models/")
+	limit, errLimit := parseModelListLimit(query, "pageSize")
+	if errLimit != nil {
+		return ModelListOptions{}, errLimit
+	}
+	opts.Limit = limit
+	return opts, nil
+}
+
+func modelListFilters(query url.Values) ModelListOptions {
+	return ModelListOptions{
+		Provider: strings.TrimSpace(query.Get("provider")),
+		OwnedBy:  strings.TrimSpace(query.Get("owned_by")),
+		Search:   strings.TrimSpace(query.Get("search")),
+	}
+}
+
+func parseModelListLimit(query url.Values, key string) (int, error) {
+	raw := strings.TrimSpace(query.Get(key))
+	if raw == "" {
+		return 0, nil
+	}
+	limit, errParse := strconv.Atoi(raw)
+	if errParse != nil || limit <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	return limit, nil
+}
+
+// ListModels filters models by opts, sorts them by the ID idOf returns and cuts the page
+// that starts after opts.After. Sorting by ID keeps pages stable when the registry changes
+// between requests: a cursor keeps working even if the model it names has gone away.
+// hasMore reports whether models remain after the returned page.
+func ListModels(models []map[string]any, idOf func(map[string]any) string, opts ModelListOptions) (page []map[string]any, hasMore bool) {
+	filtered := make([]map[string]any, 0, len(models))
+	for _, model := range models {
+		if model != nil && modelMatchesListOptions(model, idOf(model), opts) {
+			filtered = append(filtered, model)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return idOf(filtered[i]) < idOf(filtered[j])
+	})
+
+	if opts.After != "" {
+		start := sort.Search(len(filtered), func(i int) bool {
+			return idOf(filtered[i]) > opts.After
+		})
+		filtered = filtered[start:]
+	}
+	if opts.Limit > 0 && len(filtered) > opts.Limit {
+		return filtered[:opts.Limit], true
+	}
+	return filtered, false
+}
+
+func modelMatchesListOptions(model map[string]any, id string, opts ModelListOptions) bool {
+	if opts.Search != "" {
+		search := strings.ToLower(opts.Search)
+		displayName, _ := model["display_name"].(string)
+		if displayName == "" {
+			displayName, _ = model["displayName"].(string)
+		}
+		if !strings.Contains(strings.ToLower(id), search) && !strings.Contains(strings.ToLower(displayName), search) {
+			return false
+		}
+	}
+	if opts.OwnedBy != "" {
+		ownedBy, _ := model["owned_by"].(string)
+		if ownedBy == "" {
+			if info := registry.LookupModelInfo(id); info != nil {
+				ownedBy = info.OwnedBy
+			}
+		}
+		if !strings.EqualFold(ownedBy, opts.OwnedBy) {
+			return false
+		}
+	}
+	if opts.Provider != "" {
+		matched := false
+		for _, provider := range registry.GetGlobalRegistry().GetModelProviders(id) {
+			if strings.EqualFold(provider, opts.Provider) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
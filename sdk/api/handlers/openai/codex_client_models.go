@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
 )
 
@@ -34,8 +35,8 @@ var codexClientAllowedReasoningLevels = map[string]struct{}{
 	"ultra":  {},
 }
 
-func (h *OpenAIAPIHandler) codexClientModelsResponse() map[string]any {
-	return codexClientModelsResponse(h.Models(), registry.GetGlobalRegistry().GetModelProviders)
+func (h *OpenAIAPIHandler) codexClientModelsResponse(c *gin.Context) map[string]any {
+	return codexClientModelsResponse(h.AllowedModels(c, h.Models(), openAIModelID), registry.GetGlobalRegistry().GetModelProviders)
 }
 
 func CodexClientModelsResponse(models []map[string]any) map[string]any {
@@ -0,0 +1,177 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// chatWebsocketExecutor streams fixed chunks, fails with err, or holds the
+// stream open until the request context ends when hold is set.
+type chatWebsocketExecutor struct {
+	chunks   []string
+	err      error
+	hold     bool
+	payload  chan []byte
+	canceled chan struct{}
+}
+
+func (*chatWebsocketExecutor) Identifier() string { return "chat-websocket-test" }
+
+func (*chatWebsocketExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *chatWebsocketExecutor) ExecuteStream(ctx context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	if e.payload != nil {
+		e.payload <- req.Payload
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	chunks := make(chan coreexecutor.StreamChunk)
+	go func() {
+		defer close(chunks)
+		for _, chunk := range e.chunks {
+			select {
+			case chunks <- coreexecutor.StreamChunk{Payload: []byte(chunk)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if e.hold {
+			<-ctx.Done()
+			close(e.canceled)
+		}
+	}()
+	return &coreexecutor.StreamResult{Chunks: chunks}, nil
+}
+
+func (*chatWebsocketExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (*chatWebsocketExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (*chatWebsocketExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func dialChatCompletionsWebsocket(t *testing.T, executor *chatWebsocketExecutor) *websocket.Conn {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	auth := &coreauth.Auth{ID: "chat-websocket-auth-" + t.Name(), Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register() error = %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "chat-websocket-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.GET("/v1/chat/completions/ws", h.ChatCompletionsWebsocket)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/chat/completions/ws"
+	conn, _, errDial := websocket.DefaultDialer.Dial(wsURL, nil)
+	if errDial != nil {
+		t.Fatalf("dial websocket: %v", errDial)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if errWrite := conn.WriteMessage(websocket.TextMessage, []byte(`{"model":"chat-websocket-model","messages":[{"role":"user","content":"hi"}]}`)); errWrite != nil {
+		t.Fatalf("write request: %v", errWrite)
+	}
+	return conn
+}
+
+func readWebsocketFrames(t *testing.T, conn *websocket.Conn) []string {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var frames []string
+	for {
+		_, payload, errRead := conn.ReadMessage()
+		if errRead != nil {
+			if !websocket.IsCloseError(errRead, websocket.CloseNormalClosure) {
+				t.Fatalf("read frame: %v", errRead)
+			}
+			return frames
+		}
+		frames = append(frames, string(payload))
+	}
+}
+
+func TestChatCompletionsWebsocketStreamsChunksAndDone(t *testing.T) {
+	executor := &chatWebsocketExecutor{
+		chunks: []string{
+			`{"object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"hel"}}]}`,
+			`{"object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"lo"}}]}`,
+		},
+		payload: make(chan []byte, 1),
+	}
+	conn := dialChatCompletionsWebsocket(t, executor)
+
+	frames := readWebsocketFrames(t, conn)
+	want := append(append([]string{}, executor.chunks...), handlers.WebsocketDoneFrame)
+	if strings.Join(frames, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("frames = %q, want %q", frames, want)
+	}
+	if payload := <-executor.payload; !gjson.GetBytes(payload, "stream").Bool() {
+		t.Fatalf("upstream payload = %s, want stream forced on", payload)
+	}
+}
+
+func TestChatCompletionsWebsocketWritesErrorEnvelope(t *testing.T) {
+	executor := &chatWebsocketExecutor{
+		err: &coreauth.Error{Code: "rate_limited", Message: "slow down", HTTPStatus: http.StatusTooManyRequests},
+	}
+	conn := dialChatCompletionsWebsocket(t, executor)
+
+	frames := readWebsocketFrames(t, conn)
+	if len(frames) != 1 {
+		t.Fatalf("frames = %q, want a single error frame", frames)
+	}
+	if got := gjson.Get(frames[0], "error.type").String(); got != "rate_limit_error" {
+		t.Fatalf("error frame = %s, want rate_limit_error envelope", frames[0])
+	}
+}
+
+func TestChatCompletionsWebsocketClientCloseCancelsUpstream(t *testing.T) {
+	executor := &chatWebsocketExecutor{
+		chunks:   []string{`{"object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"a"}}]}`},
+		hold:     true,
+		canceled: make(chan struct{}),
+	}
+	conn := dialChatCompletionsWebsocket(t, executor)
+
+	if _, _, errRead := conn.ReadMessage(); errRead != nil {
+		t.Fatalf("read first chunk: %v", errRead)
+	}
+	if errClose := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")); errClose != nil {
+		t.Fatalf("write close: %v", errClose)
+	}
+
+	select {
+	case <-executor.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream context was not cancelled after the client closed the websocket")
+	}
+}
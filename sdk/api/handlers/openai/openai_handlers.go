@@ -57,15 +57,28 @@ func (h *OpenAIAPIHandler) Models() []map[string]any {
 
 // OpenAIModels handles the /v1/models endpoint.
 // It returns a list of available AI models with their capabilities
-// and specifications in OpenAI-compatible format.
+// and specifications in OpenAI-compatible format, sorted by ID. The provider, owned_by
+// and search query parameters filter the list; limit and after return one page of it
+// together with has_more, first_id and last_id.
 func (h *OpenAIAPIHandler) OpenAIModels(c *gin.Context) {
 	if _, ok := c.Request.URL.Query()["client_version"]; ok {
-		c.JSON(http.StatusOK, h.codexClientModelsResponse())
+		c.JSON(http.StatusOK, h.codexClientModelsResponse(c))
 		return
 	}
 
-	// Get all available models
-	allModels := h.Models()
+	opts, errOpts := handlers.ParseOpenAIModelListOptions(c.Request.URL.Query())
+	if errOpts != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", errOpts),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	// Get the available models matching the filters, one page at a time when asked to
+	allModels, hasMore := handlers.ListModels(h.AllowedModels(c, h.Models(), openAIModelID), openAIModelID, opts)
 
 	// Filter to only include the 4 required fields: id, object, created, owned_by
 	filteredModels := make([]map[string]any, len(allModels))
@@ -88,10 +101,26 @@ func (h *OpenAIAPIHandler) OpenAIModels(c *gin.Context) {
 		filteredModels[i] = filteredModel
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"object": "list",
 		"data":   filteredModels,
-	})
+	}
+	if opts.Paginated() {
+		firstID, lastID := "", ""
+		if len(allModels) > 0 {
+			firstID = openAIModelID(allModels[0])
+			lastID = openAIModelID(allModels[len(allModels)-1])
+		}
+		response["has_more"] = hasMore
+		response["first_id"] = firstID
+		response["last_id"] = lastID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func openAIModelID(model map[string]any) string {
+	id, _ := model["id"].(string)
+	return id
 }
 
 // ChatCompletions handles the /v1/chat/completions endpoint.
@@ -133,6 +162,23 @@ func (h *OpenAIAPIHandler) ChatCompletions(c *gin.Context) {
 
 }
 
+// ChatCompletionsWebsocket handles the /v1/chat/completions/ws endpoint. The
+// client sends the same JSON body as the POST endpoint as its first websocket
+// message and receives each chat.completion.chunk as a text frame, for clients
+// behind proxies that buffer SSE responses.
+func (h *OpenAIAPIHandler) ChatCompletionsWebsocket(c *gin.Context) {
+	alt := h.GetAlt(c)
+	h.ServeStreamWebsocket(c, h, func(ctx context.Context, rawJSON []byte) (<-chan []byte, http.Header, <-chan *interfaces.ErrorMessage) {
+		if shouldTreatAsResponsesFormat(rawJSON) {
+			modelName := gjson.GetBytes(rawJSON, "model").String()
+			rawJSON = responsesconverter.ConvertOpenAIResponsesRequestToOpenAIChatCompletions(modelName, rawJSON, true)
+		}
+		rawJSON, _ = sjson.SetBytes(rawJSON, "stream", true)
+		modelName := gjson.GetBytes(rawJSON, "model").String()
+		return h.ExecuteStreamWithAuthManager(ctx, h.HandlerType(), modelName, rawJSON, alt)
+	})
+}
+
 // shouldTreatAsResponsesFormat detects OpenAI Responses-style payloads that are
 // accidentally sent to the Chat Completions endpoint.
 func shouldTreatAsResponsesFormat(rawJSON []byte) bool {
@@ -664,6 +710,7 @@ func (h *OpenAIAPIHandler) handleCompletionsStreamingResponse(c *gin.Context, ra
 }
 func (h *OpenAIAPIHandler) handleStreamResult(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage) {
 	h.ForwardStream(c, flusher, cancel, data, errs, handlers.StreamForwardOptions{
+		Coalesce: OpenAI,
 		WriteChunk: func(chunk []byte) {
 			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(chunk))
 		},
@@ -679,8 +726,8 @@ func (h *OpenAIAPIHandler) handleStreamResult(c *gin.Context, flusher http.Flush
 			if errMsg.Error != nil && errMsg.Error.Error() != "" {
 				errText = errMsg.Error.Error()
 			}
-			body := handlers.BuildErrorResponseBody(status, errText)
-			_, _ = fmt.Fprintf(c.Writer, "data: %s\n\n", string(body))
+			body := handlers.BuildStreamErrorResponseBody(status, errText)
+			_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", string(body))
 		},
 		WriteDone: func() {
 			_, _ = fmt.Fprint(c.Writer, "data: [DONE]\n\n")
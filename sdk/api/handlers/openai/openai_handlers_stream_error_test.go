@@ -0,0 +1,55 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+func TestHandleStreamResultWrapsHTMLErrorInJSONErrorEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil)
+	h := NewOpenAIAPIHandler(base)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		t.Fatalf("expected gin writer to implement http.Flusher")
+	}
+
+	data := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage, 1)
+	errs <- &interfaces.ErrorMessage{
+		StatusCode: http.StatusBadGateway,
+		Error:      errors.New("<html>\n<head><title>502 Bad Gateway</title></head>\n<body>nginx</body>\n</html>"),
+	}
+	close(errs)
+
+	h.handleStreamResult(c, flusher, func(error) {}, data, errs)
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "event: error\ndata: ") || !strings.HasSuffix(body, "\n\n") {
+		t.Fatalf("expected a single error event, got: %q", body)
+	}
+	payload := strings.TrimSuffix(strings.TrimPrefix(body, "event: error\ndata: "), "\n\n")
+	if strings.Contains(payload, "\n") || !json.Valid([]byte(payload)) {
+		t.Fatalf("expected one line of JSON in the error event, got: %q", payload)
+	}
+	if got := gjson.Get(payload, "error.status").Int(); got != http.StatusBadGateway {
+		t.Fatalf("error.status = %d, want 502; payload=%s", got, payload)
+	}
+	if got := gjson.Get(payload, "error.type").String(); got != "server_error" {
+		t.Fatalf("error.type = %q, want server_error", got)
+	}
+}
@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// isGeminiImagesModel reports whether the model is a Gemini image generation model
+// such as gemini-3-pro-image-preview.
+func isGeminiImagesModel(model string) bool {
+	prefix, baseModel := imagesModelParts(model)
+	baseModel = strings.ToLower(strings.TrimSpace(baseModel))
+	if !strings.HasPrefix(baseModel, "gemini-") || !strings.Contains(baseModel, "-image") {
+		return false
+	}
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	return prefix == "" || prefix == "gemini" || prefix == "google"
+}
+
+// buildGeminiImagesGenerationsRequest converts an OpenAI images/generations request into
+// a Gemini generateContent request for an image model.
+func buildGeminiImagesGenerationsRequest(rawJSON []byte, prompt string) []byte {
+	req := []byte(`{"contents":[{"role":"user","parts":[{"text":""}]}],"generationConfig":{"responseModalities":["IMAGE"]}}`)
+	req, _ = sjson.SetBytes(req, "contents.0.parts.0.text", strings.TrimSpace(prompt))
+
+	if v := gjson.GetBytes(rawJSON, "n"); v.Exists() && v.Type == gjson.Number && v.Int() > 1 {
+		req, _ = sjson.SetBytes(req, "generationConfig.candidateCount", v.Int())
+	}
+
+	size := strings.TrimSpace(gjson.GetBytes(rawJSON, "size").String())
+	aspectRatio := xaiImagesAspectRatio(gjson.GetBytes(rawJSON, "aspect_ratio").String(), "")
+	aspectRatio = xaiImagesAspectRatioFromSize(size, aspectRatio)
+	if aspectRatio != "" {
+		req, _ = sjson.SetBytes(req, "generationConfig.imageConfig.aspectRatio", aspectRatio)
+	}
+	if strings.Contains(size, "2048") {
+		req, _ = sjson.SetBytes(req, "generationConfig.imageConfig.imageSize", "2K")
+	}
+	return req
+}
+
+// buildImagesAPIResponseFromGemini converts Gemini inlineData image parts into an
+// OpenAI images API response.
+func buildImagesAPIResponseFromGemini(payload []byte, responseFormat string) ([]byte, error) {
+	root := gjson.ParseBytes(payload)
+	if root.IsArray() && len(root.Array()) > 0 {
+		root = root.Array()[0]
+	}
+
+	out := []byte(`{"created":0,"data":[]}`)
+	out, _ = sjson.SetBytes(out, "created", time.Now().Unix())
+	responseFormat = normalizeImagesResponseFormat(responseFormat)
+
+	count := 0
+	root.Get("candidates").ForEach(func(_, candidate gjson.Result) bool {
+		candidate.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			if part.Get("thought").Bool() {
+				return true
+			}
+			inlineData := part.Get("inlineData")
+			if !inlineData.Exists() {
+				inlineData = part.Get("inline_data")
+			}
+			data := inlineData.Get("data").String()
+			if data == "" {
+				return true
+			}
+			mimeType := inlineData.Get("mimeType").String()
+			if mimeType == "" {
+				mimeType = inlineData.Get("mime_type").String()
+			}
+
+			item := []byte(`{}`)
+			if responseFormat == "url" {
+				item, _ = sjson.SetBytes(item, "url", "data:"+mimeTypeFromOutputFormat(mimeType)+";base64,"+data)
+			} else {
+				item, _ = sjson.SetBytes(item, "b64_json", data)
+			}
+			out, _ = sjson.SetRawBytes(out, "data.-1", item)
+			count++
+			return true
+		})
+		return true
+	})
+	if count == 0 {
+		return nil, fmt.Errorf("upstream did not return image output")
+	}
+
+	if usage := root.Get("usageMetadata"); usage.Exists() {
+		out, _ = sjson.SetBytes(out, "usage.input_tokens", usage.Get("promptTokenCount").Int())
+		out, _ = sjson.SetBytes(out, "usage.output_tokens", usage.Get("candidatesTokenCount").Int())
+		out, _ = sjson.SetBytes(out, "usage.total_tokens", usage.Get("totalTokenCount").Int())
+	}
+
+	return out, nil
+}
+
+func (h *OpenAIAPIHandler) collectGeminiImages(c *gin.Context, geminiReq []byte, imageModel string, responseFormat string) {
+	c.Header("Content-Type", "application/json")
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	stopKeepAlive := h.StartNonStreamingKeepAlive(c, cliCtx)
+
+	_, model := imagesModelParts(imageModel)
+	resp, upstreamHeaders, errMsg := h.ExecuteWithAuthManager(cliCtx, constant.Gemini, model, geminiReq, "")
+	stopKeepAlive()
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		if errMsg.Error != nil {
+			cliCancel(errMsg.Error)
+		} else {
+			cliCancel(nil)
+		}
+		return
+	}
+
+	out, err := buildImagesAPIResponseFromGemini(resp, responseFormat)
+	if err != nil {
+		errMsg := &interfaces.ErrorMessage{StatusCode: http.StatusBadGateway, Error: err}
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(err)
+		return
+	}
+
+	handlers.WriteUpstreamHeaders(c.Writer.Header(), upstreamHeaders)
+	_, _ = c.Writer.Write(out)
+	cliCancel(nil)
+}
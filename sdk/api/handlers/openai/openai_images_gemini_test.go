@@ -0,0 +1,146 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const testGeminiImagePNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk+M9QDwADhgGAWjR9awAAAABJRU5ErkJggg=="
+
+type geminiImagesCaptureExecutor struct {
+	model        string
+	sourceFormat string
+	payload      []byte
+}
+
+func (e *geminiImagesCaptureExecutor) Identifier() string { return "gemini-images-test" }
+
+func (e *geminiImagesCaptureExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	e.model = req.Model
+	e.sourceFormat = opts.SourceFormat.String()
+	e.payload = append([]byte(nil), req.Payload...)
+	return coreexecutor.Response{Payload: []byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"thinking","thought":true},{"inlineData":{"mimeType":"image/png","data":"` + testGeminiImagePNG + `"}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":1290,"totalTokenCount":1297}}`)}, nil
+}
+
+func (e *geminiImagesCaptureExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *geminiImagesCaptureExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *geminiImagesCaptureExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, errors.New("not implemented")
+}
+
+func (e *geminiImagesCaptureExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func newGeminiImagesTestHandler(t *testing.T, authID string) (*OpenAIAPIHandler, *geminiImagesCaptureExecutor) {
+	t.Helper()
+
+	executor := &geminiImagesCaptureExecutor{}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+
+	auth := &coreauth.Auth{ID: authID, Provider: executor.Identifier(), Status: coreauth.StatusActive}
+	if _, err := manager.Register(context.Background(), auth); err != nil {
+		t.Fatalf("Register auth: %v", err)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: "gemini-3-pro-image-preview"}})
+	t.Cleanup(func() {
+		registry.GetGlobalRegistry().UnregisterClient(auth.ID)
+	})
+
+	base := handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	return NewOpenAIAPIHandler(base), executor
+}
+
+func TestIsGeminiImagesModel(t *testing.T) {
+	for _, model := range []string{"gemini-3-pro-image-preview", "gemini/gemini-3-pro-image", "gemini-3.1-flash-image"} {
+		if !isGeminiImagesModel(model) {
+			t.Fatalf("expected %s to be a Gemini images model", model)
+		}
+	}
+	for _, model := range []string{"gemini-2.5-pro", "gpt-image-2", "xai/gemini-3-pro-image"} {
+		if isGeminiImagesModel(model) {
+			t.Fatalf("expected %s not to be a Gemini images model", model)
+		}
+	}
+}
+
+func TestBuildGeminiImagesGenerationsRequest(t *testing.T) {
+	req := buildGeminiImagesGenerationsRequest([]byte(`{"prompt":"a red square","n":2,"size":"1792x1024"}`), "a red square")
+
+	if got := gjson.GetBytes(req, "contents.0.parts.0.text").String(); got != "a red square" {
+		t.Fatalf("prompt = %q, want a red square", got)
+	}
+	if got := gjson.GetBytes(req, "generationConfig.responseModalities.0").String(); got != "IMAGE" {
+		t.Fatalf("responseModalities[0] = %q, want IMAGE", got)
+	}
+	if got := gjson.GetBytes(req, "generationConfig.candidateCount").Int(); got != 2 {
+		t.Fatalf("candidateCount = %d, want 2", got)
+	}
+	if got := gjson.GetBytes(req, "generationConfig.imageConfig.aspectRatio").String(); got != "16:9" {
+		t.Fatalf("aspectRatio = %q, want 16:9", got)
+	}
+}
+
+func TestImagesGenerationsGeminiModelReturnsB64JSON(t *testing.T) {
+	handler, executor := newGeminiImagesTestHandler(t, "gemini-images-b64")
+	body := strings.NewReader(`{"model":"gemini-3-pro-image-preview","prompt":"a red square","size":"1024x1024"}`)
+
+	resp := performImagesEndpointRequest(t, imagesGenerationsPath, "application/json", body, handler.ImagesGenerations)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", resp.Code, http.StatusOK, resp.Body.String())
+	}
+	if executor.sourceFormat != "gemini" {
+		t.Fatalf("source format = %q, want gemini", executor.sourceFormat)
+	}
+	if executor.model != "gemini-3-pro-image-preview" {
+		t.Fatalf("model = %q, want gemini-3-pro-image-preview", executor.model)
+	}
+	if got := gjson.GetBytes(executor.payload, "generationConfig.imageConfig.aspectRatio").String(); got != "1:1" {
+		t.Fatalf("upstream aspectRatio = %q, want 1:1", got)
+	}
+	data := gjson.GetBytes(resp.Body.Bytes(), "data").Array()
+	if len(data) != 1 {
+		t.Fatalf("data length = %d, want 1: %s", len(data), resp.Body.String())
+	}
+	if got := data[0].Get("b64_json").String(); got != testGeminiImagePNG {
+		t.Fatalf("b64_json = %q, want test PNG", got)
+	}
+	if got := gjson.GetBytes(resp.Body.Bytes(), "usage.total_tokens").Int(); got != 1297 {
+		t.Fatalf("usage.total_tokens = %d, want 1297", got)
+	}
+}
+
+func TestImagesGenerationsGeminiModelReturnsDataURL(t *testing.T) {
+	handler, _ := newGeminiImagesTestHandler(t, "gemini-images-url")
+	body := strings.NewReader(`{"model":"gemini-3-pro-image-preview","prompt":"a red square","response_format":"url"}`)
+
+	resp := performImagesEndpointRequest(t, imagesGenerationsPath, "application/json", body, handler.ImagesGenerations)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", resp.Code, http.StatusOK, resp.Body.String())
+	}
+	if got := gjson.GetBytes(resp.Body.Bytes(), "data.0.url").String(); got != "data:image/png;base64,"+testGeminiImagePNG {
+		t.Fatalf("url = %q, want PNG data URL", got)
+	}
+	if gjson.GetBytes(resp.Body.Bytes(), "data.0.b64_json").Exists() {
+		t.Fatalf("b64_json should be omitted for url response format: %s", resp.Body.String())
+	}
+}
@@ -99,7 +99,7 @@ func writeImagesStreamErrorEvent(c *gin.Context, errMsg *interfaces.ErrorMessage
 	if errMsg.Error != nil && strings.TrimSpace(errMsg.Error.Error()) != "" {
 		errText = errMsg.Error.Error()
 	}
-	body := handlers.BuildErrorResponseBody(status, errText)
+	body := handlers.BuildStreamErrorResponseBody(status, errText)
 	_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", string(body))
 }
 
@@ -607,7 +607,8 @@ func (h *OpenAIAPIHandler) ImagesGenerations(c *gin.Context) {
 	if imageModel == "" {
 		imageModel = defaultImagesToolModel
 	}
-	if rejectUnsupportedImagesModel(c, imageModel) {
+	// Gemini image models are only available for generations, not edits.
+	if !isGeminiImagesModel(imageModel) && rejectUnsupportedImagesModel(c, imageModel) {
 		return
 	}
 
@@ -638,6 +639,12 @@ func (h *OpenAIAPIHandler) ImagesGenerations(c *gin.Context) {
 		h.handleXAIImages(c, xaiReq, responseFormat, "image_generation", stream)
 		return
 	}
+	if isGeminiImagesModel(imageModel) {
+		// Gemini image models return complete images only, so streaming requests are answered as JSON.
+		geminiReq := buildGeminiImagesGenerationsRequest(rawJSON, prompt)
+		h.collectGeminiImages(c, geminiReq, imageModel, responseFormat)
+		return
+	}
 	if isOpenAICompatImagesModel(imageModel) {
 		compatReq := buildOpenAICompatImagesJSONRequest(rawJSON, imageModel, stream)
 		h.handleOpenAICompatImages(c, compatReq, imageModel, responseFormat, "image_generation", stream)
@@ -0,0 +1,128 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+type openAIModelsPage struct {
+	Object string `json:"object"`
+	Data   []struct {
+		ID      string `json:"id"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+	HasMore *bool  `json:"has_more"`
+	FirstID string `json:"first_id"`
+	LastID  string `json:"last_id"`
+}
+
+func registerOpenAIListingModels(t *testing.T, clientID, provider, ownedBy string, ids ...string) {
+	t.Helper()
+	models := make([]*registry.ModelInfo, 0, len(ids))
+	for _, id := range ids {
+		models = append(models, &registry.ModelInfo{ID: id, Object: "model", OwnedBy: ownedBy})
+	}
+	registryRef := registry.GetGlobalRegistry()
+	registryRef.RegisterClient(clientID, provider, models)
+	t.Cleanup(func() {
+		registryRef.UnregisterClient(clientID)
+	})
+}
+
+func requestOpenAIModels(t *testing.T, h *OpenAIAPIHandler, query url.Values) (int, openAIModelsPage) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/models?"+query.Encode(), nil)
+	h.OpenAIModels(c)
+
+	var page openAIModelsPage
+	if recorder.Code == http.StatusOK {
+		if errUnmarshal := json.Unmarshal(recorder.Body.Bytes(), &page); errUnmarshal != nil {
+			t.Fatalf("decode response: %v; body=%s", errUnmarshal, recorder.Body.String())
+		}
+	}
+	return recorder.Code, page
+}
+
+func TestOpenAIModelsWalksFilteredPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil))
+
+	var antigravityIDs, codexIDs []string
+	for i := 1; i <= 24; i++ {
+		antigravityIDs = append(antigravityIDs, fmt.Sprintf("listing-walk-%02d", i))
+	}
+	for i := 25; i <= 36; i++ {
+		codexIDs = append(codexIDs, fmt.Sprintf("listing-walk-%02d", i))
+	}
+	registerOpenAIListingModels(t, "listing-walk-antigravity", "antigravity", "google", antigravityIDs...)
+	registerOpenAIListingModels(t, "listing-walk-codex", "codex", "openai", codexIDs...)
+
+	var walked []string
+	query := url.Values{"search": {"LISTING-WALK"}, "provider": {"antigravity"}, "limit": {"5"}}
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatalf("pagination did not terminate; walked %v", walked)
+		}
+		status, page := requestOpenAIModels(t, h, query)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, want 200", status)
+		}
+		if page.HasMore == nil || len(page.Data) == 0 {
+			t.Fatalf("page %d = %+v, want has_more and models", pages, page)
+		}
+		if page.FirstID != page.Data[0].ID || page.LastID != page.Data[len(page.Data)-1].ID {
+			t.Fatalf("first_id/last_id = %q/%q, want the page bounds", page.FirstID, page.LastID)
+		}
+		for _, model := range page.Data {
+			walked = append(walked, model.ID)
+		}
+		if pages == 0 {
+			// A model registered between pages that sorts before the cursor must not shift
+			// the remaining pages.
+			registerOpenAIListingModels(t, "listing-walk-late", "antigravity", "google", "listing-walk-00")
+		}
+		if !*page.HasMore {
+			break
+		}
+		query.Set("after", page.LastID)
+	}
+
+	if fmt.Sprint(walked) != fmt.Sprint(antigravityIDs) {
+		t.Fatalf("walked %v, want %v", walked, antigravityIDs)
+	}
+}
+
+func TestOpenAIModelsFiltersByOwnerAndSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil))
+	registerOpenAIListingModels(t, "listing-filter-google", "gemini", "google", "listing-filter-gemini-a", "listing-filter-gemini-b")
+	registerOpenAIListingModels(t, "listing-filter-openai", "codex", "openai", "listing-filter-gpt-a")
+
+	status, page := requestOpenAIModels(t, h, url.Values{"search": {"listing-filter"}, "owned_by": {"OpenAI"}})
+	if status != http.StatusOK || len(page.Data) != 1 || page.Data[0].ID != "listing-filter-gpt-a" {
+		t.Fatalf("status = %d, page = %+v, want only listing-filter-gpt-a", status, page)
+	}
+	if page.HasMore != nil {
+		t.Fatalf("unpaginated list reported has_more: %+v", page)
+	}
+
+	_, page = requestOpenAIModels(t, h, url.Values{"search": {"listing-filter-gemini"}})
+	if len(page.Data) != 2 || page.Data[0].ID != "listing-filter-gemini-a" || page.Data[1].ID != "listing-filter-gemini-b" {
+		t.Fatalf("page = %+v, want both gemini models sorted by ID", page)
+	}
+
+	if status, _ = requestOpenAIModels(t, h, url.Values{"limit": {"zero"}}); status != http.StatusBadRequest {
+		t.Fatalf("status for invalid limit = %d, want 400", status)
+	}
+}
@@ -0,0 +1,263 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// responsesToolRoundTripRequest carries an earlier tool call and its output, so
+// backends must receive both and answer with another function_call item.
+const responsesToolRoundTripRequest = `{
+	"model": %q,
+	"stream": %t,
+	"input": [
+		{"type": "message", "role": "user", "content": [{"type": "input_text", "text": "Weather in Paris and Rome?"}]},
+		{"type": "function_call", "call_id": "call_paris", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}"},
+		{"type": "function_call_output", "call_id": "call_paris", "output": "sunny"}
+	],
+	"tools": [{"type": "function", "name": "get_weather", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}}}]
+}`
+
+type responsesBackend struct {
+	name     string
+	provider string
+	model    string
+	newExec  func(*config.Config) coreauth.ProviderExecutor
+	// respond writes the upstream answer: "Sunny" text followed by a get_weather
+	// call for Rome.
+	respond func(w http.ResponseWriter, stream bool)
+	// assertUpstream checks the earlier call and its output reached the backend.
+	assertUpstream func(t *testing.T, body []byte)
+}
+
+var responsesBackends = []responsesBackend{
+	{
+		name:     "gemini",
+		provider: "gemini",
+		model:    "responses-backend-gemini-model",
+		newExec:  func(cfg *config.Config) coreauth.ProviderExecutor { return executor.NewGeminiExecutor(cfg) },
+		respond: func(w http.ResponseWriter, stream bool) {
+			text := `{"candidates":[{"content":{"role":"model","parts":[{"text":"Sunny"}]}}]}`
+			call := `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"get_weather","args":{"city":"Rome"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3,"totalTokenCount":8}}`
+			if stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write([]byte("data: " + text + "\n\ndata: " + call + "\n\n"))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Sunny"},{"functionCall":{"name":"get_weather","args":{"city":"Rome"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3,"totalTokenCount":8}}`))
+		},
+		assertUpstream: func(t *testing.T, body []byte) {
+			t.Helper()
+			if got := gjson.GetBytes(body, `contents.#.parts.#(functionCall).functionCall.name`).String(); !strings.Contains(got, "get_weather") {
+				t.Fatalf("upstream request lacks the earlier functionCall. Body: %s", body)
+			}
+			if got := gjson.GetBytes(body, `contents.#.parts.#(functionResponse).functionResponse.name`).String(); !strings.Contains(got, "get_weather") {
+				t.Fatalf("upstream request lacks the functionResponse. Body: %s", body)
+			}
+		},
+	},
+	{
+		name:     "claude",
+		provider: "claude",
+		model:    "responses-backend-claude-model",
+		newExec:  func(cfg *config.Config) coreauth.ProviderExecutor { return executor.NewClaudeExecutor(cfg) },
+		respond: func(w http.ResponseWriter, stream bool) {
+			if stream {
+				w.Header().Set("Content-Type", "text/event-stream")
+				events := []string{
+					`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":5,"output_tokens":0}}}`,
+					`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+					`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Sunny"}}`,
+					`{"type":"content_block_stop","index":0}`,
+					`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_rome","name":"get_weather","input":{}}}`,
+					`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":\"Rome\"}"}}`,
+					`{"type":"content_block_stop","index":1}`,
+					`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":3}}`,
+					`{"type":"message_stop"}`,
+				}
+				for _, event := range events {
+					_, _ = w.Write([]byte("event: " + gjson.Get(event, "type").String() + "\ndata: " + event + "\n\n"))
+				}
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"Sunny"},{"type":"tool_use","id":"toolu_rome","name":"get_weather","input":{"city":"Rome"}}],"stop_reason":"tool_use","usage":{"input_tokens":5,"output_tokens":3}}`))
+		},
+		assertUpstream: func(t *testing.T, body []byte) {
+			t.Helper()
+			if got := gjson.GetBytes(body, `messages.#.content.#(type=="tool_use").id`).String(); !strings.Contains(got, "call_paris") {
+				t.Fatalf("upstream request lacks the earlier tool_use. Body: %s", body)
+			}
+			if got := gjson.GetBytes(body, `messages.#.content.#(type=="tool_result").tool_use_id`).String(); !strings.Contains(got, "call_paris") {
+				t.Fatalf("upstream request lacks the tool_result. Body: %s", body)
+			}
+		},
+	},
+}
+
+// newResponsesBackendServer serves /v1/responses through the real executor and
+// translators of backend, pointed at a fake upstream.
+func newResponsesBackendServer(t *testing.T, backend responsesBackend) (*httptest.Server, func() []byte) {
+	t.Helper()
+	var mu sync.Mutex
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, errRead := io.ReadAll(r.Body)
+		if errRead != nil {
+			http.Error(w, errRead.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		upstreamBody = body
+		mu.Unlock()
+		stream := strings.Contains(r.URL.Path, "streamGenerateContent") || gjson.GetBytes(body, "stream").Bool()
+		backend.respond(w, stream)
+	}))
+	t.Cleanup(upstream.Close)
+
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(backend.newExec(&config.Config{}))
+	auth := &coreauth.Auth{
+		ID:       "responses-backend-" + backend.name,
+		Provider: backend.provider,
+		Status:   coreauth.StatusActive,
+		Attributes: map[string]string{
+			"api_key":  "test-key",
+			"base_url": upstream.URL,
+		},
+	}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register(): %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: backend.model}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAIResponsesAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.POST("/v1/responses", h.Responses)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, func() []byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return upstreamBody
+	}
+}
+
+func postResponses(t *testing.T, server *httptest.Server, body string) *http.Response {
+	t.Helper()
+	resp, errPost := http.Post(server.URL+"/v1/responses", "application/json", strings.NewReader(body))
+	if errPost != nil {
+		t.Fatalf("post /v1/responses: %v", errPost)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status = %d, body = %s", resp.StatusCode, data)
+	}
+	return resp
+}
+
+func TestResponsesNonStreamRoundTripsToolCallsAcrossBackends(t *testing.T) {
+	for _, backend := range responsesBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			server, upstreamBody := newResponsesBackendServer(t, backend)
+			resp := postResponses(t, server, fmt.Sprintf(responsesToolRoundTripRequest, backend.model, false))
+			body, errRead := io.ReadAll(resp.Body)
+			if errRead != nil {
+				t.Fatalf("read body: %v", errRead)
+			}
+			backend.assertUpstream(t, upstreamBody())
+
+			if got := gjson.GetBytes(body, "object").String(); got != "response" {
+				t.Fatalf("object = %q, want response. Body: %s", got, body)
+			}
+			if got := gjson.GetBytes(body, `output.#(type=="message").content.#(type=="output_text").text`).String(); got != "Sunny" {
+				t.Fatalf("output_text = %q, want Sunny. Body: %s", got, body)
+			}
+			call := gjson.GetBytes(body, `output.#(type=="function_call")`)
+			if call.Get("name").String() != "get_weather" || gjson.Get(call.Get("arguments").String(), "city").String() != "Rome" {
+				t.Fatalf("function_call item = %s, want get_weather for Rome. Body: %s", call.Raw, body)
+			}
+			if call.Get("call_id").String() == "" {
+				t.Fatalf("function_call item has no call_id. Body: %s", body)
+			}
+		})
+	}
+}
+
+func TestResponsesStreamEmitsResponsesEventsAcrossBackends(t *testing.T) {
+	for _, backend := range responsesBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			server, upstreamBody := newResponsesBackendServer(t, backend)
+			resp := postResponses(t, server, fmt.Sprintf(responsesToolRoundTripRequest, backend.model, true))
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+				t.Fatalf("Content-Type = %q, want text/event-stream", got)
+			}
+
+			var types []string
+			var text strings.Builder
+			var call, completed gjson.Result
+			scanner := bufio.NewScanner(resp.Body)
+			scanner.Buffer(nil, 1<<20)
+			for scanner.Scan() {
+				line := scanner.Text()
+				if !strings.HasPrefix(line, "data:") {
+					continue
+				}
+				event := gjson.Parse(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+				eventType := event.Get("type").String()
+				types = append(types, eventType)
+				switch eventType {
+				case "response.output_text.delta":
+					text.WriteString(event.Get("delta").String())
+				case "response.output_item.done":
+					if event.Get("item.type").String() == "function_call" {
+						call = event.Get("item")
+					}
+				case "response.completed":
+					completed = event.Get("response")
+				}
+			}
+			if errScan := scanner.Err(); errScan != nil {
+				t.Fatalf("read stream: %v", errScan)
+			}
+			backend.assertUpstream(t, upstreamBody())
+
+			if len(types) == 0 || types[0] != "response.created" {
+				t.Fatalf("event types = %v, want response.created first", types)
+			}
+			if types[len(types)-1] != "response.completed" {
+				t.Fatalf("event types = %v, want response.completed last", types)
+			}
+			if text.String() != "Sunny" {
+				t.Fatalf("output_text deltas = %q, want Sunny", text.String())
+			}
+			if call.Get("name").String() != "get_weather" || gjson.Get(call.Get("arguments").String(), "city").String() != "Rome" {
+				t.Fatalf("function_call item = %s, want get_weather for Rome", call.Raw)
+			}
+			if !completed.Get(`output.#(type=="function_call")`).Exists() {
+				t.Fatalf("response.completed output lacks the function_call: %s", completed.Raw)
+			}
+		})
+	}
+}
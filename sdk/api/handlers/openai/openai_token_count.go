@@ -0,0 +1,109 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	"github.com/tidwall/gjson"
+)
+
+// tokenCountResponse is the body returned by the /v1/token_count endpoint.
+type tokenCountResponse struct {
+	InputTokens int64  `json:"input_tokens"`
+	Model       string `json:"model"`
+	// Estimated is set when the provider has no count API and the tokens were counted
+	// with a local tokenizer instead.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// TokenCount handles the /v1/token_count endpoint. It accepts a chat completions
+// request and counts its input tokens through the provider the model resolves to,
+// using the upstream count API (Gemini, Antigravity, Claude, ...) when there is one.
+//
+// Parameters:
+//   - c: The Gin context containing the HTTP request and response
+func (h *OpenAIAPIHandler) TokenCount(c *gin.Context) {
+	rawJSON, err := handlers.ReadRequestBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: fmt.Sprintf("Invalid request: %v", err),
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+	modelName := strings.TrimSpace(gjson.GetBytes(rawJSON, "model").String())
+	if modelName == "" {
+		c.JSON(http.StatusBadRequest, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: "Invalid request: model is required",
+				Type:    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	cliCtx, cliCancel := h.GetContextWithCancel(h, c, context.Background())
+	resp, upstreamHeaders, errMsg := h.ExecuteCountWithAuthManager(cliCtx, h.HandlerType(), modelName, rawJSON, h.GetAlt(c))
+	if errMsg != nil {
+		h.WriteErrorResponse(c, errMsg)
+		cliCancel(errMsg.Error)
+		return
+	}
+	count, estimated, ok := tokenCountFromResponse(resp)
+	if !ok {
+		errParse := fmt.Errorf("unrecognized token count response")
+		c.JSON(http.StatusBadGateway, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: errParse.Error(),
+				Type:    "server_error",
+			},
+		})
+		cliCancel(errParse)
+		return
+	}
+	out, err := json.Marshal(tokenCountResponse{InputTokens: count, Model: modelName, Estimated: estimated})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, handlers.ErrorResponse{
+			Error: handlers.ErrorDetail{
+				Message: err.Error(),
+				Type:    "server_error",
+			},
+		})
+		cliCancel(err)
+		return
+	}
+	handlers.WriteUpstreamHeaders(c.Writer.Header(), upstreamHeaders)
+	c.Data(http.StatusOK, "application/json", out)
+	cliCancel()
+}
+
+// tokenCountFromResponse extracts the input tokens from an executor count response to
+// an OpenAI-format request, which no translator rewrites. Upstream count APIs answer as
+// is, with Gemini's totalTokens or Claude's input_tokens; executors without one report a
+// local tokenizer estimate as a chat usage or Responses usage object.
+func tokenCountFromResponse(resp []byte) (count int64, estimated bool, ok bool) {
+	if !gjson.ValidBytes(resp) {
+		return 0, false, false
+	}
+	root := gjson.ParseBytes(resp)
+	if v := root.Get("totalTokens"); v.Exists() {
+		return v.Int(), false, true
+	}
+	if v := root.Get("input_tokens"); v.Exists() {
+		return v.Int(), false, true
+	}
+	if v := root.Get("usage.prompt_tokens"); v.Exists() {
+		return v.Int(), true, true
+	}
+	if v := root.Get("response.usage.input_tokens"); v.Exists() {
+		return v.Int(), true, true
+	}
+	return 0, false, false
+}
@@ -0,0 +1,146 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/runtime/executor"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/api/handlers"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+const tokenCountRequest = `{"model":%q,"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"How many tokens is this?"}]}`
+
+// newTokenCountServer serves /v1/token_count with a single auth of provider, whose
+// executor newExec builds, registered for model.
+func newTokenCountServer(t *testing.T, provider, model string, newExec func(*config.Config) coreauth.ProviderExecutor, attrs map[string]string) *httptest.Server {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(newExec(&config.Config{}))
+	auth := &coreauth.Auth{
+		ID:         "token-count-" + provider,
+		Provider:   provider,
+		Status:     coreauth.StatusActive,
+		Attributes: attrs,
+	}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register(): %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, auth.Provider, []*registry.ModelInfo{{ID: model}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+
+	gin.SetMode(gin.TestMode)
+	h := NewOpenAIAPIHandler(handlers.NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager))
+	router := gin.New()
+	router.POST("/v1/token_count", h.TokenCount)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postTokenCount(t *testing.T, server *httptest.Server, model string) []byte {
+	t.Helper()
+	resp, errPost := http.Post(server.URL+"/v1/token_count", "application/json", strings.NewReader(fmt.Sprintf(tokenCountRequest, model)))
+	if errPost != nil {
+		t.Fatalf("post /v1/token_count: %v", errPost)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, errRead := io.ReadAll(resp.Body)
+	if errRead != nil {
+		t.Fatalf("read body: %v", errRead)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", resp.StatusCode, body)
+	}
+	return body
+}
+
+func TestTokenCountUsesGeminiCountTokens(t *testing.T) {
+	var upstreamPath string
+	var upstreamBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		upstreamBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalTokens":17}`))
+	}))
+	defer upstream.Close()
+
+	const model = "token-count-gemini-model"
+	server := newTokenCountServer(t, "gemini", model, func(cfg *config.Config) coreauth.ProviderExecutor {
+		return executor.NewGeminiExecutor(cfg)
+	}, map[string]string{"api_key": "test-key", "base_url": upstream.URL})
+
+	body := postTokenCount(t, server, model)
+
+	if !strings.HasSuffix(upstreamPath, "/models/"+model+":countTokens") {
+		t.Fatalf("upstream path = %q, want the countTokens endpoint", upstreamPath)
+	}
+	if got := gjson.GetBytes(upstreamBody, "contents.0.parts.0.text").String(); got != "How many tokens is this?" {
+		t.Fatalf("upstream contents = %s, want the translated chat messages", upstreamBody)
+	}
+	if got := gjson.GetBytes(body, "input_tokens").Int(); got != 17 {
+		t.Fatalf("input_tokens = %d, want 17. Body: %s", got, body)
+	}
+	if got := gjson.GetBytes(body, "model").String(); got != model {
+		t.Fatalf("model = %q, want %q", got, model)
+	}
+	if gjson.GetBytes(body, "estimated").Exists() {
+		t.Fatalf("exact count marked as estimated. Body: %s", body)
+	}
+}
+
+func TestTokenCountFallsBackToLocalEstimate(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upstream request %s", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	const model = "token-count-compat-model"
+	server := newTokenCountServer(t, "openai-compatible-local", model, func(cfg *config.Config) coreauth.ProviderExecutor {
+		return executor.NewOpenAICompatExecutor("openai-compatible-local", cfg)
+	}, map[string]string{"api_key": "test-key", "base_url": upstream.URL + "/v1", "compat_name": "local"})
+
+	body := postTokenCount(t, server, model)
+
+	if got := gjson.GetBytes(body, "input_tokens").Int(); got <= 0 {
+		t.Fatalf("input_tokens = %d, want a positive estimate. Body: %s", got, body)
+	}
+	if !gjson.GetBytes(body, "estimated").Bool() {
+		t.Fatalf("local count not marked as estimated. Body: %s", body)
+	}
+}
+
+func TestTokenCountFromResponse(t *testing.T) {
+	tests := []struct {
+		resp          string
+		wantCount     int64
+		wantEstimated bool
+		wantOK        bool
+	}{
+		{resp: `{"totalTokens":12}`, wantCount: 12, wantOK: true},
+		{resp: `{"input_tokens":7}`, wantCount: 7, wantOK: true},
+		{resp: `{"usage":{"prompt_tokens":9,"completion_tokens":0,"total_tokens":9}}`, wantCount: 9, wantEstimated: true, wantOK: true},
+		{resp: `{"response":{"usage":{"input_tokens":5,"output_tokens":0,"total_tokens":5}}}`, wantCount: 5, wantEstimated: true, wantOK: true},
+		{resp: `{"unexpected":true}`},
+		{resp: `not json`},
+	}
+	for _, tt := range tests {
+		count, estimated, ok := tokenCountFromResponse([]byte(tt.resp))
+		if count != tt.wantCount || estimated != tt.wantEstimated || ok != tt.wantOK {
+			t.Errorf("tokenCountFromResponse(%s) = (%d, %t, %t), want (%d, %t, %t)", tt.resp, count, estimated, ok, tt.wantCount, tt.wantEstimated, tt.wantOK)
+		}
+	}
+}
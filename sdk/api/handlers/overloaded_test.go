@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+const overloadedTestProvider = "overloaded-test"
+
+type overloadedTestError struct {
+	retryAfter *time.Duration
+}
+
+func (overloadedTestError) Error() string {
+	return `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`
+}
+func (overloadedTestError) StatusCode() int              { return statusOverloaded }
+func (e overloadedTestError) RetryAfter() *time.Duration { return e.retryAfter }
+
+// overloadedTestExecutor answers every request with an overloaded error.
+type overloadedTestExecutor struct {
+	err overloadedTestError
+}
+
+func (overloadedTestExecutor) Identifier() string { return overloadedTestProvider }
+
+func (e overloadedTestExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, e.err
+}
+
+func (e overloadedTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	return nil, e.err
+}
+
+func (overloadedTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (overloadedTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (overloadedTestExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func newOverloadedTestHandler(t *testing.T, retryAfter *time.Duration) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(overloadedTestExecutor{err: overloadedTestError{retryAfter: retryAfter}})
+	auth := &coreauth.Auth{ID: t.Name() + "-auth", Provider: overloadedTestProvider, Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register(): %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, overloadedTestProvider, []*registry.ModelInfo{{ID: "overloaded-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	return NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+}
+
+func TestOverloadedUpstreamMapsTo503WithRetryAfter(t *testing.T) {
+	hint := 12 * time.Second
+	tests := []struct {
+		name       string
+		retryAfter *time.Duration
+		want       string
+	}{
+		{"upstream hint", &hint, "12"},
+		{"default delay", nil, "30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newOverloadedTestHandler(t, tt.retryAfter)
+			gin.SetMode(gin.TestMode)
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			ctx, cancel := h.GetContextWithCancel(nil, c, context.Background())
+			defer cancel()
+
+			_, _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", "overloaded-model", []byte(`{}`), "")
+			if errMsg == nil {
+				t.Fatal("ExecuteWithAuthManager() succeeded, want an overloaded error")
+			}
+			if errMsg.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want %d", errMsg.StatusCode, http.StatusServiceUnavailable)
+			}
+			h.WriteErrorResponse(c, errMsg)
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Fatalf("response status = %d, want %d", recorder.Code, http.StatusServiceUnavailable)
+			}
+			if got := recorder.Header().Get("Retry-After"); got != tt.want {
+				t.Fatalf("Retry-After = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverloadedStreamMapsTo503(t *testing.T) {
+	h := newOverloadedTestHandler(t, nil)
+	_, _, errs := h.ExecuteStreamWithAuthManager(context.Background(), "openai", "overloaded-model", []byte(`{}`), "")
+	var status int
+	for errMsg := range errs {
+		if errMsg != nil {
+			status = errMsg.StatusCode
+		}
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("stream status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+	if !streamRetryEligible(overloadedTestError{}) {
+		t.Fatal("overloaded errors are not eligible for streaming bootstrap retries")
+	}
+}
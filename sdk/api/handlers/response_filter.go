@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultRedactionReplacement replaces matches of rules without a replacement.
+const defaultRedactionReplacement = "[REDACTED]"
+
+// ResponseFilter post-processes responses after translation, right before they are written
+// to the client. Filters run for every provider and format, in registration order, and
+// must be cheap: FilterStreamChunk runs once per streamed chunk.
+//
+// format is the client response format (e.g. "openai", "claude", "gemini") and model is
+// the model the client requested. Returning an empty slice keeps the input unchanged. A
+// filter that panics is logged and skipped for that body or chunk.
+type ResponseFilter interface {
+	// FilterNonStream returns the filtered body of a non-streaming response.
+	FilterNonStream(format, model string, body []byte) []byte
+	// FilterStreamChunk returns the filtered chunk of a streaming response. Chunks are
+	// filtered one at a time, so a match split across two chunks is not seen.
+	FilterStreamChunk(format, model string, chunk []byte) []byte
+}
+
+// SetResponseFilters replaces the response filters applied before responses are written.
+func (h *BaseAPIHandler) SetResponseFilters(filters ...ResponseFilter) {
+	if h == nil {
+		return
+	}
+	active := make([]ResponseFilter, 0, len(filters))
+	for _, filter := range filters {
+		if !isNilInterface(filter) {
+			active = append(active, filter)
+		}
+	}
+	h.responseFilters.Store(&active)
+}
+
+func (h *BaseAPIHandler) activeResponseFilters() []ResponseFilter {
+	if h == nil {
+		return nil
+	}
+	if filters := h.responseFilters.Load(); filters != nil {
+		return *filters
+	}
+	return nil
+}
+
+// filterNonStreamResponse applies the response filters to a non-streaming body.
+func (h *BaseAPIHandler) filterNonStreamResponse(format, model string, body []byte) []byte {
+	for _, filter := range h.activeResponseFilters() {
+		body = runResponseFilter(filter, body, func() []byte { return filter.FilterNonStream(format, model, body) })
+	}
+	return body
+}
+
+// filterStreamChunk applies the response filters to one streamed chunk.
+func (h *BaseAPIHandler) filterStreamChunk(format, model string, chunk []byte) []byte {
+	for _, filter := range h.activeResponseFilters() {
+		chunk = runResponseFilter(filter, chunk, func() []byte { return filter.FilterStreamChunk(format, model, chunk) })
+	}
+	return chunk
+}
+
+// runResponseFilter runs apply and returns its result, or input when the filter returned
+// nothing or panicked.
+func runResponseFilter(filter ResponseFilter, input []byte, apply func() []byte) (out []byte) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Errorf("response filter %T panicked, passing response through: %v\n%s", filter, recovered, debug.Stack())
+			out = input
+		}
+	}()
+	if filtered := apply(); len(filtered) > 0 {
+		return filtered
+	}
+	return input
+}
+
+// RegexRedactionFilter is a ResponseFilter that replaces regular expression matches in
+// every response, configured by response-redaction.
+type RegexRedactionFilter struct {
+	rules []regexRedactionRule
+}
+
+type regexRedactionRule struct {
+	pattern     *regexp.Regexp
+	replacement []byte
+}
+
+// NewRegexRedactionFilter compiles rules into a filter. Rules with an empty or invalid
+// pattern are skipped and reported in the returned error; the filter holds the others.
+// It returns a nil filter when no rule compiles.
+func NewRegexRedactionFilter(rules []config.ResponseRedactionRule) (*RegexRedactionFilter, error) {
+	filter := &RegexRedactionFilter{}
+	var errs []error
+	for i, rule := range rules {
+		pattern := strings.TrimSpace(rule.Pattern)
+		if pattern == "" {
+			errs = append(errs, fmt.Errorf("response-redaction[%d]: empty pattern", i))
+			continue
+		}
+		compiled, errCompile := regexp.Compile(pattern)
+		if errCompile != nil {
+			errs = append(errs, fmt.Errorf("response-redaction[%d]: %w", i, errCompile))
+			continue
+		}
+		replacement := rule.Replacement
+		if replacement == "" {
+			replacement = defaultRedactionReplacement
+		}
+		filter.rules = append(filter.rules, regexRedactionRule{pattern: compiled, replacement: []byte(replacement)})
+	}
+	if len(filter.rules) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return filter, errors.Join(errs...)
+}
+
+// FilterNonStream redacts body.
+func (f *RegexRedactionFilter) FilterNonStream(_, _ string, body []byte) []byte {
+	return f.redact(body)
+}
+
+// FilterStreamChunk redacts chunk.
+func (f *RegexRedactionFilter) FilterStreamChunk(_, _ string, chunk []byte) []byte {
+	return f.redact(chunk)
+}
+
+func (f *RegexRedactionFilter) redact(data []byte) []byte {
+	if f == nil {
+		return data
+	}
+	for _, rule := range f.rules {
+		if rule.pattern.Match(data) {
+			data = rule.pattern.ReplaceAll(data, rule.replacement)
+		}
+	}
+	return data
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+const responseFilterTestProvider = "response-filter-test"
+
+// responseFilterTestExecutor answers with text naming an internal host and a ticket.
+type responseFilterTestExecutor struct{}
+
+func (responseFilterTestExecutor) Identifier() string { return responseFilterTestProvider }
+
+func (responseFilterTestExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{Payload: []byte(`{"text":"see build-7.corp.example.com for TICKET-1234"}`)}, nil
+}
+
+func (responseFilterTestExecutor) ExecuteStream(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	chunks := make(chan coreexecutor.StreamChunk, 2)
+	chunks <- coreexecutor.StreamChunk{Payload: []byte(`data: {"text":"ask build-7.corp.example.com"}`)}
+	chunks <- coreexecutor.StreamChunk{Payload: []byte(`data: {"text":"about TICKET-99"}`)}
+	close(chunks)
+	return &coreexecutor.StreamResult{Chunks: chunks}, nil
+}
+
+func (responseFilterTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (responseFilterTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (responseFilterTestExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func newResponseFilterTestHandler(t *testing.T, filters ...ResponseFilter) *BaseAPIHandler {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(responseFilterTestExecutor{})
+	auth := &coreauth.Auth{ID: t.Name() + "-auth", Provider: responseFilterTestProvider, Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register(): %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, responseFilterTestProvider, []*registry.ModelInfo{{ID: "response-filter-model"}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+	h.SetResponseFilters(filters...)
+	return h
+}
+
+func newTestRedactionFilter(t *testing.T) *RegexRedactionFilter {
+	t.Helper()
+	filter, errFilter := NewRegexRedactionFilter([]sdkconfig.ResponseRedactionRule{
+		{Pattern: `[a-z0-9-]+\.corp\.example\.com`},
+		{Pattern: `TICKET-[0-9]+`, Replacement: "TICKET-XXXX"},
+	})
+	if errFilter != nil {
+		t.Fatalf("NewRegexRedactionFilter(): %v", errFilter)
+	}
+	return filter
+}
+
+// panickingResponseFilter panics on every call.
+type panickingResponseFilter struct{}
+
+func (panickingResponseFilter) FilterNonStream(string, string, []byte) []byte { panic("boom") }
+
+func (panickingResponseFilter) FilterStreamChunk(string, string, []byte) []byte { panic("boom") }
+
+// recordingResponseFilter records the format and model it was called with.
+type recordingResponseFilter struct {
+	formats []string
+	models  []string
+}
+
+func (f *recordingResponseFilter) FilterNonStream(format, model string, body []byte) []byte {
+	f.formats = append(f.formats, format)
+	f.models = append(f.models, model)
+	return body
+}
+
+func (f *recordingResponseFilter) FilterStreamChunk(format, model string, chunk []byte) []byte {
+	return f.FilterNonStream(format, model, chunk)
+}
+
+func collectFilteredStream(t *testing.T, h *BaseAPIHandler, handlerType string) string {
+	t.Helper()
+	data, _, errs := h.ExecuteStreamWithAuthManager(context.Background(), handlerType, "response-filter-model", []byte(`{}`), "")
+	if data == nil {
+		for errMsg := range errs {
+			t.Fatalf("ExecuteStreamWithAuthManager() error = %v", errMsg.Error)
+		}
+		t.Fatal("ExecuteStreamWithAuthManager() returned no data channel")
+	}
+	var chunks []string
+	for chunk := range data {
+		chunks = append(chunks, string(chunk))
+	}
+	for errMsg := range errs {
+		if errMsg != nil {
+			t.Fatalf("stream error = %v", errMsg.Error)
+		}
+	}
+	return strings.Join(chunks, "\n")
+}
+
+func TestResponseFilterRedactsNonStreamResponses(t *testing.T) {
+	for _, handlerType := range []string{"openai", "claude", "gemini"} {
+		t.Run(handlerType, func(t *testing.T) {
+			h := newResponseFilterTestHandler(t, newTestRedactionFilter(t))
+
+			body, _, errMsg := h.ExecuteWithAuthManager(context.Background(), handlerType, "response-filter-model", []byte(`{}`), "")
+			if errMsg != nil {
+				t.Fatalf("ExecuteWithAuthManager() error = %v", errMsg.Error)
+			}
+			if want := `{"text":"see [REDACTED] for TICKET-XXXX"}`; string(body) != want {
+				t.Fatalf("body = %s, want %s", body, want)
+			}
+		})
+	}
+}
+
+func TestResponseFilterRedactsStreamChunks(t *testing.T) {
+	for _, handlerType := range []string{"openai", "claude", "gemini"} {
+		t.Run(handlerType, func(t *testing.T) {
+			h := newResponseFilterTestHandler(t, newTestRedactionFilter(t))
+
+			got := collectFilteredStream(t, h, handlerType)
+			want := "data: {\"text\":\"ask [REDACTED]\"}\ndata: {\"text\":\"about TICKET-XXXX\"}"
+			if got != want {
+				t.Fatalf("stream = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestResponseFilterReceivesFormatAndRequestedModel(t *testing.T) {
+	recorder := &recordingResponseFilter{}
+	h := newResponseFilterTestHandler(t, recorder)
+
+	if _, _, errMsg := h.ExecuteWithAuthManager(context.Background(), "claude", "response-filter-model", []byte(`{}`), ""); errMsg != nil {
+		t.Fatalf("ExecuteWithAuthManager() error = %v", errMsg.Error)
+	}
+	if len(recorder.formats) != 1 || recorder.formats[0] != "claude" || recorder.models[0] != "response-filter-model" {
+		t.Fatalf("filter calls = %v %v, want claude response-filter-model", recorder.formats, recorder.models)
+	}
+}
+
+func TestResponseFilterPanicPassesResponseThrough(t *testing.T) {
+	h := newResponseFilterTestHandler(t, panickingResponseFilter{}, newTestRedactionFilter(t))
+
+	body, _, errMsg := h.ExecuteWithAuthManager(context.Background(), "openai", "response-filter-model", []byte(`{}`), "")
+	if errMsg != nil {
+		t.Fatalf("ExecuteWithAuthManager() error = %v", errMsg.Error)
+	}
+	if want := `{"text":"see [REDACTED] for TICKET-XXXX"}`; string(body) != want {
+		t.Fatalf("body = %s, want %s", body, want)
+	}
+
+	got := collectFilteredStream(t, h, "openai")
+	if want := "data: {\"text\":\"ask [REDACTED]\"}\ndata: {\"text\":\"about TICKET-XXXX\"}"; got != want {
+		t.Fatalf("stream = %q, want %q", got, want)
+	}
+}
+
+func TestNewRegexRedactionFilterSkipsInvalidPatterns(t *testing.T) {
+	filter, errFilter := NewRegexRedactionFilter([]sdkconfig.ResponseRedactionRule{
+		{Pattern: `(`},
+		{Pattern: `secret`},
+	})
+	if errFilter == nil {
+		t.Fatal("NewRegexRedactionFilter() error = nil, want invalid pattern error")
+	}
+	if filter == nil {
+		t.Fatal("NewRegexRedactionFilter() = nil, want filter with the valid rule")
+	}
+	if got := string(filter.FilterNonStream("openai", "m", []byte("a secret"))); got != "a [REDACTED]" {
+		t.Fatalf("FilterNonStream() = %q, want %q", got, "a [REDACTED]")
+	}
+
+	if filter, _ := NewRegexRedactionFilter([]sdkconfig.ResponseRedactionRule{{Pattern: `[`}}); filter != nil {
+		t.Fatal("NewRegexRedactionFilter() with no valid rule should return nil")
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+
+	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// streamCoalesceMaxBytes writes a coalesced chunk as soon as it grows past this size,
+// even before the coalescing window (streaming.coalesce-ms) ends.
+const streamCoalesceMaxBytes = 4096
+
+// streamChunkCoalescible reports whether chunk is a plain text delta of format that may
+// be held back and merged with the deltas following it. Everything else, such as finish
+// chunks, usage, tool calls and block boundaries, is written at once.
+func streamChunkCoalescible(format string, chunk []byte) bool {
+	switch format {
+	case OpenAI:
+		_, _, ok := openAITextDelta(chunk)
+		return ok
+	case Claude:
+		_, ok := claudeTextDelta(chunk)
+		return ok
+	default:
+		return false
+	}
+}
+
+// coalesceStreamChunks merges next into pending, both coalescible chunks of format. ok is
+// false when the deltas cannot be merged, e.g. because they belong to different blocks.
+func coalesceStreamChunks(format string, pending, next []byte) ([]byte, bool) {
+	switch format {
+	case OpenAI:
+		return coalesceOpenAIDeltas(pending, next)
+	case Claude:
+		return coalesceClaudeDeltas(pending, next)
+	default:
+		return nil, false
+	}
+}
+
+// openAITextDelta returns the delta field ("content" or "reasoning_content") and text of
+// a single-choice chat completion chunk that carries nothing but text.
+func openAITextDelta(chunk []byte) (string, string, bool) {
+	if !gjson.ValidBytes(chunk) {
+		return "", "", false
+	}
+	root := gjson.ParseBytes(chunk)
+	if usage := root.Get("usage"); usage.Exists() && usage.Type != gjson.Null {
+		return "", "", false
+	}
+	choices := root.Get("choices").Array()
+	if len(choices) != 1 || choices[0].Get("index").Int() != 0 {
+		return "", "", false
+	}
+	if finish := choices[0].Get("finish_reason"); finish.Exists() && finish.Type != gjson.Null {
+		return "", "", false
+	}
+	field, text := "", ""
+	ok := true
+	choices[0].Get("delta").ForEach(func(key, value gjson.Result) bool {
+		switch key.String() {
+		case "role":
+		case "content", "reasoning_content":
+			if value.Type != gjson.String || (field != "" && field != key.String()) {
+				ok = false
+				return false
+			}
+			field, text = key.String(), value.String()
+		default:
+			ok = value.Type == gjson.Null
+		}
+		return ok
+	})
+	return field, text, ok && field != ""
+}
+
+func coalesceOpenAIDeltas(pending, next []byte) ([]byte, bool) {
+	pendingField, pendingText, okPending := openAITextDelta(pending)
+	nextField, nextText, okNext := openAITextDelta(next)
+	if !okPending || !okNext || pendingField != nextField {
+		return nil, false
+	}
+	if gjson.GetBytes(next, "choices.0.delta.role").Exists() {
+		return nil, false
+	}
+	if gjson.GetBytes(pending, "id").String() != gjson.GetBytes(next, "id").String() {
+		return nil, false
+	}
+	merged, errSet := sjson.SetBytes(bytes.Clone(pending), "choices.0.delta."+pendingField, pendingText+nextText)
+	if errSet != nil {
+		return nil, false
+	}
+	return merged, true
+}
+
+// claudeTextDelta returns the data of a chunk holding exactly one content_block_delta
+// event with a text or thinking delta.
+func claudeTextDelta(chunk []byte) ([]byte, bool) {
+	var data []byte
+	events := 0
+	for _, event := range bytes.Split(chunk, []byte("\n\n")) {
+		if len(bytes.TrimSpace(event)) == 0 {
+			continue
+		}
+		events++
+		data = sseEventData(event)
+	}
+	if events != 1 || !gjson.ValidBytes(data) || gjson.GetBytes(data, "type").String() != "content_block_delta" {
+		return nil, false
+	}
+	switch gjson.GetBytes(data, "delta.type").String() {
+	case "text_delta", "thinking_delta":
+		return data, true
+	default:
+		return nil, false
+	}
+}
+
+func coalesceClaudeDeltas(pending, next []byte) ([]byte, bool) {
+	pendingData, okPending := claudeTextDelta(pending)
+	nextData, okNext := claudeTextDelta(next)
+	if !okPending || !okNext {
+		return nil, false
+	}
+	deltaType := gjson.GetBytes(pendingData, "delta.type").String()
+	if gjson.GetBytes(pendingData, "index").Int() != gjson.GetBytes(nextData, "index").Int() ||
+		deltaType != gjson.GetBytes(nextData, "delta.type").String() {
+		return nil, false
+	}
+	field := "delta.text"
+	if deltaType == "thinking_delta" {
+		field = "delta.thinking"
+	}
+	text := gjson.GetBytes(pendingData, field).String() + gjson.GetBytes(nextData, field).String()
+	merged, errSet := sjson.SetBytes(bytes.Clone(pendingData), field, text)
+	if errSet != nil {
+		return nil, false
+	}
+	out := make([]byte, 0, len(merged)+40)
+	out = append(out, "event: content_block_delta\ndata: "...)
+	out = append(out, merged...)
+	return append(out, "\n\n"...), true
+}
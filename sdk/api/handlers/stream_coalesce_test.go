@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// forwardCoalesced runs ForwardStream over chunks, all available up front, and returns
+// what WriteChunk received followed by "[DONE]".
+func forwardCoalesced(t testing.TB, coalesceMs int, format string, chunks []string) []string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &sdkconfig.SDKConfig{}
+	cfg.Streaming.CoalesceMs = coalesceMs
+	h := NewBaseAPIHandlers(cfg, nil)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/stream", nil)
+
+	data := make(chan []byte, len(chunks))
+	for _, chunk := range chunks {
+		data <- []byte(chunk)
+	}
+	close(data)
+	errs := make(chan *interfaces.ErrorMessage)
+
+	var writes []string
+	disabled := time.Duration(0)
+	h.ForwardStream(c, c.Writer.(http.Flusher), func(error) {}, data, errs, StreamForwardOptions{
+		KeepAliveInterval: &disabled,
+		Coalesce:          format,
+		WriteChunk: func(chunk []byte) {
+			writes = append(writes, string(chunk))
+		},
+		WriteDone: func() {
+			writes = append(writes, "[DONE]")
+		},
+	})
+	return writes
+}
+
+func openAIChunk(delta string) string {
+	return `{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":` + delta + `,"finish_reason":null}]}`
+}
+
+func claudeEvent(event, data string) string {
+	return "event: " + event + "\ndata: " + data + "\n\n"
+}
+
+func claudeTextDeltaEvent(index int, text string) string {
+	return claudeEvent("content_block_delta", fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"text_delta","text":%q}}`, index, text))
+}
+
+// openAIText concatenates the content deltas of writes.
+func openAIText(writes []string) string {
+	var text strings.Builder
+	for _, write := range writes {
+		text.WriteString(gjson.Get(write, "choices.0.delta.content").String())
+	}
+	return text.String()
+}
+
+// claudeText concatenates the text deltas of writes.
+func claudeText(writes []string) string {
+	var text strings.Builder
+	for _, write := range writes {
+		data := sseEventData([]byte(strings.TrimSpace(write)))
+		text.WriteString(gjson.GetBytes(data, "delta.text").String())
+	}
+	return text.String()
+}
+
+func TestForwardStreamCoalescesOpenAIDeltas(t *testing.T) {
+	chunks := []string{
+		openAIChunk(`{"role":"assistant","content":"Hel"}`),
+		openAIChunk(`{"content":"lo"}`),
+		openAIChunk(`{"content":", "}`),
+		openAIChunk(`{"content":"world"}`),
+		openAIChunk(`{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"f","arguments":""}}]}`),
+		openAIChunk(`{"content":"!"}`),
+		`{"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	}
+
+	plain := forwardCoalesced(t, 0, OpenAI, chunks)
+	coalesced := forwardCoalesced(t, 1000, OpenAI, chunks)
+
+	if len(plain) != len(chunks)+1 {
+		t.Fatalf("plain writes = %d, want %d", len(plain), len(chunks)+1)
+	}
+	if len(coalesced) != 5 {
+		t.Fatalf("coalesced writes = %d, want 5: %q", len(coalesced), coalesced)
+	}
+	if got, want := openAIText(coalesced), openAIText(plain); got != want {
+		t.Fatalf("coalesced text = %q, want %q", got, want)
+	}
+	if got := gjson.Get(coalesced[0], "choices.0.delta.role").String(); got != "assistant" {
+		t.Fatalf("first write role = %q, want assistant", got)
+	}
+	if !gjson.Get(coalesced[1], "choices.0.delta.tool_calls").Exists() {
+		t.Fatalf("second write = %s, want the tool call right after the merged text", coalesced[1])
+	}
+	if coalesced[2] != chunks[5] || coalesced[3] != chunks[6] || coalesced[4] != "[DONE]" {
+		t.Fatalf("tail writes = %q, want the trailing text, finish chunk and [DONE] in order", coalesced[2:])
+	}
+}
+
+func TestForwardStreamCoalescesClaudeDeltas(t *testing.T) {
+	chunks := []string{
+		claudeEvent("content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`),
+		claudeTextDeltaEvent(0, "Hel"),
+		claudeTextDeltaEvent(0, "lo"),
+		claudeTextDeltaEvent(0, " world"),
+		claudeEvent("content_block_stop", `{"type":"content_block_stop","index":0}`),
+		claudeEvent("content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"f","input":{}}}`),
+		claudeEvent("content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{}"}}`),
+		claudeEvent("content_block_stop", `{"type":"content_block_stop","index":1}`),
+		claudeEvent("message_stop", `{"type":"message_stop"}`),
+	}
+
+	plain := forwardCoalesced(t, 0, Claude, chunks)
+	coalesced := forwardCoalesced(t, 1000, Claude, chunks)
+
+	if len(coalesced) != len(plain)-2 {
+		t.Fatalf("coalesced writes = %d, want %d: %q", len(coalesced), len(plain)-2, coalesced)
+	}
+	if got, want := claudeText(coalesced), claudeText(plain); got != want || got != "Hello world" {
+		t.Fatalf("coalesced text = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(coalesced[1], "event: content_block_delta\ndata: ") {
+		t.Fatalf("merged write = %q, want a content_block_delta event", coalesced[1])
+	}
+	for i, want := range append(chunks[4:], "[DONE]") {
+		if coalesced[2+i] != want {
+			t.Fatalf("write %d = %q, want %q", 2+i, coalesced[2+i], want)
+		}
+	}
+}
+
+func TestForwardStreamCoalesceFlushesAfterWindow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &sdkconfig.SDKConfig{}
+	cfg.Streaming.CoalesceMs = 20
+	h := NewBaseAPIHandlers(cfg, nil)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/stream", nil)
+
+	data := make(chan []byte)
+	errs := make(chan *interfaces.ErrorMessage)
+	written := make(chan string, 4)
+	done := make(chan struct{})
+	disabled := time.Duration(0)
+	go func() {
+		defer close(done)
+		h.ForwardStream(c, c.Writer.(http.Flusher), func(error) {}, data, errs, StreamForwardOptions{
+			KeepAliveInterval: &disabled,
+			Coalesce:          OpenAI,
+			WriteChunk: func(chunk []byte) {
+				written <- string(chunk)
+			},
+		})
+	}()
+
+	data <- []byte(openAIChunk(`{"content":"a"}`))
+	data <- []byte(openAIChunk(`{"content":"b"}`))
+	select {
+	case got := <-written:
+		if text := gjson.Get(got, "choices.0.delta.content").String(); text != "ab" {
+			t.Fatalf("flushed text = %q, want %q", text, "ab")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("pending deltas were not written after the coalescing window")
+	}
+	close(data)
+	<-done
+}
+
+func BenchmarkForwardStreamCoalesce(b *testing.B) {
+	chunks := make([]string, 0, 200)
+	for i := 0; i < cap(chunks); i++ {
+		chunks = append(chunks, openAIChunk(`{"content":"token "}`))
+	}
+	for _, coalesceMs := range []int{0, 50} {
+		b.Run(fmt.Sprintf("coalesce-ms=%d", coalesceMs), func(b *testing.B) {
+			var writes int
+			for b.Loop() {
+				writes = len(forwardCoalesced(b, coalesceMs, OpenAI, chunks))
+			}
+			b.ReportMetric(float64(writes), "writes/stream")
+		})
+	}
+}
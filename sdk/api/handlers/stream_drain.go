@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errServerShuttingDown is reported to streaming clients cancelled by a shutdown drain.
+var errServerShuttingDown = errors.New("server is shutting down")
+
+// streamRegistry tracks active streaming responses so shutdown can wait for them
+// and cancel the ones that outlive the drain window.
+type streamRegistry struct {
+	mu      sync.Mutex
+	nextID  uint64
+	active  map[uint64]chan struct{}
+	idle    chan struct{}
+	aborted bool
+}
+
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{active: make(map[uint64]chan struct{})}
+}
+
+// register tracks a new stream. The returned channel is closed when the stream must
+// stop because shutdown is cancelling it; release must be called when the stream ends.
+func (r *streamRegistry) register() (<-chan struct{}, func()) {
+	if r == nil {
+		return nil, func() {}
+	}
+	abort := make(chan struct{})
+	r.mu.Lock()
+	if r.aborted {
+		close(abort)
+	}
+	r.nextID++
+	id := r.nextID
+	r.active[id] = abort
+	r.mu.Unlock()
+
+	var once sync.Once
+	return abort, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.active, id)
+			if len(r.active) == 0 && r.idle != nil {
+				close(r.idle)
+				r.idle = nil
+			}
+			r.mu.Unlock()
+		})
+	}
+}
+
+// count returns the number of active streams.
+func (r *streamRegistry) count() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.active)
+}
+
+// waitIdle blocks until no streams are active or done fires. It reports whether
+// all streams finished.
+func (r *streamRegistry) waitIdle(done <-chan struct{}) bool {
+	r.mu.Lock()
+	if len(r.active) == 0 {
+		r.mu.Unlock()
+		return true
+	}
+	if r.idle == nil {
+		r.idle = make(chan struct{})
+	}
+	idle := r.idle
+	r.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// abortAll cancels every active stream and any stream registered afterwards.
+// It returns the number of streams cancelled.
+func (r *streamRegistry) abortAll() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.aborted {
+		return 0
+	}
+	r.aborted = true
+	for _, abort := range r.active {
+		close(abort)
+	}
+	return len(r.active)
+}
+
+// ActiveStreams returns the number of streaming responses currently being forwarded.
+func (h *BaseAPIHandler) ActiveStreams() int {
+	if h == nil {
+		return 0
+	}
+	return h.streams.count()
+}
+
+// DrainStreams waits up to window for active streams to finish. Streams still running
+// afterwards are cancelled with a terminal error event, and DrainStreams then waits for
+// them to exit until ctx is done. It returns the number of streams cancelled.
+func (h *BaseAPIHandler) DrainStreams(ctx context.Context, window time.Duration) int {
+	if h == nil || h.streams == nil {
+		return 0
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if window > 0 {
+		timer := time.NewTimer(window)
+		drainCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-timer.C:
+				cancel()
+			case <-drainCtx.Done():
+			}
+		}()
+		finished := h.streams.waitIdle(drainCtx.Done())
+		timer.Stop()
+		cancel()
+		if finished {
+			return 0
+		}
+	}
+	aborted := h.streams.abortAll()
+	h.streams.waitIdle(ctx.Done())
+	return aborted
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+func newSlowStreamServer(t *testing.T, h *BaseAPIHandler, finish <-chan struct{}) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		flusher := c.Writer.(http.Flusher)
+		data := make(chan []byte, 1)
+		errs := make(chan *interfaces.ErrorMessage)
+		data <- []byte("first")
+		go func() {
+			<-finish
+			close(data)
+		}()
+		disabled := time.Duration(0)
+		h.ForwardStream(c, flusher, func(error) {}, data, errs, StreamForwardOptions{
+			KeepAliveInterval: &disabled,
+			WriteChunk: func(chunk []byte) {
+				_, _ = c.Writer.Write([]byte("data: " + string(chunk) + "\n\n"))
+			},
+			WriteDone: func() {
+				_, _ = c.Writer.Write([]byte("data: [DONE]\n\n"))
+			},
+		})
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openSlowStream(t *testing.T, server *httptest.Server, h *BaseAPIHandler) *bufio.Reader {
+	t.Helper()
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET /stream: %v", err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	reader := bufio.NewReader(resp.Body)
+	if line, errRead := reader.ReadString('\n'); errRead != nil || line != "data: first\n" {
+		t.Fatalf("first line = %q, err = %v", line, errRead)
+	}
+	if got := h.ActiveStreams(); got != 1 {
+		t.Fatalf("ActiveStreams() = %d, want 1", got)
+	}
+	return reader
+}
+
+func TestDrainStreamsCancelsStreamsAfterWindowWithErrorEvent(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil)
+	finish := make(chan struct{})
+	defer close(finish)
+	reader := openSlowStream(t, newSlowStreamServer(t, h, finish), h)
+
+	window := 100 * time.Millisecond
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if aborted := h.DrainStreams(ctx, window); aborted != 1 {
+		t.Fatalf("DrainStreams() aborted = %d, want 1", aborted)
+	}
+	elapsed := time.Since(start)
+	if elapsed < window || elapsed > window+time.Second {
+		t.Fatalf("DrainStreams() took %s, want about %s", elapsed, window)
+	}
+	if got := h.ActiveStreams(); got != 0 {
+		t.Fatalf("ActiveStreams() = %d after drain, want 0", got)
+	}
+
+	var rest strings.Builder
+	for {
+		line, errRead := reader.ReadString('\n')
+		rest.WriteString(line)
+		if errRead != nil {
+			break
+		}
+	}
+	body := rest.String()
+	if !strings.Contains(body, "event: error\ndata: {") || !strings.Contains(body, "server is shutting down") {
+		t.Fatalf("stream tail = %q, want error event", body)
+	}
+}
+
+func TestDrainStreamsWaitsForStreamsFinishingInsideWindow(t *testing.T) {
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, nil)
+	finish := make(chan struct{})
+	reader := openSlowStream(t, newSlowStreamServer(t, h, finish), h)
+
+	time.AfterFunc(50*time.Millisecond, func() { close(finish) })
+	if aborted := h.DrainStreams(context.Background(), 5*time.Second); aborted != 0 {
+		t.Fatalf("DrainStreams() aborted = %d, want 0", aborted)
+	}
+	var rest strings.Builder
+	for {
+		line, errRead := reader.ReadString('\n')
+		rest.WriteString(line)
+		if errRead != nil {
+			break
+		}
+	}
+	if body := rest.String(); !strings.Contains(body, "data: [DONE]") || strings.Contains(body, "event: error") {
+		t.Fatalf("stream tail = %q, want normal completion", body)
+	}
+}
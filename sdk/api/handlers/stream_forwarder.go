@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
 )
 
 type StreamForwardOptions struct {
@@ -27,6 +29,11 @@ type StreamForwardOptions struct {
 	// WriteKeepAlive optionally writes a keep-alive heartbeat. It should not flush.
 	// When nil, a standard SSE comment heartbeat is used.
 	WriteKeepAlive func()
+
+	// Coalesce names the wire format of the chunks (OpenAI or Claude). When set, text deltas
+	// arriving within the streaming.coalesce-ms window are merged into a single write.
+	// Empty disables coalescing.
+	Coalesce string
 }
 
 func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, cancel func(error), data <-chan []byte, errs <-chan *interfaces.ErrorMessage, opts StreamForwardOptions) {
@@ -37,6 +44,23 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 		return
 	}
 
+	abort, release := h.streams.register()
+	defer release()
+
+	// The stream span lasts until the data channel closes or the stream fails.
+	_, streamSpan := tracing.Start(c.Request.Context(), "stream")
+	var streamErr *interfaces.ErrorMessage
+	defer func() {
+		var errStream error
+		if streamErr != nil {
+			errStream = streamErr.Error
+			if streamErr.StatusCode > 0 {
+				streamSpan.SetAttributes(tracing.AttrStatus.Int(streamErr.StatusCode))
+			}
+		}
+		tracing.End(streamSpan, errStream)
+	}()
+
 	writeChunk := opts.WriteChunk
 	if writeChunk == nil {
 		writeChunk = func([]byte) {}
@@ -61,14 +85,52 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 		keepAliveC = keepAlive.C
 	}
 
+	// Text deltas are held in pending for up to coalesceWindow; any other chunk, a
+	// terminal marker or an error writes them out first so ordering is preserved.
+	var coalesceWindow time.Duration
+	if opts.Coalesce != "" {
+		coalesceWindow = StreamingCoalesceWindow(h.Cfg)
+	}
+	var pending []byte
+	var coalesceTimer *time.Timer
+	var coalesceC <-chan time.Time
+	writePending := func() {
+		if pending == nil {
+			return
+		}
+		writeChunk(pending)
+		pending = nil
+		coalesceTimer.Stop()
+		coalesceC = nil
+	}
+	defer func() {
+		if coalesceTimer != nil {
+			coalesceTimer.Stop()
+		}
+	}()
+
 	var terminalErr *interfaces.ErrorMessage
 	for {
 		select {
 		case <-c.Request.Context().Done():
+			streamErr = &interfaces.ErrorMessage{Error: c.Request.Context().Err()}
 			cancel(c.Request.Context().Err())
 			return
+		case <-abort:
+			writePending()
+			errMsg := &interfaces.ErrorMessage{StatusCode: http.StatusServiceUnavailable, Error: errServerShuttingDown}
+			if opts.WriteTerminalError != nil {
+				opts.WriteTerminalError(errMsg)
+			} else {
+				_, _ = fmt.Fprintf(c.Writer, "event: error\ndata: %s\n\n", BuildStreamErrorResponseBody(errMsg.StatusCode, errMsg.Error.Error()))
+			}
+			flusher.Flush()
+			streamErr = errMsg
+			cancel(errMsg.Error)
+			return
 		case chunk, ok := <-data:
 			if !ok {
+				writePending()
 				// Prefer surfacing a terminal error if one is pending.
 				if terminalErr == nil {
 					select {
@@ -84,6 +146,7 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 						opts.WriteTerminalError(terminalErr)
 					}
 					flusher.Flush()
+					streamErr = terminalErr
 					cancel(terminalErr.Error)
 					return
 				}
@@ -94,21 +157,54 @@ func (h *BaseAPIHandler) ForwardStream(c *gin.Context, flusher http.Flusher, can
 				cancel(nil)
 				return
 			}
+			if coalesceWindow > 0 && streamChunkCoalescible(opts.Coalesce, chunk) {
+				if pending != nil {
+					if merged, okMerge := coalesceStreamChunks(opts.Coalesce, pending, chunk); okMerge {
+						pending = merged
+					} else {
+						writePending()
+					}
+				}
+				if pending == nil {
+					pending = chunk
+					if coalesceTimer == nil {
+						coalesceTimer = time.NewTimer(coalesceWindow)
+					} else {
+						coalesceTimer.Reset(coalesceWindow)
+					}
+					coalesceC = coalesceTimer.C
+				}
+				if len(pending) >= streamCoalesceMaxBytes {
+					writePending()
+					flusher.Flush()
+				}
+				continue
+			}
+			writePending()
 			writeChunk(chunk)
 			flusher.Flush()
+		case <-coalesceC:
+			writePending()
+			flusher.Flush()
 		case errMsg, ok := <-errs:
 			if !ok {
 				continue
 			}
+			flush := pending != nil
+			writePending()
 			if errMsg != nil {
 				terminalErr = errMsg
 				if opts.WriteTerminalError != nil {
 					opts.WriteTerminalError(errMsg)
-					flusher.Flush()
+					flush = true
 				}
 			}
+			if flush {
+				flusher.Flush()
+			}
 			var execErr error
 			if errMsg != nil {
+				streamErr = errMsg
 				execErr = errMsg.Error
 			}
 			cancel(execErr)
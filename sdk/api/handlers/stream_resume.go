@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/router-for-me/CLIProxyAPI/v7/internal/constant"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxStreamResumes bounds how often one stream is resumed after mid-stream failures.
+const maxStreamResumes = 2
+
+// resumeAlignBytes is how much of the emitted text a resumed attempt must repeat before it
+// counts as regenerated from the start, so a continuation that merely begins like the
+// emitted text ("Sure. " followed by "Such...") is not mistaken for a replay.
+const resumeAlignBytes = 32
+
+// streamResume tracks the client-visible output of a stream so that a stream cut after
+// its first byte can be continued on another attempt (streaming.resume-on-error).
+//
+// The resumed request carries the text emitted so far as an assistant prefill. Upstreams
+// that honour the prefill continue after it; upstreams that drop it regenerate from the
+// start, in which case the replayed prefix is skipped by diffing it against the emitted
+// text. The text of a resumed attempt is held back until it either diverges from the
+// emitted text or repeats enough of it to tell the two cases apart. Only plain text output can be resumed: once tool calls, thinking or several
+// choices were streamed, resumption is off and failures surface as before.
+type streamResume struct {
+	protocol  string
+	emitted   strings.Builder
+	resumable bool
+
+	// Resumed attempt state.
+	resumed  bool
+	aligned  bool
+	replay   string
+	matched  int
+	pending  string
+	prefill  string
+	streamID string
+
+	// Claude content block bookkeeping: openText is the index of the text block that is
+	// still open on the client side (-1 for none), nextIndex the next free block index,
+	// and indexMap maps block indices of the resumed attempt to client indices.
+	openText  int
+	nextIndex int
+	indexMap  map[int64]int
+}
+
+// newStreamResume returns the resume tracker of a stream, or nil when the protocol pair
+// cannot be resumed.
+func newStreamResume(entryProtocol, responseProtocol string) *streamResume {
+	if entryProtocol != responseProtocol {
+		return nil
+	}
+	switch entryProtocol {
+	case OpenAI, Claude:
+		return &streamResume{protocol: entryProtocol, resumable: true, openText: -1}
+	default:
+		return nil
+	}
+}
+
+// canResume reports whether the output seen so far can be continued by a new attempt.
+func (r *streamResume) canResume() bool {
+	return r != nil && r.resumable && r.emitted.Len() > 0
+}
+
+// resumeRequest returns the request payload of the next attempt: payload with the text
+// emitted so far appended as an assistant turn. ok is false when the payload has no
+// message list to extend.
+func (r *streamResume) resumeRequest(payload []byte) ([]byte, bool) {
+	if !gjson.GetBytes(payload, "messages").IsArray() {
+		return nil, false
+	}
+	// Anthropic rejects a final assistant turn that ends with whitespace.
+	prefill := strings.TrimRightFunc(r.emitted.String(), func(c rune) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+	})
+	if prefill == "" {
+		return nil, false
+	}
+	out, ok := appendAssistantPrefill(bytes.Clone(payload), r.protocol, prefill)
+	if !ok {
+		return nil, false
+	}
+	r.prefill = prefill
+	r.resumed = true
+	r.aligned = false
+	r.replay = ""
+	r.matched = 0
+	r.pending = ""
+	r.indexMap = make(map[int64]int)
+	return out, true
+}
+
+// appendAssistantPrefill adds prefill to the end of the message list of payload. When the
+// client already ended it with an assistant turn, the upstream continues that turn, so the
+// prefill is appended to its text instead of opening a second assistant turn in a row.
+func appendAssistantPrefill(payload []byte, protocol, prefill string) ([]byte, bool) {
+	messages := gjson.GetBytes(payload, "messages").Array()
+	if n := len(messages); n > 0 && messages[n-1].Get("role").String() == "assistant" {
+		path := "messages." + strconv.Itoa(n-1) + ".content"
+		content := messages[n-1].Get("content")
+		var errSet error
+		switch {
+		case content.Type == gjson.String:
+			payload, errSet = sjson.SetBytes(payload, path, content.String()+prefill)
+		case content.IsArray():
+			parts := content.Array()
+			if last := len(parts) - 1; last >= 0 && parts[last].Get("type").String() == "text" {
+				textPath := path + "." + strconv.Itoa(last) + ".text"
+				payload, errSet = sjson.SetBytes(payload, textPath, parts[last].Get("text").String()+prefill)
+			} else {
+				payload, errSet = sjson.SetRawBytes(payload, path+".-1", []byte(`{"type":"text"}`))
+				if errSet == nil {
+					payload, errSet = sjson.SetBytes(payload, path+"."+strconv.Itoa(len(parts))+".text", prefill)
+				}
+			}
+		default:
+			payload, errSet = sjson.SetBytes(payload, path, prefill)
+		}
+		return payload, errSet == nil
+	}
+	message := []byte(`{"role":"assistant"}`)
+	if protocol == Claude {
+		message, _ = sjson.SetRawBytes(message, "content", []byte(`[{"type":"text"}]`))
+		message, _ = sjson.SetBytes(message, "content.0.text", prefill)
+	} else {
+		message, _ = sjson.SetBytes(message, "content", prefill)
+	}
+	out, errSet := sjson.SetRawBytes(payload, "messages.-1", message)
+	return out, errSet == nil
+}
+
+// process records a payload about to reach the client and, on a resumed attempt,
+// rewrites it so the client sees one continuous stream. It returns nil when the payload
+// carries nothing new for the client.
+func (r *streamResume) process(payload []byte) []byte {
+	if r == nil || (!r.resumable && !r.resumed) {
+		return payload
+	}
+	if r.protocol == Claude {
+		return r.processClaude(payload)
+	}
+	return r.processOpenAI(payload)
+}
+
+func (r *streamResume) processOpenAI(payload []byte) []byte {
+	if !gjson.ValidBytes(payload) {
+		return payload
+	}
+	if !r.resumed {
+		if id := gjson.GetBytes(payload, "id").String(); id != "" && r.streamID == "" {
+			r.streamID = id
+		}
+	} else if r.streamID != "" && gjson.GetBytes(payload, "id").Exists() {
+		payload, _ = sjson.SetBytes(payload, "id", r.streamID)
+	}
+
+	choices := gjson.GetBytes(payload, "choices").Array()
+	for i, choice := range choices {
+		delta := choice.Get("delta")
+		if choice.Get("index").Int() != 0 || delta.Get("tool_calls").Exists() || delta.Get("reasoning_content").String() != "" {
+			r.resumable = false
+			return payload
+		}
+		text := delta.Get("content").String()
+		if r.resumed {
+			text = r.skipReplayed(text)
+			if finish := choice.Get("finish_reason"); finish.Exists() && finish.Type != gjson.Null {
+				text += r.settlePending()
+			}
+			if delta.Get("content").Exists() || text != "" {
+				payload, _ = sjson.SetBytes(payload, "choices."+strconv.Itoa(i)+".delta.content", text)
+			}
+			if text == "" && len(choices) == 1 && isBareOpenAIDelta(payload) {
+				return nil
+			}
+		}
+		r.emitted.WriteString(text)
+	}
+	return payload
+}
+
+// isBareOpenAIDelta reports whether a single-choice chunk carries nothing but the role
+// and empty content, like the opening chunk every attempt starts with.
+func isBareOpenAIDelta(payload []byte) bool {
+	if gjson.GetBytes(payload, "usage").IsObject() {
+		return false
+	}
+	choice := gjson.GetBytes(payload, "choices.0")
+	if finish := choice.Get("finish_reason"); finish.Exists() && finish.Type != gjson.Null {
+		return false
+	}
+	bare := true
+	choice.Get("delta").ForEach(func(key, value gjson.Result) bool {
+		switch key.String() {
+		case "role":
+		case "content":
+			bare = value.String() == ""
+		default:
+			bare = value.Type == gjson.Null
+		}
+		return bare
+	})
+	return bare
+}
+
+func (r *streamResume) processClaude(payload []byte) []byte {
+	var out bytes.Buffer
+	for _, event := range bytes.Split(payload, []byte("\n\n")) {
+		if len(bytes.TrimSpace(event)) == 0 {
+			continue
+		}
+		data := sseEventData(event)
+		if data == nil || !gjson.ValidBytes(data) {
+			out.Write(event)
+			out.WriteString("\n\n")
+			continue
+		}
+		events := r.processClaudeEvent(data)
+		if !r.resumed {
+			continue
+		}
+		for _, item := range events {
+			out.WriteString("event: ")
+			out.WriteString(gjson.GetBytes(item, "type").String())
+			out.WriteString("\ndata: ")
+			out.Write(item)
+			out.WriteString("\n\n")
+		}
+	}
+	if !r.resumed {
+		// The first attempt is only observed; its bytes reach the client unchanged.
+		return payload
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return out.Bytes()
+}
+
+// processClaudeEvent returns the events the client should see for one upstream event.
+// Block indices of a resumed attempt are renumbered after the blocks the client already
+// has, and its first text block continues the text block the cut left open.
+func (r *streamResume) processClaudeEvent(data []byte) [][]byte {
+	index := gjson.GetBytes(data, "index").Int()
+	switch gjson.GetBytes(data, "type").String() {
+	case "message_start":
+		if r.resumed {
+			return nil
+		}
+	case "content_block_start":
+		isText := gjson.GetBytes(data, "content_block.type").String() == "text"
+		if !isText {
+			r.resumable = false
+		}
+		if !r.resumed {
+			if isText {
+				r.openText = int(index)
+			}
+			if int(index) >= r.nextIndex {
+				r.nextIndex = int(index) + 1
+			}
+			return [][]byte{data}
+		}
+		if isText && len(r.indexMap) == 0 && r.openText >= 0 {
+			r.indexMap[index] = r.openText
+			return nil
+		}
+		var events [][]byte
+		if r.openText >= 0 {
+			stop, _ := sjson.SetBytes([]byte(`{"type":"content_block_stop"}`), "index", r.openText)
+			events = append(events, stop)
+			r.openText = -1
+		}
+		r.indexMap[index] = r.nextIndex
+		if isText {
+			r.openText = r.nextIndex
+		}
+		r.nextIndex++
+		data, _ = sjson.SetBytes(data, "index", r.indexMap[index])
+		return append(events, data)
+	case "content_block_delta":
+		if r.resumed {
+			if mapped, ok := r.indexMap[index]; ok {
+				data, _ = sjson.SetBytes(data, "index", mapped)
+			}
+		}
+		if gjson.GetBytes(data, "delta.type").String() != "text_delta" {
+			r.resumable = false
+			return [][]byte{data}
+		}
+		text := gjson.GetBytes(data, "delta.text").String()
+		if r.resumed {
+			text = r.skipReplayed(text)
+			if text == "" {
+				return nil
+			}
+			data, _ = sjson.SetBytes(data, "delta.text", text)
+		}
+		r.emitted.WriteString(text)
+	case "content_block_stop":
+		var events [][]byte
+		if r.resumed {
+			if mapped, ok := r.indexMap[index]; ok {
+				data, _ = sjson.SetBytes(data, "index", mapped)
+				index = int64(mapped)
+			}
+			if held := r.settlePending(); held != "" {
+				delta, _ := sjson.SetBytes([]byte(`{"type":"content_block_delta","delta":{"type":"text_delta"}}`), "index", index)
+				delta, _ = sjson.SetBytes(delta, "delta.text", held)
+				events = append(events, delta)
+				r.emitted.WriteString(held)
+			}
+		}
+		if int(index) == r.openText {
+			r.openText = -1
+		}
+		return append(events, data)
+	}
+	return [][]byte{data}
+}
+
+// skipReplayed drops the part of text a resumed attempt repeats from the output the
+// client already has. The first text of the attempt decides how it is aligned: when it
+// repeats a substantial prefix of the emitted output (resumeAlignBytes, or all of it) the
+// upstream regenerated from the beginning; when it diverges before that it continued
+// after the prefill and only the whitespace trimmed off the prefill may be repeated.
+// Until one of the two is certain the text is held back and "" is returned.
+func (r *streamResume) skipReplayed(text string) string {
+	if text == "" {
+		return text
+	}
+	if !r.aligned {
+		r.pending += text
+		emitted := r.emitted.String()
+		n := commonPrefixLen(r.pending, emitted)
+		switch {
+		case n >= min(len(emitted), resumeAlignBytes):
+			r.alignReplay(emitted)
+		case n < len(r.pending):
+			r.alignReplay(emitted[len(r.prefill):])
+		default:
+			return ""
+		}
+		text, r.pending = r.pending, ""
+	}
+	if r.matched >= len(r.replay) {
+		return text
+	}
+	pending := r.replay[r.matched:]
+	n := commonPrefixLen(text, pending)
+	r.matched += n
+	if n < len(text) && n < len(pending) {
+		// The attempt diverged from the emitted text; everything from here on is new.
+		r.matched = len(r.replay)
+	}
+	return text[n:]
+}
+
+// settlePending ends an alignment still undecided when the attempt's text ends: the held
+// text is treated as a continuation. It returns the held text that is new to the client.
+func (r *streamResume) settlePending() string {
+	if r.aligned || r.pending == "" {
+		return ""
+	}
+	held := r.pending
+	r.pending = ""
+	r.alignReplay(r.emitted.String()[len(r.prefill):])
+	return r.skipReplayed(held)
+}
+
+func (r *streamResume) alignReplay(replay string) {
+	r.aligned = true
+	r.replay = replay
+	r.matched = 0
+}
+
+// commonPrefixLen returns the length in bytes of the common prefix of a and b, cut back
+// to a rune boundary of a.
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	for n > 0 && n < len(a) && !utf8.RuneStart(a[n]) {
+		n--
+	}
+	return n
+}
+
+// sseEventData returns the data payload of one SSE event, or nil when it has none.
+func sseEventData(event []byte) []byte {
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("data:")) {
+			return bytes.TrimSpace(line[5:])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	"github.com/tidwall/gjson"
+)
+
+// scriptedStreamExecutor replays one scripted attempt per ExecuteStream call. An attempt
+// whose last entry is nil ends with a connection reset instead of closing cleanly.
+type scriptedStreamExecutor struct {
+	mu       sync.Mutex
+	attempts [][][]byte
+	payloads [][]byte
+}
+
+func (e *scriptedStreamExecutor) Identifier() string { return "codex" }
+
+func (e *scriptedStreamExecutor) Execute(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "Execute not implemented"}
+}
+
+func (e *scriptedStreamExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, _ coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	e.mu.Lock()
+	call := len(e.payloads)
+	e.payloads = append(e.payloads, req.Payload)
+	e.mu.Unlock()
+
+	ch := make(chan coreexecutor.StreamChunk, 16)
+	if call < len(e.attempts) {
+		for _, payload := range e.attempts[call] {
+			if payload == nil {
+				ch <- coreexecutor.StreamChunk{Err: errors.New("read tcp: connection reset by peer")}
+				continue
+			}
+			ch <- coreexecutor.StreamChunk{Payload: payload}
+		}
+	}
+	close(ch)
+	return &coreexecutor.StreamResult{Chunks: ch}, nil
+}
+
+func (e *scriptedStreamExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (e *scriptedStreamExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (e *scriptedStreamExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented", HTTPStatus: http.StatusNotImplemented}
+}
+
+func (e *scriptedStreamExecutor) Payloads() [][]byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([][]byte(nil), e.payloads...)
+}
+
+func runScriptedStream(t *testing.T, handlerType string, resumeOnError bool, body string, attempts [][][]byte) (*scriptedStreamExecutor, [][]byte, error) {
+	t.Helper()
+	executor := &scriptedStreamExecutor{attempts: attempts}
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(executor)
+	for _, id := range []string{"resume-auth1", "resume-auth2"} {
+		auth := &coreauth.Auth{ID: id, Provider: "codex", Status: coreauth.StatusActive}
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("manager.Register(%s): %v", id, errRegister)
+		}
+		registry.GetGlobalRegistry().RegisterClient(id, "codex", []*registry.ModelInfo{{ID: "test-model"}})
+		t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(id) })
+	}
+
+	handler := NewBaseAPIHandlers(&sdkconfig.SDKConfig{
+		Streaming: sdkconfig.StreamingConfig{ResumeOnError: resumeOnError},
+	}, manager)
+	dataChan, _, errChan := handler.ExecuteStreamWithAuthManager(context.Background(), handlerType, "test-model", []byte(body), "")
+
+	var chunks [][]byte
+	for chunk := range dataChan {
+		chunks = append(chunks, chunk)
+	}
+	var streamErr error
+	for msg := range errChan {
+		if msg != nil && msg.Error != nil {
+			streamErr = msg.Error
+		}
+	}
+	return executor, chunks, streamErr
+}
+
+func openAIDelta(content string) []byte {
+	return []byte(`{"id":"chatcmpl-upstream","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"` + content + `"},"finish_reason":null}]}`)
+}
+
+func openAIStreamText(chunks [][]byte) string {
+	var text strings.Builder
+	for _, chunk := range chunks {
+		text.WriteString(gjson.GetBytes(chunk, "choices.0.delta.content").String())
+	}
+	return text.String()
+}
+
+func TestExecuteStreamWithAuthManager_ResumesOpenAIStreamWithoutDuplicatingText(t *testing.T) {
+	roleChunk := []byte(`{"id":"chatcmpl-upstream","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`)
+	finishChunk := []byte(`{"id":"chatcmpl-upstream","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)
+	attempts := [][][]byte{
+		{roleChunk, openAIDelta("The quick "), openAIDelta("brown fox "), nil},
+		// The upstream ignores the prefill and regenerates from the start.
+		{roleChunk, openAIDelta("The quick br"), openAIDelta("own fox jumps "), openAIDelta("over the dog."), finishChunk},
+	}
+	executor, chunks, streamErr := runScriptedStream(t, "openai", true, `{"model":"test-model","messages":[{"role":"user","content":"tell me"}]}`, attempts)
+	if streamErr != nil {
+		t.Fatalf("unexpected stream error: %v", streamErr)
+	}
+	if got, want := openAIStreamText(chunks), "The quick brown fox jumps over the dog."; got != want {
+		t.Fatalf("client text = %q, want %q", got, want)
+	}
+	roles := 0
+	for _, chunk := range chunks {
+		if gjson.GetBytes(chunk, "choices.0.delta.role").Exists() {
+			roles++
+		}
+		if id := gjson.GetBytes(chunk, "id").String(); id != "chatcmpl-upstream" {
+			t.Fatalf("chunk id = %q, want the id of the first attempt", id)
+		}
+	}
+	if roles != 1 {
+		t.Fatalf("role chunks = %d, want 1", roles)
+	}
+
+	payloads := executor.Payloads()
+	if len(payloads) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(payloads))
+	}
+	last := gjson.GetBytes(payloads[1], "messages.@reverse.0")
+	if last.Get("role").String() != "assistant" || last.Get("content").String() != "The quick brown fox" {
+		t.Fatalf("resumed request lacks the assistant prefill: %s", payloads[1])
+	}
+}
+
+func TestExecuteStreamWithAuthManager_ResumesClaudeStreamAsContinuation(t *testing.T) {
+	event := func(name, data string) []byte {
+		return []byte("event: " + name + "\ndata: " + data + "\n\n")
+	}
+	textDelta := func(index, text string) []byte {
+		return event("content_block_delta", `{"type":"content_block_delta","index":`+index+`,"delta":{"type":"text_delta","text":"`+text+`"}}`)
+	}
+	messageStart := event("message_start", `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[]}}`)
+	blockStart := event("content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`)
+	attempts := [][][]byte{
+		{messageStart, blockStart, textDelta("0", "Hello "), textDelta("0", "there, "), nil},
+		// The upstream honours the prefill "Hello there," and continues after it.
+		{messageStart, blockStart, textDelta("0", " general"), textDelta("0", " Kenobi."),
+			event("content_block_stop", `{"type":"content_block_stop","index":0}`),
+			event("message_delta", `{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`),
+			event("message_stop", `{"type":"message_stop"}`)},
+	}
+	executor, chunks, streamErr := runScriptedStream(t, "claude", true, `{"model":"test-model","max_tokens":64,"messages":[{"role":"user","content":"hi"}]}`, attempts)
+	if streamErr != nil {
+		t.Fatalf("unexpected stream error: %v", streamErr)
+	}
+
+	var text strings.Builder
+	counts := map[string]int{}
+	for _, chunk := range chunks {
+		for _, raw := range strings.Split(string(chunk), "\n\n") {
+			data := sseEventData([]byte(raw))
+			if data == nil {
+				continue
+			}
+			counts[gjson.GetBytes(data, "type").String()]++
+			if gjson.GetBytes(data, "delta.type").String() == "text_delta" {
+				if index := gjson.GetBytes(data, "index").Int(); index != 0 {
+					t.Fatalf("text delta index = %d, want 0", index)
+				}
+				text.WriteString(gjson.GetBytes(data, "delta.text").String())
+			}
+		}
+	}
+	if got, want := text.String(), "Hello there, general Kenobi."; got != want {
+		t.Fatalf("client text = %q, want %q", got, want)
+	}
+	if counts["message_start"] != 1 || counts["content_block_start"] != 1 || counts["message_stop"] != 1 {
+		t.Fatalf("unexpected event counts: %v", counts)
+	}
+
+	payloads := executor.Payloads()
+	if len(payloads) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(payloads))
+	}
+	if got := gjson.GetBytes(payloads[1], "messages.1.content.0.text").String(); got != "Hello there," {
+		t.Fatalf("prefill = %q, want trailing whitespace trimmed: %s", got, payloads[1])
+	}
+}
+
+func TestExecuteStreamWithAuthManager_ResumedContinuationSharingAPrefixIsKept(t *testing.T) {
+	finishChunk := []byte(`{"id":"chatcmpl-upstream","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`)
+	tests := []struct {
+		name     string
+		resumed  [][]byte
+		wantText string
+	}{
+		{
+			name:     "continuation starting like the emitted text",
+			resumed:  [][]byte{openAIDelta("Such"), openAIDelta(" a nice day."), finishChunk},
+			wantText: "Sure. Such a nice day.",
+		},
+		{
+			name:     "continuation ending while undecided",
+			resumed:  [][]byte{openAIDelta("Su"), finishChunk},
+			wantText: "Sure. Su",
+		},
+		{
+			name:     "regeneration",
+			resumed:  [][]byte{openAIDelta("Sure"), openAIDelta(". Such"), openAIDelta(" a nice day."), finishChunk},
+			wantText: "Sure. Such a nice day.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := [][][]byte{{openAIDelta("Sure. "), nil}, tt.resumed}
+			_, chunks, streamErr := runScriptedStream(t, "openai", true, `{"model":"test-model","messages":[{"role":"user","content":"hi"}]}`, attempts)
+			if streamErr != nil {
+				t.Fatalf("unexpected stream error: %v", streamErr)
+			}
+			if got := openAIStreamText(chunks); got != tt.wantText {
+				t.Fatalf("client text = %q, want %q", got, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestStreamResumeMergesPrefillIntoTrailingAssistantTurn(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		payload  string
+		path     string
+		want     string
+	}{
+		{
+			name:     "openai string content",
+			protocol: "openai",
+			payload:  `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"Answer: "}]}`,
+			path:     "messages.1.content",
+			want:     "Answer: forty-two",
+		},
+		{
+			name:     "claude text block",
+			protocol: "claude",
+			payload:  `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"text","text":"Answer: "}]}]}`,
+			path:     "messages.1.content.0.text",
+			want:     "Answer: forty-two",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resume := newStreamResume(tt.protocol, tt.protocol)
+			resume.emitted.WriteString("forty-two ")
+			out, ok := resume.resumeRequest([]byte(tt.payload))
+			if !ok {
+				t.Fatal("resumeRequest() refused the payload")
+			}
+			if n := len(gjson.GetBytes(out, "messages").Array()); n != 2 {
+				t.Fatalf("messages = %d, want the prefill merged into the trailing assistant turn: %s", n, out)
+			}
+			if got := gjson.GetBytes(out, tt.path).String(); got != tt.want {
+				t.Fatalf("%s = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteStreamWithAuthManager_ResumeOnErrorFallsBack(t *testing.T) {
+	toolCall := []byte(`{"id":"chatcmpl-upstream","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"f","arguments":""}}]},"finish_reason":null}]}`)
+	tests := []struct {
+		name     string
+		enabled  bool
+		attempts [][][]byte
+	}{
+		{name: "disabled", attempts: [][][]byte{{openAIDelta("partial"), nil}, {openAIDelta("partial answer")}}},
+		{name: "tool call output", enabled: true, attempts: [][][]byte{{openAIDelta("partial"), toolCall, nil}, {openAIDelta("partial answer")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor, chunks, streamErr := runScriptedStream(t, "openai", tt.enabled, `{"model":"test-model","messages":[{"role":"user","content":"hi"}]}`, tt.attempts)
+			if streamErr == nil {
+				t.Fatal("expected the mid-stream error to reach the client")
+			}
+			if got := openAIStreamText(chunks); got != "partial" {
+				t.Fatalf("client text = %q, want partial", got)
+			}
+			if got := len(executor.Payloads()); got != 1 {
+				t.Fatalf("attempts = %d, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/interfaces"
+)
+
+// WebsocketDoneFrame is the text frame that ends a successful websocket stream.
+const WebsocketDoneFrame = "[DONE]"
+
+// WebsocketStreamStarter starts the upstream stream for the request JSON a
+// websocket client sent as its first message. Implementations normally wrap
+// ExecuteStreamWithAuthManager so websocket and SSE clients share translation,
+// retries and usage reporting.
+type WebsocketStreamStarter func(ctx context.Context, rawJSON []byte) (<-chan []byte, http.Header, <-chan *interfaces.ErrorMessage)
+
+var streamWebsocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// ServeStreamWebsocket upgrades the request to a websocket, reads the first
+// message as the request body and streams every chunk the SSE path would emit
+// as its own text frame. The stream ends with a WebsocketDoneFrame, or with a
+// frame holding the standard error envelope when the upstream fails. Closing
+// the websocket from the client cancels the upstream request.
+func (h *BaseAPIHandler) ServeStreamWebsocket(c *gin.Context, handler interfaces.APIHandler, start WebsocketStreamStarter) {
+	conn, errUpgrade := streamWebsocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if errUpgrade != nil {
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	writer := &streamWebsocketWriter{conn: conn}
+
+	messageType, rawJSON, errRead := conn.ReadMessage()
+	if errRead != nil {
+		return
+	}
+	if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage || !json.Valid(rawJSON) {
+		writer.writeError(&interfaces.ErrorMessage{
+			StatusCode: http.StatusBadRequest,
+			Error:      errors.New("invalid request: first websocket message must be a JSON request body"),
+		})
+		writer.close(websocket.CloseInvalidFramePayloadData)
+		return
+	}
+
+	cliCtx, cliCancel := h.GetContextWithCancel(handler, c, context.Background())
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		for {
+			if _, _, errNext := conn.ReadMessage(); errNext != nil {
+				return
+			}
+		}
+	}()
+
+	abort, release := h.streams.register()
+	defer release()
+
+	data, _, errs := start(cliCtx, rawJSON)
+
+	var keepAliveC <-chan time.Time
+	if interval := StreamingKeepAliveInterval(h.Cfg); interval > 0 {
+		keepAlive := time.NewTicker(interval)
+		defer keepAlive.Stop()
+		keepAliveC = keepAlive.C
+	}
+
+	for {
+		select {
+		case <-clientGone:
+			cliCancel(context.Canceled)
+			return
+		case <-abort:
+			errMsg := &interfaces.ErrorMessage{StatusCode: http.StatusServiceUnavailable, Error: errServerShuttingDown}
+			writer.writeError(errMsg)
+			writer.close(websocket.CloseGoingAway)
+			cliCancel(errMsg.Error)
+			return
+		case chunk, ok := <-data:
+			if !ok {
+				// Prefer surfacing a terminal error if one is pending.
+				var terminalErr *interfaces.ErrorMessage
+				select {
+				case errMsg, okErr := <-errs:
+					if okErr {
+						terminalErr = errMsg
+					}
+				default:
+				}
+				if terminalErr != nil {
+					writer.writeError(terminalErr)
+					writer.close(websocket.CloseNormalClosure)
+					cliCancel(terminalErr.Error)
+					return
+				}
+				_ = writer.write(websocket.TextMessage, []byte(WebsocketDoneFrame))
+				writer.close(websocket.CloseNormalClosure)
+				cliCancel(nil)
+				return
+			}
+			if errWrite := writer.write(websocket.TextMessage, chunk); errWrite != nil {
+				cliCancel(context.Canceled)
+				return
+			}
+		case errMsg, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if errMsg == nil {
+				continue
+			}
+			writer.writeError(errMsg)
+			writer.close(websocket.CloseNormalClosure)
+			cliCancel(errMsg.Error)
+			return
+		case <-keepAliveC:
+			_ = writer.ping()
+		}
+	}
+}
+
+// streamWebsocketWriter writes frames for ServeStreamWebsocket. Only the stream
+// loop writes, which satisfies gorilla's single concurrent writer rule.
+type streamWebsocketWriter struct {
+	conn *websocket.Conn
+}
+
+func (w *streamWebsocketWriter) write(messageType int, payload []byte) error {
+	return w.conn.WriteMessage(messageType, payload)
+}
+
+func (w *streamWebsocketWriter) writeError(errMsg *interfaces.ErrorMessage) {
+	status := http.StatusInternalServerError
+	if errMsg != nil && errMsg.StatusCode > 0 {
+		status = errMsg.StatusCode
+	}
+	errText := http.StatusText(status)
+	if errMsg != nil && errMsg.Error != nil && errMsg.Error.Error() != "" {
+		errText = errMsg.Error.Error()
+	}
+	_ = w.write(websocket.TextMessage, BuildErrorResponseBody(status, errText))
+}
+
+func (w *streamWebsocketWriter) ping() error {
+	return w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+}
+
+func (w *streamWebsocketWriter) close(code int) {
+	_ = w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/thinking/provider/gemini"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/thinking/provider/openai"
+	_ "github.com/router-for-me/CLIProxyAPI/v7/internal/translator"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	coreexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+	"github.com/tidwall/gjson"
+)
+
+const thinkingHeaderTestProvider = "thinking-header-test"
+
+// thinkingHeaderTestExecutor translates the request into its target format and applies
+// the thinking config the way the provider executors do, answering with the payload it
+// would have sent upstream.
+type thinkingHeaderTestExecutor struct {
+	to string
+}
+
+func (thinkingHeaderTestExecutor) Identifier() string { return thinkingHeaderTestProvider }
+
+func (e thinkingHeaderTestExecutor) translate(req coreexecutor.Request, opts coreexecutor.Options, stream bool) ([]byte, error) {
+	baseModel := thinking.ParseSuffix(req.Model).ModelName
+	body := sdktranslator.TranslateRequest(opts.SourceFormat, sdktranslator.FromString(e.to), baseModel, req.Payload, stream)
+	return thinking.ApplyThinking(body, req.Model, opts.SourceFormat.String(), e.to, thinkingHeaderTestProvider)
+}
+
+func (e thinkingHeaderTestExecutor) Execute(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (coreexecutor.Response, error) {
+	body, errApply := e.translate(req, opts, false)
+	if errApply != nil {
+		return coreexecutor.Response{}, errApply
+	}
+	return coreexecutor.Response{Payload: body}, nil
+}
+
+func (e thinkingHeaderTestExecutor) ExecuteStream(_ context.Context, _ *coreauth.Auth, req coreexecutor.Request, opts coreexecutor.Options) (*coreexecutor.StreamResult, error) {
+	ch := make(chan coreexecutor.StreamChunk, 1)
+	body, errApply := e.translate(req, opts, true)
+	ch <- coreexecutor.StreamChunk{Payload: body, Err: errApply}
+	close(ch)
+	return &coreexecutor.StreamResult{Chunks: ch}, nil
+}
+
+func (thinkingHeaderTestExecutor) Refresh(_ context.Context, auth *coreauth.Auth) (*coreauth.Auth, error) {
+	return auth, nil
+}
+
+func (thinkingHeaderTestExecutor) CountTokens(context.Context, *coreauth.Auth, coreexecutor.Request, coreexecutor.Options) (coreexecutor.Response, error) {
+	return coreexecutor.Response{}, &coreauth.Error{Code: "not_implemented", Message: "CountTokens not implemented"}
+}
+
+func (thinkingHeaderTestExecutor) HttpRequest(context.Context, *coreauth.Auth, *http.Request) (*http.Response, error) {
+	return nil, &coreauth.Error{Code: "not_implemented", Message: "HttpRequest not implemented"}
+}
+
+func newThinkingHeaderTestRouter(t *testing.T, to string) *gin.Engine {
+	t.Helper()
+	manager := coreauth.NewManager(nil, nil, nil)
+	manager.RegisterExecutor(thinkingHeaderTestExecutor{to: to})
+	auth := &coreauth.Auth{ID: t.Name() + "-auth", Provider: thinkingHeaderTestProvider, Status: coreauth.StatusActive}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("manager.Register(): %v", errRegister)
+	}
+	registry.GetGlobalRegistry().RegisterClient(auth.ID, thinkingHeaderTestProvider, []*registry.ModelInfo{{
+		ID:       "thinking-header-model",
+		Thinking: &registry.ThinkingSupport{Min: 128, Max: 32768, Levels: []string{"low", "medium", "high"}, ZeroAllowed: true, DynamicAllowed: true},
+	}})
+	t.Cleanup(func() { registry.GetGlobalRegistry().UnregisterClient(auth.ID) })
+	h := NewBaseAPIHandlers(&sdkconfig.SDKConfig{}, manager)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/nonstream", func(c *gin.Context) {
+		ctx, cancel := h.GetContextWithCancel(nil, c, context.Background())
+		defer cancel()
+		rawJSON := []byte(c.GetHeader("X-Test-Body"))
+		resp, _, errMsg := h.ExecuteWithAuthManager(ctx, "openai", c.GetHeader("X-Test-Model"), rawJSON, "")
+		if errMsg != nil {
+			t.Errorf("execute error: %v", errMsg.Error)
+			c.Status(errMsg.StatusCode)
+			return
+		}
+		_, _ = c.Writer.Write(resp)
+	})
+	router.POST("/stream", func(c *gin.Context) {
+		ctx, cancel := h.GetContextWithCancel(nil, c, context.Background())
+		defer cancel()
+		rawJSON := []byte(c.GetHeader("X-Test-Body"))
+		data, _, errs := h.ExecuteStreamWithAuthManager(ctx, "openai", c.GetHeader("X-Test-Model"), rawJSON, "")
+		for chunk := range data {
+			_, _ = c.Writer.Write(chunk)
+		}
+		for errMsg := range errs {
+			if errMsg != nil {
+				t.Errorf("stream error: %v", errMsg.Error)
+			}
+		}
+	})
+	return router
+}
+
+func TestThinkingHeaderReachesTranslatedPayload(t *testing.T) {
+	const messages = `"messages":[{"role":"user","content":"hi"}]`
+	cases := []struct {
+		name   string
+		to     string
+		model  string
+		header string
+		body   string
+		field  string
+		want   string
+	}{
+		{"budget", "gemini", "thinking-header-model", "8192", `{` + messages + `}`, "generationConfig.thinkingConfig.thinkingBudget", "8192"},
+		{"none", "openai", "thinking-header-model", "none", `{"reasoning_effort":"high",` + messages + `}`, "reasoning_effort", "none"},
+		{"level", "openai", "thinking-header-model", "high", `{` + messages + `}`, "reasoning_effort", "high"},
+		{"header over body", "openai", "thinking-header-model", "high", `{"reasoning_effort":"low",` + messages + `}`, "reasoning_effort", "high"},
+		{"suffix over header", "gemini", "thinking-header-model(1024)", "8192", `{` + messages + `}`, "generationConfig.thinkingConfig.thinkingBudget", "1024"},
+		{"invalid header ignored", "openai", "thinking-header-model", "ultra", `{"reasoning_effort":"low",` + messages + `}`, "reasoning_effort", "low"},
+	}
+	for _, tc := range cases {
+		for _, path := range []string{"/nonstream", "/stream"} {
+			t.Run(tc.name+path, func(t *testing.T) {
+				router := newThinkingHeaderTestRouter(t, tc.to)
+				httpReq := httptest.NewRequest(http.MethodPost, path, nil)
+				httpReq.Header.Set(ThinkingHeader, tc.header)
+				httpReq.Header.Set("X-Test-Model", tc.model)
+				httpReq.Header.Set("X-Test-Body", tc.body)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httpReq)
+
+				if got := gjson.Get(recorder.Body.String(), tc.field).String(); got != tc.want {
+					t.Fatalf("%s = %q, want %q; payload %s", tc.field, got, tc.want, recorder.Body.String())
+				}
+			})
+		}
+	}
+}
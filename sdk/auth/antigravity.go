@@ -18,7 +18,10 @@ import (
 )
 
 // AntigravityAuthenticator implements OAuth login for the antigravity provider.
-type AntigravityAuthenticator struct{}
+type AntigravityAuthenticator struct {
+	// httpClient overrides the client used for the token exchange and account lookups.
+	httpClient *http.Client
+}
 
 // NewAntigravityAuthenticator constructs a new authenticator instance.
 func NewAntigravityAuthenticator() Authenticator { return &AntigravityAuthenticator{} }
@@ -32,7 +35,7 @@ func (AntigravityAuthenticator) RefreshLead() *time.Duration {
 }
 
 // Login launches a local OAuth flow to obtain antigravity tokens and persists them.
-func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, opts *LoginOptions) (*coreauth.Auth, error) {
+func (a AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, opts *LoginOptions) (*coreauth.Auth, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("cliproxy auth: configuration is required")
 	}
@@ -48,22 +51,32 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 		callbackPort = opts.CallbackPort
 	}
 
-	authSvc := antigravity.NewAntigravityAuth(cfg, nil)
+	authSvc := antigravity.NewAntigravityAuth(cfg, a.httpClient)
 
 	state, err := misc.GenerateRandomState()
 	if err != nil {
 		return nil, fmt.Errorf("antigravity: failed to generate state: %w", err)
 	}
 
-	srv, port, cbChan, errServer := startAntigravityCallbackServer(callbackPort)
-	if errServer != nil {
+	// Headless logins (no browser, with a prompt) do not need the callback server: the
+	// redirect URL or code shown by the browser elsewhere is pasted instead.
+	headless := opts.NoBrowser && opts.Prompt != nil
+	port := callbackPort
+	var cbChan <-chan callbackResult
+	srv, boundPort, resultCh, errServer := startAntigravityCallbackServer(callbackPort)
+	switch {
+	case errServer == nil:
+		port, cbChan = boundPort, resultCh
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+	case headless:
+		log.Warnf("antigravity: callback server unavailable, waiting for the pasted code: %v", errServer)
+	default:
 		return nil, fmt.Errorf("antigravity: failed to start callback server: %w", errServer)
 	}
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-		_ = srv.Shutdown(shutdownCtx)
-	}()
 
 	redirectURI := fmt.Sprintf("http://localhost:%d/oauth-callback", port)
 	authURL := authSvc.BuildAuthURL(state, redirectURI)
@@ -79,6 +92,9 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 			util.PrintSSHTunnelInstructions(port)
 			fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
 		}
+	} else if headless {
+		fmt.Printf("Visit the following URL on any device to continue authentication:\n%s\n", authURL)
+		fmt.Println("After approving, copy the URL of the page the browser is redirected to (it may fail to load).")
 	} else {
 		util.PrintSSHTunnelInstructions(port)
 		fmt.Printf("Visit the following URL to continue authentication:\n%s\n", authURL)
@@ -93,13 +109,19 @@ func (AntigravityAuthenticator) Login(ctx context.Context, cfg *config.Config, o
 	var manualPromptTimer *time.Timer
 	var manualPromptC <-chan time.Time
 	if opts.Prompt != nil {
-		manualPromptTimer = time.NewTimer(15 * time.Second)
+		manualPromptDelay := 15 * time.Second
+		if headless {
+			manualPromptDelay = 0
+		}
+		manualPromptTimer = time.NewTimer(manualPromptDelay)
 		manualPromptC = manualPromptTimer.C
 		defer manualPromptTimer.Stop()
 	}
 
 	var manualInputCh <-chan string
 	var manualInputErrCh <-chan error
+	// manualCode is set when a bare code was pasted, which carries no state to verify.
+	manualCode := false
 
 waitForCallback:
 	for {
@@ -118,23 +140,23 @@ waitForCallback:
 				break waitForCallback
 			default:
 			}
-			manualInputCh, manualInputErrCh = misc.AsyncPrompt(opts.Prompt, "Paste the antigravity callback URL (or press Enter to keep waiting): ")
+			manualInputCh, manualInputErrCh = misc.AsyncPrompt(opts.Prompt, "Paste the antigravity callback URL or code (or press Enter to keep waiting): ")
 			continue
 		case input := <-manualInputCh:
 			manualInputCh = nil
 			manualInputErrCh = nil
-			parsed, errParse := misc.ParseOAuthCallback(input)
+			res, okInput, errParse := parseAntigravityManualInput(input)
 			if errParse != nil {
 				return nil, errParse
 			}
-			if parsed == nil {
+			if !okInput {
+				if headless {
+					manualInputCh, manualInputErrCh = misc.AsyncPrompt(opts.Prompt, "Paste the antigravity callback URL or code: ")
+				}
 				continue
 			}
-			cbRes = callbackResult{
-				Code:  parsed.Code,
-				State: parsed.State,
-				Error: parsed.Error,
-			}
+			cbRes = res
+			manualCode = res.State == ""
 			break waitForCallback
 		case errManual := <-manualInputErrCh:
 			return nil, errManual
@@ -146,7 +168,7 @@ waitForCallback:
 	if cbRes.Error != "" {
 		return nil, fmt.Errorf("antigravity: authentication failed: %s", cbRes.Error)
 	}
-	if cbRes.State != state {
+	if cbRes.State != state && !manualCode {
 		return nil, fmt.Errorf("antigravity: invalid state")
 	}
 	if cbRes.Code == "" {
@@ -187,21 +209,7 @@ waitForCallback:
 		return nil, fmt.Errorf("antigravity: project ID discovery returned empty project")
 	}
 
-	now := time.Now()
-	metadata := map[string]any{
-		"type":          "antigravity",
-		"access_token":  tokenResp.AccessToken,
-		"refresh_token": tokenResp.RefreshToken,
-		"expires_in":    tokenResp.ExpiresIn,
-		"timestamp":     now.UnixMilli(),
-		"expired":       now.Add(time.Duration(tokenResp.ExpiresIn) * time.Second).Format(time.RFC3339),
-	}
-	if email != "" {
-		metadata["email"] = email
-	}
-	if projectID != "" {
-		metadata["project_id"] = projectID
-	}
+	metadata := antigravity.CredentialMetadata(tokenResp, email, projectID, time.Now())
 
 	fileName := antigravity.CredentialFileName(email)
 	label := email
@@ -222,6 +230,27 @@ waitForCallback:
 	}, nil
 }
 
+// parseAntigravityManualInput parses a pasted callback URL, query string or bare
+// authorization code. ok is false for empty input.
+func parseAntigravityManualInput(input string) (res callbackResult, ok bool, err error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return callbackResult{}, false, nil
+	}
+	if !strings.Contains(input, "code=") && !strings.Contains(input, "error=") {
+		// Google authorization codes look like "4/0Ab...", which is not a URL.
+		return callbackResult{Code: input}, true, nil
+	}
+	parsed, errParse := misc.ParseOAuthCallback(input)
+	if errParse != nil {
+		return callbackResult{}, false, errParse
+	}
+	if parsed == nil {
+		return callbackResult{}, false, nil
+	}
+	return callbackResult{Code: parsed.Code, State: parsed.State, Error: parsed.Error}, true, nil
+}
+
 type callbackResult struct {
 	Code  string
 	Error string
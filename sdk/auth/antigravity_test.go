@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/auth/antigravity"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+type antigravityRoundTripper func(*http.Request) (*http.Response, error)
+
+func (f antigravityRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func antigravityJSONResponse(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestAntigravityHeadlessLoginWritesCredentialFile(t *testing.T) {
+	// Occupy the callback port: headless logins continue without the callback server.
+	listener, errListen := net.Listen("tcp", ":0")
+	if errListen != nil {
+		t.Fatalf("listen: %v", errListen)
+	}
+	defer func() { _ = listener.Close() }()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	const code = "4/0Ab-test-code"
+	var exchanged bool
+	authenticator := &AntigravityAuthenticator{httpClient: &http.Client{Transport: antigravityRoundTripper(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.String() {
+		case antigravity.TokenEndpoint:
+			body, _ := io.ReadAll(req.Body)
+			form, _ := url.ParseQuery(string(body))
+			if form.Get("code") != code || form.Get("grant_type") != "authorization_code" {
+				t.Errorf("token request form = %v", form)
+			}
+			if want := fmt.Sprintf("http://localhost:%d/oauth-callback", port); form.Get("redirect_uri") != want {
+				t.Errorf("redirect_uri = %q, want %q", form.Get("redirect_uri"), want)
+			}
+			if form.Get("client_id") != antigravity.ClientID || form.Get("client_secret") != antigravity.ClientSecret {
+				t.Errorf("token request used unexpected client credentials")
+			}
+			exchanged = true
+			return antigravityJSONResponse(`{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3599,"token_type":"Bearer"}`), nil
+		case antigravity.UserInfoEndpoint:
+			return antigravityJSONResponse(`{"email":"user@example.com"}`), nil
+		case antigravity.APIEndpoint + "/" + antigravity.APIVersion + ":loadCodeAssist":
+			return antigravityJSONResponse(`{"cloudaicompanionProject":"project-1"}`), nil
+		default:
+			t.Errorf("unexpected request to %s", req.URL)
+			return nil, fmt.Errorf("unexpected request")
+		}
+	})}}
+
+	prompts := 0
+	record, errLogin := authenticator.Login(context.Background(), &config.Config{}, &LoginOptions{
+		NoBrowser:    true,
+		CallbackPort: port,
+		Prompt: func(string) (string, error) {
+			prompts++
+			return code, nil
+		},
+	})
+	if errLogin != nil {
+		t.Fatalf("Login() error = %v", errLogin)
+	}
+	if !exchanged || prompts != 1 {
+		t.Fatalf("exchanged = %t, prompts = %d; want the pasted code exchanged once", exchanged, prompts)
+	}
+
+	store := NewFileTokenStore()
+	dir := t.TempDir()
+	store.SetBaseDir(dir)
+	path, errSave := store.Save(context.Background(), record)
+	if errSave != nil {
+		t.Fatalf("Save() error = %v", errSave)
+	}
+	if path != filepath.Join(dir, "antigravity-user@example.com.json") {
+		t.Fatalf("saved path = %s", path)
+	}
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		t.Fatalf("read credential file: %v", errRead)
+	}
+	file := gjson.ParseBytes(data)
+	for field, want := range map[string]string{
+		"type":          "antigravity",
+		"access_token":  "access-1",
+		"refresh_token": "refresh-1",
+		"email":         "user@example.com",
+		"project_id":    "project-1",
+	} {
+		if got := file.Get(field).String(); got != want {
+			t.Errorf("%s = %q, want %q", field, got, want)
+		}
+	}
+	expired, errParse := time.Parse(time.RFC3339, file.Get("expired").String())
+	if errParse != nil {
+		t.Fatalf("expired = %q: %v", file.Get("expired").String(), errParse)
+	}
+	if until := time.Until(expired); until < 58*time.Minute || until > time.Hour {
+		t.Fatalf("expired is %s away, want about an hour", until)
+	}
+}
+
+func TestParseAntigravityManualInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  callbackResult
+		ok    bool
+	}{
+		{input: "  "},
+		{input: "4/0Ab-code", want: callbackResult{Code: "4/0Ab-code"}, ok: true},
+		{input: "http://localhost:51121/oauth-callback?state=s1&code=4/0Ab-code", want: callbackResult{Code: "4/0Ab-code", State: "s1"}, ok: true},
+		{input: "http://localhost:51121/oauth-callback?state=s1&error=access_denied", want: callbackResult{State: "s1", Error: "access_denied"}, ok: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseAntigravityManualInput(tt.input)
+		if err != nil || ok != tt.ok || got != tt.want {
+			t.Errorf("parseAntigravityManualInput(%q) = %+v, %t, %v; want %+v, %t", tt.input, got, ok, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/pluginapi"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/secrets"
+	"github.com/tidwall/gjson"
 )
 
 func TestExtractAccessToken(t *testing.T) {
@@ -228,3 +230,66 @@ func (f fileStoreMultiAuthParserFunc) ParseAuth(context.Context, pluginapi.AuthP
 func (f fileStoreMultiAuthParserFunc) ParseAuths(ctx context.Context, req pluginapi.AuthParseRequest) ([]*cliproxyauth.Auth, bool, error) {
 	return f(ctx, req)
 }
+
+func TestFileTokenStoreSecretReferencesRoundTripThroughRefresh(t *testing.T) {
+	t.Setenv(secrets.EncryptionKeyEnv, "filestore-test-key")
+	t.Setenv("FILESTORE_TEST_CLAUDE_EMAIL", "user@example.com")
+	dir := t.TempDir()
+	vaultRoot := t.TempDir()
+	previous := secrets.CurrentPolicy()
+	secrets.SetPolicy(secrets.Policy{Enable: true, AuthFiles: true, Env: []string{"FILESTORE_TEST_CLAUDE_EMAIL"}, FileRoots: []string{vaultRoot}})
+	t.Cleanup(func() { secrets.SetPolicy(previous) })
+	vault := filepath.Join(vaultRoot, "tokens.enc")
+	refreshRef := "encfile://" + vault + "#claude_refresh"
+	ref, _ := secrets.ParseReference(refreshRef)
+	if errWrite := secrets.Lookup("encfile").(secrets.SecretWriter).Write(context.Background(), ref, "rt-old"); errWrite != nil {
+		t.Fatalf("seed encrypted file: %v", errWrite)
+	}
+	authPath := filepath.Join(dir, "claude-user.json")
+	raw := `{"type":"claude","email":"env://FILESTORE_TEST_CLAUDE_EMAIL","access_token":"at-plain","refresh_token":"` + refreshRef + `"}`
+	if errWrite := os.WriteFile(authPath, []byte(raw), 0o600); errWrite != nil {
+		t.Fatalf("write auth file: %v", errWrite)
+	}
+
+	store := NewFileTokenStore()
+	store.SetBaseDir(dir)
+	auths, errList := store.List(context.Background())
+	if errList != nil || len(auths) != 1 {
+		t.Fatalf("List() = %d auths, %v", len(auths), errList)
+	}
+	auth := auths[0]
+	if auth.Metadata["refresh_token"] != "rt-old" || auth.Metadata["email"] != "user@example.com" {
+		t.Fatalf("metadata not resolved: %v", auth.Metadata)
+	}
+
+	manager := cliproxyauth.NewManager(store, nil, nil)
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("Register() error = %v", errRegister)
+	}
+
+	// Simulate a refresh that rotates both tokens.
+	refreshed := auth.Clone()
+	refreshed.Metadata["refresh_token"] = "rt-new"
+	refreshed.Metadata["access_token"] = "at-new"
+	if _, errUpdate := manager.Update(context.Background(), refreshed); errUpdate != nil {
+		t.Fatalf("Update() error = %v", errUpdate)
+	}
+
+	data, errRead := os.ReadFile(authPath)
+	if errRead != nil {
+		t.Fatalf("read auth file: %v", errRead)
+	}
+	saved := gjson.ParseBytes(data)
+	if got := saved.Get("refresh_token").String(); got != refreshRef {
+		t.Fatalf("persisted refresh_token = %q, want the reference", got)
+	}
+	if got := saved.Get("email").String(); got != "env://FILESTORE_TEST_CLAUDE_EMAIL" {
+		t.Fatalf("persisted email = %q, want the reference", got)
+	}
+	if got := saved.Get("access_token").String(); got != "at-new" {
+		t.Fatalf("persisted access_token = %q, want at-new", got)
+	}
+	if got, _ := secrets.Resolve(context.Background(), refreshRef); got != "rt-new" {
+		t.Fatalf("encrypted refresh_token = %q, want rt-new", got)
+	}
+}
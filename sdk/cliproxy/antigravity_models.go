@@ -3,9 +3,14 @@ package cliproxy
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/misc"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
@@ -17,8 +22,23 @@ const (
 	antigravityModelBaseURLDaily = "https://daily-cloudcode-pa.googleapis.com"
 	antigravityModelBaseURLProd  = "https://cloudcode-pa.googleapis.com"
 	antigravityModelsPath        = "/v1internal:fetchAvailableModels"
+
+	// antigravityModelHintsTTL bounds how long the last successful
+	// fetchAvailableModels result is served while the upstream keeps failing.
+	antigravityModelHintsTTL = time.Hour
+)
+
+// Backoff bounds for background re-fetches after fetchAvailableModels fails.
+// They are variables so tests can shorten them.
+var (
+	antigravityModelRefetchBaseDelay = 30 * time.Second
+	antigravityModelRefetchMaxDelay  = 15 * time.Minute
 )
 
+// errAntigravityModelFetchNoToken marks auths that cannot query the upstream
+// yet; they are re-registered after their next token refresh instead of retried.
+var errAntigravityModelFetchNoToken = errors.New("antigravity model fetch: missing access token")
+
 type antigravityFetchAvailableModelsResponse struct {
 	WebSearchModelIDs []string `json:"webSearchModelIds"`
 }
@@ -27,14 +47,40 @@ type antigravityModelCapabilityHints struct {
 	WebSearchModelIDs map[string]struct{}
 }
 
-func (s *Service) fetchAntigravityModelCapabilityHintsForAuth(ctx context.Context, auth *coreauth.Auth) antigravityModelCapabilityHints {
-	if auth == nil || auth.Metadata == nil {
+// antigravityModelCapabilityHintsForAuth fetches capability hints for auth and
+// falls back to the last successful result when the upstream is unavailable,
+// so a transient fetchAvailableModels failure does not strip capabilities such
+// as web search from already registered models. Failures also schedule a
+// background re-fetch that re-registers the auth's models once it succeeds.
+func (s *Service) antigravityModelCapabilityHintsForAuth(ctx context.Context, auth *coreauth.Auth) antigravityModelCapabilityHints {
+	if auth == nil || auth.ID == "" {
 		return antigravityModelCapabilityHints{}
 	}
-	accessToken, _ := auth.Metadata["access_token"].(string)
-	accessToken = strings.TrimSpace(accessToken)
+	hints, errFetch := s.fetchAntigravityModelCapabilityHintsForAuth(ctx, auth)
+	if errFetch == nil {
+		s.antigravityModels.store(auth.ID, hints, time.Now())
+		return hints
+	}
+	cached, ok := s.antigravityModels.load(auth.ID, time.Now())
+	if ctx.Err() != nil || errors.Is(errFetch, errAntigravityModelFetchNoToken) {
+		return cached
+	}
+	if ok {
+		log.Warnf("antigravity model fetch failed for auth %s, serving cached model capabilities: %v", auth.ID, errFetch)
+	} else {
+		log.Warnf("antigravity model fetch failed for auth %s: %v", auth.ID, errFetch)
+	}
+	s.scheduleAntigravityModelRefetch(auth)
+	return cached
+}
+
+// fetchAntigravityModelCapabilityHintsForAuth queries fetchAvailableModels on
+// each candidate base URL. It returns an error only when no base URL answered
+// successfully; a successful response without hints is not a failure.
+func (s *Service) fetchAntigravityModelCapabilityHintsForAuth(ctx context.Context, auth *coreauth.Auth) (antigravityModelCapabilityHints, error) {
+	accessToken := antigravityModelFetchToken(auth)
 	if accessToken == "" {
-		return antigravityModelCapabilityHints{}
+		return antigravityModelCapabilityHints{}, errAntigravityModelFetchNoToken
 	}
 
 	client := &http.Client{}
@@ -42,9 +88,12 @@ func (s *Service) fetchAntigravityModelCapabilityHintsForAuth(ctx context.Contex
 		client.Transport = transport
 	}
 
+	var lastErr error
+	answered := false
 	for _, baseURL := range antigravityModelBaseURLs(auth) {
 		req, errReq := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+antigravityModelsPath, strings.NewReader(`{}`))
 		if errReq != nil {
+			lastErr = errReq
 			continue
 		}
 		req.Close = true
@@ -54,6 +103,7 @@ func (s *Service) fetchAntigravityModelCapabilityHintsForAuth(ctx context.Contex
 
 		resp, errDo := client.Do(req)
 		if errDo != nil {
+			lastErr = errDo
 			continue
 		}
 		body, errRead := io.ReadAll(resp.Body)
@@ -61,17 +111,185 @@ func (s *Service) fetchAntigravityModelCapabilityHintsForAuth(ctx context.Contex
 			log.Debugf("antigravity model fetch: close response body: %v", errClose)
 		}
 		if errRead != nil {
+			lastErr = errRead
 			continue
 		}
 		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			lastErr = fmt.Errorf("%s returned status %d", baseURL, resp.StatusCode)
+			continue
+		}
+		hints, errParse := parseAntigravityModelCapabilityHints(body)
+		if errParse != nil {
+			lastErr = fmt.Errorf("%s returned invalid body: %w", baseURL, errParse)
 			continue
 		}
-		hints := parseAntigravityModelCapabilityHints(body)
+		answered = true
 		if len(hints.WebSearchModelIDs) > 0 {
-			return hints
+			return hints, nil
+		}
+	}
+	if answered {
+		return antigravityModelCapabilityHints{}, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no base URL available")
+	}
+	return antigravityModelCapabilityHints{}, fmt.Errorf("fetch available models: %w", lastErr)
+}
+
+// antigravityModelHintsCache keeps the last successful capability hints per
+// auth ID and the state of pending background re-fetches. The zero value is
+// ready to use.
+type antigravityModelHintsCache struct {
+	mu      sync.Mutex
+	entries map[string]antigravityModelHintsEntry
+	refetch map[string]*antigravityModelRefetch
+	stopped bool
+}
+
+type antigravityModelHintsEntry struct {
+	hints     antigravityModelCapabilityHints
+	fetchedAt time.Time
+}
+
+type antigravityModelRefetch struct {
+	attempts int
+	timer    *time.Timer
+}
+
+// store records a successful fetch and clears any failure backoff for authID.
+// Entries older than antigravityModelHintsTTL are pruned on the way.
+func (c *antigravityModelHintsCache) store(authID string, hints antigravityModelCapabilityHints, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]antigravityModelHintsEntry)
+	}
+	for id, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) > antigravityModelHintsTTL {
+			delete(c.entries, id)
 		}
 	}
-	return antigravityModelCapabilityHints{}
+	c.entries[authID] = antigravityModelHintsEntry{hints: hints, fetchedAt: now}
+	if pending := c.refetch[authID]; pending != nil {
+		if pending.timer != nil {
+			pending.timer.Stop()
+		}
+		delete(c.refetch, authID)
+	}
+}
+
+// load returns the cached hints for authID while they are younger than
+// antigravityModelHintsTTL.
+func (c *antigravityModelHintsCache) load(authID string, now time.Time) (antigravityModelCapabilityHints, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[authID]
+	if !ok {
+		return antigravityModelCapabilityHints{}, false
+	}
+	if now.Sub(entry.fetchedAt) > antigravityModelHintsTTL {
+		delete(c.entries, authID)
+		return antigravityModelCapabilityHints{}, false
+	}
+	return entry.hints, true
+}
+
+// forget drops the cached hints and any pending re-fetch of a removed auth.
+func (c *antigravityModelHintsCache) forget(authID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, authID)
+	if pending := c.refetch[authID]; pending != nil {
+		if pending.timer != nil {
+			pending.timer.Stop()
+		}
+		delete(c.refetch, authID)
+	}
+}
+
+// stop cancels every pending re-fetch and prevents new ones from being scheduled.
+func (c *antigravityModelHintsCache) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	for authID, pending := range c.refetch {
+		if pending.timer != nil {
+			pending.timer.Stop()
+		}
+		delete(c.refetch, authID)
+	}
+}
+
+// scheduleAntigravityModelRefetch arms a single background re-fetch for auth
+// with jittered exponential backoff. Calls made while a re-fetch is pending
+// are ignored so repeated registrations do not pile up goroutines.
+func (s *Service) scheduleAntigravityModelRefetch(auth *coreauth.Auth) {
+	authID := auth.ID
+	fallback := auth.Clone()
+	c := &s.antigravityModels
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	if c.refetch == nil {
+		c.refetch = make(map[string]*antigravityModelRefetch)
+	}
+	pending := c.refetch[authID]
+	if pending == nil {
+		pending = &antigravityModelRefetch{}
+		c.refetch[authID] = pending
+	}
+	if pending.timer != nil {
+		return
+	}
+	pending.attempts++
+	delay := antigravityModelRefetchDelay(pending.attempts)
+	pending.timer = time.AfterFunc(delay, func() {
+		c.mu.Lock()
+		if current := c.refetch[authID]; current == pending {
+			pending.timer = nil
+		}
+		stopped := c.stopped
+		c.mu.Unlock()
+		if stopped {
+			return
+		}
+
+		latest := fallback
+		if s.coreManager != nil {
+			current, ok := s.coreManager.GetByID(authID)
+			if !ok || current == nil || current.Disabled {
+				c.mu.Lock()
+				if c.refetch[authID] == pending {
+					delete(c.refetch, authID)
+				}
+				c.mu.Unlock()
+				return
+			}
+			latest = current
+		}
+		s.registerModelsForAuth(context.Background(), latest)
+	})
+}
+
+// antigravityModelRefetchDelay returns the backoff before re-fetch attempt n,
+// doubling from antigravityModelRefetchBaseDelay up to
+// antigravityModelRefetchMaxDelay, with up to half of it randomized so auths
+// that failed together do not retry in lockstep.
+func antigravityModelRefetchDelay(attempt int) time.Duration {
+	delay := antigravityModelRefetchBaseDelay
+	for i := 1; i < attempt && delay < antigravityModelRefetchMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > antigravityModelRefetchMaxDelay {
+		delay = antigravityModelRefetchMaxDelay
+	}
+	if half := delay / 2; half > 0 {
+		return half + rand.N(half+1)
+	}
+	return delay
 }
 
 func (s *Service) antigravityModelFetchProxyURL(auth *coreauth.Auth) string {
@@ -86,6 +304,26 @@ func (s *Service) antigravityModelFetchProxyURL(auth *coreauth.Auth) string {
 	return ""
 }
 
+// antigravityModelFetchToken returns the bearer token for fetchAvailableModels: the
+// static api_key of an auth with a custom base URL, or the OAuth access token.
+func antigravityModelFetchToken(auth *coreauth.Auth) string {
+	if auth == nil {
+		return ""
+	}
+	if resolveAntigravityModelBaseURL(auth) != "" {
+		if auth.Attributes != nil {
+			if apiKey := strings.TrimSpace(auth.Attributes["api_key"]); apiKey != "" {
+				return apiKey
+			}
+		}
+		if apiKey, ok := auth.Metadata["api_key"].(string); ok && strings.TrimSpace(apiKey) != "" {
+			return strings.TrimSpace(apiKey)
+		}
+	}
+	accessToken, _ := auth.Metadata["access_token"].(string)
+	return strings.TrimSpace(accessToken)
+}
+
 func antigravityModelBaseURLs(auth *coreauth.Auth) []string {
 	if baseURL := resolveAntigravityModelBaseURL(auth); baseURL != "" {
 		return []string{baseURL}
@@ -113,10 +351,10 @@ func resolveAntigravityModelBaseURL(auth *coreauth.Auth) string {
 	return ""
 }
 
-func parseAntigravityModelCapabilityHints(body []byte) antigravityModelCapabilityHints {
+func parseAntigravityModelCapabilityHints(body []byte) (antigravityModelCapabilityHints, error) {
 	var parsed antigravityFetchAvailableModelsResponse
 	if err := json.Unmarshal(body, &parsed); err != nil {
-		return antigravityModelCapabilityHints{}
+		return antigravityModelCapabilityHints{}, err
 	}
 	webSearchModels := make(map[string]struct{}, len(parsed.WebSearchModelIDs))
 	for _, modelID := range parsed.WebSearchModelIDs {
@@ -125,7 +363,7 @@ func parseAntigravityModelCapabilityHints(body []byte) antigravityModelCapabilit
 			webSearchModels[modelID] = struct{}{}
 		}
 	}
-	return antigravityModelCapabilityHints{WebSearchModelIDs: webSearchModels}
+	return antigravityModelCapabilityHints{WebSearchModelIDs: webSearchModels}, nil
 }
 
 func applyAntigravityFetchedModelCapabilities(models []*ModelInfo, hints antigravityModelCapabilityHints) []*ModelInfo {
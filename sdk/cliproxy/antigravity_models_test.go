@@ -0,0 +1,205 @@
+package cliproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalregistry "github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/watcher/synthesizer"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+func registeredAntigravityWebSearch(t *testing.T, authID, modelID string) bool {
+	t.Helper()
+	for _, model := range internalregistry.GetGlobalRegistry().GetModelsForClient(authID) {
+		if model != nil && model.ID == modelID {
+			return model.SupportsWebSearch
+		}
+	}
+	t.Fatalf("model %s not registered for %s", modelID, authID)
+	return false
+}
+
+func TestRegisterModelsForAuth_AntigravityServesCachedHintsAndRefetches(t *testing.T) {
+	originalBase, originalMax := antigravityModelRefetchBaseDelay, antigravityModelRefetchMaxDelay
+	antigravityModelRefetchBaseDelay, antigravityModelRefetchMaxDelay = 10*time.Millisecond, 40*time.Millisecond
+	t.Cleanup(func() {
+		antigravityModelRefetchBaseDelay, antigravityModelRefetchMaxDelay = originalBase, originalMax
+	})
+
+	// phase 0 answers with the first list, phase 1 fails twice, phase 2 answers with a new list.
+	var phase, failures atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch phase.Load() {
+		case 0:
+			_, _ = w.Write([]byte(`{"webSearchModelIds":["gemini-3.1-flash-lite"]}`))
+		default:
+			if failures.Add(1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"webSearchModelIds":["gemini-3-flash-agent"]}`))
+		}
+	}))
+	defer server.Close()
+
+	service := &Service{cfg: &config.Config{}}
+	t.Cleanup(service.antigravityModels.stop)
+	auth := &coreauth.Auth{
+		ID:         "auth-antigravity-cached-hints",
+		Provider:   "antigravity",
+		Status:     coreauth.StatusActive,
+		Attributes: map[string]string{"base_url": server.URL},
+		Metadata:   map[string]any{"access_token": "token"},
+	}
+	registry := internalregistry.GetGlobalRegistry()
+	registry.UnregisterClient(auth.ID)
+	t.Cleanup(func() { registry.UnregisterClient(auth.ID) })
+
+	service.registerModelsForAuth(context.Background(), auth)
+	if !registeredAntigravityWebSearch(t, auth.ID, "gemini-3.1-flash-lite") {
+		t.Fatal("expected web search from the initial fetch")
+	}
+
+	phase.Store(1)
+	service.registerModelsForAuth(context.Background(), auth)
+	if !registeredAntigravityWebSearch(t, auth.ID, "gemini-3.1-flash-lite") {
+		t.Fatal("expected cached web search capability to survive a failed fetch")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !registeredAntigravityWebSearch(t, auth.ID, "gemini-3-flash-agent") {
+		if time.Now().After(deadline) {
+			t.Fatalf("background re-fetch did not refresh models after %d upstream calls", failures.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if registeredAntigravityWebSearch(t, auth.ID, "gemini-3.1-flash-lite") {
+		t.Fatal("expected refreshed hints to replace the cached list")
+	}
+	if got := failures.Load(); got < 3 {
+		t.Fatalf("upstream calls after failure = %d, want retries until success", got)
+	}
+
+	service.antigravityModels.mu.Lock()
+	pending := len(service.antigravityModels.refetch)
+	service.antigravityModels.mu.Unlock()
+	if pending != 0 {
+		t.Fatalf("pending re-fetches = %d, want none after success", pending)
+	}
+}
+
+func TestFetchAntigravityModelCapabilityHints_UsesStaticAPIKey(t *testing.T) {
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"webSearchModelIds":["gemini-3.1-flash-lite"]}`))
+	}))
+	defer server.Close()
+
+	service := &Service{cfg: &config.Config{}}
+	auth := &coreauth.Auth{
+		ID:         "auth-antigravity-static-key",
+		Provider:   "antigravity",
+		Attributes: map[string]string{"base_url": server.URL, "api_key": "static-key"},
+	}
+
+	hints, errFetch := service.fetchAntigravityModelCapabilityHintsForAuth(context.Background(), auth)
+	if errFetch != nil {
+		t.Fatalf("fetchAntigravityModelCapabilityHintsForAuth() error = %v", errFetch)
+	}
+	if len(hints.WebSearchModelIDs) != 1 {
+		t.Fatalf("web search model IDs = %v, want one", hints.WebSearchModelIDs)
+	}
+	if got, _ := authorization.Load().(string); got != "Bearer static-key" {
+		t.Fatalf("Authorization = %q, want %q", got, "Bearer static-key")
+	}
+}
+
+func TestFetchAntigravityModelCapabilityHints_UsesStaticAPIKeyFromAuthFile(t *testing.T) {
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"webSearchModelIds":["gemini-3.1-flash-lite"]}`))
+	}))
+	defer server.Close()
+
+	authDir := t.TempDir()
+	authFile := `{"type":"antigravity","base_url":"` + server.URL + `","api_key":"static-key"}`
+	if errWrite := os.WriteFile(filepath.Join(authDir, "antigravity-gateway.json"), []byte(authFile), 0o600); errWrite != nil {
+		t.Fatalf("write auth file: %v", errWrite)
+	}
+	auths, errSynth := synthesizer.NewFileSynthesizer().Synthesize(&synthesizer.SynthesisContext{
+		Config:      &config.Config{},
+		AuthDir:     authDir,
+		Now:         time.Now(),
+		IDGenerator: synthesizer.NewStableIDGenerator(),
+	})
+	if errSynth != nil || len(auths) != 1 {
+		t.Fatalf("Synthesize() = %d auths, error %v; want one auth", len(auths), errSynth)
+	}
+
+	service := &Service{cfg: &config.Config{}}
+	if _, errFetch := service.fetchAntigravityModelCapabilityHintsForAuth(context.Background(), auths[0]); errFetch != nil {
+		t.Fatalf("fetchAntigravityModelCapabilityHintsForAuth() error = %v", errFetch)
+	}
+	if got, _ := authorization.Load().(string); got != "Bearer static-key" {
+		t.Fatalf("Authorization = %q, want %q", got, "Bearer static-key")
+	}
+}
+
+func TestAntigravityModelHintsCacheExpires(t *testing.T) {
+	var cache antigravityModelHintsCache
+	now := time.Now()
+	hints := antigravityModelCapabilityHints{WebSearchModelIDs: map[string]struct{}{"m": {}}}
+	cache.store("auth", hints, now)
+
+	if _, ok := cache.load("auth", now.Add(antigravityModelHintsTTL-time.Minute)); !ok {
+		t.Fatal("expected cached hints within TTL")
+	}
+	if _, ok := cache.load("auth", now.Add(antigravityModelHintsTTL+time.Minute)); ok {
+		t.Fatal("expected cached hints to expire after TTL")
+	}
+}
+
+func TestAntigravityModelHintsCacheDropsStaleAndRemovedAuths(t *testing.T) {
+	var cache antigravityModelHintsCache
+	t.Cleanup(cache.stop)
+	now := time.Now()
+	hints := antigravityModelCapabilityHints{WebSearchModelIDs: map[string]struct{}{"m": {}}}
+	cache.store("auth-stale", hints, now.Add(-antigravityModelHintsTTL-time.Minute))
+	cache.store("auth-live", hints, now)
+	if _, ok := cache.entries["auth-stale"]; ok {
+		t.Fatal("expected stale hints to be pruned when storing another auth")
+	}
+
+	cache.refetch = map[string]*antigravityModelRefetch{"auth-live": {timer: time.AfterFunc(time.Hour, func() {})}}
+	cache.forget("auth-live")
+	if _, ok := cache.entries["auth-live"]; ok {
+		t.Fatal("expected hints of a removed auth to be dropped")
+	}
+	if _, ok := cache.refetch["auth-live"]; ok {
+		t.Fatal("expected the pending re-fetch of a removed auth to be dropped")
+	}
+}
+
+func TestAntigravityModelRefetchDelayBackoff(t *testing.T) {
+	for attempt, ceiling := range map[int]time.Duration{
+		1:  antigravityModelRefetchBaseDelay,
+		2:  2 * antigravityModelRefetchBaseDelay,
+		50: antigravityModelRefetchMaxDelay,
+	} {
+		for i := 0; i < 20; i++ {
+			if got := antigravityModelRefetchDelay(attempt); got < ceiling/2 || got > ceiling {
+				t.Fatalf("antigravityModelRefetchDelay(%d) = %v, want within [%v, %v]", attempt, got, ceiling/2, ceiling)
+			}
+		}
+	}
+}
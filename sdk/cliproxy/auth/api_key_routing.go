@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+// apiKeyRouteFromMetadata returns the routing.api-key-routes entry of the client API key
+// carried in meta, or nil when the key has none.
+func (m *Manager) apiKeyRouteFromMetadata(meta map[string]any) *internalconfig.APIKeyRoute {
+	if m == nil || len(meta) == 0 {
+		return nil
+	}
+	apiKey, _ := meta[cliproxyexecutor.ClientAPIKeyMetadataKey].(string)
+	if strings.TrimSpace(apiKey) == "" {
+		return nil
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Routing.APIKeyRoute(apiKey)
+}
+
+// availableAuthsForAPIKeyRoute narrows candidates to the auths listed by route and returns
+// the available ones. routed is false, without an error, when none of them is available
+// and the route falls back to the pool; the caller then selects from all candidates.
+func (m *Manager) availableAuthsForAPIKeyRoute(route *internalconfig.APIKeyRoute, candidates []*Auth, provider, model string, now time.Time) (available []*Auth, routed bool, err error) {
+	listed := make(map[string]struct{}, len(route.Auths))
+	for _, entry := range route.Auths {
+		listed[entry.ID] = struct{}{}
+	}
+	routeCandidates := make([]*Auth, 0, len(route.Auths))
+	for _, candidate := range candidates {
+		if _, ok := listed[candidate.ID]; ok {
+			routeCandidates = append(routeCandidates, candidate)
+		}
+	}
+	if len(routeCandidates) == 0 {
+		if route.FallbackToPool {
+			return nil, false, nil
+		}
+		return nil, false, &Error{Code: "auth_not_found", Message: "no auth available for the client API key"}
+	}
+	available, err = m.availableAuthsForRouteModel(routeCandidates, provider, model, now)
+	if err != nil {
+		if route.FallbackToPool {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return available, true, nil
+}
+
+// pickWeightedAuth picks one of available with a probability proportional to its weight
+// in route.
+func pickWeightedAuth(route *internalconfig.APIKeyRoute, available []*Auth) *Auth {
+	if len(available) == 0 {
+		return nil
+	}
+	weights := make(map[string]int, len(route.Auths))
+	for _, entry := range route.Auths {
+		weights[entry.ID] = entry.EffectiveWeight()
+	}
+	total := 0
+	for _, candidate := range available {
+		total += max(weights[candidate.ID], 1)
+	}
+	n := rand.IntN(total)
+	for _, candidate := range available {
+		n -= max(weights[candidate.ID], 1)
+		if n < 0 {
+			return candidate
+		}
+	}
+	return available[len(available)-1]
+}
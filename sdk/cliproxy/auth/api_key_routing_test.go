@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+// newAPIKeyRoutingManager registers a gemini auth per ID for model and configures routes.
+func newAPIKeyRoutingManager(t *testing.T, model string, routes []internalconfig.APIKeyRoute, auths ...*Auth) *Manager {
+	t.Helper()
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.executors["gemini"] = schedulerTestExecutor{}
+	manager.SetConfig(&internalconfig.Config{Routing: internalconfig.RoutingConfig{APIKeyRoutes: routes}})
+	ids := make([]string, 0, len(auths))
+	for _, auth := range auths {
+		ids = append(ids, auth.ID)
+		if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("Register(%s) error = %v", auth.ID, errRegister)
+		}
+	}
+	registerSchedulerModels(t, "gemini", model, ids...)
+	return manager
+}
+
+func apiKeyOptions(apiKey string) cliproxyexecutor.Options {
+	if apiKey == "" {
+		return cliproxyexecutor.Options{}
+	}
+	return cliproxyexecutor.Options{Metadata: map[string]any{cliproxyexecutor.ClientAPIKeyMetadataKey: apiKey}}
+}
+
+func TestManagerAPIKeyRoutesIsolateKeys(t *testing.T) {
+	model := "route-isolation-model"
+	manager := newAPIKeyRoutingManager(t, model, []internalconfig.APIKeyRoute{
+		{APIKey: "team-a", Auths: []internalconfig.WeightedAuth{{ID: "route-iso-1"}, {ID: "route-iso-2"}}},
+		{APIKey: "team-b", Auths: []internalconfig.WeightedAuth{{ID: "route-iso-3", Weight: 70}, {ID: "route-iso-4", Weight: 30}}},
+	},
+		&Auth{ID: "route-iso-1", Provider: "gemini"},
+		&Auth{ID: "route-iso-2", Provider: "gemini"},
+		&Auth{ID: "route-iso-3", Provider: "gemini"},
+		&Auth{ID: "route-iso-4", Provider: "gemini"},
+		&Auth{ID: "route-iso-5", Provider: "gemini"},
+	)
+
+	tests := []struct {
+		apiKey string
+		want   []string
+	}{
+		{apiKey: "team-a", want: []string{"route-iso-1", "route-iso-2"}},
+		{apiKey: "team-b", want: []string{"route-iso-3", "route-iso-4"}},
+		{apiKey: "", want: []string{"route-iso-1", "route-iso-2", "route-iso-3", "route-iso-4", "route-iso-5"}},
+		{apiKey: "unrouted", want: []string{"route-iso-1", "route-iso-2", "route-iso-3", "route-iso-4", "route-iso-5"}},
+	}
+	for _, tt := range tests {
+		seen := make(map[string]int)
+		for i := 0; i < 200; i++ {
+			got, _, errPick := manager.pickNext(context.Background(), "gemini", model, apiKeyOptions(tt.apiKey), nil)
+			if errPick != nil {
+				t.Fatalf("pickNext(%q) error = %v", tt.apiKey, errPick)
+			}
+			seen[got.ID]++
+		}
+		for _, id := range tt.want {
+			if seen[id] == 0 {
+				t.Errorf("key %q never picked %s: %v", tt.apiKey, id, seen)
+			}
+			delete(seen, id)
+		}
+		if len(seen) != 0 {
+			t.Errorf("key %q picked auths outside its route: %v", tt.apiKey, seen)
+		}
+	}
+
+	got, _, _, errPick := manager.pickNextMixed(context.Background(), []string{"gemini"}, model, apiKeyOptions("team-a"), nil)
+	if errPick != nil {
+		t.Fatalf("pickNextMixed() error = %v", errPick)
+	}
+	if got.ID != "route-iso-1" && got.ID != "route-iso-2" {
+		t.Fatalf("pickNextMixed() auth.ID = %q, want a team-a auth", got.ID)
+	}
+}
+
+func TestManagerAPIKeyRoutesFollowWeights(t *testing.T) {
+	model := "route-weight-model"
+	manager := newAPIKeyRoutingManager(t, model, []internalconfig.APIKeyRoute{
+		{APIKey: "team-b", Auths: []internalconfig.WeightedAuth{{ID: "route-weight-3", Weight: 70}, {ID: "route-weight-4", Weight: 30}}},
+	},
+		&Auth{ID: "route-weight-3", Provider: "gemini"},
+		&Auth{ID: "route-weight-4", Provider: "gemini"},
+	)
+
+	const picks = 10000
+	counts := make(map[string]int)
+	for i := 0; i < picks; i++ {
+		got, _, errPick := manager.pickNext(context.Background(), "gemini", model, apiKeyOptions("team-b"), nil)
+		if errPick != nil {
+			t.Fatalf("pickNext() error = %v", errPick)
+		}
+		counts[got.ID]++
+	}
+	share := float64(counts["route-weight-3"]) / picks
+	if share < 0.66 || share > 0.74 {
+		t.Fatalf("route-weight-3 share = %.3f, want about 0.70 (counts %v)", share, counts)
+	}
+}
+
+func TestManagerAPIKeyRoutesFallBackToPool(t *testing.T) {
+	model := "route-fallback-model"
+	coolingDown := func(id string) *Auth {
+		return &Auth{ID: id, Provider: "gemini", ModelStates: map[string]*ModelState{
+			model: {Status: StatusError, Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour)},
+		}}
+	}
+	manager := newAPIKeyRoutingManager(t, model, []internalconfig.APIKeyRoute{
+		{APIKey: "strict", Auths: []internalconfig.WeightedAuth{{ID: "route-fallback-1"}}},
+		{APIKey: "lenient", Auths: []internalconfig.WeightedAuth{{ID: "route-fallback-1"}}, FallbackToPool: true},
+	},
+		coolingDown("route-fallback-1"),
+		&Auth{ID: "route-fallback-2", Provider: "gemini"},
+	)
+
+	if got, _, errPick := manager.pickNext(context.Background(), "gemini", model, apiKeyOptions("strict"), nil); errPick == nil {
+		t.Fatalf("pickNext(strict) = %s, want an error while the listed auth cools down", got.ID)
+	}
+	got, _, errPick := manager.pickNext(context.Background(), "gemini", model, apiKeyOptions("lenient"), nil)
+	if errPick != nil {
+		t.Fatalf("pickNext(lenient) error = %v", errPick)
+	}
+	if got.ID != "route-fallback-2" {
+		t.Fatalf("pickNext(lenient) auth.ID = %q, want the pool auth", got.ID)
+	}
+}
@@ -21,8 +21,10 @@ import (
 	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/home"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
 	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executionregistry"
 	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
@@ -98,7 +100,8 @@ func SetQuotaCooldownDisabled(disable bool) {
 	quotaCooldownDisabled.Store(disable)
 }
 
-// SetTransientErrorCooldownSeconds configures cooldowns for 408/500/502/503/504.
+// SetTransientErrorCooldownSeconds configures cooldowns for 408/500/502/503/504 and
+// Anthropic's 529 overloaded status.
 // 0 keeps the legacy default; negative values disable transient error cooldowns.
 func SetTransientErrorCooldownSeconds(seconds int) {
 	transientErrorCooldownSeconds.Store(int64(seconds))
@@ -246,6 +249,15 @@ type Manager struct {
 	requestRetry        atomic.Int32
 	maxRetryCredentials atomic.Int32
 	maxRetryInterval    atomic.Int64
+	// upstreamRetryBudget limits same-credential retries of transient upstream failures.
+	upstreamRetryBudget upstreamRetryBudget
+
+	// responseCache holds non-streaming responses served to identical requests.
+	responseCache responseCache
+	// shadow mirrors sampled non-streaming requests to the shadow model.
+	shadow shadowState
+	// priorityLimiter queues requests beyond the request-priority concurrency caps.
+	priorityLimiter priorityLimiter
 
 	// oauthModelAlias stores global OAuth model alias mappings (alias -> upstream name) keyed by channel.
 	oauthModelAlias atomic.Value
@@ -1831,6 +1843,7 @@ func (m *Manager) pickViaBuiltinScheduler(ctx context.Context, strategy schedule
 	}
 	providerKey := strings.ToLower(strings.TrimSpace(provider))
 	disallowFreeAuth := disallowFreeAuthFromMetadata(opts.Metadata)
+	quotaSkips := newTokenQuotaSkips(model)
 	for {
 		var selected *Auth
 		var errPick error
@@ -1848,12 +1861,12 @@ func (m *Manager) pickViaBuiltinScheduler(ctx context.Context, strategy schedule
 			}
 		}
 		if errPick != nil {
-			return nil, true, errPick
+			return nil, true, quotaSkips.pickError(errPick)
 		}
 		if selected == nil {
-			return nil, true, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+			return nil, true, quotaSkips.pickError(&Error{Code: "auth_not_found", Message: "selector returned no auth"})
 		}
-		if disallowFreeAuth && isFreeCodexAuth(selected) {
+		if (disallowFreeAuth && isFreeCodexAuth(selected)) || quotaSkips.exhausted(selected, time.Now()) {
 			if tried == nil {
 				tried = make(map[string]struct{})
 			}
@@ -2011,8 +2024,10 @@ func readStreamBootstrap(ctx context.Context, ch <-chan cliproxyexecutor.StreamC
 
 func (m *Manager) wrapStreamResult(ctx context.Context, auth *Auth, provider, resultModel string, headers http.Header, buffered []cliproxyexecutor.StreamChunk, remaining <-chan cliproxyexecutor.StreamChunk, aliasResult OAuthModelAliasResult, ephemeralResult bool) *cliproxyexecutor.StreamResult {
 	out := make(chan cliproxyexecutor.StreamChunk)
+	streamDone := metrics.StreamStarted(provider)
 	go func() {
 		defer close(out)
+		defer streamDone()
 		var failed bool
 		forward := true
 		var rewriter *StreamRewriter
@@ -2105,7 +2120,7 @@ func (m *Manager) executeStreamWithModelPool(ctx context.Context, executor Provi
 		if errCtx := ctx.Err(); errCtx != nil {
 			return nil, errCtx
 		}
-		streamResult, errStream := executor.ExecuteStream(ctx, auth, execReq, execOpts)
+		streamResult, errStream := m.executeStreamWithUpstreamRetry(ctx, executor, auth, execReq, execOpts)
 		if errStream != nil {
 			if errCtx := ctx.Err(); errCtx != nil {
 				return nil, errCtx
@@ -2581,16 +2596,47 @@ func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxye
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	ctx = withRequestQueueDeadline(ctx)
+	ctx, cancel, _ := m.withModelTimeout(ctx, req, opts, false)
+	defer cancel()
+	var (
+		resp    cliproxyexecutor.Response
+		errExec error
+	)
+	start := time.Now()
 	if m.HomeEnabled() {
-		return m.executeHome(ctx, normalized, req, opts, false)
+		resp, errExec = m.executeHome(ctx, normalized, req, opts, false)
+	} else {
+		resp, errExec = m.executeWithResponseCache(ctx, normalized, req, opts)
+	}
+	if errExec != nil {
+		if errTimeout := modelTimeoutCause(ctx); errTimeout != nil {
+			return cliproxyexecutor.Response{}, errTimeout
+		}
+		return resp, errExec
+	}
+	if !m.HomeEnabled() {
+		m.maybeShadow(ctx, req, opts, resp, time.Since(start))
 	}
+	return resp, nil
+}
 
+// executeUncached runs a non-streaming request against the upstream, retrying across
+// credentials and falling back to Antigravity credits as configured.
+func (m *Manager) executeUncached(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	_, maxRetryCredentials, maxWait := m.retrySettings()
 
 	var lastErr error
 	retryModel := authSelectionModelFromOptions(opts, req.Model)
+	hedgeDelay, hedge := m.requestHedgeDelay(normalized, opts)
 	for attempt := 0; ; attempt++ {
-		resp, errExec := m.executeMixedOnce(ctx, normalized, req, opts, maxRetryCredentials)
+		var resp cliproxyexecutor.Response
+		var errExec error
+		if hedge {
+			resp, errExec = m.executeHedged(ctx, normalized, req, opts, maxRetryCredentials, hedgeDelay)
+		} else {
+			resp, errExec = m.executeMixedOnce(ctx, normalized, req, opts, maxRetryCredentials)
+		}
 		if errExec == nil {
 			return resp, nil
 		}
@@ -2662,7 +2708,25 @@ func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cli
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
+	ctx = withRequestQueueDeadline(ctx)
+	limitedCtx, cancel, limited := m.withModelTimeout(ctx, req, opts, true)
+	if !limited {
+		return m.executeStream(ctx, normalized, req, opts)
+	}
+	result, errStream := m.executeStream(limitedCtx, normalized, req, opts)
+	if errStream != nil {
+		cancel()
+		if errTimeout := modelTimeoutCause(limitedCtx); errTimeout != nil {
+			return nil, errTimeout
+		}
+		return nil, errStream
+	}
+	return limitStreamDuration(ctx, limitedCtx, cancel, result), nil
+}
 
+// executeStream opens a stream, retrying across credentials and falling back to
+// Antigravity credits as configured.
+func (m *Manager) executeStream(ctx context.Context, normalized []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
 	_, maxRetryCredentials, maxWait := m.retrySettings()
 
 	var lastErr error
@@ -2913,6 +2977,13 @@ func mergeRequestHeaders(current, updates http.Header, clear []string) http.Head
 }
 
 func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int) (cliproxyexecutor.Response, error) {
+	return m.executeMixedOnceClaiming(ctx, providers, req, opts, maxRetryCredentials, nil)
+}
+
+// executeMixedOnceClaiming is executeMixedOnce for one attempt of a hedged
+// request. Auths already claimed by another attempt are skipped; a nil claims
+// allows every auth.
+func (m *Manager) executeMixedOnceClaiming(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int, claims *hedgeClaims) (cliproxyexecutor.Response, error) {
 	if len(providers) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
 	}
@@ -2943,6 +3014,11 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			return cliproxyexecutor.Response{}, errPick
 		}
 
+		if !claims.claim(auth.ID) {
+			tried[auth.ID] = struct{}{}
+			continue
+		}
+
 		entry := logEntryWithRequestID(ctx)
 		debugLogAuthSelection(entry, auth, provider, routeModel)
 		publishSelectedAuthMetadata(opts.Metadata, auth)
@@ -2979,22 +3055,38 @@ func (m *Manager) executeMixedOnce(ctx context.Context, providers []string, req
 			}
 			execOpts := opts
 			execReq, execOpts = applyRequestAfterAuthInterceptor(execCtx, executor, provider, execReq, execOpts, requestedModelAliasFromOptions(execOpts, routeModel))
-			resp, errExec := executor.Execute(execCtx, auth, execReq, execOpts)
+			slot, errSlot := m.acquireRequestSlot(execCtx, auth, provider, execOpts)
+			if errSlot != nil {
+				if errCtx := execCtx.Err(); errCtx != nil {
+					return cliproxyexecutor.Response{}, errCtx
+				}
+				// No slot for this credential; try the next one.
+				authErr = errSlot
+				break
+			}
+			attemptCtx, attemptSpan := startAttemptSpan(execCtx, provider, auth, execReq.Model)
+			resp, errExec := m.executeWithUpstreamRetry(attemptCtx, executor, auth, execReq, execOpts)
 			if errExec != nil {
 				if errCtx := execCtx.Err(); errCtx != nil {
+					tracing.End(attemptSpan, errCtx)
+					slot.Release()
 					return cliproxyexecutor.Response{}, errCtx
 				}
 				if refreshed, okRefresh := m.tryRefreshAfterUnauthorized(execCtx, auth, errExec, didRefreshOnUnauthorized); okRefresh {
 					auth = refreshed
 					didRefreshOnUnauthorized = true
-					resp, errExec = executor.Execute(execCtx, auth, execReq, execOpts)
+					resp, errExec = executor.Execute(attemptCtx, auth, execReq, execOpts)
 					if errExec != nil {
 						if errCtx := execCtx.Err(); errCtx != nil {
+							tracing.End(attemptSpan, errCtx)
+							slot.Release()
 							return cliproxyexecutor.Response{}, errCtx
 						}
 					}
 				}
 			}
+			tracing.End(attemptSpan, errExec)
+			slot.Release()
 			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: errExec == nil}
 			if errExec != nil {
 				result.Error = resultErrorFromError(errExec)
@@ -3092,22 +3184,26 @@ func (m *Manager) executeCountMixedOnce(ctx context.Context, providers []string,
 			}
 			execOpts := opts
 			execReq, execOpts = applyRequestAfterAuthInterceptor(execCtx, executor, provider, execReq, execOpts, requestedModelAliasFromOptions(execOpts, routeModel))
-			resp, errExec := executor.CountTokens(execCtx, auth, execReq, execOpts)
+			attemptCtx, attemptSpan := startAttemptSpan(execCtx, provider, auth, execReq.Model)
+			resp, errExec := executor.CountTokens(attemptCtx, auth, execReq, execOpts)
 			if errExec != nil {
 				if errCtx := execCtx.Err(); errCtx != nil {
+					tracing.End(attemptSpan, errCtx)
 					return cliproxyexecutor.Response{}, errCtx
 				}
 				if refreshed, okRefresh := m.tryRefreshAfterUnauthorized(execCtx, auth, errExec, didRefreshOnUnauthorized); okRefresh {
 					auth = refreshed
 					didRefreshOnUnauthorized = true
-					resp, errExec = executor.CountTokens(execCtx, auth, execReq, execOpts)
+					resp, errExec = executor.CountTokens(attemptCtx, auth, execReq, execOpts)
 					if errExec != nil {
 						if errCtx := execCtx.Err(); errCtx != nil {
+							tracing.End(attemptSpan, errCtx)
 							return cliproxyexecutor.Response{}, errCtx
 						}
 					}
 				}
 			}
+			tracing.End(attemptSpan, errExec)
 			result := Result{AuthID: auth.ID, Provider: provider, Model: resultModel, Success: errExec == nil}
 			if errExec != nil {
 				result.Error = resultErrorFromError(errExec)
@@ -3273,8 +3369,37 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			models = models[:1]
 			pooled = false
 		}
-		streamResult, errStream := m.executeStreamWithModelPool(execCtx, executor, auth, provider, execReq, execOpts, routeModel, streamExecutionModel, models, pooled, aliasResult, !homeMode, selection != nil)
+		slot, errSlot := m.acquireRequestSlot(execCtx, auth, provider, execOpts)
+		if errSlot != nil {
+			if selection != nil {
+				releaseAttempt()
+				if errEnd := m.endHomeSelectionBeforeRedispatch(ctx, selection, "request_slot_unavailable"); errEnd != nil {
+					return nil, errEnd
+				}
+			}
+			if errCtx := execCtx.Err(); errCtx != nil {
+				return nil, errCtx
+			}
+			// No slot for this credential; try the next one.
+			lastErr = errSlot
+			if homeMode {
+				homeAuthCount++
+			}
+			continue
+		}
+		if slot != nil {
+			// The slot stays taken until the stream is fully consumed.
+			release := releaseAttempt
+			releaseAttempt = func() {
+				slot.Release()
+				release()
+			}
+		}
+		attemptCtx, attemptSpan := startAttemptSpan(execCtx, provider, auth, routeModel)
+		streamResult, errStream := m.executeStreamWithModelPool(attemptCtx, executor, auth, provider, execReq, execOpts, routeModel, streamExecutionModel, models, pooled, aliasResult, !homeMode, selection != nil)
 		if errStream != nil {
+			tracing.End(attemptSpan, errStream)
+			slot.Release()
 			if selection != nil {
 				releaseAttempt()
 				if errEnd := m.endHomeSelectionBeforeRedispatch(ctx, selection, "stream_start_failed"); errEnd != nil {
@@ -3293,12 +3418,23 @@ func (m *Manager) executeStreamMixedOnce(ctx context.Context, providers []string
 			}
 			continue
 		}
+		if attemptSpan.IsRecording() {
+			// The attempt span ends once the stream is fully consumed.
+			release := releaseAttempt
+			releaseAttempt = func() {
+				release()
+				tracing.End(attemptSpan, nil)
+			}
+		}
 		if selection != nil {
 			if m.retainHomeWebsocketSelection(ctx, opts, routeModel, selection) {
 				return wrapHomeStream(ctx, streamResult, nil, releaseAttempt), nil
 			}
 			return wrapHomeStream(ctx, streamResult, selection, releaseAttempt), nil
 		}
+		if slot != nil || attemptSpan.IsRecording() {
+			return wrapHomeStream(ctx, streamResult, nil, releaseAttempt), nil
+		}
 		return streamResult, nil
 	}
 }
@@ -4076,6 +4212,32 @@ func (m *Manager) HasProviderAuth(provider string) bool {
 	return false
 }
 
+// HasAvailableAuthForModel reports whether the provider has at least one auth that is
+// not disabled and not cooling down for model. Like AvailableProviders it is a
+// best-effort snapshot used for routing decisions.
+func (m *Manager) HasAvailableAuthForModel(provider, model string) bool {
+	if m == nil {
+		return false
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		return false
+	}
+	model = strings.TrimSpace(model)
+	now := time.Now()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, auth := range m.auths {
+		if auth == nil || strings.ToLower(strings.TrimSpace(auth.Provider)) != provider {
+			continue
+		}
+		if blocked, _, _ := isAuthBlockedForModel(auth, model, now); !blocked {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Manager) retrySettings() (int, int, time.Duration) {
 	if m == nil {
 		return 0, 0, 0
@@ -4404,7 +4566,7 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 								shouldSuspendModel = true
 								setModelQuota = true
 							}
-						case 408, 500, 502, 503, 504:
+						case 408, 500, 502, 503, 504, 529:
 							if disableCooling {
 								state.NextRetryAfter = time.Time{}
 							} else {
@@ -5222,7 +5384,7 @@ func applyAuthFailureState(auth *Auth, resultErr *Error, retryAfter *time.Durati
 		}
 		auth.Quota.NextRecoverAt = next
 		auth.NextRetryAfter = next
-	case 408, 500, 502, 503, 504:
+	case 408, 500, 502, 503, 504, 529:
 		auth.StatusMessage = "transient upstream error"
 		if disableCooling {
 			auth.NextRetryAfter = time.Time{}
@@ -5411,6 +5573,9 @@ func (m *Manager) pickNextLegacy(ctx context.Context, provider, model string, op
 
 	pinnedAuthID := pinnedAuthIDFromMetadata(opts.Metadata)
 	disallowFreeAuth := disallowFreeAuthFromMetadata(opts.Metadata)
+	apiKeyRoute := m.apiKeyRouteFromMetadata(opts.Metadata)
+	quotaSkips := newTokenQuotaSkips(model)
+	now := time.Now()
 
 	m.mu.RLock()
 	selector := m.selector
@@ -5446,29 +5611,59 @@ func (m *Manager) pickNextLegacy(ctx context.Context, provider, model string, op
 		if modelKey != "" && !m.authSupportsRouteModel(registryRef, candidate, model) {
 			continue
 		}
+		if quotaSkips.exhausted(candidate, now) {
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
+		return nil, nil, quotaSkips.pickError(&Error{Code: "auth_not_found", Message: "no auth available"})
 	}
-	available, errAvailable := m.availableAuthsForRouteModel(candidates, provider, model, time.Now())
-	if errAvailable != nil {
-		m.mu.RUnlock()
-		return nil, nil, errAvailable
+	var available []*Auth
+	routed := false
+	if apiKeyRoute != nil {
+		var errRoute error
+		available, routed, errRoute = m.availableAuthsForAPIKeyRoute(apiKeyRoute, candidates, provider, model, now)
+		if errRoute != nil {
+			m.mu.RUnlock()
+			return nil, nil, errRoute
+		}
+	}
+	if !routed {
+		var errAvailable error
+		available, errAvailable = m.availableAuthsForRouteModel(candidates, provider, model, now)
+		if errAvailable != nil {
+			m.mu.RUnlock()
+			return nil, nil, errAvailable
+		}
 	}
 	available = cloneAuthSlice(available)
 	m.mu.RUnlock()
 
-	selected, handled, errPick := m.pickViaPluginScheduler(ctx, pluginScheduler, provider, []string{provider}, model, opts, tried, available)
-	if errPick != nil {
-		return nil, nil, errPick
-	}
-	if !handled {
+	var selected *Auth
+	switch {
+	case routed && apiKeyRoute.Weighted():
+		selected = pickWeightedAuth(apiKeyRoute, available)
+	case routed:
+		var errPick error
 		selected, errPick = selector.Pick(ctx, provider, selectionArgForSelector(selector, model), opts, available)
 		if errPick != nil {
 			return nil, nil, errPick
 		}
+	default:
+		var handled bool
+		var errPick error
+		selected, handled, errPick = m.pickViaPluginScheduler(ctx, pluginScheduler, provider, []string{provider}, model, opts, tried, available)
+		if errPick != nil {
+			return nil, nil, errPick
+		}
+		if !handled {
+			selected, errPick = selector.Pick(ctx, provider, selectionArgForSelector(selector, model), opts, available)
+			if errPick != nil {
+				return nil, nil, errPick
+			}
+		}
 	}
 	if selected == nil {
 		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
@@ -5590,7 +5785,7 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		return auth, exec, err
 	}
 
-	if m.hasPluginScheduler() || !m.useSchedulerFastPath() {
+	if m.hasPluginScheduler() || !m.useSchedulerFastPath() || m.apiKeyRouteFromMetadata(opts.Metadata) != nil {
 		return m.pickNextLegacy(ctx, provider, model, opts, tried)
 	}
 	if strings.TrimSpace(model) != "" {
@@ -5614,6 +5809,7 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 		return nil, nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
 	disallowFreeAuth := disallowFreeAuthFromMetadata(opts.Metadata)
+	quotaSkips := newTokenQuotaSkips(model)
 	for {
 		selected, errPick := m.scheduler.pickSingle(ctx, provider, model, opts, tried)
 		if errPick != nil && model != "" && shouldRetrySchedulerPick(errPick) {
@@ -5621,12 +5817,12 @@ func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cli
 			selected, errPick = m.scheduler.pickSingle(ctx, provider, model, opts, tried)
 		}
 		if errPick != nil {
-			return nil, nil, errPick
+			return nil, nil, quotaSkips.pickError(errPick)
 		}
 		if selected == nil {
-			return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+			return nil, nil, quotaSkips.pickError(&Error{Code: "auth_not_found", Message: "selector returned no auth"})
 		}
-		if disallowFreeAuth && isFreeCodexAuth(selected) {
+		if (disallowFreeAuth && isFreeCodexAuth(selected)) || quotaSkips.exhausted(selected, time.Now()) {
 			if tried == nil {
 				tried = make(map[string]struct{})
 			}
@@ -5653,6 +5849,9 @@ func (m *Manager) pickNextMixedLegacy(ctx context.Context, providers []string, m
 
 	pinnedAuthID := pinnedAuthIDFromMetadata(opts.Metadata)
 	disallowFreeAuth := disallowFreeAuthFromMetadata(opts.Metadata)
+	apiKeyRoute := m.apiKeyRouteFromMetadata(opts.Metadata)
+	quotaSkips := newTokenQuotaSkips(model)
+	now := time.Now()
 
 	providerSet := make(map[string]struct{}, len(providers))
 	for _, provider := range providers {
@@ -5705,29 +5904,59 @@ func (m *Manager) pickNextMixedLegacy(ctx context.Context, providers []string, m
 		if modelKey != "" && !m.authSupportsRouteModel(registryRef, candidate, model) {
 			continue
 		}
+		if quotaSkips.exhausted(candidate, now) {
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
 	if len(candidates) == 0 {
 		m.mu.RUnlock()
-		return nil, nil, "", &Error{Code: "auth_not_found", Message: "no auth available"}
+		return nil, nil, "", quotaSkips.pickError(&Error{Code: "auth_not_found", Message: "no auth available"})
 	}
-	available, errAvailable := m.availableAuthsForRouteModel(candidates, "mixed", model, time.Now())
-	if errAvailable != nil {
-		m.mu.RUnlock()
-		return nil, nil, "", errAvailable
+	var available []*Auth
+	routed := false
+	if apiKeyRoute != nil {
+		var errRoute error
+		available, routed, errRoute = m.availableAuthsForAPIKeyRoute(apiKeyRoute, candidates, "mixed", model, now)
+		if errRoute != nil {
+			m.mu.RUnlock()
+			return nil, nil, "", errRoute
+		}
+	}
+	if !routed {
+		var errAvailable error
+		available, errAvailable = m.availableAuthsForRouteModel(candidates, "mixed", model, now)
+		if errAvailable != nil {
+			m.mu.RUnlock()
+			return nil, nil, "", errAvailable
+		}
 	}
 	available = cloneAuthSlice(available)
 	m.mu.RUnlock()
 
-	selected, handled, errPick := m.pickViaPluginScheduler(ctx, pluginScheduler, "mixed", providers, model, opts, tried, available)
-	if errPick != nil {
-		return nil, nil, "", errPick
-	}
-	if !handled {
+	var selected *Auth
+	switch {
+	case routed && apiKeyRoute.Weighted():
+		selected = pickWeightedAuth(apiKeyRoute, available)
+	case routed:
+		var errPick error
 		selected, errPick = selector.Pick(ctx, "mixed", selectionArgForSelector(selector, model), opts, available)
 		if errPick != nil {
 			return nil, nil, "", errPick
 		}
+	default:
+		var handled bool
+		var errPick error
+		selected, handled, errPick = m.pickViaPluginScheduler(ctx, pluginScheduler, "mixed", providers, model, opts, tried, available)
+		if errPick != nil {
+			return nil, nil, "", errPick
+		}
+		if !handled {
+			selected, errPick = selector.Pick(ctx, "mixed", selectionArgForSelector(selector, model), opts, available)
+			if errPick != nil {
+				return nil, nil, "", errPick
+			}
+		}
 	}
 	if selected == nil {
 		return nil, nil, "", &Error{Code: "auth_not_found", Message: "selector returned no auth"}
@@ -5749,12 +5978,12 @@ func (m *Manager) pickNextMixedLegacy(ctx context.Context, providers []string, m
 	return authCopy, executor, providerKey, nil
 }
 
-func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+func (m *Manager) selectNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
 	if m.HomeEnabled() {
 		return m.pickNextViaHome(ctx, model, opts, tried)
 	}
 
-	if m.hasPluginScheduler() || !m.useSchedulerFastPath() {
+	if m.hasPluginScheduler() || !m.useSchedulerFastPath() || m.apiKeyRouteFromMetadata(opts.Metadata) != nil {
 		return m.pickNextMixedLegacy(ctx, providers, model, opts, tried)
 	}
 
@@ -5802,6 +6031,7 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 	}
 
 	disallowFreeAuth := disallowFreeAuthFromMetadata(opts.Metadata)
+	quotaSkips := newTokenQuotaSkips(model)
 	for {
 		selected, providerKey, errPick := m.scheduler.pickMixed(ctx, eligibleProviders, model, opts, tried)
 		if errPick != nil && model != "" && shouldRetrySchedulerPick(errPick) {
@@ -5809,12 +6039,12 @@ func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model s
 			selected, providerKey, errPick = m.scheduler.pickMixed(ctx, eligibleProviders, model, opts, tried)
 		}
 		if errPick != nil {
-			return nil, nil, "", errPick
+			return nil, nil, "", quotaSkips.pickError(errPick)
 		}
 		if selected == nil {
-			return nil, nil, "", &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+			return nil, nil, "", quotaSkips.pickError(&Error{Code: "auth_not_found", Message: "selector returned no auth"})
 		}
-		if disallowFreeAuth && isFreeCodexAuth(selected) {
+		if (disallowFreeAuth && isFreeCodexAuth(selected)) || quotaSkips.exhausted(selected, time.Now()) {
 			if tried == nil {
 				tried = make(map[string]struct{})
 			}
@@ -6865,7 +7095,11 @@ func (m *Manager) persist(ctx context.Context, auth *Auth) error {
 	if auth.Metadata == nil {
 		return nil
 	}
-	_, err := m.store.Save(ctx, auth)
+	persisted, err := secretPersistAuth(ctx, auth)
+	if err != nil {
+		return err
+	}
+	_, err = m.store.Save(ctx, persisted)
 	return err
 }
 
@@ -7281,6 +7515,7 @@ func (m *Manager) refreshAuthForRequest(ctx context.Context, id, failedAccessTok
 		log.Debugf("refresh canceled for %s, %s", auth.Provider, auth.ID)
 		return nil, err
 	}
+	metrics.ObserveAuthRefresh(auth.Provider, err)
 	log.Debugf("refreshed %s, %s, %v", auth.Provider, auth.ID, err)
 	now := time.Now()
 	if err != nil {
@@ -7374,6 +7609,12 @@ type RequestPreparer interface {
 	PrepareRequest(req *http.Request, auth *Auth) error
 }
 
+// MessageBatchExecutor is an optional interface for provider executors that can
+// proxy the Anthropic message batches API. path is relative to /v1/messages/batches.
+type MessageBatchExecutor interface {
+	MessageBatchRequest(ctx context.Context, auth *Auth, method, path, rawQuery string, body []byte, headers http.Header) (*http.Response, error)
+}
+
 func executorKeyFromAuth(auth *Auth) string {
 	if auth == nil {
 		return ""
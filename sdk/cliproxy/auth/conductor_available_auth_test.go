@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestManager_HasAvailableAuthForModel(t *testing.T) {
+	ctx := context.Background()
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	cooling := &Auth{
+		ID:       "available-auth-cooling",
+		Provider: "gemini",
+		ModelStates: map[string]*ModelState{
+			"gemini-2.5-pro": {Unavailable: true, NextRetryAfter: time.Now().Add(time.Hour)},
+		},
+	}
+	disabled := &Auth{ID: "available-auth-disabled", Provider: "claude", Disabled: true}
+	for _, auth := range []*Auth{cooling, disabled} {
+		if _, errRegister := manager.Register(ctx, auth); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+	}
+
+	if manager.HasAvailableAuthForModel("gemini", "gemini-2.5-pro") {
+		t.Fatal("gemini-2.5-pro reported available while its only auth is cooling down")
+	}
+	if !manager.HasAvailableAuthForModel("GEMINI", "gemini-2.5-flash") {
+		t.Fatal("gemini-2.5-flash reported unavailable, want cooldown to be model scoped")
+	}
+	if manager.HasAvailableAuthForModel("claude", "claude-sonnet-4-5") {
+		t.Fatal("claude reported available with only a disabled auth")
+	}
+	if manager.HasAvailableAuthForModel("codex", "gpt-5") {
+		t.Fatal("provider without auths reported available")
+	}
+}
+
+func TestExtractConversationID(t *testing.T) {
+	explicit := []byte(`{"metadata":{"session_id":"abc"},"messages":[{"role":"user","content":"hi"}]}`)
+	if got := ExtractConversationID(explicit); got != "session:abc" {
+		t.Fatalf("ExtractConversationID() = %q, want session:abc", got)
+	}
+
+	firstTurn := []byte(`{"messages":[{"role":"user","content":"plan a trip"}]}`)
+	laterTurn := []byte(`{"messages":[{"role":"user","content":"plan a trip"},{"role":"assistant","content":"where to?"},{"role":"user","content":"Lisbon"}]}`)
+	first := ExtractConversationID(firstTurn)
+	if first == "" || first != ExtractConversationID(laterTurn) {
+		t.Fatalf("conversation IDs differ across turns: %q vs %q", first, ExtractConversationID(laterTurn))
+	}
+	if got := ExtractConversationID(nil); got != "" {
+		t.Fatalf("ExtractConversationID(nil) = %q, want empty", got)
+	}
+}
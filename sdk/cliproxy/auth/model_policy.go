@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"strings"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+)
+
+// ModelPolicyConfigured reports whether any provider of the runtime config has a model policy.
+func (m *Manager) ModelPolicyConfigured() bool {
+	if m == nil {
+		return false
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	return cfg.ModelPolicyConfigured()
+}
+
+// ModelAllowed reports whether the model policy of provider lets requests made with apiKey
+// use model. Aliases are checked by their upstream models, so denying a model also denies
+// its aliases: deny matches the requested name or an upstream model, and allow must match
+// the upstream model when model is an alias.
+func (m *Manager) ModelAllowed(provider, model, apiKey string) bool {
+	if m == nil {
+		return true
+	}
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil {
+		return true
+	}
+	policy, ok := cfg.ModelPolicy(provider, apiKey)
+	if !ok {
+		return true
+	}
+	model = strings.TrimSpace(thinking.ParseSuffix(model).ModelName)
+	if model == "" {
+		return true
+	}
+	targets := cfg.UpstreamModelAliases(provider, model)
+	if len(targets) == 0 {
+		targets = []string{model}
+	}
+	for _, name := range append([]string{model}, targets...) {
+		if matchesModelPolicyPattern(policy.Deny, name) {
+			return false
+		}
+	}
+	if len(policy.Allow) == 0 {
+		return true
+	}
+	for _, name := range targets {
+		if matchesModelPolicyPattern(policy.Allow, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesModelPolicyPattern(patterns []string, model string) bool {
+	model = strings.ToLower(model)
+	for _, pattern := range patterns {
+		if util.MatchModelPattern(strings.ToLower(pattern), model) {
+			return true
+		}
+	}
+	return false
+}
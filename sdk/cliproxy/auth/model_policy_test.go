@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+)
+
+func TestManagerModelAllowed(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	if manager.ModelPolicyConfigured() {
+		t.Fatal("ModelPolicyConfigured() = true without a config")
+	}
+	manager.SetConfig(&internalconfig.Config{
+		Providers: map[string]internalconfig.ProviderHTTPConfig{
+			"antigravity": {ModelPolicy: internalconfig.ModelPolicy{Deny: []string{"Claude-Opus-*"}}},
+			"codex":       {ModelPolicy: internalconfig.ModelPolicy{Allow: []string{"gpt-5*"}, Deny: []string{"gpt-5-pro"}}},
+			"claude":      {ModelPolicy: internalconfig.ModelPolicy{Allow: []string{"claude-sonnet-*"}, APIKeys: []string{"team-a"}}},
+		},
+		OAuthModelAlias: map[string][]internalconfig.OAuthModelAlias{
+			"antigravity": {{Name: "claude-opus-4-5-thinking", Alias: "best"}},
+			"codex":       {{Name: "gpt-5-codex", Alias: "coder"}},
+		},
+	})
+	if !manager.ModelPolicyConfigured() {
+		t.Fatal("ModelPolicyConfigured() = false, want true")
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		apiKey   string
+		want     bool
+	}{
+		{"deny matches case-insensitively", "antigravity", "claude-opus-4-5-thinking", "", false},
+		{"deny ignores thinking suffix", "antigravity", "claude-opus-4-5-thinking(8192)", "", false},
+		{"deny covers alias of denied model", "antigravity", "best", "", false},
+		{"model outside deny", "antigravity", "gemini-3-pro-high", "", true},
+		{"allow matches", "codex", "gpt-5.1", "", true},
+		{"deny wins over allow", "codex", "gpt-5-pro", "", false},
+		{"allow checks alias target", "codex", "coder", "", true},
+		{"not in allow", "codex", "o3", "", false},
+		{"scoped policy applies to key", "claude", "claude-opus-4-1", "team-a", false},
+		{"scoped policy skips other keys", "claude", "claude-opus-4-1", "team-b", true},
+		{"provider without policy", "gemini", "gemini-2.5-pro", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := manager.ModelAllowed(tt.provider, tt.model, tt.apiKey); got != tt.want {
+				t.Fatalf("ModelAllowed(%q, %q, %q) = %v, want %v", tt.provider, tt.model, tt.apiKey, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+// Error codes reported when a per-model time limit expires.
+const (
+	// ModelTimeoutCodeRequest marks a non-streaming request that ran past its deadline.
+	ModelTimeoutCodeRequest = "request_timeout"
+	// ModelTimeoutCodeStream marks a stream that ran past its maximum duration.
+	ModelTimeoutCodeStream = "deadline_exceeded"
+)
+
+// ModelTimeoutError reports that a request ran past the time limit of its model
+// (model-timeouts, or the models.json default). The message is a JSON error envelope
+// so handlers relay the code to clients unchanged.
+type ModelTimeoutError struct {
+	Model   string
+	Timeout time.Duration
+	Stream  bool
+}
+
+// Code returns the machine readable error code.
+func (e *ModelTimeoutError) Code() string {
+	if e.Stream {
+		return ModelTimeoutCodeStream
+	}
+	return ModelTimeoutCodeRequest
+}
+
+func (e *ModelTimeoutError) Error() string {
+	kind := "request"
+	if e.Stream {
+		kind = "stream"
+	}
+	message := fmt.Sprintf("%s for model %s exceeded its time limit of %s", kind, e.Model, e.Timeout)
+	data, err := json.Marshal(map[string]any{"error": map[string]any{
+		"message":         message,
+		"type":            "timeout_error",
+		"code":            e.Code(),
+		"timeout_seconds": e.Timeout.Seconds(),
+	}})
+	if err != nil {
+		return fmt.Sprintf(`{"error":{"message":%q,"type":"timeout_error","code":"%s"}}`, message, e.Code())
+	}
+	return string(data)
+}
+
+// StatusCode returns 504: the upstream did not answer within the allowed time.
+func (e *ModelTimeoutError) StatusCode() int {
+	return http.StatusGatewayTimeout
+}
+
+// IsModelTimeout reports whether err was caused by a per-model time limit.
+func IsModelTimeout(err error) bool {
+	var timeoutErr *ModelTimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// modelTimeout resolves the time limit of a request. Config entries are matched against
+// the requested model and then the routed model, the first matching entry winning; models
+// without an entry use their registry default. It returns 0 when the model has no limit.
+func (m *Manager) modelTimeout(req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) (string, time.Duration) {
+	routeModel := authSelectionModelFromOptions(opts, req.Model)
+	requested := requestedModelAliasFromOptions(opts, routeModel)
+	if cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config); cfg != nil {
+		for _, entry := range cfg.ModelTimeouts {
+			for _, model := range []string{requested, routeModel} {
+				if model == "" || !util.MatchModelPattern(entry.Name, model) {
+					continue
+				}
+				if stream {
+					return model, entry.StreamMaxDuration()
+				}
+				return model, entry.Timeout()
+			}
+		}
+	}
+	for _, model := range []string{routeModel, requested} {
+		if model == "" {
+			continue
+		}
+		if timeout := registry.ModelDefaultTimeout(model); timeout > 0 {
+			return model, timeout
+		}
+	}
+	return requested, 0
+}
+
+// withModelTimeout bounds ctx by the time limit of the request's model. limited is false
+// when the model has no limit; the returned cancel func must be called either way.
+func (m *Manager) withModelTimeout(ctx context.Context, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, stream bool) (_ context.Context, cancel context.CancelFunc, limited bool) {
+	model, timeout := m.modelTimeout(req, opts, stream)
+	if timeout <= 0 {
+		return ctx, func() {}, false
+	}
+	ctx, cancel = context.WithTimeoutCause(ctx, timeout, &ModelTimeoutError{Model: model, Timeout: timeout, Stream: stream})
+	return ctx, cancel, true
+}
+
+// modelTimeoutCause returns the time limit error when ctx ended because of it, or nil.
+func modelTimeoutCause(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); IsModelTimeout(cause) {
+		return cause
+	}
+	return nil
+}
+
+// limitStreamDuration forwards the chunks of result until the stream's time limit ends
+// it with a ModelTimeoutError chunk. ctx is the limited context derived from parent, the
+// request context; cancel releases it once the stream is over. Chunks the upstream still
+// sends after the limit, or after the client behind parent went away, are drained and
+// dropped.
+func limitStreamDuration(parent, ctx context.Context, cancel context.CancelFunc, result *cliproxyexecutor.StreamResult) *cliproxyexecutor.StreamResult {
+	if result == nil || result.Chunks == nil {
+		cancel()
+		return result
+	}
+	in := result.Chunks
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		defer cancel()
+		send := func(chunk cliproxyexecutor.StreamChunk) bool {
+			select {
+			case out <- chunk:
+				return true
+			case <-parent.Done():
+				return false
+			}
+		}
+		done := ctx.Done()
+		for {
+			select {
+			case chunk, ok := <-in:
+				if !ok {
+					return
+				}
+				if !send(chunk) {
+					drainStreamChunks(in)
+					return
+				}
+			case <-done:
+				if errTimeout := modelTimeoutCause(ctx); errTimeout != nil {
+					drainStreamChunks(in)
+					send(cliproxyexecutor.StreamChunk{Err: errTimeout})
+					return
+				}
+				// The client went away: keep forwarding until the upstream notices, and
+				// stop as soon as a chunk finds nobody reading.
+				done = nil
+			}
+		}
+	}()
+	return &cliproxyexecutor.StreamResult{Headers: result.Headers, Chunks: out}
+}
+
+// drainStreamChunks discards the rest of ch in the background so its producer can finish.
+func drainStreamChunks(ch <-chan cliproxyexecutor.StreamChunk) {
+	go func() {
+		for range ch {
+		}
+	}()
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	modelTimeoutTestProvider = "model-timeout-test"
+	// modelTimeoutTestDelay is how long the test upstream takes to answer.
+	modelTimeoutTestDelay = 1500 * time.Millisecond
+)
+
+// modelTimeoutTestExecutor answers after modelTimeoutTestDelay unless the request context
+// ends first. Streams send one chunk right away and the second one after the delay.
+type modelTimeoutTestExecutor struct{}
+
+func (modelTimeoutTestExecutor) Identifier() string { return modelTimeoutTestProvider }
+
+func (modelTimeoutTestExecutor) Execute(ctx context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	select {
+	case <-time.After(modelTimeoutTestDelay):
+		return cliproxyexecutor.Response{Payload: []byte(req.Model)}, nil
+	case <-ctx.Done():
+		return cliproxyexecutor.Response{}, ctx.Err()
+	}
+}
+
+func (modelTimeoutTestExecutor) ExecuteStream(ctx context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	ch := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(ch)
+		ch <- cliproxyexecutor.StreamChunk{Payload: []byte("first")}
+		select {
+		case <-time.After(modelTimeoutTestDelay):
+			ch <- cliproxyexecutor.StreamChunk{Payload: []byte("second")}
+		case <-ctx.Done():
+			ch <- cliproxyexecutor.StreamChunk{Err: ctx.Err()}
+		}
+	}()
+	return &cliproxyexecutor.StreamResult{Chunks: ch}, nil
+}
+
+func (modelTimeoutTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (modelTimeoutTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+func (modelTimeoutTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+func newModelTimeoutTestManager(t *testing.T, timeouts []internalconfig.ModelTimeout) *Manager {
+	t.Helper()
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{ModelTimeouts: timeouts})
+	m.RegisterExecutor(modelTimeoutTestExecutor{})
+	auth := &Auth{ID: t.Name() + "-auth", Provider: modelTimeoutTestProvider, Status: StatusActive}
+	if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(auth.ID, modelTimeoutTestProvider, []*registry.ModelInfo{
+		{ID: "timeout-fast-model"},
+		{ID: "timeout-slow-model"},
+		{ID: "timeout-default-model", Config: &registry.ModelConfig{TimeoutSeconds: 1}},
+	})
+	t.Cleanup(func() { reg.UnregisterClient(auth.ID) })
+	return m
+}
+
+func TestManagerExecuteEnforcesPerModelTimeout(t *testing.T) {
+	m := newModelTimeoutTestManager(t, []internalconfig.ModelTimeout{
+		{Name: "timeout-fast-*", TimeoutSeconds: 1},
+		{Name: "timeout-slow-*", TimeoutSeconds: 60},
+	})
+
+	tests := []struct {
+		model    string
+		timedOut bool
+	}{
+		{model: "timeout-fast-model", timedOut: true},
+		{model: "timeout-slow-model"},
+		{model: "timeout-default-model", timedOut: true},
+	}
+	var wg sync.WaitGroup
+	for _, tt := range tests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			resp, errExec := m.Execute(context.Background(), []string{modelTimeoutTestProvider}, cliproxyexecutor.Request{Model: tt.model}, cliproxyexecutor.Options{})
+			if !tt.timedOut {
+				if errExec != nil || string(resp.Payload) != tt.model {
+					t.Errorf("%s: Execute() = %q, %v; want a response", tt.model, resp.Payload, errExec)
+				}
+				return
+			}
+			if !IsModelTimeout(errExec) {
+				t.Errorf("%s: Execute() error = %v, want a model timeout", tt.model, errExec)
+				return
+			}
+			if elapsed := time.Since(start); elapsed >= modelTimeoutTestDelay {
+				t.Errorf("%s: request took %s, want it cut at the 1s limit", tt.model, elapsed)
+			}
+			if status := statusCodeFromError(errExec); status != http.StatusGatewayTimeout {
+				t.Errorf("%s: status = %d, want 504", tt.model, status)
+			}
+			if code := gjson.Get(errExec.Error(), "error.code").String(); code != ModelTimeoutCodeRequest {
+				t.Errorf("%s: error code = %q, want %q", tt.model, code, ModelTimeoutCodeRequest)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestManagerExecuteStreamEnforcesMaxDuration(t *testing.T) {
+	m := newModelTimeoutTestManager(t, []internalconfig.ModelTimeout{
+		{Name: "timeout-fast-model", TimeoutSeconds: 60, StreamMaxDurationSeconds: 1},
+		{Name: "timeout-slow-model", TimeoutSeconds: 60},
+		// Config entries override the registry default of the model.
+		{Name: "timeout-default-model", TimeoutSeconds: 60},
+	})
+
+	tests := []struct {
+		model    string
+		timedOut bool
+	}{
+		{model: "timeout-fast-model", timedOut: true},
+		{model: "timeout-slow-model"},
+		{model: "timeout-default-model"},
+	}
+	var wg sync.WaitGroup
+	for _, tt := range tests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, errStream := m.ExecuteStream(context.Background(), []string{modelTimeoutTestProvider}, cliproxyexecutor.Request{Model: tt.model}, cliproxyexecutor.Options{})
+			if errStream != nil {
+				t.Errorf("%s: ExecuteStream() error = %v", tt.model, errStream)
+				return
+			}
+			var payloads []string
+			var streamErr error
+			for chunk := range result.Chunks {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				payloads = append(payloads, string(chunk.Payload))
+			}
+			if !tt.timedOut {
+				if streamErr != nil || len(payloads) != 2 {
+					t.Errorf("%s: stream = %q, %v; want both chunks", tt.model, payloads, streamErr)
+				}
+				return
+			}
+			if len(payloads) != 1 || payloads[0] != "first" {
+				t.Errorf("%s: payloads = %q, want the first chunk only", tt.model, payloads)
+			}
+			if !IsModelTimeout(streamErr) {
+				t.Errorf("%s: stream error = %v, want a model timeout", tt.model, streamErr)
+				return
+			}
+			if code := gjson.Get(streamErr.Error(), "error.code").String(); code != ModelTimeoutCodeStream {
+				t.Errorf("%s: error code = %q, want %q", tt.model, code, ModelTimeoutCodeStream)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLimitStreamDurationStopsForCancelledConsumer(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := context.WithTimeout(parent, time.Minute)
+	in := make(chan cliproxyexecutor.StreamChunk)
+	producerDone := make(chan struct{})
+	go func() {
+		defer close(producerDone)
+		defer close(in)
+		for i := 0; i < 5; i++ {
+			in <- cliproxyexecutor.StreamChunk{Payload: []byte("chunk")}
+		}
+	}()
+
+	result := limitStreamDuration(parent, ctx, cancel, &cliproxyexecutor.StreamResult{Chunks: in})
+	if chunk := <-result.Chunks; string(chunk.Payload) != "chunk" {
+		t.Fatalf("first chunk = %q, want chunk", chunk.Payload)
+	}
+	// The consumer goes away without reading the rest of the stream.
+	cancelParent()
+
+	select {
+	case <-producerDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("producer still blocked after the consumer was cancelled")
+	}
+}
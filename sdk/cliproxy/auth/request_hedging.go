@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+// errHedgeLost cancels the attempts of a hedged request that did not win.
+var errHedgeLost = errors.New("request hedging: another attempt completed first")
+
+// hedgeClaims records the auths used by the attempts of one hedged request so
+// each attempt runs on a different credential.
+type hedgeClaims struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+// claim reserves authID and reports whether it was still free. A nil
+// hedgeClaims allows every auth.
+func (c *hedgeClaims) claim(authID string) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, taken := c.ids[authID]; taken {
+		return false
+	}
+	c.ids[authID] = struct{}{}
+	return true
+}
+
+// hedgeAttempt is one copy of a hedged request.
+type hedgeAttempt struct {
+	cancel context.CancelCauseFunc
+	hold   *coreusage.PublishHold
+	// authID and authIndex are written by the attempt's selected-auth
+	// callbacks and read once its result is received.
+	authID    string
+	authIndex string
+}
+
+type hedgeResult struct {
+	attempt *hedgeAttempt
+	resp    cliproxyexecutor.Response
+	err     error
+}
+
+// requestHedgeDelay reports whether a non-streaming request may be hedged and
+// after how long. Hedging needs the request-hedging config, a client
+// Idempotency-Key marking the request as safe to send twice, no pinned auth,
+// and every provider listed when the config limits providers.
+func (m *Manager) requestHedgeDelay(providers []string, opts cliproxyexecutor.Options) (time.Duration, bool) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.RequestHedging.Enabled {
+		return 0, false
+	}
+	if stringMetadataValue(opts.Metadata, cliproxyexecutor.IdempotencyKeyMetadataKey) == "" {
+		return 0, false
+	}
+	if pinnedAuthIDFromMetadata(opts.Metadata) != "" {
+		return 0, false
+	}
+	if len(cfg.RequestHedging.Providers) > 0 {
+		allowed := make(map[string]struct{}, len(cfg.RequestHedging.Providers))
+		for _, provider := range cfg.RequestHedging.Providers {
+			allowed[strings.ToLower(strings.TrimSpace(provider))] = struct{}{}
+		}
+		for _, provider := range providers {
+			if _, ok := allowed[strings.ToLower(strings.TrimSpace(provider))]; !ok {
+				return 0, false
+			}
+		}
+	}
+	return cfg.RequestHedging.Delay(), true
+}
+
+// executeHedged runs a non-streaming request and, if it has not completed after
+// delay, starts a second copy on another auth. The first success wins: the
+// other attempt is cancelled and its usage discarded, so only the winner is
+// accounted. A failure before the hedge starts is returned as-is; once both
+// attempts run, an error is only returned when both fail.
+func (m *Manager) executeHedged(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, maxRetryCredentials int, delay time.Duration) (cliproxyexecutor.Response, error) {
+	opts = ensureRequestedModelMetadata(opts, authSelectionModelFromOptions(opts, req.Model))
+	claims := &hedgeClaims{ids: make(map[string]struct{})}
+	results := make(chan hedgeResult, 2)
+	var attempts []*hedgeAttempt
+	start := func() {
+		attemptCtx, cancel := context.WithCancelCause(ctx)
+		attemptCtx, hold := coreusage.WithPublishHold(attemptCtx)
+		attempt := &hedgeAttempt{cancel: cancel, hold: hold}
+		attemptOpts := hedgeAttemptOptions(opts, attempt)
+		attempts = append(attempts, attempt)
+		go func() {
+			resp, errExec := m.executeMixedOnceClaiming(attemptCtx, providers, req, attemptOpts, maxRetryCredentials, claims)
+			results <- hedgeResult{attempt: attempt, resp: resp, err: errExec}
+		}()
+	}
+	start()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending := 1
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			if len(attempts) == 1 && pending == 1 {
+				logEntryWithRequestID(ctx).Debugf("request hedging: no response after %s, sending a second attempt", delay)
+				start()
+				pending++
+			}
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				for _, attempt := range attempts {
+					if attempt == result.attempt {
+						continue
+					}
+					attempt.hold.Discard()
+					attempt.cancel(errHedgeLost)
+				}
+				result.attempt.hold.Release()
+				result.attempt.cancel(nil)
+				publishHedgeWinner(opts.Metadata, result.attempt)
+				return result.resp, nil
+			}
+			lastErr = result.err
+			if pending > 0 {
+				continue
+			}
+			// Every attempt failed; their failures are real upstream requests.
+			for _, attempt := range attempts {
+				attempt.hold.Release()
+				attempt.cancel(nil)
+			}
+			if len(attempts) > 0 {
+				publishHedgeWinner(opts.Metadata, result.attempt)
+			}
+			return cliproxyexecutor.Response{}, lastErr
+		}
+	}
+}
+
+// hedgeAttemptOptions gives an attempt its own metadata so concurrent attempts
+// never share a map. The client's selected-auth callbacks are replaced by ones
+// recording the attempt's auth; publishHedgeWinner reports the winner later.
+func hedgeAttemptOptions(opts cliproxyexecutor.Options, attempt *hedgeAttempt) cliproxyexecutor.Options {
+	meta := make(map[string]any, len(opts.Metadata)+2)
+	for k, v := range opts.Metadata {
+		meta[k] = v
+	}
+	meta[cliproxyexecutor.SelectedAuthCallbackMetadataKey] = func(authID string) { attempt.authID = authID }
+	meta[cliproxyexecutor.SelectedAuthIndexCallbackMetadataKey] = func(authIndex string) { attempt.authIndex = authIndex }
+	opts.Metadata = meta
+	return opts
+}
+
+// publishHedgeWinner reports the auth of the attempt that produced the result
+// through the client's selected-auth metadata and callbacks.
+func publishHedgeWinner(meta map[string]any, attempt *hedgeAttempt) {
+	if len(meta) == 0 || attempt == nil {
+		return
+	}
+	if attempt.authID != "" {
+		meta[cliproxyexecutor.SelectedAuthMetadataKey] = attempt.authID
+		if callback, ok := meta[cliproxyexecutor.SelectedAuthCallbackMetadataKey].(func(string)); ok && callback != nil {
+			callback(attempt.authID)
+		}
+	}
+	if attempt.authIndex != "" {
+		meta[cliproxyexecutor.SelectedAuthIndexMetadataKey] = attempt.authIndex
+		if callback, ok := meta[cliproxyexecutor.SelectedAuthIndexCallbackMetadataKey].(func(string)); ok && callback != nil {
+			callback(attempt.authIndex)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
+)
+
+const hedgingTestProvider = "hedging-test"
+
+// hedgingTestExecutor posts to the auth's base_url and publishes a usage record
+// for every attempt, failures included, like the real executors do.
+type hedgingTestExecutor struct{}
+
+func (hedgingTestExecutor) Identifier() string { return hedgingTestProvider }
+
+func (hedgingTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	httpReq, errReq := http.NewRequestWithContext(ctx, http.MethodPost, auth.Attributes["base_url"], nil)
+	if errReq != nil {
+		return cliproxyexecutor.Response{}, errReq
+	}
+	httpResp, errDo := http.DefaultClient.Do(httpReq)
+	if errDo != nil {
+		coreusage.PublishRecord(ctx, coreusage.Record{Provider: hedgingTestProvider, AuthID: auth.ID, Failed: true})
+		return cliproxyexecutor.Response{}, errDo
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+	body, errRead := io.ReadAll(httpResp.Body)
+	if errRead != nil {
+		coreusage.PublishRecord(ctx, coreusage.Record{Provider: hedgingTestProvider, AuthID: auth.ID, Failed: true})
+		return cliproxyexecutor.Response{}, errRead
+	}
+	coreusage.PublishRecord(ctx, coreusage.Record{Provider: hedgingTestProvider, AuthID: auth.ID})
+	return cliproxyexecutor.Response{Payload: body}, nil
+}
+
+func (hedgingTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+func (hedgingTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (hedgingTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+func (hedgingTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+type hedgingUsageRecorder struct {
+	mu      sync.Mutex
+	records []coreusage.Record
+}
+
+func (r *hedgingUsageRecorder) HandleUsage(_ context.Context, record coreusage.Record) {
+	if record.Provider != hedgingTestProvider {
+		return
+	}
+	r.mu.Lock()
+	r.records = append(r.records, record)
+	r.mu.Unlock()
+}
+
+func (r *hedgingUsageRecorder) snapshot() []coreusage.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]coreusage.Record(nil), r.records...)
+}
+
+// newHedgingTestManager registers one auth per upstream URL.
+func newHedgingTestManager(t *testing.T, hedging internalconfig.RequestHedgingConfig, upstreams ...string) *Manager {
+	t.Helper()
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{RequestHedging: hedging})
+	m.RegisterExecutor(hedgingTestExecutor{})
+	reg := registry.GetGlobalRegistry()
+	for i, upstream := range upstreams {
+		auth := &Auth{
+			ID:         t.Name() + "-auth-" + string(rune('a'+i)),
+			Provider:   hedgingTestProvider,
+			Status:     StatusActive,
+			Attributes: map[string]string{"base_url": upstream},
+		}
+		if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+		reg.RegisterClient(auth.ID, hedgingTestProvider, []*registry.ModelInfo{{ID: "hedging-model"}})
+		t.Cleanup(func() { reg.UnregisterClient(auth.ID) })
+	}
+	return m
+}
+
+func hedgingTestOptions(idempotencyKey string) cliproxyexecutor.Options {
+	meta := map[string]any{}
+	if idempotencyKey != "" {
+		meta[cliproxyexecutor.IdempotencyKeyMetadataKey] = idempotencyKey
+	}
+	return cliproxyexecutor.Options{Metadata: meta}
+}
+
+func TestManagerExecuteHedgesSlowRequestAndPublishesWinnerUsageOnly(t *testing.T) {
+	recorder := &hedgingUsageRecorder{}
+	coreusage.RegisterNamedPlugin("request-hedging-test", recorder)
+
+	// Whichever upstream the first attempt lands on stalls until the attempt is
+	// cancelled; the hedge on the other upstream answers right away.
+	var arrivals atomic.Int32
+	loserCancelled := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if arrivals.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				close(loserCancelled)
+			case <-time.After(10 * time.Second):
+			}
+			_, _ = w.Write([]byte("slow"))
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	})
+	upstreamA := httptest.NewServer(handler)
+	defer upstreamA.Close()
+	upstreamB := httptest.NewServer(handler)
+	defer upstreamB.Close()
+
+	m := newHedgingTestManager(t, internalconfig.RequestHedgingConfig{Enabled: true, DelaySeconds: 1}, upstreamA.URL, upstreamB.URL)
+	var selected []string
+	opts := hedgingTestOptions("idem-hedge")
+	opts.Metadata[cliproxyexecutor.SelectedAuthCallbackMetadataKey] = func(authID string) { selected = append(selected, authID) }
+
+	started := time.Now()
+	resp, errExec := m.Execute(context.Background(), []string{hedgingTestProvider}, cliproxyexecutor.Request{Model: "hedging-model"}, opts)
+	if errExec != nil {
+		t.Fatalf("Execute() error = %v", errExec)
+	}
+	if string(resp.Payload) != "fast" {
+		t.Fatalf("payload = %q, want the hedged attempt's response", resp.Payload)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("Execute() took %s, want the hedge to answer soon after the delay", elapsed)
+	}
+	if got := arrivals.Load(); got != 2 {
+		t.Fatalf("upstream requests = %d, want 2", got)
+	}
+	select {
+	case <-loserCancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow attempt was not cancelled")
+	}
+	if len(selected) != 1 {
+		t.Fatalf("selected auth callbacks = %v, want only the winner reported", selected)
+	}
+
+	// Give the usage queue time to deliver anything the loser published.
+	time.Sleep(300 * time.Millisecond)
+	records := recorder.snapshot()
+	if len(records) != 1 {
+		t.Fatalf("usage records = %+v, want exactly one", records)
+	}
+	if records[0].Failed || records[0].AuthID != selected[0] {
+		t.Fatalf("usage record = %+v, want the winner %q's success", records[0], selected[0])
+	}
+}
+
+func TestManagerExecuteDoesNotHedgeWithoutIdempotencyKey(t *testing.T) {
+	var arrivals atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrivals.Add(1)
+		time.Sleep(1500 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	m := newHedgingTestManager(t, internalconfig.RequestHedgingConfig{Enabled: true, DelaySeconds: 1}, upstream.URL, upstream.URL)
+	resp, errExec := m.Execute(context.Background(), []string{hedgingTestProvider}, cliproxyexecutor.Request{Model: "hedging-model"}, hedgingTestOptions(""))
+	if errExec != nil {
+		t.Fatalf("Execute() error = %v", errExec)
+	}
+	if string(resp.Payload) != "ok" {
+		t.Fatalf("payload = %q, want ok", resp.Payload)
+	}
+	if got := arrivals.Load(); got != 1 {
+		t.Fatalf("upstream requests = %d, want 1 without an Idempotency-Key", got)
+	}
+}
+
+func TestRequestHedgeDelay(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	keyed := hedgingTestOptions("idem")
+
+	if _, ok := m.requestHedgeDelay([]string{"antigravity"}, keyed); ok {
+		t.Fatal("hedging enabled without config")
+	}
+
+	m.SetConfig(&internalconfig.Config{RequestHedging: internalconfig.RequestHedgingConfig{Enabled: true, Providers: []string{"Antigravity"}}})
+	if delay, ok := m.requestHedgeDelay([]string{"antigravity"}, keyed); !ok || delay != internalconfig.DefaultRequestHedgingDelaySeconds*time.Second {
+		t.Fatalf("requestHedgeDelay() = %s, %v; want default delay for a listed provider", delay, ok)
+	}
+	if _, ok := m.requestHedgeDelay([]string{"codex"}, keyed); ok {
+		t.Fatal("hedged a provider that is not listed")
+	}
+	pinned := hedgingTestOptions("idem")
+	pinned.Metadata[cliproxyexecutor.PinnedAuthMetadataKey] = "auth-1"
+	if _, ok := m.requestHedgeDelay([]string{"antigravity"}, pinned); ok {
+		t.Fatal("hedged a request pinned to one auth")
+	}
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+// requestQueueError is returned when a request gets no upstream slot under the
+// request-priority concurrency caps, because the queue is full or the wait timed out.
+type requestQueueError struct {
+	code     string
+	message  string
+	priority string
+}
+
+func (e *requestQueueError) Error() string {
+	message := e.message
+	if e.priority != "" {
+		message = fmt.Sprintf("%s (%s priority)", message, e.priority)
+	}
+	errorBody := map[string]any{
+		"code":    e.code,
+		"message": message,
+	}
+	if e.priority != "" {
+		errorBody["priority"] = e.priority
+	}
+	data, err := json.Marshal(map[string]any{"error": errorBody})
+	if err != nil {
+		return fmt.Sprintf(`{"error":{"code":"%s","message":"%s"}}`, e.code, message)
+	}
+	return string(data)
+}
+
+func (e *requestQueueError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+func (e *requestQueueError) Headers() http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	return headers
+}
+
+// priorityWaiter is a request queued for a concurrency slot.
+type priorityWaiter struct {
+	rank    int
+	seq     uint64
+	index   int
+	granted bool
+	ready   chan struct{}
+}
+
+// priorityWaiterHeap orders waiters by rank, then by arrival.
+type priorityWaiterHeap []*priorityWaiter
+
+func (h priorityWaiterHeap) Len() int { return len(h) }
+func (h priorityWaiterHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+func (h priorityWaiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *priorityWaiterHeap) Push(x any) {
+	w := x.(*priorityWaiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+func (h *priorityWaiterHeap) Pop() any {
+	old := *h
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	w.index = -1
+	return w
+}
+
+// prioritySemaphore tracks the in-flight requests and waiters of one concurrency cap.
+type prioritySemaphore struct {
+	limit    int
+	inFlight int
+	waiters  priorityWaiterHeap
+}
+
+// priorityLimiter holds the concurrency caps keyed by provider or auth. The zero value is
+// ready to use.
+type priorityLimiter struct {
+	mu    sync.Mutex
+	seq   uint64
+	slots map[string]*prioritySemaphore
+}
+
+// acquire takes a slot of the cap named key, waiting in priority order while limit slots
+// are in use. It fails when maxDepth requests already wait, when ctx ends, or at deadline.
+func (l *priorityLimiter) acquire(ctx context.Context, key string, limit, rank, maxDepth int, deadline time.Time) (func(), error) {
+	l.mu.Lock()
+	if l.slots == nil {
+		l.slots = make(map[string]*prioritySemaphore)
+	}
+	sem := l.slots[key]
+	if sem == nil {
+		sem = &prioritySemaphore{}
+		l.slots[key] = sem
+	}
+	sem.limit = limit
+	if sem.inFlight < sem.limit && sem.waiters.Len() == 0 {
+		sem.inFlight++
+		l.mu.Unlock()
+		return l.releaser(key), nil
+	}
+	if sem.waiters.Len() >= maxDepth {
+		l.mu.Unlock()
+		return nil, &requestQueueError{code: "queue_full", message: "too many requests are waiting for an upstream slot"}
+	}
+	l.seq++
+	waiter := &priorityWaiter{rank: rank, seq: l.seq, ready: make(chan struct{})}
+	heap.Push(&sem.waiters, waiter)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	var errWait error
+	select {
+	case <-waiter.ready:
+		return l.releaser(key), nil
+	case <-ctx.Done():
+		errWait = ctx.Err()
+	case <-timer.C:
+		errWait = &requestQueueError{code: "queue_timeout", message: "timed out waiting for an upstream slot"}
+	}
+
+	l.mu.Lock()
+	if waiter.granted {
+		// The slot was handed over while the wait ended; give it back.
+		l.mu.Unlock()
+		l.releaser(key)()
+		return nil, errWait
+	}
+	heap.Remove(&sem.waiters, waiter.index)
+	if sem.inFlight == 0 && sem.waiters.Len() == 0 {
+		delete(l.slots, key)
+	}
+	l.mu.Unlock()
+	return nil, errWait
+}
+
+// releaser returns a function that frees one slot of key and hands it to the first
+// waiter. Calling it more than once frees the slot only once.
+func (l *priorityLimiter) releaser(key string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			sem := l.slots[key]
+			if sem == nil {
+				return
+			}
+			sem.inFlight--
+			for sem.inFlight < sem.limit && sem.waiters.Len() > 0 {
+				waiter := heap.Pop(&sem.waiters).(*priorityWaiter)
+				waiter.granted = true
+				sem.inFlight++
+				close(waiter.ready)
+			}
+			if sem.inFlight == 0 && sem.waiters.Len() == 0 {
+				delete(l.slots, key)
+			}
+		})
+	}
+}
+
+// queued returns the number of requests waiting for the cap named key.
+func (l *priorityLimiter) queued(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if sem := l.slots[key]; sem != nil {
+		return sem.waiters.Len()
+	}
+	return 0
+}
+
+// inFlight returns the number of requests holding a slot of the cap named key.
+func (l *priorityLimiter) inFlight(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if sem := l.slots[key]; sem != nil {
+		return sem.inFlight
+	}
+	return 0
+}
+
+func priorityLimiterProviderKey(provider string) string { return "provider:" + provider }
+func priorityLimiterAuthKey(authID string) string       { return "auth:" + authID }
+
+// requestPriorityFromOptions returns the priority class stored in the request metadata,
+// defaulting to normal.
+func requestPriorityFromOptions(opts cliproxyexecutor.Options) string {
+	if raw, ok := opts.Metadata[cliproxyexecutor.PriorityMetadataKey].(string); ok {
+		if priority, valid := internalconfig.NormalizeRequestPriority(raw); valid {
+			return priority
+		}
+	}
+	return internalconfig.RequestPriorityNormal
+}
+
+// requestSlot holds the concurrency slots of one upstream request. A nil slot holds nothing.
+type requestSlot struct {
+	releases []func()
+}
+
+// Release frees the held slots; later calls do nothing.
+func (s *requestSlot) Release() {
+	if s == nil {
+		return
+	}
+	for i := len(s.releases) - 1; i >= 0; i-- {
+		s.releases[i]()
+	}
+}
+
+type requestQueueDeadlineKey struct{}
+
+// requestQueueDeadline is the queue deadline shared by every credential attempt of one
+// request. It starts with the first attempt.
+type requestQueueDeadline struct {
+	once sync.Once
+	at   time.Time
+}
+
+// withRequestQueueDeadline gives ctx a queue deadline for the request, so the queue
+// timeout of its priority class bounds the total wait across credentials.
+func withRequestQueueDeadline(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestQueueDeadlineKey{}).(*requestQueueDeadline); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestQueueDeadlineKey{}, &requestQueueDeadline{})
+}
+
+// queueDeadline returns the queue deadline of the request in ctx, starting it timeout
+// from now on first use. Without a request deadline in ctx it starts a fresh one.
+func queueDeadline(ctx context.Context, timeout time.Duration) time.Time {
+	shared, ok := ctx.Value(requestQueueDeadlineKey{}).(*requestQueueDeadline)
+	if !ok {
+		return time.Now().Add(timeout)
+	}
+	shared.once.Do(func() { shared.at = time.Now().Add(timeout) })
+	return shared.at
+}
+
+// acquireRequestSlot waits for a slot under the configured provider and auth concurrency
+// caps, in that order. It returns a nil slot when no cap is configured, and a
+// *requestQueueError when the request gets no slot. The queue deadline is shared by
+// all attempts of the request, so moving on to another credential does not restart it.
+func (m *Manager) acquireRequestSlot(ctx context.Context, auth *Auth, provider string, opts cliproxyexecutor.Options) (*requestSlot, error) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.RequestPriority.Enabled() || auth == nil {
+		return nil, nil
+	}
+	priorityCfg := cfg.RequestPriority
+	priority := requestPriorityFromOptions(opts)
+	rank := internalconfig.RequestPriorityRank(priority)
+	deadline := queueDeadline(ctx, priorityCfg.QueueTimeout(priority))
+
+	caps := []struct {
+		key   string
+		limit int
+	}{
+		{priorityLimiterProviderKey(strings.ToLower(provider)), priorityCfg.MaxConcurrentPerProvider},
+		{priorityLimiterAuthKey(auth.ID), priorityCfg.MaxConcurrentPerAuth},
+	}
+	slot := &requestSlot{}
+	for _, c := range caps {
+		if c.limit <= 0 {
+			continue
+		}
+		release, errAcquire := m.priorityLimiter.acquire(ctx, c.key, c.limit, rank, priorityCfg.QueueDepth(), deadline)
+		if errAcquire != nil {
+			slot.Release()
+			if queueErr, ok := errAcquire.(*requestQueueError); ok {
+				logEntryWithRequestID(ctx).Debugf("request priority: %s request for %s rejected: %s", priority, c.key, queueErr.code)
+				queueErr.priority = priority
+			}
+			return nil, errAcquire
+		}
+		slot.releases = append(slot.releases, release)
+	}
+	return slot, nil
+}
@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executionregistry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+// priorityBlockingExecutor records the payload of each request and holds it until release
+// receives a value.
+type priorityBlockingExecutor struct {
+	schedulerProviderTestExecutor
+	started chan string
+	release chan struct{}
+
+	mu    sync.Mutex
+	order []string
+	auths []string
+}
+
+func (e *priorityBlockingExecutor) Execute(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.mu.Lock()
+	e.order = append(e.order, string(req.Payload))
+	e.auths = append(e.auths, auth.ID)
+	e.mu.Unlock()
+	e.started <- string(req.Payload)
+	<-e.release
+	return cliproxyexecutor.Response{Payload: req.Payload}, nil
+}
+
+func newPriorityTestManager(t *testing.T, priorityCfg internalconfig.RequestPriorityConfig) (*Manager, *priorityBlockingExecutor) {
+	t.Helper()
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	cfg := &internalconfig.Config{}
+	cfg.RequestPriority = priorityCfg
+	manager.SetConfig(cfg)
+	executor := &priorityBlockingExecutor{
+		schedulerProviderTestExecutor: schedulerProviderTestExecutor{provider: "codex"},
+		started:                       make(chan string, 8),
+		release:                       make(chan struct{}),
+	}
+	manager.RegisterExecutor(executor)
+	auth := &Auth{ID: "priority-auth-" + t.Name(), Provider: "codex"}
+	if _, errRegister := manager.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+	registerSchedulerModels(t, "codex", "priority-model", auth.ID)
+	return manager, executor
+}
+
+func executeWithPriority(ctx context.Context, manager *Manager, payload, priority string) error {
+	_, err := manager.Execute(ctx, []string{"codex"}, cliproxyexecutor.Request{Model: "priority-model", Payload: []byte(payload)}, cliproxyexecutor.Options{
+		Metadata: map[string]any{cliproxyexecutor.PriorityMetadataKey: priority},
+	})
+	return err
+}
+
+func waitForQueued(t *testing.T, manager *Manager, want int) {
+	t.Helper()
+	key := priorityLimiterProviderKey("codex")
+	deadline := time.Now().Add(2 * time.Second)
+	for manager.priorityLimiter.queued(key) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", manager.priorityLimiter.queued(key), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManagerExecute_RequestPriorityServesHighBeforeLow(t *testing.T) {
+	manager, executor := newPriorityTestManager(t, internalconfig.RequestPriorityConfig{MaxConcurrentPerProvider: 1})
+	ctx := context.Background()
+
+	errs := make(chan error, 3)
+	go func() { errs <- executeWithPriority(ctx, manager, "first", "normal") }()
+	if got := <-executor.started; got != "first" {
+		t.Fatalf("started %q, want first", got)
+	}
+	go func() { errs <- executeWithPriority(ctx, manager, "low", "low") }()
+	waitForQueued(t, manager, 1)
+	go func() { errs <- executeWithPriority(ctx, manager, "high", "high") }()
+	waitForQueued(t, manager, 2)
+
+	for i := 0; i < 3; i++ {
+		executor.release <- struct{}{}
+		if i < 2 {
+			<-executor.started
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+	}
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if len(executor.order) != 3 || executor.order[1] != "high" || executor.order[2] != "low" {
+		t.Fatalf("execution order = %v, want [first high low]", executor.order)
+	}
+}
+
+func TestManagerExecute_RequestPriorityQueueTimeout(t *testing.T) {
+	manager, executor := newPriorityTestManager(t, internalconfig.RequestPriorityConfig{
+		MaxConcurrentPerProvider: 1,
+		QueueTimeoutSeconds:      map[string]int{"low": 1},
+	})
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() { done <- executeWithPriority(ctx, manager, "first", "normal") }()
+	<-executor.started
+
+	start := time.Now()
+	err := executeWithPriority(ctx, manager, "low", "low")
+	var queueErr *requestQueueError
+	if !errors.As(err, &queueErr) || queueErr.code != "queue_timeout" || queueErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Execute error = %v, want a 429 queue_timeout", err)
+	}
+	if code := gjson.Get(err.Error(), "error.code").String(); code != "queue_timeout" {
+		t.Fatalf("error body code = %q, want queue_timeout in %s", code, err.Error())
+	}
+	if priority := gjson.Get(err.Error(), "error.priority").String(); priority != "low" {
+		t.Fatalf("error body priority = %q, want low in %s", priority, err.Error())
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond || elapsed > 5*time.Second {
+		t.Fatalf("queue wait = %s, want about the 1s low-priority timeout", elapsed)
+	}
+	if got := manager.priorityLimiter.queued(priorityLimiterProviderKey("codex")); got != 0 {
+		t.Fatalf("queued after timeout = %d, want 0", got)
+	}
+
+	executor.release <- struct{}{}
+	if errFirst := <-done; errFirst != nil {
+		t.Fatalf("first Execute error: %v", errFirst)
+	}
+}
+
+func TestManagerExecute_RequestPriorityQueueFullAndCancel(t *testing.T) {
+	manager, executor := newPriorityTestManager(t, internalconfig.RequestPriorityConfig{
+		MaxConcurrentPerProvider: 1,
+		MaxQueueDepth:            1,
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- executeWithPriority(context.Background(), manager, "first", "normal") }()
+	<-executor.started
+
+	waitCtx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() { cancelled <- executeWithPriority(waitCtx, manager, "waiting", "high") }()
+	waitForQueued(t, manager, 1)
+
+	err := executeWithPriority(context.Background(), manager, "overflow", "high")
+	var queueErr *requestQueueError
+	if !errors.As(err, &queueErr) || queueErr.code != "queue_full" || queueErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Execute error = %v, want a 429 queue_full", err)
+	}
+
+	cancel()
+	if errCancelled := <-cancelled; !errors.Is(errCancelled, context.Canceled) {
+		t.Fatalf("cancelled Execute error = %v, want context.Canceled", errCancelled)
+	}
+	waitForQueued(t, manager, 0)
+
+	executor.release <- struct{}{}
+	if errFirst := <-done; errFirst != nil {
+		t.Fatalf("first Execute error: %v", errFirst)
+	}
+	if got := manager.priorityLimiter.queued(priorityLimiterProviderKey("codex")); got != 0 {
+		t.Fatalf("queued after release = %d, want 0", got)
+	}
+}
+
+func TestManagerExecute_RequestPrioritySlotTimeoutMovesToNextAuth(t *testing.T) {
+	manager := NewManager(nil, &FillFirstSelector{}, nil)
+	cfg := &internalconfig.Config{}
+	cfg.RequestPriority = internalconfig.RequestPriorityConfig{
+		MaxConcurrentPerAuth: 1,
+		QueueTimeoutSeconds:  map[string]int{"low": 1},
+	}
+	manager.SetConfig(cfg)
+	executor := &priorityBlockingExecutor{
+		schedulerProviderTestExecutor: schedulerProviderTestExecutor{provider: "codex"},
+		started:                       make(chan string, 8),
+		release:                       make(chan struct{}),
+	}
+	manager.RegisterExecutor(executor)
+	for _, id := range []string{"priority-a", "priority-b"} {
+		if _, errRegister := manager.Register(context.Background(), &Auth{ID: id, Provider: "codex"}); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+	}
+	registerSchedulerModels(t, "codex", "priority-model", "priority-a", "priority-b")
+
+	done := make(chan error, 1)
+	go func() { done <- executeWithPriority(context.Background(), manager, "first", "normal") }()
+	<-executor.started
+
+	second := make(chan error, 1)
+	go func() { second <- executeWithPriority(context.Background(), manager, "second", "low") }()
+	if got := <-executor.started; got != "second" {
+		t.Fatalf("started %q, want second", got)
+	}
+	executor.release <- struct{}{}
+	executor.release <- struct{}{}
+	if errSecond := <-second; errSecond != nil {
+		t.Fatalf("second Execute error: %v", errSecond)
+	}
+	if errFirst := <-done; errFirst != nil {
+		t.Fatalf("first Execute error: %v", errFirst)
+	}
+
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	if len(executor.auths) != 2 || executor.auths[0] != "priority-a" || executor.auths[1] != "priority-b" {
+		t.Fatalf("executed on auths %v, want [priority-a priority-b]", executor.auths)
+	}
+	if state, ok := manager.GetByID("priority-a"); ok && !state.NextRetryAfter.IsZero() {
+		t.Fatalf("priority-a cooled down until %s after a queue timeout", state.NextRetryAfter)
+	}
+}
+
+func TestManagerExecute_RequestPriorityQueueTimeoutSpansAllAuths(t *testing.T) {
+	manager := NewManager(nil, &FillFirstSelector{}, nil)
+	cfg := &internalconfig.Config{}
+	cfg.RequestPriority = internalconfig.RequestPriorityConfig{
+		MaxConcurrentPerAuth: 1,
+		QueueTimeoutSeconds:  map[string]int{"low": 1},
+	}
+	manager.SetConfig(cfg)
+	manager.RegisterExecutor(&priorityBlockingExecutor{schedulerProviderTestExecutor: schedulerProviderTestExecutor{provider: "codex"}})
+	ids := []string{"priority-span-a", "priority-span-b", "priority-span-c"}
+	for _, id := range ids {
+		if _, errRegister := manager.Register(context.Background(), &Auth{ID: id, Provider: "codex"}); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+		release, errAcquire := manager.priorityLimiter.acquire(context.Background(), priorityLimiterAuthKey(id), 1, 0, 1, time.Now())
+		if errAcquire != nil {
+			t.Fatalf("occupy %s: %v", id, errAcquire)
+		}
+		t.Cleanup(release)
+	}
+	registerSchedulerModels(t, "codex", "priority-model", ids...)
+
+	start := time.Now()
+	err := executeWithPriority(context.Background(), manager, "low", "low")
+	var queueErr *requestQueueError
+	if !errors.As(err, &queueErr) || queueErr.code != "queue_timeout" {
+		t.Fatalf("Execute error = %v, want queue_timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 1800*time.Millisecond {
+		t.Fatalf("queue wait = %s across %d busy auths, want one 1s low-priority timeout in total", elapsed, len(ids))
+	}
+}
+
+func TestManagerExecuteStream_RequestPriorityHoldsSlotForHomeStream(t *testing.T) {
+	manager := NewManager(nil, nil, nil)
+	cfg := &internalconfig.Config{Home: internalconfig.HomeConfig{Enabled: true}}
+	cfg.RequestPriority = internalconfig.RequestPriorityConfig{MaxConcurrentPerProvider: 1}
+	manager.SetConfig(cfg)
+	registry := executionregistry.New()
+	manager.PublishHomeDispatch(homeExecutionDispatcher{}, registry, 1)
+	chunks := make(chan cliproxyexecutor.StreamChunk, 1)
+	chunks <- cliproxyexecutor.StreamChunk{Payload: []byte("initial")}
+	manager.RegisterExecutor(&homeExecutionStreamExecutor{chunks: chunks})
+
+	result, errExecute := manager.ExecuteStream(context.Background(), []string{"home-execution"}, cliproxyexecutor.Request{Model: "test"}, cliproxyexecutor.Options{Stream: true})
+	if errExecute != nil {
+		t.Fatalf("ExecuteStream() error = %v", errExecute)
+	}
+	key := priorityLimiterProviderKey("home-execution")
+	if got := manager.priorityLimiter.inFlight(key); got != 1 {
+		t.Fatalf("in-flight Home streams = %d, want 1", got)
+	}
+
+	close(chunks)
+	for range result.Chunks {
+	}
+	if errDrain := registry.Drain(context.Background()); errDrain != nil {
+		t.Fatalf("Drain() error = %v", errDrain)
+	}
+	if got := manager.priorityLimiter.inFlight(key); got != 0 {
+		t.Fatalf("in-flight Home streams after the stream ended = %d, want 0", got)
+	}
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+// responseCacheTemperaturePaths lists where the supported client formats carry the
+// sampling temperature.
+var responseCacheTemperaturePaths = []string{
+	"temperature",
+	"generationConfig.temperature",
+	"request.generationConfig.temperature",
+}
+
+// responseCacheEntry is one cached non-streaming response.
+type responseCacheEntry struct {
+	key       string
+	resp      cliproxyexecutor.Response
+	expiresAt time.Time
+}
+
+// responseCache holds non-streaming responses keyed by responseCacheKey. It keeps at
+// most the configured number of entries and evicts the least recently used one first.
+// The zero value is ready to use.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// get returns a copy of the response cached under key if it has not expired.
+func (c *responseCache) get(key string, now time.Time) (cliproxyexecutor.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return cliproxyexecutor.Response{}, false
+	}
+	entry := elem.Value.(*responseCacheEntry)
+	if !now.Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return cliproxyexecutor.Response{}, false
+	}
+	c.order.MoveToFront(elem)
+	return cloneCachedResponse(entry.resp), true
+}
+
+// put stores a copy of resp under key until now+ttl, evicting the least recently used
+// entries beyond maxEntries.
+func (c *responseCache) put(key string, resp cliproxyexecutor.Response, now time.Time, ttl time.Duration, maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+	entry := &responseCacheEntry{key: key, resp: cloneCachedResponse(resp), expiresAt: now.Add(ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(entry)
+	}
+	for c.order.Len() > maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+func cloneCachedResponse(resp cliproxyexecutor.Response) cliproxyexecutor.Response {
+	out := cliproxyexecutor.Response{
+		Payload: bytes.Clone(resp.Payload),
+		Headers: resp.Headers.Clone(),
+	}
+	if resp.Metadata != nil {
+		out.Metadata = make(map[string]any, len(resp.Metadata))
+		for k, v := range resp.Metadata {
+			out.Metadata[k] = v
+		}
+	}
+	return out
+}
+
+// responseCacheKey hashes everything that decides the upstream request and the response
+// returned to the client: the providers, upstream and requested model, client and response
+// formats, a pinned auth, the requested Anthropic betas, the canonical client payload and
+// the payload rules. Translation
+// and payload-rule application are deterministic functions of these inputs, so requests
+// with equal keys produce the same upstream payload without running the executor pipeline.
+func responseCacheKey(cfg *internalconfig.Config, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) string {
+	hash := sha256.New()
+	write := func(value string) {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	write(strings.Join(providers, ","))
+	write(req.Model)
+	write(opts.SourceFormat.String())
+	write(cliproxyexecutor.ResponseFormatOrSource(opts).String())
+	write(opts.Alt)
+	write(stringMetadataValue(opts.Metadata, cliproxyexecutor.RequestedModelMetadataKey))
+	write(pinnedAuthIDFromMetadata(opts.Metadata))
+	write(normalizedAnthropicBetas(opts.Headers))
+	hash.Write(canonicalJSON(req.Payload))
+	hash.Write([]byte{0})
+	if payloadRules, errMarshal := json.Marshal(cfg.Payload); errMarshal == nil {
+		hash.Write(payloadRules)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizedAnthropicBetas returns the distinct betas of the anthropic-beta headers,
+// sorted and comma separated. Betas change the upstream response, while their order,
+// spacing and repetition do not.
+func normalizedAnthropicBetas(headers http.Header) string {
+	var betas []string
+	for _, value := range headers.Values("Anthropic-Beta") {
+		for _, beta := range strings.Split(value, ",") {
+			if beta = strings.TrimSpace(beta); beta != "" {
+				betas = append(betas, beta)
+			}
+		}
+	}
+	slices.Sort(betas)
+	return strings.Join(slices.Compact(betas), ",")
+}
+
+// canonicalJSON re-encodes payload with sorted object keys and no insignificant
+// whitespace, so formatting differences do not split cache entries. Payloads that are
+// not valid JSON are returned unchanged.
+func canonicalJSON(payload []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value any
+	if errDecode := decoder.Decode(&value); errDecode != nil {
+		return payload
+	}
+	out, errMarshal := json.Marshal(value)
+	if errMarshal != nil {
+		return payload
+	}
+	return out
+}
+
+// responseCacheable reports whether the sampling settings of payload make its response
+// reusable. Only an explicit temperature of 0 qualifies, since providers default to a
+// temperature above 0, unless the cache config ignores the temperature.
+func responseCacheable(cfg internalconfig.ResponseCacheConfig, payload []byte) bool {
+	if cfg.IgnoreTemperature {
+		return true
+	}
+	for _, path := range responseCacheTemperaturePaths {
+		if temperature := gjson.GetBytes(payload, path); temperature.Exists() {
+			return temperature.Type == gjson.Number && temperature.Float() <= 0
+		}
+	}
+	return false
+}
+
+// requestsNoCache reports whether the client sent Cache-Control: no-cache.
+func requestsNoCache(headers http.Header) bool {
+	for _, value := range headers.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// executeWithResponseCache answers a non-streaming request from the response cache when
+// the cache config is enabled and the request is eligible, and otherwise executes it and
+// caches its successful response when it is no larger than the configured limit. A
+// Cache-Control: no-cache request skips the lookup but still refreshes the entry.
+func (m *Manager) executeWithResponseCache(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.Cache.Enabled {
+		return m.executeUncached(ctx, providers, req, opts)
+	}
+	if !responseCacheable(cfg.Cache, req.Payload) {
+		metrics.ObserveResponseCache("bypass")
+		return m.executeUncached(ctx, providers, req, opts)
+	}
+	key := responseCacheKey(cfg, providers, req, opts)
+	if requestsNoCache(opts.Headers) {
+		metrics.ObserveResponseCache("bypass")
+	} else if resp, ok := m.responseCache.get(key, time.Now()); ok {
+		metrics.ObserveResponseCache("hit")
+		logEntryWithRequestID(ctx).Debugf("response cache: hit for model %s", req.Model)
+		return resp, nil
+	} else {
+		metrics.ObserveResponseCache("miss")
+	}
+	resp, errExec := m.executeUncached(ctx, providers, req, opts)
+	if errExec == nil && len(resp.Payload) <= cfg.Cache.EntryBytesLimit() {
+		m.responseCache.put(key, resp, time.Now(), cfg.Cache.TTL(), cfg.Cache.EntryLimit())
+	}
+	return resp, errExec
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/metrics"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+// countingUpstream answers every request with its arrival number, so a response shows
+// which upstream call produced it.
+func countingUpstream(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var arrivals atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, "answer %d", arrivals.Add(1))
+	}))
+	t.Cleanup(server.Close)
+	return server, &arrivals
+}
+
+func newResponseCacheTestManager(t *testing.T, cache internalconfig.ResponseCacheConfig) (*Manager, *atomic.Int32) {
+	t.Helper()
+	upstream, arrivals := countingUpstream(t)
+	m := newUpstreamRetryTestManager(t, internalconfig.UpstreamRetryConfig{}, map[string]string{"base_url": upstream.URL})
+	m.SetConfig(&internalconfig.Config{Cache: cache})
+	return m, arrivals
+}
+
+func executeCached(t *testing.T, m *Manager, payload string, headers http.Header) string {
+	t.Helper()
+	resp, errExec := m.Execute(context.Background(), []string{upstreamRetryTestProvider},
+		cliproxyexecutor.Request{Model: "retry-model", Payload: []byte(payload)},
+		cliproxyexecutor.Options{SourceFormat: "openai", Headers: headers})
+	if errExec != nil {
+		t.Fatalf("Execute() error = %v", errExec)
+	}
+	return string(resp.Payload)
+}
+
+func TestManagerExecuteServesIdenticalRequestFromCache(t *testing.T) {
+	metrics.Reset()
+	metrics.SetEnabled(true)
+	t.Cleanup(func() {
+		metrics.SetEnabled(false)
+		metrics.Reset()
+	})
+	m, arrivals := newResponseCacheTestManager(t, internalconfig.ResponseCacheConfig{Enabled: true})
+
+	first := executeCached(t, m, `{"messages":[{"role":"user","content":"hi"}],"temperature":0}`, nil)
+	second := executeCached(t, m, `{ "temperature": 0, "messages": [{"content": "hi", "role": "user"}] }`, nil)
+	if first != "answer 1" || second != "answer 1" || arrivals.Load() != 1 {
+		t.Fatalf("responses %q, %q after %d upstream calls; want the second served from the cache", first, second, arrivals.Load())
+	}
+	if got := executeCached(t, m, `{"messages":[{"role":"user","content":"bye"}],"temperature":0}`, nil); got != "answer 2" {
+		t.Fatalf("different payload returned %q, want a fresh upstream response", got)
+	}
+
+	var exposition bytes.Buffer
+	if errWrite := metrics.Write(&exposition); errWrite != nil {
+		t.Fatalf("metrics.Write() error = %v", errWrite)
+	}
+	for _, want := range []string{`cliproxy_response_cache_total{result="hit"} 1`, `cliproxy_response_cache_total{result="miss"} 2`} {
+		if !strings.Contains(exposition.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, exposition.String())
+		}
+	}
+}
+
+func TestManagerExecuteNoCacheHeaderRefreshesEntry(t *testing.T) {
+	m, arrivals := newResponseCacheTestManager(t, internalconfig.ResponseCacheConfig{Enabled: true})
+	payload := `{"temperature":0}`
+
+	executeCached(t, m, payload, nil)
+	if got := executeCached(t, m, payload, http.Header{"Cache-Control": {"max-age=0, no-cache"}}); got != "answer 2" {
+		t.Fatalf("no-cache request returned %q, want a fresh upstream response", got)
+	}
+	if got := executeCached(t, m, payload, nil); got != "answer 2" || arrivals.Load() != 2 {
+		t.Fatalf("request after refresh returned %q after %d upstream calls, want the refreshed entry", got, arrivals.Load())
+	}
+}
+
+func TestManagerExecuteCacheKeysOnAnthropicBetas(t *testing.T) {
+	m, arrivals := newResponseCacheTestManager(t, internalconfig.ResponseCacheConfig{Enabled: true})
+	payload := `{"temperature":0}`
+
+	executeCached(t, m, payload, http.Header{"Anthropic-Beta": {"context-1m-2025-08-07, files-api-2025-04-14"}})
+	if got := executeCached(t, m, payload, http.Header{"Anthropic-Beta": {"files-api-2025-04-14", "context-1m-2025-08-07,files-api-2025-04-14"}}); got != "answer 1" {
+		t.Fatalf("reordered betas returned %q, want the cached response", got)
+	}
+	if got := executeCached(t, m, payload, http.Header{"Anthropic-Beta": {"files-api-2025-04-14"}}); got != "answer 2" {
+		t.Fatalf("different betas returned %q, want a fresh upstream response", got)
+	}
+	if got := executeCached(t, m, payload, nil); got != "answer 3" || arrivals.Load() != 3 {
+		t.Fatalf("request without betas returned %q after %d upstream calls, want a fresh upstream response", got, arrivals.Load())
+	}
+}
+
+func TestManagerExecuteCacheBypassesSampledRequests(t *testing.T) {
+	for _, payload := range []string{`{}`, `{"temperature":0.7}`, `{"generationConfig":{"temperature":1}}`} {
+		m, arrivals := newResponseCacheTestManager(t, internalconfig.ResponseCacheConfig{Enabled: true})
+		executeCached(t, m, payload, nil)
+		executeCached(t, m, payload, nil)
+		if arrivals.Load() != 2 {
+			t.Errorf("payload %s reached the upstream %d times, want the cache bypassed", payload, arrivals.Load())
+		}
+	}
+
+	m, arrivals := newResponseCacheTestManager(t, internalconfig.ResponseCacheConfig{Enabled: true, IgnoreTemperature: true})
+	executeCached(t, m, `{"temperature":0.7}`, nil)
+	executeCached(t, m, `{"temperature":0.7}`, nil)
+	if arrivals.Load() != 1 {
+		t.Fatalf("ignore-temperature request reached the upstream %d times, want 1", arrivals.Load())
+	}
+}
+
+func TestManagerExecuteCacheSkipsLargeResponses(t *testing.T) {
+	m, arrivals := newResponseCacheTestManager(t, internalconfig.ResponseCacheConfig{Enabled: true, MaxEntryBytes: 4})
+	executeCached(t, m, `{"temperature":0}`, nil)
+	executeCached(t, m, `{"temperature":0}`, nil)
+	if arrivals.Load() != 2 {
+		t.Fatalf("oversized response was served from the cache; upstream calls = %d", arrivals.Load())
+	}
+}
+
+func TestResponseCacheExpiresAndEvicts(t *testing.T) {
+	var cache responseCache
+	now := time.Now()
+	cache.put("a", cliproxyexecutor.Response{Payload: []byte("a")}, now, time.Minute, 2)
+	cache.put("b", cliproxyexecutor.Response{Payload: []byte("b")}, now, time.Minute, 2)
+
+	if _, ok := cache.get("a", now.Add(time.Minute)); ok {
+		t.Fatal("expired entry was returned")
+	}
+	cache.put("a", cliproxyexecutor.Response{Payload: []byte("a")}, now, time.Minute, 2)
+	if _, ok := cache.get("b", now); !ok {
+		t.Fatal("entry b missing before eviction")
+	}
+	// b is now the most recently used entry, so adding c evicts a.
+	cache.put("c", cliproxyexecutor.Response{Payload: []byte("c")}, now, time.Minute, 2)
+	if _, ok := cache.get("a", now); ok {
+		t.Fatal("least recently used entry was not evicted")
+	}
+	resp, ok := cache.get("b", now)
+	if !ok || string(resp.Payload) != "b" {
+		t.Fatalf("get(b) = %q, %v; want the cached response", resp.Payload, ok)
+	}
+	resp.Payload[0] = 'x'
+	if resp, _ = cache.get("b", now); string(resp.Payload) != "b" {
+		t.Fatalf("cached payload was modified through a returned copy: %q", resp.Payload)
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/secrets"
+)
+
+// AttributeSecretRefPrefix prefixes the attributes recording which metadata values were
+// resolved from secret references; the rest of the key is the metadata path.
+const AttributeSecretRefPrefix = "secret_ref:"
+
+// ResolveSecretMetadata resolves the secret references in auth metadata in place and
+// records them on auth, so persisting it writes references rather than plaintext.
+func ResolveSecretMetadata(ctx context.Context, auth *Auth) error {
+	if auth == nil || len(auth.Metadata) == 0 {
+		return nil
+	}
+	refs, err := secrets.ResolveMetadata(ctx, auth.Metadata)
+	if err != nil {
+		return err
+	}
+	SetSecretRefs(auth, refs)
+	return nil
+}
+
+// SetSecretRefs records on auth the secret references its metadata was resolved from.
+func SetSecretRefs(auth *Auth, refs secrets.Refs) {
+	if auth == nil || len(refs) == 0 {
+		return
+	}
+	if auth.Attributes == nil {
+		auth.Attributes = make(map[string]string, len(refs))
+	}
+	for path, raw := range refs {
+		auth.Attributes[AttributeSecretRefPrefix+path] = raw
+	}
+}
+
+// SecretRefs returns the secret references recorded on auth by ResolveSecretMetadata.
+func SecretRefs(auth *Auth) secrets.Refs {
+	if auth == nil {
+		return nil
+	}
+	var refs secrets.Refs
+	for key, raw := range auth.Attributes {
+		path, ok := strings.CutPrefix(key, AttributeSecretRefPrefix)
+		if !ok || path == "" {
+			continue
+		}
+		if refs == nil {
+			refs = secrets.Refs{}
+		}
+		refs[path] = raw
+	}
+	return refs
+}
+
+// secretPersistAuth returns the auth to hand to the store: auth itself, or a copy whose
+// metadata holds secret references again after changed values, such as rotated refresh
+// tokens, were written back through their resolvers.
+func secretPersistAuth(ctx context.Context, auth *Auth) (*Auth, error) {
+	refs := SecretRefs(auth)
+	if len(refs) == 0 {
+		return auth, nil
+	}
+	metadata, err := secrets.PersistMetadata(ctx, auth.Metadata, refs)
+	if err != nil {
+		return nil, err
+	}
+	persisted := auth.Clone()
+	persisted.Metadata = metadata
+	return persisted, nil
+}
@@ -377,6 +377,9 @@ type SessionAffinitySelector struct {
 type SessionAffinityConfig struct {
 	Fallback Selector
 	TTL      time.Duration
+	// MaxEntries bounds the session-to-auth bindings kept; the least recently used
+	// binding is evicted beyond it. Zero uses DefaultSessionCacheMaxEntries.
+	MaxEntries int
 }
 
 // NewSessionAffinitySelector creates a new session-aware selector.
@@ -397,19 +400,21 @@ func NewSessionAffinitySelectorWithConfig(cfg SessionAffinityConfig) *SessionAff
 	}
 	return &SessionAffinitySelector{
 		fallback: cfg.Fallback,
-		cache:    NewSessionCache(cfg.TTL),
+		cache:    NewSessionCacheWithLimit(cfg.TTL, cfg.MaxEntries),
 	}
 }
 
 // Pick selects an auth with session affinity when possible.
 // Priority for session ID extraction:
 //  1. metadata.user_id (Claude Code format with _session_{uuid}) - highest priority
-//  2. X-Session-ID header
+//  2. X-Session-ID header, then a session pinned by a session_id body field (Options.Metadata)
 //  3. Session_id header (Codex)
 //  4. X-Client-Request-Id header (PI)
 //  5. metadata.user_id (non-Claude Code format)
 //  6. conversation_id field in request body
-//  7. Stable hash from first few messages content (fallback)
+//  7. session_id or metadata.session_id field in request body
+//  8. Stable hash from first few messages content (fallback)
+//  9. Idempotency-Key header (Options.Metadata), when there is no content to hash
 //
 // Note: The cache key includes provider, session ID, and model to handle cases where
 // a session uses multiple models (e.g., gemini-2.5-pro and gemini-3-flash-preview)
@@ -505,17 +510,36 @@ func (s *SessionAffinitySelector) InvalidateAuth(authID string) {
 // ExtractSessionID extracts session identifier from multiple sources.
 // Priority order:
 //  1. metadata.user_id (Claude Code format with _session_{uuid}) - highest priority for Claude Code clients
-//  2. X-Session-ID header
+//  2. X-Session-ID header, then a session pinned by a session_id body field (Options.Metadata)
 //  3. Session_id header (Codex)
 //  4. X-Client-Request-Id header (PI)
 //  5. metadata.user_id (non-Claude Code format)
 //  6. conversation_id field in request body
-//  7. Stable hash from first few messages content (fallback)
+//  7. session_id or metadata.session_id field in request body
+//  8. Stable hash from first few messages content (fallback)
+//  9. Idempotency-Key header (Options.Metadata), when there is no content to hash
 func ExtractSessionID(headers http.Header, payload []byte, metadata map[string]any) string {
 	primary, _ := extractSessionIDs(headers, payload, metadata)
 	return primary
 }
 
+// ExtractConversationID returns a conversation key that stays stable across turns:
+// an explicit metadata.session_id when present, otherwise a hash of the system
+// prompt and first user message. It returns "" when neither is available.
+func ExtractConversationID(payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	if sid := strings.TrimSpace(gjson.GetBytes(payload, "metadata.session_id").String()); sid != "" {
+		return "session:" + sid
+	}
+	primaryID, fallbackID := extractMessageHashIDs(payload)
+	if fallbackID != "" {
+		return fallbackID
+	}
+	return primaryID
+}
+
 // extractSessionIDs returns (primaryID, fallbackID) for session affinity.
 // primaryID: full hash including assistant response (stable after first turn)
 // fallbackID: short hash without assistant (used to inherit binding from first turn)
@@ -546,6 +570,11 @@ func extractSessionIDs(headers http.Header, payload []byte, metadata map[string]
 		}
 	}
 
+	// 2b. Session pinned by a session_id body field (Options.Metadata)
+	if sid := stringMetadataValue(metadata, cliproxyexecutor.SessionIDMetadataKey); sid != "" {
+		return "session:" + sid, ""
+	}
+
 	// 3. Session_id header (Codex)
 	if headers != nil {
 		if sid := headers.Get("Session-Id"); sid != "" {
@@ -578,8 +607,24 @@ func extractSessionIDs(headers http.Header, payload []byte, metadata map[string]
 		return "conv:" + convID, ""
 	}
 
-	// 8. Hash-based fallback from message content
-	return extractMessageHashIDs(payload)
+	// 8. explicit session_id field
+	for _, path := range []string{"session_id", "metadata.session_id"} {
+		if sid := strings.TrimSpace(gjson.GetBytes(payload, path).String()); sid != "" {
+			return "session:" + sid, ""
+		}
+	}
+
+	// 9. Hash-based fallback from message content
+	if primaryID, fallbackID := extractMessageHashIDs(payload); primaryID != "" {
+		return primaryID, fallbackID
+	}
+
+	// 10. Idempotency-Key, only when there is no message content to hash: many SDKs
+	// generate a fresh key per request, which would otherwise defeat the hash.
+	if key := stringMetadataValue(metadata, cliproxyexecutor.IdempotencyKeyMetadataKey); key != "" {
+		return "idempotency:" + key, ""
+	}
+	return "", ""
 }
 
 func extractMessageHashIDs(payload []byte) (primaryID, fallbackID string) {
@@ -692,6 +692,21 @@ func TestExtractSessionID_CodexSessionIDPriorityOverClientRequestID(t *testing.T
 	}
 }
 
+func TestExtractSessionID_PinnedSessionMetadata(t *testing.T) {
+	t.Parallel()
+
+	headers := make(http.Header)
+	headers.Set("Session_id", "codex-session-456")
+	metadata := map[string]any{cliproxyexecutor.SessionIDMetadataKey: "pinned-1"}
+	payload := []byte(`{"conversation_id":"conv-1","messages":[{"role":"user","content":"hi"}]}`)
+
+	got := ExtractSessionID(headers, payload, metadata)
+	want := "session:pinned-1"
+	if got != want {
+		t.Errorf("ExtractSessionID() = %q, want %q (pinned session should take priority over Session_id)", got, want)
+	}
+}
+
 // TestExtractSessionID_IdempotencyKey verifies that idempotency_key is intentionally
 // ignored for session affinity (it's auto-generated per-request, causing cache misses).
 func TestExtractSessionID_IdempotencyKey(t *testing.T) {
@@ -1275,3 +1290,118 @@ func TestSessionAffinitySelector_Concurrent(t *testing.T) {
 	default:
 	}
 }
+
+func TestSessionAffinitySelector_StickyUntilTTLExpiry(t *testing.T) {
+	t.Parallel()
+
+	selector := NewSessionAffinitySelectorWithConfig(SessionAffinityConfig{
+		Fallback: &RoundRobinSelector{},
+		TTL:      80 * time.Millisecond,
+	})
+	defer selector.Stop()
+
+	auths := []*Auth{{ID: "auth-a"}, {ID: "auth-b"}, {ID: "auth-c"}}
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"session_id":"conv-42","messages":[{"role":"user","content":"hi"}]}`)}
+
+	first, err := selector.Pick(context.Background(), "gemini", "gemini-2.5-pro", opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		got, errPick := selector.Pick(context.Background(), "gemini", "gemini-2.5-pro", opts, auths)
+		if errPick != nil {
+			t.Fatalf("Pick() #%d error = %v", i+2, errPick)
+		}
+		if got.ID != first.ID {
+			t.Fatalf("Pick() #%d = %q, want the sticky auth %q", i+2, got.ID, first.ID)
+		}
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	afterExpiry, err := selector.Pick(context.Background(), "gemini", "gemini-2.5-pro", opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() after expiry error = %v", err)
+	}
+	if afterExpiry.ID == first.ID {
+		t.Fatalf("Pick() after TTL expiry = %q, want a fresh selection", afterExpiry.ID)
+	}
+}
+
+func TestSessionAffinitySelector_RebindsWhenStickyAuthCoolsDown(t *testing.T) {
+	t.Parallel()
+
+	selector := NewSessionAffinitySelectorWithConfig(SessionAffinityConfig{Fallback: &RoundRobinSelector{}, TTL: time.Minute})
+	defer selector.Stop()
+
+	auths := []*Auth{{ID: "auth-a"}, {ID: "auth-b"}}
+	opts := cliproxyexecutor.Options{OriginalRequest: []byte(`{"metadata":{"session_id":"conv-cooldown"}}`)}
+
+	first, err := selector.Pick(context.Background(), "claude", "claude-3", opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() error = %v", err)
+	}
+	first.ModelStates = map[string]*ModelState{"claude-3": {Unavailable: true, NextRetryAfter: time.Now().Add(time.Minute)}}
+
+	second, err := selector.Pick(context.Background(), "claude", "claude-3", opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() during cooldown error = %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatalf("Pick() during cooldown = %q, want another auth", second.ID)
+	}
+
+	first.ModelStates = nil
+	third, err := selector.Pick(context.Background(), "claude", "claude-3", opts, auths)
+	if err != nil {
+		t.Fatalf("Pick() after cooldown error = %v", err)
+	}
+	if third.ID != second.ID {
+		t.Fatalf("Pick() after cooldown = %q, want the updated binding %q", third.ID, second.ID)
+	}
+}
+
+func TestExtractSessionID_SessionIDFieldAndIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	metadata := map[string]any{"idempotency_key": "idem-12345"}
+	if got := ExtractSessionID(nil, []byte(`{"session_id":"conv-1","messages":[{"role":"user","content":"hi"}]}`), metadata); got != "session:conv-1" {
+		t.Fatalf("ExtractSessionID() with session_id = %q, want session:conv-1", got)
+	}
+	if got := ExtractSessionID(nil, []byte(`{"metadata":{"session_id":"conv-2"}}`), nil); got != "session:conv-2" {
+		t.Fatalf("ExtractSessionID() with metadata.session_id = %q, want session:conv-2", got)
+	}
+
+	hashed := ExtractSessionID(nil, []byte(`{"messages":[{"role":"user","content":"hi"}]}`), metadata)
+	if hashed == "" || strings.HasPrefix(hashed, "idempotency:") {
+		t.Fatalf("ExtractSessionID() with content = %q, want the message hash over the idempotency key", hashed)
+	}
+	if got := ExtractSessionID(nil, []byte(`{"input":[]}`), metadata); got != "idempotency:idem-12345" {
+		t.Fatalf("ExtractSessionID() without content = %q, want idempotency:idem-12345", got)
+	}
+}
+
+func TestSessionCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	cache := NewSessionCacheWithLimit(time.Minute, 2)
+	defer cache.Stop()
+
+	cache.Set("s1", "auth1")
+	cache.Set("s2", "auth2")
+	if _, ok := cache.Get("s1"); !ok {
+		t.Fatal("Get(s1) missed before eviction")
+	}
+	cache.Set("s3", "auth3")
+
+	if cache.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", cache.Len())
+	}
+	if _, ok := cache.Get("s2"); ok {
+		t.Fatal("s2 should have been evicted as least recently used")
+	}
+	for _, sid := range []string{"s1", "s3"} {
+		if _, ok := cache.Get(sid); !ok {
+			t.Fatalf("Get(%s) missed, want it kept", sid)
+		}
+	}
+}
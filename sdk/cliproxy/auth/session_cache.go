@@ -1,34 +1,54 @@
 package auth
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
 
+// DefaultSessionCacheMaxEntries bounds the session cache when no limit is configured.
+const DefaultSessionCacheMaxEntries = 10000
+
 // sessionEntry stores auth binding with expiration.
 type sessionEntry struct {
+	sessionID string
 	authID    string
 	expiresAt time.Time
 }
 
 // SessionCache provides TTL-based session to auth mapping with automatic cleanup.
+// It holds at most maxEntries bindings and evicts the least recently used one
+// when a new session would exceed the limit.
 type SessionCache struct {
-	mu      sync.RWMutex
-	entries map[string]sessionEntry
-	ttl     time.Duration
-	stopCh  chan struct{}
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	ttl        time.Duration
+	maxEntries int
+	stopCh     chan struct{}
 }
 
-// NewSessionCache creates a cache with the specified TTL.
+// NewSessionCache creates a cache with the specified TTL and the default size limit.
 // A background goroutine periodically cleans expired entries.
 func NewSessionCache(ttl time.Duration) *SessionCache {
+	return NewSessionCacheWithLimit(ttl, DefaultSessionCacheMaxEntries)
+}
+
+// NewSessionCacheWithLimit creates a cache with the specified TTL holding at most
+// maxEntries bindings. A non-positive maxEntries uses DefaultSessionCacheMaxEntries.
+func NewSessionCacheWithLimit(ttl time.Duration, maxEntries int) *SessionCache {
 	if ttl <= 0 {
 		ttl = 30 * time.Minute
 	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultSessionCacheMaxEntries
+	}
 	c := &SessionCache{
-		entries: make(map[string]sessionEntry),
-		ttl:     ttl,
-		stopCh:  make(chan struct{}),
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		stopCh:     make(chan struct{}),
 	}
 	go c.cleanupLoop()
 	return c
@@ -37,46 +57,35 @@ func NewSessionCache(ttl time.Duration) *SessionCache {
 // Get retrieves the auth ID bound to a session, if still valid.
 // Does NOT refresh the TTL on access.
 func (c *SessionCache) Get(sessionID string) (string, bool) {
-	if sessionID == "" {
-		return "", false
-	}
-	c.mu.RLock()
-	entry, ok := c.entries[sessionID]
-	c.mu.RUnlock()
-	if !ok {
-		return "", false
-	}
-	if time.Now().After(entry.expiresAt) {
-		c.mu.Lock()
-		delete(c.entries, sessionID)
-		c.mu.Unlock()
-		return "", false
-	}
-	return entry.authID, true
+	return c.get(sessionID, false)
 }
 
 // GetAndRefresh retrieves the auth ID bound to a session and refreshes TTL on hit.
 // This extends the binding lifetime for active sessions.
 func (c *SessionCache) GetAndRefresh(sessionID string) (string, bool) {
+	return c.get(sessionID, true)
+}
+
+func (c *SessionCache) get(sessionID string, refresh bool) (string, bool) {
 	if sessionID == "" {
 		return "", false
 	}
 	now := time.Now()
 	c.mu.Lock()
-	entry, ok := c.entries[sessionID]
+	defer c.mu.Unlock()
+	elem, ok := c.entries[sessionID]
 	if !ok {
-		c.mu.Unlock()
 		return "", false
 	}
+	entry := elem.Value.(*sessionEntry)
 	if now.After(entry.expiresAt) {
-		delete(c.entries, sessionID)
-		c.mu.Unlock()
+		c.removeLocked(elem)
 		return "", false
 	}
-	// Refresh TTL on successful access
-	entry.expiresAt = now.Add(c.ttl)
-	c.entries[sessionID] = entry
-	c.mu.Unlock()
+	if refresh {
+		entry.expiresAt = now.Add(c.ttl)
+	}
+	c.order.MoveToFront(elem)
 	return entry.authID, true
 }
 
@@ -85,12 +94,28 @@ func (c *SessionCache) Set(sessionID, authID string) {
 	if sessionID == "" || authID == "" {
 		return
 	}
+	expiresAt := time.Now().Add(c.ttl)
 	c.mu.Lock()
-	c.entries[sessionID] = sessionEntry{
-		authID:    authID,
-		expiresAt: time.Now().Add(c.ttl),
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[sessionID]; ok {
+		entry := elem.Value.(*sessionEntry)
+		entry.authID = authID
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
 	}
-	c.mu.Unlock()
+	c.entries[sessionID] = c.order.PushFront(&sessionEntry{sessionID: sessionID, authID: authID, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// Len returns the number of bindings currently held, including expired ones
+// not yet cleaned up.
+func (c *SessionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
 }
 
 // Invalidate removes a specific session binding.
@@ -99,7 +124,9 @@ func (c *SessionCache) Invalidate(sessionID string) {
 		return
 	}
 	c.mu.Lock()
-	delete(c.entries, sessionID)
+	if elem, ok := c.entries[sessionID]; ok {
+		c.removeLocked(elem)
+	}
 	c.mu.Unlock()
 }
 
@@ -110,9 +137,9 @@ func (c *SessionCache) InvalidateAuth(authID string) {
 		return
 	}
 	c.mu.Lock()
-	for sid, entry := range c.entries {
-		if entry.authID == authID {
-			delete(c.entries, sid)
+	for _, elem := range c.entries {
+		if elem.Value.(*sessionEntry).authID == authID {
+			c.removeLocked(elem)
 		}
 	}
 	c.mu.Unlock()
@@ -143,10 +170,19 @@ func (c *SessionCache) cleanupLoop() {
 func (c *SessionCache) cleanup() {
 	now := time.Now()
 	c.mu.Lock()
-	for sid, entry := range c.entries {
-		if now.After(entry.expiresAt) {
-			delete(c.entries, sid)
+	for _, elem := range c.entries {
+		if now.After(elem.Value.(*sessionEntry).expiresAt) {
+			c.removeLocked(elem)
 		}
 	}
 	c.mu.Unlock()
 }
+
+// removeLocked drops elem from the cache. The caller must hold c.mu.
+func (c *SessionCache) removeLocked(elem *list.Element) {
+	if elem == nil {
+		return
+	}
+	entry := c.order.Remove(elem).(*sessionEntry)
+	delete(c.entries, entry.sessionID)
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+// shadowRequestTimeout bounds a shadow request, which no client waits for.
+const shadowRequestTimeout = 10 * time.Minute
+
+// ShadowResult is one side of a shadow pair.
+type ShadowResult struct {
+	// Model is the model the request was sent to.
+	Model string
+	// Payload is the response in the client format. It is empty when Err is set.
+	Payload []byte
+	// Usage is the raw usage object of Payload, when it has one.
+	Usage []byte
+	// Latency is the time the request took.
+	Latency time.Duration
+	// Err is the error of a failed request.
+	Err error
+}
+
+// ShadowPair is a request answered by its primary model and mirrored to the shadow model.
+type ShadowPair struct {
+	// RequestID is the ID of the client request.
+	RequestID string
+	// SourceFormat is the format of Request and of both response payloads.
+	SourceFormat string
+	// Request is the client request payload.
+	Request []byte
+	// Primary is the response the client received.
+	Primary ShadowResult
+	// Shadow is the response of the shadow model.
+	Shadow ShadowResult
+}
+
+// ShadowRecorder stores shadow pairs for offline comparison.
+type ShadowRecorder interface {
+	// ShadowRecordingEnabled reports whether recorded pairs are kept. Requests are not
+	// mirrored while it is false.
+	ShadowRecordingEnabled() bool
+	// RecordShadowPair stores pair.
+	RecordShadowPair(pair ShadowPair)
+}
+
+// shadowState holds the shadow traffic runtime of a Manager.
+type shadowState struct {
+	recorder atomic.Pointer[ShadowRecorder]
+	inFlight atomic.Int64
+	// sample returns a value in [0, 1) compared against the sample rate. Tests replace it.
+	sample func() float64
+	// done is called when a shadow request finished recording. Tests use it to wait.
+	done func()
+}
+
+// SetShadowRecorder sets where shadow pairs are recorded. Shadow traffic is off while no
+// recorder is set.
+func (m *Manager) SetShadowRecorder(recorder ShadowRecorder) {
+	if m == nil {
+		return
+	}
+	if recorder == nil {
+		m.shadow.recorder.Store(nil)
+		return
+	}
+	m.shadow.recorder.Store(&recorder)
+}
+
+func (m *Manager) shadowRecorder() ShadowRecorder {
+	if recorder := m.shadow.recorder.Load(); recorder != nil {
+		return *recorder
+	}
+	return nil
+}
+
+// maybeShadow mirrors a completed non-streaming request to the shadow model when the
+// shadow config samples it. The shadow request runs in the background on its own context,
+// so neither the client response nor its request log can be affected by it.
+func (m *Manager) maybeShadow(ctx context.Context, req cliproxyexecutor.Request, opts cliproxyexecutor.Options, resp cliproxyexecutor.Response, latency time.Duration) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || !cfg.Shadow.Active() || opts.Stream {
+		return
+	}
+	recorder := m.shadowRecorder()
+	if recorder == nil || !recorder.ShadowRecordingEnabled() {
+		return
+	}
+	shadowCfg := cfg.Shadow
+	requestedModel := authSelectionModelFromOptions(opts, req.Model)
+	if pattern := strings.ToLower(strings.TrimSpace(shadowCfg.SourceModelPattern)); pattern != "" {
+		if !util.MatchModelPattern(pattern, strings.ToLower(thinking.ParseSuffix(requestedModel).ModelName)) {
+			return
+		}
+	}
+	shadowModel := strings.TrimSpace(shadowCfg.ShadowModel)
+	if strings.EqualFold(shadowModel, requestedModel) {
+		return
+	}
+	sample := m.shadow.sample
+	if sample == nil {
+		sample = rand.Float64
+	}
+	if sample() >= shadowCfg.SampleRate {
+		return
+	}
+	providers := m.normalizeProviders(registry.GetGlobalRegistry().GetModelProviders(shadowModel))
+	if len(providers) == 0 {
+		logEntryWithRequestID(ctx).Debugf("shadow traffic: no provider serves shadow model %s", shadowModel)
+		return
+	}
+	if m.shadow.inFlight.Add(1) > int64(shadowCfg.EffectiveMaxConcurrent()) {
+		m.shadow.inFlight.Add(-1)
+		logEntryWithRequestID(ctx).Debugf("shadow traffic: concurrency limit reached, not mirroring to %s", shadowModel)
+		return
+	}
+
+	requestID := logging.GetRequestID(ctx)
+	pair := ShadowPair{
+		RequestID:    requestID,
+		SourceFormat: opts.SourceFormat.String(),
+		Request:      append([]byte(nil), req.Payload...),
+		Primary:      newShadowResult(requestedModel, resp.Payload, latency, nil),
+	}
+	shadowReq := cliproxyexecutor.Request{Model: shadowModel, Payload: pair.Request}
+	shadowOpts := opts
+	shadowOpts.Metadata = map[string]any{}
+	shadowOpts.OriginalRequest = append([]byte(nil), opts.OriginalRequest...)
+
+	go func() {
+		defer m.shadow.inFlight.Add(-1)
+		if m.shadow.done != nil {
+			defer m.shadow.done()
+		}
+		// A fresh context keeps the shadow request out of the client's gin context and
+		// request log; only the request ID is carried over.
+		shadowCtx, cancel := context.WithTimeout(logging.WithRequestID(context.Background(), requestID), shadowRequestTimeout)
+		defer cancel()
+		start := time.Now()
+		shadowResp, errShadow := m.executeUncached(shadowCtx, providers, shadowReq, shadowOpts)
+		pair.Shadow = newShadowResult(shadowModel, shadowResp.Payload, time.Since(start), errShadow)
+		recorder.RecordShadowPair(pair)
+	}()
+}
+
+func newShadowResult(model string, payload []byte, latency time.Duration, err error) ShadowResult {
+	result := ShadowResult{Model: model, Latency: latency, Err: err}
+	if err != nil {
+		return result
+	}
+	result.Payload = append([]byte(nil), payload...)
+	for _, path := range []string{"usage", "usageMetadata", "response.usage"} {
+		if usage := gjson.GetBytes(payload, path); usage.IsObject() {
+			result.Usage = []byte(usage.Raw)
+			break
+		}
+	}
+	return result
+}
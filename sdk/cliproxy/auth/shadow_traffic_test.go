@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/logging"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
+)
+
+const (
+	shadowTestPrimaryProvider = "shadow-test-primary"
+	shadowTestShadowProvider  = "shadow-test-shadow"
+	shadowTestPrimaryModel    = "shadow-primary-model"
+	shadowTestShadowModel     = "shadow-eval-model"
+)
+
+// shadowTestExecutor answers with a payload naming its provider and the requested model.
+type shadowTestExecutor struct {
+	provider string
+	calls    *atomic.Int32
+}
+
+func (e shadowTestExecutor) Identifier() string { return e.provider }
+
+func (e shadowTestExecutor) Execute(_ context.Context, _ *Auth, req cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	e.calls.Add(1)
+	payload := `{"provider":"` + e.provider + `","model":"` + req.Model + `","usage":{"total_tokens":7}}`
+	return cliproxyexecutor.Response{Payload: []byte(payload)}, nil
+}
+
+func (e shadowTestExecutor) ExecuteStream(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	e.calls.Add(1)
+	chunks := make(chan cliproxyexecutor.StreamChunk, 1)
+	chunks <- cliproxyexecutor.StreamChunk{Payload: []byte(`{"provider":"` + e.provider + `"}`)}
+	close(chunks)
+	return &cliproxyexecutor.StreamResult{Chunks: chunks}, nil
+}
+
+func (e shadowTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) { return auth, nil }
+
+func (e shadowTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+func (e shadowTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+type shadowTestRecorder struct {
+	mu    sync.Mutex
+	pairs []ShadowPair
+}
+
+func (r *shadowTestRecorder) ShadowRecordingEnabled() bool { return true }
+
+func (r *shadowTestRecorder) RecordShadowPair(pair ShadowPair) {
+	r.mu.Lock()
+	r.pairs = append(r.pairs, pair)
+	r.mu.Unlock()
+}
+
+func (r *shadowTestRecorder) snapshot() []ShadowPair {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ShadowPair(nil), r.pairs...)
+}
+
+type shadowTestHarness struct {
+	manager       *Manager
+	recorder      *shadowTestRecorder
+	primaryCalls  *atomic.Int32
+	shadowCalls   *atomic.Int32
+	shadowPending sync.WaitGroup
+}
+
+// newShadowTestHarness registers one auth serving the primary model and one serving the
+// shadow model. samples are returned in order by the sampler, then 0.
+func newShadowTestHarness(t *testing.T, shadow internalconfig.ShadowConfig, samples ...float64) *shadowTestHarness {
+	t.Helper()
+	h := &shadowTestHarness{
+		manager:      NewManager(nil, nil, nil),
+		recorder:     &shadowTestRecorder{},
+		primaryCalls: &atomic.Int32{},
+		shadowCalls:  &atomic.Int32{},
+	}
+	m := h.manager
+	m.SetConfig(&internalconfig.Config{Shadow: shadow})
+	m.RegisterExecutor(shadowTestExecutor{provider: shadowTestPrimaryProvider, calls: h.primaryCalls})
+	m.RegisterExecutor(shadowTestExecutor{provider: shadowTestShadowProvider, calls: h.shadowCalls})
+	m.SetShadowRecorder(h.recorder)
+
+	var sampleMu sync.Mutex
+	m.shadow.sample = func() float64 {
+		sampleMu.Lock()
+		defer sampleMu.Unlock()
+		if len(samples) == 0 {
+			return 0
+		}
+		value := samples[0]
+		samples = samples[1:]
+		return value
+	}
+	m.shadow.done = h.shadowPending.Done
+
+	reg := registry.GetGlobalRegistry()
+	for _, entry := range []struct{ provider, model string }{
+		{shadowTestPrimaryProvider, shadowTestPrimaryModel},
+		{shadowTestShadowProvider, shadowTestShadowModel},
+	} {
+		auth := &Auth{ID: t.Name() + "-" + entry.provider, Provider: entry.provider, Status: StatusActive}
+		if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+			t.Fatalf("register auth: %v", errRegister)
+		}
+		reg.RegisterClient(auth.ID, entry.provider, []*registry.ModelInfo{{ID: entry.model}})
+		t.Cleanup(func() { reg.UnregisterClient(auth.ID) })
+	}
+	return h
+}
+
+// execute runs a primary request; expectShadow tells whether the harness should wait for
+// a shadow request to be recorded.
+func (h *shadowTestHarness) execute(t *testing.T, ctx context.Context, expectShadow bool) cliproxyexecutor.Response {
+	t.Helper()
+	if expectShadow {
+		h.shadowPending.Add(1)
+	}
+	req := cliproxyexecutor.Request{Model: shadowTestPrimaryModel, Payload: []byte(`{"messages":[{"role":"user","content":"hi"}]}`)}
+	opts := cliproxyexecutor.Options{SourceFormat: sdktranslator.FromString("openai")}
+	resp, errExec := h.manager.Execute(ctx, []string{shadowTestPrimaryProvider}, req, opts)
+	if errExec != nil {
+		t.Fatalf("Execute() error = %v", errExec)
+	}
+	return resp
+}
+
+func TestManagerExecuteShadowLeavesClientResponseUnchanged(t *testing.T) {
+	plain := newShadowTestHarness(t, internalconfig.ShadowConfig{})
+	want := plain.execute(t, context.Background(), false)
+
+	h := newShadowTestHarness(t, internalconfig.ShadowConfig{
+		Enabled:     true,
+		SampleRate:  1,
+		ShadowModel: shadowTestShadowModel,
+	})
+	ctx := logging.WithRequestID(context.Background(), "req-shadow-1")
+	got := h.execute(t, ctx, true)
+	h.shadowPending.Wait()
+
+	if !bytes.Equal(got.Payload, want.Payload) {
+		t.Fatalf("client payload = %s, want %s", got.Payload, want.Payload)
+	}
+	pairs := h.recorder.snapshot()
+	if len(pairs) != 1 {
+		t.Fatalf("recorded pairs = %d, want 1", len(pairs))
+	}
+	pair := pairs[0]
+	if pair.RequestID != "req-shadow-1" {
+		t.Fatalf("pair request ID = %q, want %q", pair.RequestID, "req-shadow-1")
+	}
+	if pair.SourceFormat != "openai" {
+		t.Fatalf("pair source format = %q, want %q", pair.SourceFormat, "openai")
+	}
+	if !bytes.Equal(pair.Primary.Payload, want.Payload) || pair.Primary.Model != shadowTestPrimaryModel {
+		t.Fatalf("primary = %s (%s), want %s (%s)", pair.Primary.Payload, pair.Primary.Model, want.Payload, shadowTestPrimaryModel)
+	}
+	if pair.Shadow.Err != nil {
+		t.Fatalf("shadow error = %v", pair.Shadow.Err)
+	}
+	wantShadow := `{"provider":"` + shadowTestShadowProvider + `","model":"` + shadowTestShadowModel + `","usage":{"total_tokens":7}}`
+	if string(pair.Shadow.Payload) != wantShadow || pair.Shadow.Model != shadowTestShadowModel {
+		t.Fatalf("shadow = %s (%s), want %s (%s)", pair.Shadow.Payload, pair.Shadow.Model, wantShadow, shadowTestShadowModel)
+	}
+	if string(pair.Shadow.Usage) != `{"total_tokens":7}` {
+		t.Fatalf("shadow usage = %s, want %s", pair.Shadow.Usage, `{"total_tokens":7}`)
+	}
+	if calls := h.primaryCalls.Load(); calls != 1 {
+		t.Fatalf("primary calls = %d, want 1", calls)
+	}
+}
+
+func TestManagerExecuteShadowFollowsSampleRate(t *testing.T) {
+	samples := []float64{0.01, 0.5, 0.04, 0.9, 0.2}
+	h := newShadowTestHarness(t, internalconfig.ShadowConfig{
+		Enabled:     true,
+		SampleRate:  0.05,
+		ShadowModel: shadowTestShadowModel,
+	}, samples...)
+
+	for _, sample := range samples {
+		h.execute(t, context.Background(), sample < 0.05)
+	}
+	h.shadowPending.Wait()
+
+	if calls := h.shadowCalls.Load(); calls != 2 {
+		t.Fatalf("shadow calls = %d, want 2", calls)
+	}
+	if pairs := h.recorder.snapshot(); len(pairs) != 2 {
+		t.Fatalf("recorded pairs = %d, want 2", len(pairs))
+	}
+	if calls := h.primaryCalls.Load(); calls != int32(len(samples)) {
+		t.Fatalf("primary calls = %d, want %d", calls, len(samples))
+	}
+}
+
+func TestManagerExecuteShadowSkipsUnmatchedModels(t *testing.T) {
+	h := newShadowTestHarness(t, internalconfig.ShadowConfig{
+		Enabled:            true,
+		SampleRate:         1,
+		SourceModelPattern: "gpt-*",
+		ShadowModel:        shadowTestShadowModel,
+	})
+	h.execute(t, context.Background(), false)
+
+	if calls := h.shadowCalls.Load(); calls != 0 {
+		t.Fatalf("shadow calls = %d, want 0", calls)
+	}
+}
+
+func TestManagerExecuteStreamIsNotShadowed(t *testing.T) {
+	h := newShadowTestHarness(t, internalconfig.ShadowConfig{
+		Enabled:     true,
+		SampleRate:  1,
+		ShadowModel: shadowTestShadowModel,
+	})
+	req := cliproxyexecutor.Request{Model: shadowTestPrimaryModel, Payload: []byte(`{}`)}
+	opts := cliproxyexecutor.Options{Stream: true, SourceFormat: sdktranslator.FromString("openai")}
+	result, errStream := h.manager.ExecuteStream(context.Background(), []string{shadowTestPrimaryProvider}, req, opts)
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	for range result.Chunks {
+	}
+
+	if calls := h.shadowCalls.Load(); calls != 0 {
+		t.Fatalf("shadow calls = %d, want 0", calls)
+	}
+}
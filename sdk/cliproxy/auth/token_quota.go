@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/thinking"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tokenquota"
+)
+
+// quotaExhaustedError is returned when every credential able to serve a request was skipped
+// because it used up a token budget configured under token-quota.
+type quotaExhaustedError struct {
+	model   string
+	resetAt time.Time
+	now     time.Time
+}
+
+func (e *quotaExhaustedError) resetSeconds() int {
+	seconds := int(math.Ceil(e.resetAt.Sub(e.now).Seconds()))
+	if seconds < 0 {
+		return 0
+	}
+	return seconds
+}
+
+func (e *quotaExhaustedError) Error() string {
+	modelName := e.model
+	if modelName == "" {
+		modelName = "requested model"
+	}
+	message := fmt.Sprintf("All credentials for model %s have exhausted their token quota", modelName)
+	errorBody := map[string]any{
+		"code":          "quota_exhausted",
+		"message":       message,
+		"model":         e.model,
+		"reset_at":      e.resetAt.UTC().Format(time.RFC3339),
+		"reset_seconds": e.resetSeconds(),
+	}
+	data, err := json.Marshal(map[string]any{"error": errorBody})
+	if err != nil {
+		return fmt.Sprintf(`{"error":{"code":"quota_exhausted","message":"%s"}}`, message)
+	}
+	return string(data)
+}
+
+func (e *quotaExhaustedError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+func (e *quotaExhaustedError) Headers() http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	headers.Set("Retry-After", strconv.Itoa(e.resetSeconds()))
+	return headers
+}
+
+// tokenQuotaSkips records the credentials a pick skipped because of their token budget.
+type tokenQuotaSkips struct {
+	model   string
+	resetAt time.Time
+	now     time.Time
+}
+
+// exhausted reports whether auth reached a token budget that applies to model, remembering
+// the earliest reset among the skipped credentials.
+func (s *tokenQuotaSkips) exhausted(auth *Auth, now time.Time) bool {
+	tracker := tokenquota.Default()
+	if tracker == nil || auth == nil {
+		return false
+	}
+	exhaustion, ok := tracker.Check(auth.ID, auth.Index, s.model, now)
+	if !ok {
+		return false
+	}
+	if s.resetAt.IsZero() || exhaustion.ResetAt.Before(s.resetAt) {
+		s.resetAt = exhaustion.ResetAt
+	}
+	s.now = now
+	return true
+}
+
+// pickError replaces an error reporting that no credential is left with quota_exhausted when
+// credentials were skipped because of their token budget.
+func (s *tokenQuotaSkips) pickError(errPick error) error {
+	if s.resetAt.IsZero() {
+		return errPick
+	}
+	var authErr *Error
+	if errPick != nil && (!errors.As(errPick, &authErr) || (authErr.Code != "auth_not_found" && authErr.Code != "auth_unavailable")) {
+		return errPick
+	}
+	return &quotaExhaustedError{model: s.model, resetAt: s.resetAt, now: s.now}
+}
+
+func newTokenQuotaSkips(model string) *tokenQuotaSkips {
+	model = strings.TrimSpace(model)
+	if parsed := thinking.ParseSuffix(model); parsed.ModelName != "" {
+		model = strings.TrimSpace(parsed.ModelName)
+	}
+	return &tokenQuotaSkips{model: model}
+}
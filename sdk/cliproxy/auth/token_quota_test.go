@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tokenquota"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+// authEchoExecutor answers every request with the ID of the auth that served it.
+type authEchoExecutor struct {
+	schedulerProviderTestExecutor
+}
+
+func (e authEchoExecutor) Execute(ctx context.Context, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{Payload: []byte(auth.ID)}, nil
+}
+
+func newTokenQuotaTestManager(t *testing.T, rules []internalconfig.TokenQuotaRule, authIDs ...string) (*Manager, *tokenquota.Tracker) {
+	t.Helper()
+	cfg := &internalconfig.Config{}
+	cfg.TokenQuota = internalconfig.TokenQuotaConfig{
+		Path:  filepath.Join(t.TempDir(), "token-quota.json"),
+		Auths: rules,
+	}
+	if errConfigure := tokenquota.Configure(cfg); errConfigure != nil {
+		t.Fatalf("tokenquota.Configure() error = %v", errConfigure)
+	}
+	t.Cleanup(func() { _ = tokenquota.CloseDefault() })
+
+	manager := NewManager(nil, &RoundRobinSelector{}, nil)
+	manager.SetConfig(cfg)
+	manager.RegisterExecutor(authEchoExecutor{schedulerProviderTestExecutor{provider: "codex"}})
+	for _, authID := range authIDs {
+		if _, errRegister := manager.Register(context.Background(), &Auth{ID: authID, Provider: "codex"}); errRegister != nil {
+			t.Fatalf("register auth %s: %v", authID, errRegister)
+		}
+	}
+	registerSchedulerModels(t, "codex", "quota-model", authIDs...)
+	return manager, tokenquota.Default()
+}
+
+func executeQuotaModel(manager *Manager) (string, error) {
+	resp, err := manager.Execute(context.Background(), []string{"codex"}, cliproxyexecutor.Request{Model: "quota-model"}, cliproxyexecutor.Options{})
+	return string(resp.Payload), err
+}
+
+func TestManagerExecute_TokenQuotaExcludesExhaustedAuth(t *testing.T) {
+	manager, tracker := newTokenQuotaTestManager(t, []internalconfig.TokenQuotaRule{
+		{Auth: "quota-a", TokenQuotaLimits: internalconfig.TokenQuotaLimits{DailyTokenLimit: 1000}},
+	}, "quota-a", "quota-b")
+
+	tracker.Add("quota-a", "", []string{"quota-model"}, 600, time.Now())
+	tracker.Add("quota-a", "", []string{"quota-model"}, 600, time.Now())
+
+	for i := 0; i < 4; i++ {
+		served, err := executeQuotaModel(manager)
+		if err != nil {
+			t.Fatalf("Execute error: %v", err)
+		}
+		if served != "quota-b" {
+			t.Fatalf("request %d served by %q, want quota-b while quota-a is over its daily limit", i, served)
+		}
+	}
+}
+
+func TestManagerExecute_TokenQuotaExhaustedReturns429(t *testing.T) {
+	manager, tracker := newTokenQuotaTestManager(t, []internalconfig.TokenQuotaRule{
+		{Auth: "quota-only", Models: map[string]internalconfig.TokenQuotaLimits{"quota-model": {MonthlyTokenLimit: 500}}},
+	}, "quota-only")
+
+	if served, err := executeQuotaModel(manager); err != nil || served != "quota-only" {
+		t.Fatalf("Execute before the limit = %q, %v; want quota-only", served, err)
+	}
+	tracker.Add("quota-only", "", []string{"quota-model"}, 500, time.Now())
+
+	_, err := executeQuotaModel(manager)
+	var quotaErr *quotaExhaustedError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Execute error = %v, want quotaExhaustedError", err)
+	}
+	if quotaErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", quotaErr.StatusCode())
+	}
+	body := []byte(err.Error())
+	if got := gjson.GetBytes(body, "error.code").String(); got != "quota_exhausted" {
+		t.Fatalf("error.code = %q, want quota_exhausted; body=%s", got, body)
+	}
+	now := time.Now().UTC()
+	wantReset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	resetAt, errParse := time.Parse(time.RFC3339, gjson.GetBytes(body, "error.reset_at").String())
+	if errParse != nil || !resetAt.Equal(wantReset) {
+		t.Fatalf("error.reset_at = %v (%v), want %s; body=%s", resetAt, errParse, wantReset, body)
+	}
+	if gjson.GetBytes(body, "error.reset_seconds").Int() <= 0 {
+		t.Fatalf("error.reset_seconds missing; body=%s", body)
+	}
+	if got := quotaErr.Headers().Get("Retry-After"); got == "" || got == "0" {
+		t.Fatalf("Retry-After = %q, want the seconds until the reset", got)
+	}
+
+	if _, errReset := tracker.Reset("quota-only"); errReset != nil {
+		t.Fatalf("Reset() error = %v", errReset)
+	}
+	if served, errAfter := executeQuotaModel(manager); errAfter != nil || served != "quota-only" {
+		t.Fatalf("Execute after reset = %q, %v; want quota-only", served, errAfter)
+	}
+}
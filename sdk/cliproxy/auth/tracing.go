@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/tracing"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// pickNextMixed selects the next auth inside an "auth.select" span. The provider and
+// auth of the selection are also set on the span in ctx, so the request span names the
+// credential of its last attempt.
+func (m *Manager) pickNextMixed(ctx context.Context, providers []string, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, string, error) {
+	spanCtx, span := tracing.Start(ctx, "auth.select", tracing.AttrModel.String(model))
+	auth, executor, provider, errPick := m.selectNextMixed(spanCtx, providers, model, opts, tried)
+	if errPick == nil && auth != nil && span.IsRecording() {
+		attrs := authSpanAttributes(provider, auth)
+		span.SetAttributes(attrs...)
+		tracing.SetAttributes(ctx, attrs...)
+	}
+	tracing.End(span, errPick)
+	return auth, executor, provider, errPick
+}
+
+// startAttemptSpan starts the span of one upstream attempt of auth for model. The HTTP
+// calls of the executor, including base URL fallbacks and retries, are its children.
+func startAttemptSpan(ctx context.Context, provider string, auth *Auth, model string) (context.Context, trace.Span) {
+	if !tracing.Enabled() {
+		return tracing.Start(ctx, "upstream.attempt")
+	}
+	attrs := append(authSpanAttributes(provider, auth), tracing.AttrModel.String(model))
+	return tracing.Start(ctx, "upstream.attempt", attrs...)
+}
+
+func authSpanAttributes(provider string, auth *Auth) []attribute.KeyValue {
+	label := strings.TrimSpace(auth.Label)
+	if label == "" {
+		label = auth.ID
+	}
+	return []attribute.KeyValue{
+		tracing.AttrProvider.String(provider),
+		tracing.AttrAuthID.String(auth.ID),
+		tracing.AttrAuthLabel.String(label),
+	}
+}
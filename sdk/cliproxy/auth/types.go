@@ -459,7 +459,7 @@ func (a *Auth) DisableCoolingOverride() (bool, bool) {
 	return false, false
 }
 
-// ToolPrefixDisabled returns whether the proxy_ tool name prefix should be
+// ToolPrefixDisabled returns whether the tool name prefix should be
 // skipped for this auth. When true, tool names are sent to Anthropic unchanged.
 // The value is read from metadata key "tool_prefix_disabled" (or "tool-prefix-disabled").
 func (a *Auth) ToolPrefixDisabled() bool {
@@ -476,6 +476,32 @@ func (a *Auth) ToolPrefixDisabled() bool {
 	return false
 }
 
+// ToolPrefix returns the Claude OAuth tool name prefix configured for this auth.
+// The value is read from attribute "tool_prefix", then metadata key "tool_prefix"
+// (or "tool-prefix"). An empty configured value disables prefixing. The boolean
+// reports whether a value was configured at all.
+func (a *Auth) ToolPrefix() (string, bool) {
+	if a == nil {
+		return "", false
+	}
+	if a.Attributes != nil {
+		if val, ok := a.Attributes["tool_prefix"]; ok {
+			return strings.TrimSpace(val), true
+		}
+	}
+	if a.Metadata == nil {
+		return "", false
+	}
+	for _, key := range []string{"tool_prefix", "tool-prefix"} {
+		if val, ok := a.Metadata[key]; ok {
+			if s, okString := val.(string); okString {
+				return strings.TrimSpace(s), true
+			}
+		}
+	}
+	return "", false
+}
+
 // RequestRetryOverride returns the auth-file scoped request_retry override when present.
 // The value is read from metadata key "request_retry" (or legacy "request-retry").
 func (a *Auth) RequestRetryOverride() (int, bool) {
@@ -40,6 +40,28 @@ func TestToolPrefixDisabled(t *testing.T) {
 	}
 }
 
+func TestToolPrefix(t *testing.T) {
+	var a *Auth
+	if _, ok := a.ToolPrefix(); ok {
+		t.Error("nil auth should report no configured prefix")
+	}
+
+	a = &Auth{Attributes: map[string]string{"tool_prefix": "mcp_"}, Metadata: map[string]any{"tool_prefix": "meta_"}}
+	if prefix, ok := a.ToolPrefix(); !ok || prefix != "mcp_" {
+		t.Errorf("attribute prefix = %q, %v; want mcp_, true", prefix, ok)
+	}
+
+	a = &Auth{Metadata: map[string]any{"tool-prefix": "meta_"}}
+	if prefix, ok := a.ToolPrefix(); !ok || prefix != "meta_" {
+		t.Errorf("metadata prefix = %q, %v; want meta_, true", prefix, ok)
+	}
+
+	a = &Auth{Attributes: map[string]string{"tool_prefix": ""}}
+	if prefix, ok := a.ToolPrefix(); !ok || prefix != "" {
+		t.Errorf("empty prefix = %q, %v; want empty, true", prefix, ok)
+	}
+}
+
 func TestEnsureIndexUsesCredentialIdentity(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+// upstreamRetryWindow is the period the per-auth retry budget applies to.
+const upstreamRetryWindow = time.Minute
+
+// upstreamRetryBudget limits how many same-credential retries each auth may spend per
+// window, so a failing upstream does not receive a multiple of the regular load.
+// The zero value is ready to use.
+type upstreamRetryBudget struct {
+	mu      sync.Mutex
+	windows map[string]*upstreamRetryWindowState
+}
+
+type upstreamRetryWindowState struct {
+	start time.Time
+	spent int
+}
+
+// take spends one retry of authID and reports whether the budget allowed it.
+func (b *upstreamRetryBudget) take(authID string, limit int, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.windows == nil {
+		b.windows = make(map[string]*upstreamRetryWindowState)
+	}
+	state := b.windows[authID]
+	if state == nil || now.Sub(state.start) >= upstreamRetryWindow {
+		// Drop other expired windows while the lock is held, keeping the map bounded by
+		// the auths that retried within the last window.
+		for id, other := range b.windows {
+			if now.Sub(other.start) >= upstreamRetryWindow {
+				delete(b.windows, id)
+			}
+		}
+		state = &upstreamRetryWindowState{start: now}
+		b.windows[authID] = state
+	}
+	if state.spent >= limit {
+		return false
+	}
+	state.spent++
+	return true
+}
+
+// isTransientUpstreamError reports whether err is a failure that may succeed when the
+// same request is sent again: HTTP 500, 502, 503, 504 or a reset connection. An
+// overloaded upstream (529) is not retried on the same auth; the auth cools down and the
+// request moves on to another credential.
+func isTransientUpstreamError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch statusCodeFromError(err) {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case 0:
+		return errors.Is(err, syscall.ECONNRESET) || strings.Contains(err.Error(), "connection reset by peer")
+	default:
+		return false
+	}
+}
+
+// upstreamRetryBackoff returns the delay before retry number attempt (1-based): an
+// exponential backoff capped at maxBackoff, with the upper half randomized.
+func upstreamRetryBackoff(cfg internalconfig.UpstreamRetryConfig, attempt int) time.Duration {
+	maxBackoff := cfg.MaxBackoff()
+	delay := cfg.InitialBackoff()
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
+
+// waitUpstreamRetry decides whether a request that failed with err on auth is retried on
+// the same auth after attempt attempts, and waits for the backoff when it is. It returns
+// false when retries are disabled or exhausted, err is not transient, the auth's budget is
+// spent, the upstream asked to wait longer than the backoff cap, or ctx ends first.
+func (m *Manager) waitUpstreamRetry(ctx context.Context, auth *Auth, err error, attempt int) bool {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || auth == nil || attempt >= cfg.Retry.MaxAttempts || !isTransientUpstreamError(err) {
+		return false
+	}
+	delay := upstreamRetryBackoff(cfg.Retry, attempt)
+	if retryAfter := retryAfterFromError(err); retryAfter != nil {
+		if *retryAfter > cfg.Retry.MaxBackoff() {
+			return false
+		}
+		delay = max(*retryAfter, 0)
+	}
+	if !m.upstreamRetryBudget.take(auth.ID, cfg.Retry.Budget(), time.Now()) {
+		logEntryWithRequestID(ctx).Debugf("upstream retry: budget of auth %s exhausted, not retrying: %v", auth.ID, err)
+		return false
+	}
+	logEntryWithRequestID(ctx).Debugf("upstream retry: attempt %d/%d on auth %s failed, retrying in %s: %v", attempt, cfg.Retry.MaxAttempts, auth.ID, delay, err)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// executeWithUpstreamRetry runs a non-streaming request, retrying transient failures on
+// the same auth as configured by the retry config.
+func (m *Manager) executeWithUpstreamRetry(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	resp, errExec := executor.Execute(ctx, auth, req, opts)
+	for attempt := 1; errExec != nil && m.waitUpstreamRetry(ctx, auth, errExec, attempt); attempt++ {
+		resp, errExec = executor.Execute(ctx, auth, req, opts)
+	}
+	return resp, errExec
+}
+
+// executeStreamWithUpstreamRetry starts a streaming request, retrying transient failures
+// on the same auth until the stream delivers its first payload. Once a payload was read
+// the stream is returned as is, so no retry can repeat data the client already received.
+func (m *Manager) executeStreamWithUpstreamRetry(ctx context.Context, executor ProviderExecutor, auth *Auth, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	cfg, _ := m.runtimeConfig.Load().(*internalconfig.Config)
+	if cfg == nil || cfg.Retry.MaxAttempts <= 1 {
+		return executor.ExecuteStream(ctx, auth, req, opts)
+	}
+	for attempt := 1; ; attempt++ {
+		streamResult, errStream := executor.ExecuteStream(ctx, auth, req, opts)
+		if errStream != nil {
+			if m.waitUpstreamRetry(ctx, auth, errStream, attempt) {
+				continue
+			}
+			return streamResult, errStream
+		}
+		if streamResult == nil || streamResult.Chunks == nil {
+			return streamResult, nil
+		}
+		buffered, closed, errBootstrap := readStreamBootstrap(ctx, streamResult.Chunks)
+		if errBootstrap == nil {
+			return prependStreamChunks(streamResult, buffered, closed), nil
+		}
+		discardStreamChunks(streamResult.Chunks)
+		if !m.waitUpstreamRetry(ctx, auth, errBootstrap, attempt) {
+			return streamErrorResult(streamResult.Headers, errBootstrap), nil
+		}
+	}
+}
+
+// prependStreamChunks returns a stream that yields buffered before the rest of result.
+func prependStreamChunks(result *cliproxyexecutor.StreamResult, buffered []cliproxyexecutor.StreamChunk, closed bool) *cliproxyexecutor.StreamResult {
+	out := make(chan cliproxyexecutor.StreamChunk, len(buffered))
+	for _, chunk := range buffered {
+		out <- chunk
+	}
+	if closed {
+		close(out)
+		return &cliproxyexecutor.StreamResult{Headers: result.Headers, Chunks: out}
+	}
+	remaining := result.Chunks
+	go func() {
+		defer close(out)
+		for chunk := range remaining {
+			out <- chunk
+		}
+	}()
+	return &cliproxyexecutor.StreamResult{Headers: result.Headers, Chunks: out}
+}
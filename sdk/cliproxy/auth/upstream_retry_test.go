@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	internalconfig "github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/executor"
+)
+
+const upstreamRetryTestProvider = "upstream-retry-test"
+
+type upstreamRetryTestError struct {
+	status     int
+	retryAfter *time.Duration
+}
+
+func (e *upstreamRetryTestError) Error() string { return fmt.Sprintf("upstream status %d", e.status) }
+
+func (e *upstreamRetryTestError) StatusCode() int { return e.status }
+
+func (e *upstreamRetryTestError) RetryAfter() *time.Duration { return e.retryAfter }
+
+// upstreamRetryTestExecutor posts to the auth's base_url. Streams send the request from
+// the stream goroutine, so upstream failures surface as the first chunk's error the way
+// they do when a real stream fails during bootstrap.
+type upstreamRetryTestExecutor struct{}
+
+func (upstreamRetryTestExecutor) Identifier() string { return upstreamRetryTestProvider }
+
+func (upstreamRetryTestExecutor) call(ctx context.Context, auth *Auth) ([]byte, error) {
+	httpReq, errReq := http.NewRequestWithContext(ctx, http.MethodPost, auth.Attributes["base_url"], nil)
+	if errReq != nil {
+		return nil, errReq
+	}
+	httpResp, errDo := http.DefaultClient.Do(httpReq)
+	if errDo != nil {
+		return nil, errDo
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+	body, errRead := io.ReadAll(httpResp.Body)
+	if errRead != nil {
+		return nil, errRead
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, &upstreamRetryTestError{status: httpResp.StatusCode}
+	}
+	return body, nil
+}
+
+func (e upstreamRetryTestExecutor) Execute(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	body, errCall := e.call(ctx, auth)
+	if errCall != nil {
+		return cliproxyexecutor.Response{}, errCall
+	}
+	return cliproxyexecutor.Response{Payload: body}, nil
+}
+
+func (e upstreamRetryTestExecutor) ExecuteStream(ctx context.Context, auth *Auth, _ cliproxyexecutor.Request, _ cliproxyexecutor.Options) (*cliproxyexecutor.StreamResult, error) {
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		body, errCall := e.call(ctx, auth)
+		if errCall != nil {
+			out <- cliproxyexecutor.StreamChunk{Err: errCall}
+			return
+		}
+		out <- cliproxyexecutor.StreamChunk{Payload: body}
+		if auth.Attributes["fail_mid_stream"] == "true" {
+			out <- cliproxyexecutor.StreamChunk{Err: &upstreamRetryTestError{status: http.StatusServiceUnavailable}}
+		}
+	}()
+	return &cliproxyexecutor.StreamResult{Chunks: out}, nil
+}
+
+func (upstreamRetryTestExecutor) Refresh(_ context.Context, auth *Auth) (*Auth, error) {
+	return auth, nil
+}
+
+func (upstreamRetryTestExecutor) CountTokens(context.Context, *Auth, cliproxyexecutor.Request, cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	return cliproxyexecutor.Response{}, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+func (upstreamRetryTestExecutor) HttpRequest(context.Context, *Auth, *http.Request) (*http.Response, error) {
+	return nil, &Error{HTTPStatus: http.StatusNotImplemented, Message: "not implemented"}
+}
+
+// flakyUpstream fails the first failures requests with status and answers "ok" afterwards.
+func flakyUpstream(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var arrivals atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if arrivals.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &arrivals
+}
+
+func newUpstreamRetryTestManager(t *testing.T, retry internalconfig.UpstreamRetryConfig, attributes map[string]string) *Manager {
+	t.Helper()
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{Retry: retry})
+	m.RegisterExecutor(upstreamRetryTestExecutor{})
+	auth := &Auth{
+		ID:         t.Name() + "-auth",
+		Provider:   upstreamRetryTestProvider,
+		Status:     StatusActive,
+		Attributes: attributes,
+	}
+	if _, errRegister := m.Register(context.Background(), auth); errRegister != nil {
+		t.Fatalf("register auth: %v", errRegister)
+	}
+	reg := registry.GetGlobalRegistry()
+	reg.RegisterClient(auth.ID, upstreamRetryTestProvider, []*registry.ModelInfo{{ID: "retry-model"}})
+	t.Cleanup(func() { reg.UnregisterClient(auth.ID) })
+	return m
+}
+
+var fastUpstreamRetry = internalconfig.UpstreamRetryConfig{MaxAttempts: 3, InitialBackoffMillis: 1, MaxBackoffMillis: 5}
+
+func readUpstreamRetryStream(t *testing.T, result *cliproxyexecutor.StreamResult) (string, error) {
+	t.Helper()
+	var payload []byte
+	for chunk := range result.Chunks {
+		if chunk.Err != nil {
+			return string(payload), chunk.Err
+		}
+		payload = append(payload, chunk.Payload...)
+	}
+	return string(payload), nil
+}
+
+func TestManagerExecuteRetriesTransientUpstreamFailures(t *testing.T) {
+	upstream, arrivals := flakyUpstream(t, 2, http.StatusServiceUnavailable)
+	m := newUpstreamRetryTestManager(t, fastUpstreamRetry, map[string]string{"base_url": upstream.URL})
+
+	resp, errExec := m.Execute(context.Background(), []string{upstreamRetryTestProvider}, cliproxyexecutor.Request{Model: "retry-model"}, cliproxyexecutor.Options{})
+	if errExec != nil {
+		t.Fatalf("Execute() error = %v", errExec)
+	}
+	if string(resp.Payload) != "ok" {
+		t.Fatalf("payload = %q, want ok", resp.Payload)
+	}
+	if got := arrivals.Load(); got != 3 {
+		t.Fatalf("upstream requests = %d, want 3", got)
+	}
+}
+
+func TestManagerExecuteStreamRetriesBeforeFirstPayload(t *testing.T) {
+	upstream, arrivals := flakyUpstream(t, 2, http.StatusBadGateway)
+	m := newUpstreamRetryTestManager(t, fastUpstreamRetry, map[string]string{"base_url": upstream.URL})
+
+	result, errStream := m.ExecuteStream(context.Background(), []string{upstreamRetryTestProvider}, cliproxyexecutor.Request{Model: "retry-model"}, cliproxyexecutor.Options{})
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	payload, errRead := readUpstreamRetryStream(t, result)
+	if errRead != nil || payload != "ok" {
+		t.Fatalf("stream = %q, %v; want ok", payload, errRead)
+	}
+	if got := arrivals.Load(); got != 3 {
+		t.Fatalf("upstream requests = %d, want 3", got)
+	}
+}
+
+func TestManagerExecuteStreamDoesNotRetryAfterFirstPayload(t *testing.T) {
+	upstream, arrivals := flakyUpstream(t, 0, http.StatusOK)
+	m := newUpstreamRetryTestManager(t, fastUpstreamRetry, map[string]string{"base_url": upstream.URL, "fail_mid_stream": "true"})
+
+	result, errStream := m.ExecuteStream(context.Background(), []string{upstreamRetryTestProvider}, cliproxyexecutor.Request{Model: "retry-model"}, cliproxyexecutor.Options{})
+	if errStream != nil {
+		t.Fatalf("ExecuteStream() error = %v", errStream)
+	}
+	payload, errRead := readUpstreamRetryStream(t, result)
+	if payload != "ok" || errRead == nil {
+		t.Fatalf("stream = %q, %v; want ok followed by the mid-stream error", payload, errRead)
+	}
+	if got := arrivals.Load(); got != 1 {
+		t.Fatalf("upstream requests = %d, want 1", got)
+	}
+}
+
+func TestManagerExecuteDoesNotRetryClientErrors(t *testing.T) {
+	upstream, arrivals := flakyUpstream(t, 1, http.StatusTooManyRequests)
+	m := newUpstreamRetryTestManager(t, fastUpstreamRetry, map[string]string{"base_url": upstream.URL})
+
+	if _, errExec := m.Execute(context.Background(), []string{upstreamRetryTestProvider}, cliproxyexecutor.Request{Model: "retry-model"}, cliproxyexecutor.Options{}); errExec == nil {
+		t.Fatal("Execute() succeeded, want the 429 returned")
+	}
+	if got := arrivals.Load(); got != 1 {
+		t.Fatalf("upstream requests = %d, want 1", got)
+	}
+}
+
+func TestManagerExecuteStopsRetryingWhenBudgetIsSpent(t *testing.T) {
+	upstream, arrivals := flakyUpstream(t, 100, http.StatusInternalServerError)
+	retry := fastUpstreamRetry
+	retry.BudgetPerMinute = 3
+	m := newUpstreamRetryTestManager(t, retry, map[string]string{"base_url": upstream.URL})
+
+	for i := 0; i < 3; i++ {
+		_, _ = m.Execute(context.Background(), []string{upstreamRetryTestProvider}, cliproxyexecutor.Request{Model: "retry-model"}, cliproxyexecutor.Options{})
+		m.mu.Lock()
+		for _, auth := range m.auths {
+			clearCooldownStateForAuth(auth, time.Now())
+		}
+		m.mu.Unlock()
+		m.syncScheduler()
+	}
+	// 3 first attempts plus the 3 retries the budget allows.
+	if got := arrivals.Load(); got != 6 {
+		t.Fatalf("upstream requests = %d, want 6", got)
+	}
+}
+
+func TestWaitUpstreamRetryHonorsRetryAfter(t *testing.T) {
+	m := NewManager(nil, nil, nil)
+	m.SetConfig(&internalconfig.Config{Retry: internalconfig.UpstreamRetryConfig{MaxAttempts: 3, MaxBackoffMillis: 200}})
+	auth := &Auth{ID: "retry-after"}
+
+	short := 20 * time.Millisecond
+	started := time.Now()
+	if !m.waitUpstreamRetry(context.Background(), auth, &upstreamRetryTestError{status: http.StatusServiceUnavailable, retryAfter: &short}, 1) {
+		t.Fatal("short Retry-After was not retried")
+	}
+	if elapsed := time.Since(started); elapsed < short {
+		t.Fatalf("waited %s, want at least the Retry-After of %s", elapsed, short)
+	}
+
+	long := time.Minute
+	if m.waitUpstreamRetry(context.Background(), auth, &upstreamRetryTestError{status: http.StatusServiceUnavailable, retryAfter: &long}, 1) {
+		t.Fatal("Retry-After above max-backoff-ms was retried")
+	}
+	if m.waitUpstreamRetry(context.Background(), auth, &upstreamRetryTestError{status: http.StatusServiceUnavailable}, 3) {
+		t.Fatal("retried past max-attempts")
+	}
+}
+
+func TestIsTransientUpstreamError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&upstreamRetryTestError{status: http.StatusInternalServerError}, true},
+		{&upstreamRetryTestError{status: http.StatusBadGateway}, true},
+		{&upstreamRetryTestError{status: http.StatusServiceUnavailable}, true},
+		{&upstreamRetryTestError{status: http.StatusGatewayTimeout}, true},
+		{&upstreamRetryTestError{status: http.StatusNotImplemented}, false},
+		{&upstreamRetryTestError{status: http.StatusBadRequest}, false},
+		{&upstreamRetryTestError{status: http.StatusTooManyRequests}, false},
+		{fmt.Errorf("post: %w", syscall.ECONNRESET), true},
+		{context.Canceled, false},
+		{fmt.Errorf("dial: connection refused"), false},
+	}
+	for _, tc := range cases {
+		if got := isTransientUpstreamError(tc.err); got != tc.want {
+			t.Errorf("isTransientUpstreamError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestUpstreamRetryBackoffGrowsAndIsCapped(t *testing.T) {
+	cfg := internalconfig.UpstreamRetryConfig{InitialBackoffMillis: 100, MaxBackoffMillis: 300}
+	for attempt, bounds := range map[int][2]time.Duration{
+		1: {50 * time.Millisecond, 100 * time.Millisecond},
+		2: {100 * time.Millisecond, 200 * time.Millisecond},
+		5: {150 * time.Millisecond, 300 * time.Millisecond},
+	} {
+		for i := 0; i < 20; i++ {
+			if got := upstreamRetryBackoff(cfg, attempt); got < bounds[0] || got > bounds[1] {
+				t.Fatalf("backoff for attempt %d = %s, want within %v", attempt, got, bounds)
+			}
+		}
+	}
+}
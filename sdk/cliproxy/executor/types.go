@@ -15,6 +15,22 @@ const RequestedModelMetadataKey = "requested_model"
 // It is optional and may be absent for non-HTTP executions.
 const RequestPathMetadataKey = "request_path"
 
+// IdempotencyKeyMetadataKey stores the client-supplied Idempotency-Key header in Options.Metadata.
+// It is only present when the client sent one.
+const IdempotencyKeyMetadataKey = "idempotency_key"
+
+// SessionIDMetadataKey stores the session ID a client pinned with the X-Session-Id header or
+// a session_id (or metadata.session_id) body field in Options.Metadata. It is only present
+// when the client sent one.
+const SessionIDMetadataKey = "session_id"
+
+// PriorityMetadataKey stores the request priority class ("high", "normal" or "low") in Options.Metadata.
+const PriorityMetadataKey = "priority"
+
+// ClientAPIKeyMetadataKey stores the API key the client authenticated with, which selects
+// the auths of its routing.api-key-routes entry.
+const ClientAPIKeyMetadataKey = "client_api_key"
+
 // DisallowFreeAuthMetadataKey instructs auth selection to skip known free-tier credentials.
 const DisallowFreeAuthMetadataKey = "disallow_free_auth"
 
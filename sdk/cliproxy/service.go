@@ -35,6 +35,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/usage"
 	"github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
 	sdkpluginstore "github.com/router-for-me/CLIProxyAPI/v7/sdk/pluginstore"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/secrets"
 	sdktranslator "github.com/router-for-me/CLIProxyAPI/v7/sdk/translator"
 	log "github.com/sirupsen/logrus"
 )
@@ -114,6 +115,10 @@ type Service struct {
 	// wsGateway manages websocket Gemini providers.
 	wsGateway *wsrelay.Manager
 
+	// antigravityModels caches fetchAvailableModels results per auth and
+	// tracks background re-fetches after failures.
+	antigravityModels antigravityModelHintsCache
+
 	homeLifecycleMu              sync.Mutex
 	homeOwnershipMu              sync.Mutex
 	homeConfigCommitMu           sync.Mutex
@@ -894,6 +899,9 @@ func (s *Service) applyCoreAuthRemoval(ctx context.Context, id string) {
 	}
 	GlobalModelRegistry().UnregisterClient(id)
 	s.coreManager.Remove(ctx, id)
+	s.antigravityModels.forget(id)
+	executor.ForgetOpenAICompatEndpointHealthForAuthID(id)
+	executor.ForgetAntigravityBaseURLCounterForAuthID(id)
 	if strings.EqualFold(provider, "codex") {
 		executor.CloseCodexWebsocketSessionsForAuthID(id, "auth_removed")
 	}
@@ -1426,6 +1434,7 @@ type routingRuntimeState struct {
 	strategy           string
 	sessionAffinity    bool
 	sessionAffinityTTL time.Duration
+	sessionAffinityMax int
 }
 
 func normalizedRoutingRuntimeState(cfg *config.Config) routingRuntimeState {
@@ -1447,6 +1456,9 @@ func normalizedRoutingRuntimeState(cfg *config.Config) routingRuntimeState {
 			state.sessionAffinityTTL = parsed
 		}
 	}
+	if cfg.Routing.SessionAffinityMaxEntries > 0 {
+		state.sessionAffinityMax = cfg.Routing.SessionAffinityMaxEntries
+	}
 	return state
 }
 
@@ -1459,8 +1471,9 @@ func newRoutingSelector(state routingRuntimeState) coreauth.Selector {
 	}
 	if state.sessionAffinity {
 		selector = coreauth.NewSessionAffinitySelectorWithConfig(coreauth.SessionAffinityConfig{
-			Fallback: selector,
-			TTL:      state.sessionAffinityTTL,
+			Fallback:   selector,
+			TTL:        state.sessionAffinityTTL,
+			MaxEntries: state.sessionAffinityMax,
 		})
 	}
 	return selector
@@ -2345,15 +2358,20 @@ func (s *Service) Run(ctx context.Context) error {
 	}()
 
 	usage.StartDefault(ctx)
+	if s.cfg != nil {
+		secrets.SetPolicy(s.cfg.SecretReferences.Policy())
+	}
 	homeEnabled := s.cfg != nil && s.cfg.Home.Enabled
 	if homeEnabled {
 		forceHomeRuntimeConfig(s.cfg)
 		redisqueue.SetUsageStatisticsEnabled(true)
 	}
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer shutdownCancel()
 	defer func() {
+		// The deadline starts when Run returns so a long-running service still gets the
+		// full drain window for active streams.
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), s.shutdownTimeout())
+		defer shutdownCancel()
 		if err := s.Shutdown(shutdownCtx); err != nil {
 			log.Errorf("service shutdown returned error: %v", err)
 		}
@@ -2510,6 +2528,18 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 }
 
+// shutdownTimeout bounds Shutdown: the stream drain window plus time for cancelled
+// streams and the remaining resources to stop.
+func (s *Service) shutdownTimeout() time.Duration {
+	drainWindow := config.DefaultShutdownDrainSeconds * time.Second
+	s.cfgMu.RLock()
+	if s.cfg != nil {
+		drainWindow = s.cfg.Server.EffectiveShutdownDrainTimeout()
+	}
+	s.cfgMu.RUnlock()
+	return drainWindow + 30*time.Second
+}
+
 // Shutdown gracefully stops background workers and the HTTP server.
 // It ensures all resources are properly cleaned up and connections are closed.
 // The shutdown is idempotent and can be called multiple times safely.
@@ -2583,6 +2613,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
 		}
+		s.antigravityModels.stop()
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
 				log.Errorf("failed to stop file watcher: %v", err)
@@ -2612,7 +2643,7 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		// no legacy clients to persist
 
 		if s.server != nil {
-			shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			shutdownCtx, cancel := context.WithTimeout(ctx, s.shutdownTimeout())
 			defer cancel()
 			if err := s.server.Stop(shutdownCtx); err != nil {
 				log.Errorf("error stopping API server: %v", err)
@@ -2751,7 +2782,7 @@ func (s *Service) registerModelsForAuthWithCache(ctx context.Context, a *coreaut
 		models = applyExcludedModels(models, excluded)
 	case "antigravity":
 		models = registry.GetAntigravityModels()
-		models = applyAntigravityFetchedModelCapabilities(models, s.fetchAntigravityModelCapabilityHintsForAuth(ctx, a))
+		models = applyAntigravityFetchedModelCapabilities(models, s.antigravityModelCapabilityHintsForAuth(ctx, a))
 		models = applyExcludedModels(models, excluded)
 	case "claude":
 		models = registry.GetClaudeModels()
@@ -3384,15 +3415,31 @@ func buildConfigModels[T modelEntry](models []T, ownedBy, modelType string) []*M
 		}
 		seen[key] = struct{}{}
 		if name != "" {
-			if upstream := registry.LookupStaticModelInfo(name); upstream != nil && upstream.Thinking != nil {
-				info.Thinking = upstream.Thinking
-			}
+			inheritStaticModelMetadata(info, registry.LookupStaticModelInfo(name))
 		}
 		out = append(out, info)
 	}
 	return out
 }
 
+// inheritStaticModelMetadata copies thinking support and token limits from the
+// upstream static model so aliases advertise the same capabilities as their target.
+func inheritStaticModelMetadata(info, upstream *ModelInfo) {
+	if info == nil || upstream == nil {
+		return
+	}
+	if upstream.Thinking != nil {
+		info.Thinking = upstream.Thinking
+	}
+	info.ContextLength = upstream.ContextLength
+	info.MaxCompletionTokens = upstream.MaxCompletionTokens
+	info.InputTokenLimit = upstream.InputTokenLimit
+	info.OutputTokenLimit = upstream.OutputTokenLimit
+	if len(upstream.SupportedParameters) > 0 {
+		info.SupportedParameters = append([]string(nil), upstream.SupportedParameters...)
+	}
+}
+
 func buildVertexCompatConfigModels(entry *config.VertexCompatKey) []*ModelInfo {
 	if entry == nil {
 		return nil
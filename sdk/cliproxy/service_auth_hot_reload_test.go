@@ -0,0 +1,91 @@
+package cliproxy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/watcher"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+	"github.com/router-for-me/CLIProxyAPI/v7/sdk/config"
+)
+
+func TestServiceAuthDirHotReload_AddsAndRemovesAuths(t *testing.T) {
+	tmpDir := t.TempDir()
+	authDir := filepath.Join(tmpDir, "auth")
+	if err := os.MkdirAll(authDir, 0o755); err != nil {
+		t.Fatalf("failed to create auth dir: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("auth-dir: "+authDir+"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg := &config.Config{AuthDir: authDir}
+	service := &Service{
+		cfg:         cfg,
+		coreManager: coreauth.NewManager(nil, nil, nil),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.ensureAuthUpdateQueue(ctx)
+	defer service.authQueueStop()
+
+	w, err := watcher.NewWatcher(configPath, authDir, nil)
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer func() { _ = w.Stop() }()
+	w.SetConfig(cfg)
+	w.SetAuthUpdateQueue(service.authUpdates)
+	if err = w.Start(ctx); err != nil {
+		t.Fatalf("failed to start watcher: %v", err)
+	}
+
+	authPath := filepath.Join(authDir, "codex-hot-reload.json")
+	if err = os.WriteFile(authPath, []byte(`{"type":"codex","email":"hot-reload@example.com","access_token":"token"}`), 0o600); err != nil {
+		t.Fatalf("failed to write auth file: %v", err)
+	}
+
+	var authID string
+	waitForAuthReload(t, "auth registration", func() bool {
+		for _, auth := range service.coreManager.List() {
+			if auth != nil && auth.Provider == "codex" {
+				authID = auth.ID
+				return len(registry.GetGlobalRegistry().GetModelsForClient(authID)) > 0
+			}
+		}
+		return false
+	})
+	t.Cleanup(func() { GlobalModelRegistry().UnregisterClient(authID) })
+
+	if err = os.WriteFile(filepath.Join(authDir, "broken.json"), []byte(`{"type":`), 0o600); err != nil {
+		t.Fatalf("failed to write malformed auth file: %v", err)
+	}
+
+	if err = os.Remove(authPath); err != nil {
+		t.Fatalf("failed to remove auth file: %v", err)
+	}
+	waitForAuthReload(t, "auth removal", func() bool {
+		_, ok := service.coreManager.GetByID(authID)
+		return !ok && len(registry.GetGlobalRegistry().GetModelsForClient(authID)) == 0
+	})
+	if got := len(service.coreManager.List()); got != 0 {
+		t.Fatalf("expected no auths after removal, got %d", got)
+	}
+}
+
+func waitForAuthReload(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
@@ -0,0 +1,102 @@
+package cliproxy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v7/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v7/sdk/cliproxy/auth"
+)
+
+func TestBuildConfigModelsInheritsTargetMetadata(t *testing.T) {
+	target := registry.GetGeminiModels()[0]
+	models := buildGeminiConfigModels(&config.GeminiKey{Models: []config.GeminiModel{{
+		Name: target.ID, Alias: "gpt-4o",
+	}}})
+	if len(models) != 1 {
+		t.Fatalf("models = %d, want 1", len(models))
+	}
+	got := models[0]
+	if got.ID != "gpt-4o" || got.OwnedBy != "google" {
+		t.Fatalf("alias model = %#v", got)
+	}
+	if got.InputTokenLimit != target.InputTokenLimit || got.OutputTokenLimit != target.OutputTokenLimit {
+		t.Fatalf("token limits = %d/%d, want %d/%d", got.InputTokenLimit, got.OutputTokenLimit, target.InputTokenLimit, target.OutputTokenLimit)
+	}
+	if got.ContextLength != target.ContextLength || got.MaxCompletionTokens != target.MaxCompletionTokens {
+		t.Fatalf("context/max tokens = %d/%d, want %d/%d", got.ContextLength, got.MaxCompletionTokens, target.ContextLength, target.MaxCompletionTokens)
+	}
+	if (got.Thinking == nil) != (target.Thinking == nil) {
+		t.Fatalf("thinking = %#v, want %#v", got.Thinking, target.Thinking)
+	}
+}
+
+func TestRegisterModelsForAuth_ConfigAliasListedOnceAndRemovedOnReload(t *testing.T) {
+	target := registry.GetGeminiModels()[0]
+	const alias = "alias-listing-gpt-4o"
+	service := &Service{
+		cfg: &config.Config{
+			GeminiKey: []config.GeminiKey{{
+				APIKey: "alias-key",
+				Models: []config.GeminiModel{
+					{Name: target.ID, Alias: alias},
+					{Name: target.ID, Alias: strings.ToUpper(alias)},
+				},
+			}},
+		},
+	}
+	auth := &coreauth.Auth{
+		ID:       "auth-gemini-alias-listing",
+		Provider: "gemini",
+		Status:   coreauth.StatusActive,
+		Attributes: map[string]string{
+			"auth_kind": "apikey",
+			"api_key":   "alias-key",
+		},
+	}
+
+	modelRegistry := GlobalModelRegistry()
+	modelRegistry.UnregisterClient(auth.ID)
+	t.Cleanup(func() {
+		modelRegistry.UnregisterClient(auth.ID)
+	})
+
+	countAlias := func(handlerType, field string) int {
+		count := 0
+		for _, model := range modelRegistry.GetAvailableModels(handlerType) {
+			id, _ := model[field].(string)
+			if strings.EqualFold(strings.TrimPrefix(id, "models/"), alias) {
+				count++
+			}
+		}
+		return count
+	}
+
+	service.registerModelsForAuth(context.Background(), auth)
+	for _, handlerType := range []string{"openai", "claude"} {
+		if got := countAlias(handlerType, "id"); got != 1 {
+			t.Fatalf("%s listing contains alias %d times, want 1", handlerType, got)
+		}
+	}
+	if got := countAlias("gemini", "name"); got != 1 {
+		t.Fatalf("gemini listing contains alias %d times, want 1", got)
+	}
+
+	service.cfg = &config.Config{
+		GeminiKey: []config.GeminiKey{{
+			APIKey: "alias-key",
+			Models: []config.GeminiModel{{Name: target.ID}},
+		}},
+	}
+	service.registerModelsForAuth(context.Background(), auth)
+	for _, handlerType := range []string{"openai", "claude"} {
+		if got := countAlias(handlerType, "id"); got != 0 {
+			t.Fatalf("%s listing still contains alias after reload (%d)", handlerType, got)
+		}
+	}
+	if got := countAlias("gemini", "name"); got != 0 {
+		t.Fatalf("gemini listing still contains alias after reload (%d)", got)
+	}
+}
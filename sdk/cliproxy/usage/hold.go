@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"context"
+	"sync"
+)
+
+type publishHoldContextKey struct{}
+
+type publishHoldState int
+
+const (
+	publishHoldPending publishHoldState = iota
+	publishHoldReleased
+	publishHoldDiscarded
+)
+
+// PublishHold buffers the usage published under a context until the caller
+// decides whether the request counts. Request hedging runs one request on
+// several credentials and only keeps the usage of the attempt that wins.
+type PublishHold struct {
+	mu      sync.Mutex
+	state   publishHoldState
+	pending []func()
+}
+
+// WithPublishHold returns a context whose usage records, and anything passed
+// to RunWhenPublished, are held until Release or dropped by Discard.
+func WithPublishHold(ctx context.Context) (context.Context, *PublishHold) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	hold := &PublishHold{}
+	return context.WithValue(ctx, publishHoldContextKey{}, hold), hold
+}
+
+// Release publishes everything held so far and lets later usage through.
+func (h *PublishHold) Release() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	if h.state != publishHoldPending {
+		h.mu.Unlock()
+		return
+	}
+	h.state = publishHoldReleased
+	pending := h.pending
+	h.pending = nil
+	h.mu.Unlock()
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+// Discard drops everything held so far and any usage published later.
+func (h *PublishHold) Discard() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state != publishHoldPending {
+		return
+	}
+	h.state = publishHoldDiscarded
+	h.pending = nil
+}
+
+// intercept reports whether fn was held or dropped; false means the hold was
+// released and the caller should go ahead.
+func (h *PublishHold) intercept(fn func()) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch h.state {
+	case publishHoldPending:
+		h.pending = append(h.pending, fn)
+		return true
+	case publishHoldDiscarded:
+		return true
+	default:
+		return false
+	}
+}
+
+func publishHoldFromContext(ctx context.Context) *PublishHold {
+	if ctx == nil {
+		return nil
+	}
+	hold, _ := ctx.Value(publishHoldContextKey{}).(*PublishHold)
+	return hold
+}
+
+// RunWhenPublished runs fn once usage published under ctx would reach plugins:
+// right away without a hold, on Release with one, and never if it is discarded.
+// Side effects of a usage record that bypass the Manager, such as metrics,
+// use it to follow the record.
+func RunWhenPublished(ctx context.Context, fn func()) {
+	if fn == nil {
+		return
+	}
+	if hold := publishHoldFromContext(ctx); hold != nil && hold.intercept(fn) {
+		return
+	}
+	fn()
+}
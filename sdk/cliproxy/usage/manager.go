@@ -311,6 +311,9 @@ func (m *Manager) Publish(ctx context.Context, record Record) {
 	if m == nil {
 		return
 	}
+	if hold := publishHoldFromContext(ctx); hold != nil && hold.intercept(func() { m.Publish(ctx, record) }) {
+		return
+	}
 	// ensure worker is running even if Start was not called explicitly
 	m.Start(context.Background())
 	m.mu.Lock()
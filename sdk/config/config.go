@@ -11,6 +11,8 @@ type SDKConfig = internalconfig.SDKConfig
 type Config = internalconfig.Config
 
 type StreamingConfig = internalconfig.StreamingConfig
+type AutoModelConfig = internalconfig.AutoModelConfig
+type AutoModelCandidate = internalconfig.AutoModelCandidate
 type TLSConfig = internalconfig.TLSConfig
 type RemoteManagement = internalconfig.RemoteManagement
 type OAuthModelAlias = internalconfig.OAuthModelAlias
@@ -18,6 +20,13 @@ type PayloadConfig = internalconfig.PayloadConfig
 type PayloadRule = internalconfig.PayloadRule
 type PayloadFilterRule = internalconfig.PayloadFilterRule
 type PayloadModelRule = internalconfig.PayloadModelRule
+type RequestStoreConfig = internalconfig.RequestStoreConfig
+type MetricsConfig = internalconfig.MetricsConfig
+type RequestPriorityConfig = internalconfig.RequestPriorityConfig
+type TokenQuotaConfig = internalconfig.TokenQuotaConfig
+type TokenQuotaRule = internalconfig.TokenQuotaRule
+type TokenQuotaLimits = internalconfig.TokenQuotaLimits
+type ResponseRedactionRule = internalconfig.ResponseRedactionRule
 
 type GeminiKey = internalconfig.GeminiKey
 type CodexKey = internalconfig.CodexKey
@@ -34,6 +43,8 @@ type TLS = internalconfig.TLSConfig
 
 const (
 	DefaultPanelGitHubRepository = internalconfig.DefaultPanelGitHubRepository
+	DefaultShutdownDrainSeconds  = internalconfig.DefaultShutdownDrainSeconds
+	RequestPriorityNormal        = internalconfig.RequestPriorityNormal
 )
 
 func LoadConfig(configFile string) (*Config, error) { return internalconfig.LoadConfig(configFile) }
@@ -55,3 +66,11 @@ func SaveConfigPreserveCommentsUpdateNestedScalar(configFile string, path []stri
 func NormalizeCommentIndentation(data []byte) []byte {
 	return internalconfig.NormalizeCommentIndentation(data)
 }
+
+func NormalizeRequestPriority(value string) (string, bool) {
+	return internalconfig.NormalizeRequestPriority(value)
+}
+
+func RequestPriorityRank(priority string) int {
+	return internalconfig.RequestPriorityRank(priority)
+}
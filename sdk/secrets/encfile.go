@@ -0,0 +1,202 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// EncryptionKeyEnv names the environment variable holding the key of the built-in
+// encfile resolver: 32 bytes encoded as base64, or a passphrase hashed with SHA-256.
+const EncryptionKeyEnv = "CLIPROXY_SECRETS_KEY"
+
+// encryptedFileVersion is the format version written to encrypted secret files.
+const encryptedFileVersion = 1
+
+// encryptedFile is the on-disk envelope of an encrypted secret file. The plaintext is a
+// JSON object mapping field names to values.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// EncryptedFileResolver resolves encfile://<path>#<field> references against a JSON
+// object stored AES-256-GCM encrypted at path. It supports writes, creating the file
+// when it does not exist yet.
+type EncryptedFileResolver struct {
+	key    []byte
+	keyEnv string
+
+	mu sync.Mutex
+}
+
+// NewEncryptedFileResolver returns an encfile resolver using key, which DeriveKey turns
+// into an AES-256 key.
+func NewEncryptedFileResolver(key string) *EncryptedFileResolver {
+	return &EncryptedFileResolver{key: DeriveKey(key)}
+}
+
+// NewEncryptedFileResolverFromEnv returns an encfile resolver reading its key from the
+// environment variable keyEnv whenever a file is accessed.
+func NewEncryptedFileResolverFromEnv(keyEnv string) *EncryptedFileResolver {
+	return &EncryptedFileResolver{keyEnv: keyEnv}
+}
+
+// DeriveKey returns the AES-256 key of secret: secret itself when it is 32 bytes encoded
+// as base64, otherwise its SHA-256 digest.
+func DeriveKey(secret string) []byte {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return nil
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(secret); err == nil && len(decoded) == 32 {
+		return decoded
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// Scheme implements SecretResolver.
+func (r *EncryptedFileResolver) Scheme() string { return "encfile" }
+
+// Resolve implements SecretResolver.
+func (r *EncryptedFileResolver) Resolve(_ context.Context, ref Reference) (string, error) {
+	if ref.Field == "" {
+		return "", fmt.Errorf("encfile reference %s has no #field", ref.Raw)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values, err := r.read(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[ref.Field]
+	if !ok {
+		return "", fmt.Errorf("field %s not found in %s", ref.Field, ref.Path)
+	}
+	return value, nil
+}
+
+// Write implements SecretWriter.
+func (r *EncryptedFileResolver) Write(_ context.Context, ref Reference, value string) error {
+	if ref.Field == "" {
+		return fmt.Errorf("encfile reference %s has no #field", ref.Raw)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	values, err := r.read(ref.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		values, err = map[string]string{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	values[ref.Field] = value
+	return r.write(ref.Path, values)
+}
+
+func (r *EncryptedFileResolver) aead() (cipher.AEAD, error) {
+	key := r.key
+	if key == nil && r.keyEnv != "" {
+		key = DeriveKey(os.Getenv(r.keyEnv))
+	}
+	if key == nil {
+		return nil, fmt.Errorf("no encryption key configured (set %s)", r.keyEnv)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (r *EncryptedFileResolver) read(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var envelope encryptedFile
+	if err = json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if envelope.Version != encryptedFileVersion {
+		return nil, fmt.Errorf("%s: unsupported version %d", path, envelope.Version)
+	}
+	nonce, errNonce := base64.StdEncoding.DecodeString(envelope.Nonce)
+	ciphertext, errCiphertext := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	if errNonce != nil || errCiphertext != nil {
+		return nil, fmt.Errorf("%s: malformed envelope", path)
+	}
+	aead, err := r.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%s: malformed envelope", path)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", path, err)
+	}
+	values := map[string]string{}
+	if err = json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("parse decrypted %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func (r *EncryptedFileResolver) write(path string, values map[string]string) error {
+	aead, err := r.aead()
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.Marshal(encryptedFile{
+		Version:    encryptedFileVersion,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, nil)),
+	})
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Replace the file atomically so a crash never leaves a truncated secret file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(0o600)
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(tmpName, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+	return err
+}
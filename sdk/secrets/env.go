@@ -0,0 +1,23 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// EnvResolver resolves env://NAME references to the value of environment variable NAME.
+// It is read-only, which suits static secrets such as API keys.
+type EnvResolver struct{}
+
+// Scheme implements SecretResolver.
+func (EnvResolver) Scheme() string { return "env" }
+
+// Resolve implements SecretResolver.
+func (EnvResolver) Resolve(_ context.Context, ref Reference) (string, error) {
+	value, ok := os.LookupEnv(ref.Path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref.Path)
+	}
+	return value, nil
+}
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Policy limits which secret references are resolved. The zero Policy resolves nothing,
+// so references stay plain strings until the operator opts in.
+type Policy struct {
+	// Enable turns on reference resolution in the config file.
+	Enable bool
+	// AuthFiles additionally resolves references in the auth files of the auth directory.
+	AuthFiles bool
+	// Env lists the environment variables env:// references may read. An entry ending in
+	// "*" allows every variable with that prefix.
+	Env []string
+	// FileRoots lists the directories encfile:// references may read and write under.
+	FileRoots []string
+}
+
+var currentPolicy atomic.Pointer[Policy]
+
+// SetPolicy installs the policy applied by ResolveReference, ResolveMetadata and
+// PersistMetadata.
+func SetPolicy(policy Policy) {
+	currentPolicy.Store(&policy)
+}
+
+// CurrentPolicy returns the installed policy.
+func CurrentPolicy() Policy {
+	if policy := currentPolicy.Load(); policy != nil {
+		return *policy
+	}
+	return Policy{}
+}
+
+// Allow reports why ref may not be resolved or written under the policy, or nil when it
+// may. References of schemes without an allowlist, such as a Vault resolver installed
+// through Register, are allowed once the policy is enabled.
+func (p Policy) Allow(ref Reference) error {
+	if !p.Enable {
+		return errors.New("secret references are disabled")
+	}
+	switch ref.Scheme {
+	case "env":
+		for _, pattern := range p.Env {
+			pattern = strings.TrimSpace(pattern)
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(ref.Path, prefix) || pattern == ref.Path {
+				return nil
+			}
+		}
+		return fmt.Errorf("environment variable %s is not allowed", ref.Path)
+	case "encfile":
+		path := filepath.Clean(ref.Path)
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("encfile path %s is not absolute", ref.Path)
+		}
+		for _, root := range p.FileRoots {
+			root = strings.TrimSpace(root)
+			if root == "" || !filepath.IsAbs(root) {
+				continue
+			}
+			if rel, errRel := filepath.Rel(filepath.Clean(root), path); errRel == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return nil
+			}
+		}
+		return fmt.Errorf("encfile path %s is outside the allowed roots", ref.Path)
+	}
+	return nil
+}
+
+// ResolveReference returns the value ref points to when the policy allows it.
+func (p Policy) ResolveReference(ctx context.Context, ref Reference) (string, error) {
+	if err := p.Allow(ref); err != nil {
+		return "", fmt.Errorf("secrets: resolve %s: %w", ref.Raw, err)
+	}
+	resolver := Lookup(ref.Scheme)
+	if resolver == nil {
+		return "", fmt.Errorf("secrets: no resolver for scheme %q", ref.Scheme)
+	}
+	resolved, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("secrets: resolve %s: %w", ref.Raw, err)
+	}
+	return resolved, nil
+}
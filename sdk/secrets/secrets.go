@@ -0,0 +1,292 @@
+// Package secrets resolves secret references written in config and auth files.
+//
+// A value such as "env://CLAUDE_KEY" or "vault://secret/claude#api_key" is a reference:
+// at load time it is replaced by the value the resolver registered for its scheme
+// returns. Resolvers whose backend accepts writes also implement SecretWriter, through
+// which refreshed credentials are persisted instead of being written out in plaintext.
+//
+// Built-in resolvers handle "env" and "encfile" (an AES-GCM encrypted JSON file). Other
+// backends, such as Vault or a cloud KMS, plug in through Register.
+//
+// Nothing is resolved until a Policy enabling it is installed through SetPolicy. The policy
+// also allowlists the environment variables and encfile directories references may use.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reference is a parsed secret reference of the form scheme://path[#field].
+type Reference struct {
+	// Raw is the reference as written.
+	Raw string
+	// Scheme selects the resolver, e.g. "env".
+	Scheme string
+	// Path locates the secret within the backend.
+	Path string
+	// Field optionally selects one value of a secret holding several.
+	Field string
+}
+
+func (r Reference) String() string {
+	return r.Raw
+}
+
+// SecretResolver resolves references of one scheme.
+type SecretResolver interface {
+	// Scheme returns the reference scheme handled by the resolver, e.g. "vault".
+	Scheme() string
+	// Resolve returns the secret value ref points to.
+	Resolve(ctx context.Context, ref Reference) (string, error)
+}
+
+// SecretWriter is implemented by resolvers whose backend accepts updated values, so
+// credentials refreshed at runtime are written back where they were read from.
+type SecretWriter interface {
+	// Write stores value at ref, replacing the current value.
+	Write(ctx context.Context, ref Reference, value string) error
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]SecretResolver{}
+)
+
+func init() {
+	Register(EnvResolver{})
+	Register(NewEncryptedFileResolverFromEnv(EncryptionKeyEnv))
+}
+
+// Register installs resolver for its scheme, replacing any resolver registered before.
+func Register(resolver SecretResolver) {
+	if resolver == nil {
+		return
+	}
+	scheme := strings.ToLower(strings.TrimSpace(resolver.Scheme()))
+	if scheme == "" {
+		return
+	}
+	resolversMu.Lock()
+	resolvers[scheme] = resolver
+	resolversMu.Unlock()
+}
+
+// Unregister removes the resolver of scheme.
+func Unregister(scheme string) {
+	resolversMu.Lock()
+	delete(resolvers, strings.ToLower(strings.TrimSpace(scheme)))
+	resolversMu.Unlock()
+}
+
+// Lookup returns the resolver registered for scheme, or nil.
+func Lookup(scheme string) SecretResolver {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	return resolvers[strings.ToLower(strings.TrimSpace(scheme))]
+}
+
+// ParseReference parses value as a secret reference. Only values whose scheme has a
+// registered resolver are references, so ordinary URLs are never mistaken for one.
+func ParseReference(value string) (Reference, bool) {
+	raw := strings.TrimSpace(value)
+	scheme, rest, found := strings.Cut(raw, "://")
+	if !found || scheme == "" || strings.ContainsAny(scheme, " /") {
+		return Reference{}, false
+	}
+	if Lookup(scheme) == nil {
+		return Reference{}, false
+	}
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return Reference{}, false
+	}
+	return Reference{Raw: raw, Scheme: strings.ToLower(scheme), Path: path, Field: field}, true
+}
+
+// IsReference reports whether value is a secret reference.
+func IsReference(value string) bool {
+	_, ok := ParseReference(value)
+	return ok
+}
+
+// Resolve returns the value value refers to. Values that are not references are
+// returned unchanged.
+func Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+	return ResolveReference(ctx, ref)
+}
+
+// ResolveReference returns the value ref points to when the installed policy allows it.
+func ResolveReference(ctx context.Context, ref Reference) (string, error) {
+	return CurrentPolicy().ResolveReference(ctx, ref)
+}
+
+// Refs maps the dot-separated metadata paths whose values were resolved to the
+// references they were written as.
+type Refs map[string]string
+
+// Paths returns the metadata paths of refs in a stable order.
+func (r Refs) Paths() []string {
+	paths := make([]string, 0, len(r))
+	for path := range r {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ResolveMetadata replaces the references among the string values of auth file metadata,
+// nested objects included, by their values and returns where they were. Metadata is left
+// unchanged unless the installed policy enables references in auth files.
+func ResolveMetadata(ctx context.Context, metadata map[string]any) (Refs, error) {
+	if policy := CurrentPolicy(); !policy.Enable || !policy.AuthFiles {
+		return nil, nil
+	}
+	refs := Refs{}
+	if err := resolveMap(ctx, metadata, "", refs); err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	return refs, nil
+}
+
+func resolveMap(ctx context.Context, values map[string]any, prefix string, refs Refs) error {
+	for key, value := range values {
+		switch typed := value.(type) {
+		case string:
+			ref, ok := ParseReference(typed)
+			if !ok {
+				continue
+			}
+			resolved, err := ResolveReference(ctx, ref)
+			if err != nil {
+				return err
+			}
+			values[key] = resolved
+			refs[prefix+key] = ref.Raw
+		case map[string]any:
+			if err := resolveMap(ctx, typed, prefix+key+".", refs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HasReferences reports whether any string value of metadata, nested objects and arrays
+// included, is a secret reference, whether or not the installed policy would resolve it.
+func HasReferences(metadata map[string]any) bool {
+	for _, value := range metadata {
+		if valueHasReference(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func valueHasReference(value any) bool {
+	switch typed := value.(type) {
+	case string:
+		return IsReference(typed)
+	case map[string]any:
+		return HasReferences(typed)
+	case []any:
+		for _, item := range typed {
+			if valueHasReference(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithReferences returns a copy of metadata with the values at the paths of refs put
+// back as references, ready to be persisted. metadata itself is left unchanged.
+func WithReferences(metadata map[string]any, refs Refs) map[string]any {
+	out := copyMap(metadata)
+	for path, raw := range refs {
+		keys := strings.Split(path, ".")
+		node := out
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := node[key].(map[string]any)
+			if !ok {
+				node = nil
+				break
+			}
+			child = copyMap(child)
+			node[key] = child
+			node = child
+		}
+		if node == nil {
+			continue
+		}
+		if _, ok := node[keys[len(keys)-1]].(string); ok {
+			node[keys[len(keys)-1]] = raw
+		}
+	}
+	return out
+}
+
+// PersistMetadata writes values that changed since they were resolved, such as tokens
+// rotated by a refresh, back through their resolver and returns the copy of metadata to
+// persist, holding references instead of values. Backends that cannot be written keep
+// their reference; the new value then lives in memory only.
+func PersistMetadata(ctx context.Context, metadata map[string]any, refs Refs) (map[string]any, error) {
+	for _, path := range refs.Paths() {
+		value, ok := lookupPath(metadata, path)
+		if !ok {
+			continue
+		}
+		ref, ok := ParseReference(refs[path])
+		if !ok {
+			continue
+		}
+		writer, ok := Lookup(ref.Scheme).(SecretWriter)
+		if !ok {
+			continue
+		}
+		if errAllow := CurrentPolicy().Allow(ref); errAllow != nil {
+			return nil, fmt.Errorf("secrets: write %s: %w", ref.Raw, errAllow)
+		}
+		current, err := ResolveReference(ctx, ref)
+		if err == nil && current == value {
+			continue
+		}
+		if errWrite := writer.Write(ctx, ref, value); errWrite != nil {
+			return nil, fmt.Errorf("secrets: write %s: %w", ref.Raw, errWrite)
+		}
+	}
+	return WithReferences(metadata, refs), nil
+}
+
+// lookupPath returns the string value at the dot-separated path of metadata.
+func lookupPath(metadata map[string]any, path string) (string, bool) {
+	keys := strings.Split(path, ".")
+	node := metadata
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := node[key].(map[string]any)
+		if !ok {
+			return "", false
+		}
+		node = child
+	}
+	value, ok := node[keys[len(keys)-1]].(string)
+	return value, ok
+}
+
+func copyMap(in map[string]any) map[string]any {
+	out := make(map[string]any, len(in))
+	for key, value := range in {
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,175 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withPolicy installs policy for the duration of the test.
+func withPolicy(t *testing.T, policy Policy) {
+	t.Helper()
+	previous := CurrentPolicy()
+	SetPolicy(policy)
+	t.Cleanup(func() { SetPolicy(previous) })
+}
+
+func TestParseReferenceRequiresRegisteredScheme(t *testing.T) {
+	ref, ok := ParseReference(" encfile:///etc/cliproxy/secrets.enc#claude_key ")
+	if !ok {
+		t.Fatal("encfile reference not recognised")
+	}
+	if ref.Scheme != "encfile" || ref.Path != "/etc/cliproxy/secrets.enc" || ref.Field != "claude_key" {
+		t.Fatalf("ParseReference() = %+v", ref)
+	}
+	for _, value := range []string{"https://api.example.com", "plain-token", "env://", "vault://secret/claude#api_key"} {
+		if _, ok := ParseReference(value); ok {
+			t.Fatalf("ParseReference(%q) recognised a reference", value)
+		}
+	}
+}
+
+func TestResolveEnvReference(t *testing.T) {
+	withPolicy(t, Policy{Enable: true, Env: []string{"SECRETS_TEST_*"}})
+	t.Setenv("SECRETS_TEST_CLAUDE_KEY", "sk-from-env")
+	got, err := Resolve(context.Background(), "env://SECRETS_TEST_CLAUDE_KEY")
+	if err != nil || got != "sk-from-env" {
+		t.Fatalf("Resolve() = %q, %v; want sk-from-env", got, err)
+	}
+	if _, err = Resolve(context.Background(), "env://SECRETS_TEST_UNSET_KEY"); err == nil {
+		t.Fatal("Resolve() of an unset variable succeeded")
+	}
+	if got, _ = Resolve(context.Background(), "literal"); got != "literal" {
+		t.Fatalf("Resolve(literal) = %q", got)
+	}
+}
+
+func TestEncryptedFileRoundTrip(t *testing.T) {
+	t.Setenv(EncryptionKeyEnv, "correct horse battery staple")
+	root := t.TempDir()
+	withPolicy(t, Policy{Enable: true, FileRoots: []string{root}})
+	path := filepath.Join(root, "secrets.enc")
+	ref, _ := ParseReference("encfile://" + path + "#refresh_token")
+
+	writer := Lookup("encfile").(SecretWriter)
+	if err := writer.Write(context.Background(), ref, "rt-1"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read encrypted file: %v", err)
+	}
+	if strings.Contains(string(data), "rt-1") {
+		t.Fatalf("encrypted file holds the plaintext: %s", data)
+	}
+	if got, errResolve := ResolveReference(context.Background(), ref); errResolve != nil || got != "rt-1" {
+		t.Fatalf("ResolveReference() = %q, %v; want rt-1", got, errResolve)
+	}
+
+	wrongKey := NewEncryptedFileResolver("another key")
+	if _, errResolve := wrongKey.Resolve(context.Background(), ref); errResolve == nil {
+		t.Fatal("Resolve() with the wrong key succeeded")
+	}
+}
+
+func TestPersistMetadataWritesBackRefreshedValues(t *testing.T) {
+	t.Setenv(EncryptionKeyEnv, "persist-test-key")
+	t.Setenv("SECRETS_TEST_CLIENT_ID", "client-1")
+	root := t.TempDir()
+	withPolicy(t, Policy{Enable: true, AuthFiles: true, Env: []string{"SECRETS_TEST_CLIENT_ID"}, FileRoots: []string{root}})
+	path := filepath.Join(root, "tokens.enc")
+	refreshRef := "encfile://" + path + "#refresh_token"
+	accessRef := "encfile://" + path + "#access_token"
+	for raw, value := range map[string]string{refreshRef: "rt-old", accessRef: "at-old"} {
+		ref, _ := ParseReference(raw)
+		if err := Lookup("encfile").(SecretWriter).Write(context.Background(), ref, value); err != nil {
+			t.Fatalf("seed %s: %v", raw, err)
+		}
+	}
+
+	metadata := map[string]any{
+		"type":      "claude",
+		"client_id": "env://SECRETS_TEST_CLIENT_ID",
+		"token":     map[string]any{"refresh_token": refreshRef, "access_token": accessRef},
+	}
+	refs, err := ResolveMetadata(context.Background(), metadata)
+	if err != nil {
+		t.Fatalf("ResolveMetadata() error = %v", err)
+	}
+	if len(refs) != 3 || metadata["client_id"] != "client-1" || metadata["token"].(map[string]any)["refresh_token"] != "rt-old" {
+		t.Fatalf("resolved metadata = %v, refs = %v", metadata, refs)
+	}
+
+	// A refresh rotates both tokens in memory.
+	metadata["token"].(map[string]any)["refresh_token"] = "rt-new"
+	metadata["token"].(map[string]any)["access_token"] = "at-new"
+	persisted, err := PersistMetadata(context.Background(), metadata, refs)
+	if err != nil {
+		t.Fatalf("PersistMetadata() error = %v", err)
+	}
+	token := persisted["token"].(map[string]any)
+	if persisted["client_id"] != "env://SECRETS_TEST_CLIENT_ID" || token["refresh_token"] != refreshRef || token["access_token"] != accessRef {
+		t.Fatalf("persisted metadata = %v, want references", persisted)
+	}
+	if metadata["token"].(map[string]any)["refresh_token"] != "rt-new" {
+		t.Fatal("PersistMetadata() modified the in-memory metadata")
+	}
+	if got, _ := Resolve(context.Background(), refreshRef); got != "rt-new" {
+		t.Fatalf("stored refresh_token = %q, want rt-new", got)
+	}
+	if got, _ := Resolve(context.Background(), accessRef); got != "at-new" {
+		t.Fatalf("stored access_token = %q, want at-new", got)
+	}
+}
+
+func TestPolicyRestrictsReferences(t *testing.T) {
+	t.Setenv("SECRETS_TEST_ALLOWED", "allowed")
+	t.Setenv("SECRETS_TEST_PRIVATE", "private")
+	root := t.TempDir()
+
+	withPolicy(t, Policy{})
+	if _, err := Resolve(context.Background(), "env://SECRETS_TEST_ALLOWED"); err == nil {
+		t.Fatal("Resolve() succeeded without an enabled policy")
+	}
+	metadata := map[string]any{"api_key": "env://SECRETS_TEST_ALLOWED"}
+	if refs, err := ResolveMetadata(context.Background(), metadata); err != nil || refs != nil || metadata["api_key"] != "env://SECRETS_TEST_ALLOWED" {
+		t.Fatalf("ResolveMetadata() = %v, %v, metadata %v; want auth file references left alone", refs, err, metadata)
+	}
+
+	withPolicy(t, Policy{Enable: true, AuthFiles: true, Env: []string{"SECRETS_TEST_ALLOWED"}, FileRoots: []string{root}})
+	if got, err := Resolve(context.Background(), "env://SECRETS_TEST_ALLOWED"); err != nil || got != "allowed" {
+		t.Fatalf("Resolve(allowed) = %q, %v", got, err)
+	}
+	denied := []string{
+		"env://SECRETS_TEST_PRIVATE",
+		"encfile://" + filepath.Join(filepath.Dir(root), "other", "secrets.enc") + "#key",
+		"encfile://" + root + "/../escape.enc#key",
+		"encfile://relative/secrets.enc#key",
+	}
+	for _, raw := range denied {
+		if _, err := Resolve(context.Background(), raw); err == nil {
+			t.Fatalf("Resolve(%q) succeeded outside the allowlist", raw)
+		}
+	}
+
+	outside := filepath.Join(t.TempDir(), "created", "secrets.enc")
+	ref, _ := ParseReference("encfile://" + outside + "#refresh_token")
+	if _, err := PersistMetadata(context.Background(), map[string]any{"refresh_token": "rt"}, Refs{"refresh_token": ref.Raw}); err == nil {
+		t.Fatal("PersistMetadata() wrote outside the allowed roots")
+	}
+	if _, err := os.Stat(filepath.Dir(outside)); !os.IsNotExist(err) {
+		t.Fatalf("stat %s = %v, want nothing created", filepath.Dir(outside), err)
+	}
+}
+
+func TestHasReferences(t *testing.T) {
+	withPolicy(t, Policy{})
+	if HasReferences(map[string]any{"type": "claude", "base_url": "https://api.example.com"}) {
+		t.Fatal("HasReferences() reported plain values")
+	}
+	if !HasReferences(map[string]any{"token": map[string]any{"list": []any{"x", "env://HOME"}}}) {
+		t.Fatal("HasReferences() missed a nested reference")
+	}
+}
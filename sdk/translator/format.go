@@ -3,7 +3,9 @@ package translator
 // Format identifies a request/response schema used inside the proxy.
 type Format string
 
-// FromString converts an arbitrary identifier to a translator format.
+// FromString converts an arbitrary identifier to a translator format. Names are
+// not restricted to the built-in formats, so custom formats registered at
+// runtime resolve the same way.
 func FromString(v string) Format {
 	return Format(v)
 }
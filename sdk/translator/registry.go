@@ -7,6 +7,9 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // Registry manages translation functions across schemas.
@@ -56,6 +59,15 @@ func (r *Registry) SetPluginHooks(hooks PluginHooks) {
 // "model" field is still updated to match the resolved model name so that
 // client-side prefixes (e.g. "copilot/gpt-5-mini") are not leaked upstream.
 func (r *Registry) TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool) []byte {
+	return r.TranslateRequestWithContext(context.Background(), from, to, model, rawJSON, stream)
+}
+
+// TranslateRequestWithContext is TranslateRequest with a caller context, which is passed
+// to plugin hooks and parents the "translate.request" span when the request is traced.
+func (r *Registry) TranslateRequestWithContext(ctx context.Context, from, to Format, model string, rawJSON []byte, stream bool) []byte {
+	ctx, span := startTranslateSpan(ctx, "translate.request", from, to)
+	defer span.End()
+
 	r.mu.RLock()
 	var fn RequestTransform
 	if byTarget, ok := r.requests[from]; ok {
@@ -78,9 +90,9 @@ func (r *Registry) TranslateRequest(from, to Format, model string, rawJSON []byt
 	}
 
 	if hooks != nil {
-		body = hooks.NormalizeRequest(context.Background(), from, to, model, body, stream)
+		body = hooks.NormalizeRequest(ctx, from, to, model, body, stream)
 		if fn == nil {
-			if translated, ok := hooks.TranslateRequest(context.Background(), from, to, model, body, stream); ok {
+			if translated, ok := hooks.TranslateRequest(ctx, from, to, model, body, stream); ok {
 				body = translated
 			}
 		}
@@ -178,6 +190,9 @@ func (r *Registry) TranslateStream(ctx context.Context, from, to Format, model s
 
 // TranslateNonStream applies the registered non-stream response translator.
 func (r *Registry) TranslateNonStream(ctx context.Context, from, to Format, model string, originalRequestRawJSON, requestRawJSON, rawJSON []byte, param *any) []byte {
+	ctx, span := startTranslateSpan(ctx, "translate.response", from, to)
+	defer span.End()
+
 	r.mu.RLock()
 	var fn ResponseTransform
 	if byTarget, ok := r.responses[to]; ok {
@@ -238,6 +253,11 @@ func TranslateRequest(from, to Format, model string, rawJSON []byte, stream bool
 	return defaultRegistry.TranslateRequest(from, to, model, rawJSON, stream)
 }
 
+// TranslateRequestWithContext is a helper on the default registry.
+func TranslateRequestWithContext(ctx context.Context, from, to Format, model string, rawJSON []byte, stream bool) []byte {
+	return defaultRegistry.TranslateRequestWithContext(ctx, from, to, model, rawJSON, stream)
+}
+
 // HasRequestTransformer inspects the default registry.
 func HasRequestTransformer(from, to Format) bool {
 	return defaultRegistry.HasRequestTransformer(from, to)
@@ -276,3 +296,21 @@ func TranslateTokenCount(ctx context.Context, from, to Format, count int64, rawJ
 func hasAnyResponseTransform(fn ResponseTransform) bool {
 	return fn.Stream != nil || fn.NonStream != nil || fn.TokenCount != nil
 }
+
+// startTranslateSpan starts a translation span with the tracer provider of the span in
+// ctx, so translations are only recorded inside traced requests.
+func startTranslateSpan(ctx context.Context, name string, from, to Format) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parent := trace.SpanFromContext(ctx)
+	if !parent.IsRecording() {
+		return ctx, noop.Span{}
+	}
+	return parent.TracerProvider().Tracer("github.com/router-for-me/CLIProxyAPI/v7/sdk/translator").Start(ctx, name,
+		trace.WithAttributes(
+			attribute.String("translator.from", from.String()),
+			attribute.String("translator.to", to.String()),
+		),
+	)
+}
@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTranslatorRegistered is returned when a runtime registration targets a
+// format pair that already has a translator for the same slot and override is false.
+var ErrTranslatorRegistered = errors.New("translator already registered")
+
+// RegisterRequestTranslator registers the request translator from the client
+// format from to the upstream format to. Unlike Register it rejects an existing
+// request translator for the pair unless override is true, so SDK users adding
+// custom formats at runtime cannot silently replace a built-in.
+func (r *Registry) RegisterRequestTranslator(from, to Format, fn RequestTransform, override bool) error {
+	if fn == nil {
+		return errors.New("translator: request translator is nil")
+	}
+	if errValidate := validateFormatPair(from, to); errValidate != nil {
+		return errValidate
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byTarget := r.requests[from]
+	if byTarget == nil {
+		byTarget = make(map[Format]RequestTransform)
+		r.requests[from] = byTarget
+	}
+	if byTarget[to] != nil && !override {
+		return fmt.Errorf("%w: request %s -> %s", ErrTranslatorRegistered, from, to)
+	}
+	byTarget[to] = fn
+	return nil
+}
+
+// RegisterStreamResponseTranslator registers the streaming response translator
+// that converts upstream format to responses back into client format from.
+func (r *Registry) RegisterStreamResponseTranslator(from, to Format, fn ResponseStreamTransform, override bool) error {
+	if fn == nil {
+		return errors.New("translator: stream response translator is nil")
+	}
+	return r.registerResponseSlot(from, to, "stream response", override,
+		func(transform ResponseTransform) bool { return transform.Stream != nil },
+		func(transform *ResponseTransform) { transform.Stream = fn })
+}
+
+// RegisterNonStreamResponseTranslator registers the non-streaming response
+// translator that converts upstream format to responses back into client format from.
+func (r *Registry) RegisterNonStreamResponseTranslator(from, to Format, fn ResponseNonStreamTransform, override bool) error {
+	if fn == nil {
+		return errors.New("translator: non-stream response translator is nil")
+	}
+	return r.registerResponseSlot(from, to, "non-stream response", override,
+		func(transform ResponseTransform) bool { return transform.NonStream != nil },
+		func(transform *ResponseTransform) { transform.NonStream = fn })
+}
+
+// RegisterTokenCountTranslator registers the token count response translator
+// for client format from served by upstream format to.
+func (r *Registry) RegisterTokenCountTranslator(from, to Format, fn ResponseTokenCountTransform, override bool) error {
+	if fn == nil {
+		return errors.New("translator: token count translator is nil")
+	}
+	return r.registerResponseSlot(from, to, "token count", override,
+		func(transform ResponseTransform) bool { return transform.TokenCount != nil },
+		func(transform *ResponseTransform) { transform.TokenCount = fn })
+}
+
+func (r *Registry) registerResponseSlot(from, to Format, kind string, override bool, exists func(ResponseTransform) bool, set func(*ResponseTransform)) error {
+	if errValidate := validateFormatPair(from, to); errValidate != nil {
+		return errValidate
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byTarget := r.responses[from]
+	if byTarget == nil {
+		byTarget = make(map[Format]ResponseTransform)
+		r.responses[from] = byTarget
+	}
+	transform := byTarget[to]
+	if exists(transform) && !override {
+		return fmt.Errorf("%w: %s %s -> %s", ErrTranslatorRegistered, kind, from, to)
+	}
+	set(&transform)
+	byTarget[to] = transform
+	return nil
+}
+
+func validateFormatPair(from, to Format) error {
+	if strings.TrimSpace(from.String()) == "" || strings.TrimSpace(to.String()) == "" {
+		return fmt.Errorf("translator: invalid format pair %q -> %q", from, to)
+	}
+	return nil
+}
+
+// RegisterRequestTranslator registers a request translator on the default registry.
+func RegisterRequestTranslator(from, to Format, fn RequestTransform, override bool) error {
+	return defaultRegistry.RegisterRequestTranslator(from, to, fn, override)
+}
+
+// RegisterStreamResponseTranslator registers a streaming response translator on the default registry.
+func RegisterStreamResponseTranslator(from, to Format, fn ResponseStreamTransform, override bool) error {
+	return defaultRegistry.RegisterStreamResponseTranslator(from, to, fn, override)
+}
+
+// RegisterNonStreamResponseTranslator registers a non-streaming response translator on the default registry.
+func RegisterNonStreamResponseTranslator(from, to Format, fn ResponseNonStreamTransform, override bool) error {
+	return defaultRegistry.RegisterNonStreamResponseTranslator(from, to, fn, override)
+}
+
+// RegisterTokenCountTranslator registers a token count translator on the default registry.
+func RegisterTokenCountTranslator(from, to Format, fn ResponseTokenCountTransform, override bool) error {
+	return defaultRegistry.RegisterTokenCountTranslator(from, to, fn, override)
+}
@@ -0,0 +1,66 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegisterRequestTranslatorRejectsDuplicates(t *testing.T) {
+	r := NewRegistry()
+	from, to := FromString("jsonrpc-chat"), FormatOpenAI
+	first := func(string, []byte, bool) []byte { return []byte("first") }
+	second := func(string, []byte, bool) []byte { return []byte("second") }
+
+	if errRegister := r.RegisterRequestTranslator(from, to, first, false); errRegister != nil {
+		t.Fatalf("first registration error = %v", errRegister)
+	}
+	if errRegister := r.RegisterRequestTranslator(from, to, second, false); !errors.Is(errRegister, ErrTranslatorRegistered) {
+		t.Fatalf("duplicate registration error = %v, want ErrTranslatorRegistered", errRegister)
+	}
+	if got := string(r.TranslateRequest(from, to, "m", []byte(`{}`), false)); got != "first" {
+		t.Fatalf("TranslateRequest() = %q after rejected duplicate, want first", got)
+	}
+	if errRegister := r.RegisterRequestTranslator(from, to, second, true); errRegister != nil {
+		t.Fatalf("override registration error = %v", errRegister)
+	}
+	if got := string(r.TranslateRequest(from, to, "m", []byte(`{}`), false)); got != "second" {
+		t.Fatalf("TranslateRequest() = %q after override, want second", got)
+	}
+}
+
+func TestRegisterResponseTranslatorsFillSeparateSlots(t *testing.T) {
+	r := NewRegistry()
+	from, to := FromString("jsonrpc-chat"), FormatOpenAI
+	stream := func(context.Context, string, []byte, []byte, []byte, *any) [][]byte { return [][]byte{[]byte("chunk")} }
+	nonStream := func(context.Context, string, []byte, []byte, []byte, *any) []byte { return []byte("whole") }
+
+	if errRegister := r.RegisterStreamResponseTranslator(from, to, stream, false); errRegister != nil {
+		t.Fatalf("stream registration error = %v", errRegister)
+	}
+	if errRegister := r.RegisterNonStreamResponseTranslator(from, to, nonStream, false); errRegister != nil {
+		t.Fatalf("non-stream registration error = %v", errRegister)
+	}
+	if errRegister := r.RegisterStreamResponseTranslator(from, to, stream, false); !errors.Is(errRegister, ErrTranslatorRegistered) {
+		t.Fatalf("duplicate stream registration error = %v, want ErrTranslatorRegistered", errRegister)
+	}
+	if r.HasRequestTransformer(from, to) {
+		t.Fatal("response registration created a request translator")
+	}
+	if got := r.TranslateStream(context.Background(), to, from, "m", nil, nil, []byte("raw"), nil); len(got) != 1 || string(got[0]) != "chunk" {
+		t.Fatalf("TranslateStream() = %q, want chunk", got)
+	}
+	if got := string(r.TranslateNonStream(context.Background(), to, from, "m", nil, nil, []byte("raw"), nil)); got != "whole" {
+		t.Fatalf("TranslateNonStream() = %q, want whole", got)
+	}
+}
+
+func TestRegisterTranslatorValidation(t *testing.T) {
+	r := NewRegistry()
+	if errRegister := r.RegisterRequestTranslator(FromString(" "), FormatOpenAI, func(string, []byte, bool) []byte { return nil }, false); errRegister == nil {
+		t.Fatal("blank source format accepted")
+	}
+	if errRegister := r.RegisterTokenCountTranslator(FromString("jsonrpc-chat"), FormatOpenAI, nil, false); errRegister == nil {
+		t.Fatal("nil token count translator accepted")
+	}
+}
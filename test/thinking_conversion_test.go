@@ -1,7 +1,9 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -40,6 +42,8 @@ type thinkingTestCase struct {
 	expectAbsent    []string
 	includeThoughts string
 	expectErr       bool
+	// maxTokens overrides the output token limit set on the translated body.
+	maxTokens int
 }
 
 // TestThinkingE2EMatrix_Suffix tests the thinking configuration transformation using model name suffix.
@@ -1082,6 +1086,42 @@ func TestThinkingE2EMatrix_Suffix(t *testing.T) {
 			includeThoughts: "true",
 			expectErr:       false,
 		},
+		// Suffix units (Cases 90-92)
+
+		// Case 90: Gemini budget 16k → 16384 (k multiplies by 1024)
+		{
+			name:            "90",
+			from:            "gemini",
+			to:              "gemini",
+			model:           "gemini-budget-model(16k)",
+			inputJSON:       `{"model":"gemini-budget-model(16k)","contents":[{"role":"user","parts":[{"text":"hi"}]}]}`,
+			expectField:     "generationConfig.thinkingConfig.thinkingBudget",
+			expectValue:     "16384",
+			includeThoughts: "true",
+			expectErr:       false,
+		},
+		// Case 91: Claude 50% → half of Max (128000) = 64000
+		{
+			name:        "91",
+			from:        "claude",
+			to:          "claude",
+			model:       "claude-budget-model(50%)",
+			inputJSON:   `{"model":"claude-budget-model(50%)","messages":[{"role":"user","content":"hi"}]}`,
+			expectField: "thinking.budget_tokens",
+			expectValue: "64000",
+			expectErr:   false,
+		},
+		// Case 92: Percentage on level-only model (no Max) → treated as no config → injected default medium
+		{
+			name:        "92",
+			from:        "openai",
+			to:          "codex",
+			model:       "level-model(50%)",
+			inputJSON:   `{"model":"level-model(50%)","messages":[{"role":"user","content":"hi"}]}`,
+			expectField: "reasoning.effort",
+			expectValue: "medium",
+			expectErr:   false,
+		},
 	}
 
 	runThinkingTests(t, cases)
@@ -2476,6 +2516,149 @@ func TestThinkingE2EProviderTargets(t *testing.T) {
 	runThinkingTests(t, cases)
 }
 
+// TestThinkingE2EBudgetClamping covers the budget clamping policy of thinking.ClampBudget
+// and thinking.FitBudgetToMaxTokens. Claude and Claude models on Antigravity must reach
+// the same outcome for the same input: both keep the budget below max tokens and both
+// drop thinking when no budget at or above the model minimum fits.
+func TestThinkingE2EBudgetClamping(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	uid := fmt.Sprintf("thinking-e2e-budget-clamping-%d", time.Now().UnixNano())
+
+	reg.RegisterClient(uid, "test", getTestModels())
+	defer reg.UnregisterClient(uid)
+
+	cases := []thinkingTestCase{
+		// Max tokens below the model minimum: no valid budget exists, thinking is dropped.
+		{
+			name:         "BC1",
+			from:         "openai",
+			to:           "claude",
+			model:        "claude-budget-model(8192)",
+			inputJSON:    `{"model":"claude-budget-model(8192)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:    1000,
+			expectField:  "max_tokens",
+			expectValue:  "1000",
+			expectAbsent: []string{"thinking"},
+		},
+		{
+			name:         "BC2",
+			from:         "openai",
+			to:           "antigravity",
+			model:        "antigravity-claude-budget-model(8192)",
+			inputJSON:    `{"model":"antigravity-claude-budget-model(8192)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:    1000,
+			expectField:  "request.generationConfig.maxOutputTokens",
+			expectValue:  "1000",
+			expectAbsent: []string{"request.generationConfig.thinkingConfig"},
+		},
+		// Max tokens above the minimum but below the budget: budget lowered to max-1.
+		{
+			name:         "BC3",
+			from:         "openai",
+			to:           "claude",
+			model:        "claude-budget-model(8192)",
+			inputJSON:    `{"model":"claude-budget-model(8192)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:    4096,
+			expectField:  "thinking.type",
+			expectValue:  "enabled",
+			expectField2: "thinking.budget_tokens",
+			expectValue2: "4095",
+		},
+		{
+			name:            "BC4",
+			from:            "openai",
+			to:              "antigravity",
+			model:           "antigravity-claude-budget-model(8192)",
+			inputJSON:       `{"model":"antigravity-claude-budget-model(8192)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:       4096,
+			expectField:     "request.generationConfig.thinkingConfig.thinkingBudget",
+			expectValue:     "4095",
+			includeThoughts: "true",
+		},
+		// Budget below the model minimum: raised to Min.
+		{
+			name:        "BC5",
+			from:        "openai",
+			to:          "claude",
+			model:       "claude-budget-model(100)",
+			inputJSON:   `{"model":"claude-budget-model(100)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:   16000,
+			expectField: "thinking.budget_tokens",
+			expectValue: "1024",
+		},
+		{
+			name:            "BC6",
+			from:            "openai",
+			to:              "antigravity",
+			model:           "antigravity-claude-budget-model(100)",
+			inputJSON:       `{"model":"antigravity-claude-budget-model(100)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:       16000,
+			expectField:     "request.generationConfig.thinkingConfig.thinkingBudget",
+			expectValue:     "1024",
+			includeThoughts: "true",
+		},
+		// Budget above the model maximum: lowered to Max, then kept below max tokens.
+		{
+			name:        "BC7",
+			from:        "openai",
+			to:          "claude",
+			model:       "claude-budget-model(200000)",
+			inputJSON:   `{"model":"claude-budget-model(200000)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:   200000,
+			expectField: "thinking.budget_tokens",
+			expectValue: "128000",
+		},
+		{
+			name:            "BC8",
+			from:            "openai",
+			to:              "antigravity",
+			model:           "antigravity-claude-budget-model(200000)",
+			inputJSON:       `{"model":"antigravity-claude-budget-model(200000)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:       200000,
+			expectField:     "request.generationConfig.thinkingConfig.thinkingBudget",
+			expectValue:     "128000",
+			includeThoughts: "true",
+		},
+		// Zero with ZeroAllowed=false: raised to Min with thoughts hidden.
+		{
+			name:            "BC9",
+			from:            "openai",
+			to:              "gemini",
+			model:           "gemini-budget-model(0)",
+			inputJSON:       `{"model":"gemini-budget-model(0)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:       16000,
+			expectField:     "generationConfig.thinkingConfig.thinkingBudget",
+			expectValue:     "128",
+			includeThoughts: "false",
+		},
+		// Zero with ZeroAllowed=true: thinking is turned off on both Claude targets.
+		{
+			name:         "BC10",
+			from:         "openai",
+			to:           "claude",
+			model:        "claude-budget-model(0)",
+			inputJSON:    `{"model":"claude-budget-model(0)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:    16000,
+			expectField:  "thinking.type",
+			expectValue:  "disabled",
+			expectAbsent: []string{"thinking.budget_tokens"},
+		},
+		{
+			name:         "BC11",
+			from:         "openai",
+			to:           "antigravity",
+			model:        "antigravity-claude-budget-model(0)",
+			inputJSON:    `{"model":"antigravity-claude-budget-model(0)","messages":[{"role":"user","content":"hi"}]}`,
+			maxTokens:    16000,
+			expectField:  "request.generationConfig.maxOutputTokens",
+			expectValue:  "16000",
+			expectAbsent: []string{"request.generationConfig.thinkingConfig"},
+		},
+	}
+
+	runThinkingTests(t, cases)
+}
+
 // TestThinkingE2EClaudeAdaptive_Body covers Group 3 cases in docs/thinking-e2e-test-cases.md.
 // It focuses on Claude 4.6 adaptive thinking and effort/level cross-protocol semantics (body-only).
 func TestThinkingE2EClaudeAdaptive_Body(t *testing.T) {
@@ -2975,6 +3158,174 @@ func TestThinkingE2EClaudeAdaptive_Body(t *testing.T) {
 	runThinkingTests(t, cases)
 }
 
+// thinkingResponseTestCase drives a request through translation and ApplyThinking, then
+// translates an upstream answer carrying a thought part back to the client format.
+type thinkingResponseTestCase struct {
+	name      string
+	from      string
+	to        string
+	model     string
+	inputJSON string
+	// expectThoughts is false when the applied config has includeThoughts=false and the
+	// thought part must not reach the client.
+	expectThoughts bool
+	// usageField and usageValue assert the thought tokens still count in the
+	// non-stream usage.
+	usageField string
+	usageValue int64
+}
+
+// TestThinkingE2EMatrix_ResponseThoughts tests the response side of includeThoughts: thought
+// parts the upstream sends anyway are dropped for (none) and include_thoughts=false configs,
+// while their tokens still count in usage.
+// Data flow: Input JSON → TranslateRequest → ApplyThinking → TranslateStream/TranslateNonStream
+func TestThinkingE2EMatrix_ResponseThoughts(t *testing.T) {
+	reg := registry.GetGlobalRegistry()
+	uid := fmt.Sprintf("thinking-e2e-response-%d", time.Now().UnixNano())
+
+	reg.RegisterClient(uid, "test", getTestModels())
+	defer reg.UnregisterClient(uid)
+
+	cases := []thinkingResponseTestCase{
+		// Suffix (none) → includeThoughts=false
+		{
+			name:       "R1",
+			from:       "openai",
+			to:         "gemini",
+			model:      "gemini-budget-model(none)",
+			inputJSON:  `{"model":"gemini-budget-model(none)","messages":[{"role":"user","content":"hi"}]}`,
+			usageField: "usage.completion_tokens_details.reasoning_tokens",
+			usageValue: 7,
+		},
+		{
+			name:       "R2",
+			from:       "claude",
+			to:         "gemini",
+			model:      "gemini-budget-model(none)",
+			inputJSON:  `{"model":"gemini-budget-model(none)","max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`,
+			usageField: "usage.output_tokens",
+			usageValue: 9,
+		},
+		{
+			name:       "R3",
+			from:       "openai-response",
+			to:         "gemini",
+			model:      "gemini-budget-model(none)",
+			inputJSON:  `{"model":"gemini-budget-model(none)","input":"hi"}`,
+			usageField: "usage.output_tokens_details.reasoning_tokens",
+			usageValue: 7,
+		},
+		{
+			name:       "R4",
+			from:       "openai",
+			to:         "antigravity",
+			model:      "antigravity-budget-model(none)",
+			inputJSON:  `{"model":"antigravity-budget-model(none)","messages":[{"role":"user","content":"hi"}]}`,
+			usageField: "usage.completion_tokens_details.reasoning_tokens",
+			usageValue: 7,
+		},
+		{
+			name:       "R5",
+			from:       "claude",
+			to:         "antigravity",
+			model:      "antigravity-budget-model(none)",
+			inputJSON:  `{"model":"antigravity-budget-model(none)","max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`,
+			usageField: "usage.output_tokens",
+			usageValue: 9,
+		},
+		// Body include_thoughts=false with a thinking budget
+		{
+			name:       "R6",
+			from:       "gemini",
+			to:         "gemini",
+			model:      "gemini-budget-model",
+			inputJSON:  `{"model":"gemini-budget-model","contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"thinkingConfig":{"thinkingBudget":8192,"include_thoughts":false}}}`,
+			usageField: "usageMetadata.thoughtsTokenCount",
+			usageValue: 7,
+		},
+		{
+			name:       "R7",
+			from:       "gemini",
+			to:         "antigravity",
+			model:      "antigravity-budget-model",
+			inputJSON:  `{"model":"antigravity-budget-model","contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"thinkingConfig":{"thinkingBudget":8192,"includeThoughts":false}}}`,
+			usageField: "usageMetadata.thoughtsTokenCount",
+			usageValue: 7,
+		},
+		// Thoughts requested → forwarded
+		{
+			name:           "R8",
+			from:           "openai",
+			to:             "gemini",
+			model:          "gemini-budget-model(8192)",
+			inputJSON:      `{"model":"gemini-budget-model(8192)","messages":[{"role":"user","content":"hi"}]}`,
+			expectThoughts: true,
+			usageField:     "usage.completion_tokens_details.reasoning_tokens",
+			usageValue:     7,
+		},
+		{
+			name:           "R9",
+			from:           "claude",
+			to:             "antigravity",
+			model:          "antigravity-budget-model(8192)",
+			inputJSON:      `{"model":"antigravity-budget-model(8192)","max_tokens":1024,"messages":[{"role":"user","content":"hi"}]}`,
+			expectThoughts: true,
+			usageField:     "usage.output_tokens",
+			usageValue:     9,
+		},
+	}
+
+	const (
+		thoughtChunk = `{"candidates":[{"content":{"role":"model","parts":[{"text":"SECRET_THOUGHT","thought":true}]}}],"usageMetadata":{"promptTokenCount":3,"thoughtsTokenCount":7,"totalTokenCount":10}}`
+		answerChunk  = `{"candidates":[{"content":{"role":"model","parts":[{"text":"VISIBLE_ANSWER"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"thoughtsTokenCount":7,"totalTokenCount":12}}`
+		nonStream    = `{"candidates":[{"content":{"role":"model","parts":[{"text":"SECRET_THOUGHT","thought":true},{"text":"VISIBLE_ANSWER"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"thoughtsTokenCount":7,"totalTokenCount":12}}`
+	)
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(fmt.Sprintf("Case%s_%s->%s_%s", tc.name, tc.from, tc.to, tc.model), func(t *testing.T) {
+			from := sdktranslator.FromString(tc.from)
+			to := sdktranslator.FromString(tc.to)
+			body := sdktranslator.TranslateRequest(from, to, thinking.ParseSuffix(tc.model).ModelName, []byte(tc.inputJSON), true)
+			body, err := thinking.ApplyThinking(body, tc.model, tc.from, tc.to, tc.to)
+			if err != nil {
+				t.Fatalf("unexpected error: %v, body=%s", err, string(body))
+			}
+
+			wrap := func(chunk string) []byte {
+				if tc.to == "antigravity" {
+					return []byte(`{"response":` + chunk + `}`)
+				}
+				return []byte(chunk)
+			}
+			ctx := context.WithValue(context.Background(), "alt", "")
+
+			var param any
+			var streamed strings.Builder
+			for _, chunk := range [][]byte{wrap(thoughtChunk), wrap(answerChunk), []byte("[DONE]")} {
+				for _, out := range sdktranslator.TranslateStream(ctx, to, from, tc.model, []byte(tc.inputJSON), body, chunk, &param) {
+					streamed.Write(out)
+					streamed.WriteByte('\n')
+				}
+			}
+			var nonStreamParam any
+			nonStreamOut := sdktranslator.TranslateNonStream(ctx, to, from, tc.model, []byte(tc.inputJSON), body, wrap(nonStream), &nonStreamParam)
+
+			for mode, out := range map[string]string{"stream": streamed.String(), "non-stream": string(nonStreamOut)} {
+				if !strings.Contains(out, "VISIBLE_ANSWER") {
+					t.Fatalf("%s output lacks the answer: %s", mode, out)
+				}
+				if got := strings.Contains(out, "SECRET_THOUGHT"); got != tc.expectThoughts {
+					t.Fatalf("%s output contains thoughts = %t, want %t: %s", mode, got, tc.expectThoughts, out)
+				}
+			}
+			if got := gjson.GetBytes(nonStreamOut, tc.usageField).Int(); got != tc.usageValue {
+				t.Fatalf("%s = %d, want %d: %s", tc.usageField, got, tc.usageValue, nonStreamOut)
+			}
+		})
+	}
+}
+
 // getTestModels returns the shared model definitions for E2E tests.
 func getTestModels() []*registry.ModelInfo {
 	return []*registry.ModelInfo{
@@ -3064,6 +3415,15 @@ func getTestModels() []*registry.ModelInfo {
 			DisplayName: "Antigravity Budget Model",
 			Thinking:    &registry.ThinkingSupport{Min: 128, Max: 20000, ZeroAllowed: true, DynamicAllowed: true},
 		},
+		{
+			ID:          "antigravity-claude-budget-model",
+			Object:      "model",
+			Created:     1700000000,
+			OwnedBy:     "test",
+			Type:        "antigravity",
+			DisplayName: "Antigravity Claude Budget Model",
+			Thinking:    &registry.ThinkingSupport{Min: 1024, Max: 128000, ZeroAllowed: true, DynamicAllowed: false},
+		},
 		{
 			ID:          "kimi-toggle-thinking-model",
 			Object:      "model",
@@ -3138,7 +3498,14 @@ func runThinkingTests(t *testing.T, cases []thinkingTestCase) {
 				[]byte(tc.inputJSON),
 				true,
 			)
-			if applyTo == "claude" {
+			switch {
+			case tc.maxTokens > 0 && applyTo == "claude":
+				body, _ = sjson.SetBytes(body, "max_tokens", tc.maxTokens)
+			case tc.maxTokens > 0 && applyTo == "antigravity":
+				body, _ = sjson.SetBytes(body, "request.generationConfig.maxOutputTokens", tc.maxTokens)
+			case tc.maxTokens > 0 && applyTo == "gemini":
+				body, _ = sjson.SetBytes(body, "generationConfig.maxOutputTokens", tc.maxTokens)
+			case applyTo == "claude":
 				body, _ = sjson.SetBytes(body, "max_tokens", 200000)
 			}
 